@@ -78,6 +78,11 @@ func ExchangeRate() string {
 	return os.Getenv(uploExchangeRate)
 }
 
+// StratumPassword returns the uploStratumPassword environment variable.
+func StratumPassword() string {
+	return os.Getenv(uploStratumPassword)
+}
+
 // apiPasswordFilePath returns the path to the API's password file. The password
 // file is stored in the Uplo data directory.
 func apiPasswordFilePath() string {
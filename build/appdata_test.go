@@ -146,3 +146,30 @@ func TestUploExchangeRate(t *testing.T) {
 		t.Errorf("Expected exchange rate to be %v but was %v", newRate, rate)
 	}
 }
+
+// TestUploStratumPassword tests getting and setting the Uplo Stratum Password
+func TestUploStratumPassword(t *testing.T) {
+	// Unset any defaults, this only affects in memory state. Any Env Vars will
+	// remain intact on disk
+	err := os.Unsetenv(uploStratumPassword)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Test Default
+	pw := StratumPassword()
+	if pw != "" {
+		t.Errorf("Expected stratum password to be blank but was %v", pw)
+	}
+
+	// Test Env Variable
+	newPW := "abc123"
+	err = os.Setenv(uploStratumPassword, newPW)
+	if err != nil {
+		t.Error(err)
+	}
+	pw = StratumPassword()
+	if pw != newPW {
+		t.Errorf("Expected stratum password to be %v but was %v", newPW, pw)
+	}
+}
@@ -0,0 +1,11 @@
+package build
+
+// ConsensusSnapshotCheckpoints maps block heights to the hex encoded hashes of
+// the trusted consensus snapshots at those heights. Unless the operator
+// supplies a trusted checkpoint, a consensus snapshot can only be imported if
+// its hash matches the checkpoint for its height.
+var ConsensusSnapshotCheckpoints = Select(Var{
+	Standard: map[uint64]string{},
+	Dev:      map[uint64]string{},
+	Testing:  map[uint64]string{},
+}).(map[uint64]string)
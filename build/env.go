@@ -20,4 +20,8 @@ var (
 	// uploExchangeRate is the environment variable that can be set to
 	// show amounts (additionally) in a different currency
 	uploExchangeRate = "UPLO_EXCHANGE_RATE"
+
+	// uploStratumPassword is the environment variable that sets the password
+	// workers need to authorize with the miner's Stratum server
+	uploStratumPassword = "UPLO_STRATUM_PASSWORD"
 )
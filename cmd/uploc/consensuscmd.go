@@ -2,12 +2,15 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/uplo-tech/uplo/node/api"
 	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/node/api"
+	"github.com/uplo-tech/uplo/types"
 )
 
 var (
@@ -17,6 +20,36 @@ var (
 		Long:  "Print the current state of consensus such as current block, block height, and target.",
 		Run:   wrap(consensuscmd),
 	}
+
+	consensusSnapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Export and import consensus snapshots",
+		Long: `Export and import snapshots of the consensus state. A new node can be
+bootstrapped from a snapshot instead of processing the whole blockchain. Only
+snapshots matching a trusted checkpoint or a checkpoint compiled into uplod can
+be imported.`,
+		Run: consensussnapshotcmd,
+	}
+
+	consensusSnapshotExportCmd = &cobra.Command{
+		Use:   "export [destination]",
+		Short: "Export a snapshot of the consensus state",
+		Long: `Export a snapshot of the consensus state to the destination on the
+machine running uplod. The snapshot is exported at the current height unless
+a height is specified with --height.`,
+		Run: wrap(consensussnapshotexportcmd),
+	}
+
+	consensusSnapshotImportCmd = &cobra.Command{
+		Use:   "import [source]",
+		Short: "Bootstrap the consensus set from a snapshot",
+		Long: `Bootstrap the consensus set from the snapshot at the source on the
+machine running uplod. The consensus set continues to sync normally from the
+height of the snapshot. The hash of the snapshot is checked against the trusted
+checkpoint given by --checkpoint, e.g. the hash printed when exporting the
+snapshot on a trusted node, or against the checkpoints compiled into uplod.`,
+		Run: wrap(consensussnapshotimportcmd),
+	}
 )
 
 // consensuscmd is the handler for the command `uploc consensus`.
@@ -60,3 +93,44 @@ Progress (estimated): %.1f%%
 		fmt.Println("Genesis Timestamp:", time.Unix(int64(cg.GenesisTimestamp), 0))
 	}
 }
+
+// consensussnapshotcmd is the handler for the command `uploc consensus
+// snapshot`.
+func consensussnapshotcmd(cmd *cobra.Command, _ []string) {
+	_ = cmd.UsageFunc()(cmd)
+	os.Exit(exitCodeUsage)
+}
+
+// consensussnapshotexportcmd is the handler for the command `uploc consensus
+// snapshot export [destination]`.
+func consensussnapshotexportcmd(destination string) {
+	height := types.BlockHeight(consensusSnapshotHeight)
+	if height == 0 {
+		cg, err := httpClient.ConsensusGet()
+		if err != nil {
+			die("Could not get current consensus state:", err)
+		}
+		height = cg.Height
+	}
+	csp, err := httpClient.ConsensusSnapshotExportPost(abs(destination), height)
+	if err != nil {
+		die("Could not export snapshot:", err)
+	}
+	fmt.Printf("Exported snapshot at height %v to %v\nHash: %v\n", csp.Height, abs(destination), csp.Hash)
+}
+
+// consensussnapshotimportcmd is the handler for the command `uploc consensus
+// snapshot import [source]`.
+func consensussnapshotimportcmd(source string) {
+	var checkpoint crypto.Hash
+	if consensusSnapshotCheckpoint != "" {
+		if err := checkpoint.LoadString(consensusSnapshotCheckpoint); err != nil {
+			die("Could not parse checkpoint:", err)
+		}
+	}
+	err := httpClient.ConsensusSnapshotImportPost(abs(source), checkpoint)
+	if err != nil {
+		die("Could not import snapshot:", err)
+	}
+	fmt.Println("Imported snapshot from", abs(source))
+}
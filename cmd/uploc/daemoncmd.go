@@ -2,12 +2,18 @@ package main
 
 import (
 	"fmt"
+	"math/big"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/uplo-tech/uplo/build"
 	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/node/api"
+	"github.com/uplo-tech/uplo/types"
 	"github.com/uplo-tech/errors"
 )
 
@@ -19,6 +25,74 @@ var (
 		Run:   wrap(alertscmd),
 	}
 
+	daemonCmd = &cobra.Command{
+		Use:   "daemon",
+		Short: "Perform daemon actions",
+		Long:  "Perform daemon actions.",
+		Run:   daemoncmd,
+	}
+
+	daemonTokensCmd = &cobra.Command{
+		Use:   "tokens",
+		Short: "View the API tokens of the daemon",
+		Long: `View the API tokens of the daemon. API tokens can be used in place of the
+API password for the routes within their scopes.`,
+		Run: wrap(daemontokenscmd),
+	}
+
+	daemonTokensAddCmd = &cobra.Command{
+		Use:   "add [name] [scopes]",
+		Short: "Create an API token",
+		Long: `Create an API token with a comma separated list of scopes. The token is
+only printed once. Available scopes:
+  ` + strings.Join(api.TokenScopes, "\n  ") + `
+
+Use --expires-in to limit the lifetime of the token and --spend-cap to limit
+the Uplocoins that can be sent with the token.`,
+		Run: wrap(daemontokensaddcmd),
+	}
+
+	daemonTokensRemoveCmd = &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove an API token",
+		Long:  "Remove an API token, revoking its access to the daemon.",
+		Run:   wrap(daemontokensremovecmd),
+	}
+
+	daemonWebhooksCmd = &cobra.Command{
+		Use:   "webhooks",
+		Short: "View the webhooks of the daemon",
+		Long: `View the webhooks of the daemon. Webhooks receive the events of the daemon
+as signed HTTP POST requests.`,
+		Run: wrap(daemonwebhookscmd),
+	}
+
+	daemonWebhooksAddCmd = &cobra.Command{
+		Use:   "add [name] [url] [secret]",
+		Short: "Add a webhook",
+		Long: `Add a webhook. The payloads are signed with the secret using HMAC-SHA256 and
+the signature is sent in the Uplo-Signature header.
+
+Use --event-types and --modules to only deliver some events and
+--min-severity to only deliver alerts of a minimum severity.`,
+		Run: wrap(daemonwebhooksaddcmd),
+	}
+
+	daemonWebhooksDeliveriesCmd = &cobra.Command{
+		Use:   "deliveries [name]",
+		Short: "View the recent deliveries of the webhooks",
+		Long: `View the recent deliveries of all webhooks, or only of the webhook with the
+given name.`,
+		Run: daemonwebhooksdeliveriescmd,
+	}
+
+	daemonWebhooksRemoveCmd = &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a webhook",
+		Long:  "Remove a webhook.",
+		Run:   wrap(daemonwebhooksremovecmd),
+	}
+
 	stopCmd = &cobra.Command{
 		Use:   "stop",
 		Short: "Stop the Uplo daemon",
@@ -68,6 +142,31 @@ directory in the uplod data directory.`,
 		Run:   wrap(profilestopcmd),
 	}
 
+	proxyCmd = &cobra.Command{
+		Use:   "proxy",
+		Short: "View the proxy settings of the daemon",
+		Long: `View the SOCKS5 proxy through which outbound connections to peers and
+hosts are routed.`,
+		Run: wrap(proxycmd),
+	}
+
+	proxyClearCmd = &cobra.Command{
+		Use:   "clear",
+		Short: "Stop using a proxy",
+		Long:  "Stop routing outbound connections through a proxy.",
+		Run:   wrap(proxyclearcmd),
+	}
+
+	proxySetCmd = &cobra.Command{
+		Use:   "set [address]",
+		Short: "Route outbound connections through a SOCKS5 proxy",
+		Long: `Route outbound connections to peers and hosts through a SOCKS5 proxy, e.g.
+the address of a local Tor daemon like 127.0.0.1:9050. Renter connections over
+the uplomux are routed through the proxy by a local relay. Use --proxy-only to
+refuse connections which can't be routed through the proxy.`,
+		Run: wrap(proxysetcmd),
+	}
+
 	stackCmd = &cobra.Command{
 		Use:   "stack",
 		Short: "Get current stack trace for the daemon",
@@ -293,6 +392,220 @@ func globalratelimitcmd(downloadSpeedStr, uploadSpeedStr string) {
 	fmt.Println("Set global maxdownloadspeed to ", downloadSpeedInt, " and maxuploadspeed to ", uploadSpeedInt)
 }
 
+// proxycmd is the handler for the command `uploc proxy`.
+// Prints the proxy settings of the daemon.
+func proxycmd() {
+	dsg, err := httpClient.DaemonSettingsGet()
+	if err != nil {
+		die("Could not get daemon settings:", err)
+	}
+	if dsg.ProxyAddress == "" {
+		fmt.Println("No proxy is set, outbound connections are direct.")
+		return
+	}
+	fmt.Println("Proxy address:", dsg.ProxyAddress)
+	fmt.Println("Refuse direct connections:", yesNo(dsg.ProxyOnly))
+}
+
+// daemoncmd displays the usage info for the command.
+func daemoncmd(cmd *cobra.Command, args []string) {
+	_ = cmd.UsageFunc()(cmd)
+	os.Exit(exitCodeUsage)
+}
+
+// daemontokenscmd is the handler for the command `uploc daemon tokens`.
+// Lists the API tokens of the daemon.
+func daemontokenscmd() {
+	dtg, err := httpClient.DaemonTokensGet()
+	if err != nil {
+		die("Could not get API tokens:", err)
+	}
+	if len(dtg.Tokens) == 0 {
+		fmt.Println("No API tokens.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tScopes\tExpiry\tSpent\tSpend Cap")
+	for _, t := range dtg.Tokens {
+		expiry := "never"
+		if !t.Expiry.IsZero() {
+			expiry = t.Expiry.Format(time.RFC822)
+		}
+		spendCap := "none"
+		if !t.SpendCap.IsZero() {
+			spendCap = currencyUnits(t.SpendCap)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", t.Name, strings.Join(t.Scopes, ","), expiry, currencyUnits(t.Spent), spendCap)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
+
+// daemontokensaddcmd is the handler for the command `uploc daemon tokens add
+// [name] [scopes]`. Creates an API token.
+func daemontokensaddcmd(name, scopes string) {
+	var expiry time.Time
+	if daemonTokenExpiresIn != "" {
+		d, err := time.ParseDuration(daemonTokenExpiresIn)
+		if err != nil {
+			die("Could not parse expires-in:", err)
+		}
+		expiry = time.Now().Add(d)
+	}
+	var spendCap types.Currency
+	if daemonTokenSpendCap != "" {
+		hastings, err := types.ParseCurrency(daemonTokenSpendCap)
+		if err != nil {
+			die("Could not parse spend-cap:", err)
+		}
+		i, _ := new(big.Int).SetString(hastings, 10)
+		spendCap = types.NewCurrency(i)
+	}
+	dtap, err := httpClient.DaemonTokensAddPost(name, strings.Split(scopes, ","), expiry, spendCap)
+	if err != nil {
+		die("Could not create API token:", err)
+	}
+	fmt.Printf("Created API token %v. Store it safely, it won't be shown again:\n%v\n", name, dtap.Token)
+}
+
+// daemontokensremovecmd is the handler for the command `uploc daemon tokens
+// remove [name]`. Removes an API token.
+func daemontokensremovecmd(name string) {
+	err := httpClient.DaemonTokensRemovePost(name)
+	if err != nil {
+		die("Could not remove API token:", err)
+	}
+	fmt.Println("Removed API token", name)
+}
+
+// daemonwebhookscmd is the handler for the command `uploc daemon webhooks`.
+// Lists the webhooks of the daemon.
+func daemonwebhookscmd() {
+	dwg, err := httpClient.DaemonWebhooksGet()
+	if err != nil {
+		die("Could not get webhooks:", err)
+	}
+	if len(dwg.Webhooks) == 0 {
+		fmt.Println("No webhooks.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tURL\tEvent Types\tModules\tMin Severity")
+	for _, wh := range dwg.Webhooks {
+		eventTypes := "all"
+		if len(wh.EventTypes) > 0 {
+			typeStrs := make([]string, 0, len(wh.EventTypes))
+			for _, t := range wh.EventTypes {
+				typeStrs = append(typeStrs, string(t))
+			}
+			eventTypes = strings.Join(typeStrs, ",")
+		}
+		moduleNames := "all"
+		if len(wh.Modules) > 0 {
+			moduleNames = strings.Join(wh.Modules, ",")
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", wh.Name, wh.URL, eventTypes, moduleNames, wh.MinSeverity)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
+
+// daemonwebhooksaddcmd is the handler for the command `uploc daemon webhooks
+// add [name] [url] [secret]`. Adds a webhook.
+func daemonwebhooksaddcmd(name, url, secret string) {
+	var eventTypes []modules.EventType
+	for _, t := range strings.Split(daemonWebhookEventTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			eventTypes = append(eventTypes, modules.EventType(t))
+		}
+	}
+	var moduleNames []string
+	for _, m := range strings.Split(daemonWebhookModules, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			moduleNames = append(moduleNames, m)
+		}
+	}
+	var minSeverity modules.AlertSeverity
+	if daemonWebhookMinSeverity != "" {
+		if err := minSeverity.UnmarshalJSON([]byte(fmt.Sprintf("%q", daemonWebhookMinSeverity))); err != nil {
+			die("Could not parse min-severity:", err)
+		}
+	}
+	err := httpClient.DaemonWebhooksAddPost(name, url, secret, eventTypes, moduleNames, minSeverity)
+	if err != nil {
+		die("Could not add webhook:", err)
+	}
+	fmt.Println("Added webhook", name)
+}
+
+// daemonwebhooksdeliveriescmd is the handler for the command `uploc daemon
+// webhooks deliveries [name]`. Lists the recent deliveries of the webhooks.
+func daemonwebhooksdeliveriescmd(cmd *cobra.Command, args []string) {
+	var name string
+	switch len(args) {
+	case 0:
+	case 1:
+		name = args[0]
+	default:
+		_ = cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	dwdg, err := httpClient.DaemonWebhookDeliveriesGet(name)
+	if err != nil {
+		die("Could not get webhook deliveries:", err)
+	}
+	if len(dwdg.Deliveries) == 0 {
+		fmt.Println("No webhook deliveries.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Time\tWebhook\tEvent\tAttempts\tStatus\tError")
+	for _, d := range dwdg.Deliveries {
+		status := "pending"
+		if d.Delivered {
+			status = "delivered"
+		} else if d.Attempts >= 1 && d.Error != "" {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v (%v)\t%v\t%v\t%v\n", d.Time.Format(time.RFC822), d.Webhook, d.EventType, d.EventID, d.Attempts, status, d.Error)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
+
+// daemonwebhooksremovecmd is the handler for the command `uploc daemon
+// webhooks remove [name]`. Removes a webhook.
+func daemonwebhooksremovecmd(name string) {
+	err := httpClient.DaemonWebhooksRemovePost(name)
+	if err != nil {
+		die("Could not remove webhook:", err)
+	}
+	fmt.Println("Removed webhook", name)
+}
+
+// proxyclearcmd is the handler for the command `uploc proxy clear`.
+// Disables the proxy of the daemon.
+func proxyclearcmd() {
+	err := httpClient.DaemonProxyPost("", false)
+	if err != nil {
+		die("Could not clear proxy:", err)
+	}
+	fmt.Println("Cleared proxy, outbound connections are direct.")
+}
+
+// proxysetcmd is the handler for the command `uploc proxy set [address]`.
+// Sets the proxy of the daemon.
+func proxysetcmd(address string) {
+	err := httpClient.DaemonProxyPost(address, daemonProxyOnly)
+	if err != nil {
+		die("Could not set proxy:", err)
+	}
+	fmt.Println("Set proxy to", address)
+}
+
 // printAlerts is a helper function to print details of a slice of alerts
 // with given severity description to command line
 func printAlerts(alerts []modules.Alert, as modules.AlertSeverity) {
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"text/tabwriter"
 	"time"
 
@@ -95,6 +96,15 @@ For example: uploc gateway blocklist set 123.123.123.123 111.222.111.222 myuploh
 		Run:   wrap(gatewaydisconnectcmd),
 	}
 
+	gatewayForgetNodeKeyCmd = &cobra.Command{
+		Use:   "forgetnodekey [address]",
+		Short: "Forget the node key of a node",
+		Long: `Forget the node key a node authenticated its encrypted connections with.
+Later connections to the node are refused if they use a different node key.
+Forget the node key if the node legitimately replaced it.`,
+		Run: wrap(gatewayforgetnodekeycmd),
+	}
+
 	gatewayListCmd = &cobra.Command{
 		Use:   "list",
 		Short: "View a list of peers",
@@ -112,6 +122,22 @@ Bits per second: Bps, Kbps, Mbps, Gbps, Tbps
 Set them to 0 for no limit.`,
 		Run: wrap(gatewayratelimitcmd),
 	}
+
+	gatewayReputationCmd = &cobra.Command{
+		Use:   "reputation",
+		Short: "View the misbehavior scores and bans of peers",
+		Long: `View the misbehavior scores and bans of peers that relayed invalid blocks or
+transactions, timed out or violated the protocol recently.`,
+		Run: wrap(gatewayreputationcmd),
+	}
+
+	gatewayRequireEncryptionCmd = &cobra.Command{
+		Use:   "requireencryption [true/false]",
+		Short: "Require encrypted peer connections",
+		Long: `Set whether the gateway refuses peers which don't support the encrypted
+transport. Existing connections are not affected.`,
+		Run: wrap(gatewayrequireencryptioncmd),
+	}
 )
 
 // gatewayconnectcmd is the handler for the command `uploc gateway add [address]`.
@@ -134,6 +160,16 @@ func gatewaydisconnectcmd(addr string) {
 	fmt.Println("Removed", addr, "from peer list.")
 }
 
+// gatewayforgetnodekeycmd is the handler for the command `uploc gateway
+// forgetnodekey [address]`. Forgets the node key of a node.
+func gatewayforgetnodekeycmd(addr string) {
+	err := httpClient.GatewayForgetNodeKeyPost(modules.NetAddress(addr))
+	if err != nil {
+		die("Could not forget node key:", err)
+	}
+	fmt.Println("Forgot the node key of", addr)
+}
+
 // gatewayaddresscmd is the handler for the command `uploc gateway address`.
 // Prints the gateway's network address.
 func gatewayaddresscmd() {
@@ -169,6 +205,14 @@ func gatewaycmd() {
 	fmt.Println("Active peers:", len(info.Peers))
 	fmt.Println("Max download speed:", info.MaxDownloadSpeed)
 	fmt.Println("Max upload speed:", info.MaxUploadSpeed)
+	fmt.Println("Require encryption:", yesNo(info.RequireEncryption))
+	var banned int
+	for _, r := range info.Reputations {
+		if time.Now().Before(r.BannedUntil) {
+			banned++
+		}
+	}
+	fmt.Println("Banned peers:", banned)
 }
 
 // gatewayblocklistcmd is the handler for the command `uploc gateway blocklist`
@@ -256,9 +300,9 @@ func gatewaylistcmd() {
 	}
 	fmt.Println(len(info.Peers), "active peers:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Version\tOutbound\tAddress")
+	fmt.Fprintln(w, "Version\tOutbound\tEncrypted\tAddress")
 	for _, peer := range info.Peers {
-		fmt.Fprintf(w, "%v\t%v\t%v\n", peer.Version, yesNo(!peer.Inbound), peer.NetAddress)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", peer.Version, yesNo(!peer.Inbound), yesNo(peer.Encrypted), peer.NetAddress)
 	}
 	if err := w.Flush(); err != nil {
 		die("failed to flush writer")
@@ -284,3 +328,43 @@ func gatewayratelimitcmd(downloadSpeedStr, uploadSpeedStr string) {
 	}
 	fmt.Println("Set gateway maxdownloadspeed to ", downloadSpeedInt, " and maxuploadspeed to ", uploadSpeedInt)
 }
+
+// gatewayrequireencryptioncmd is the handler for the command `uploc gateway
+// requireencryption`. Sets whether the gateway refuses unencrypted peer
+// connections.
+func gatewayrequireencryptioncmd(requireStr string) {
+	require, err := strconv.ParseBool(requireStr)
+	if err != nil {
+		die("Could not parse requireencryption:", err)
+	}
+	err = httpClient.GatewayRequireEncryptionPost(require)
+	if err != nil {
+		die("Could not set requireencryption:", err)
+	}
+	fmt.Println("Set gateway requireencryption to", require)
+}
+
+// gatewayreputationcmd is the handler for the command `uploc gateway
+// reputation`. Prints the misbehavior scores and bans of peers.
+func gatewayreputationcmd() {
+	info, err := httpClient.GatewayGet()
+	if err != nil {
+		die("Could not get peer reputations:", err)
+	}
+	if len(info.Reputations) == 0 {
+		fmt.Println("No peers misbehaved recently.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Address\tScore\tBans\tBanned Until")
+	for _, r := range info.Reputations {
+		bannedUntil := "-"
+		if time.Now().Before(r.BannedUntil) {
+			bannedUntil = r.BannedUntil.Format(time.RFC822)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", r.Address, r.Score, r.Bans, bannedUntil)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
@@ -23,12 +23,22 @@ var (
 
 	// Module Specific Flags
 	//
+	// Consensus Flags
+	consensusSnapshotCheckpoint string // Trusted hash of an imported snapshot.
+	consensusSnapshotHeight     uint64 // Height at which the snapshot is exported.
+
 	// Daemon Flags
-	daemonStackOutputFile  string // The file that the stack trace will be written to
-	daemonCPUProfile       bool   // Indicates that the CPU profile should be started
-	daemonMemoryProfile    bool   // Indicates that the Memory profile should be started
-	daemonProfileDirectory string // The Directory where the profile logs are saved
-	daemonTraceProfile     bool   // Indicates that the Trace profile should be started
+	daemonStackOutputFile    string // The file that the stack trace will be written to
+	daemonCPUProfile         bool   // Indicates that the CPU profile should be started
+	daemonMemoryProfile      bool   // Indicates that the Memory profile should be started
+	daemonProfileDirectory   string // The Directory where the profile logs are saved
+	daemonProxyOnly          bool   // Indicates that direct connections should be refused
+	daemonTokenExpiresIn     string // The lifetime of a new API token
+	daemonTokenSpendCap      string // The maximum amount of Uplocoins a new API token can send
+	daemonTraceProfile       bool   // Indicates that the Trace profile should be started
+	daemonWebhookEventTypes  string // The event types delivered to a new webhook
+	daemonWebhookMinSeverity string // The minimum severity of alerts delivered to a new webhook
+	daemonWebhookModules     string // The modules whose events are delivered to a new webhook
 
 	// Host Flags
 	hostContractOutputType string // output type for host contracts
@@ -47,6 +57,10 @@ var (
 	renterListRoot            bool   // List path start from root instead of the UserFolder.
 	renterRenameRoot          bool   // Rename files relative to root instead of the UserFolder.
 	renterShowHistory         bool   // Show download history in addition to download queue.
+	renterUploadCompression   string // Compression applied to the uploaded files.
+	renterUploadDedup         bool   // Deduplicate the uploaded files against previously deduplicated uploads.
+	renterVersionsMaxAge      string // Maximum age of file versions.
+	renterVersionsMaxVersions uint64 // Maximum number of versions per file.
 
 	// Renter Allowance Flags
 	allowanceFunds       string // amount of money to be used within a period
@@ -72,6 +86,7 @@ var (
 	skykeyID              string // ID used to identify a Skykey.
 	skykeyName            string // Name used to identify a Skykey.
 	skykeyRenameAs        string // Optional parameter to rename a Skykey while adding it.
+	skykeyRotateBlocklist bool   // Blocklist the old skylinks when rotating a Skykey.
 	skykeyShowPrivateKeys bool   // Set to true to show private key data.
 	skykeyType            string // Type used to create a new Skykey.
 
@@ -85,6 +100,7 @@ var (
 	skynetUploadDefaultPath        string // Specify the file to serve when no specific file is specified.
 	skynetUploadDisableDefaultPath bool   // This skyfile will not have a default path. The only way to use it is to download it.
 	skynetUploadDryRun             bool   // Perform a dry-run of the upload. This returns the skylink without actually uploading the file to the network.
+	skynetUploadHLSSegmentDuration uint64 // Split an MPEG-TS video into HLS segments of this many seconds.
 	skynetUploadRoot               bool   // Use root as the base instead of the Skynet folder.
 	skynetUploadSeparately         bool   // When uploading all files from a directory, upload each file separately, generating individual skylinks.
 	skynetUploadSilent             bool   // Don't report progress while uploading
@@ -95,17 +111,31 @@ var (
 
 	// Wallet Flags
 	initForce            bool   // destroy and re-encrypt the wallet on init if it already exists
+	walletAccount        string // account of the wallet to use
 	initPassword         bool   // supply a custom password when creating a wallet
 	walletRawTxn         bool   // Encode/decode transactions in base64-encoded binary.
 	walletStartHeight    uint64 // Start height for transaction search.
 	walletEndHeight      uint64 // End height for transaction search.
+	walletExportFormat   string // format of the wallet history export
+	walletExportRates    string // dated exchange rates of the wallet history export
+	walletScheduleCount  uint64 // number of payments of a scheduled payment
+	walletScheduleEvery  string // interval of a scheduled payment
+	walletScheduleStart  uint64 // height of the first payment of a scheduled payment
 	walletTxnFeeIncluded bool   // include the fee in the balance being sent
+	walletTxnInputs      string // comma separated list of the outputs to spend
+	walletTxnTimelock    uint64 // height until which the sent coins are locked
 )
 
 var (
 	// Globals.
 	rootCmd    *cobra.Command // Root command cobra object, used by bash completion cmd.
 	httpClient client.Client
+
+	// API TLS Flags
+	apiTLS     bool   // connect to the API over TLS
+	apiTLSCA   string // CA file used to verify the certificate of the API
+	apiTLSCert string // client certificate for mutual TLS
+	apiTLSKey  string // key of the client certificate
 )
 
 // Exit codes.
@@ -269,6 +299,9 @@ func main() {
 		// set API password if it was not set
 		setAPIPasswordIfNotSet()
 
+		// set API TLS config if TLS was enabled
+		setAPITLSConfig()
+
 		// Check if the uplodir is set.
 		if uplodir == "" {
 			// No uplodir passed in, fetch the uplodir
@@ -305,6 +338,10 @@ func initCmds() *cobra.Command {
 
 	// create command tree (alphabetized by root command)
 	root.AddCommand(consensusCmd)
+	consensusCmd.AddCommand(consensusSnapshotCmd)
+	consensusSnapshotCmd.AddCommand(consensusSnapshotExportCmd, consensusSnapshotImportCmd)
+	consensusSnapshotExportCmd.Flags().Uint64Var(&consensusSnapshotHeight, "height", 0, "Height at which the snapshot is exported, defaults to the current height")
+	consensusSnapshotImportCmd.Flags().StringVar(&consensusSnapshotCheckpoint, "checkpoint", "", "Trusted hash of the snapshot, defaults to the checkpoints compiled into uplod")
 	root.AddCommand(jsonCmd)
 
 	// Add feemanager commands
@@ -312,7 +349,7 @@ func initCmds() *cobra.Command {
 	feeManagerCmd.AddCommand(feeManagerCancelFeeCmd)
 
 	root.AddCommand(gatewayCmd)
-	gatewayCmd.AddCommand(gatewayAddressCmd, gatewayBandwidthCmd, gatewayBlocklistCmd, gatewayConnectCmd, gatewayDisconnectCmd, gatewayListCmd, gatewayRatelimitCmd)
+	gatewayCmd.AddCommand(gatewayAddressCmd, gatewayBandwidthCmd, gatewayBlocklistCmd, gatewayConnectCmd, gatewayDisconnectCmd, gatewayForgetNodeKeyCmd, gatewayListCmd, gatewayRatelimitCmd, gatewayReputationCmd, gatewayRequireEncryptionCmd)
 	gatewayBlocklistCmd.AddCommand(gatewayBlocklistAppendCmd, gatewayBlocklistClearCmd, gatewayBlocklistRemoveCmd, gatewayBlocklistSetCmd)
 
 	root.AddCommand(hostCmd)
@@ -327,7 +364,7 @@ func initCmds() *cobra.Command {
 	hostdbCmd.Flags().IntVarP(&hostdbNumHosts, "numhosts", "n", 0, "Number of hosts to display from the hostdb")
 
 	root.AddCommand(minerCmd)
-	minerCmd.AddCommand(minerStartCmd, minerStopCmd)
+	minerCmd.AddCommand(minerStartCmd, minerStopCmd, minerStratumCmd)
 
 	root.AddCommand(renterCmd)
 	renterCmd.AddCommand(renterAllowanceCmd, renterBackupCreateCmd, renterBackupListCmd, renterBackupLoadCmd,
@@ -336,7 +373,7 @@ func initCmds() *cobra.Command {
 		renterFilesListCmd, renterFilesRenameCmd, renterFilesUnstuckCmd, renterFilesUploadCmd,
 		renterFuseCmd, renterLostCmd, renterPricesCmd, renterRatelimitCmd, renterSetAllowanceCmd,
 		renterSetLocalPathCmd, renterTriggerContractRecoveryScanCmd, renterUploadsCmd, renterWorkersCmd,
-		renterHealthSummaryCmd)
+		renterHealthSummaryCmd, renterTrashCmd, renterVersionsCmd)
 	renterWorkersCmd.AddCommand(renterWorkersAccountsCmd, renterWorkersDownloadsCmd, renterWorkersPriceTableCmd, renterWorkersReadJobsCmd, renterWorkersHasSectorJobSCmd, renterWorkersUploadsCmd, renterWorkersReadRegistryCmd, renterWorkersUpdateRegistryCmd)
 
 	renterAllowanceCmd.AddCommand(renterAllowanceCancelCmd)
@@ -353,6 +390,8 @@ func initCmds() *cobra.Command {
 	renterFilesListCmd.Flags().BoolVar(&renterListRoot, "root", false, "List files and folders from root instead of from the user home directory")
 	renterFilesUploadCmd.Flags().StringVar(&dataPieces, "data-pieces", "", "the number of data pieces a files should be uploaded with")
 	renterFilesUploadCmd.Flags().StringVar(&parityPieces, "parity-pieces", "", "the number of parity pieces a files should be uploaded with")
+	renterFilesUploadCmd.Flags().BoolVar(&renterUploadDedup, "dedup", false, "reuse the pieces of identical chunks uploaded by other deduplicated files")
+	renterFilesUploadCmd.Flags().StringVar(&renterUploadCompression, "compression", "none", "compress the files before uploading them, can be 'none' or 'deflate'")
 	renterExportCmd.AddCommand(renterExportContractTxnsCmd)
 	renterFilesRenameCmd.Flags().BoolVar(&renterRenameRoot, "root", false, "Rename files relative to root instead of the user homedir")
 
@@ -373,6 +412,12 @@ func initCmds() *cobra.Command {
 	renterSetAllowanceCmd.Flags().StringVar(&allowanceMaxUploadBandwidthPrice, "max-upload-bandwidth-price", "", "the maximum price that the renter will pay to upload data to a host")
 
 	renterFuseCmd.AddCommand(renterFuseMountCmd, renterFuseUnmountCmd)
+	renterTrashCmd.AddCommand(renterTrashEmptyCmd, renterTrashRestoreCmd, renterTrashRetentionCmd)
+	renterVersionsCmd.AddCommand(renterVersionsDisableCmd, renterVersionsEnableCmd, renterVersionsPruneCmd, renterVersionsRestoreCmd)
+	renterVersionsEnableCmd.Flags().StringVar(&renterVersionsMaxAge, "max-age", "", "Prune versions older than this duration, e.g. 720h")
+	renterVersionsEnableCmd.Flags().Uint64Var(&renterVersionsMaxVersions, "max-versions", 0, "Maximum number of versions kept per file, 0 for unlimited")
+	renterVersionsPruneCmd.Flags().StringVar(&renterVersionsMaxAge, "max-age", "", "Prune versions older than this duration instead of applying the directory's policy")
+	renterVersionsPruneCmd.Flags().Uint64Var(&renterVersionsMaxVersions, "max-versions", 0, "Maximum number of versions kept per file instead of applying the directory's policy")
 	renterFuseMountCmd.Flags().BoolVarP(&renterFuseMountAllowOther, "allow-other", "", false, "Allow users other than the user that mounted the fuse directory to access and use the fuse directory")
 
 	root.AddCommand(skynetCmd)
@@ -385,6 +430,7 @@ func initCmds() *cobra.Command {
 	skynetUploadCmd.Flags().StringVar(&skynetUploadDefaultPath, "defaultpath", "", "Specify the file to serve when no specific file is specified.")
 	skynetUploadCmd.Flags().BoolVarP(&skynetUploadDisableDefaultPath, "disabledefaultpath", "", false, "This skyfile will not have a default path. The only way to use it is to download it. Mutually exclusive with --defaultpath")
 	skynetUploadCmd.Flags().BoolVarP(&skynetUploadSilent, "silent", "", false, "Don't report progress while uploading")
+	skynetUploadCmd.Flags().Uint64Var(&skynetUploadHLSSegmentDuration, "hls-segment-duration", 0, "Split an MPEG-TS video into HLS segments of the given number of seconds")
 	skynetUploadCmd.Flags().StringVar(&skykeyID, "skykeyid", "", "Specify the skykey to be used by its key identifier.")
 	skynetUploadCmd.Flags().StringVar(&skykeyName, "skykeyname", "", "Specify the skykey to be used by name.")
 	skynetUnpinCmd.Flags().BoolVar(&skynetUnpinRoot, "root", false, "Use the root folder as the base instead of the Skynet folder")
@@ -399,17 +445,35 @@ func initCmds() *cobra.Command {
 	skynetPortalsAddCmd.Flags().BoolVar(&skynetPortalPublic, "public", false, "Add this Skynet portal as public")
 
 	root.AddCommand(skykeyCmd)
-	skykeyCmd.AddCommand(skykeyAddCmd, skykeyCreateCmd, skykeyDeleteCmd, skykeyGetCmd, skykeyGetIDCmd, skykeyListCmd)
+	skykeyCmd.AddCommand(skykeyAddCmd, skykeyCreateCmd, skykeyDeleteCmd, skykeyGetCmd, skykeyGetIDCmd, skykeyListCmd, skykeyRotateCmd, skykeyShareCmd)
 	skykeyAddCmd.Flags().StringVar(&skykeyRenameAs, "rename-as", "", "The new name for the skykey being added")
 	skykeyCreateCmd.Flags().StringVar(&skykeyType, "type", "", "The type of the skykey")
 	skykeyDeleteCmd.AddCommand(skykeyDeleteNameCmd, skykeyDeleteIDCmd)
 	skykeyGetCmd.Flags().StringVar(&skykeyName, "name", "", "The name of the skykey")
 	skykeyGetCmd.Flags().StringVar(&skykeyID, "id", "", "The base-64 encoded skykey ID")
 	skykeyListCmd.Flags().BoolVar(&skykeyShowPrivateKeys, "show-priv-keys", false, "Show private key data.")
+	skykeyRotateCmd.Flags().StringVar(&skykeyName, "name", "", "The name of the skykey to rotate")
+	skykeyRotateCmd.Flags().StringVar(&skykeyID, "id", "", "The base-64 encoded ID of the skykey to rotate")
+	skykeyRotateCmd.Flags().BoolVar(&skykeyRotateBlocklist, "blocklist", false, "Add the old skylinks of the re-encrypted skyfiles to the skynet blocklist")
+	skykeyShareCmd.AddCommand(skykeySharePubkeyCmd, skykeyShareImportCmd)
+	skykeyShareCmd.Flags().StringVar(&skykeyName, "name", "", "The name of the skykey to share")
+	skykeyShareCmd.Flags().StringVar(&skykeyID, "id", "", "The base-64 encoded ID of the skykey to share")
+	skykeyShareImportCmd.Flags().StringVar(&skykeyRenameAs, "rename-as", "", "The new name for the skykey being imported")
 
 	// Daemon Commands
-	root.AddCommand(alertsCmd, globalRatelimitCmd, profileCmd, stackCmd, stopCmd, updateCmd, versionCmd)
+	root.AddCommand(alertsCmd, daemonCmd, globalRatelimitCmd, profileCmd, proxyCmd, stackCmd, stopCmd, updateCmd, versionCmd)
+	daemonCmd.AddCommand(daemonTokensCmd)
+	daemonTokensCmd.AddCommand(daemonTokensAddCmd, daemonTokensRemoveCmd)
+	daemonTokensAddCmd.Flags().StringVar(&daemonTokenExpiresIn, "expires-in", "", "The lifetime of the token, e.g. 720h")
+	daemonTokensAddCmd.Flags().StringVar(&daemonTokenSpendCap, "spend-cap", "", "The maximum amount of Uplocoins that can be sent with the token, e.g. 100SC")
+	daemonCmd.AddCommand(daemonWebhooksCmd)
+	daemonWebhooksCmd.AddCommand(daemonWebhooksAddCmd, daemonWebhooksDeliveriesCmd, daemonWebhooksRemoveCmd)
+	daemonWebhooksAddCmd.Flags().StringVar(&daemonWebhookEventTypes, "event-types", "", "Comma separated list of event types to deliver, e.g. alert.registered,upload.failed")
+	daemonWebhooksAddCmd.Flags().StringVar(&daemonWebhookMinSeverity, "min-severity", "", "The minimum severity of delivered alerts: warning, error or critical")
+	daemonWebhooksAddCmd.Flags().StringVar(&daemonWebhookModules, "modules", "", "Comma separated list of modules whose events are delivered, e.g. renter,wallet")
 	profileCmd.AddCommand(profileStartCmd, profileStopCmd)
+	proxyCmd.AddCommand(proxyClearCmd, proxySetCmd)
+	proxySetCmd.Flags().BoolVar(&daemonProxyOnly, "proxy-only", false, "Refuse connections which can't be routed through the proxy")
 	profileStartCmd.Flags().BoolVarP(&daemonCPUProfile, "cpu", "c", false, "Start the CPU profile")
 	profileStartCmd.Flags().BoolVarP(&daemonMemoryProfile, "memory", "m", false, "Start the Memory profile")
 	profileStartCmd.Flags().StringVar(&daemonProfileDirectory, "profileDir", "", "Specify the directory where the profile logs are to be saved")
@@ -425,20 +489,36 @@ func initCmds() *cobra.Command {
 	utilsVerifySeedCmd.Flags().StringVarP(&dictionaryLanguage, "language", "l", "english", "which dictionary you want to use")
 
 	root.AddCommand(walletCmd)
-	walletCmd.AddCommand(walletAddressCmd, walletAddressesCmd, walletBalanceCmd, walletBroadcastCmd, walletChangepasswordCmd,
-		walletInitCmd, walletInitSeedCmd, walletLoadCmd, walletLockCmd, walletSeedsCmd, walletSendCmd,
-		walletSignCmd, walletSweepCmd, walletTransactionsCmd, walletUnlockCmd)
+	walletCmd.AddCommand(walletAccountsCmd, walletAddressCmd, walletAddressesCmd, walletBalanceCmd, walletBroadcastCmd, walletBumpCmd, walletChangepasswordCmd,
+		walletExportCmd, walletInitCmd, walletInitSeedCmd, walletLoadCmd, walletLockCmd, walletSeedsCmd, walletSendCmd,
+		walletScheduledCmd, walletSignCmd, walletSweepCmd, walletTransactionsCmd, walletUnlockCmd, walletLabelsCmd, walletUnspentCmd)
+	walletAccountsCmd.AddCommand(walletAccountsCreateCmd, walletAccountsPinCmd)
+	walletAddressCmd.Flags().StringVar(&walletAccount, "account", "", "Generate the address from the given account")
+	walletLabelsCmd.AddCommand(walletLabelsSetCmd)
+	walletScheduledCmd.AddCommand(walletScheduledAddCmd, walletScheduledRemoveCmd)
+	walletScheduledAddCmd.Flags().StringVar(&walletAccount, "account", "", "Fund the payments from the given account")
+	walletScheduledAddCmd.Flags().Uint64Var(&walletScheduleCount, "count", 0, "Number of payments, 0 for no limit")
+	walletScheduledAddCmd.Flags().StringVar(&walletScheduleEvery, "every", "", "Interval between payments in blocks, hours, days or weeks, e.g. 30d. Omit to pay once")
+	walletScheduledAddCmd.Flags().Uint64Var(&walletScheduleStart, "start", 0, "Height of the first payment, defaults to the current height")
+	walletUnspentCmd.AddCommand(walletUnspentFreezeCmd, walletUnspentUnfreezeCmd)
 	walletInitCmd.Flags().BoolVarP(&initPassword, "password", "p", false, "Prompt for a custom password")
 	walletInitCmd.Flags().BoolVarP(&initForce, "force", "", false, "destroy the existing wallet and re-encrypt")
 	walletInitSeedCmd.Flags().BoolVarP(&initForce, "force", "", false, "destroy the existing wallet")
 	walletLoadCmd.AddCommand(walletLoad033xCmd, walletLoadSeedCmd, walletLoadUplogCmd)
 	walletSendCmd.AddCommand(walletSendUplocoinsCmd, walletSendUplofundsCmd)
 	walletSendUplocoinsCmd.Flags().BoolVarP(&walletTxnFeeIncluded, "fee-included", "", false, "Take the transaction fee out of the balance being submitted instead of the fee being additional")
+	walletSendUplocoinsCmd.Flags().StringVar(&walletAccount, "account", "", "Only spend the outputs of the given account")
+	walletSendUplocoinsCmd.Flags().StringVar(&walletTxnInputs, "inputs", "", "Comma separated list of the wallet outputs to spend, including frozen outputs")
+	walletSendUplocoinsCmd.Flags().Uint64Var(&walletTxnTimelock, "timelock", 0, "Lock the sent coins until the given height")
 	walletUnlockCmd.Flags().BoolVarP(&initPassword, "password", "p", false, "Display interactive password prompt even if UPLO_WALLET_PASSWORD is set")
 	walletBroadcastCmd.Flags().BoolVarP(&walletRawTxn, "raw", "", false, "Decode transaction as base64 instead of JSON")
 	walletSignCmd.Flags().BoolVarP(&walletRawTxn, "raw", "", false, "Encode signed transaction as base64 instead of JSON")
 	walletTransactionsCmd.Flags().Uint64Var(&walletStartHeight, "startheight", 0, " Height of the block where transaction history should begin.")
 	walletTransactionsCmd.Flags().Uint64Var(&walletEndHeight, "endheight", math.MaxUint64, " Height of the block where transaction history should end.")
+	walletExportCmd.Flags().StringVar(&walletExportFormat, "format", "csv", "Format of the export, either csv or json")
+	walletExportCmd.Flags().StringVar(&walletExportRates, "exchangerates", "", "Exchange rates prefixed with the unix timestamp they apply from, e.g. 1600000000:0.004 USD")
+	walletExportCmd.Flags().Uint64Var(&walletStartHeight, "startheight", 0, "Height of the block where the export should begin")
+	walletExportCmd.Flags().Uint64Var(&walletEndHeight, "endheight", math.MaxUint64, "Height of the block where the export should end")
 
 	return root
 }
@@ -451,6 +531,23 @@ func initClient(root *cobra.Command, verbose *bool, client *client.Client, uplod
 	root.PersistentFlags().StringVarP(uplodir, "uplo-directory", "d", "", "location of the uplo directory")
 	root.PersistentFlags().StringVarP(&client.UserAgent, "useragent", "", "Uplo-Agent", "the useragent used by uploc to connect to the daemon's API")
 	root.PersistentFlags().BoolVarP(alertSuppress, "alert-suppress", "s", false, "suppress uploc alerts")
+	root.PersistentFlags().BoolVarP(&apiTLS, "api-tls", "", false, "connect to the API over TLS")
+	root.PersistentFlags().StringVarP(&apiTLSCA, "api-tls-ca", "", "", "CA file used to verify the API's certificate, e.g. uplod's self-signed certificate; implies --api-tls")
+	root.PersistentFlags().StringVarP(&apiTLSCert, "api-tls-cert", "", "", "client certificate presented to the API for mutual TLS; implies --api-tls")
+	root.PersistentFlags().StringVarP(&apiTLSKey, "api-tls-key", "", "", "key of the client certificate")
+}
+
+// setAPITLSConfig sets the TLS config of the client if TLS was enabled.
+func setAPITLSConfig() {
+	if !apiTLS && apiTLSCA == "" && apiTLSCert == "" {
+		return
+	}
+	tlsConfig, err := client.NewTLSConfig(apiTLSCA, apiTLSCert, apiTLSKey)
+	if err != nil {
+		fmt.Println("Exiting: Error loading API TLS config:", err)
+		os.Exit(exitCodeUsage)
+	}
+	httpClient.TLSConfig = tlsConfig
 }
 
 // setAPIPasswordIfNotSet sets API password if it was not set
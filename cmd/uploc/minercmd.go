@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/uplo-tech/uplo/node/api"
@@ -29,6 +32,13 @@ var (
 		Long:  "Stop mining (this may take a few moments).",
 		Run:   wrap(minerstopcmd),
 	}
+
+	minerStratumCmd = &cobra.Command{
+		Use:   "stratum",
+		Short: "View the stratum server status",
+		Long:  "View the status of the miner's stratum server and the stats of its workers.",
+		Run:   wrap(minerstratumcmd),
+	}
 )
 
 // minerstartcmd is the handler for the command `uploc miner start`.
@@ -73,3 +83,37 @@ func minerstopcmd() {
 	}
 	fmt.Println("Stopped mining.")
 }
+
+// minerstratumcmd is the handler for the command `uploc miner stratum`.
+// Prints the status of the stratum server and its workers.
+func minerstratumcmd() {
+	status, err := httpClient.MinerStratumGet()
+	if err != nil {
+		die("Could not get stratum status:", err)
+	}
+	if status.Address == "" {
+		fmt.Println("Stratum server is not running.")
+		return
+	}
+	fmt.Printf(`Stratum server:
+Address:          %v
+Share Difficulty: %v
+Workers:          %v
+`, status.Address, status.ShareDifficulty, len(status.Workers))
+	if len(status.Workers) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tHashrate\tAccepted\tRejected\tBlocks\tLast Share")
+	for _, worker := range status.Workers {
+		lastShare := "-"
+		if !worker.LastShareTime.IsZero() {
+			lastShare = worker.LastShareTime.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%v\t%v KH/s\t%v\t%v\t%v\t%v\n", worker.Name, worker.Hashrate/1000, worker.AcceptedShares, worker.RejectedShares, worker.BlocksFound, lastShare)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
@@ -204,7 +204,10 @@ local path where the Uplo folder is mounted.`,
 		Use:   "upload [source] [path]",
 		Short: "Upload a file or folder",
 		Long: `Upload a file or folder to [path] on the Uplo network. The --data-pieces and --parity-pieces
-flags can be used to set a custom redundancy for the file.`,
+flags can be used to set a custom redundancy for the file. The --dedup flag enables deduplication,
+which reuses the data of identical chunks previously uploaded with --dedup instead of uploading them
+again. The --compression flag compresses the files before they are encrypted and uploaded. Compressed
+files are decompressed transparently when they are downloaded or streamed.`,
 		Run: wrap(renterfilesuploadcmd),
 	}
 
@@ -273,6 +276,37 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run:   wrap(rentertriggercontractrecoveryrescancmd),
 	}
 
+	renterTrashCmd = &cobra.Command{
+		Use:   "trash",
+		Short: "List the items within the trash",
+		Long: `List the files and directories within the trash. If the trash retention is set,
+deleted files and directories are moved to the trash where they are kept
+healthy until the retention expires.`,
+		Run: wrap(rentertrashcmd),
+	}
+
+	renterTrashEmptyCmd = &cobra.Command{
+		Use:   "empty",
+		Short: "Empty the trash",
+		Long:  "Permanently delete all the files and directories within the trash.",
+		Run:   wrap(rentertrashemptycmd),
+	}
+
+	renterTrashRestoreCmd = &cobra.Command{
+		Use:   "restore [id]",
+		Short: "Restore an item from the trash",
+		Long:  "Move a file or directory from the trash back to its original location.",
+		Run:   wrap(rentertrashrestorecmd),
+	}
+
+	renterTrashRetentionCmd = &cobra.Command{
+		Use:   "retention [duration]",
+		Short: "Set the trash retention",
+		Long: `Set how long deleted files and directories are kept in the trash before they
+are purged, e.g. 720h. A retention of 0 disables the trash.`,
+		Run: wrap(rentertrashretentioncmd),
+	}
+
 	renterUploadsCmd = &cobra.Command{
 		Use:   "uploads",
 		Short: "View the upload queue",
@@ -280,6 +314,49 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run:   wrap(renteruploadscmd),
 	}
 
+	renterVersionsCmd = &cobra.Command{
+		Use:   "versions [path]",
+		Short: "List the previous versions of a file",
+		Long: `List the previous versions of a file. Versions are kept when files are deleted or
+overwritten within a directory that has versioning enabled.`,
+		Run: wrap(renterversionscmd),
+	}
+
+	renterVersionsDisableCmd = &cobra.Command{
+		Use:   "disable [dir]",
+		Short: "Disable versioning for a directory",
+		Long: `Disable versioning for a directory. Existing versions are kept until they are
+pruned.`,
+		Run: wrap(renterversionsdisablecmd),
+	}
+
+	renterVersionsEnableCmd = &cobra.Command{
+		Use:   "enable [dir]",
+		Short: "Enable versioning for a directory",
+		Long: `Enable versioning for a directory. Files within the directory and its
+subdirectories are kept as versions when they are deleted or overwritten. The
+--max-versions and --max-age flags limit the number of versions kept per file
+and the age of the versions.`,
+		Run: wrap(renterversionsenablecmd),
+	}
+
+	renterVersionsPruneCmd = &cobra.Command{
+		Use:   "prune [path]",
+		Short: "Prune the versions of a file or directory",
+		Long: `Prune the versions of a file or of all the files within a directory. By default
+the versioning policies of the directories apply. The --max-versions and
+--max-age flags can be used to prune with custom limits instead.`,
+		Run: wrap(renterversionsprunecmd),
+	}
+
+	renterVersionsRestoreCmd = &cobra.Command{
+		Use:   "restore [path] [id]",
+		Short: "Restore a previous version of a file",
+		Long: `Restore a previous version of a file. If the file exists, it is kept as a
+version before it is replaced.`,
+		Run: wrap(renterversionsrestorecmd),
+	}
+
 	renterWorkersCmd = &cobra.Command{
 		Use:   "workers",
 		Short: "View the Renter's workers",
@@ -2027,7 +2104,7 @@ func renterfilesuploadcmd(source, path string) {
 			if err != nil {
 				die("Couldn't parse UploPath:", err)
 			}
-			err = httpClient.RenterUploadPost(abs(file), fUploPath, uint64(numDataPieces), uint64(numParityPieces))
+			err = renterUploadFile(abs(file), fUploPath, uint64(numDataPieces), uint64(numParityPieces))
 			if err != nil {
 				failed++
 				fmt.Printf("Could not upload file %s :%v\n", file, err)
@@ -2041,7 +2118,7 @@ func renterfilesuploadcmd(source, path string) {
 		if err != nil {
 			die("Couldn't parse UploPath:", err)
 		}
-		err = httpClient.RenterUploadPost(abs(source), uploPath, uint64(numDataPieces), uint64(numParityPieces))
+		err = renterUploadFile(abs(source), uploPath, uint64(numDataPieces), uint64(numParityPieces))
 		if err != nil {
 			die("Could not upload file:", err)
 		}
@@ -2049,6 +2126,19 @@ func renterfilesuploadcmd(source, path string) {
 	}
 }
 
+// renterUploadFile uploads a single file, deduplicating it if the --dedup flag
+// was set and compressing it if the --compression flag was set.
+func renterUploadFile(source string, uploPath modules.UploPath, dataPieces, parityPieces uint64) error {
+	var ct modules.CompressionType
+	if err := ct.FromString(renterUploadCompression); err != nil {
+		return errors.AddContext(err, "unable to parse compression type")
+	}
+	if renterUploadDedup || ct != modules.CompressionNone {
+		return httpClient.RenterUploadCustomPost(source, uploPath, dataPieces, parityPieces, false, renterUploadDedup, ct)
+	}
+	return httpClient.RenterUploadPost(source, uploPath, dataPieces, parityPieces)
+}
+
 // renterfilesuploadpausecmd is the handler for the command `uploc renter upload
 // pause`.  It pauses all renter uploads for the duration (in minutes)
 // passed in.
@@ -2589,3 +2679,164 @@ func renterworkersupdateregistrycmd() {
 	// Write Upload Info
 	writeWorkerReadUpdateRegistryInfo(false, w, rw)
 }
+
+// rentertrashcmd is the handler for the command `uploc renter trash`. It lists
+// the items within the trash.
+func rentertrashcmd() {
+	rt, err := httpClient.RenterTrashGet()
+	if err != nil {
+		die("Could not get trash:", err)
+	}
+	if len(rt.Items) == 0 {
+		fmt.Println("The trash is empty.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDeleted\tType\tPath")
+	for _, item := range rt.Items {
+		itemType := "file"
+		if item.IsDir {
+			itemType = "dir"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", item.ID, item.DeleteTime.Format(time.RFC3339), itemType, item.UploPath)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
+
+// rentertrashemptycmd is the handler for the command `uploc renter trash
+// empty`.
+func rentertrashemptycmd() {
+	err := httpClient.RenterTrashEmptyPost()
+	if err != nil {
+		die("Could not empty trash:", err)
+	}
+	fmt.Println("Emptied the trash")
+}
+
+// rentertrashrestorecmd is the handler for the command `uploc renter trash
+// restore [id]`.
+func rentertrashrestorecmd(id string) {
+	err := httpClient.RenterTrashRestorePost(id)
+	if err != nil {
+		die("Could not restore trashed item:", err)
+	}
+	fmt.Printf("Restored trashed item %v\n", id)
+}
+
+// rentertrashretentioncmd is the handler for the command `uploc renter trash
+// retention [duration]`.
+func rentertrashretentioncmd(duration string) {
+	retention, err := time.ParseDuration(duration)
+	if err != nil {
+		die("Couldn't parse retention:", err)
+	}
+	err = httpClient.RenterTrashRetentionPost(retention)
+	if err != nil {
+		die("Could not set trash retention:", err)
+	}
+	if retention == 0 {
+		fmt.Println("Disabled the trash")
+		return
+	}
+	fmt.Printf("Set the trash retention to %v\n", retention)
+}
+
+// renterversionscmd is the handler for the command `uploc renter versions
+// [path]`. It lists the previous versions of a file.
+func renterversionscmd(path string) {
+	uploPath, err := modules.NewUploPath(path)
+	if err != nil {
+		die("Couldn't parse UploPath:", err)
+	}
+	rfv, err := httpClient.RenterFileVersionsGet(uploPath)
+	if err != nil {
+		die("Could not get file versions:", err)
+	}
+	if len(rfv.Versions) == 0 {
+		fmt.Println("No versions.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCreated\tSize")
+	for _, v := range rfv.Versions {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", v.ID, v.CreateTime.Format(time.RFC3339), modules.FilesizeUnits(v.Filesize))
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
+
+// renterversionsdisablecmd is the handler for the command `uploc renter
+// versions disable [dir]`.
+func renterversionsdisablecmd(path string) {
+	uploPath, err := modules.NewUploPath(path)
+	if err != nil {
+		die("Couldn't parse UploPath:", err)
+	}
+	err = httpClient.RenterDirSetVersioningPost(uploPath, modules.VersioningPolicy{})
+	if err != nil {
+		die("Could not disable versioning:", err)
+	}
+	fmt.Printf("Disabled versioning for %v\n", path)
+}
+
+// renterversionsenablecmd is the handler for the command `uploc renter
+// versions enable [dir]`.
+func renterversionsenablecmd(path string) {
+	uploPath, err := modules.NewUploPath(path)
+	if err != nil {
+		die("Couldn't parse UploPath:", err)
+	}
+	policy := modules.VersioningPolicy{
+		Enabled:     true,
+		MaxAge:      parseVersionsMaxAge(),
+		MaxVersions: renterVersionsMaxVersions,
+	}
+	err = httpClient.RenterDirSetVersioningPost(uploPath, policy)
+	if err != nil {
+		die("Could not enable versioning:", err)
+	}
+	fmt.Printf("Enabled versioning for %v\n", path)
+}
+
+// renterversionsprunecmd is the handler for the command `uploc renter versions
+// prune [path]`.
+func renterversionsprunecmd(path string) {
+	uploPath, err := modules.NewUploPath(path)
+	if err != nil {
+		die("Couldn't parse UploPath:", err)
+	}
+	err = httpClient.RenterFileVersionsPrunePost(uploPath, renterVersionsMaxVersions, parseVersionsMaxAge())
+	if err != nil {
+		die("Could not prune versions:", err)
+	}
+	fmt.Printf("Pruned versions of %v\n", path)
+}
+
+// renterversionsrestorecmd is the handler for the command `uploc renter
+// versions restore [path] [id]`.
+func renterversionsrestorecmd(path, id string) {
+	uploPath, err := modules.NewUploPath(path)
+	if err != nil {
+		die("Couldn't parse UploPath:", err)
+	}
+	err = httpClient.RenterFileVersionRestorePost(uploPath, id)
+	if err != nil {
+		die("Could not restore version:", err)
+	}
+	fmt.Printf("Restored version %v of %v\n", id, path)
+}
+
+// parseVersionsMaxAge parses the --max-age flag of the versions commands.
+func parseVersionsMaxAge() time.Duration {
+	if renterVersionsMaxAge == "" {
+		return 0
+	}
+	maxAge, err := time.ParseDuration(renterVersionsMaxAge)
+	if err != nil {
+		die("Couldn't parse max age:", err)
+	}
+	return maxAge
+}
@@ -5,9 +5,11 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/node/api/client"
 	"github.com/uplo-tech/uplo/skykey"
 	"github.com/uplo-tech/errors"
@@ -85,6 +87,48 @@ var (
 		Long:  "List all skykeys. Use with --show-priv-keys to show full encoding with private key also.",
 		Run:   wrap(skykeylistcmd),
 	}
+
+	skykeyRotateCmd = &cobra.Command{
+		Use:   "rotate [new name]",
+		Short: "Rotate a skykey",
+		Long: `Create a new skykey with the given name and re-encrypt all skyfiles of the
+renter that were encrypted with the skykey given by --name or --id. The
+re-encrypted skyfiles replace the old ones and get new skylinks. The mapping
+from the old to the new skylinks is written to a file in the renter directory.
+Use --blocklist to add the old skylinks to the skynet blocklist.
+
+Large skyfiles are downloaded and uploaded again entirely, so this can take a
+long time. The rotation runs in the background of the daemon and uploc waits
+for it to finish. The mapping file is updated after every skyfile, so it can
+be checked if uploc is interrupted. The old skykey is kept until it's deleted
+with 'uploc skykey delete'.`,
+		Run: wrap(skykeyrotatecmd),
+	}
+
+	skykeyShareCmd = &cobra.Command{
+		Use:   "share [recipient public key]",
+		Short: "Share a skykey with another user",
+		Long: `Wrap the skykey given by --name or --id for the owner of the given public key.
+The recipient can get their public key with 'uploc skykey share pubkey' and
+import the printed share with 'uploc skykey share import'. Only the recipient
+can open the share, so it can be sent over an insecure channel.`,
+		Run: wrap(skykeysharecmd),
+	}
+
+	skykeySharePubkeyCmd = &cobra.Command{
+		Use:   "pubkey",
+		Short: "Print the public key for receiving skykeys",
+		Long:  "Print the public key other users need to share skykeys with this node.",
+		Run:   wrap(skykeysharepubkeycmd),
+	}
+
+	skykeyShareImportCmd = &cobra.Command{
+		Use:   "import [share]",
+		Short: "Import a skykey that was shared with this node",
+		Long: `Open a skykey that was shared with this node's public key and add it to the
+key manager. Use --rename-as to store it under a different name.`,
+		Run: wrap(skykeyshareimportcmd),
+	}
 )
 
 // skykeycmd displays the usage info for the command.
@@ -283,3 +327,119 @@ func skykeyListKeys(c client.Client, showPrivateKeys bool) (string, error) {
 	}
 	return b.String(), nil
 }
+
+// skykeyrotatecmd rotates a skykey and prints the new skylinks of the
+// re-encrypted skyfiles.
+func skykeyrotatecmd(newName string) {
+	err := validateSkyKeyNameAndIDUsage(skykeyName, skykeyID)
+	if err != nil {
+		die(errors.AddContext(err, "cannot validate skykey name and ID usage to rotate skykey"))
+	}
+	var rotation modules.SkykeyRotation
+	if skykeyName != "" {
+		rotation, err = httpClient.SkykeyRotateByNamePost(skykeyName, newName, skykeyRotateBlocklist)
+	} else {
+		var id skykey.SkykeyID
+		err = id.FromString(skykeyID)
+		if err != nil {
+			die(errors.AddContext(err, "could not decode skykey ID"))
+		}
+		rotation, err = httpClient.SkykeyRotateByIDPost(id, newName, skykeyRotateBlocklist)
+	}
+	if err != nil {
+		die("Failed to rotate skykey:", err)
+	}
+	fmt.Printf("Created skykey %v with ID %v\n", rotation.NewSkykeyName, rotation.NewSkykeyID)
+	fmt.Printf("Re-encrypting skyfiles, the skylink mapping is written to %v\n", rotation.MappingFile)
+
+	// Wait for the rotation to finish.
+	var newID skykey.SkykeyID
+	err = newID.FromString(rotation.NewSkykeyID)
+	if err != nil {
+		die(errors.AddContext(err, "could not decode new skykey ID"))
+	}
+	for !rotation.Finished {
+		time.Sleep(3 * time.Second)
+		rotation, err = httpClient.SkykeyRotationGet(newID)
+		if err != nil {
+			die("Failed to get state of skykey rotation:", err)
+		}
+		fmt.Printf("\r%v skylinks processed", len(rotation.Skyfiles))
+	}
+	fmt.Println()
+	if rotation.Error != "" {
+		die("Skykey rotation failed:", rotation.Error)
+	}
+
+	if len(rotation.Skyfiles) == 0 {
+		fmt.Println("No skyfiles were encrypted with the old skykey.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Uplopath\tOld Skylink\tNew Skylink")
+	var failed int
+	for _, sf := range rotation.Skyfiles {
+		newSkylink := sf.NewSkylink
+		if sf.Error != "" {
+			newSkylink = "failed: " + sf.Error
+			failed++
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", sf.UploPath, sf.OldSkylink, newSkylink)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+	fmt.Printf("\nRe-encrypted %v of %v skylinks. The skylink mapping was written to %v\n", len(rotation.Skyfiles)-failed, len(rotation.Skyfiles), rotation.MappingFile)
+}
+
+// skykeysharecmd wraps a skykey for the owner of the given public key and
+// prints the share.
+func skykeysharecmd(publicKey string) {
+	err := validateSkyKeyNameAndIDUsage(skykeyName, skykeyID)
+	if err != nil {
+		die(errors.AddContext(err, "cannot validate skykey name and ID usage to share skykey"))
+	}
+	recipient, err := skykey.SharePublicKeyFromString(publicKey)
+	if err != nil {
+		die(errors.AddContext(err, "could not decode public key"))
+	}
+	var ss skykey.SkykeyShare
+	if skykeyName != "" {
+		ss, err = httpClient.SkykeyShareByNamePost(skykeyName, recipient)
+	} else {
+		var id skykey.SkykeyID
+		err = id.FromString(skykeyID)
+		if err != nil {
+			die(errors.AddContext(err, "could not decode skykey ID"))
+		}
+		ss, err = httpClient.SkykeyShareByIDPost(id, recipient)
+	}
+	if err != nil {
+		die("Failed to share skykey:", err)
+	}
+	fmt.Println(ss.ToString())
+}
+
+// skykeysharepubkeycmd prints the public key other users can share skykeys
+// with this node for.
+func skykeysharepubkeycmd() {
+	xpk, err := httpClient.SkykeySharePublicKeyGet()
+	if err != nil {
+		die("Failed to get skykey sharing public key:", err)
+	}
+	fmt.Println(skykey.SharePublicKeyToString(xpk))
+}
+
+// skykeyshareimportcmd imports a skykey that was shared with this node.
+func skykeyshareimportcmd(shareString string) {
+	var ss skykey.SkykeyShare
+	err := ss.FromString(shareString)
+	if err != nil {
+		die(errors.AddContext(err, "could not decode skykey share"))
+	}
+	sk, err := httpClient.SkykeyShareImportPost(ss, skykeyRenameAs)
+	if err != nil {
+		die("Failed to import shared skykey:", err)
+	}
+	fmt.Printf("Successfully added shared skykey %v with ID %v\n", sk.Name, sk.ID().ToString())
+}
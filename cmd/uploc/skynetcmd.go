@@ -14,6 +14,7 @@ import (
 	"strings"
 	"sync"
 	"text/tabwriter"
+	"time"
 
 	"github.com/vbauerster/mpb/v5"
 
@@ -157,7 +158,9 @@ is passed, in which case all files under that directory will be uploaded
 individually and an individual skylink will be produced for each. All files that
 get uploaded will be pinned to this Uplo node, meaning that this node will pay
 for storage and repairs until the files are manually deleted. Use the --dry-run 
-flag to fetch the skylink without actually uploading the file.`,
+flag to fetch the skylink without actually uploading the file. Use the
+--hls-segment-duration flag to split an MPEG-TS video into segments of the given
+number of seconds that can be streamed using the index.m3u8 HLS playlist.`,
 		Run: skynetuploadcmd,
 	}
 )
@@ -687,6 +690,10 @@ func skynetuploadcmd(_ *cobra.Command, args []string) {
 		die("Unable to fetch source fileinfo:", err)
 	}
 
+	if fi.IsDir() && skynetUploadHLSSegmentDuration != 0 {
+		die("--hls-segment-duration can only be used when uploading a single file")
+	}
+
 	// create a new progress bar set:
 	pbs := mpb.New(mpb.WithWidth(40))
 
@@ -980,6 +987,8 @@ func skynetUploadFileFromReader(source io.Reader, filename string, uploPath modu
 
 		DryRun: skynetUploadDryRun,
 		Reader: source,
+
+		HLSSegmentDuration: time.Duration(skynetUploadHLSSegmentDuration) * time.Second,
 	}
 	sup = parseAndAddSkykey(sup)
 	skylink, _, err := httpClient.SkynetSkyfilePost(sup)
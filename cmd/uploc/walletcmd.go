@@ -4,11 +4,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/big"
 	"os"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -26,10 +28,37 @@ import (
 )
 
 var (
+	walletAccountsCmd = &cobra.Command{
+		Use:   "accounts",
+		Short: "View the accounts of the wallet",
+		Long:  "View the accounts of the wallet, their balances and the modules that are pinned to them.",
+		Run:   wrap(walletaccountscmd),
+	}
+
+	walletAccountsCreateCmd = &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create a new account",
+		Long: `Create a new sub-account of the wallet. The account derives its addresses
+from its own subseed of the primary seed, so its balance is kept separate from
+the other accounts. Accounts are numbered in the order they are created, so
+create them in the same order to recover their funds after restoring a wallet
+from its seed.`,
+		Run: wrap(walletaccountscreatecmd),
+	}
+
+	walletAccountsPinCmd = &cobra.Command{
+		Use:   "pin [module] [account]",
+		Short: "Pin a module to an account",
+		Long: `Pin the feemanager, host or renter to an account. The transactions of the
+module are funded by the account and its payouts are sent to addresses of the
+account. Pin a module to the default account to unpin it.`,
+		Run: wrap(walletaccountspincmd),
+	}
+
 	walletAddressCmd = &cobra.Command{
 		Use:   "address",
 		Short: "Get a new wallet address",
-		Long:  "Generate a new wallet address from the wallet's primary seed.",
+		Long:  "Generate a new wallet address from the wallet's primary seed, or from an account with --account.",
 		Run:   wrap(walletaddresscmd),
 	}
 
@@ -55,6 +84,16 @@ be valid. txn may be either JSON, base64, or a file containing either.`,
 		Run: wrap(walletbroadcastcmd),
 	}
 
+	walletBumpCmd = &cobra.Command{
+		Use:   "bump [txid]",
+		Short: "Increase the fees of an unconfirmed transaction",
+		Long: `Increase the fees of a transaction that is stuck in the transaction pool. If
+possible, the transaction is replaced with a version that pays a higher fee.
+Otherwise a child transaction spending the wallet's change output pays the
+higher fee for the transaction.`,
+		Run: wrap(walletbumpcmd),
+	}
+
 	walletChangepasswordCmd = &cobra.Command{
 		Use:   "change-password",
 		Short: "Change the wallet password",
@@ -81,6 +120,20 @@ The smallest unit of Uplocoins is the hasting. One Uplocoin is 10^24 hastings. O
 		Run: wrap(walletbalancecmd),
 	}
 
+	walletExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the transaction history for accounting",
+		Long: `Export every Uplocoin inflow and outflow of the wallet with its category,
+timestamp and the running balance of the wallet as CSV or JSON.
+
+If --exchangerates is set, the amounts are also valued in fiat. Every rate is
+prefixed with the unix timestamp from which on it applies, e.g.
+"1600000000:0.004 USD,1610000000:0.005 USD". Entries are valued with the most
+recent rate at their timestamp, and entries before the first rate are not
+valued.`,
+		Run: wrap(walletexportcmd),
+	}
+
 	walletInitCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Initialize and encrypt a new wallet",
@@ -154,7 +207,13 @@ By default the wallet encryption / unlock password is the same as the generated
 'amount' can be specified in units, e.g. 1.23KS. Run 'wallet --help' for a list of units.
 If no unit is supplied, hastings will be assumed.
 
-A dynamic transaction fee is applied depending on the size of the transaction and how busy the network is.`,
+A dynamic transaction fee is applied depending on the size of the transaction and how busy the network is.
+
+Use --inputs to only spend the given outputs of the wallet, e.g. frozen outputs.
+Use --account to only spend the outputs of an account of the wallet.
+Use --timelock to send the coins to a time-locked version of 'dest' which can't
+be spent before the given height. The unlock conditions of 'dest' must be known
+to the wallet.`,
 		Run: wrap(walletsendUplocoinscmd),
 	}
 
@@ -166,6 +225,34 @@ Run 'wallet send --help' to see a list of available units.`,
 		Run: wrap(walletsenduplofundscmd),
 	}
 
+	walletScheduledCmd = &cobra.Command{
+		Use:   "scheduled",
+		Short: "View the scheduled payments of the wallet",
+		Long:  "View the scheduled payments of the wallet and the payments that were sent for them.",
+		Run:   wrap(walletscheduledcmd),
+	}
+
+	walletScheduledAddCmd = &cobra.Command{
+		Use:   "add [name] [amount] [dest]",
+		Short: "Schedule a payment",
+		Long: `Schedule a payment that uplod sends automatically once the blockchain
+reaches the start height. With --every the payment is repeated at the given
+interval, either indefinitely or --count times. 'amount' can be specified in
+units, e.g. 1.23KS.
+
+Payments are only sent while the wallet is unlocked. Missed payments are sent as
+soon as possible.`,
+		Example: "uploc wallet scheduled add hosting-partner 500KS [dest] --every 30d --count 12",
+		Run:     wrap(walletscheduledaddcmd),
+	}
+
+	walletScheduledRemoveCmd = &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a scheduled payment",
+		Long:  "Stop and remove a scheduled payment. Payments that were already sent are not affected.",
+		Run:   wrap(walletscheduledremovecmd),
+	}
+
 	walletSignCmd = &cobra.Command{
 		Use:   "sign [txn] [tosign]",
 		Short: "Sign a transaction",
@@ -196,6 +283,45 @@ will be sent to your wallet.`,
 		Run:   wrap(wallettransactionscmd),
 	}
 
+	walletLabelsCmd = &cobra.Command{
+		Use:   "labels",
+		Short: "View the labels of the wallet",
+		Long:  "View the labels of the addresses, outputs and transactions of the wallet.",
+		Run:   wrap(walletlabelscmd),
+	}
+
+	walletLabelsSetCmd = &cobra.Command{
+		Use:   "set [type] [id] [label]",
+		Short: "Label an address, output or transaction",
+		Long: `Label an address, output or transaction of the wallet. 'type' is either
+address, output or transaction. An empty label removes the label.`,
+		Run: wrap(walletlabelssetcmd),
+	}
+
+	walletUnspentCmd = &cobra.Command{
+		Use:   "unspent",
+		Short: "View the unspent outputs of the wallet",
+		Long:  "View the unspent outputs of the wallet, including whether they are frozen and their labels.",
+		Run:   wrap(walletunspentcmd),
+	}
+
+	walletUnspentFreezeCmd = &cobra.Command{
+		Use:   "freeze [ids]",
+		Short: "Freeze outputs of the wallet",
+		Long: `Freeze a comma separated list of Uplocoin outputs of the wallet. Frozen outputs
+are never used to fund transactions automatically. They can only be spent by
+selecting them with 'wallet send Uplocoins --inputs', which allows keeping
+funds like host collateral separate from operating funds.`,
+		Run: wrap(walletunspentfreezecmd),
+	}
+
+	walletUnspentUnfreezeCmd = &cobra.Command{
+		Use:   "unfreeze [ids]",
+		Short: "Unfreeze outputs of the wallet",
+		Long:  "Unfreeze a comma separated list of frozen Uplocoin outputs of the wallet.",
+		Run:   wrap(walletunspentunfreezecmd),
+	}
+
 	walletUnlockCmd = &cobra.Command{
 		Use:   `unlock`,
 		Short: "Unlock the wallet",
@@ -236,10 +362,60 @@ func confirmPassword(prev string) error {
 	return nil
 }
 
+// walletaccountscmd lists the accounts of the wallet.
+func walletaccountscmd() {
+	wag, err := httpClient.WalletAccountsGet()
+	if err != nil {
+		die("Could not get accounts:", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tConfirmed Balance\tUnconfirmed Delta\tUplofunds\tModules")
+	for _, acc := range wag.Accounts {
+		var delta string
+		if acc.UnconfirmedOutgoingUplocoins.Cmp(acc.UnconfirmedIncomingUplocoins) > 0 {
+			delta = "-" + currencyUnits(acc.UnconfirmedOutgoingUplocoins.Sub(acc.UnconfirmedIncomingUplocoins))
+		} else {
+			delta = "+" + currencyUnits(acc.UnconfirmedIncomingUplocoins.Sub(acc.UnconfirmedOutgoingUplocoins))
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v UF\t%v\n", acc.Name, currencyUnits(acc.ConfirmedUplocoinBalance), delta, acc.UplofundBalance, strings.Join(acc.Modules, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
+
+// walletaccountscreatecmd creates a new account.
+func walletaccountscreatecmd(name string) {
+	err := httpClient.WalletAccountsPost(name)
+	if err != nil {
+		die("Could not create account:", err)
+	}
+	fmt.Printf("Created account %v\n", name)
+}
+
+// walletaccountspincmd pins a module to an account.
+func walletaccountspincmd(module, account string) {
+	err := httpClient.WalletAccountsPinPost(module, account)
+	if err != nil {
+		die("Could not pin module:", err)
+	}
+	if account == modules.DefaultWalletAccount {
+		fmt.Printf("Unpinned %v\n", module)
+		return
+	}
+	fmt.Printf("Pinned %v to account %v\n", module, account)
+}
+
 // walletaddresscmd fetches a new address from the wallet that will be able to
 // receive coins.
 func walletaddresscmd() {
-	addr, err := httpClient.WalletAddressGet()
+	var addr api.WalletAddressGET
+	var err error
+	if walletAccount != "" {
+		addr, err = httpClient.WalletAccountAddressGet(walletAccount)
+	} else {
+		addr, err = httpClient.WalletAddressGet()
+	}
 	if err != nil {
 		die("Could not generate new address:", err)
 	}
@@ -414,13 +590,128 @@ func walletsendUplocoinscmd(amount, dest string) {
 	if _, err := fmt.Sscan(dest, &hash); err != nil {
 		die("Failed to parse destination address", err)
 	}
-	_, err = httpClient.WalletUplocoinsPost(value, hash, walletTxnFeeIncluded)
+	if walletTxnTimelock > 0 {
+		if walletTxnFeeIncluded || walletAccount != "" || walletTxnInputs != "" {
+			die("Cannot use --fee-included, --account or --inputs together with --timelock")
+		}
+		wstp, err := httpClient.WalletUplocoinsTimelockedPost(value, hash, types.BlockHeight(walletTxnTimelock))
+		if err != nil {
+			die("Could not send Uplocoins:", err)
+		}
+		uc, err := json.MarshalIndent(wstp.UnlockConditions, "", "  ")
+		if err != nil {
+			die("Could not marshal unlock conditions:", err)
+		}
+		fmt.Printf("Sent %s hastings to %s, which is locked until height %v\n", hastings, wstp.Address, walletTxnTimelock)
+		fmt.Println("The coins can be spent with the unlock conditions:")
+		fmt.Println(string(uc))
+		return
+	}
+	if walletTxnInputs != "" {
+		if walletTxnFeeIncluded || walletAccount != "" {
+			die("Cannot use --fee-included or --account together with --inputs")
+		}
+		_, err = httpClient.WalletUplocoinsFromOutputsPost(value, hash, parseOutputIDs(walletTxnInputs))
+	} else if walletAccount != "" {
+		if walletTxnFeeIncluded {
+			die("Cannot use --fee-included together with --account")
+		}
+		_, err = httpClient.WalletUplocoinsFromAccountPost(walletAccount, value, hash)
+	} else {
+		_, err = httpClient.WalletUplocoinsPost(value, hash, walletTxnFeeIncluded)
+	}
 	if err != nil {
 		die("Could not send Uplocoins:", err)
 	}
 	fmt.Printf("Sent %s hastings to %s\n", hastings, dest)
 }
 
+// walletscheduledcmd lists the scheduled payments of the wallet.
+func walletscheduledcmd() {
+	wspg, err := httpClient.WalletScheduledPaymentsGet()
+	if err != nil {
+		die("Could not get scheduled payments:", err)
+	}
+	if len(wspg.ScheduledPayments) == 0 {
+		fmt.Println("No scheduled payments.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tAmount\tDestination\tAccount\tNext Height\tInterval\tPayments\tLast Error")
+	for _, sp := range wspg.ScheduledPayments {
+		next := "done"
+		if sp.NextHeight != 0 || len(sp.Payments) == 0 {
+			next = fmt.Sprint(sp.NextHeight)
+		}
+		interval := "once"
+		if sp.Interval > 0 {
+			interval = fmt.Sprintf("%v blocks", sp.Interval)
+		}
+		payments := fmt.Sprint(len(sp.Payments))
+		if sp.Interval > 0 && sp.Count > 0 {
+			payments = fmt.Sprintf("%v/%v", len(sp.Payments), sp.Count)
+		}
+		var stuck int
+		for _, r := range sp.Payments {
+			if r.Status == modules.ScheduledPaymentStuck {
+				stuck++
+			}
+		}
+		if stuck > 0 {
+			payments += fmt.Sprintf(" (%v stuck)", stuck)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", sp.Name, currencyUnits(sp.Amount), sp.Destination, sp.Account, next, interval, payments, sp.LastError)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
+
+// walletscheduledaddcmd schedules a payment.
+func walletscheduledaddcmd(name, amount, dest string) {
+	hastings, err := types.ParseCurrency(amount)
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	sp := modules.ScheduledPayment{
+		Name:    name,
+		Account: walletAccount,
+		Count:   walletScheduleCount,
+	}
+	if _, err := fmt.Sscan(hastings, &sp.Amount); err != nil {
+		die("Failed to parse amount", err)
+	}
+	if _, err := fmt.Sscan(dest, &sp.Destination); err != nil {
+		die("Failed to parse destination address", err)
+	}
+	if walletScheduleEvery != "" {
+		blocks, err := parsePeriod(walletScheduleEvery)
+		if err != nil {
+			die("Could not parse interval:", err)
+		}
+		if _, err := fmt.Sscan(blocks, &sp.Interval); err != nil {
+			die("Failed to parse interval", err)
+		}
+	}
+	sp.StartHeight = types.BlockHeight(walletScheduleStart)
+	if err := httpClient.WalletScheduledPaymentsPost(sp); err != nil {
+		die("Could not schedule payment:", err)
+	}
+	if sp.StartHeight == 0 {
+		fmt.Printf("Scheduled payment %v, the first payment is sent at the current height\n", name)
+		return
+	}
+	fmt.Printf("Scheduled payment %v, the first payment is sent at height %v\n", name, sp.StartHeight)
+}
+
+// walletscheduledremovecmd removes a scheduled payment.
+func walletscheduledremovecmd(name string) {
+	if err := httpClient.WalletScheduledPaymentsRemovePost(name); err != nil {
+		die("Could not remove scheduled payment:", err)
+	}
+	fmt.Printf("Removed scheduled payment %v\n", name)
+}
+
 // walletsenduplofundscmd sends uplofunds to a destination address.
 func walletsenduplofundscmd(amount, dest string) {
 	var value types.Currency
@@ -501,6 +792,23 @@ func walletbroadcastcmd(txnStr string) {
 	fmt.Println("Transaction has been broadcast successfully")
 }
 
+// walletbumpcmd increases the fees of an unconfirmed transaction.
+func walletbumpcmd(txidStr string) {
+	var txid types.TransactionID
+	err := txid.UnmarshalJSON([]byte("\"" + txidStr + "\""))
+	if err != nil {
+		die("Could not decode transaction id:", err)
+	}
+	wbp, err := httpClient.WalletBumpPost(txid)
+	if err != nil {
+		die("Could not bump transaction:", err)
+	}
+	fmt.Println("Submitted transactions to bump the fees of", txid)
+	for _, id := range wbp.TransactionIDs {
+		fmt.Println("\t", id)
+	}
+}
+
 // walletsweepcmd sweeps coins and funds from a seed.
 func walletsweepcmd() {
 	seed, err := passwordPrompt("Seed: ")
@@ -605,7 +913,7 @@ func wallettransactionscmd() {
 	if err != nil {
 		die("Could not fetch consensus information:", err)
 	}
-	fmt.Println("             [timestamp]    [height]                                                   [transaction id]    [net Uplocoins]   [net uplofunds]")
+	fmt.Println("             [timestamp]    [height]                                                   [transaction id]    [net Uplocoins]   [net uplofunds]   [label]")
 	txns := append(wtg.ConfirmedTransactions, wtg.UnconfirmedTransactions...)
 	sts, err := wallet.ComputeValuedTransactions(txns, cg.Height)
 	if err != nil {
@@ -646,11 +954,130 @@ func wallettransactionscmd() {
 		fmt.Printf("%67v%15.2f UC", txn.TransactionID, incomingUplocoinsFloat-outgoingUplocoinsFloat)
 		// For uplofunds, need to avoid having a negative types.Currency.
 		if incomingUplofunds.Cmp(outgoingUplofunds) >= 0 {
-			fmt.Printf("%14v UF\n", incomingUplofunds.Sub(outgoingUplofunds))
+			fmt.Printf("%14v UF", incomingUplofunds.Sub(outgoingUplofunds))
 		} else {
-			fmt.Printf("-%14v UF\n", outgoingUplofunds.Sub(incomingUplofunds))
+			fmt.Printf("-%14v UF", outgoingUplofunds.Sub(incomingUplofunds))
+		}
+		fmt.Printf("   %v\n", wtg.Labels[txn.TransactionID.String()])
+	}
+}
+
+// walletexportcmd prints the transaction history of the wallet for
+// accounting.
+func walletexportcmd() {
+	start, end := types.BlockHeight(walletStartHeight), types.BlockHeight(walletEndHeight)
+	switch walletExportFormat {
+	case "csv":
+		csv, err := httpClient.WalletExportCSVGet(start, end, walletExportRates)
+		if err != nil {
+			die("Could not export transaction history:", err)
+		}
+		fmt.Print(string(csv))
+	case "json":
+		weg, err := httpClient.WalletExportGet(start, end, walletExportRates)
+		if err != nil {
+			die("Could not export transaction history:", err)
+		}
+		b, err := json.MarshalIndent(weg.Entries, "", "  ")
+		if err != nil {
+			die("Could not marshal transaction history:", err)
+		}
+		fmt.Println(string(b))
+	default:
+		die("format must be either csv or json")
+	}
+}
+
+// walletlabelscmd lists the labels of the wallet.
+func walletlabelscmd() {
+	wlg, err := httpClient.WalletLabelsGet()
+	if err != nil {
+		die("Could not get labels:", err)
+	}
+	if len(wlg.Labels) == 0 {
+		fmt.Println("No labels.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Type\tID\tLabel")
+	for _, l := range wlg.Labels {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", l.Type, l.ID, l.Label)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
+
+// walletlabelssetcmd sets the label of an address, output or transaction.
+func walletlabelssetcmd(labelType, id, label string) {
+	err := httpClient.WalletLabelsPost(modules.WalletLabelType(labelType), id, label)
+	if err != nil {
+		die("Could not set label:", err)
+	}
+	if label == "" {
+		fmt.Printf("Removed label of %v %v\n", labelType, id)
+		return
+	}
+	fmt.Printf("Labeled %v %v as %q\n", labelType, id, label)
+}
+
+// walletunspentcmd lists the unspent outputs of the wallet.
+func walletunspentcmd() {
+	wug, err := httpClient.WalletUnspentGet()
+	if err != nil {
+		die("Could not get unspent outputs:", err)
+	}
+	if len(wug.Outputs) == 0 {
+		fmt.Println("No unspent outputs.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tType\tValue\tHeight\tAccount\tFrozen\tLabel")
+	for _, o := range wug.Outputs {
+		fundType, value := "UC", currencyUnits(o.Value)
+		if o.FundType == types.SpecifierUplofundOutput {
+			fundType, value = "UF", o.Value.String()
+		}
+		height := "unconfirmed"
+		if o.ConfirmationHeight != types.BlockHeight(math.MaxUint64) {
+			height = fmt.Sprint(o.ConfirmationHeight)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", o.ID, fundType, value, height, o.Account, yesNo(o.Frozen), o.Label)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer")
+	}
+}
+
+// walletunspentfreezecmd freezes outputs of the wallet.
+func walletunspentfreezecmd(ids string) {
+	err := httpClient.WalletUnspentFreezePost(parseOutputIDs(ids))
+	if err != nil {
+		die("Could not freeze outputs:", err)
+	}
+	fmt.Println("Froze outputs")
+}
+
+// walletunspentunfreezecmd unfreezes outputs of the wallet.
+func walletunspentunfreezecmd(ids string) {
+	err := httpClient.WalletUnspentUnfreezePost(parseOutputIDs(ids))
+	if err != nil {
+		die("Could not unfreeze outputs:", err)
+	}
+	fmt.Println("Unfroze outputs")
+}
+
+// parseOutputIDs parses a comma separated list of Uplocoin output IDs.
+func parseOutputIDs(s string) []types.UplocoinOutputID {
+	var ids []types.UplocoinOutputID
+	for _, idStr := range strings.Split(s, ",") {
+		var h crypto.Hash
+		if err := h.LoadString(strings.TrimSpace(idStr)); err != nil {
+			die("Could not parse output ID:", err)
 		}
+		ids = append(ids, types.UplocoinOutputID(h))
 	}
+	return ids
 }
 
 // walletunlockcmd unlocks a saved wallet
@@ -20,6 +20,14 @@ import (
 	"github.com/uplo-tech/uplo/profile"
 )
 
+const (
+	// apiTLSCertFilename and apiTLSKeyFilename are the names of the files in
+	// the uplod directory which contain the self-signed certificate of the API
+	// and its key.
+	apiTLSCertFilename = "apitls.crt"
+	apiTLSKeyFilename  = "apitls.key"
+)
+
 // passwordPrompt securely reads a password from stdin.
 func passwordPrompt(prompt string) (string, error) {
 	fmt.Print(prompt)
@@ -28,18 +36,29 @@ func passwordPrompt(prompt string) (string, error) {
 	return string(pw), err
 }
 
+// apiTLSSecured returns true if the API is served over TLS with either a
+// provided certificate or mutual TLS, and requires authentication. Such an API
+// may be bound to a non-localhost address without --disable-api-security.
+func apiTLSSecured(config Config) bool {
+	if !config.uplod.APITLS || !config.uplod.AuthenticateAPI {
+		return false
+	}
+	return config.uplod.APITLSCert != "" || config.uplod.APITLSClientCA != ""
+}
+
 // verifyAPISecurity checks that the security values are consistent with a
 // sane, secure system.
 func verifyAPISecurity(config Config) error {
-	// Make sure that only the loopback address is allowed unless the
-	// --disable-api-security flag has been used.
+	// Make sure that only the loopback address is allowed unless the API is
+	// secured by TLS and authentication or the --disable-api-security flag
+	// has been used.
 	if !config.uplod.AllowAPIBind {
 		addr := modules.NetAddress(config.uplod.APIaddr)
-		if !addr.IsLoopback() {
+		if !addr.IsLoopback() && !apiTLSSecured(config) {
 			if addr.Host() == "" {
-				return fmt.Errorf("a blank host will listen on all interfaces, did you mean localhost:%v?\nyou must pass --disable-api-security to bind uplod to a non-localhost address", addr.Port())
+				return fmt.Errorf("a blank host will listen on all interfaces, did you mean localhost:%v?\nyou must enable TLS with --api-tls-cert or --api-tls-client-ca, or pass --disable-api-security to bind uplod to a non-localhost address", addr.Port())
 			}
-			return errors.New("you must pass --disable-api-security to bind uplod to a non-localhost address")
+			return errors.New("you must enable TLS with --api-tls-cert or --api-tls-client-ca, or pass --disable-api-security to bind uplod to a non-localhost address")
 		}
 		return nil
 	}
@@ -52,6 +71,37 @@ func verifyAPISecurity(config Config) error {
 	return nil
 }
 
+// verifyAPITLS checks that the TLS flags of the API are consistent.
+func verifyAPITLS(config Config) error {
+	if (config.uplod.APITLSCert == "") != (config.uplod.APITLSKey == "") {
+		return errors.New("--api-tls-cert and --api-tls-key must be used together")
+	}
+	if config.uplod.APITLSClientCA != "" && !config.uplod.APITLS {
+		return errors.New("--api-tls-client-ca requires the API to be served over TLS")
+	}
+	return nil
+}
+
+// apiTLSConfig returns the TLS config of the API server, or nil if the API is
+// served over plain HTTP. Without a certificate, uplod uses a self-signed
+// certificate in its directory which is generated on the first run.
+func apiTLSConfig(config Config) *server.TLSConfig {
+	if !config.uplod.APITLS {
+		return nil
+	}
+	tlsCfg := &server.TLSConfig{
+		CertFile:     config.uplod.APITLSCert,
+		KeyFile:      config.uplod.APITLSKey,
+		ClientCAFile: config.uplod.APITLSClientCA,
+	}
+	if tlsCfg.CertFile == "" {
+		tlsCfg.CertFile = filepath.Join(config.uplod.uplodir, apiTLSCertFilename)
+		tlsCfg.KeyFile = filepath.Join(config.uplod.uplodir, apiTLSKeyFilename)
+		tlsCfg.GenerateCert = true
+	}
+	return tlsCfg
+}
+
 // processNetAddr adds a ':' to a bare integer, so that it is a proper port
 // number.
 func processNetAddr(addr string) string {
@@ -89,8 +139,12 @@ func processConfig(config Config) (Config, error) {
 	if config.uplod.Profile != "" {
 		config.uplod.Profile, err2 = profile.ProcessProfileFlags(config.uplod.Profile)
 	}
+	if config.uplod.APITLSCert != "" {
+		config.uplod.APITLS = true
+	}
 	err3 := verifyAPISecurity(config)
-	err := build.JoinErrors([]error{err1, err2, err3}, ", and ")
+	err4 := verifyAPITLS(config)
+	err := build.JoinErrors([]error{err1, err2, err3, err4}, ", and ")
 	if err != nil {
 		return Config{}, err
 	}
@@ -193,7 +247,7 @@ func startDaemon(config Config) (err error) {
 	nodeParams := parseModules(config)
 
 	// Start and run the server.
-	srv, err := server.New(config.uplod.APIaddr, config.uplod.RequiredUserAgent, config.APIPassword, nodeParams, loadStart)
+	srv, err := server.New(config.uplod.APIaddr, config.uplod.RequiredUserAgent, config.APIPassword, apiTLSConfig(config), nodeParams, loadStart)
 	if err != nil {
 		return err
 	}
@@ -225,6 +279,11 @@ func startDaemon(config Config) (err error) {
 	// Wait for server to complete shutdown.
 	srv.WaitClose()
 
+	// Close the relays of the proxy settings.
+	if err := modules.GlobalDialer.Close(); err != nil {
+		fmt.Println("Failed to close the proxy relays:", err)
+	}
+
 	return nil
 }
 
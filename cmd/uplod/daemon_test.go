@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -192,4 +193,87 @@ func TestVerifyAPISecurity(t *testing.T) {
 	if err != nil {
 		t.Error("public + securityOff with authentication was rejected:", err)
 	}
+
+	// Check that a public hostname is accepted when security is enabled but
+	// the API is served over TLS with a provided certificate or client CA and
+	// requires authentication.
+	var tlsPublic Config
+	tlsPublic.uplod.APIaddr = "uplo.tech:8480"
+	tlsPublic.uplod.APITLS = true
+	tlsPublic.uplod.APITLSCert = "cert.pem"
+	tlsPublic.uplod.APITLSKey = "key.pem"
+	tlsPublic.uplod.AuthenticateAPI = true
+	err = verifyAPISecurity(tlsPublic)
+	if err != nil {
+		t.Error("public + TLS with authentication was rejected:", err)
+	}
+	var mutualTLSPublic Config
+	mutualTLSPublic.uplod.APIaddr = "uplo.tech:8480"
+	mutualTLSPublic.uplod.APITLS = true
+	mutualTLSPublic.uplod.APITLSClientCA = "ca.pem"
+	mutualTLSPublic.uplod.AuthenticateAPI = true
+	err = verifyAPISecurity(mutualTLSPublic)
+	if err != nil {
+		t.Error("public + mutual TLS with authentication was rejected:", err)
+	}
+
+	// Check that TLS isn't enough without authentication, and that a
+	// generated self-signed certificate isn't enough either.
+	tlsPublic.uplod.AuthenticateAPI = false
+	err = verifyAPISecurity(tlsPublic)
+	if err == nil {
+		t.Error("public + TLS was accepted without authentication")
+	}
+	var selfSignedPublic Config
+	selfSignedPublic.uplod.APIaddr = "uplo.tech:8480"
+	selfSignedPublic.uplod.APITLS = true
+	selfSignedPublic.uplod.AuthenticateAPI = true
+	err = verifyAPISecurity(selfSignedPublic)
+	if err == nil {
+		t.Error("public + self-signed TLS was accepted")
+	}
+}
+
+// TestVerifyAPITLS checks that the TLS flags of the API are verified and that
+// a self-signed certificate is only used if no certificate was provided.
+func TestVerifyAPITLS(t *testing.T) {
+	// Plain HTTP doesn't need a TLS config.
+	var config Config
+	if err := verifyAPITLS(config); err != nil {
+		t.Fatal(err)
+	}
+	if apiTLSConfig(config) != nil {
+		t.Fatal("TLS config for plain HTTP")
+	}
+
+	// A client CA requires TLS.
+	config.uplod.APITLSClientCA = "ca.crt"
+	if err := verifyAPITLS(config); err == nil {
+		t.Fatal("client CA without TLS was accepted")
+	}
+
+	// Without a certificate, a self-signed certificate is generated.
+	config.uplod.APITLS = true
+	config.uplod.uplodir = "dir"
+	if err := verifyAPITLS(config); err != nil {
+		t.Fatal(err)
+	}
+	tlsCfg := apiTLSConfig(config)
+	if tlsCfg == nil || !tlsCfg.GenerateCert || tlsCfg.ClientCAFile != "ca.crt" || tlsCfg.CertFile != filepath.Join("dir", apiTLSCertFilename) {
+		t.Fatal("unexpected TLS config", tlsCfg)
+	}
+
+	// A certificate requires a key.
+	config.uplod.APITLSCert = "api.crt"
+	if err := verifyAPITLS(config); err == nil {
+		t.Fatal("certificate without key was accepted")
+	}
+	config.uplod.APITLSKey = "api.key"
+	if err := verifyAPITLS(config); err != nil {
+		t.Fatal(err)
+	}
+	tlsCfg = apiTLSConfig(config)
+	if tlsCfg == nil || tlsCfg.GenerateCert || tlsCfg.CertFile != "api.crt" || tlsCfg.KeyFile != "api.key" {
+		t.Fatal("unexpected TLS config", tlsCfg)
+	}
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
 )
 
 var (
@@ -37,14 +38,21 @@ type Config struct {
 		HostAddr      string
 		UploMuxTCPAddr string
 		UploMuxWSAddr  string
+		StratumAddr    string
 		AllowAPIBind  bool
 
 		Modules           string
+		StratumDifficulty uint64
 		NoBootstrap       bool
 		RequiredUserAgent string
 		AuthenticateAPI   bool
 		TempPassword      bool
 
+		APITLS         bool
+		APITLSCert     string
+		APITLSKey      string
+		APITLSClientCA string
+
 		Profile    string
 		ProfileDir string
 
@@ -185,10 +193,16 @@ func main() {
 	root.Flags().StringVarP(&globalConfig.uplod.RPCaddr, "rpc-addr", "", ":8481", "which port the gateway listens on")
 	root.Flags().StringVarP(&globalConfig.uplod.UploMuxTCPAddr, "uplomux-addr", "", ":8483", "which port the UploMux listens on")
 	root.Flags().StringVarP(&globalConfig.uplod.UploMuxWSAddr, "uplomux-addr-ws", "", ":8484", "which port the UploMux websocket listens on")
+	root.Flags().StringVarP(&globalConfig.uplod.StratumAddr, "stratum-addr", "", "", "which port the miner's stratum server listens on, disabled if empty. Requires UPLO_STRATUM_PASSWORD to be set")
+	root.Flags().Uint64VarP(&globalConfig.uplod.StratumDifficulty, "stratum-difficulty", "", modules.DefaultStratumDifficulty, "share difficulty of the miner's stratum server")
 	root.Flags().StringVarP(&globalConfig.uplod.Modules, "modules", "M", "cghrtwf", "enabled modules, see 'uplod modules' for more info")
 	root.Flags().BoolVarP(&globalConfig.uplod.AuthenticateAPI, "authenticate-api", "", true, "enable API password protection")
 	root.Flags().BoolVarP(&globalConfig.uplod.TempPassword, "temp-password", "", false, "enter a temporary API password during startup")
 	root.Flags().BoolVarP(&globalConfig.uplod.AllowAPIBind, "disable-api-security", "", false, "allow uplod to listen on a non-localhost address (DANGEROUS)")
+	root.Flags().BoolVarP(&globalConfig.uplod.APITLS, "api-tls", "", false, "serve the API over TLS, using a generated self-signed certificate if --api-tls-cert is not set")
+	root.Flags().StringVarP(&globalConfig.uplod.APITLSCert, "api-tls-cert", "", "", "location of the TLS certificate of the API, implies --api-tls")
+	root.Flags().StringVarP(&globalConfig.uplod.APITLSKey, "api-tls-key", "", "", "location of the key of the TLS certificate of the API")
+	root.Flags().StringVarP(&globalConfig.uplod.APITLSClientCA, "api-tls-client-ca", "", "", "require API clients to present a certificate signed by a CA in this file")
 
 	// If globalConfig.uplod.uplodir is not set, use the environment variable provided.
 	if globalConfig.uplod.uplodir == "" {
@@ -3,6 +3,7 @@ package main
 import (
 	"strings"
 
+	"github.com/uplo-tech/uplo/build"
 	"github.com/uplo-tech/uplo/node"
 )
 
@@ -44,6 +45,9 @@ func parseModules(config Config) node.NodeParams {
 	params.RPCAddress = config.uplod.RPCaddr
 	params.UploMuxTCPAddress = config.uplod.UploMuxTCPAddr
 	params.UploMuxWSAddress = config.uplod.UploMuxWSAddr
+	params.StratumAddress = config.uplod.StratumAddr
+	params.StratumDifficulty = config.uplod.StratumDifficulty
+	params.StratumPassword = build.StratumPassword()
 	params.Dir = config.uplod.uplodir
 	return params
 }
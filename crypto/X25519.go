@@ -29,3 +29,9 @@ func DeriveSharedSecret(xsk X25519SecretKey, xpk X25519PublicKey) (secret [32]by
 	curve25519.ScalarMult(&dst, (*[32]byte)(&xsk), (*[32]byte)(&xpk))
 	return blake2b.Sum256(dst[:])
 }
+
+// PublicKey returns the X25519PublicKey corresponding to the secret key.
+func (xsk X25519SecretKey) PublicKey() (xpk X25519PublicKey) {
+	curve25519.ScalarBaseMult((*[32]byte)(&xpk), (*[32]byte)(&xsk))
+	return
+}
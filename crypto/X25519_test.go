@@ -16,3 +16,12 @@ func TestDeriveSharedSecret(t *testing.T) {
 		t.Fatal("shared secret should not match")
 	}
 }
+
+// TestX25519PublicKey tests that the public key derived from a secret key
+// matches the one returned by GenerateX25519KeyPair.
+func TestX25519PublicKey(t *testing.T) {
+	xsk, xpk := GenerateX25519KeyPair()
+	if xsk.PublicKey() != xpk {
+		t.Fatal("derived public key does not match")
+	}
+}
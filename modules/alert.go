@@ -153,6 +153,7 @@ func (a AlertSeverity) String() string {
 type (
 	GenericAlerter struct {
 		alerts map[AlertID]Alert
+		events EventNotifier
 		module string
 		mu     sync.Mutex
 	}
@@ -189,21 +190,45 @@ func (a *GenericAlerter) Alerts() (crit, err, warn []Alert) {
 
 // RegisterAlert adds an alert to the alerter.
 func (a *GenericAlerter) RegisterAlert(id AlertID, msg, cause string, severity AlertSeverity) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.alerts[id] = Alert{
+	alert := Alert{
 		Cause:    cause,
 		Module:   a.module,
 		Msg:      msg,
 		Severity: severity,
 	}
+	a.mu.Lock()
+	old, exists := a.alerts[id]
+	a.alerts[id] = alert
+	a.mu.Unlock()
+
+	// Alerts are registered repeatedly, so subscribers are only notified about
+	// new or changed alerts.
+	if !exists || !old.Equals(alert) {
+		a.events.Notify(EventAlertRegistered, AlertEvent{ID: id, Alert: alert})
+	}
+}
+
+// SubscribeEvents adds a listener which is notified when an alert is
+// registered or unregistered.
+func (a *GenericAlerter) SubscribeEvents(l EventListener) {
+	a.events.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents removes a listener of the alerter.
+func (a *GenericAlerter) UnsubscribeEvents(l EventListener) {
+	a.events.UnsubscribeEvents(l)
 }
 
 // UnregisterAlert removes an alert from the alerter by id.
 func (a *GenericAlerter) UnregisterAlert(id AlertID) {
 	a.mu.Lock()
-	defer a.mu.Unlock()
+	alert, exists := a.alerts[id]
 	delete(a.alerts, id)
+	a.mu.Unlock()
+
+	if exists {
+		a.events.Notify(EventAlertUnregistered, AlertEvent{ID: id, Alert: alert})
+	}
 }
 
 // registerTestAlerts registers one alert of every severity for testing.
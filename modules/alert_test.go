@@ -96,3 +96,47 @@ func TestAlertsSorted(t *testing.T) {
 		}
 	}
 }
+
+// TestAlerterEvents checks that subscribers are notified about new, changed
+// and removed alerts but not about alerts that are registered again.
+func TestAlerterEvents(t *testing.T) {
+	a := NewAlerter("test")
+	l := &testEventListener{}
+	a.SubscribeEvents(l)
+	a.RegisterAlert("id", "msg", "cause", SeverityWarning)
+	a.RegisterAlert("id", "msg", "cause", SeverityWarning)
+	a.RegisterAlert("id", "msg", "cause", SeverityError)
+	a.UnregisterAlert("id")
+	a.UnregisterAlert("id")
+	expected := []EventType{EventAlertRegistered, EventAlertRegistered, EventAlertUnregistered}
+	if len(l.events) != len(expected) {
+		t.Fatal("unexpected events", l.events)
+	}
+	for i := range expected {
+		if l.events[i] != expected[i] {
+			t.Fatal("unexpected events", l.events)
+		}
+		if ae, ok := l.data[i].(AlertEvent); !ok || ae.ID != "id" {
+			t.Fatal("unexpected event data", l.data[i])
+		}
+	}
+
+	// Unsubscribed listeners aren't notified anymore.
+	a.UnsubscribeEvents(l)
+	a.RegisterAlert("id", "msg", "cause", SeverityWarning)
+	if len(l.events) != len(expected) {
+		t.Fatal("unsubscribed listener was notified", l.events)
+	}
+}
+
+// testEventListener is an EventListener which records all events.
+type testEventListener struct {
+	events []EventType
+	data   []interface{}
+}
+
+// ProcessEvent implements the EventListener interface.
+func (l *testEventListener) ProcessEvent(typ EventType, data interface{}) {
+	l.events = append(l.events, typ)
+	l.data = append(l.data, data)
+}
@@ -0,0 +1,394 @@
+package modules
+
+// compression.go contains the optional compression stage of renter uploads.
+// The data of a compressed file is split into frames of a fixed uncompressed
+// size which are compressed independently and stored back to back. The offset
+// of every compressed frame is recorded in a frame index, which allows for
+// decompressing arbitrary ranges of a file without fetching all of the data
+// that comes before it.
+//
+// NOTE: Repairs from the local copy of a file recompress the frames they need
+// and rely on the compression being deterministic. If the output of the
+// compressor ever changes, the integrity check of the repair will fail and the
+// renter falls back to repairing from the network.
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+)
+
+// CompressionType is the type of compression applied to a file before it is
+// encrypted and uploaded.
+type CompressionType uint8
+
+const (
+	// CompressionNone indicates that a file is not compressed.
+	CompressionNone CompressionType = iota
+
+	// CompressionDeflate indicates that the frames of a file are compressed
+	// using DEFLATE.
+	CompressionDeflate
+)
+
+var (
+	// DefaultCompressionFrameSize is the default uncompressed size of a
+	// compression frame.
+	DefaultCompressionFrameSize = build.Select(build.Var{
+		Dev:      uint64(1 << 20), // 1 MiB
+		Standard: uint64(4 << 20), // 4 MiB
+		Testing:  uint64(1 << 12), // 4 KiB
+	}).(uint64)
+
+	// ErrInvalidCompressionType is returned if a compression type is unknown.
+	ErrInvalidCompressionType = errors.New("invalid compression type")
+
+	// ErrCompressionMismatch is returned if recompressing a frame doesn't
+	// produce the frame that was recorded in the frame index.
+	ErrCompressionMismatch = errors.New("recompressed frame doesn't match frame index")
+)
+
+type (
+	// CompressionFrame is the location of a compressed frame within the
+	// stored data of a file.
+	CompressionFrame struct {
+		Offset uint64 `json:"offset"`
+		Length uint64 `json:"length"`
+	}
+
+	// CompressionInfo describes the compression of a file. The frame with
+	// index i contains the uncompressed data starting at i*FrameSize.
+	CompressionInfo struct {
+		Type             CompressionType    `json:"type"`
+		FrameSize        uint64             `json:"framesize"`
+		Frames           []CompressionFrame `json:"frames"`
+		UncompressedSize uint64             `json:"uncompressedsize"`
+	}
+
+	// decompressionReadSeeker decompresses the compressed data of a file
+	// while allowing for seeking within the uncompressed data.
+	decompressionReadSeeker struct {
+		staticInfo   CompressionInfo
+		staticSource io.ReadSeeker
+
+		frame      []byte
+		frameIndex int
+		offset     uint64
+	}
+
+	// decompressionWriter receives sequential compressed frames, decompresses
+	// them and writes a range of the uncompressed data to the underlying
+	// writer.
+	decompressionWriter struct {
+		staticInfo   CompressionInfo
+		staticWriter io.Writer
+
+		buf        []byte
+		frameIndex int
+		skip       uint64
+		remaining  uint64
+	}
+)
+
+// String returns the string representation of a CompressionType.
+func (ct CompressionType) String() string {
+	switch ct {
+	case CompressionNone:
+		return "none"
+	case CompressionDeflate:
+		return "deflate"
+	default:
+		return ""
+	}
+}
+
+// FromString parses a CompressionType from its string representation.
+func (ct *CompressionType) FromString(s string) error {
+	switch s {
+	case "", "none":
+		*ct = CompressionNone
+	case "deflate":
+		*ct = CompressionDeflate
+	default:
+		return ErrInvalidCompressionType
+	}
+	return nil
+}
+
+// CompressFrame compresses a single frame of data.
+func CompressFrame(ct CompressionType, data []byte) ([]byte, error) {
+	switch ct {
+	case CompressionNone:
+		return append([]byte{}, data...), nil
+	case CompressionDeflate:
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to create compressor")
+		}
+		_, err = w.Write(data)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to compress frame")
+		}
+		err = w.Close()
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to compress frame")
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrInvalidCompressionType
+	}
+}
+
+// DecompressFrame decompresses a single frame of data. The uncompressed frame
+// is expected to be no larger than maxSize.
+func DecompressFrame(ct CompressionType, data []byte, maxSize uint64) ([]byte, error) {
+	switch ct {
+	case CompressionNone:
+		return append([]byte{}, data...), nil
+	case CompressionDeflate:
+		r := flate.NewReader(bytes.NewReader(data))
+		frame, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to decompress frame")
+		}
+		if uint64(len(frame)) > maxSize {
+			return nil, fmt.Errorf("decompressed frame exceeds max size of %v bytes", maxSize)
+		}
+		return frame, errors.AddContext(r.Close(), "unable to close decompressor")
+	default:
+		return nil, ErrInvalidCompressionType
+	}
+}
+
+// NewCompressionInfo compresses the data from r frame by frame and returns the
+// resulting frame index. If w is not nil, the compressed data is written to it,
+// otherwise it is discarded.
+func NewCompressionInfo(r io.Reader, w io.Writer, ct CompressionType, frameSize uint64) (CompressionInfo, error) {
+	if ct == CompressionNone {
+		return CompressionInfo{}, errors.New("can't create frame index without compression")
+	}
+	if frameSize == 0 {
+		return CompressionInfo{}, errors.New("frame size must be greater than 0")
+	}
+	ci := CompressionInfo{
+		Type:      ct,
+		FrameSize: frameSize,
+	}
+	frame := make([]byte, frameSize)
+	var offset uint64
+	for {
+		n, err := io.ReadFull(r, frame)
+		if errors.Contains(err, io.EOF) {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return CompressionInfo{}, errors.AddContext(err, "unable to read frame")
+		}
+		compressed, err := CompressFrame(ct, frame[:n])
+		if err != nil {
+			return CompressionInfo{}, err
+		}
+		if w != nil {
+			if _, err := w.Write(compressed); err != nil {
+				return CompressionInfo{}, errors.AddContext(err, "unable to write compressed frame")
+			}
+		}
+		ci.Frames = append(ci.Frames, CompressionFrame{
+			Offset: offset,
+			Length: uint64(len(compressed)),
+		})
+		offset += uint64(len(compressed))
+		ci.UncompressedSize += uint64(n)
+		if uint64(n) < frameSize {
+			break
+		}
+	}
+	return ci, nil
+}
+
+// Compressed returns whether the CompressionInfo describes a compressed file.
+func (ci CompressionInfo) Compressed() bool {
+	return ci.Type != CompressionNone
+}
+
+// CompressedSize returns the size of the stored, compressed data.
+func (ci CompressionInfo) CompressedSize() uint64 {
+	if len(ci.Frames) == 0 {
+		return 0
+	}
+	last := ci.Frames[len(ci.Frames)-1]
+	return last.Offset + last.Length
+}
+
+// CompressedRange returns the range of the compressed data that contains the
+// uncompressed range [offset;offset+length).
+func (ci CompressionInfo) CompressedRange(offset, length uint64) (uint64, uint64, error) {
+	if length == 0 || offset+length > ci.UncompressedSize {
+		return 0, 0, fmt.Errorf("range [%v;%v) is out of bounds (%v)", offset, offset+length, ci.UncompressedSize)
+	}
+	first := ci.Frames[offset/ci.FrameSize]
+	last := ci.Frames[(offset+length-1)/ci.FrameSize]
+	return first.Offset, last.Offset + last.Length - first.Offset, nil
+}
+
+// frameSize returns the uncompressed size of the frame with the given index.
+func (ci CompressionInfo) frameSize(i int) uint64 {
+	start := uint64(i) * ci.FrameSize
+	if start+ci.FrameSize > ci.UncompressedSize {
+		return ci.UncompressedSize - start
+	}
+	return ci.FrameSize
+}
+
+// ReadCompressedRange recompresses the frames of the uncompressed data in ra
+// that are needed to return the compressed data in the range
+// [offset;offset+length). This allows for repairing a compressed file from its
+// uncompressed local copy. The returned data is shorter than length if the
+// range extends beyond the end of the compressed data.
+func (ci CompressionInfo) ReadCompressedRange(ra io.ReaderAt, offset, length uint64) ([]byte, error) {
+	data := make([]byte, 0, length)
+	end := offset + length
+	// Find the first frame which ends after the offset.
+	i := sort.Search(len(ci.Frames), func(i int) bool {
+		return ci.Frames[i].Offset+ci.Frames[i].Length > offset
+	})
+	for ; i < len(ci.Frames) && ci.Frames[i].Offset < end; i++ {
+		frame := make([]byte, ci.frameSize(i))
+		_, err := ra.ReadAt(frame, int64(uint64(i)*ci.FrameSize))
+		if err != nil && !errors.Contains(err, io.EOF) {
+			return nil, errors.AddContext(err, "unable to read frame")
+		}
+		compressed, err := CompressFrame(ci.Type, frame)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(compressed)) != ci.Frames[i].Length {
+			return nil, ErrCompressionMismatch
+		}
+		// Cut off the parts of the frame outside of the range.
+		frameStart, frameEnd := ci.Frames[i].Offset, ci.Frames[i].Offset+ci.Frames[i].Length
+		if frameStart < offset {
+			compressed = compressed[offset-frameStart:]
+		}
+		if frameEnd > end {
+			compressed = compressed[:uint64(len(compressed))-(frameEnd-end)]
+		}
+		data = append(data, compressed...)
+	}
+	return data, nil
+}
+
+// NewDecompressionReadSeeker returns a io.ReadSeeker for the uncompressed data
+// of a file given a io.ReadSeeker for its compressed data.
+func NewDecompressionReadSeeker(rs io.ReadSeeker, ci CompressionInfo) io.ReadSeeker {
+	return &decompressionReadSeeker{
+		staticInfo:   ci,
+		staticSource: rs,
+		frameIndex:   -1,
+	}
+}
+
+// Read implements io.Reader.
+func (d *decompressionReadSeeker) Read(b []byte) (int, error) {
+	if d.offset >= d.staticInfo.UncompressedSize {
+		return 0, io.EOF
+	}
+	// Fetch the frame containing the offset if necessary.
+	index := int(d.offset / d.staticInfo.FrameSize)
+	if index != d.frameIndex {
+		f := d.staticInfo.Frames[index]
+		_, err := d.staticSource.Seek(int64(f.Offset), io.SeekStart)
+		if err != nil {
+			return 0, errors.AddContext(err, "unable to seek to frame")
+		}
+		compressed := make([]byte, f.Length)
+		_, err = io.ReadFull(d.staticSource, compressed)
+		if err != nil {
+			return 0, errors.AddContext(err, "unable to read frame")
+		}
+		d.frame, err = DecompressFrame(d.staticInfo.Type, compressed, d.staticInfo.FrameSize)
+		if err != nil {
+			return 0, err
+		}
+		if uint64(len(d.frame)) != d.staticInfo.frameSize(index) {
+			return 0, fmt.Errorf("frame %v has wrong size %v", index, len(d.frame))
+		}
+		d.frameIndex = index
+	}
+	n := copy(b, d.frame[d.offset-uint64(index)*d.staticInfo.FrameSize:])
+	d.offset += uint64(n)
+	return n, nil
+}
+
+// Seek implements io.Seeker.
+func (d *decompressionReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = 0
+	case io.SeekCurrent:
+		newOffset = int64(d.offset)
+	case io.SeekEnd:
+		newOffset = int64(d.staticInfo.UncompressedSize)
+	}
+	newOffset += offset
+	if newOffset < 0 {
+		return int64(d.offset), errors.New("cannot seek to negative offset")
+	}
+	d.offset = uint64(newOffset)
+	return newOffset, nil
+}
+
+// NewDecompressionWriter returns a writer which expects the compressed data
+// returned by CompressedRange for the uncompressed range [offset;
+// offset+length) and writes that uncompressed range to w.
+func NewDecompressionWriter(w io.Writer, ci CompressionInfo, offset, length uint64) io.Writer {
+	return &decompressionWriter{
+		staticInfo:   ci,
+		staticWriter: w,
+		frameIndex:   int(offset / ci.FrameSize),
+		skip:         offset % ci.FrameSize,
+		remaining:    length,
+	}
+}
+
+// Write implements io.Writer.
+func (d *decompressionWriter) Write(b []byte) (int, error) {
+	d.buf = append(d.buf, b...)
+	for d.remaining > 0 && d.frameIndex < len(d.staticInfo.Frames) {
+		frameLen := d.staticInfo.Frames[d.frameIndex].Length
+		if uint64(len(d.buf)) < frameLen {
+			break
+		}
+		frame, err := DecompressFrame(d.staticInfo.Type, d.buf[:frameLen], d.staticInfo.FrameSize)
+		if err != nil {
+			return 0, err
+		}
+		d.buf = d.buf[frameLen:]
+		d.frameIndex++
+
+		// Write the requested part of the frame.
+		if d.skip > uint64(len(frame)) {
+			return 0, fmt.Errorf("frame %v is too short", d.frameIndex-1)
+		}
+		frame = frame[d.skip:]
+		d.skip = 0
+		if uint64(len(frame)) > d.remaining {
+			frame = frame[:d.remaining]
+		}
+		_, err = d.staticWriter.Write(frame)
+		if err != nil {
+			return 0, err
+		}
+		d.remaining -= uint64(len(frame))
+	}
+	return len(b), nil
+}
@@ -0,0 +1,240 @@
+package modules
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/fastrand"
+)
+
+// compressibleData returns data of the given size which compresses well but
+// not uniformly.
+func compressibleData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i / 64)
+	}
+	// Sprinkle in some random bytes to vary the size of the frames.
+	for i := 0; i < size/100; i++ {
+		data[fastrand.Intn(size)] = byte(fastrand.Intn(256))
+	}
+	return data
+}
+
+// compressData compresses data frame by frame and returns the compressed data
+// together with its frame index.
+func compressData(t *testing.T, data []byte, frameSize uint64) ([]byte, CompressionInfo) {
+	var buf bytes.Buffer
+	ci, err := NewCompressionInfo(bytes.NewReader(data), &buf, CompressionDeflate, frameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed []byte
+	for i := range ci.Frames {
+		start := uint64(i) * frameSize
+		end := start + frameSize
+		if end > uint64(len(data)) {
+			end = uint64(len(data))
+		}
+		frame, err := CompressFrame(ci.Type, data[start:end])
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressed = append(compressed, frame...)
+	}
+	if uint64(len(compressed)) != ci.CompressedSize() {
+		t.Fatal("wrong compressed size", len(compressed), ci.CompressedSize())
+	}
+	if !bytes.Equal(buf.Bytes(), compressed) {
+		t.Fatal("written compressed data doesn't match the frames")
+	}
+	return compressed, ci
+}
+
+// TestCompressionType tests the string conversion of the compression types.
+func TestCompressionType(t *testing.T) {
+	for _, ct := range []CompressionType{CompressionNone, CompressionDeflate} {
+		var parsed CompressionType
+		if err := parsed.FromString(ct.String()); err != nil {
+			t.Fatal(err)
+		}
+		if parsed != ct {
+			t.Fatal("wrong type", parsed, ct)
+		}
+	}
+	var ct CompressionType
+	if err := ct.FromString("zip"); !errors.Contains(err, ErrInvalidCompressionType) {
+		t.Fatal("expected ErrInvalidCompressionType", err)
+	}
+}
+
+// TestCompressionInfo tests creating a frame index and mapping ranges of the
+// uncompressed data to the compressed data.
+func TestCompressionInfo(t *testing.T) {
+	frameSize := uint64(1 << 10)
+	data := compressibleData(int(10*frameSize + 100))
+	compressed, ci := compressData(t, data, frameSize)
+	if ci.UncompressedSize != uint64(len(data)) {
+		t.Fatal("wrong uncompressed size", ci.UncompressedSize)
+	}
+	if len(ci.Frames) != 11 {
+		t.Fatal("wrong number of frames", len(ci.Frames))
+	}
+	if ci.CompressedSize() >= ci.UncompressedSize {
+		t.Fatal("data wasn't compressed")
+	}
+
+	// Every frame should decompress to the corresponding uncompressed data.
+	for i, f := range ci.Frames {
+		frame, err := DecompressFrame(ci.Type, compressed[f.Offset:f.Offset+f.Length], frameSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(frame, data[uint64(i)*frameSize:uint64(i)*frameSize+ci.frameSize(i)]) {
+			t.Fatal("wrong frame", i)
+		}
+	}
+
+	// A range within a single frame maps to that frame.
+	off, length, err := ci.CompressedRange(frameSize+10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != ci.Frames[1].Offset || length != ci.Frames[1].Length {
+		t.Fatal("wrong range", off, length)
+	}
+	// A range spanning frames maps to all of them.
+	off, length, err = ci.CompressedRange(frameSize-1, frameSize+2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != ci.Frames[0].Offset || off+length != ci.Frames[2].Offset+ci.Frames[2].Length {
+		t.Fatal("wrong range", off, length)
+	}
+	// Out of bounds ranges are rejected.
+	if _, _, err = ci.CompressedRange(ci.UncompressedSize-1, 2); err == nil {
+		t.Fatal("expected error for out of bounds range")
+	}
+
+	// Empty input results in an empty index.
+	ci, err = NewCompressionInfo(bytes.NewReader(nil), nil, CompressionDeflate, frameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ci.Frames) != 0 || ci.CompressedSize() != 0 {
+		t.Fatal("expected empty index")
+	}
+}
+
+// TestReadCompressedRange tests recreating ranges of the compressed data from
+// the uncompressed data.
+func TestReadCompressedRange(t *testing.T) {
+	frameSize := uint64(1 << 10)
+	data := compressibleData(int(10*frameSize + 100))
+	compressed, ci := compressData(t, data, frameSize)
+	ra := bytes.NewReader(data)
+
+	// Read the compressed data in chunks that don't line up with the frames.
+	chunkSize := uint64(333)
+	for off := uint64(0); off < uint64(len(compressed)); off += chunkSize {
+		b, err := ci.ReadCompressedRange(ra, off, chunkSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		end := off + chunkSize
+		if end > uint64(len(compressed)) {
+			end = uint64(len(compressed))
+		}
+		if !bytes.Equal(b, compressed[off:end]) {
+			t.Fatal("wrong data at offset", off)
+		}
+	}
+
+	// Changing the uncompressed data should be detected.
+	modified := append([]byte{}, data...)
+	fastrand.Read(modified[:frameSize])
+	_, err := ci.ReadCompressedRange(bytes.NewReader(modified), 0, chunkSize)
+	if !errors.Contains(err, ErrCompressionMismatch) {
+		t.Fatal("expected ErrCompressionMismatch", err)
+	}
+}
+
+// TestDecompressionReadSeeker tests reading and seeking the uncompressed data
+// of a file.
+func TestDecompressionReadSeeker(t *testing.T) {
+	frameSize := uint64(1 << 10)
+	data := compressibleData(int(10*frameSize + 100))
+	compressed, ci := compressData(t, data, frameSize)
+	rs := NewDecompressionReadSeeker(bytes.NewReader(compressed), ci)
+
+	// Read the whole file.
+	b, err := ioutil.ReadAll(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Fatal("wrong data")
+	}
+
+	// Seeking to the end should return the uncompressed size.
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(end) != ci.UncompressedSize {
+		t.Fatal("wrong size", end)
+	}
+
+	// Read random ranges.
+	for i := 0; i < 20; i++ {
+		off := fastrand.Intn(len(data))
+		length := fastrand.Intn(len(data)-off) + 1
+		if _, err := rs.Seek(int64(off), io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, length)
+		if _, err := io.ReadFull(rs, b); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, data[off:off+length]) {
+			t.Fatal("wrong data for range", off, length)
+		}
+	}
+}
+
+// TestDecompressionWriter tests writing ranges of the uncompressed data using
+// the compressed data returned by CompressedRange.
+func TestDecompressionWriter(t *testing.T) {
+	frameSize := uint64(1 << 10)
+	data := compressibleData(int(10*frameSize + 100))
+	compressed, ci := compressData(t, data, frameSize)
+
+	for i := 0; i < 20; i++ {
+		off := uint64(fastrand.Intn(len(data)))
+		length := uint64(fastrand.Intn(len(data)-int(off))) + 1
+		cOff, cLen, err := ci.CompressedRange(off, length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Write the compressed data in small pieces.
+		var buf bytes.Buffer
+		w := NewDecompressionWriter(&buf, ci, off, length)
+		src := compressed[cOff : cOff+cLen]
+		for len(src) > 0 {
+			n := fastrand.Intn(100) + 1
+			if n > len(src) {
+				n = len(src)
+			}
+			if _, err := w.Write(src[:n]); err != nil {
+				t.Fatal(err)
+			}
+			src = src[n:]
+		}
+		if !bytes.Equal(buf.Bytes(), data[off:off+length]) {
+			t.Fatal("wrong data for range", off, length)
+		}
+	}
+}
@@ -222,12 +222,22 @@ type (
 		// blockchain.
 		CurrentBlock() types.Block
 
+		// ExportSnapshot writes a snapshot of the consensus state at the given
+		// height to the writer and returns the hash of the snapshot.
+		ExportSnapshot(io.Writer, types.BlockHeight) (crypto.Hash, error)
+
 		// Height returns the current height of consensus.
 		Height() types.BlockHeight
 
 		// Synced returns true if the consensus set is synced with the network.
 		Synced() bool
 
+		// ImportSnapshot bootstraps the consensus set from a snapshot. The hash
+		// of the snapshot needs to match the provided checkpoint or, if it is
+		// empty, the checkpoint compiled into the binary for the height of the
+		// snapshot.
+		ImportSnapshot(io.Reader, crypto.Hash) error
+
 		// InCurrentPath returns true if the block id presented is found in the
 		// current path, false otherwise.
 		InCurrentPath(types.BlockID) bool
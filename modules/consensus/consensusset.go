@@ -172,11 +172,15 @@ func consensusSetAsyncStartup(cs *ConsensusSet, bootstrap bool) error {
 	cs.gateway.RegisterRPC("SendBlocks", cs.rpcSendBlocks)
 	cs.gateway.RegisterRPC("RelayHeader", cs.threadedRPCRelayHeader)
 	cs.gateway.RegisterRPC("SendBlk", cs.rpcSendBlk)
+	cs.gateway.RegisterRPC("SendHeaders", cs.rpcSendHeaders)
+	cs.gateway.RegisterRPC("SendBlocksByID", cs.rpcSendBlocksByID)
 	cs.gateway.RegisterConnectCall("SendBlocks", cs.threadedReceiveBlocks)
 	err := cs.tg.OnStop(func() error {
 		cs.gateway.UnregisterRPC("SendBlocks")
 		cs.gateway.UnregisterRPC("RelayHeader")
 		cs.gateway.UnregisterRPC("SendBlk")
+		cs.gateway.UnregisterRPC("SendHeaders")
+		cs.gateway.UnregisterRPC("SendBlocksByID")
 		cs.gateway.UnregisterConnectCall("SendBlocks")
 		return nil
 	})
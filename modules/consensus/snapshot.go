@@ -0,0 +1,594 @@
+package consensus
+
+// snapshot.go implements exporting and importing snapshots of the consensus
+// state. A snapshot contains the headers of all the blocks in the current path,
+// the most recent blocks, and the unspent outputs, file contracts, delayed
+// outputs and uplofund pool at a certain height. Importing a snapshot allows a
+// new node to skip processing the blockchain up to the height of the snapshot.
+//
+// A snapshot is streamed to and from disk. It starts with the height and the
+// headers of the snapshot, followed by the most recent blocks and the entries
+// of the consensus state buckets. Each bucket and each entry is preceded by a
+// true bool and a false bool terminates the list. Snapshots are only imported
+// if their hash matches a checkpoint, which is either supplied by the operator
+// or compiled into the binary. After the import, the changelog contains a single entry which
+// reverts the blocks that were previously in the current path and applies the
+// blocks of the snapshot. Subscribers receive the blocks of the snapshot which
+// were not included in full as blocks containing only the header fields, and
+// they receive the full consensus state of the snapshot as the diffs of the
+// last block. Blocks at or below the snapshot height can't be reverted
+// afterwards.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/uplo-tech/bolt"
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+const (
+	// snapshotAllocLimit is the maximum number of bytes that are allocated
+	// when decoding a snapshot.
+	snapshotAllocLimit = 1 << 36
+
+	// snapshotCopyFilename is the name of the temporary copy of the consensus
+	// database that is used to export snapshots below the current height.
+	snapshotCopyFilename = "snapshot.db.tmp"
+)
+
+var (
+	// Snapshot is a database bucket containing the height of the imported
+	// snapshot, the id of its changelog entry and its diffs.
+	Snapshot = []byte("Snapshot")
+
+	// SnapshotHeaders is a database bucket containing the headers of the
+	// blocks of an imported snapshot, keyed by their id.
+	SnapshotHeaders = []byte("SnapshotHeaders")
+
+	// FieldSnapshotChangeID is a field in the Snapshot bucket containing the
+	// id of the changelog entry of the snapshot.
+	FieldSnapshotChangeID = []byte("ChangeID")
+
+	// FieldSnapshotDiffs is a field in the Snapshot bucket containing the diffs
+	// that take subscribers from the genesis state to the state of the
+	// snapshot.
+	FieldSnapshotDiffs = []byte("Diffs")
+
+	// FieldSnapshotHeight is a field in the Snapshot bucket containing the
+	// height of the snapshot.
+	FieldSnapshotHeight = []byte("Height")
+)
+
+var (
+	// errSnapshotExists is returned when importing a snapshot into a consensus
+	// set which already imported one.
+	errSnapshotExists = errors.New("consensus set was already bootstrapped from a snapshot")
+
+	// errSnapshotHashMismatch is returned if the hash of a snapshot doesn't
+	// match the checkpoint for its height.
+	errSnapshotHashMismatch = errors.New("snapshot hash doesn't match the checkpoint")
+
+	// errSnapshotHeight is returned if the snapshot height is not above the
+	// current height of the consensus set.
+	errSnapshotHeight = errors.New("consensus set is already at or above the snapshot height")
+
+	// errSnapshotReorg is returned if a block would revert blocks at or below
+	// the height of an imported snapshot.
+	errSnapshotReorg = errors.New("cannot revert blocks below the height of the imported snapshot")
+
+	// errSnapshotRollback is used to roll back the database transaction after
+	// reading the state from a copy of the database for an export.
+	errSnapshotRollback = errors.New("rolling back snapshot export")
+
+	// errUnknownSnapshotCheckpoint is returned if there is no checkpoint for
+	// the height of a snapshot.
+	errUnknownSnapshotCheckpoint = errors.New("no checkpoint for snapshot height")
+)
+
+// isSnapshotBucket returns whether the database bucket with the given name is
+// part of a snapshot.
+func isSnapshotBucket(name []byte) bool {
+	for _, bucket := range [][]byte{UplocoinOutputs, FileContracts, UplofundOutputs, UplofundPool, FoundationUnlockHashes, BucketOak} {
+		if bytes.Equal(name, bucket) {
+			return true
+		}
+	}
+	return bytes.HasPrefix(name, prefixDSCO) || bytes.HasPrefix(name, prefixFCEX)
+}
+
+// getSnapshotHeight returns the height of the imported snapshot.
+func getSnapshotHeight(tx *bolt.Tx) (height types.BlockHeight, exists bool) {
+	b := tx.Bucket(Snapshot)
+	if b == nil {
+		return 0, false
+	}
+	err := encoding.Unmarshal(b.Get(FieldSnapshotHeight), &height)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return height, err == nil
+}
+
+// isSnapshotEntry returns whether the change entry is the entry of the
+// imported snapshot.
+func isSnapshotEntry(tx *bolt.Tx, ce changeEntry) bool {
+	b := tx.Bucket(Snapshot)
+	if b == nil {
+		return false
+	}
+	ceid := ce.ID()
+	return bytes.Equal(b.Get(FieldSnapshotChangeID), ceid[:])
+}
+
+// getSnapshotBlock returns a block of the imported snapshot and its diffs for
+// subscribers. Blocks which were not included in full only contain the header
+// fields. Only the last block of the snapshot has diffs.
+func getSnapshotBlock(tx *bolt.Tx, id types.BlockID, last bool) (types.Block, modules.ConsensusChangeDiffs, error) {
+	var block types.Block
+	var diffs modules.ConsensusChangeDiffs
+	if pb, err := getBlockMap(tx, id); err == nil {
+		block = pb.Block
+	} else {
+		var header types.BlockHeader
+		if err := encoding.Unmarshal(tx.Bucket(SnapshotHeaders).Get(id[:]), &header); err != nil {
+			return types.Block{}, modules.ConsensusChangeDiffs{}, errors.AddContext(err, "unable to get snapshot header")
+		}
+		block = types.Block{
+			ParentID:  header.ParentID,
+			Nonce:     header.Nonce,
+			Timestamp: header.Timestamp,
+		}
+	}
+	if last {
+		err := encoding.Unmarshal(tx.Bucket(Snapshot).Get(FieldSnapshotDiffs), &diffs)
+		if err != nil {
+			return types.Block{}, modules.ConsensusChangeDiffs{}, errors.AddContext(err, "unable to get snapshot diffs")
+		}
+	}
+	return block, diffs, nil
+}
+
+// getBlockHeader returns the header of the block with the given id. Headers of
+// blocks below the height of an imported snapshot are taken from the snapshot.
+func getBlockHeader(tx *bolt.Tx, id types.BlockID) (types.BlockHeader, error) {
+	if pb, err := getBlockMap(tx, id); err == nil {
+		return pb.Block.Header(), nil
+	}
+	var header types.BlockHeader
+	b := tx.Bucket(SnapshotHeaders)
+	if b == nil {
+		return types.BlockHeader{}, errNilItem
+	}
+	err := encoding.Unmarshal(b.Get(id[:]), &header)
+	return header, err
+}
+
+// snapshotStateDiffs returns the diffs which take subscribers from the genesis
+// state to the state in the database.
+func (cs *ConsensusSet) snapshotStateDiffs(tx *bolt.Tx) (cd modules.ConsensusChangeDiffs, err error) {
+	// Revert the diffs of the genesis block.
+	cd = computeConsensusChangeDiffs(&cs.blockRoot, false)
+
+	// Apply the current state.
+	err = tx.Bucket(UplocoinOutputs).ForEach(func(k, v []byte) error {
+		d := modules.UplocoinOutputDiff{Direction: modules.DiffApply}
+		copy(d.ID[:], k)
+		cd.UplocoinOutputDiffs = append(cd.UplocoinOutputDiffs, d)
+		return encoding.Unmarshal(v, &cd.UplocoinOutputDiffs[len(cd.UplocoinOutputDiffs)-1].UplocoinOutput)
+	})
+	if err != nil {
+		return modules.ConsensusChangeDiffs{}, err
+	}
+	err = tx.Bucket(FileContracts).ForEach(func(k, v []byte) error {
+		d := modules.FileContractDiff{Direction: modules.DiffApply}
+		copy(d.ID[:], k)
+		cd.FileContractDiffs = append(cd.FileContractDiffs, d)
+		return encoding.Unmarshal(v, &cd.FileContractDiffs[len(cd.FileContractDiffs)-1].FileContract)
+	})
+	if err != nil {
+		return modules.ConsensusChangeDiffs{}, err
+	}
+	err = tx.Bucket(UplofundOutputs).ForEach(func(k, v []byte) error {
+		d := modules.UplofundOutputDiff{Direction: modules.DiffApply}
+		copy(d.ID[:], k)
+		cd.UplofundOutputDiffs = append(cd.UplofundOutputDiffs, d)
+		return encoding.Unmarshal(v, &cd.UplofundOutputDiffs[len(cd.UplofundOutputDiffs)-1].UplofundOutput)
+	})
+	if err != nil {
+		return modules.ConsensusChangeDiffs{}, err
+	}
+	err = tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !bytes.HasPrefix(name, prefixDSCO) {
+			return nil
+		}
+		var maturityHeight types.BlockHeight
+		if err := encoding.Unmarshal(name[len(prefixDSCO):], &maturityHeight); err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			d := modules.DelayedUplocoinOutputDiff{
+				Direction:      modules.DiffApply,
+				MaturityHeight: maturityHeight,
+			}
+			copy(d.ID[:], k)
+			cd.DelayedUplocoinOutputDiffs = append(cd.DelayedUplocoinOutputDiffs, d)
+			return encoding.Unmarshal(v, &cd.DelayedUplocoinOutputDiffs[len(cd.DelayedUplocoinOutputDiffs)-1].UplocoinOutput)
+		})
+	})
+	if err != nil {
+		return modules.ConsensusChangeDiffs{}, err
+	}
+	cd.UplofundPoolDiffs = append(cd.UplofundPoolDiffs, modules.UplofundPoolDiff{
+		Direction: modules.DiffApply,
+		Previous:  types.ZeroCurrency,
+		Adjusted:  getUplofundPool(tx),
+	})
+	return cd, nil
+}
+
+// writeSnapshot writes the snapshot of the consensus state at the current
+// height of the database to w.
+func writeSnapshot(tx *bolt.Tx, w io.Writer) error {
+	// Write the headers and remember the most recent blocks.
+	enc := encoding.NewEncoder(w)
+	height := blockHeight(tx)
+	if err := enc.EncodeAll(height, uint64(height)+1); err != nil {
+		return err
+	}
+	var recentBlocks []processedBlock
+	for i := types.BlockHeight(0); i <= height; i++ {
+		id, err := getPath(tx, i)
+		if err != nil {
+			return err
+		}
+		header, err := getBlockHeader(tx, id)
+		if err != nil {
+			return errors.AddContext(err, "unable to get header")
+		}
+		if err := enc.Encode(header); err != nil {
+			return err
+		}
+		if uint64(height-i) >= types.MedianTimestampWindow {
+			continue
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return errors.AddContext(err, "unable to get recent block")
+		}
+		recentBlocks = append(recentBlocks, *pb)
+	}
+	if err := enc.Encode(recentBlocks); err != nil {
+		return err
+	}
+
+	// Write the buckets of the consensus state. Only the oak fields of the
+	// recent blocks are required to validate new blocks.
+	recent := make(map[string]struct{})
+	for _, pb := range recentBlocks {
+		id := pb.Block.ID()
+		recent[string(id[:])] = struct{}{}
+	}
+	recent[string(FieldOakInit)] = struct{}{}
+	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !isSnapshotBucket(name) {
+			return nil
+		}
+		if err := enc.EncodeAll(true, name); err != nil {
+			return err
+		}
+		err := b.ForEach(func(k, v []byte) error {
+			if _, exists := recent[string(k)]; !exists && bytes.Equal(name, BucketOak) {
+				return nil
+			}
+			return enc.EncodeAll(true, k, v)
+		})
+		if err != nil {
+			return err
+		}
+		return enc.Encode(false)
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Encode(false)
+}
+
+// writeSnapshotFromCopy writes the snapshot at the given height from a copy
+// of the consensus database to w. The blocks above the height are reverted
+// within a transaction that is rolled back after writing the snapshot.
+func (cs *ConsensusSet) writeSnapshotFromCopy(path string, height types.BlockHeight, w io.Writer) (err error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return errors.AddContext(err, "unable to open database copy")
+	}
+	defer func() {
+		err = errors.Compose(err, db.Close())
+	}()
+	err = db.Update(func(tx *bolt.Tx) error {
+		id, err := getPath(tx, height)
+		if err != nil {
+			return err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		cs.revertToBlock(tx, pb)
+		if err := writeSnapshot(tx, w); err != nil {
+			return err
+		}
+		return errSnapshotRollback
+	})
+	if !errors.Contains(err, errSnapshotRollback) {
+		return err
+	}
+	return nil
+}
+
+// readSnapshotBlocks reads the headers and the most recent blocks of a
+// snapshot and adds them to the current path, which must only contain the
+// genesis block. The ids of the applied blocks are added to the change entry.
+func (cs *ConsensusSet) readSnapshotBlocks(tx *bolt.Tx, dec *encoding.Decoder, height types.BlockHeight, ce *changeEntry) error {
+	// Read the headers and check that they form a chain starting at the
+	// genesis block.
+	var numHeaders uint64
+	if err := dec.Decode(&numHeaders); err != nil {
+		return err
+	}
+	if numHeaders != uint64(height)+1 {
+		return errors.New("wrong number of headers in snapshot")
+	}
+	headers, err := tx.CreateBucket(SnapshotHeaders)
+	if err != nil {
+		return err
+	}
+	var parentID types.BlockID
+	for i := uint64(0); i < numHeaders; i++ {
+		var header types.BlockHeader
+		if err := dec.Decode(&header); err != nil {
+			return err
+		}
+		id := header.ID()
+		if i == 0 && id != cs.blockRoot.Block.ID() {
+			return errors.New("snapshot has wrong genesis block")
+		} else if i > 0 {
+			if header.ParentID != parentID {
+				return errors.New("snapshot headers don't form a chain")
+			}
+			if err := headers.Put(id[:], encoding.Marshal(header)); err != nil {
+				return err
+			}
+			pushPath(tx, id)
+			ce.AppliedBlocks = append(ce.AppliedBlocks, id)
+		}
+		parentID = id
+	}
+
+	// Read the recent blocks and check that they belong to the chain and end
+	// at the height of the snapshot.
+	var recentBlocks []processedBlock
+	if err := dec.Decode(&recentBlocks); err != nil {
+		return err
+	}
+	if len(recentBlocks) == 0 {
+		return errors.New("snapshot doesn't contain any blocks")
+	}
+	for i, pb := range recentBlocks {
+		if pb.Height > height {
+			return errors.New("snapshot contains block which is not part of the chain")
+		}
+		if id, err := getPath(tx, pb.Height); err != nil || pb.Block.ID() != id {
+			return errors.New("snapshot contains block which is not part of the chain")
+		}
+		addBlockMap(tx, &recentBlocks[i])
+	}
+	if recentBlocks[len(recentBlocks)-1].Height != height {
+		return errors.New("snapshot doesn't contain the block at its height")
+	}
+	return nil
+}
+
+// readSnapshotBuckets reads the buckets of a snapshot and writes their
+// entries to the database.
+func readSnapshotBuckets(tx *bolt.Tx, dec *encoding.Decoder) error {
+	for {
+		var more bool
+		if err := dec.Decode(&more); err != nil {
+			return err
+		} else if !more {
+			return nil
+		}
+		var name []byte
+		if err := dec.Decode(&name); err != nil {
+			return err
+		}
+		if !isSnapshotBucket(name) {
+			return errors.New("snapshot contains unknown bucket " + hex.EncodeToString(name))
+		}
+		b, err := tx.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+		for {
+			if err := dec.Decode(&more); err != nil {
+				return err
+			} else if !more {
+				break
+			}
+			var key, value []byte
+			if err := dec.DecodeAll(&key, &value); err != nil {
+				return err
+			}
+			if err := b.Put(key, value); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ExportSnapshot writes a snapshot of the consensus state at the given height
+// to the writer and returns the hash of the snapshot.
+func (cs *ConsensusSet) ExportSnapshot(w io.Writer, height types.BlockHeight) (_ crypto.Hash, err error) {
+	if err := cs.tg.Add(); err != nil {
+		return crypto.Hash{}, err
+	}
+	defer cs.tg.Done()
+
+	// The snapshot is hashed while it is written.
+	h := crypto.NewHash()
+	bw := bufio.NewWriter(io.MultiWriter(w, h))
+
+	// The state is written within a read-only transaction to avoid blocking
+	// the consensus set. If the snapshot is below the current height, the
+	// database is copied instead and the blocks above the height are reverted
+	// in the copy.
+	var copied bool
+	copyPath := filepath.Join(cs.persistDir, snapshotCopyFilename)
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		if height > blockHeight(tx) {
+			return errors.New("height is above the current height of the consensus set")
+		}
+		if snapshotHeight, exists := getSnapshotHeight(tx); exists && height < snapshotHeight {
+			return errSnapshotReorg
+		}
+		if height == blockHeight(tx) {
+			return writeSnapshot(tx, bw)
+		}
+		copied = true
+		return tx.CopyFile(copyPath, 0600)
+	})
+	if copied {
+		defer func() {
+			err = errors.Compose(err, os.Remove(copyPath))
+		}()
+	}
+	if err == nil && copied {
+		err = cs.writeSnapshotFromCopy(copyPath, height, bw)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		return crypto.Hash{}, errors.AddContext(err, "unable to write snapshot")
+	}
+	var hash crypto.Hash
+	copy(hash[:], h.Sum(nil))
+	return hash, nil
+}
+
+// ImportSnapshot bootstraps the consensus set from a snapshot. The hash of the
+// snapshot needs to match the given checkpoint. If the checkpoint is empty,
+// the checkpoint compiled into the binary for the height of the snapshot is
+// used instead. Afterwards the consensus set continues to sync normally from
+// the height of the snapshot.
+func (cs *ConsensusSet) ImportSnapshot(r io.Reader, checkpoint crypto.Hash) error {
+	if err := cs.tg.Add(); err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// The snapshot is hashed while it is read and written to the database.
+	// The hash is only checked after reading the whole snapshot, which rolls
+	// back the transaction if it doesn't match.
+	h := crypto.NewHash()
+	dec := encoding.NewDecoder(io.TeeReader(bufio.NewReader(r), h), snapshotAllocLimit)
+	var height types.BlockHeight
+	if err := dec.Decode(&height); err != nil {
+		return errors.AddContext(err, "unable to read snapshot")
+	}
+	if checkpoint == (crypto.Hash{}) {
+		compiled, exists := build.ConsensusSnapshotCheckpoints[uint64(height)]
+		if !exists {
+			return errors.AddContext(errUnknownSnapshotCheckpoint, "invalid snapshot")
+		}
+		if err := checkpoint.LoadString(compiled); err != nil {
+			return errors.AddContext(err, "invalid checkpoint")
+		}
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	var ce changeEntry
+	err := cs.db.Update(func(tx *bolt.Tx) error {
+		if _, exists := getSnapshotHeight(tx); exists {
+			return errSnapshotExists
+		}
+		if blockHeight(tx) >= height {
+			return errSnapshotHeight
+		}
+
+		// Revert the current path back to the genesis block.
+		for _, pb := range cs.revertToBlock(tx, &cs.blockRoot) {
+			ce.RevertedBlocks = append(ce.RevertedBlocks, pb.Block.ID())
+		}
+
+		// Add the blocks of the snapshot to the current path.
+		if err := cs.readSnapshotBlocks(tx, dec, height, &ce); err != nil {
+			return errors.AddContext(err, "invalid snapshot")
+		}
+
+		// Replace the consensus state with the state of the snapshot.
+		var obsolete [][]byte
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if isSnapshotBucket(name) && !bytes.Equal(name, BucketOak) {
+				obsolete = append(obsolete, append([]byte(nil), name...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range obsolete {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		if err := readSnapshotBuckets(tx, dec); err != nil {
+			return errors.AddContext(err, "invalid snapshot")
+		}
+
+		// Check the hash of the snapshot now that it was read completely.
+		var hash crypto.Hash
+		copy(hash[:], h.Sum(nil))
+		if hash != checkpoint {
+			return errors.AddContext(errSnapshotHashMismatch, "invalid snapshot")
+		}
+
+		// Record the snapshot and add its entry to the changelog.
+		diffs, err := cs.snapshotStateDiffs(tx)
+		if err != nil {
+			return errors.AddContext(err, "unable to compute snapshot diffs")
+		}
+		b, err := tx.CreateBucket(Snapshot)
+		if err != nil {
+			return err
+		}
+		ceid := ce.ID()
+		err = errors.Compose(
+			b.Put(FieldSnapshotHeight, encoding.Marshal(height)),
+			b.Put(FieldSnapshotChangeID, ceid[:]),
+			b.Put(FieldSnapshotDiffs, encoding.Marshal(diffs)),
+		)
+		if err != nil {
+			return err
+		}
+		return appendChangeLog(tx, ce)
+	})
+	if err != nil {
+		return errors.AddContext(err, "unable to import snapshot")
+	}
+	cs.log.Println("Bootstrapped consensus set from snapshot at height", height)
+	cs.updateSubscribers(ce)
+	return nil
+}
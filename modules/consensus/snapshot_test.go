@@ -0,0 +1,107 @@
+package consensus
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+)
+
+// TestSnapshot probes exporting a snapshot of the consensus state and
+// bootstrapping a new consensus set from it.
+func TestSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := cst.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	cst2, err := blankConsensusSetTester(t.Name()+"2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := cst2.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Export a snapshot at a height below the current height.
+	height := cst.cs.Height() - 2
+	var buf bytes.Buffer
+	hash, err := cst.cs.ExportSnapshot(&buf, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cst.cs.Height() != height+2 {
+		t.Fatal("exporting a snapshot changed the height of the consensus set")
+	}
+	snapshot := buf.Bytes()
+
+	// The copy of the database used for the export should be removed.
+	if _, err := os.Stat(filepath.Join(cst.cs.persistDir, snapshotCopyFilename)); !os.IsNotExist(err) {
+		t.Fatal("copy of the database wasn't removed", err)
+	}
+
+	// Exporting at the current height reads the state directly.
+	if _, err := cst.cs.ExportSnapshot(new(bytes.Buffer), cst.cs.Height()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Importing the snapshot fails without a checkpoint or if the checkpoint
+	// doesn't match. A failed import leaves the consensus set unchanged.
+	err = cst2.cs.ImportSnapshot(bytes.NewReader(snapshot), crypto.Hash{})
+	if !errors.Contains(err, errUnknownSnapshotCheckpoint) {
+		t.Fatal("expected errUnknownSnapshotCheckpoint", err)
+	}
+	build.ConsensusSnapshotCheckpoints[uint64(height)] = crypto.Hash{1}.String()
+	defer delete(build.ConsensusSnapshotCheckpoints, uint64(height))
+	err = cst2.cs.ImportSnapshot(bytes.NewReader(snapshot), crypto.Hash{})
+	if !errors.Contains(err, errSnapshotHashMismatch) {
+		t.Fatal("expected errSnapshotHashMismatch", err)
+	}
+	if cst2.cs.Height() != 0 {
+		t.Fatal("failed import changed the height of the consensus set", cst2.cs.Height())
+	}
+
+	// Import the snapshot with a checkpoint supplied by the operator.
+	if err := cst2.cs.ImportSnapshot(bytes.NewReader(snapshot), hash); err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.Height() != height {
+		t.Fatal("wrong height after import", cst2.cs.Height(), height)
+	}
+	b, _ := cst.cs.BlockAtHeight(height)
+	if cst2.cs.CurrentBlock().ID() != b.ID() {
+		t.Fatal("wrong current block after import")
+	}
+	if err := cst2.cs.ImportSnapshot(bytes.NewReader(snapshot), hash); !errors.Contains(err, errSnapshotExists) {
+		t.Fatal("expected errSnapshotExists", err)
+	}
+
+	// The bootstrapped consensus set should continue from the snapshot.
+	for i := height + 1; i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		if err := cst2.cs.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cst2.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.Height() != cst.cs.Height()+1 {
+		t.Fatal("bootstrapped consensus set didn't sync", cst2.cs.Height(), cst.cs.Height())
+	}
+}
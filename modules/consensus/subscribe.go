@@ -73,7 +73,20 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		cc.RevertedDiffs = append(cc.RevertedDiffs, diffs)
 		cc.AppendDiffs(diffs)
 	}
-	for _, appliedBlockID := range ce.AppliedBlocks {
+	snapshotEntry := isSnapshotEntry(tx, ce)
+	for i, appliedBlockID := range ce.AppliedBlocks {
+		// The blocks of an imported snapshot don't have diffs of their own.
+		if snapshotEntry {
+			block, diffs, err := getSnapshotBlock(tx, appliedBlockID, i == len(ce.AppliedBlocks)-1)
+			if err != nil {
+				cs.log.Critical("getSnapshotBlock failed in computeConsensusChange:", err)
+				return modules.ConsensusChange{}, err
+			}
+			cc.AppliedBlocks = append(cc.AppliedBlocks, block)
+			cc.AppliedDiffs = append(cc.AppliedDiffs, diffs)
+			cc.AppendDiffs(diffs)
+			continue
+		}
 		appliedBlock, err := getBlockMap(tx, appliedBlockID)
 		if err != nil {
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
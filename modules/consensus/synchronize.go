@@ -90,6 +90,22 @@ func isTimeoutErr(err error) bool {
 	return (err.Error() == "Read timeout" || err.Error() == "Write timeout")
 }
 
+// isInvalidBlockErr returns true if err indicates that a block or block header
+// relayed by a peer is invalid. Blocks which are already known, don't extend
+// the longest chain, have no known parent or have a timestamp in the future are
+// not considered invalid, since honest peers relay them too.
+func isInvalidBlockErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	return !errors.Contains(err, modules.ErrBlockKnown) &&
+		!errors.Contains(err, modules.ErrNonExtendingBlock) &&
+		!errors.Contains(err, errOrphan) &&
+		!errors.Contains(err, ErrFutureTimestamp) &&
+		!errors.Contains(err, ErrExtremeFutureTimestamp) &&
+		!errors.Contains(err, threadgroup.ErrStopped)
+}
+
 // blockHistory returns up to 32 block ids, starting with recent blocks and
 // then proving exponentially increasingly less recent blocks. The genesis
 // block is always included as the last block. This block history can be used
@@ -132,6 +148,33 @@ func blockHistory(tx *bolt.Tx) (blockIDs [32]types.BlockID) {
 	return blockIDs
 }
 
+// commonChildHeight returns the height of the child of the most recent block
+// from knownBlocks that is in the current path. If none of the blocks is in the
+// current path, or if the most recent one is the current block, false is
+// returned.
+func commonChildHeight(tx *bolt.Tx, knownBlocks [32]types.BlockID) (types.BlockHeight, bool) {
+	csHeight := blockHeight(tx)
+	for _, id := range knownBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			continue
+		}
+		pathID, err := getPath(tx, pb.Height)
+		if err != nil {
+			continue
+		}
+		if pathID != pb.Block.ID() {
+			continue
+		}
+		if pb.Height == csHeight {
+			break
+		}
+		// Start from the child of the common block.
+		return pb.Height + 1, true
+	}
+	return 0, false
+}
+
 // managedReceiveBlocks is the calling end of the SendBlocks RPC, without the
 // threadgroup wrapping.
 func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr error) {
@@ -230,6 +273,9 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 		// sharing is implemented, block already in database should also be
 		// ignored.
 		if acceptErr != nil && !errors.Contains(acceptErr, modules.ErrNonExtendingBlock) && !errors.Contains(acceptErr, modules.ErrBlockKnown) {
+			if isInvalidBlockErr(acceptErr) {
+				cs.gateway.ReportPeer(conn.RPCAddr(), modules.PeerOffenseInvalidBlock)
+			}
 			return acceptErr
 		}
 	}
@@ -295,29 +341,12 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 	// Find the most recent block from knownBlocks in the current path.
 	found := false
 	var start types.BlockHeight
-	var csHeight types.BlockHeight
 	cs.mu.RLock()
 	err = cs.db.View(func(tx *bolt.Tx) error {
-		csHeight = blockHeight(tx)
-		for _, id := range knownBlocks {
-			pb, err := getBlockMap(tx, id)
-			if err != nil {
-				continue
-			}
-			pathID, err := getPath(tx, pb.Height)
-			if err != nil {
-				continue
-			}
-			if pathID != pb.Block.ID() {
-				continue
-			}
-			if pb.Height == csHeight {
-				break
-			}
-			found = true
-			// Start from the child of the common block.
-			start = pb.Height + 1
-			break
+		start, found = commonChildHeight(tx, knownBlocks)
+		// Blocks below the height of an imported snapshot are not available.
+		if snapshotHeight, exists := getSnapshotHeight(tx); exists && found && start <= snapshotHeight {
+			found = false
 		}
 		return nil
 	})
@@ -449,6 +478,9 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 		}()
 		return nil
 	} else if err != nil {
+		if isInvalidBlockErr(err) {
+			cs.gateway.ReportPeer(conn.RPCAddr(), modules.PeerOffenseInvalidBlock)
+		}
 		return err
 	}
 
@@ -530,6 +562,9 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 		if chainExtended {
 			cs.managedBroadcastBlock(block)
 		}
+		if isInvalidBlockErr(err) {
+			cs.gateway.ReportPeer(conn.RPCAddr(), modules.PeerOffenseInvalidBlock)
+		}
 		if err != nil {
 			return err
 		}
@@ -575,9 +610,14 @@ func (cs *ConsensusSet) managedInitialBlockchainDownload() error {
 				}
 				defer cs.tg.Done()
 
-				// Request blocks from the peer. The error returned will only be
-				// 'nil' if there are no more blocks to receive.
-				err = cs.gateway.RPC(p.NetAddress, "SendBlocks", cs.managedReceiveBlocks)
+				// Synchronize with the peer, preferring headers-first
+				// synchronization. Peers which don't support it are synced
+				// by requesting blocks directly. The error returned will only
+				// be 'nil' if there are no more blocks to receive.
+				err = cs.managedHeadersFirstSync(p.NetAddress)
+				if errors.Contains(err, errSendHeadersFailed) {
+					err = cs.gateway.RPC(p.NetAddress, "SendBlocks", cs.managedReceiveBlocks)
+				}
 				if err == nil {
 					numOutboundSynced++
 					// In this case, 'return nil' is equivalent to skipping to
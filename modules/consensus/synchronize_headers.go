@@ -0,0 +1,582 @@
+package consensus
+
+// synchronize_headers.go implements headers-first synchronization. The header
+// chain of a peer is downloaded and validated first. Afterwards the
+// corresponding blocks are downloaded in parallel from all of the peers and
+// applied in order. Downloads which stall or fail are retried with the next
+// peer.
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/uplo-tech/bolt"
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/threadgroup"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+var (
+	// errBlockMismatch is returned if a peer sends blocks other than the
+	// requested ones.
+	errBlockMismatch = errors.New("peer sent blocks that weren't requested")
+
+	// errNoBlockPeers is returned if none of the peers was able to send a
+	// batch of blocks.
+	errNoBlockPeers = errors.New("no peer was able to send the requested blocks")
+
+	// errSendHeadersFailed is returned if the SendHeaders RPC failed, usually
+	// because the peer doesn't support headers-first synchronization.
+	errSendHeadersFailed = errors.New("SendHeaders RPC failed")
+
+	// MaxCatchUpHeaders is the maximum number of headers that are sent in a
+	// single SendHeaders RPC.
+	MaxCatchUpHeaders = build.Select(build.Var{
+		Standard: types.BlockHeight(2000),
+		Dev:      types.BlockHeight(500),
+		Testing:  types.BlockHeight(20),
+	}).(types.BlockHeight)
+
+	// numBlockDownloadWorkers is the number of batches of blocks that are
+	// downloaded in parallel during headers-first synchronization.
+	numBlockDownloadWorkers = build.Select(build.Var{
+		Standard: 8,
+		Dev:      4,
+		Testing:  3,
+	}).(int)
+
+	// blockBatchTimeout is the time the blocks of the next batch to be applied
+	// may take to arrive. Afterwards the peers which are currently downloading
+	// the batch are considered stalled and the batch is handed to another
+	// peer. It is shorter than sendBlocksByIDTimeout so that a single slow
+	// peer can't hold up the synchronization until its RPC times out.
+	blockBatchTimeout = build.Select(build.Var{
+		Standard: 60 * time.Second,
+		Dev:      20 * time.Second,
+		Testing:  2 * time.Second,
+	}).(time.Duration)
+
+	// maxPendingBlockBatches is the maximum number of batches which are
+	// downloaded ahead of the batch that is applied next. It bounds the
+	// memory used by batches which were downloaded but not yet applied.
+	maxPendingBlockBatches = 2 * numBlockDownloadWorkers
+
+	// sendBlocksByIDTimeout is the timeout for the SendBlocksByID RPC. A peer
+	// which doesn't send the requested blocks before the timeout is
+	// considered stalled.
+	sendBlocksByIDTimeout = build.Select(build.Var{
+		Standard: 120 * time.Second,
+		Dev:      40 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// sendHeadersTimeout is the timeout for the SendHeaders RPC.
+	sendHeadersTimeout = build.Select(build.Var{
+		Standard: 120 * time.Second,
+		Dev:      40 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+)
+
+// blockDownloadBatch is a batch of consecutive blocks which is downloaded from
+// a single peer.
+type blockDownloadBatch struct {
+	ids []types.BlockID
+
+	// blocks, err and peer are set before done is closed.
+	blocks []types.Block
+	err    error
+
+	// peer is the peer which sent the blocks.
+	peer modules.NetAddress
+
+	// failedPeers contains the peers which failed to send the batch or
+	// stalled while sending it.
+	failedPeers map[modules.NetAddress]struct{}
+
+	// activePeers contains the peers which are currently downloading the
+	// batch.
+	activePeers map[modules.NetAddress]struct{}
+
+	// queued indicates whether the batch is currently in the queue. A batch
+	// is never queued more than once, so the queue never has to hold more
+	// than one entry per batch.
+	queued bool
+
+	// finished indicates whether done was closed.
+	finished bool
+
+	// done is closed once the blocks were downloaded or all peers failed.
+	done chan struct{}
+
+	mu sync.Mutex
+}
+
+// managedFinish completes the batch with the given blocks or error, unless it
+// was already completed by another peer.
+func (b *blockDownloadBatch) managedFinish(blocks []types.Block, peer modules.NetAddress, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return
+	}
+	b.blocks = blocks
+	b.peer = peer
+	b.err = err
+	b.finished = true
+	close(b.done)
+}
+
+// managedStalled marks the peers which are currently downloading the batch as
+// failed and puts the batch back into the queue, so that it is retried with
+// another peer. The active downloads continue and may still complete the
+// batch.
+func (b *blockDownloadBatch) managedStalled(queue chan<- *blockDownloadBatch) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return
+	}
+	for peer := range b.activePeers {
+		b.failedPeers[peer] = struct{}{}
+	}
+	b.enqueue(queue)
+}
+
+// enqueue puts the batch into the queue unless it is already queued. The
+// queue has room for every batch, so this never blocks.
+func (b *blockDownloadBatch) enqueue(queue chan<- *blockDownloadBatch) {
+	if b.queued {
+		return
+	}
+	b.queued = true
+	queue <- b
+}
+
+// maxTargetDrop returns the maximum factor by which the difficulty can drop
+// from one block to the next at the given height.
+func maxTargetDrop(height types.BlockHeight) *big.Rat {
+	if height < types.OakHardforkBlock && types.MaxTargetAdjustmentDown.Cmp(types.OakMaxDrop) < 0 {
+		return types.MaxTargetAdjustmentDown
+	}
+	return types.OakMaxDrop
+}
+
+// validateHeaderChain does some early, low computation verification on a
+// chain of headers to determine if the corresponding blocks should be
+// downloaded. The first header needs to extend a known block. The target of
+// the following blocks is not known until the blocks are applied, so the
+// headers are only checked against the easiest target which the difficulty
+// adjustment allows. The blocks are fully validated when they are applied.
+func (cs *ConsensusSet) validateHeaderChain(tx *bolt.Tx, headers []types.BlockHeader) error {
+	parent, err := getBlockMap(tx, headers[0].ParentID)
+	if err != nil {
+		return errOrphan
+	}
+	parentID := parent.Block.ID()
+	height := parent.Height
+	target := parent.ChildTarget
+	for _, h := range headers {
+		height++
+		id := h.ID()
+		if _, exists := cs.dosBlocks[id]; exists {
+			return errDoSBlock
+		}
+		if h.ParentID != parentID {
+			return errors.New("headers don't form a chain")
+		}
+		// The target is reset at the ASIC hardfork height, which makes it
+		// impossible to bound the target of the following blocks.
+		if height == types.ASICHardforkHeight {
+			target = types.RootDepth
+		}
+		if height >= types.ASICHardforkHeight && binary.LittleEndian.Uint64(h.Nonce[:])%types.ASICHardforkFactor != 0 {
+			return errors.New("block does not meet nonce requirements")
+		}
+		if !checkHeaderTarget(h, target) {
+			return modules.ErrBlockUnsolved
+		}
+		if h.Timestamp > types.CurrentTimestamp()+types.ExtremeFutureThreshold {
+			return ErrExtremeFutureTimestamp
+		}
+		parentID = id
+		target = target.MulDifficulty(maxTargetDrop(height))
+	}
+	return nil
+}
+
+// managedDownloadBlocks downloads the blocks with the given ids in parallel
+// from the peers and applies them in order. Batches of blocks which stall or
+// fail are retried with the next peer. At most maxPendingBlockBatches batches
+// are downloaded ahead of the batch that is applied next. The returned bool
+// indicates whether the current path was extended.
+func (cs *ConsensusSet) managedDownloadBlocks(ids []types.BlockID, peers []modules.NetAddress) (extended bool, err error) {
+	// Split the blocks into batches.
+	var batches []*blockDownloadBatch
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > int(MaxCatchUpBlocks) {
+			n = int(MaxCatchUpBlocks)
+		}
+		batches = append(batches, &blockDownloadBatch{
+			ids:         ids[:n],
+			failedPeers: make(map[modules.NetAddress]struct{}),
+			activePeers: make(map[modules.NetAddress]struct{}),
+			done:        make(chan struct{}),
+		})
+		ids = ids[n:]
+	}
+	queue := make(chan *blockDownloadBatch, len(batches))
+	enqueue := func(i int) {
+		if i >= len(batches) {
+			return
+		}
+		batches[i].mu.Lock()
+		batches[i].enqueue(queue)
+		batches[i].mu.Unlock()
+	}
+	for i := 0; i < maxPendingBlockBatches; i++ {
+		enqueue(i)
+	}
+
+	// Start the workers. Every worker starts with a different peer.
+	cancel := make(chan struct{})
+	defer close(cancel)
+	for i := 0; i < numBlockDownloadWorkers && i < len(batches); i++ {
+		go cs.threadedDownloadBlocks(queue, peers, i, cancel)
+	}
+
+	// Apply the batches in order. Every applied batch makes room for the
+	// download of another one. If the blocks of the next batch don't arrive
+	// in time, the batch is handed to another peer.
+	for i, batch := range batches {
+		for waiting := true; waiting; {
+			select {
+			case <-batch.done:
+				waiting = false
+			case <-time.After(blockBatchTimeout):
+				cs.log.Debugf("WARN: download of %v blocks stalled, retrying with another peer", len(batch.ids))
+				batch.managedStalled(queue)
+			case <-cs.tg.StopChan():
+				return extended, threadgroup.ErrStopped
+			}
+		}
+		enqueue(i + maxPendingBlockBatches)
+		if batch.err != nil {
+			return extended, batch.err
+		}
+		batchExtended, acceptErr := cs.managedAcceptBlocks(batch.blocks)
+		if batchExtended {
+			extended = true
+		}
+		if acceptErr != nil && !errors.Contains(acceptErr, modules.ErrNonExtendingBlock) && !errors.Contains(acceptErr, modules.ErrBlockKnown) {
+			if isInvalidBlockErr(acceptErr) {
+				cs.gateway.ReportPeer(batch.peer, modules.PeerOffenseInvalidBlock)
+			}
+			return extended, acceptErr
+		}
+	}
+	return extended, nil
+}
+
+// managedHeadersFirstSync synchronizes with a peer by downloading and
+// validating its header chain and downloading the corresponding blocks in
+// parallel from all peers. The error returned will only be 'nil' if the peer
+// has no more headers to send.
+func (cs *ConsensusSet) managedHeadersFirstSync(addr modules.NetAddress) error {
+	var tip types.BlockID
+	for {
+		// Download the next headers of the peer.
+		var headers []types.BlockHeader
+		var moreAvailable bool
+		err := cs.gateway.RPC(addr, "SendHeaders", cs.managedReceiveHeaders(tip, &headers, &moreAvailable))
+		if err != nil {
+			return errors.Compose(errSendHeadersFailed, err)
+		}
+		if len(headers) == 0 {
+			return nil
+		}
+		cs.mu.RLock()
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			return cs.validateHeaderChain(tx, headers)
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			if errors.Contains(err, errOrphan) {
+				cs.gateway.ReportPeer(addr, modules.PeerOffenseProtocolViolation)
+			} else if isInvalidBlockErr(err) {
+				cs.gateway.ReportPeer(addr, modules.PeerOffenseInvalidBlock)
+			}
+			return errors.AddContext(err, "peer sent invalid headers")
+		}
+
+		// Download the blocks from all peers.
+		ids := make([]types.BlockID, len(headers))
+		for i, h := range headers {
+			ids[i] = h.ID()
+		}
+		peers := []modules.NetAddress{addr}
+		for _, p := range cs.gateway.Peers() {
+			if p.NetAddress != addr {
+				peers = append(peers, p.NetAddress)
+			}
+		}
+		_, err = cs.managedDownloadBlocks(ids, peers)
+		if err != nil {
+			return err
+		}
+		if !moreAvailable {
+			return nil
+		}
+		// Continue from the last header, even if it didn't extend the current
+		// path yet.
+		tip = ids[len(ids)-1]
+	}
+}
+
+// managedReceiveBlocksByID returns an RPCFunc that is the calling end of the
+// SendBlocksByID RPC. The received blocks are written to 'blocks'.
+func (cs *ConsensusSet) managedReceiveBlocksByID(ids []types.BlockID, blocks *[]types.Block) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := conn.SetDeadline(time.Now().Add(sendBlocksByIDTimeout))
+		if err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(conn, ids); err != nil {
+			return err
+		}
+		var received []types.Block
+		if err := encoding.ReadObject(conn, &received, uint64(len(ids))*types.BlockSizeLimit+8); err != nil {
+			return err
+		}
+		if len(received) != len(ids) {
+			return errBlockMismatch
+		}
+		for i := range received {
+			if received[i].ID() != ids[i] {
+				return errBlockMismatch
+			}
+		}
+		*blocks = received
+		return nil
+	}
+}
+
+// managedReceiveHeaders returns an RPCFunc that is the calling end of the
+// SendHeaders RPC. If a tip is provided, the peer is asked for the headers
+// following the tip. The received headers and whether more headers are
+// available are written to the provided pointers.
+func (cs *ConsensusSet) managedReceiveHeaders(tip types.BlockID, headers *[]types.BlockHeader, moreAvailable *bool) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := conn.SetDeadline(time.Now().Add(sendHeadersTimeout))
+		if err != nil {
+			return err
+		}
+		var history [32]types.BlockID
+		cs.mu.RLock()
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			history = blockHistory(tx)
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if tip != (types.BlockID{}) {
+			copy(history[1:31], history[:30])
+			history[0] = tip
+		}
+		if err := encoding.WriteObject(conn, history); err != nil {
+			return err
+		}
+		if err := encoding.ReadObject(conn, headers, uint64(MaxCatchUpHeaders)*types.BlockHeaderSize+8); err != nil {
+			return err
+		}
+		if uint64(len(*headers)) > uint64(MaxCatchUpHeaders) {
+			return errors.New("peer sent too many headers")
+		}
+		return encoding.ReadObject(conn, moreAvailable, 1)
+	}
+}
+
+// rpcSendBlocksByID is the receiving end of the SendBlocksByID RPC. It sends
+// the requested blocks to the peer.
+func (cs *ConsensusSet) rpcSendBlocksByID(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBlocksByIDTimeout))
+	if err != nil {
+		return err
+	}
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Read the ids of the requested blocks.
+	var ids []types.BlockID
+	err = encoding.ReadObject(conn, &ids, uint64(MaxCatchUpBlocks)*crypto.HashSize+8)
+	if err != nil {
+		return err
+	}
+	if uint64(len(ids)) > uint64(MaxCatchUpBlocks) {
+		return errors.New("too many blocks requested")
+	}
+
+	// Look up the blocks.
+	blocks := make([]types.Block, 0, len(ids))
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, pb.Block)
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return encoding.WriteObject(conn, blocks)
+}
+
+// rpcSendHeaders is the receiving end of the SendHeaders RPC. It sends up to
+// 'MaxCatchUpHeaders' headers following the most recent of the 32 input block
+// IDs that is in the current path, and a boolean indicating whether more
+// headers are available.
+func (cs *ConsensusSet) rpcSendHeaders(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendHeadersTimeout))
+	if err != nil {
+		return err
+	}
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Read a list of blocks known to the requester.
+	var knownBlocks [32]types.BlockID
+	err = encoding.ReadObject(conn, &knownBlocks, 32*crypto.HashSize)
+	if err != nil {
+		return err
+	}
+
+	// Collect the headers following the most recent common block.
+	headers := []types.BlockHeader{}
+	var moreAvailable bool
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		start, found := commonChildHeight(tx, knownBlocks)
+		if !found {
+			return nil
+		}
+		height := blockHeight(tx)
+		for i := start; i <= height && i < start+MaxCatchUpHeaders; i++ {
+			id, err := getPath(tx, i)
+			if err != nil {
+				return err
+			}
+			header, err := getBlockHeader(tx, id)
+			if err != nil {
+				return err
+			}
+			headers = append(headers, header)
+		}
+		moreAvailable = start+MaxCatchUpHeaders <= height
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := encoding.WriteObject(conn, headers); err != nil {
+		return err
+	}
+	return encoding.WriteObject(conn, moreAvailable)
+}
+
+// threadedDownloadBlocks downloads batches of blocks from the queue. If a peer
+// fails to send a batch, the batch is put back into the queue and the worker
+// rotates to the next peer.
+func (cs *ConsensusSet) threadedDownloadBlocks(queue chan *blockDownloadBatch, peers []modules.NetAddress, peerIndex int, cancel <-chan struct{}) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+	for {
+		var batch *blockDownloadBatch
+		select {
+		case batch = <-queue:
+		case <-cancel:
+			return
+		case <-cs.tg.StopChan():
+			return
+		}
+
+		// Find the next peer which neither failed to send the batch nor is
+		// already downloading it.
+		batch.mu.Lock()
+		batch.queued = false
+		if batch.finished {
+			batch.mu.Unlock()
+			continue
+		}
+		var peer modules.NetAddress
+		found := false
+		for i := 0; i < len(peers); i++ {
+			p := peers[(peerIndex+i)%len(peers)]
+			_, failed := batch.failedPeers[p]
+			_, active := batch.activePeers[p]
+			if !failed && !active {
+				peer = p
+				peerIndex = (peerIndex + i) % len(peers)
+				found = true
+				break
+			}
+		}
+		if !found {
+			// If another peer is still downloading the batch, it will either
+			// complete the batch or put it back into the queue.
+			noPeers := len(batch.activePeers) == 0
+			batch.mu.Unlock()
+			if noPeers {
+				batch.managedFinish(nil, "", errNoBlockPeers)
+			}
+			continue
+		}
+		batch.activePeers[peer] = struct{}{}
+		batch.mu.Unlock()
+
+		var blocks []types.Block
+		err := cs.gateway.RPC(peer, "SendBlocksByID", cs.managedReceiveBlocksByID(batch.ids, &blocks))
+		if errors.Contains(err, errBlockMismatch) {
+			cs.gateway.ReportPeer(peer, modules.PeerOffenseProtocolViolation)
+		}
+		if err != nil {
+			cs.log.Debugf("WARN: failed to download blocks from %v: %v", peer, err)
+			batch.mu.Lock()
+			delete(batch.activePeers, peer)
+			batch.failedPeers[peer] = struct{}{}
+			if !batch.finished {
+				batch.enqueue(queue)
+			}
+			batch.mu.Unlock()
+			peerIndex++
+			continue
+		}
+		batch.mu.Lock()
+		delete(batch.activePeers, peer)
+		batch.mu.Unlock()
+		batch.managedFinish(blocks, peer, nil)
+	}
+}
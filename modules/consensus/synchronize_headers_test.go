@@ -0,0 +1,218 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/bolt"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestHeadersFirstSync probes synchronizing with a peer by downloading its
+// headers first and the blocks in parallel from multiple peers.
+func TestHeadersFirstSync(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	local, err := blankConsensusSetTester(t.Name()+" - local", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := local.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	remote, err := blankConsensusSetTester(t.Name()+" - remote", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := remote.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	partial, err := blankConsensusSetTester(t.Name()+" - partial", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := partial.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Connect the peers while they are all at the genesis block.
+	if err := local.gateway.Connect(remote.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.gateway.Connect(partial.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	// Wait to let the OnConnectRPCs finish.
+	time.Sleep(100 * time.Millisecond)
+
+	// Mine blocks on the remote without broadcasting them. The partial peer
+	// only knows about the first half of the blocks, which forces the local
+	// peer to retry the remaining batches with the remote.
+	numBlocks := 2*MaxCatchUpHeaders + 5
+	for i := types.BlockHeight(0); i < numBlocks; i++ {
+		b, _ := remote.miner.FindBlock()
+		if _, err := remote.cs.managedAcceptBlocks([]types.Block{b}); err != nil {
+			t.Fatal(err)
+		}
+		if i < numBlocks/2 {
+			if _, err := partial.cs.managedAcceptBlocks([]types.Block{b}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Synchronize with the remote.
+	if err := local.cs.managedHeadersFirstSync(remote.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if local.cs.CurrentBlock().ID() != remote.cs.CurrentBlock().ID() {
+		t.Fatal("local didn't synchronize with remote", local.cs.Height(), remote.cs.Height())
+	}
+
+	// Synchronizing again shouldn't do anything.
+	if err := local.cs.managedHeadersFirstSync(remote.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if local.cs.Height() != remote.cs.Height() {
+		t.Fatal("height changed after synchronizing again")
+	}
+}
+
+// TestHeadersFirstSyncStalledPeer probes that a batch of blocks which a peer
+// stalls on is handed to another peer before the RPC times out.
+func TestHeadersFirstSyncStalledPeer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	local, err := blankConsensusSetTester(t.Name()+" - local", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := local.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	remote, err := blankConsensusSetTester(t.Name()+" - remote", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := remote.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	staller, err := blankConsensusSetTester(t.Name()+" - staller", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := staller.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Replace the SendBlocksByID RPC of the staller with one that never
+	// sends any blocks.
+	stop := make(chan struct{})
+	defer close(stop)
+	staller.gateway.UnregisterRPC("SendBlocksByID")
+	staller.gateway.RegisterRPC("SendBlocksByID", func(conn modules.PeerConn) error {
+		select {
+		case <-stop:
+		case <-time.After(2 * sendBlocksByIDTimeout):
+		}
+		return nil
+	})
+
+	// Connect the peers while they are all at the genesis block.
+	if err := local.gateway.Connect(remote.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.gateway.Connect(staller.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	// Wait to let the OnConnectRPCs finish.
+	time.Sleep(100 * time.Millisecond)
+
+	// Mine a few batches of blocks on the remote without broadcasting them.
+	numBlocks := MaxCatchUpHeaders - 1
+	for i := types.BlockHeight(0); i < numBlocks; i++ {
+		b, _ := remote.miner.FindBlock()
+		if _, err := remote.cs.managedAcceptBlocks([]types.Block{b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Synchronize with the remote. The batches requested from the staller
+	// should be retried with the remote before the RPC times out.
+	start := time.Now()
+	if err := local.cs.managedHeadersFirstSync(remote.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= sendBlocksByIDTimeout {
+		t.Fatal("stalled batch wasn't handed to another peer in time", elapsed)
+	}
+	if local.cs.CurrentBlock().ID() != remote.cs.CurrentBlock().ID() {
+		t.Fatal("local didn't synchronize with remote", local.cs.Height(), remote.cs.Height())
+	}
+}
+
+// TestValidateHeaderChain probes the validation of header chains.
+func TestValidateHeaderChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := cst.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Create a valid chain of headers.
+	var blocks []types.Block
+	for i := 0; i < 3; i++ {
+		b, _ := cst.miner.FindBlock()
+		blocks = append(blocks, b)
+		if _, err := cst.cs.managedAcceptBlocks([]types.Block{b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	headers := make([]types.BlockHeader, len(blocks))
+	for i, b := range blocks {
+		headers[i] = b.Header()
+	}
+	validate := func(headers []types.BlockHeader) error {
+		return cst.cs.db.View(func(tx *bolt.Tx) error {
+			return cst.cs.validateHeaderChain(tx, headers)
+		})
+	}
+	if err := validate(headers); err != nil {
+		t.Fatal(err)
+	}
+
+	// Headers which don't form a chain are rejected.
+	if err := validate([]types.BlockHeader{headers[0], headers[2]}); err == nil {
+		t.Fatal("expected unconnected headers to be rejected")
+	}
+
+	// Headers with an unknown parent are rejected.
+	orphan := headers[0]
+	orphan.ParentID = types.BlockID{1}
+	if err := validate([]types.BlockHeader{orphan}); err != errOrphan {
+		t.Fatal("expected errOrphan", err)
+	}
+}
@@ -235,9 +235,10 @@ func (pd *ProductionDependencies) Destruct() {
 }
 
 // DialTimeout creates a tcp connection to a certain address with the specified
-// timeout.
+// timeout. The connection is established using the global dialer, so it
+// respects the proxy settings.
 func (*ProductionDependencies) DialTimeout(addr NetAddress, timeout time.Duration) (net.Conn, error) {
-	return net.DialTimeout("tcp", string(addr), timeout)
+	return GlobalDialer.Dial(&net.Dialer{Timeout: timeout}, string(addr))
 }
 
 // Disrupt can be used to inject specific behavior into a module by overwriting
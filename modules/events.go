@@ -0,0 +1,205 @@
+package modules
+
+import (
+	"sync"
+
+	"github.com/uplo-tech/uplo/types"
+)
+
+// The following consts are the types of events that modules notify their
+// subscribers about.
+const (
+	// EventAlertRegistered is the type of the event of a new or changed alert.
+	EventAlertRegistered EventType = "alert.registered"
+	// EventAlertUnregistered is the type of the event of a removed alert.
+	EventAlertUnregistered EventType = "alert.unregistered"
+	// EventBlockApplied is the type of the event of a block that was added
+	// to the blockchain.
+	EventBlockApplied EventType = "block.applied"
+	// EventBlockReverted is the type of the event of a block that was removed
+	// from the blockchain by a reorg.
+	EventBlockReverted EventType = "block.reverted"
+	// EventContractExpired is the type of the event of a renter contract that
+	// expired and was archived.
+	EventContractExpired EventType = "contract.expired"
+	// EventContractFormed is the type of the event of a new renter contract.
+	EventContractFormed EventType = "contract.formed"
+	// EventContractRenewed is the type of the event of a renewed renter
+	// contract.
+	EventContractRenewed EventType = "contract.renewed"
+	// EventFileHealthChanged is the type of the event of a file whose health
+	// changed.
+	EventFileHealthChanged EventType = "file.health"
+	// EventHostContractFailed is the type of the event of a host contract
+	// whose storage proof was missed.
+	EventHostContractFailed EventType = "host.contract.failed"
+	// EventHostContractFormed is the type of the event of a new host
+	// contract.
+	EventHostContractFormed EventType = "host.contract.formed"
+	// EventHostContractRenewed is the type of the event of a host contract
+	// which was renewed.
+	EventHostContractRenewed EventType = "host.contract.renewed"
+	// EventHostContractSucceeded is the type of the event of a host contract
+	// which ended successfully, either with a storage proof or without
+	// needing one.
+	EventHostContractSucceeded EventType = "host.contract.succeeded"
+	// EventUploadFailed is the type of the event of a chunk of a file that
+	// couldn't be uploaded.
+	EventUploadFailed EventType = "upload.failed"
+	// EventUploadFinished is the type of the event of a file that was fully
+	// uploaded.
+	EventUploadFinished EventType = "upload.finished"
+	// EventWalletTransaction is the type of the event of a new unconfirmed or
+	// confirmed transaction of the wallet.
+	EventWalletTransaction EventType = "wallet.transaction"
+)
+
+var (
+	// knownEventTypes contains all the types of events that are published by
+	// the modules.
+	knownEventTypes = map[EventType]struct{}{
+		EventAlertRegistered:       {},
+		EventAlertUnregistered:     {},
+		EventBlockApplied:          {},
+		EventBlockReverted:         {},
+		EventContractExpired:       {},
+		EventContractFormed:        {},
+		EventContractRenewed:       {},
+		EventFileHealthChanged:     {},
+		EventHostContractFailed:    {},
+		EventHostContractFormed:    {},
+		EventHostContractRenewed:   {},
+		EventHostContractSucceeded: {},
+		EventUploadFailed:          {},
+		EventUploadFinished:        {},
+		EventWalletTransaction:     {},
+	}
+)
+
+type (
+	// EventType is the type of an event.
+	EventType string
+
+	// EventListener is the interface of types which are notified about the
+	// events of modules.
+	EventListener interface {
+		// ProcessEvent is called with the type and the data of every event of
+		// the modules the listener subscribed to. It is called synchronously,
+		// so it must not block or call back into the module.
+		ProcessEvent(typ EventType, data interface{})
+	}
+
+	// EventSubscriber is the interface of modules which notify subscribers
+	// about events.
+	EventSubscriber interface {
+		// SubscribeEvents adds a listener which is notified about every event
+		// of the module.
+		SubscribeEvents(l EventListener)
+
+		// UnsubscribeEvents removes a listener. If the listener isn't
+		// subscribed, no action is taken.
+		UnsubscribeEvents(l EventListener)
+	}
+
+	// EventNotifier is a helper type which modules use to notify their
+	// subscribers about events. The zero value is ready to use.
+	EventNotifier struct {
+		listeners []EventListener
+		mu        sync.Mutex
+	}
+
+	// AlertEvent is the data of an EventAlertRegistered or
+	// EventAlertUnregistered event.
+	AlertEvent struct {
+		ID AlertID `json:"id"`
+		Alert
+	}
+
+	// BlockEvent is the data of an EventBlockApplied or EventBlockReverted
+	// event.
+	BlockEvent struct {
+		ID     types.BlockID     `json:"id"`
+		Height types.BlockHeight `json:"height"`
+	}
+
+	// ContractEvent is the data of an EventContractFormed,
+	// EventContractRenewed or EventContractExpired event. RenewedFrom is only
+	// set for renewed contracts.
+	ContractEvent struct {
+		ID            types.FileContractID `json:"id"`
+		HostPublicKey types.UploPublicKey  `json:"hostpublickey"`
+		EndHeight     types.BlockHeight    `json:"endheight"`
+		RenewedFrom   types.FileContractID `json:"renewedfrom"`
+	}
+
+	// HostContractEvent is the data of an EventHostContractFormed,
+	// EventHostContractRenewed, EventHostContractSucceeded or
+	// EventHostContractFailed event. RenewedFrom is only set for renewed
+	// contracts.
+	HostContractEvent struct {
+		ID               types.FileContractID `json:"id"`
+		ExpirationHeight types.BlockHeight    `json:"expirationheight"`
+		ProofDeadline    types.BlockHeight    `json:"proofdeadline"`
+		RenewedFrom      types.FileContractID `json:"renewedfrom"`
+	}
+
+	// FileHealthEvent is the data of an EventFileHealthChanged event.
+	FileHealthEvent struct {
+		UploPath       UploPath `json:"uplopath"`
+		Health         float64  `json:"health"`
+		PreviousHealth float64  `json:"previoushealth"`
+	}
+
+	// UploadEvent is the data of an EventUploadFinished or EventUploadFailed
+	// event.
+	UploadEvent struct {
+		UploPath UploPath `json:"uplopath"`
+		Error    string   `json:"error,omitempty"`
+	}
+
+	// WalletTransactionEvent is the data of an EventWalletTransaction event.
+	// Unconfirmed transactions have a ConfirmationHeight of math.MaxUint64.
+	WalletTransactionEvent struct {
+		TransactionID      types.TransactionID `json:"transactionid"`
+		ConfirmationHeight types.BlockHeight   `json:"confirmationheight"`
+	}
+)
+
+// IsKnown returns true if the type is the type of an event that is published
+// by the modules.
+func (t EventType) IsKnown() bool {
+	_, known := knownEventTypes[t]
+	return known
+}
+
+// SubscribeEvents adds a listener which is notified about every event.
+func (en *EventNotifier) SubscribeEvents(l EventListener) {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+	en.listeners = append(en.listeners, l)
+}
+
+// UnsubscribeEvents removes a listener. If the listener isn't subscribed, no
+// action is taken.
+func (en *EventNotifier) UnsubscribeEvents(l EventListener) {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+	for i := range en.listeners {
+		if en.listeners[i] == l {
+			// Copy the remaining listeners since Notify might still be
+			// iterating over the old slice.
+			en.listeners = append(en.listeners[:i:i], en.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// Notify notifies all subscribed listeners about the event.
+func (en *EventNotifier) Notify(typ EventType, data interface{}) {
+	en.mu.Lock()
+	listeners := en.listeners
+	en.mu.Unlock()
+	for _, l := range listeners {
+		l.ProcessEvent(typ, data)
+	}
+}
@@ -25,13 +25,13 @@ type (
 		// Transaction type counts.
 		MinerPayoutCount          uint64 `json:"minerpayoutcount"`
 		TransactionCount          uint64 `json:"transactioncount"`
-		UplocoinInputCount         uint64 `json:"Uplocoininputcount"`
-		UplocoinOutputCount        uint64 `json:"Uplocoinoutputcount"`
+		UplocoinInputCount        uint64 `json:"Uplocoininputcount"`
+		UplocoinOutputCount       uint64 `json:"Uplocoinoutputcount"`
 		FileContractCount         uint64 `json:"filecontractcount"`
 		FileContractRevisionCount uint64 `json:"filecontractrevisioncount"`
 		StorageProofCount         uint64 `json:"storageproofcount"`
-		UplofundInputCount         uint64 `json:"uplofundinputcount"`
-		UplofundOutputCount        uint64 `json:"uplofundoutputcount"`
+		UplofundInputCount        uint64 `json:"uplofundinputcount"`
+		UplofundOutputCount       uint64 `json:"uplofundoutputcount"`
 		MinerFeeCount             uint64 `json:"minerfeecount"`
 		ArbitraryDataCount        uint64 `json:"arbitrarydatacount"`
 		TransactionSignatureCount uint64 `json:"transactionsignaturecount"`
@@ -45,6 +45,58 @@ type (
 		TotalRevisionVolume types.Currency `json:"totalrevisionvolume"`
 	}
 
+	// ExplorerAddress contains the balance and the unspent outputs of an
+	// unlock hash.
+	ExplorerAddress struct {
+		UnlockHash      types.UnlockHash         `json:"unlockhash"`
+		UplocoinBalance types.Currency           `json:"uplocoinbalance"`
+		UplofundBalance types.Currency           `json:"uplofundbalance"`
+		UplocoinOutputs []ExplorerUplocoinOutput `json:"uplocoinoutputs"`
+		UplofundOutputs []ExplorerUplofundOutput `json:"uplofundoutputs"`
+	}
+
+	// ExplorerUplocoinOutput is an unspent Uplocoin output together with its
+	// ID.
+	ExplorerUplocoinOutput struct {
+		ID types.UplocoinOutputID `json:"id"`
+		types.UplocoinOutput
+	}
+
+	// ExplorerUplofundOutput is an unspent uplofund output together with its
+	// ID.
+	ExplorerUplofundOutput struct {
+		ID types.UplofundOutputID `json:"id"`
+		types.UplofundOutput
+	}
+
+	// ExplorerHost contains the announcements of a host and the outcome of its
+	// file contracts as they appear in the blockchain. Contracts are
+	// attributed to a host once a revision of the contract reveals the
+	// public key of the host in its unlock conditions. From then on, every
+	// contract formed paying out to the same host address is attributed to
+	// the host from its formation, even if it is never revised.
+	ExplorerHost struct {
+		PublicKey           types.UploPublicKey        `json:"publickey"`
+		NetAddress          NetAddress                 `json:"netaddress"`
+		FirstAnnounced      types.BlockHeight          `json:"firstannounced"`
+		LastAnnounced       types.BlockHeight          `json:"lastannounced"`
+		Announcements       []ExplorerHostAnnouncement `json:"announcements"`
+		FirstContractFormed types.BlockHeight          `json:"firstcontractformed"`
+		LastContractFormed  types.BlockHeight          `json:"lastcontractformed"`
+		Contracts           uint64                     `json:"contracts"`
+		ActiveContracts     uint64                     `json:"activecontracts"`
+		SuccessfulProofs    uint64                     `json:"successfulproofs"`
+		MissedProofs        uint64                     `json:"missedproofs"`
+	}
+
+	// ExplorerHostAnnouncement is a host announcement that appeared in the
+	// blockchain.
+	ExplorerHostAnnouncement struct {
+		Height        types.BlockHeight   `json:"height"`
+		TransactionID types.TransactionID `json:"transactionid"`
+		NetAddress    NetAddress          `json:"netaddress"`
+	}
+
 	// Explorer tracks the blockchain and provides tools for gathering
 	// statistics and finding objects or patterns within the blockchain.
 	Explorer interface {
@@ -73,6 +125,15 @@ type (
 		// provided unlock hash.
 		UnlockHash(types.UnlockHash) []types.TransactionID
 
+		// Address returns the balance and the unspent outputs of the provided
+		// unlock hash.
+		Address(types.UnlockHash) ExplorerAddress
+
+		// AddressHistory returns the ids of the transactions associated with
+		// the provided unlock hash, ordered from the most recent to the
+		// oldest.
+		AddressHistory(types.UnlockHash) []types.TransactionID
+
 		// UplocoinOutput will return the Uplocoin output associated with the
 		// input id.
 		UplocoinOutput(types.UplocoinOutputID) (types.UplocoinOutput, bool)
@@ -101,6 +162,17 @@ type (
 		// the provided uplofund output id.
 		UplofundOutputID(types.UplofundOutputID) []types.TransactionID
 
+		// Host returns the announcements and contract history of the host
+		// with the provided public key. The bool indicates whether the host
+		// has announced itself in the blockchain.
+		Host(types.UploPublicKey) (ExplorerHost, bool)
+
+		// Hosts returns the announcements and contract history of the hosts
+		// that have announced themselves in the blockchain, starting at the
+		// provided offset and returning at most limit hosts. The total number
+		// of hosts is returned as well.
+		Hosts(offset, limit uint64) ([]ExplorerHost, uint64)
+
 		Close() error
 	}
 )
@@ -26,6 +26,25 @@ var (
 	bucketTransactionIDs   = []byte("TransactionIDs")
 	bucketUnlockHashes     = []byte("UnlockHashes")
 
+	// bucketUnlockHashUplocoinOutputs and bucketUnlockHashUplofundOutputs map
+	// an unlock hash to a bucket of its unspent outputs.
+	bucketUnlockHashUplocoinOutputs = []byte("UnlockHashUplocoinOutputs")
+	bucketUnlockHashUplofundOutputs = []byte("UnlockHashUplofundOutputs")
+
+	// bucketHostAnnouncements and bucketHostContracts map the public key of a
+	// host to a bucket of its announcements and a bucket of the file
+	// contracts attributed to it.
+	bucketHostAnnouncements = []byte("HostAnnouncements")
+	bucketHostContracts     = []byte("HostContracts")
+
+	// bucketHostPayoutContracts maps a host payout address to a bucket of the
+	// file contracts that were formed paying out to it, and
+	// bucketHostPayoutKeys maps it to a bucket of the host keys revealed by
+	// the revisions paying out to it. Together they attribute contracts to a
+	// host even if the contracts are never revised.
+	bucketHostPayoutContracts = []byte("HostPayoutContracts")
+	bucketHostPayoutKeys      = []byte("HostPayoutKeys")
+
 	errNotExist = errors.New("entry does not exist")
 
 	// keys for bucketInternal
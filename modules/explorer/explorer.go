@@ -23,11 +23,12 @@ var (
 type (
 	// fileContractHistory stores the original file contract and the chain of
 	// revisions that have affected a file contract through the life of the
-	// blockchain.
+	// blockchain, along with the height at which the contract was formed.
 	fileContractHistory struct {
-		Contract     types.FileContract
-		Revisions    []types.FileContractRevision
-		StorageProof types.StorageProof
+		Contract        types.FileContract
+		Revisions       []types.FileContractRevision
+		StorageProof    types.StorageProof
+		FormationHeight types.BlockHeight
 	}
 
 	// blockFacts contains a set of facts about the consensus set related to a
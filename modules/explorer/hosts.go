@@ -0,0 +1,247 @@
+package explorer
+
+import (
+	"sort"
+
+	"github.com/uplo-tech/bolt"
+	"github.com/uplo-tech/encoding"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+type (
+	// hostAnnouncementKey is the key of an announcement in the bucket of
+	// announcements of a host. A transaction can contain multiple
+	// announcements, so the index of the arbitrary data is part of the key.
+	hostAnnouncementKey struct {
+		TransactionID types.TransactionID
+		Index         uint64
+	}
+
+	// hostContractKey is the key of a file contract in the bucket of contracts
+	// attributed to a host. Every revision that reveals the host has its own
+	// key, so that reverting one of the revisions doesn't remove the
+	// attribution made by the others.
+	hostContractKey struct {
+		FileContractID types.FileContractID
+		TransactionID  types.TransactionID
+	}
+)
+
+// revisionHostKey returns the public key of the host of a file contract
+// revision. Contracts are formed with unlock conditions that contain the
+// public key of the renter followed by the public key of the host.
+func revisionHostKey(fcr types.FileContractRevision) (types.UploPublicKey, bool) {
+	uc := fcr.UnlockConditions
+	if len(uc.PublicKeys) != 2 || uc.SignaturesRequired != 2 || uc.PublicKeys[1].Algorithm != types.SignatureEd25519 {
+		return types.UploPublicKey{}, false
+	}
+	return uc.PublicKeys[1], true
+}
+
+// contractHostPayout returns the address that a file contract pays the host to
+// when the storage proof succeeds. Contracts are formed with the valid proof
+// output of the renter followed by the valid proof output of the host.
+func contractHostPayout(validProofOutputs []types.UplocoinOutput) (types.UnlockHash, bool) {
+	if len(validProofOutputs) != 2 {
+		return types.UnlockHash{}, false
+	}
+	return validProofOutputs[1].UnlockHash, true
+}
+
+// dbHostPayoutOwner returns the host key that the revisions paying out to the
+// specified address revealed. If no revision revealed a key, or the revisions
+// revealed the keys of different hosts, the address has no owner.
+func dbHostPayoutOwner(tx *bolt.Tx, addr types.UnlockHash) (types.UploPublicKey, bool) {
+	bucket := tx.Bucket(bucketHostPayoutKeys).Bucket(encoding.Marshal(addr))
+	if bucket == nil {
+		return types.UploPublicKey{}, false
+	}
+	var owner types.UploPublicKey
+	found, ambiguous := false, false
+	err := bucket.ForEach(func(_, v []byte) error {
+		var hostKey types.UploPublicKey
+		if err := encoding.Unmarshal(v, &hostKey); err != nil {
+			return err
+		}
+		if found && !owner.Equals(hostKey) {
+			ambiguous = true
+		}
+		owner, found = hostKey, true
+		return nil
+	})
+	if err != nil || ambiguous {
+		return types.UploPublicKey{}, false
+	}
+	return owner, found
+}
+
+// dbGetHost returns a 'func(*bolt.Tx) error' that builds the ExplorerHost of
+// the host with the specified public key. If the host never announced itself,
+// dbGetHost returns errNotExist.
+func dbGetHost(hostKey types.UploPublicKey, host *modules.ExplorerHost) func(*bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		annBucket := tx.Bucket(bucketHostAnnouncements).Bucket(encoding.Marshal(hostKey))
+		if annBucket == nil {
+			return errNotExist
+		}
+		h := modules.ExplorerHost{PublicKey: hostKey}
+		err := annBucket.ForEach(func(_, v []byte) error {
+			var ann modules.ExplorerHostAnnouncement
+			if err := encoding.Unmarshal(v, &ann); err != nil {
+				return err
+			}
+			h.Announcements = append(h.Announcements, ann)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(h.Announcements, func(i, j int) bool {
+			return h.Announcements[i].Height < h.Announcements[j].Height
+		})
+		h.FirstAnnounced = h.Announcements[0].Height
+		h.LastAnnounced = h.Announcements[len(h.Announcements)-1].Height
+		h.NetAddress = h.Announcements[len(h.Announcements)-1].NetAddress
+
+		// Collect the contracts attributed to the host by its revisions, and the
+		// payout addresses that those revisions pay the host to.
+		fcids := make(map[types.FileContractID]struct{})
+		addrs := make(map[types.UnlockHash]struct{})
+		if contractBucket := tx.Bucket(bucketHostContracts).Bucket(encoding.Marshal(hostKey)); contractBucket != nil {
+			err = contractBucket.ForEach(func(k, _ []byte) error {
+				var key hostContractKey
+				if err := encoding.Unmarshal(k, &key); err != nil {
+					return err
+				}
+				fcids[key.FileContractID] = struct{}{}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		histories := make(map[types.FileContractID]fileContractHistory)
+		for fcid := range fcids {
+			var history fileContractHistory
+			err := dbGetAndDecode(bucketFileContractHistories, fcid, &history)(tx)
+			if err != nil {
+				return err
+			}
+			histories[fcid] = history
+			for _, fcr := range history.Revisions {
+				if addr, ok := contractHostPayout(fcr.NewValidProofOutputs); ok {
+					addrs[addr] = struct{}{}
+				}
+			}
+		}
+
+		// A host that stops responding never revises its contracts, so the
+		// contracts formed with a payout address that belongs to the host are
+		// attributed to it as well. An address that was revealed by different
+		// hosts is ignored, since its contracts can't be attributed reliably.
+		for addr := range addrs {
+			owner, ok := dbHostPayoutOwner(tx, addr)
+			if !ok || !owner.Equals(hostKey) {
+				continue
+			}
+			payoutBucket := tx.Bucket(bucketHostPayoutContracts).Bucket(encoding.Marshal(addr))
+			if payoutBucket == nil {
+				continue
+			}
+			err = payoutBucket.ForEach(func(k, _ []byte) error {
+				var fcid types.FileContractID
+				if err := encoding.Unmarshal(k, &fcid); err != nil {
+					return err
+				}
+				if _, exists := histories[fcid]; exists {
+					return nil
+				}
+				var history fileContractHistory
+				if err := dbGetAndDecode(bucketFileContractHistories, fcid, &history)(tx); err != nil {
+					return err
+				}
+				histories[fcid] = history
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// Determine the outcome of the contracts. A contract without a storage
+		// proof missed its proof once the proof window of its latest revision
+		// has ended.
+		var height types.BlockHeight
+		err = dbGetInternal(internalBlockHeight, &height)(tx)
+		if err != nil {
+			return err
+		}
+		for fcid, history := range histories {
+			if h.Contracts == 0 || history.FormationHeight < h.FirstContractFormed {
+				h.FirstContractFormed = history.FormationHeight
+			}
+			if history.FormationHeight > h.LastContractFormed {
+				h.LastContractFormed = history.FormationHeight
+			}
+			h.Contracts++
+			windowEnd := history.Contract.WindowEnd
+			if len(history.Revisions) > 0 {
+				windowEnd = history.Revisions[len(history.Revisions)-1].NewWindowEnd
+			}
+			switch {
+			case history.StorageProof.ParentID == fcid:
+				h.SuccessfulProofs++
+			case windowEnd <= height:
+				h.MissedProofs++
+			default:
+				h.ActiveContracts++
+			}
+		}
+		*host = h
+		return nil
+	}
+}
+
+// Host returns the announcements and the contract history of the host with
+// the specified public key.
+func (e *Explorer) Host(hostKey types.UploPublicKey) (modules.ExplorerHost, bool) {
+	var host modules.ExplorerHost
+	err := e.db.View(dbGetHost(hostKey, &host))
+	if err != nil {
+		return modules.ExplorerHost{}, false
+	}
+	return host, true
+}
+
+// Hosts returns the announcements and the contract history of the hosts that
+// announced themselves in the blockchain, starting at offset and returning at
+// most limit hosts, along with the total number of hosts.
+func (e *Explorer) Hosts(offset, limit uint64) ([]modules.ExplorerHost, uint64) {
+	hosts := []modules.ExplorerHost{}
+	var total uint64
+	err := e.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketHostAnnouncements).ForEach(func(k, _ []byte) error {
+			total++
+			if total <= offset || uint64(len(hosts)) >= limit {
+				return nil
+			}
+			var hostKey types.UploPublicKey
+			if err := encoding.Unmarshal(k, &hostKey); err != nil {
+				return err
+			}
+			var host modules.ExplorerHost
+			if err := dbGetHost(hostKey, &host)(tx); err != nil {
+				return err
+			}
+			hosts = append(hosts, host)
+			return nil
+		})
+	})
+	if err != nil {
+		build.Critical("failed to read hosts from the explorer database:", err)
+	}
+	return hosts, total
+}
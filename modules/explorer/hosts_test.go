@@ -0,0 +1,162 @@
+package explorer
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestExplorerHosts probes the host announcement and contract index of the
+// explorer.
+func TestExplorerHosts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSK, hostPK := crypto.GenerateKeyPair()
+	hostKey := types.Ed25519PublicKey(hostPK)
+	if _, exists := et.explorer.Host(hostKey); exists {
+		t.Fatal("host shouldn't exist before announcing")
+	}
+
+	// Announce the host twice with different addresses.
+	announce := func(addr modules.NetAddress) {
+		ann, err := modules.CreateAnnouncement(addr, hostKey, hostSK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		builder, err := et.wallet.StartTransaction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fee := types.UplocoinPrecision
+		if err := builder.FundUplocoins(fee); err != nil {
+			t.Fatal(err)
+		}
+		builder.AddMinerFee(fee)
+		builder.AddArbitraryData(ann)
+		txns, err := builder.Sign(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := et.tpool.AcceptTransactionSet(txns); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := et.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	announce("foo.com:1234")
+	announce("bar.com:1234")
+	host, exists := et.explorer.Host(hostKey)
+	if !exists {
+		t.Fatal("host wasn't indexed")
+	}
+	if len(host.Announcements) != 2 || host.NetAddress != "bar.com:1234" || host.FirstAnnounced >= host.LastAnnounced {
+		t.Fatal("wrong announcements", host)
+	}
+	if hosts, total := et.explorer.Hosts(0, 10); total != 1 || len(hosts) != 1 || hosts[0].PublicKey.String() != hostKey.String() {
+		t.Fatal("wrong hosts", hosts, total)
+	}
+	if hosts, total := et.explorer.Hosts(1, 10); total != 1 || len(hosts) != 0 {
+		t.Fatal("offset wasn't applied", hosts, total)
+	}
+
+	// Form two contracts with the host that pay out to the same host address.
+	// Only the first one is ever revised.
+	renterSK, renterPK := crypto.GenerateKeyPair()
+	hostAddr := types.UnlockHash{1}
+	uc := types.UnlockConditions{
+		PublicKeys:         []types.UploPublicKey{types.Ed25519PublicKey(renterPK), hostKey},
+		SignaturesRequired: 2,
+	}
+	builder, err := et.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.FundUplocoins(types.NewCurrency64(10e9)); err != nil {
+		t.Fatal(err)
+	}
+	fcOutputs := []types.UplocoinOutput{
+		{Value: types.NewCurrency64(4e9)},
+		{Value: types.NewCurrency64(805e6), UnlockHash: hostAddr},
+	}
+	fc := types.FileContract{
+		WindowStart:        et.cs.Height() + 4,
+		WindowEnd:          et.cs.Height() + 5,
+		Payout:             types.NewCurrency64(5e9),
+		ValidProofOutputs:  fcOutputs,
+		MissedProofOutputs: fcOutputs,
+		UnlockHash:         uc.UnlockHash(),
+	}
+	fcIndex := builder.AddFileContract(fc)
+	builder.AddFileContract(fc)
+	formationHeight := et.cs.Height() + 1
+	txns, err := builder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcid := txns[len(txns)-1].FileContractID(fcIndex)
+	if err := et.tpool.AcceptTransactionSet(txns); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := et.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The contracts are attributed to the host once one of them is revised.
+	host, _ = et.explorer.Host(hostKey)
+	if host.Contracts != 0 {
+		t.Fatal("contracts shouldn't be attributed before a revision", host.Contracts)
+	}
+	revTxn := types.Transaction{
+		FileContractRevisions: []types.FileContractRevision{{
+			ParentID:              fcid,
+			UnlockConditions:      uc,
+			NewRevisionNumber:     1,
+			NewWindowStart:        fc.WindowStart,
+			NewWindowEnd:          fc.WindowEnd,
+			NewValidProofOutputs:  fc.ValidProofOutputs,
+			NewMissedProofOutputs: fc.MissedProofOutputs,
+			NewUnlockHash:         fc.UnlockHash,
+		}},
+		TransactionSignatures: []types.TransactionSignature{
+			{ParentID: crypto.Hash(fcid), PublicKeyIndex: 0, CoveredFields: types.CoveredFields{WholeTransaction: true}},
+			{ParentID: crypto.Hash(fcid), PublicKeyIndex: 1, CoveredFields: types.CoveredFields{WholeTransaction: true}},
+		},
+	}
+	for i, sk := range []crypto.SecretKey{renterSK, hostSK} {
+		sig := crypto.SignHash(revTxn.SigHash(i, et.cs.Height()), sk)
+		revTxn.TransactionSignatures[i].Signature = sig[:]
+	}
+	if err := et.tpool.AcceptTransactionSet([]types.Transaction{revTxn}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := et.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	host, _ = et.explorer.Host(hostKey)
+	if host.Contracts != 2 || host.ActiveContracts != 2 || host.MissedProofs != 0 {
+		t.Fatal("contracts weren't attributed to the host", host)
+	}
+	if host.FirstContractFormed != formationHeight || host.LastContractFormed != formationHeight {
+		t.Fatal("wrong formation heights", host.FirstContractFormed, host.LastContractFormed, formationHeight)
+	}
+
+	// The host never submits a storage proof, so the proofs of both contracts
+	// are missed once the window ends.
+	for et.cs.Height() < fc.WindowEnd {
+		if _, err := et.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	host, _ = et.explorer.Host(hostKey)
+	if host.Contracts != 2 || host.ActiveContracts != 0 || host.MissedProofs != 2 || host.SuccessfulProofs != 0 {
+		t.Fatal("missed proofs weren't counted", host)
+	}
+}
@@ -1,7 +1,10 @@
 package explorer
 
 import (
+	"sort"
+
 	"github.com/uplo-tech/bolt"
+	"github.com/uplo-tech/encoding"
 
 	"github.com/uplo-tech/uplo/build"
 	"github.com/uplo-tech/uplo/modules"
@@ -82,6 +85,91 @@ func (e *Explorer) UnlockHash(uh types.UnlockHash) []types.TransactionID {
 	return ids
 }
 
+// Address returns the balance and the unspent outputs of the unlock hash.
+func (e *Explorer) Address(uh types.UnlockHash) modules.ExplorerAddress {
+	addr := modules.ExplorerAddress{
+		UnlockHash:      uh,
+		UplocoinOutputs: []modules.ExplorerUplocoinOutput{},
+		UplofundOutputs: []modules.ExplorerUplofundOutput{},
+	}
+	err := e.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(bucketUnlockHashUplocoinOutputs).Bucket(encoding.Marshal(uh)); b != nil {
+			err := b.ForEach(func(k, v []byte) error {
+				var sco modules.ExplorerUplocoinOutput
+				if err := encoding.Unmarshal(k, &sco.ID); err != nil {
+					return err
+				}
+				if err := encoding.Unmarshal(v, &sco.UplocoinOutput); err != nil {
+					return err
+				}
+				addr.UplocoinBalance = addr.UplocoinBalance.Add(sco.Value)
+				addr.UplocoinOutputs = append(addr.UplocoinOutputs, sco)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if b := tx.Bucket(bucketUnlockHashUplofundOutputs).Bucket(encoding.Marshal(uh)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var sfo modules.ExplorerUplofundOutput
+				if err := encoding.Unmarshal(k, &sfo.ID); err != nil {
+					return err
+				}
+				if err := encoding.Unmarshal(v, &sfo.UplofundOutput); err != nil {
+					return err
+				}
+				addr.UplofundBalance = addr.UplofundBalance.Add(sfo.Value)
+				addr.UplofundOutputs = append(addr.UplofundOutputs, sfo)
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		build.Critical("failed to read unspent outputs of unlock hash:", err)
+	}
+	return addr
+}
+
+// AddressHistory returns the IDs of all the transactions that contain the
+// unlock hash, ordered from the most recent to the oldest. Transactions within
+// the same block are ordered by ID.
+func (e *Explorer) AddressHistory(uh types.UnlockHash) []types.TransactionID {
+	type historyEntry struct {
+		id     types.TransactionID
+		height types.BlockHeight
+	}
+	var history []historyEntry
+	err := e.db.View(func(tx *bolt.Tx) error {
+		var ids []types.TransactionID
+		err := dbGetTransactionIDSet(bucketUnlockHashes, uh, &ids)(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			var height types.BlockHeight
+			err := dbGetAndDecode(bucketTransactionIDs, id, &height)(tx)
+			if err != nil {
+				return err
+			}
+			history = append(history, historyEntry{id: id, height: height})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].height > history[j].height
+	})
+	ids := make([]types.TransactionID, 0, len(history))
+	for _, entry := range history {
+		ids = append(ids, entry.id)
+	}
+	return ids
+}
+
 // UplocoinOutput returns the Uplocoin output associated with the specified ID.
 func (e *Explorer) UplocoinOutput(id types.UplocoinOutputID) (types.UplocoinOutput, bool) {
 	var sco types.UplocoinOutput
@@ -3,9 +3,11 @@ package explorer
 import (
 	"testing"
 
+	"github.com/uplo-tech/errors"
 	"github.com/uplo-tech/fastrand"
 
 	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/types"
 )
 
@@ -269,3 +271,102 @@ func TestFileContractsPayoutValidProof(t *testing.T) {
 		t.Errorf("expected %v, got %v ", fc.MissedProofOutputs, outputs)
 	}
 }
+
+// TestAddress probes the Address and AddressHistory functions of the explorer,
+// including how they handle reorgs.
+func TestAddress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The genesis uplofund allocation should be indexed.
+	sfuh := types.GenesisUplofundAllocation[0].UnlockHash
+	var sfBalance types.Currency
+	var sfOutputs int
+	for _, sfo := range types.GenesisUplofundAllocation {
+		if sfo.UnlockHash == sfuh {
+			sfBalance = sfBalance.Add(sfo.Value)
+			sfOutputs++
+		}
+	}
+	sfAddr := et.explorer.Address(sfuh)
+	if !sfAddr.UplofundBalance.Equals(sfBalance) || len(sfAddr.UplofundOutputs) != sfOutputs {
+		t.Fatal("genesis uplofunds weren't indexed", sfAddr.UplofundBalance, sfBalance)
+	}
+
+	// A fresh address shouldn't have any outputs.
+	uc, err := et.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uh := uc.UnlockHash()
+	addr := et.explorer.Address(uh)
+	if !addr.UplocoinBalance.IsZero() || len(addr.UplocoinOutputs) != 0 || len(et.explorer.AddressHistory(uh)) != 0 {
+		t.Fatal("fresh address has outputs", addr)
+	}
+
+	// Send coins to the address twice.
+	for i := uint64(1); i <= 2; i++ {
+		_, err = et.wallet.SendUplocoins(types.UplocoinPrecision.Mul64(i), uh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = et.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addr = et.explorer.Address(uh)
+	if !addr.UplocoinBalance.Equals(types.UplocoinPrecision.Mul64(3)) || len(addr.UplocoinOutputs) != 2 {
+		t.Fatal("wrong balance", addr.UplocoinBalance, len(addr.UplocoinOutputs))
+	}
+	for _, sco := range addr.UplocoinOutputs {
+		if out, exists := et.explorer.UplocoinOutput(sco.ID); !exists || out.Value.Cmp(sco.Value) != 0 {
+			t.Fatal("indexed output doesn't match the output in the explorer")
+		}
+	}
+	history := et.explorer.AddressHistory(uh)
+	if len(history) != 2 {
+		t.Fatal("expected 2 transactions in the history, got", len(history))
+	}
+	_, newest, _ := et.explorer.Transaction(history[0])
+	_, oldest, _ := et.explorer.Transaction(history[1])
+	if newest <= oldest {
+		t.Fatal("history isn't ordered from the most recent transaction", newest, oldest)
+	}
+
+	// Reorg the explorer onto a longer chain which doesn't contain any of
+	// the transactions of the address.
+	et2, err := createExplorerTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for et2.cs.Height() <= et.cs.Height() {
+		_, err = et2.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for h := types.BlockHeight(1); h <= et2.cs.Height(); h++ {
+		b, _ := et2.cs.BlockAtHeight(h)
+		err = et.cs.AcceptBlock(b)
+		if err != nil && !errors.Contains(err, modules.ErrNonExtendingBlock) {
+			t.Fatal(err)
+		}
+	}
+	if et.cs.CurrentBlock().ID() != et2.cs.CurrentBlock().ID() {
+		t.Fatal("reorg failed")
+	}
+	addr = et.explorer.Address(uh)
+	if !addr.UplocoinBalance.IsZero() || len(addr.UplocoinOutputs) != 0 || len(et.explorer.AddressHistory(uh)) != 0 {
+		t.Fatal("reverted outputs are still indexed", addr)
+	}
+	sfAddr = et.explorer.Address(sfuh)
+	if !sfAddr.UplofundBalance.Equals(sfBalance) {
+		t.Fatal("genesis uplofunds were lost during the reorg")
+	}
+}
@@ -48,7 +48,31 @@ func (e *Explorer) initPersist() error {
 			bucketUplofundOutputs,
 			bucketTransactionIDs,
 			bucketUnlockHashes,
+			bucketUnlockHashUplocoinOutputs,
+			bucketUnlockHashUplofundOutputs,
+			bucketHostAnnouncements,
+			bucketHostContracts,
+			bucketHostPayoutContracts,
+			bucketHostPayoutKeys,
 		}
+
+		// Databases created before one of the indices was added are rebuilt
+		// from the beginning of the blockchain to populate the index.
+		missingIndex := false
+		for _, b := range buckets {
+			missingIndex = missingIndex || tx.Bucket(b) == nil
+		}
+		if tx.Bucket(bucketInternal) != nil && missingIndex {
+			for _, b := range buckets {
+				if tx.Bucket(b) == nil {
+					continue
+				}
+				if err := tx.DeleteBucket(b); err != nil {
+					return err
+				}
+			}
+		}
+
 		for _, b := range buckets {
 			_, err := tx.CreateBucketIfNotExists(b)
 			if err != nil {
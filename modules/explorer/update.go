@@ -84,6 +84,9 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 						dbRemoveUplocoinOutputID(tx, scoid, txid)
 						dbRemoveUnlockHash(tx, sco.UnlockHash, txid)
 					}
+					if addr, ok := contractHostPayout(fc.ValidProofOutputs); ok {
+						dbRemoveHostPayoutContract(tx, addr, fcid)
+					}
 					dbRemoveFileContract(tx, fcid)
 				}
 				for _, fcr := range txn.FileContractRevisions {
@@ -100,6 +103,12 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 						dbRemoveUplocoinOutputID(tx, scoid, txid)
 						dbRemoveUnlockHash(tx, sco.UnlockHash, txid)
 					}
+					if hostKey, ok := revisionHostKey(fcr); ok {
+						dbRemoveHostContract(tx, hostKey, fcr.ParentID, txid)
+						if addr, ok := contractHostPayout(fcr.NewValidProofOutputs); ok {
+							dbRemoveHostPayoutKey(tx, addr, fcr.ParentID, txid)
+						}
+					}
 					// Remove the file contract revision from the revision chain.
 					dbRemoveFileContractRevision(tx, fcr.ParentID)
 				}
@@ -116,6 +125,11 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 					dbRemoveUplofundOutputID(tx, sfoid, txid)
 					dbRemoveUnlockHash(tx, sfo.UnlockHash, txid)
 				}
+				for k, arb := range txn.ArbitraryData {
+					if _, hostKey, err := modules.DecodeAnnouncement(arb); err == nil {
+						dbRemoveHostAnnouncement(tx, hostKey, txid, uint64(k))
+					}
+				}
 			}
 
 			// remove the associated block facts
@@ -169,7 +183,10 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 					fcid := txn.FileContractID(uint64(k))
 					dbAddFileContractID(tx, fcid, txid)
 					dbAddUnlockHash(tx, fc.UnlockHash, txid)
-					dbAddFileContract(tx, fcid, fc)
+					dbAddFileContract(tx, fcid, fc, blockheight)
+					if addr, ok := contractHostPayout(fc.ValidProofOutputs); ok {
+						dbAddHostPayoutContract(tx, addr, fcid)
+					}
 					for l, sco := range fc.ValidProofOutputs {
 						scoid := fcid.StorageProofOutputID(types.ProofValid, uint64(l))
 						dbAddUplocoinOutputID(tx, scoid, txid)
@@ -196,6 +213,12 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 						dbAddUnlockHash(tx, sco.UnlockHash, txid)
 					}
 					dbAddFileContractRevision(tx, fcr.ParentID, fcr)
+					if hostKey, ok := revisionHostKey(fcr); ok {
+						dbAddHostContract(tx, hostKey, fcr.ParentID, txid)
+						if addr, ok := contractHostPayout(fcr.NewValidProofOutputs); ok {
+							dbAddHostPayoutKey(tx, addr, hostKey, fcr.ParentID, txid)
+						}
+					}
 				}
 				for _, sp := range txn.StorageProofs {
 					dbAddFileContractID(tx, sp.ParentID, txid)
@@ -211,6 +234,17 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 					dbAddUplofundOutputID(tx, sfoid, txid)
 					dbAddUnlockHash(tx, sfo.UnlockHash, txid)
 				}
+				for k, arb := range txn.ArbitraryData {
+					netAddress, hostKey, err := modules.DecodeAnnouncement(arb)
+					if err != nil {
+						continue
+					}
+					dbAddHostAnnouncement(tx, hostKey, txid, uint64(k), modules.ExplorerHostAnnouncement{
+						Height:        blockheight,
+						TransactionID: txid,
+						NetAddress:    netAddress,
+					})
+				}
 			}
 
 			// calculate and add new block facts, if possible
@@ -220,17 +254,26 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 		}
 
-		// Update stats according to UplocoinOutputDiffs
+		// Update stats and the unspent outputs of each unlock hash according
+		// to UplocoinOutputDiffs. The diffs are ordered, so reverting a block
+		// restores the outputs that were spent by it.
 		for _, scod := range cc.UplocoinOutputDiffs {
 			if scod.Direction == modules.DiffApply {
 				dbAddUplocoinOutput(tx, scod.ID, scod.UplocoinOutput)
+				dbAddUnlockHashUplocoinOutput(tx, scod.UplocoinOutput.UnlockHash, scod.ID, scod.UplocoinOutput)
+			} else {
+				dbRemoveUnlockHashUplocoinOutput(tx, scod.UplocoinOutput.UnlockHash, scod.ID)
 			}
 		}
 
-		// Update stats according to UplofundOutputDiffs
+		// Update stats and the unspent outputs of each unlock hash according
+		// to UplofundOutputDiffs
 		for _, sfod := range cc.UplofundOutputDiffs {
 			if sfod.Direction == modules.DiffApply {
 				dbAddUplofundOutput(tx, sfod.ID, sfod.UplofundOutput)
+				dbAddUnlockHashUplofundOutput(tx, sfod.UplofundOutput.UnlockHash, sfod.ID, sfod.UplofundOutput)
+			} else {
+				dbRemoveUnlockHashUplofundOutput(tx, sfod.UplofundOutput.UnlockHash, sfod.ID)
 			}
 		}
 
@@ -331,8 +374,8 @@ func dbRemoveBlockTarget(tx *bolt.Tx, id types.BlockID, target types.Target) {
 }
 
 // Add/Remove file contract
-func dbAddFileContract(tx *bolt.Tx, id types.FileContractID, fc types.FileContract) {
-	history := fileContractHistory{Contract: fc}
+func dbAddFileContract(tx *bolt.Tx, id types.FileContractID, fc types.FileContract, height types.BlockHeight) {
+	history := fileContractHistory{Contract: fc, FormationHeight: height}
 	mustPut(tx.Bucket(bucketFileContractHistories), id, history)
 }
 func dbRemoveFileContract(tx *bolt.Tx, id types.FileContractID) {
@@ -441,6 +484,108 @@ func dbRemoveUnlockHash(tx *bolt.Tx, uh types.UnlockHash, txid types.Transaction
 	}
 }
 
+// Add/Remove unspent Uplocoin output from unlock hash bucket
+func dbAddUnlockHashUplocoinOutput(tx *bolt.Tx, uh types.UnlockHash, id types.UplocoinOutputID, output types.UplocoinOutput) {
+	b, err := tx.Bucket(bucketUnlockHashUplocoinOutputs).CreateBucketIfNotExists(encoding.Marshal(uh))
+	assertNil(err)
+	mustPut(b, id, output)
+}
+func dbRemoveUnlockHashUplocoinOutput(tx *bolt.Tx, uh types.UnlockHash, id types.UplocoinOutputID) {
+	bucket := tx.Bucket(bucketUnlockHashUplocoinOutputs).Bucket(encoding.Marshal(uh))
+	if bucket == nil {
+		return
+	}
+	mustDelete(bucket, id)
+	if bucketIsEmpty(bucket) {
+		tx.Bucket(bucketUnlockHashUplocoinOutputs).DeleteBucket(encoding.Marshal(uh))
+	}
+}
+
+// Add/Remove unspent uplofund output from unlock hash bucket
+func dbAddUnlockHashUplofundOutput(tx *bolt.Tx, uh types.UnlockHash, id types.UplofundOutputID, output types.UplofundOutput) {
+	b, err := tx.Bucket(bucketUnlockHashUplofundOutputs).CreateBucketIfNotExists(encoding.Marshal(uh))
+	assertNil(err)
+	mustPut(b, id, output)
+}
+func dbRemoveUnlockHashUplofundOutput(tx *bolt.Tx, uh types.UnlockHash, id types.UplofundOutputID) {
+	bucket := tx.Bucket(bucketUnlockHashUplofundOutputs).Bucket(encoding.Marshal(uh))
+	if bucket == nil {
+		return
+	}
+	mustDelete(bucket, id)
+	if bucketIsEmpty(bucket) {
+		tx.Bucket(bucketUnlockHashUplofundOutputs).DeleteBucket(encoding.Marshal(uh))
+	}
+}
+
+// Add/Remove host announcement
+func dbAddHostAnnouncement(tx *bolt.Tx, hostKey types.UploPublicKey, txid types.TransactionID, index uint64, ann modules.ExplorerHostAnnouncement) {
+	b, err := tx.Bucket(bucketHostAnnouncements).CreateBucketIfNotExists(encoding.Marshal(hostKey))
+	assertNil(err)
+	mustPut(b, hostAnnouncementKey{TransactionID: txid, Index: index}, ann)
+}
+func dbRemoveHostAnnouncement(tx *bolt.Tx, hostKey types.UploPublicKey, txid types.TransactionID, index uint64) {
+	bucket := tx.Bucket(bucketHostAnnouncements).Bucket(encoding.Marshal(hostKey))
+	if bucket == nil {
+		return
+	}
+	mustDelete(bucket, hostAnnouncementKey{TransactionID: txid, Index: index})
+	if bucketIsEmpty(bucket) {
+		tx.Bucket(bucketHostAnnouncements).DeleteBucket(encoding.Marshal(hostKey))
+	}
+}
+
+// Add/Remove file contract attributed to a host
+func dbAddHostContract(tx *bolt.Tx, hostKey types.UploPublicKey, fcid types.FileContractID, txid types.TransactionID) {
+	b, err := tx.Bucket(bucketHostContracts).CreateBucketIfNotExists(encoding.Marshal(hostKey))
+	assertNil(err)
+	mustPutSet(b, hostContractKey{FileContractID: fcid, TransactionID: txid})
+}
+func dbRemoveHostContract(tx *bolt.Tx, hostKey types.UploPublicKey, fcid types.FileContractID, txid types.TransactionID) {
+	bucket := tx.Bucket(bucketHostContracts).Bucket(encoding.Marshal(hostKey))
+	if bucket == nil {
+		return
+	}
+	mustDelete(bucket, hostContractKey{FileContractID: fcid, TransactionID: txid})
+	if bucketIsEmpty(bucket) {
+		tx.Bucket(bucketHostContracts).DeleteBucket(encoding.Marshal(hostKey))
+	}
+}
+
+// Add/Remove file contract formed with a host payout address
+func dbAddHostPayoutContract(tx *bolt.Tx, addr types.UnlockHash, fcid types.FileContractID) {
+	b, err := tx.Bucket(bucketHostPayoutContracts).CreateBucketIfNotExists(encoding.Marshal(addr))
+	assertNil(err)
+	mustPutSet(b, fcid)
+}
+func dbRemoveHostPayoutContract(tx *bolt.Tx, addr types.UnlockHash, fcid types.FileContractID) {
+	bucket := tx.Bucket(bucketHostPayoutContracts).Bucket(encoding.Marshal(addr))
+	if bucket == nil {
+		return
+	}
+	mustDelete(bucket, fcid)
+	if bucketIsEmpty(bucket) {
+		tx.Bucket(bucketHostPayoutContracts).DeleteBucket(encoding.Marshal(addr))
+	}
+}
+
+// Add/Remove host key revealed for a host payout address
+func dbAddHostPayoutKey(tx *bolt.Tx, addr types.UnlockHash, hostKey types.UploPublicKey, fcid types.FileContractID, txid types.TransactionID) {
+	b, err := tx.Bucket(bucketHostPayoutKeys).CreateBucketIfNotExists(encoding.Marshal(addr))
+	assertNil(err)
+	mustPut(b, hostContractKey{FileContractID: fcid, TransactionID: txid}, hostKey)
+}
+func dbRemoveHostPayoutKey(tx *bolt.Tx, addr types.UnlockHash, fcid types.FileContractID, txid types.TransactionID) {
+	bucket := tx.Bucket(bucketHostPayoutKeys).Bucket(encoding.Marshal(addr))
+	if bucket == nil {
+		return
+	}
+	mustDelete(bucket, hostContractKey{FileContractID: fcid, TransactionID: txid})
+	if bucketIsEmpty(bucket) {
+		tx.Bucket(bucketHostPayoutKeys).DeleteBucket(encoding.Marshal(addr))
+	}
+}
+
 func dbCalculateBlockFacts(tx *bolt.Tx, cs modules.ConsensusSet, block types.Block) blockFacts {
 	// get the parent block facts
 	var bf blockFacts
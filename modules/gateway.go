@@ -13,6 +13,24 @@ const (
 	GatewayDir = "gateway"
 )
 
+const (
+	// PeerOffenseInvalidBlock indicates that a peer relayed an invalid block
+	// or block header.
+	PeerOffenseInvalidBlock PeerOffense = iota
+
+	// PeerOffenseInvalidTransaction indicates that a peer relayed an invalid
+	// transaction set.
+	PeerOffenseInvalidTransaction
+
+	// PeerOffenseProtocolViolation indicates that a peer didn't follow the
+	// protocol of an RPC.
+	PeerOffenseProtocolViolation
+
+	// PeerOffenseRPCTimeout indicates that a peer didn't respond to an RPC in
+	// time.
+	PeerOffenseRPCTimeout
+)
+
 var (
 	// BootstrapPeers is a list of peers that can be used to find other peers -
 	// when a client first connects to the network, the only options for
@@ -35,12 +53,32 @@ var (
 )
 
 type (
+	// PeerOffense is a type of misbehavior of a peer which increases its
+	// misbehavior score.
+	PeerOffense int
+
+	// PeerReputation contains the misbehavior score and bans of a peer's IP
+	// address. A peer is banned temporarily once its score reaches the ban
+	// threshold, and the duration of the ban doubles with every ban.
+	PeerReputation struct {
+		Address     string    `json:"address"`
+		Score       int64     `json:"score"`
+		Bans        uint64    `json:"bans"`
+		BannedUntil time.Time `json:"banneduntil"`
+	}
+
 	// Peer contains all the info necessary to Broadcast to a peer.
 	Peer struct {
 		Inbound    bool       `json:"inbound"`
 		Local      bool       `json:"local"`
 		NetAddress NetAddress `json:"netaddress"`
 		Version    string     `json:"version"`
+
+		// Encrypted indicates whether the connection to the peer uses the
+		// encrypted transport. EncryptionRequired indicates whether the peer
+		// refuses unencrypted connections.
+		Encrypted          bool `json:"encrypted"`
+		EncryptionRequired bool `json:"encryptionrequired"`
 	}
 
 	// A PeerConn is the connection type used when communicating with peers during
@@ -66,6 +104,7 @@ type (
 	// with the "network" consensus set.
 	Gateway interface {
 		Alerter
+		EventSubscriber
 
 		// BandwidthCounters returns the Gateway's upload and download bandwidth
 		BandwidthCounters() (uint64, uint64, time.Time, error)
@@ -117,9 +156,31 @@ type (
 		// supply the given RPC ID.
 		RegisterRPC(string, RPCFunc)
 
+		// PeerReputations returns the misbehavior scores and bans of the peers
+		// that misbehaved recently.
+		PeerReputations() []PeerReputation
+
 		// RateLimits returns the currently set bandwidth limits of the gateway.
 		RateLimits() (int64, int64)
 
+		// ReportPeer increases the misbehavior score of a peer. If the score
+		// reaches the ban threshold, the peer is disconnected and banned
+		// temporarily.
+		ReportPeer(NetAddress, PeerOffense)
+
+		// RequireEncryption returns whether the gateway refuses peer
+		// connections which don't use the encrypted transport.
+		RequireEncryption() bool
+
+		// ForgetNodeKey forgets the node key a node authenticated with, so
+		// the next connection to the node isn't required to use the same
+		// node key.
+		ForgetNodeKey(NetAddress) error
+
+		// SetRequireEncryption sets whether the gateway refuses peer
+		// connections which don't use the encrypted transport.
+		SetRequireEncryption(bool) error
+
 		// SetRateLimits changes the rate limits for the peer-connections of the
 		// gateway.
 		SetRateLimits(downloadSpeed, uploadSpeed int64) error
@@ -156,3 +217,19 @@ type (
 		Close() error
 	}
 )
+
+// String returns the human-readable name of the offense.
+func (po PeerOffense) String() string {
+	switch po {
+	case PeerOffenseInvalidBlock:
+		return "invalid block"
+	case PeerOffenseInvalidTransaction:
+		return "invalid transaction"
+	case PeerOffenseProtocolViolation:
+		return "protocol violation"
+	case PeerOffenseRPCTimeout:
+		return "rpc timeout"
+	default:
+		return "unknown offense"
+	}
+}
@@ -6,3 +6,15 @@ import "github.com/uplo-tech/uplo/modules"
 func (g *Gateway) Alerts() (crit, err, warn []modules.Alert) {
 	return g.staticAlerter.Alerts()
 }
+
+// SubscribeEvents implements the modules.EventSubscriber interface for the
+// gateway.
+func (g *Gateway) SubscribeEvents(l modules.EventListener) {
+	g.staticAlerter.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents implements the modules.EventSubscriber interface for the
+// gateway.
+func (g *Gateway) UnsubscribeEvents(l modules.EventListener) {
+	g.staticAlerter.UnsubscribeEvents(l)
+}
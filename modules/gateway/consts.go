@@ -265,3 +265,43 @@ var (
 		Testing:  100 * time.Millisecond,
 	}).(time.Duration)
 )
+
+// Constants related to peer reputations.
+var (
+	// banScore is the misbehavior score at which a peer is banned.
+	banScore = int64(100)
+
+	// offensePenalties are the amounts by which the misbehavior score of a
+	// peer is increased for each type of offense. Peers are only reported for
+	// transactions which are provably invalid, e.g. because of a bad
+	// signature.
+	offensePenalties = map[modules.PeerOffense]int64{
+		modules.PeerOffenseInvalidBlock:       50,
+		modules.PeerOffenseInvalidTransaction: 10,
+		modules.PeerOffenseProtocolViolation:  25,
+		modules.PeerOffenseRPCTimeout:         5,
+	}
+
+	// baseBanDuration is the duration of the first ban of a peer. Every
+	// further ban doubles the duration up to maxBanDuration.
+	baseBanDuration = build.Select(build.Var{
+		Standard: 10 * time.Minute,
+		Dev:      time.Minute,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// maxBanDuration is the maximum duration of a ban.
+	maxBanDuration = build.Select(build.Var{
+		Standard: 7 * 24 * time.Hour,
+		Dev:      time.Hour,
+		Testing:  time.Minute,
+	}).(time.Duration)
+
+	// scoreDecayInterval is the interval at which the misbehavior score of a
+	// peer is decreased by one.
+	scoreDecayInterval = build.Select(build.Var{
+		Standard: time.Minute,
+		Dev:      10 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+)
@@ -0,0 +1,367 @@
+package gateway
+
+// encrypt.go implements the optional encrypted transport of peer connections.
+// Peers which support it append an encryptionHeader to their sessionHeader.
+// Older peers ignore the trailing bytes of the header. If both peers support
+// encryption, they derive a shared secret from ephemeral X25519 keys and wrap
+// the connection in an encryptedConn before the stream multiplexer is created.
+//
+// The ephemeral keys are bound to the long-term node key of each gateway:
+// once the connection is encrypted, both peers sign the transcript of the
+// encryption headers with their node key. The node key and the fact that a
+// node supports encryption are remembered per node address, so that later
+// connections to the same node are refused if the node key changed or the
+// encryption header was stripped. The first connection to a node is trusted,
+// since there is no other source for its node key.
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+const (
+	// encryptionDisabled is the encryption mode of peers which don't support
+	// the encrypted transport.
+	encryptionDisabled uint8 = iota
+
+	// encryptionSupported is the encryption mode of peers which encrypt the
+	// connection if the other peer supports it.
+	encryptionSupported
+
+	// encryptionRequired is the encryption mode of peers which refuse
+	// unencrypted connections.
+	encryptionRequired
+)
+
+const (
+	// encodedEncryptionHeaderSize is the size of an encoded encryptionHeader.
+	encodedEncryptionHeaderSize = 1 + 32 + crypto.PublicKeySize
+
+	// maxEncryptedFrameSize is the maximum size of the plaintext of a single
+	// frame of an encryptedConn.
+	maxEncryptedFrameSize = 1 << 16
+)
+
+var (
+	// errEncryptionRequired is returned if a peer which doesn't support the
+	// encrypted transport connects to a gateway that requires it.
+	errEncryptionRequired = errors.New("encrypted connection required")
+
+	// errEncryptionDowngrade is returned if a node which supported the
+	// encrypted transport before connects without it.
+	errEncryptionDowngrade = errors.New("peer supported encryption before but connected without it")
+
+	// errNodeKeyMismatch is returned if a node connects with a different node
+	// key than it used before.
+	errNodeKeyMismatch = errors.New("peer's node key doesn't match its known node key")
+
+	// errNodeNotFound is returned if the node key of an unknown node is
+	// forgotten.
+	errNodeNotFound = errors.New("no record of that node")
+
+	// specifierInitiatorKey and specifierResponderKey are used to derive the
+	// keys for both directions of an encrypted connection.
+	specifierInitiatorKey = types.NewSpecifier("InitiatorKey")
+	specifierResponderKey = types.NewSpecifier("ResponderKey")
+
+	// specifierInitiatorSig and specifierResponderSig are used to sign the
+	// handshake transcript, so that the signature of one peer can't be
+	// reflected as the signature of the other.
+	specifierInitiatorSig = types.NewSpecifier("InitiatorSig")
+	specifierResponderSig = types.NewSpecifier("ResponderSig")
+)
+
+type (
+	// encryptionHeader is appended to the sessionHeader by peers which
+	// support the encrypted transport. PublicKey is the ephemeral key of the
+	// connection and NodeKey the long-term key of the gateway.
+	encryptionHeader struct {
+		Mode      uint8
+		PublicKey crypto.X25519PublicKey
+		NodeKey   crypto.PublicKey
+	}
+
+	// encryptedConn is a net.Conn which encrypts and authenticates all data
+	// using ChaCha20-Poly1305. Every frame is prefixed with its length and
+	// uses a counter as the nonce, so that frames can't be reordered or
+	// replayed.
+	encryptedConn struct {
+		net.Conn
+
+		readAEAD  cipher.AEAD
+		readBuf   []byte
+		readMu    sync.Mutex
+		readNonce uint64
+
+		writeAEAD  cipher.AEAD
+		writeMu    sync.Mutex
+		writeNonce uint64
+	}
+)
+
+// newEncryptedConn wraps conn in an encryptedConn. The keys for both
+// directions are derived from the shared secret of the ephemeral keys.
+func newEncryptedConn(conn net.Conn, xsk crypto.X25519SecretKey, remoteKey crypto.X25519PublicKey, initiator bool) (*encryptedConn, error) {
+	secret := crypto.DeriveSharedSecret(xsk, remoteKey)
+	writeKey := crypto.HashAll(secret, specifierInitiatorKey)
+	readKey := crypto.HashAll(secret, specifierResponderKey)
+	crypto.SecureWipe(secret[:])
+	if !initiator {
+		writeKey, readKey = readKey, writeKey
+	}
+	writeAEAD, err := chacha20poly1305.New(writeKey[:])
+	if err != nil {
+		return nil, err
+	}
+	readAEAD, err := chacha20poly1305.New(readKey[:])
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedConn{
+		Conn:      conn,
+		readAEAD:  readAEAD,
+		writeAEAD: writeAEAD,
+	}, nil
+}
+
+// frameNonce returns the nonce of the frame with the given index.
+func frameNonce(aead cipher.AEAD, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.LittleEndian.PutUint64(nonce, index)
+	return nonce
+}
+
+// Read implements net.Conn.
+func (ec *encryptedConn) Read(p []byte) (int, error) {
+	ec.readMu.Lock()
+	defer ec.readMu.Unlock()
+	if len(ec.readBuf) == 0 {
+		ciphertext, err := encoding.ReadPrefixedBytes(ec.Conn, maxEncryptedFrameSize+uint64(ec.readAEAD.Overhead()))
+		if err != nil {
+			return 0, err
+		}
+		plaintext, err := ec.readAEAD.Open(ciphertext[:0], frameNonce(ec.readAEAD, ec.readNonce), ciphertext, nil)
+		if err != nil {
+			return 0, errors.AddContext(err, "unable to decrypt frame")
+		}
+		ec.readNonce++
+		ec.readBuf = plaintext
+	}
+	n := copy(p, ec.readBuf)
+	ec.readBuf = ec.readBuf[n:]
+	return n, nil
+}
+
+// Write implements net.Conn.
+func (ec *encryptedConn) Write(p []byte) (int, error) {
+	ec.writeMu.Lock()
+	defer ec.writeMu.Unlock()
+	var written int
+	for len(p) > 0 {
+		frame := p
+		if len(frame) > maxEncryptedFrameSize {
+			frame = frame[:maxEncryptedFrameSize]
+		}
+		ciphertext := ec.writeAEAD.Seal(nil, frameNonce(ec.writeAEAD, ec.writeNonce), frame, nil)
+		if err := encoding.WritePrefixedBytes(ec.Conn, ciphertext); err != nil {
+			return written, err
+		}
+		ec.writeNonce++
+		written += len(frame)
+		p = p[len(frame):]
+	}
+	return written, nil
+}
+
+// exchangeOurEncryptedHeader writes ourHeader followed by encHeader and reads
+// the remote's error response.
+func exchangeOurEncryptedHeader(conn net.Conn, ourHeader sessionHeader, encHeader encryptionHeader) error {
+	// Send our header.
+	if err := encoding.WritePrefixedBytes(conn, encoding.MarshalAll(ourHeader, encHeader)); err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
+	}
+	return readHeaderResponse(conn)
+}
+
+// exchangeRemoteEncryptedHeader reads the remote header and the optional
+// encryptionHeader that follows it and writes an error response. If the remote
+// peer doesn't support encryption, the returned encryptionHeader has the mode
+// encryptionDisabled.
+func exchangeRemoteEncryptedHeader(conn net.Conn, ourHeader sessionHeader, ourMode uint8) (sessionHeader, encryptionHeader, error) {
+	// Read remote header.
+	b, err := encoding.ReadPrefixedBytes(conn, maxEncodedSessionHeaderSize+encodedEncryptionHeaderSize)
+	if err != nil {
+		return sessionHeader{}, encryptionHeader{}, fmt.Errorf("failed to read remote header: %v", err)
+	}
+	r := bytes.NewReader(b)
+	dec := encoding.NewDecoder(r, len(b)*3)
+	var remoteHeader sessionHeader
+	var encHeader encryptionHeader
+	err = dec.Decode(&remoteHeader)
+	if err == nil && r.Len() > 0 {
+		err = dec.Decode(&encHeader)
+	}
+	if err != nil {
+		return sessionHeader{}, encryptionHeader{}, fmt.Errorf("failed to decode remote header: %v", err)
+	}
+
+	// Validate remote header and write acceptance or rejection.
+	err = acceptableSessionHeader(ourHeader, remoteHeader, conn.RemoteAddr().String())
+	if err == nil {
+		err = acceptableEncryption(ourMode, encHeader.Mode)
+	}
+	if err := writeHeaderResponse(conn, err); err != nil {
+		return sessionHeader{}, encryptionHeader{}, err
+	}
+	return remoteHeader, encHeader, nil
+}
+
+// acceptableEncryption returns an error if the encryption modes of the peers
+// are incompatible.
+func acceptableEncryption(ourMode, remoteMode uint8) error {
+	if ourMode == encryptionRequired && remoteMode == encryptionDisabled {
+		return errEncryptionRequired
+	}
+	return nil
+}
+
+// staticEncryptConn wraps conn in an encryptedConn if the remote peer supports
+// the encrypted transport and authenticates the remote peer with its node key.
+// Otherwise conn is returned unchanged.
+func staticEncryptConn(conn net.Conn, xsk crypto.X25519SecretKey, sk crypto.SecretKey, ours, remote encryptionHeader, initiator bool) (net.Conn, error) {
+	if remote.Mode == encryptionDisabled {
+		return conn, nil
+	}
+	ec, err := newEncryptedConn(conn, xsk, remote.PublicKey, initiator)
+	if err != nil {
+		return nil, err
+	}
+	if err := staticAuthenticateConn(ec, sk, ours, remote, initiator); err != nil {
+		return nil, errors.AddContext(err, "unable to authenticate peer")
+	}
+	return ec, nil
+}
+
+// staticAuthenticateConn exchanges the signatures of the handshake transcript
+// over the encrypted connection. The initiator sends its signature first. An
+// error is returned if the signature of the remote peer doesn't match its
+// node key.
+func staticAuthenticateConn(conn net.Conn, sk crypto.SecretKey, ours, remote encryptionHeader, initiator bool) error {
+	initiatorHeader, responderHeader := ours, remote
+	ourSpecifier, remoteSpecifier := specifierInitiatorSig, specifierResponderSig
+	if !initiator {
+		initiatorHeader, responderHeader = remote, ours
+		ourSpecifier, remoteSpecifier = remoteSpecifier, ourSpecifier
+	}
+	transcript := crypto.HashAll(initiatorHeader, responderHeader)
+
+	writeSig := func() error {
+		sig := crypto.SignHash(crypto.HashAll(ourSpecifier, transcript), sk)
+		return encoding.WriteObject(conn, sig)
+	}
+	readSig := func() error {
+		var sig crypto.Signature
+		if err := encoding.ReadObject(conn, &sig, crypto.SignatureSize); err != nil {
+			return err
+		}
+		return crypto.VerifyHash(crypto.HashAll(remoteSpecifier, transcript), remote.NodeKey, sig)
+	}
+	if initiator {
+		if err := writeSig(); err != nil {
+			return err
+		}
+		return readSig()
+	}
+	if err := readSig(); err != nil {
+		return err
+	}
+	return writeSig()
+}
+
+// verifyNodeEncryption returns an error if the node at addr authenticated
+// with its node key before but now connects without encryption or with a
+// different node key.
+func (g *Gateway) verifyNodeEncryption(addr modules.NetAddress, remote encryptionHeader) error {
+	n, exists := g.nodes[addr]
+	if !exists || !n.Encrypted {
+		return nil
+	}
+	if remote.Mode == encryptionDisabled {
+		return errEncryptionDowngrade
+	}
+	if n.NodeKey == nil || remote.NodeKey != *n.NodeKey {
+		return errNodeKeyMismatch
+	}
+	return nil
+}
+
+// rememberNodeEncryption records that the node at addr supports encryption
+// and the node key it authenticated with.
+func (g *Gateway) rememberNodeEncryption(addr modules.NetAddress, remote encryptionHeader) {
+	n, exists := g.nodes[addr]
+	if !exists || remote.Mode == encryptionDisabled {
+		return
+	}
+	nodeKey := remote.NodeKey
+	n.Encrypted = true
+	n.NodeKey = &nodeKey
+}
+
+// ForgetNodeKey forgets the node key the node at addr authenticated with, so
+// the next connection to the node may use a different node key or no
+// encryption at all. It's needed if a node legitimately replaced its node key,
+// e.g. because it lost its gateway directory. Existing connections are not
+// affected.
+func (g *Gateway) ForgetNodeKey(addr modules.NetAddress) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n, exists := g.nodes[addr]
+	if !exists {
+		return errNodeNotFound
+	}
+	n.Encrypted = false
+	n.NodeKey = nil
+	return g.saveSyncNodes()
+}
+
+// encryptionMode returns the encryption mode of the gateway.
+func (g *Gateway) encryptionMode() uint8 {
+	if g.persist.RequireEncryption {
+		return encryptionRequired
+	}
+	return encryptionSupported
+}
+
+// RequireEncryption returns whether the gateway refuses unencrypted peer
+// connections.
+func (g *Gateway) RequireEncryption() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.persist.RequireEncryption
+}
+
+// SetRequireEncryption sets whether the gateway refuses unencrypted peer
+// connections. Existing connections are not affected.
+func (g *Gateway) SetRequireEncryption(require bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.persist.RequireEncryption = require
+	return g.saveSync()
+}
@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/fastrand"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestEncryptedConn checks that data written to one end of an encryptedConn
+// can be read from the other end, and that tampered frames are rejected.
+func TestEncryptedConn(t *testing.T) {
+	c1, c2 := net.Pipe()
+	xsk1, xpk1 := crypto.GenerateX25519KeyPair()
+	xsk2, xpk2 := crypto.GenerateX25519KeyPair()
+	ec1, err := newEncryptedConn(c1, xsk1, xpk2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec2, err := newEncryptedConn(c2, xsk2, xpk1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Send data that spans multiple frames in both directions.
+	data := fastrand.Bytes(maxEncryptedFrameSize*2 + 100)
+	go func() {
+		if _, err := ec1.Write(data); err != nil {
+			t.Error(err)
+		}
+	}()
+	received := make([]byte, len(data))
+	if _, err := io.ReadFull(ec2, received); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, received) {
+		t.Fatal("received data doesn't match sent data")
+	}
+	go func() {
+		if _, err := ec2.Write(data[:100]); err != nil {
+			t.Error(err)
+		}
+	}()
+	if _, err := io.ReadFull(ec1, received[:100]); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[:100], received[:100]) {
+		t.Fatal("received data doesn't match sent data")
+	}
+
+	// A tampered frame should be rejected.
+	go func() {
+		ciphertext := ec1.writeAEAD.Seal(nil, frameNonce(ec1.writeAEAD, ec1.writeNonce), data[:100], nil)
+		ciphertext[0] ^= 1
+		encoding.WritePrefixedBytes(c1, ciphertext)
+	}()
+	if _, err := ec2.Read(received); err == nil {
+		t.Fatal("tampered frame was accepted")
+	}
+}
+
+// TestEncryptedConnect checks that gateways encrypt their connections and that
+// gateways requiring encryption refuse peers which don't support it.
+func TestEncryptedConnect(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer func() {
+		if err := g1.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	g2 := newNamedTestingGateway(t, "2")
+	defer func() {
+		if err := g2.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if err := g1.SetRequireEncryption(true); err != nil {
+		t.Fatal(err)
+	}
+	if !g1.RequireEncryption() {
+		t.Fatal("encryption should be required")
+	}
+
+	// Connect the gateways. Both ends of the connection should be encrypted.
+	if err := g2.Connect(g1.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		peers := g1.Peers()
+		if len(peers) != 1 || !peers[0].Encrypted || peers[0].EncryptionRequired {
+			return errors.New("g1 should have an encrypted peer")
+		}
+		peers = g2.Peers()
+		if len(peers) != 1 || !peers[0].Encrypted || !peers[0].EncryptionRequired {
+			return errors.New("g2 should have an encrypted peer which requires encryption")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// g2 should remember the node key of g1 and refuse connections to g1's
+	// address which are unencrypted or use a different node key.
+	g2.mu.RLock()
+	n, exists := g2.nodes[g1.Address()]
+	var nodeKey crypto.PublicKey
+	if exists && n.NodeKey != nil {
+		nodeKey = *n.NodeKey
+	}
+	encrypted := exists && n.Encrypted
+	verifyErrs := []error{
+		g2.verifyNodeEncryption(g1.Address(), encryptionHeader{Mode: encryptionSupported, NodeKey: nodeKey}),
+		g2.verifyNodeEncryption(g1.Address(), encryptionHeader{Mode: encryptionDisabled}),
+		g2.verifyNodeEncryption(g1.Address(), encryptionHeader{Mode: encryptionSupported}),
+	}
+	g2.mu.RUnlock()
+	if !encrypted || nodeKey != g1.staticNodeKey.PublicKey() {
+		t.Fatal("g2 didn't remember the node key of g1")
+	}
+	if verifyErrs[0] != nil {
+		t.Fatal("known node key was refused", verifyErrs[0])
+	}
+	if !errors.Contains(verifyErrs[1], errEncryptionDowngrade) {
+		t.Fatal("expected errEncryptionDowngrade", verifyErrs[1])
+	}
+	if !errors.Contains(verifyErrs[2], errNodeKeyMismatch) {
+		t.Fatal("expected errNodeKeyMismatch", verifyErrs[2])
+	}
+
+	// Once g2 forgets the node key of g1, a different node key should be
+	// accepted.
+	if err := g2.ForgetNodeKey(g1.Address()); err != nil {
+		t.Fatal(err)
+	}
+	g2.mu.RLock()
+	err = g2.verifyNodeEncryption(g1.Address(), encryptionHeader{Mode: encryptionSupported})
+	g2.mu.RUnlock()
+	if err != nil {
+		t.Fatal("node key wasn't forgotten", err)
+	}
+	if err := g2.ForgetNodeKey("1.2.3.4:5"); !errors.Contains(err, errNodeNotFound) {
+		t.Fatal("expected errNodeNotFound", err)
+	}
+
+	// RPCs should work over the encrypted connection.
+	g1.RegisterRPC("Foo", func(conn modules.PeerConn) error {
+		return encoding.WriteObject(conn, "foo")
+	})
+	var response string
+	err = g2.RPC(g1.Address(), "Foo", func(conn modules.PeerConn) error {
+		return encoding.ReadObject(conn, &response, 100)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "foo" {
+		t.Fatal("unexpected response", response)
+	}
+
+	// A peer which doesn't support encryption should be refused.
+	conn, err := net.Dial("tcp", string(g1.Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := connectVersionHandshake(conn, build.Version); err != nil {
+		t.Fatal(err)
+	}
+	header := sessionHeader{
+		GenesisID:  types.GenesisID,
+		UniqueID:   gatewayID{},
+		NetAddress: modules.NetAddress(conn.LocalAddr().String()),
+	}
+	err = exchangeOurHeader(conn, header)
+	if err == nil || !strings.Contains(err.Error(), errEncryptionRequired.Error()) {
+		t.Fatal("expected errEncryptionRequired", err)
+	}
+}
+
+// TestAuthenticateConn checks that peers sign the handshake transcript with
+// their node keys and that a signature of the wrong node key is rejected.
+func TestAuthenticateConn(t *testing.T) {
+	sk1, pk1 := crypto.GenerateKeyPair()
+	sk2, pk2 := crypto.GenerateKeyPair()
+	_, xpk1 := crypto.GenerateX25519KeyPair()
+	_, xpk2 := crypto.GenerateX25519KeyPair()
+	h1 := encryptionHeader{Mode: encryptionSupported, PublicKey: xpk1, NodeKey: pk1}
+	h2 := encryptionHeader{Mode: encryptionSupported, PublicKey: xpk2, NodeKey: pk2}
+
+	authenticate := func(sk2 crypto.SecretKey, remote2 encryptionHeader) (err1, err2 error) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+		done := make(chan struct{})
+		go func() {
+			err2 = staticAuthenticateConn(c2, sk2, h2, h1, false)
+			if err2 != nil {
+				c2.Close()
+			}
+			close(done)
+		}()
+		err1 = staticAuthenticateConn(c1, sk1, h1, remote2, true)
+		if err1 != nil {
+			c1.Close()
+		}
+		<-done
+		return
+	}
+
+	// Both peers should accept the other's signature.
+	if err1, err2 := authenticate(sk2, h2); err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+
+	// A peer which doesn't own the node key in its header should be
+	// rejected.
+	sk3, _ := crypto.GenerateKeyPair()
+	if err1, _ := authenticate(sk3, h2); err1 == nil {
+		t.Fatal("signature of the wrong node key was accepted")
+	}
+
+	// A header that was tampered with changes the transcript, so the
+	// signatures don't match.
+	tampered := h2
+	tampered.PublicKey[0] ^= 1
+	if err1, _ := authenticate(sk2, tampered); err1 == nil {
+		t.Fatal("signature of a tampered transcript was accepted")
+	}
+}
@@ -70,9 +70,6 @@ package gateway
 // peers of the same IP address, it should favor kicking peers of the same ip
 // address range.
 //
-// TODO: There is no public key exchange, so communications cannot be
-// effectively encrypted or authenticated.
-//
 // TODO: Gateway hostname discovery currently has significant centralization,
 // namely the fallback is a single third-party website that can easily form any
 // response it wants. Instead, multiple TLS-protected third party websites
@@ -85,11 +82,13 @@ package gateway
 // hostname, which means they will not be able to dial you back, which means
 // they will not add you to their node list.
 //
-// TODO: The gateway should encrypt and authenticate all communications. Though
-// the gateway participates in a flood network, practical attacks have been
-// demonstrated which have been able to confuse nodes by manipulating messages
-// from their peers. Encryption + authentication would have made the attack
-// more difficult.
+// TODO: Connections to peers that support the encrypted transport are
+// encrypted and authenticated with the node key of the peer, but the node key
+// of a peer is trusted on the first connection. Though the gateway
+// participates in a flood network, practical attacks have been demonstrated
+// which have been able to confuse nodes by manipulating messages from their
+// peers. Requiring encryption for all peers would make such attacks more
+// difficult.
 
 import (
 	"fmt"
@@ -102,6 +101,7 @@ import (
 	"github.com/uplo-tech/ratelimit"
 	"github.com/uplo-tech/threadgroup"
 
+	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/persist"
 
@@ -132,6 +132,9 @@ type Gateway struct {
 	//
 	// peers are the nodes that the gateway is currently connected to.
 	//
+	// reputations are the misbehavior scores and bans of the IP addresses of
+	// peers that misbehaved recently.
+	//
 	// peerTG is a special thread group for tracking peer connections, and will
 	// block shutdown until all peer connections have been closed out. The peer
 	// connections are put in a separate TG because of their unique
@@ -143,15 +146,17 @@ type Gateway struct {
 	// and would block any threads.Flush() calls. So a second threadgroup is
 	// added which handles clean-shutdown for the peers, without blocking
 	// threads.Flush() calls.
-	blocklist map[string]struct{}
-	nodes     map[modules.NetAddress]*node
-	peers     map[modules.NetAddress]*peer
-	peerTG    threadgroup.ThreadGroup
+	blocklist   map[string]struct{}
+	nodes       map[modules.NetAddress]*node
+	peers       map[modules.NetAddress]*peer
+	reputations map[string]*peerReputation
+	peerTG      threadgroup.ThreadGroup
 
 	// Utilities.
 	log           *persist.Logger
 	mu            sync.RWMutex
 	persist       persistence
+	persistMu     sync.Mutex
 	persistDir    string
 	threads       threadgroup.ThreadGroup
 	staticAlerter *modules.GenericAlerter
@@ -159,6 +164,10 @@ type Gateway struct {
 
 	// Unique ID
 	staticID gatewayID
+
+	// staticNodeKey is the long-term key that authenticates the encrypted
+	// transport of the gateway.
+	staticNodeKey crypto.SecretKey
 }
 
 type gatewayID [8]byte
@@ -371,9 +380,10 @@ func NewCustomGateway(addr string, bootstrap bool, persistDir string, deps modul
 		handlers: make(map[rpcID]modules.RPCFunc),
 		initRPCs: make(map[string]modules.RPCFunc),
 
-		blocklist: make(map[string]struct{}),
-		nodes:     make(map[modules.NetAddress]*node),
-		peers:     make(map[modules.NetAddress]*peer),
+		blocklist:   make(map[string]struct{}),
+		nodes:       make(map[modules.NetAddress]*node),
+		peers:       make(map[modules.NetAddress]*peer),
+		reputations: make(map[string]*peerReputation),
 
 		persistDir:    persistDir,
 		staticAlerter: modules.NewAlerter("gateway"),
@@ -429,6 +439,14 @@ func NewCustomGateway(addr string, bootstrap bool, persistDir string, deps modul
 	if loadErr := g.load(); loadErr != nil && !os.IsNotExist(loadErr) {
 		return nil, errors.AddContext(loadErr, "unable to load gateway")
 	}
+	// Generate the node key if the gateway doesn't have one yet.
+	if g.persist.NodeKey == (crypto.SecretKey{}) {
+		g.persist.NodeKey, _ = crypto.GenerateKeyPair()
+		if err := g.saveSync(); err != nil {
+			return nil, errors.AddContext(err, "unable to save gateway node key")
+		}
+	}
+	g.staticNodeKey = g.persist.NodeKey
 	// Create the ratelimiter and set it to the persisted limits.
 	g.rl = ratelimit.NewRateLimit(0, 0, 0)
 	if err := setRateLimits(g.rl, g.persist.MaxDownloadSpeed, g.persist.MaxUploadSpeed); err != nil {
@@ -9,6 +9,7 @@ import (
 	"github.com/uplo-tech/fastrand"
 
 	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/types"
 	"github.com/uplo-tech/encoding"
@@ -25,6 +26,12 @@ var (
 type node struct {
 	NetAddress      modules.NetAddress `json:"netaddress"`
 	WasOutboundPeer bool               `json:"wasoutboundpeer"`
+
+	// Encrypted indicates that the node authenticated an encrypted
+	// connection with NodeKey before. Later connections to the node must
+	// be encrypted and authenticated with the same key.
+	Encrypted bool              `json:"encrypted,omitempty"`
+	NodeKey   *crypto.PublicKey `json:"nodekey,omitempty"`
 }
 
 // addNode adds an address to the set of nodes on the network.
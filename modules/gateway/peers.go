@@ -11,6 +11,7 @@ import (
 	"github.com/uplo-tech/ratelimit"
 
 	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/types"
 	"github.com/uplo-tech/encoding"
@@ -165,6 +166,14 @@ func (g *Gateway) threadedAcceptConn(conn net.Conn) {
 		conn.Close()
 		return
 	}
+	g.mu.RLock()
+	banned := g.isBanned(addr)
+	g.mu.RUnlock()
+	if banned {
+		g.log.Debugf("INFO: %v was rejected. (banned)", addr)
+		conn.Close()
+		return
+	}
 	remoteVersion, err := acceptVersionHandshake(conn, build.Version)
 	if err != nil {
 		g.log.Debugf("INFO: %v wanted to connect but version handshake failed: %v", addr, err)
@@ -213,14 +222,26 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 		NetAddress: g.myAddr,
 	}
 	rl := g.rl
+	ourMode := g.encryptionMode()
 	g.mu.RUnlock()
+	xsk, xpk := crypto.GenerateX25519KeyPair()
+	ourEncHeader := encryptionHeader{
+		Mode:      ourMode,
+		PublicKey: xpk,
+		NodeKey:   g.staticNodeKey.PublicKey(),
+	}
 
-	remoteHeader, err := exchangeRemoteHeader(conn, ourHeader)
+	remoteHeader, remoteEncHeader, err := exchangeRemoteEncryptedHeader(conn, ourHeader, ourMode)
 	if err != nil {
 		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), err)
 		return err
 	}
-	if err := exchangeOurHeader(conn, ourHeader); err != nil {
+	if err := exchangeOurEncryptedHeader(conn, ourHeader, ourEncHeader); err != nil {
+		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), err)
+		return err
+	}
+	sessConn, err := staticEncryptConn(conn, xsk, g.staticNodeKey, ourEncHeader, remoteEncHeader, false)
+	if err != nil {
 		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), err)
 		return err
 	}
@@ -233,6 +254,15 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	remoteAddr := modules.NetAddress(net.JoinHostPort(remoteIP, remotePort))
 	g.log.Debugln("Making connection with remote peer", remoteAddr)
 
+	// Refuse the peer if it authenticated differently before.
+	g.mu.RLock()
+	err = g.verifyNodeEncryption(remoteAddr, remoteEncHeader)
+	g.mu.RUnlock()
+	if err != nil {
+		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), err)
+		return err
+	}
+
 	// Accept the peer.
 	peer := &peer{
 		Peer: modules.Peer{
@@ -242,12 +272,14 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 			Local: remoteAddr.IsLocal(),
 			// Ignoring claimed IP address (which should be == to the socket address)
 			// by the host but keeping note of the port number so we can call back
-			NetAddress: remoteAddr,
-			Version:    remoteVersion,
+			NetAddress:         remoteAddr,
+			Version:            remoteVersion,
+			Encrypted:          remoteEncHeader.Mode != encryptionDisabled,
+			EncryptionRequired: remoteEncHeader.Mode == encryptionRequired,
 		},
 		m:    g.m,
 		rl:   rl,
-		sess: newServerStream(conn, remoteVersion),
+		sess: newServerStream(sessConn, remoteVersion),
 	}
 	g.mu.Lock()
 	g.acceptPeer(peer)
@@ -262,6 +294,7 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 		if err == nil {
 			g.mu.Lock()
 			g.addNode(remoteAddr)
+			g.rememberNodeEncryption(remoteAddr, remoteEncHeader)
 			g.mu.Unlock()
 		}
 	}()
@@ -374,8 +407,11 @@ func exchangeOurHeader(conn net.Conn, ourHeader sessionHeader) error {
 	if err := encoding.WriteObject(conn, ourHeader); err != nil {
 		return fmt.Errorf("failed to write header: %v", err)
 	}
+	return readHeaderResponse(conn)
+}
 
-	// Read remote response.
+// readHeaderResponse reads the remote's response to our header.
+func readHeaderResponse(conn net.Conn) error {
 	var response string
 	if err := encoding.ReadObject(conn, &response, 100); err != nil {
 		return fmt.Errorf("failed to read header acceptance: %v", err)
@@ -397,19 +433,28 @@ func exchangeRemoteHeader(conn net.Conn, ourHeader sessionHeader) (sessionHeader
 
 	// Validate remote header and write acceptance or rejection.
 	err := acceptableSessionHeader(ourHeader, remoteHeader, conn.RemoteAddr().String())
-	if err != nil {
-		encoding.WriteObject(conn, err.Error()) // error can be ignored
-		return sessionHeader{}, fmt.Errorf("peer's header was not acceptable: %v", err)
-	} else if err := encoding.WriteObject(conn, modules.AcceptResponse); err != nil {
-		return sessionHeader{}, fmt.Errorf("failed to write header acceptance: %v", err)
+	if err := writeHeaderResponse(conn, err); err != nil {
+		return sessionHeader{}, err
 	}
-
 	return remoteHeader, nil
 }
 
+// writeHeaderResponse writes the acceptance of the remote header, or the
+// rejection if headerErr is not nil.
+func writeHeaderResponse(conn net.Conn, headerErr error) error {
+	if headerErr != nil {
+		encoding.WriteObject(conn, headerErr.Error()) // error can be ignored
+		return fmt.Errorf("peer's header was not acceptable: %v", headerErr)
+	} else if err := encoding.WriteObject(conn, modules.AcceptResponse); err != nil {
+		return fmt.Errorf("failed to write header acceptance: %v", err)
+	}
+	return nil
+}
+
 // managedConnectPeer connects to peers >= v1.3.1. The peer is added as a
-// node and a peer. The peer is only added if a nil error is returned.
-func (g *Gateway) managedConnectPeer(conn net.Conn, remoteVersion string, remoteAddr modules.NetAddress) error {
+// node and a peer. The peer is only added if a nil error is returned. The
+// returned connection is encrypted if the remote peer supports it.
+func (g *Gateway) managedConnectPeer(conn net.Conn, remoteVersion string, remoteAddr modules.NetAddress) (net.Conn, encryptionHeader, error) {
 	g.log.Debugln("Sending sessionHeader with address", g.myAddr, g.myAddr.IsLocal())
 	// Perform header handshake.
 	g.mu.RLock()
@@ -418,14 +463,34 @@ func (g *Gateway) managedConnectPeer(conn net.Conn, remoteVersion string, remote
 		UniqueID:   g.staticID,
 		NetAddress: g.myAddr,
 	}
+	ourMode := g.encryptionMode()
 	g.mu.RUnlock()
+	xsk, xpk := crypto.GenerateX25519KeyPair()
+	ourEncHeader := encryptionHeader{
+		Mode:      ourMode,
+		PublicKey: xpk,
+		NodeKey:   g.staticNodeKey.PublicKey(),
+	}
 
-	if err := exchangeOurHeader(conn, ourHeader); err != nil {
-		return err
-	} else if _, err := exchangeRemoteHeader(conn, ourHeader); err != nil {
-		return err
+	if err := exchangeOurEncryptedHeader(conn, ourHeader, ourEncHeader); err != nil {
+		return nil, encryptionHeader{}, err
 	}
-	return nil
+	_, remoteEncHeader, err := exchangeRemoteEncryptedHeader(conn, ourHeader, ourMode)
+	if err != nil {
+		return nil, encryptionHeader{}, err
+	}
+	// Refuse the peer if it authenticated differently before.
+	g.mu.RLock()
+	err = g.verifyNodeEncryption(remoteAddr, remoteEncHeader)
+	g.mu.RUnlock()
+	if err != nil {
+		return nil, encryptionHeader{}, err
+	}
+	sessConn, err := staticEncryptConn(conn, xsk, g.staticNodeKey, ourEncHeader, remoteEncHeader, true)
+	if err != nil {
+		return nil, encryptionHeader{}, err
+	}
+	return sessConn, remoteEncHeader, nil
 }
 
 // managedConnect establishes a persistent connection to a peer, and adds it to
@@ -458,11 +523,16 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	}
 	g.mu.RLock()
 	_, exists := g.peers[addr]
+	banned := g.isBanned(addr)
 	g.mu.RUnlock()
 	if exists {
 		g.log.Debugln("Unable to connect to", addr, "error:", errPeerExists)
 		return errPeerExists
 	}
+	if banned {
+		g.log.Debugln("Unable to connect to", addr, "error:", errPeerBanned)
+		return errPeerBanned
+	}
 
 	// Dial the peer and perform peer initialization.
 	conn, err := g.staticDial(addr)
@@ -480,8 +550,10 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 		return err
 	}
 
+	var sessConn net.Conn
+	var remoteEncHeader encryptionHeader
 	if err = acceptableVersion(remoteVersion); err == nil {
-		err = g.managedConnectPeer(conn, remoteVersion, addr)
+		sessConn, remoteEncHeader, err = g.managedConnectPeer(conn, remoteVersion, addr)
 	}
 	if err != nil {
 		conn.Close()
@@ -499,17 +571,20 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 
 	g.addPeer(&peer{
 		Peer: modules.Peer{
-			Inbound:    false,
-			Local:      addr.IsLocal(),
-			NetAddress: addr,
-			Version:    remoteVersion,
+			Inbound:            false,
+			Local:              addr.IsLocal(),
+			NetAddress:         addr,
+			Version:            remoteVersion,
+			Encrypted:          remoteEncHeader.Mode != encryptionDisabled,
+			EncryptionRequired: remoteEncHeader.Mode == encryptionRequired,
 		},
 		m:    g.m,
 		rl:   g.rl,
-		sess: newClientStream(conn, remoteVersion),
+		sess: newClientStream(sessConn, remoteVersion),
 	})
 	g.addNode(addr)
 	g.nodes[addr].WasOutboundPeer = true
+	g.rememberNodeEncryption(addr, remoteEncHeader)
 
 	if err := g.saveSyncNodes(); err != nil {
 		g.log.Println("ERROR: Unable to save new outbound peer to gateway:", err)
@@ -565,7 +640,7 @@ func (g *Gateway) Disconnect(addr modules.NetAddress) error {
 
 // ConnectManual is a wrapper for the Connect function. It is specifically used
 // if a user wants to connect to a node manually. This also removes the node
-// from the blocklist.
+// from the blocklist and lifts its ban.
 func (g *Gateway) ConnectManual(addr modules.NetAddress) error {
 	g.log.Debugln("Attempting to Manually Connect to", addr)
 	g.mu.Lock()
@@ -575,6 +650,11 @@ func (g *Gateway) ConnectManual(addr modules.NetAddress) error {
 		delete(g.blocklist, addr.Host())
 		err = g.saveSync()
 	}
+	if g.isBanned(addr) {
+		g.log.Debugln("Lifting the ban of", addr, "due to Manually trying to Connect")
+		g.reputations[addr.Host()].BannedUntil = time.Now()
+		err = build.ComposeErrors(err, g.saveSync())
+	}
 	g.mu.Unlock()
 	return build.ComposeErrors(err, g.Connect(addr))
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/uplo-tech/errors"
 
+	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/persist"
 )
@@ -37,6 +38,17 @@ type (
 
 		// blocklisted IPs
 		Blocklist []string
+
+		// Reputations are the misbehavior scores and bans of peers.
+		Reputations []modules.PeerReputation
+
+		// RequireEncryption indicates whether peer connections which don't
+		// use the encrypted transport are refused.
+		RequireEncryption bool
+
+		// NodeKey is the long-term key that authenticates the encrypted
+		// transport of the gateway.
+		NodeKey crypto.SecretKey
 	}
 )
 
@@ -94,17 +106,45 @@ func (g *Gateway) load() error {
 	for _, ip := range g.persist.Blocklist {
 		g.blocklist[ip] = struct{}{}
 	}
+	// create map from reputations
+	for _, pr := range g.persist.Reputations {
+		g.reputations[pr.Address] = &peerReputation{PeerReputation: pr}
+	}
 	return nil
 }
 
+// persistData returns a copy of the Gateway's persistent data.
+func (g *Gateway) persistData() persistence {
+	data := g.persist
+	data.Blocklist = make([]string, 0, len(g.blocklist))
+	for ip := range g.blocklist {
+		data.Blocklist = append(data.Blocklist, ip)
+	}
+	data.Reputations = make([]modules.PeerReputation, 0, len(g.reputations))
+	for _, pr := range g.reputations {
+		data.Reputations = append(data.Reputations, pr.PeerReputation)
+	}
+	return data
+}
+
 // saveSync stores the Gateway's persistent data on disk, and then syncs to
 // disk to minimize the possibility of data loss.
 func (g *Gateway) saveSync() error {
-	g.persist.Blocklist = make([]string, 0, len(g.blocklist))
-	for ip := range g.blocklist {
-		g.persist.Blocklist = append(g.persist.Blocklist, ip)
-	}
-	return persist.SaveJSON(persistMetadata, g.persist, filepath.Join(g.persistDir, persistFilename))
+	g.persistMu.Lock()
+	defer g.persistMu.Unlock()
+	return persist.SaveJSON(persistMetadata, g.persistData(), filepath.Join(g.persistDir, persistFilename))
+}
+
+// managedSaveSync stores the Gateway's persistent data on disk without holding
+// the lock while writing. persistMu is acquired before the lock is released, so
+// the copies of the data are written in the order they were made.
+func (g *Gateway) managedSaveSync() error {
+	g.mu.Lock()
+	data := g.persistData()
+	g.persistMu.Lock()
+	g.mu.Unlock()
+	defer g.persistMu.Unlock()
+	return persist.SaveJSON(persistMetadata, data, filepath.Join(g.persistDir, persistFilename))
 }
 
 // saveSyncNodes stores the Gateway's persistent node data on disk, and then
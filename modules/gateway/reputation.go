@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+)
+
+var (
+	// errPeerBanned is returned when connecting to a peer that is banned.
+	errPeerBanned = errors.New("can't connect to banned address")
+)
+
+// peerReputation tracks the misbehavior score and bans of an IP address.
+type peerReputation struct {
+	modules.PeerReputation
+
+	// lastDecay is the time at which the score was last decreased.
+	lastDecay time.Time
+}
+
+// banDuration returns the duration of a ban of a peer which has been banned
+// the given number of times before.
+func banDuration(bans uint64) time.Duration {
+	d := baseBanDuration
+	for i := uint64(0); i < bans && d < maxBanDuration; i++ {
+		d *= 2
+	}
+	if d > maxBanDuration {
+		d = maxBanDuration
+	}
+	return d
+}
+
+// decay decreases the score of the reputation by one for every
+// scoreDecayInterval that passed since the last decay.
+func (pr *peerReputation) decay(now time.Time) {
+	if pr.Score == 0 || pr.lastDecay.IsZero() {
+		pr.lastDecay = now
+		return
+	}
+	steps := int64(now.Sub(pr.lastDecay) / scoreDecayInterval)
+	if steps <= 0 {
+		return
+	}
+	pr.Score -= steps
+	if pr.Score < 0 {
+		pr.Score = 0
+	}
+	pr.lastDecay = pr.lastDecay.Add(time.Duration(steps) * scoreDecayInterval)
+}
+
+// banned returns true if the reputation has an active ban.
+func (pr *peerReputation) banned(now time.Time) bool {
+	return now.Before(pr.BannedUntil)
+}
+
+// isBanned returns true if the host of addr is currently banned.
+func (g *Gateway) isBanned(addr modules.NetAddress) bool {
+	pr, exists := g.reputations[addr.Host()]
+	return exists && pr.banned(time.Now())
+}
+
+// pruneReputations removes the reputations of peers which have no score left
+// and whose last ban ended so long ago that it doesn't affect the duration of
+// future bans anymore.
+func (g *Gateway) pruneReputations(now time.Time) {
+	for host, pr := range g.reputations {
+		pr.decay(now)
+		if pr.Score == 0 && now.Sub(pr.BannedUntil) > maxBanDuration {
+			delete(g.reputations, host)
+		}
+	}
+}
+
+// ban bans the host of a peer and disconnects all peers with the same host. The
+// ban isn't saved to disk.
+func (g *Gateway) ban(host string, pr *peerReputation, now time.Time) error {
+	pr.BannedUntil = now.Add(banDuration(pr.Bans))
+	pr.Bans++
+	pr.Score = 0
+	var err error
+	for peerAddr, peer := range g.peers {
+		if peerAddr.Host() == host {
+			err = errors.Compose(err, peer.sess.Close())
+			delete(g.peers, peerAddr)
+		}
+	}
+	g.log.Printf("INFO: banned %v until %v after %v bans", host, pr.BannedUntil, pr.Bans)
+	return err
+}
+
+// PeerReputations returns the misbehavior scores and bans of the peers that
+// misbehaved recently.
+func (g *Gateway) PeerReputations() []modules.PeerReputation {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	g.pruneReputations(now)
+	reputations := make([]modules.PeerReputation, 0, len(g.reputations))
+	for _, pr := range g.reputations {
+		reputations = append(reputations, pr.PeerReputation)
+	}
+	return reputations
+}
+
+// ReportPeer increases the misbehavior score of a peer. If the score reaches
+// banScore, the peer is disconnected and banned temporarily. The duration of
+// the ban doubles with every ban of the peer.
+func (g *Gateway) ReportPeer(addr modules.NetAddress, offense modules.PeerOffense) {
+	if err := g.threads.Add(); err != nil {
+		return
+	}
+	defer g.threads.Done()
+
+	host := addr.Host()
+	if host == "" {
+		return
+	}
+	g.mu.Lock()
+	now := time.Now()
+	pr, exists := g.reputations[host]
+	if !exists {
+		// Prune the reputations before tracking another host, so that the
+		// reputations of hosts which stopped misbehaving don't accumulate.
+		g.pruneReputations(now)
+		pr = &peerReputation{
+			PeerReputation: modules.PeerReputation{Address: host},
+		}
+		g.reputations[host] = pr
+	}
+	pr.decay(now)
+	pr.Score += offensePenalties[offense]
+	g.log.Debugf("INFO: %v reported for %v, score is now %v", addr, offense, pr.Score)
+	if pr.Score < banScore {
+		g.mu.Unlock()
+		return
+	}
+	err := g.ban(host, pr, now)
+	g.mu.Unlock()
+	err = errors.Compose(err, g.managedSaveSync())
+	if err != nil {
+		g.log.Println("WARN: failed to ban peer:", err)
+	}
+}
+
+// isTimeoutErr returns true if err is a timeout of a connection.
+func isTimeoutErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
+		return true
+	}
+	// COMPATv1.3.0
+	return (err.Error() == "Read timeout" || err.Error() == "Write timeout")
+}
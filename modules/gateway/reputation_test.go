@@ -0,0 +1,175 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+)
+
+// TestBanDuration checks that the ban duration doubles with every ban up to
+// maxBanDuration.
+func TestBanDuration(t *testing.T) {
+	if banDuration(0) != baseBanDuration {
+		t.Fatal("first ban should last baseBanDuration")
+	}
+	if banDuration(1) != 2*baseBanDuration || banDuration(2) != 4*baseBanDuration {
+		t.Fatal("ban duration should double with every ban")
+	}
+	if banDuration(1000) != maxBanDuration {
+		t.Fatal("ban duration should be capped at maxBanDuration")
+	}
+}
+
+// TestScoreDecay checks that misbehavior scores decrease over time.
+func TestScoreDecay(t *testing.T) {
+	now := time.Now()
+	pr := &peerReputation{}
+	pr.decay(now)
+	pr.Score = 10
+	pr.decay(now.Add(3*scoreDecayInterval + scoreDecayInterval/2))
+	if pr.Score != 7 {
+		t.Fatal("expected score of 7, got", pr.Score)
+	}
+	pr.decay(now.Add(100 * scoreDecayInterval))
+	if pr.Score != 0 {
+		t.Fatal("score shouldn't decay below 0, got", pr.Score)
+	}
+}
+
+// TestReportPeerPrune checks that reporting a new peer prunes the reputations
+// which expired.
+func TestReportPeerPrune(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newNamedTestingGateway(t, "1")
+	defer func() {
+		if err := g.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Add an expired and an active reputation.
+	now := time.Now()
+	g.mu.Lock()
+	g.reputations["1.1.1.1"] = &peerReputation{
+		PeerReputation: modules.PeerReputation{
+			Address:     "1.1.1.1",
+			BannedUntil: now.Add(-2 * maxBanDuration),
+			Bans:        1,
+		},
+	}
+	g.reputations["2.2.2.2"] = &peerReputation{
+		PeerReputation: modules.PeerReputation{
+			Address:     "2.2.2.2",
+			BannedUntil: now.Add(maxBanDuration),
+			Bans:        1,
+		},
+	}
+	g.mu.Unlock()
+
+	// Report another peer.
+	g.ReportPeer("3.3.3.3:1234", modules.PeerOffenseInvalidBlock)
+	g.mu.Lock()
+	_, expired := g.reputations["1.1.1.1"]
+	_, active := g.reputations["2.2.2.2"]
+	_, reported := g.reputations["3.3.3.3"]
+	g.mu.Unlock()
+	if expired || !active || !reported {
+		t.Fatal("unexpected reputations after pruning", expired, active, reported)
+	}
+}
+
+// TestReportPeer checks that peers are disconnected and banned once their
+// misbehavior score reaches banScore and that manually connecting lifts the
+// ban.
+func TestReportPeer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer func() {
+		if err := g1.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	g2 := newNamedTestingGateway(t, "2")
+	defer func() {
+		if err := g2.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if err := connectToNode(g1, g2, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// A single offense shouldn't ban the peer.
+	g1.ReportPeer(g2.Address(), modules.PeerOffenseInvalidBlock)
+	if len(g1.Peers()) != 1 {
+		t.Fatal("peer shouldn't be disconnected yet")
+	}
+	reputations := g1.PeerReputations()
+	if len(reputations) != 1 || reputations[0].Score != offensePenalties[modules.PeerOffenseInvalidBlock] {
+		t.Fatal("unexpected reputations", reputations)
+	}
+
+	// A second offense should ban the peer.
+	g1.ReportPeer(g2.Address(), modules.PeerOffenseInvalidBlock)
+	if len(g1.Peers()) != 0 {
+		t.Fatal("banned peer should be disconnected")
+	}
+	reputations = g1.PeerReputations()
+	if len(reputations) != 1 || reputations[0].Bans != 1 || !time.Now().Before(reputations[0].BannedUntil) {
+		t.Fatal("peer should be banned", reputations)
+	}
+
+	// The peer can't reconnect while it is banned.
+	if err := g1.Connect(g2.Address()); !errors.Contains(err, errPeerBanned) {
+		t.Fatal("expected errPeerBanned", err)
+	}
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g2.Peers()) != 0 {
+			return errors.New("g2 should be disconnected from g1")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2.Connect(g1.Address())
+	time.Sleep(time.Second)
+	if len(g1.Peers()) != 0 {
+		t.Fatal("banned peer was able to reconnect")
+	}
+
+	// The ban should survive a restart of the gateway.
+	g1.mu.Lock()
+	err = g1.saveSync()
+	g1.reputations = make(map[string]*peerReputation)
+	if err == nil {
+		err = g1.load()
+	}
+	g1.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reputations = g1.PeerReputations()
+	if len(reputations) != 1 || reputations[0].Bans != 1 {
+		t.Fatal("ban wasn't persisted", reputations)
+	}
+
+	// Manually connecting lifts the ban.
+	g2.Disconnect(g1.Address())
+	if err := g1.ConnectManual(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if len(g1.Peers()) != 1 {
+		t.Fatal("peer should be connected after lifting the ban")
+	}
+}
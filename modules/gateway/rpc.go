@@ -61,6 +61,9 @@ func (g *Gateway) managedRPC(addr modules.NetAddress, name string, fn modules.RP
 	// write header
 	conn.SetDeadline(time.Now().Add(rpcStdDeadline))
 	if err := encoding.WriteObject(conn, handlerName(name)); err != nil {
+		if isTimeoutErr(err) {
+			g.ReportPeer(addr, modules.PeerOffenseRPCTimeout)
+		}
 		return err
 	}
 	conn.SetDeadline(time.Time{})
@@ -235,6 +238,9 @@ func (g *Gateway) threadedHandleConn(conn modules.PeerConn) {
 	if err != nil {
 		g.log.Debugf("WARN: incoming RPC \"%v\" from conn %v failed: %v", id, conn.RPCAddr(), err)
 	}
+	if isTimeoutErr(err) {
+		g.ReportPeer(conn.RPCAddr(), modules.PeerOffenseRPCTimeout)
+	}
 	// Log the amount of time it took the handler to do the RPC.
 	g.log.Debugf("%s RPC time: %v", id, time.Since(startRPCTime).Round(time.Millisecond))
 }
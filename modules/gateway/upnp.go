@@ -19,10 +19,11 @@ import (
 )
 
 // myExternalIP discovers the gateway's external IP by querying a centralized
-// service, http://myexternalip.com.
+// service, http://myexternalip.com. The service is contacted using the global
+// dialer, so the request respects the proxy settings.
 func myExternalIP() (_ string, err error) {
 	// timeout after 10 seconds
-	client := http.Client{Timeout: time.Duration(10 * time.Second)}
+	client := modules.GlobalDialer.HTTPClient(10 * time.Second)
 	resp, err := client.Get("http://myexternalip.com/raw")
 	if err != nil {
 		return "", err
@@ -62,11 +63,16 @@ func (g *Gateway) managedLearnHostname(cancel <-chan struct{}) (net.IP, error) {
 	}()
 
 	// try UPnP first, then fallback to myexternalip.com and peer-to-peer
-	// discovery.
+	// discovery. UPnP talks to the router directly, so it's skipped if direct
+	// connections are refused.
 	var host string
-	d, err := upnp.Load(g.persist.RouterURL)
-	if err != nil {
-		d, err = upnp.DiscoverCtx(ctx)
+	var d *upnp.IGD
+	err := modules.GlobalDialer.DirectAllowed()
+	if err == nil {
+		d, err = upnp.Load(g.persist.RouterURL)
+		if err != nil {
+			d, err = upnp.DiscoverCtx(ctx)
+		}
 	}
 	if err == nil {
 		g.mu.Lock()
@@ -158,6 +164,11 @@ func (g *Gateway) managedForwardPort(port string) error {
 		return err
 	}
 
+	// UPnP talks to the router directly.
+	if err := modules.GlobalDialer.DirectAllowed(); err != nil {
+		return fmt.Errorf("WARN: could not automatically forward port %s: %v", port, err)
+	}
+
 	// Create a context to stop UPnP discovery in case of a shutdown.
 	ctx, cancel := context.WithCancel(g.threads.StopCtx())
 	defer cancel()
@@ -196,6 +207,9 @@ func (g *Gateway) managedClearPort(port string) {
 	if build.Release == "testing" {
 		return
 	}
+	if modules.GlobalDialer.DirectAllowed() != nil {
+		return
+	}
 
 	ctx, cancel := context.WithCancel(g.threads.StopCtx())
 	defer cancel()
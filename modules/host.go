@@ -356,6 +356,7 @@ type (
 	// of the host protocol.
 	Host interface {
 		Alerter
+		EventSubscriber
 
 		// AddSector will add a sector on the host. If the sector already
 		// exists, a virtual sector will be added, meaning that the 'sectorData'
@@ -22,3 +22,20 @@ func (h *Host) tryUnregisterInsufficientCollateralBudgetAlert() {
 		h.staticAlerter.UnregisterAlert(modules.AlertIDHostInsufficientCollateral)
 	}
 }
+
+// SubscribeEvents implements the modules.EventSubscriber interface for the
+// host. Subscribers are notified about the alerts of the host and its storage
+// manager and about the lifecycle of the host's contracts.
+func (h *Host) SubscribeEvents(l modules.EventListener) {
+	h.staticEvents.SubscribeEvents(l)
+	h.staticAlerter.SubscribeEvents(l)
+	h.StorageManager.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents implements the modules.EventSubscriber interface for the
+// host.
+func (h *Host) UnsubscribeEvents(l modules.EventListener) {
+	h.staticEvents.UnsubscribeEvents(l)
+	h.staticAlerter.UnsubscribeEvents(l)
+	h.StorageManager.UnsubscribeEvents(l)
+}
@@ -246,3 +246,15 @@ func NewCustomContractManager(dependencies modules.Dependencies, persistDir stri
 func (cm *ContractManager) Alerts() (crit, err, warn []modules.Alert) {
 	return cm.staticAlerter.Alerts()
 }
+
+// SubscribeEvents implements the modules.EventSubscriber interface for the
+// contract manager.
+func (cm *ContractManager) SubscribeEvents(l modules.EventListener) {
+	cm.staticAlerter.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents implements the modules.EventSubscriber interface for the
+// contract manager.
+func (cm *ContractManager) UnsubscribeEvents(l modules.EventListener) {
+	cm.staticAlerter.UnsubscribeEvents(l)
+}
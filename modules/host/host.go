@@ -153,6 +153,7 @@ type Host struct {
 	tpool         modules.TransactionPool
 	wallet        modules.Wallet
 	staticAlerter *modules.GenericAlerter
+	staticEvents  modules.EventNotifier
 	staticMux     *uplomux.UploMux
 	dependencies  modules.Dependencies
 	modules.StorageManager
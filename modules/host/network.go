@@ -146,7 +146,7 @@ func (h *Host) threadedTrackConnectabilityStatus(closeChan chan struct{}) {
 			Cancel:  h.tg.StopChan(),
 			Timeout: connectabilityCheckTimeout,
 		}
-		conn, err := dialer.Dial("tcp", string(activeAddr))
+		conn, err := modules.GlobalDialer.Dial(dialer, string(activeAddr))
 
 		var status modules.HostConnectabilityStatus
 		if err != nil {
@@ -470,6 +470,15 @@ func (so storageObligation) proofDeadline() types.BlockHeight {
 	return so.OriginTransactionSet[len(so.OriginTransactionSet)-1].FileContracts[0].WindowEnd
 }
 
+// event returns the data of an event about the storage obligation.
+func (so storageObligation) event() modules.HostContractEvent {
+	return modules.HostContractEvent{
+		ID:               so.id(),
+		ExpirationHeight: so.expiration(),
+		ProofDeadline:    so.proofDeadline(),
+	}
+}
+
 // transactionID returns the ID of the transaction containing the file
 // contract.
 func (so storageObligation) transactionID() types.TransactionID {
@@ -642,6 +651,7 @@ func (h *Host) managedAddStorageObligation(so storageObligation) error {
 		h.log.Println("Error with transaction set, redacting obligation, id", so.id())
 		return composeErrors(err, h.removeStorageObligation(so, obligationRejected))
 	}
+	h.staticEvents.Notify(modules.EventHostContractFormed, so.event())
 	return nil
 }
 
@@ -726,6 +736,9 @@ func (h *Host) managedAddRenewedStorageObligation(oldSO, newSO storageObligation
 		h.log.Println("Error with transaction set, redacting obligation, id", newSO.id())
 		return composeErrors(err, h.removeStorageObligation(newSO, obligationRejected))
 	}
+	e := newSO.event()
+	e.RenewedFrom = oldSO.id()
+	h.staticEvents.Notify(modules.EventHostContractRenewed, e)
 	return nil
 }
 
@@ -1026,9 +1039,22 @@ func (h *Host) removeStorageObligation(so storageObligation, sos storageObligati
 	h.financialMetrics.ContractCount--
 	so.ObligationStatus = sos
 	so.SectorRoots = nil
-	return h.db.Update(func(tx *bolt.Tx) error {
+	err := h.db.Update(func(tx *bolt.Tx) error {
 		return putStorageObligation(tx, so)
 	})
+	if err != nil {
+		return err
+	}
+
+	// Notify subscribers about the outcome of the obligation. Rejected
+	// obligations never became contracts, so there is nothing to report.
+	switch sos {
+	case obligationSucceeded:
+		h.staticEvents.Notify(modules.EventHostContractSucceeded, so.event())
+	case obligationFailed:
+		h.staticEvents.Notify(modules.EventHostContractFailed, so.event())
+	}
+	return nil
 }
 
 // resetFinancialMetrics completely resets the host's financial metrics using
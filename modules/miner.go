@@ -2,11 +2,16 @@ package modules
 
 import (
 	"io"
+	"time"
 
 	"github.com/uplo-tech/uplo/types"
 )
 
 const (
+	// DefaultStratumDifficulty is the default share difficulty of the miner's
+	// Stratum server.
+	DefaultStratumDifficulty = 1 << 32
+
 	// MinerDir is the name of the directory that is used to store the miner's
 	// persistent data.
 	MinerDir = "miner"
@@ -48,6 +53,50 @@ type CPUMiner interface {
 	StopCPUMining()
 }
 
+// StratumServer provides access to the miner's Stratum server which hands out
+// work to external miners over TCP.
+type StratumServer interface {
+	// StratumStatus returns the status of the Stratum server and of the
+	// workers which submitted shares to it.
+	StratumStatus() StratumStatus
+}
+
+// StratumStatus is the status of the miner's Stratum server.
+type StratumStatus struct {
+	// Address is the address the server is listening on. It is empty if the
+	// server isn't running.
+	Address string `json:"address"`
+
+	// ShareDifficulty is the difficulty a share needs to meet to be accepted.
+	ShareDifficulty uint64 `json:"sharedifficulty"`
+
+	// Workers contains the stats of the workers which connected to the
+	// server.
+	Workers []StratumWorker `json:"workers"`
+}
+
+// StratumWorker contains the stats of a single Stratum worker.
+type StratumWorker struct {
+	// Name is the name the worker authorized with.
+	Name string `json:"name"`
+
+	// AcceptedShares and RejectedShares are the number of shares the worker
+	// submitted which met or didn't meet the share difficulty.
+	AcceptedShares uint64 `json:"acceptedshares"`
+	RejectedShares uint64 `json:"rejectedshares"`
+
+	// BlocksFound is the number of shares which were valid blocks.
+	BlocksFound uint64 `json:"blocksfound"`
+
+	// Hashrate is the estimated hashrate of the worker in hashes per second
+	// based on its recently accepted shares.
+	Hashrate uint64 `json:"hashrate"`
+
+	// LastShareTime is the time at which the worker submitted its last
+	// accepted share.
+	LastShareTime time.Time `json:"lastsharetime"`
+}
+
 // TestMiner provides direct access to block fetching, solving, and
 // manipulation. The primary use of this interface is integration testing.
 type TestMiner interface {
@@ -80,5 +129,6 @@ type TestMiner interface {
 type Miner interface {
 	BlockManager
 	CPUMiner
+	StratumServer
 	io.Closer
 }
@@ -80,7 +80,12 @@ func (m *Miner) HeaderForWork() (types.BlockHeader, types.Target, error) {
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.headerForWork()
+}
 
+// headerForWork returns a header that is ready for nonce grinding and
+// remembers the block it was derived from. The caller needs to hold the lock.
+func (m *Miner) headerForWork() (types.BlockHeader, types.Target, error) {
 	// Return a blank header with an error if the wallet is locked.
 	unlocked, err := m.wallet.Unlocked()
 	if err != nil {
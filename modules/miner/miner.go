@@ -101,6 +101,10 @@ type Miner struct {
 	mining   bool  // indicates if the miner is actually running
 	hashRate int64 // indicates hashes per second
 
+	// stratum is the miner's Stratum server. It is nil if the server isn't
+	// running.
+	stratum *stratumServer
+
 	// Utils
 	log        *persist.Logger
 	mu         sync.RWMutex
@@ -0,0 +1,631 @@
+package miner
+
+// stratum.go implements a Stratum-compatible mining server. Workers connect to
+// the server over TCP and exchange newline-delimited JSON-RPC messages with it.
+//
+// The server supports the following methods:
+//  - mining.subscribe: subscribes the connection to new jobs. The result is
+//    the id of the subscription.
+//  - mining.authorize [name, password]: authorizes a worker on the
+//    connection. The password needs to match the password of the server. The
+//    number of workers is limited.
+//  - mining.submit [name, jobID, nonce]: submits a share for a job. The nonce
+//    is the hex encoded 8 byte nonce of the block header.
+//
+// The server sends the following notifications:
+//  - mining.set_difficulty [difficulty]: the difficulty a share needs to
+//    meet.
+//  - mining.notify [jobID, header, cleanJobs]: a new job. The header is the
+//    hex encoded 80 byte block header with a zero nonce. If cleanJobs is true,
+//    the previous jobs of the connection are stale.
+//
+// Every job is derived from the miner's source block just like the headers
+// handed out by HeaderForWork. Shares which also meet the target of the
+// network are submitted as blocks. The payouts of all blocks go to the
+// miner's address, independent of the worker that found the block.
+//
+// Every connection has its own write lock and writes time out, so a worker
+// which stops reading can't block the notifications of the other workers.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/fastrand"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+const (
+	// stratumJobMemory is the number of jobs remembered per connection.
+	// Shares for older jobs are rejected as stale.
+	stratumJobMemory = 10
+
+	// stratumMaxMessageSize is the maximum size of a message sent by a
+	// worker.
+	stratumMaxMessageSize = 1 << 12
+)
+
+var (
+	// stratumHashrateWindow is the timespan of accepted shares which are used
+	// to estimate the hashrate of a worker.
+	stratumHashrateWindow = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: 10 * time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+
+	// stratumMaxWorkers is the maximum number of workers which can authorize
+	// with the Stratum server.
+	stratumMaxWorkers = build.Select(build.Var{
+		Dev:      100,
+		Standard: 1000,
+		Testing:  3,
+	}).(int)
+
+	// stratumWriteTimeout is the time a worker has to accept a message before
+	// its connection is closed.
+	stratumWriteTimeout = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// errStratumDuplicateShare is returned when a share is submitted twice.
+	errStratumDuplicateShare = errors.New("duplicate share")
+
+	// errStratumNoPassword is returned when starting the Stratum server
+	// without a password.
+	errStratumNoPassword = errors.New("stratum server requires a password")
+
+	// errStratumLowDifficulty is returned when a share doesn't meet the share
+	// difficulty.
+	errStratumLowDifficulty = errors.New("low difficulty share")
+
+	// errStratumRunning is returned when trying to start the Stratum server
+	// twice.
+	errStratumRunning = errors.New("stratum server is already running")
+
+	// errStratumStaleJob is returned when a share is submitted for an unknown
+	// job.
+	errStratumStaleJob = errors.New("job not found")
+
+	// errStratumTooManyWorkers is returned when a new worker tries to
+	// authorize while the server already has the maximum number of workers.
+	errStratumTooManyWorkers = errors.New("too many workers")
+
+	// errStratumUnauthorized is returned when a share is submitted by a worker
+	// which isn't authorized on the connection.
+	errStratumUnauthorized = errors.New("unauthorized worker")
+
+	// errStratumWrongPassword is returned when a worker tries to authorize
+	// with the wrong password.
+	errStratumWrongPassword = errors.New("wrong password")
+
+	// errStratumZeroDifficulty is returned when starting the Stratum server
+	// with a share difficulty of 0.
+	errStratumZeroDifficulty = errors.New("share difficulty must be greater than 0")
+)
+
+type (
+	// stratumServer is the miner's Stratum server.
+	stratumServer struct {
+		staticListener        net.Listener
+		staticPassword        string
+		staticShareDifficulty uint64
+		staticShareTarget     types.Target
+		// staticWakeChan is signaled whenever new jobs should be pushed to the
+		// workers.
+		staticWakeChan chan struct{}
+
+		conns        map[*stratumConn]struct{}
+		jobCounter   uint64
+		pendingClean bool
+		workers      map[string]*stratumWorker
+		mu           sync.Mutex
+	}
+
+	// stratumConn is a connection of a worker to the Stratum server.
+	stratumConn struct {
+		staticConn net.Conn
+
+		authorized map[string]struct{}
+		jobs       map[string]*stratumJob
+		jobOrder   []string
+		subscribed bool
+		mu         sync.Mutex
+
+		// writeMu serializes the writes to the connection. It's separate from
+		// mu so that a slow write doesn't block handling the requests of the
+		// connection.
+		writeMu sync.Mutex
+	}
+
+	// stratumJob is a job which was handed out to a connection.
+	stratumJob struct {
+		header types.BlockHeader
+		nonces map[types.BlockNonce]struct{}
+		target types.Target
+	}
+
+	// stratumWorker contains the stats of a worker.
+	stratumWorker struct {
+		acceptedShares uint64
+		blocksFound    uint64
+		lastShareTime  time.Time
+		rejectedShares uint64
+		shareTimes     []time.Time
+	}
+
+	// stratumRequest is a JSON-RPC request sent by a worker.
+	stratumRequest struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+
+	// stratumResponse is a JSON-RPC response sent by the server.
+	stratumResponse struct {
+		ID     json.RawMessage `json:"id"`
+		Result interface{}     `json:"result"`
+		Error  interface{}     `json:"error"`
+	}
+
+	// stratumNotification is a JSON-RPC notification sent by the server.
+	stratumNotification struct {
+		ID     interface{}   `json:"id"`
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}
+)
+
+// difficultyToTarget returns the target which corresponds to the given
+// difficulty.
+func difficultyToTarget(difficulty uint64) types.Target {
+	return types.IntToTarget(new(big.Int).Div(types.RootDepth.Int(), new(big.Int).SetUint64(difficulty)))
+}
+
+// StartStratum starts the Stratum server on the given address. Workers need to
+// authorize with the provided password and shares need to meet the provided
+// share difficulty to be accepted.
+func (m *Miner) StartStratum(address, password string, shareDifficulty uint64) error {
+	if err := m.tg.Add(); err != nil {
+		return err
+	}
+	defer m.tg.Done()
+	if password == "" {
+		return errStratumNoPassword
+	}
+	if shareDifficulty == 0 {
+		return errStratumZeroDifficulty
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stratum != nil {
+		return errStratumRunning
+	}
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.AddContext(err, "unable to listen for stratum connections")
+	}
+	ss := &stratumServer{
+		staticListener:        l,
+		staticPassword:        password,
+		staticShareDifficulty: shareDifficulty,
+		staticShareTarget:     difficultyToTarget(shareDifficulty),
+		staticWakeChan:        make(chan struct{}, 1),
+
+		conns:   make(map[*stratumConn]struct{}),
+		workers: make(map[string]*stratumWorker),
+	}
+	m.stratum = ss
+
+	// Close the listener and all connections on shutdown.
+	err = m.tg.OnStop(func() error {
+		err := ss.staticListener.Close()
+		ss.mu.Lock()
+		for sc := range ss.conns {
+			err = errors.Compose(err, sc.staticConn.Close())
+		}
+		ss.mu.Unlock()
+		return err
+	})
+	if err != nil {
+		return errors.Compose(err, l.Close())
+	}
+	go m.threadedListenStratum(ss)
+	go m.threadedPushStratumJobs(ss)
+	m.log.Println("Stratum server listening on", l.Addr())
+	return nil
+}
+
+// StratumStatus returns the status of the Stratum server and of the workers
+// which submitted shares to it.
+func (m *Miner) StratumStatus() modules.StratumStatus {
+	m.mu.RLock()
+	ss := m.stratum
+	m.mu.RUnlock()
+	if ss == nil {
+		return modules.StratumStatus{}
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	status := modules.StratumStatus{
+		Address:         ss.staticListener.Addr().String(),
+		ShareDifficulty: ss.staticShareDifficulty,
+		Workers:         make([]modules.StratumWorker, 0, len(ss.workers)),
+	}
+	for name, w := range ss.workers {
+		w.pruneShareTimes()
+		status.Workers = append(status.Workers, modules.StratumWorker{
+			Name:           name,
+			AcceptedShares: w.acceptedShares,
+			RejectedShares: w.rejectedShares,
+			BlocksFound:    w.blocksFound,
+			Hashrate:       uint64(len(w.shareTimes)) * ss.staticShareDifficulty / uint64(stratumHashrateWindow.Seconds()),
+			LastShareTime:  w.lastShareTime,
+		})
+	}
+	sort.Slice(status.Workers, func(i, j int) bool {
+		return status.Workers[i].Name < status.Workers[j].Name
+	})
+	return status
+}
+
+// notifyStratum signals the Stratum server that new jobs should be pushed to
+// the workers. If clean is true, the previous jobs are stale. The caller needs
+// to hold the lock.
+func (m *Miner) notifyStratum(clean bool) {
+	if m.stratum == nil {
+		return
+	}
+	m.stratum.mu.Lock()
+	m.stratum.pendingClean = m.stratum.pendingClean || clean
+	m.stratum.mu.Unlock()
+	select {
+	case m.stratum.staticWakeChan <- struct{}{}:
+	default:
+	}
+}
+
+// pruneShareTimes drops the share times which are outside of the hashrate
+// window.
+func (w *stratumWorker) pruneShareTimes() {
+	i := 0
+	for i < len(w.shareTimes) && time.Since(w.shareTimes[i]) > stratumHashrateWindow {
+		i++
+	}
+	w.shareTimes = w.shareTimes[i:]
+}
+
+// managedAddJob adds a job to the connection, dropping the oldest job if the
+// connection remembers too many jobs already.
+func (sc *stratumConn) managedAddJob(id string, job *stratumJob, clean bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if clean {
+		sc.jobs = make(map[string]*stratumJob)
+		sc.jobOrder = nil
+	}
+	if len(sc.jobOrder) >= stratumJobMemory {
+		delete(sc.jobs, sc.jobOrder[0])
+		sc.jobOrder = sc.jobOrder[1:]
+	}
+	sc.jobs[id] = job
+	sc.jobOrder = append(sc.jobOrder, id)
+}
+
+// managedWrite writes a message to the connection. If the write fails, the
+// connection is closed since a partial message can't be recovered from.
+func (sc *stratumConn) managedWrite(msg interface{}) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	err = sc.staticConn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+	if err == nil {
+		_, err = sc.staticConn.Write(append(b, '\n'))
+	}
+	if err != nil {
+		return errors.Compose(err, sc.staticConn.Close())
+	}
+	return nil
+}
+
+// managedHandleRequest handles a single request of a worker and returns the
+// result of the request.
+func (m *Miner) managedHandleRequest(ss *stratumServer, sc *stratumConn, req stratumRequest) (interface{}, error) {
+	switch req.Method {
+	case "mining.subscribe":
+		sc.mu.Lock()
+		sc.subscribed = true
+		sc.mu.Unlock()
+		return []interface{}{hex.EncodeToString(fastrand.Bytes(8))}, nil
+	case "mining.authorize":
+		var name, password string
+		if len(req.Params) < 2 ||
+			json.Unmarshal(req.Params[0], &name) != nil || name == "" ||
+			json.Unmarshal(req.Params[1], &password) != nil {
+			return nil, errors.New("worker name and password required")
+		}
+		if subtle.ConstantTimeCompare([]byte(password), []byte(ss.staticPassword)) != 1 {
+			return nil, errStratumWrongPassword
+		}
+		ss.mu.Lock()
+		if _, exists := ss.workers[name]; !exists {
+			if len(ss.workers) >= stratumMaxWorkers {
+				ss.mu.Unlock()
+				return nil, errStratumTooManyWorkers
+			}
+			ss.workers[name] = new(stratumWorker)
+		}
+		ss.mu.Unlock()
+		sc.mu.Lock()
+		sc.authorized[name] = struct{}{}
+		sc.mu.Unlock()
+		return true, nil
+	case "mining.submit":
+		var name, jobID, nonceHex string
+		if len(req.Params) < 3 ||
+			json.Unmarshal(req.Params[0], &name) != nil ||
+			json.Unmarshal(req.Params[1], &jobID) != nil ||
+			json.Unmarshal(req.Params[2], &nonceHex) != nil {
+			return nil, errors.New("expected worker name, job id and nonce")
+		}
+		err := m.managedSubmitShare(ss, sc, name, jobID, nonceHex)
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	default:
+		return nil, fmt.Errorf("unknown method '%v'", req.Method)
+	}
+}
+
+// managedPushJob creates a new job for the connection and sends it to the
+// worker.
+func (m *Miner) managedPushJob(ss *stratumServer, sc *stratumConn, clean bool) error {
+	m.mu.Lock()
+	header, target, err := m.headerForWork()
+	m.mu.Unlock()
+	if err != nil {
+		return errors.AddContext(err, "unable to get header for work")
+	}
+	ss.mu.Lock()
+	ss.jobCounter++
+	id := strconv.FormatUint(ss.jobCounter, 16)
+	ss.mu.Unlock()
+
+	job := &stratumJob{
+		header: header,
+		nonces: make(map[types.BlockNonce]struct{}),
+		target: target,
+	}
+	sc.managedAddJob(id, job, clean)
+	return sc.managedWrite(stratumNotification{
+		Method: "mining.notify",
+		Params: []interface{}{id, hex.EncodeToString(encoding.Marshal(header)), clean},
+	})
+}
+
+// managedSubmitShare validates a share submitted by a worker and submits it as
+// a block if it meets the target of the network.
+func (m *Miner) managedSubmitShare(ss *stratumServer, sc *stratumConn, name, jobID, nonceHex string) (err error) {
+	ss.mu.Lock()
+	w, exists := ss.workers[name]
+	ss.mu.Unlock()
+	sc.mu.Lock()
+	_, authorized := sc.authorized[name]
+	sc.mu.Unlock()
+	if !exists || !authorized {
+		return errStratumUnauthorized
+	}
+
+	// Count the share as rejected if it turns out to be invalid.
+	defer func() {
+		if err != nil {
+			ss.mu.Lock()
+			w.rejectedShares++
+			ss.mu.Unlock()
+		}
+	}()
+
+	var nonce types.BlockNonce
+	b, err := hex.DecodeString(nonceHex)
+	if err != nil || len(b) != len(nonce) {
+		return errors.New("nonce must be 8 hex encoded bytes")
+	}
+	copy(nonce[:], b)
+
+	// Look up the job and check for duplicates.
+	sc.mu.Lock()
+	job, exists := sc.jobs[jobID]
+	if !exists {
+		sc.mu.Unlock()
+		return errStratumStaleJob
+	}
+	if _, duplicate := job.nonces[nonce]; duplicate {
+		sc.mu.Unlock()
+		return errStratumDuplicateShare
+	}
+	job.nonces[nonce] = struct{}{}
+	header := job.header
+	target := job.target
+	sc.mu.Unlock()
+
+	// Check the nonce and the difficulty of the share.
+	m.mu.RLock()
+	height := m.persist.Height
+	m.mu.RUnlock()
+	if height+1 >= types.ASICHardforkHeight && binary.LittleEndian.Uint64(nonce[:])%types.ASICHardforkFactor != 0 {
+		return fmt.Errorf("nonce must be a multiple of %v", types.ASICHardforkFactor)
+	}
+	header.Nonce = nonce
+	id := header.ID()
+	if bytes.Compare(ss.staticShareTarget[:], id[:]) < 0 {
+		return errStratumLowDifficulty
+	}
+	ss.mu.Lock()
+	w.acceptedShares++
+	w.lastShareTime = time.Now()
+	w.shareTimes = append(w.shareTimes, w.lastShareTime)
+	w.pruneShareTimes()
+	ss.mu.Unlock()
+
+	// Submit the share as a block if it meets the target of the network.
+	if bytes.Compare(target[:], id[:]) < 0 {
+		return nil
+	}
+	if err := m.SubmitHeader(header); err != nil {
+		m.log.Printf("Stratum worker %v found a block which couldn't be submitted: %v", name, err)
+		return nil
+	}
+	ss.mu.Lock()
+	w.blocksFound++
+	ss.mu.Unlock()
+	m.log.Printf("Stratum worker %v found block %v", name, id)
+	return nil
+}
+
+// threadedHandleStratumConn handles the requests of a single connection.
+func (m *Miner) threadedHandleStratumConn(ss *stratumServer, sc *stratumConn) {
+	if err := m.tg.Add(); err != nil {
+		return
+	}
+	defer m.tg.Done()
+	defer func() {
+		ss.mu.Lock()
+		delete(ss.conns, sc)
+		ss.mu.Unlock()
+		_ = sc.staticConn.Close()
+	}()
+
+	scanner := bufio.NewScanner(sc.staticConn)
+	scanner.Buffer(make([]byte, stratumMaxMessageSize), stratumMaxMessageSize)
+	for scanner.Scan() {
+		var req stratumRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			m.log.Debugln("Invalid stratum request:", err)
+			return
+		}
+		result, err := m.managedHandleRequest(ss, sc, req)
+		resp := stratumResponse{
+			ID:     req.ID,
+			Result: result,
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		if err := sc.managedWrite(resp); err != nil {
+			return
+		}
+
+		// Send the difficulty and a first job after subscribing.
+		if req.Method == "mining.subscribe" {
+			err = sc.managedWrite(stratumNotification{
+				Method: "mining.set_difficulty",
+				Params: []interface{}{ss.staticShareDifficulty},
+			})
+			if err != nil {
+				return
+			}
+			if err := m.managedPushJob(ss, sc, true); err != nil {
+				m.log.Debugln("Unable to push stratum job:", err)
+			}
+		}
+	}
+}
+
+// threadedListenStratum accepts incoming Stratum connections.
+func (m *Miner) threadedListenStratum(ss *stratumServer) {
+	if err := m.tg.Add(); err != nil {
+		return
+	}
+	defer m.tg.Done()
+	for {
+		conn, err := ss.staticListener.Accept()
+		if err != nil {
+			return
+		}
+		sc := &stratumConn{
+			staticConn: conn,
+			authorized: make(map[string]struct{}),
+			jobs:       make(map[string]*stratumJob),
+		}
+		ss.mu.Lock()
+		ss.conns[sc] = struct{}{}
+		ss.mu.Unlock()
+		go m.threadedHandleStratumConn(ss, sc)
+	}
+}
+
+// threadedPushStratumJobs pushes new jobs to all subscribed connections
+// whenever the miner's block changes.
+func (m *Miner) threadedPushStratumJobs(ss *stratumServer) {
+	if err := m.tg.Add(); err != nil {
+		return
+	}
+	defer m.tg.Done()
+	for {
+		select {
+		case <-m.tg.StopChan():
+			return
+		case <-ss.staticWakeChan:
+		}
+		ss.mu.Lock()
+		clean := ss.pendingClean
+		ss.pendingClean = false
+		conns := make([]*stratumConn, 0, len(ss.conns))
+		for sc := range ss.conns {
+			conns = append(conns, sc)
+		}
+		ss.mu.Unlock()
+
+		// Transactions were added to the block, make sure that the new jobs
+		// include them.
+		if !clean {
+			m.mu.Lock()
+			m.newSourceBlock()
+			m.mu.Unlock()
+		}
+
+		// Push the jobs concurrently so that a slow worker doesn't delay the
+		// jobs of the other workers.
+		var wg sync.WaitGroup
+		for _, sc := range conns {
+			sc.mu.Lock()
+			subscribed := sc.subscribed
+			sc.mu.Unlock()
+			if !subscribed {
+				continue
+			}
+			wg.Add(1)
+			go func(sc *stratumConn) {
+				defer wg.Done()
+				if err := m.managedPushJob(ss, sc, clean); err != nil {
+					m.log.Debugln("Unable to push stratum job:", err)
+				}
+			}(sc)
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,200 @@
+package miner
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/encoding"
+
+	"github.com/uplo-tech/uplo/types"
+)
+
+// stratumTestMessage is a message received by a Stratum test client. It can
+// either be a response or a notification.
+type stratumTestMessage struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  interface{}       `json:"error"`
+}
+
+// TestStratum tests handing out jobs and accepting shares with the Stratum
+// server.
+func TestStratum(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	mt, err := createMinerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := mt.miner.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Start the server with the lowest possible share difficulty. A password
+	// is required.
+	if err := mt.miner.StartStratum("localhost:0", "", 1); err != errStratumNoPassword {
+		t.Fatal("expected errStratumNoPassword", err)
+	}
+	if err := mt.miner.StartStratum("localhost:0", "password", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mt.miner.StartStratum("localhost:0", "password", 1); err != errStratumRunning {
+		t.Fatal("expected errStratumRunning", err)
+	}
+	status := mt.miner.StratumStatus()
+	conn, err := net.Dial("tcp", status.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+
+	// call sends a request to the server.
+	call := func(method string, params ...interface{}) {
+		b, err := json.Marshal(map[string]interface{}{
+			"id":     1,
+			"method": method,
+			"params": params,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write(append(b, '\n')); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// read reads the next message from the server.
+	read := func() stratumTestMessage {
+		if err := conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if !scanner.Scan() {
+			t.Fatal("unable to read message", scanner.Err())
+		}
+		var msg stratumTestMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	// readResponse reads messages until a response is received.
+	readResponse := func() stratumTestMessage {
+		for {
+			if msg := read(); msg.Method == "" {
+				return msg
+			}
+		}
+	}
+	// readJob reads messages until a new job is received and returns the id
+	// of the job and its header.
+	readJob := func(clean bool) (string, types.BlockHeader) {
+		for {
+			msg := read()
+			if msg.Method != "mining.notify" {
+				continue
+			}
+			var id, headerHex string
+			var cleanJobs bool
+			err1 := json.Unmarshal(msg.Params[0], &id)
+			err2 := json.Unmarshal(msg.Params[1], &headerHex)
+			err3 := json.Unmarshal(msg.Params[2], &cleanJobs)
+			if err1 != nil || err2 != nil || err3 != nil {
+				t.Fatal("invalid job", err1, err2, err3)
+			}
+			if cleanJobs != clean {
+				continue
+			}
+			b, err := hex.DecodeString(headerHex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var header types.BlockHeader
+			if err := encoding.Unmarshal(b, &header); err != nil {
+				t.Fatal(err)
+			}
+			return id, header
+		}
+	}
+
+	// Subscribe and authorize a worker.
+	call("mining.subscribe")
+	if msg := readResponse(); msg.Error != nil {
+		t.Fatal("unable to subscribe", msg.Error)
+	}
+	jobID, header := readJob(true)
+	call("mining.authorize", "worker", "wrong")
+	if msg := readResponse(); msg.Error == nil {
+		t.Fatal("worker with wrong password was authorized")
+	}
+	call("mining.authorize", "worker", "password")
+	if msg := readResponse(); msg.Error != nil {
+		t.Fatal("unable to authorize", msg.Error)
+	}
+
+	// Every share meets the share difficulty of 1. The same share can't be
+	// submitted twice.
+	nonce := hex.EncodeToString(header.Nonce[:])
+	call("mining.submit", "worker", jobID, nonce)
+	if msg := readResponse(); msg.Error != nil {
+		t.Fatal("share wasn't accepted", msg.Error)
+	}
+	call("mining.submit", "worker", jobID, nonce)
+	if msg := readResponse(); msg.Error == nil {
+		t.Fatal("duplicate share was accepted")
+	}
+	// Shares for unknown jobs and workers are rejected.
+	call("mining.submit", "worker", "unknown", nonce)
+	if msg := readResponse(); msg.Error == nil {
+		t.Fatal("share for unknown job was accepted")
+	}
+	call("mining.submit", "unknown", jobID, nonce)
+	if msg := readResponse(); msg.Error == nil {
+		t.Fatal("share of unknown worker was accepted")
+	}
+	status = mt.miner.StratumStatus()
+	if len(status.Workers) != 1 {
+		t.Fatal("wrong number of workers", len(status.Workers))
+	}
+	w := status.Workers[0]
+	if w.Name != "worker" || w.AcceptedShares != 1 || w.RejectedShares != 2 {
+		t.Fatal("wrong worker stats", w)
+	}
+	if w.LastShareTime.IsZero() {
+		t.Fatal("last share time wasn't set", w)
+	}
+
+	// Only a limited number of workers can authorize.
+	for i := 1; i < stratumMaxWorkers; i++ {
+		call("mining.authorize", fmt.Sprintf("worker%v", i), "password")
+		if msg := readResponse(); msg.Error != nil {
+			t.Fatal("unable to authorize", msg.Error)
+		}
+	}
+	call("mining.authorize", "oneTooMany", "password")
+	if msg := readResponse(); msg.Error == nil {
+		t.Fatal("worker was authorized despite the limit")
+	}
+	call("mining.authorize", "worker", "password")
+	if msg := readResponse(); msg.Error != nil {
+		t.Fatal("known worker should still be able to authorize", msg.Error)
+	}
+
+	// A new block should push a clean job to the worker.
+	if _, err := mt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	newJobID, _ := readJob(true)
+	if newJobID == jobID {
+		t.Fatal("expected a new job")
+	}
+}
@@ -291,6 +291,7 @@ func (m *Miner) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// the stale rate as low as possible.
 	if cc.Synced {
 		m.newSourceBlock()
+		m.notifyStratum(true)
 	}
 	m.persist.RecentChange = cc.ID
 }
@@ -303,6 +304,7 @@ func (m *Miner) ReceiveUpdatedUnconfirmedTransactions(diff *modules.TransactionP
 
 	m.deleteReverts(diff)
 	m.addNewTxns(diff)
+	m.notifyStratum(false)
 }
 
 // removeSplitSetFromUnsolvedBlock removes a split set from the miner's unsolved
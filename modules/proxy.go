@@ -0,0 +1,307 @@
+package modules
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/threadgroup"
+	"golang.org/x/net/proxy"
+
+	"github.com/uplo-tech/uplo/build"
+)
+
+const (
+	// proxyRelayDialTimeout is the timeout for connecting to the target of a
+	// relay through the proxy.
+	proxyRelayDialTimeout = 2 * time.Minute
+)
+
+var (
+	// ErrDirectConnectionRefused is returned when a connection can't be routed
+	// through the proxy and direct connections are refused.
+	ErrDirectConnectionRefused = errors.New("direct connections are refused by the proxy settings")
+
+	// GlobalDialer is the global object for establishing outbound connections
+	// to peers and hosts throughout uplod. It is set using the uplod config.
+	GlobalDialer = new(ProxyDialer)
+
+	// proxyRelayIdleTimeout is the time after which a relay without open
+	// connections is closed.
+	proxyRelayIdleTimeout = build.Select(build.Var{
+		Standard: 10 * time.Minute,
+		Dev:      time.Minute,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+)
+
+// ProxyDialer establishes outbound tcp connections, either directly or through
+// a SOCKS5 proxy such as a local Tor daemon. Hostnames are resolved by the
+// proxy to avoid leaking DNS requests.
+type ProxyDialer struct {
+	address   string
+	proxyOnly bool
+
+	// relays are the local relays of RelayAddress by their target address.
+	relays         map[string]*proxyRelay
+	janitorRunning bool
+
+	mu sync.RWMutex
+	tg threadgroup.ThreadGroup
+}
+
+// proxyRelay is a local listener which forwards every connection to its
+// target through the proxy.
+type proxyRelay struct {
+	listener net.Listener
+	target   string
+	active   int
+	lastUsed time.Time
+}
+
+// Dial connects to the address using the timeout, cancel channel and local
+// address of the provided dialer. If a proxy is set, the dialer is used to
+// connect to the proxy, which connects to the address.
+func (pd *ProxyDialer) Dial(dialer *net.Dialer, address string) (net.Conn, error) {
+	pd.mu.RLock()
+	proxyAddress := pd.address
+	pd.mu.RUnlock()
+	if proxyAddress == "" {
+		return dialer.Dial("tcp", address)
+	}
+
+	// The proxy might accept the connection and then stall the handshake, so
+	// the timeout and cancel channel apply to the whole dial.
+	ctx := context.Background()
+	if dialer.Timeout != 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, dialer.Timeout)
+		defer cancelTimeout()
+	}
+	if dialer.Cancel != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-dialer.Cancel:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	forward := &net.Dialer{
+		LocalAddr: dialer.LocalAddr,
+	}
+	socks, err := proxy.SOCKS5("tcp", proxyAddress, nil, forward)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to create proxy dialer")
+	}
+	contextDialer, ok := socks.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("proxy dialer doesn't support contexts")
+	}
+	conn, err := contextDialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to connect through proxy")
+	}
+	return conn, nil
+}
+
+// HTTPClient returns an http client which establishes its connections using
+// the dialer. A timeout of zero means no timeout.
+func (pd *ProxyDialer) HTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: timeout}
+		if deadline, ok := ctx.Deadline(); ok {
+			dialer.Deadline = deadline
+		}
+		return pd.Dial(dialer, address)
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// DirectAllowed returns ErrDirectConnectionRefused if connections which can't
+// be routed through the proxy are not allowed.
+func (pd *ProxyDialer) DirectAllowed() error {
+	pd.mu.RLock()
+	defer pd.mu.RUnlock()
+	if pd.address != "" && pd.proxyOnly {
+		return ErrDirectConnectionRefused
+	}
+	return nil
+}
+
+// Close closes the relays of the dialer and stops its background threads.
+func (pd *ProxyDialer) Close() error {
+	err := pd.tg.Stop()
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	pd.closeRelays()
+	return err
+}
+
+// Settings returns the address of the proxy and whether direct connections
+// are refused.
+func (pd *ProxyDialer) Settings() (address string, proxyOnly bool) {
+	pd.mu.RLock()
+	defer pd.mu.RUnlock()
+	return pd.address, pd.proxyOnly
+}
+
+// SetProxy sets the address of the proxy and whether connections which can't
+// be routed through the proxy are refused. An empty address disables the
+// proxy.
+func (pd *ProxyDialer) SetProxy(address string, proxyOnly bool) error {
+	if address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return errors.AddContext(err, "invalid proxy address")
+		}
+	} else if proxyOnly {
+		return errors.New("can't refuse direct connections without a proxy")
+	}
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	pd.address = address
+	pd.proxyOnly = proxyOnly
+
+	// Close the relays of the old settings. Established connections are kept
+	// until they are closed.
+	pd.closeRelays()
+	return nil
+}
+
+// closeRelays closes the listeners of all relays.
+func (pd *ProxyDialer) closeRelays() {
+	for target, r := range pd.relays {
+		r.listener.Close()
+		delete(pd.relays, target)
+	}
+}
+
+// RelayAddress returns the address to connect to in order to reach the target
+// address with libraries that dial on their own, like the uplomux. Without a
+// proxy, that's the target address itself. Otherwise it's the address of a
+// local relay which forwards every connection to the target through the
+// proxy, so that the target is never dialed directly. The relay only accepts
+// connections from this process.
+func (pd *ProxyDialer) RelayAddress(address string) (string, error) {
+	if err := pd.tg.Add(); err != nil {
+		return "", err
+	}
+	defer pd.tg.Done()
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	if pd.address == "" {
+		return address, nil
+	}
+	if r, exists := pd.relays[address]; exists {
+		r.lastUsed = time.Now()
+		return r.listener.Addr().String(), nil
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", errors.AddContext(err, "unable to create proxy relay")
+	}
+	r := &proxyRelay{
+		listener: l,
+		target:   address,
+		lastUsed: time.Now(),
+	}
+	if pd.relays == nil {
+		pd.relays = make(map[string]*proxyRelay)
+	}
+	pd.relays[address] = r
+	go pd.threadedServeRelay(r)
+	if !pd.janitorRunning {
+		pd.janitorRunning = true
+		go pd.threadedCloseIdleRelays()
+	}
+	return l.Addr().String(), nil
+}
+
+// threadedCloseIdleRelays periodically closes the relays which haven't been
+// used for proxyRelayIdleTimeout. It returns once there are no relays left.
+func (pd *ProxyDialer) threadedCloseIdleRelays() {
+	if err := pd.tg.Add(); err != nil {
+		return
+	}
+	defer pd.tg.Done()
+
+	for {
+		select {
+		case <-pd.tg.StopChan():
+			return
+		case <-time.After(proxyRelayIdleTimeout / 2):
+		}
+		pd.mu.Lock()
+		for target, r := range pd.relays {
+			if r.active == 0 && time.Since(r.lastUsed) > proxyRelayIdleTimeout {
+				r.listener.Close()
+				delete(pd.relays, target)
+			}
+		}
+		if len(pd.relays) == 0 {
+			pd.janitorRunning = false
+			pd.mu.Unlock()
+			return
+		}
+		pd.mu.Unlock()
+	}
+}
+
+// threadedServeRelay accepts connections to the relay until it's closed.
+// Connections from other processes are closed right away, since they would
+// otherwise be able to reach the target through the proxy.
+func (pd *ProxyDialer) threadedServeRelay(r *proxyRelay) {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		if !isOwnConn(conn) {
+			conn.Close()
+			continue
+		}
+		go pd.threadedRelayConn(r, conn)
+	}
+}
+
+// threadedRelayConn connects to the target of the relay through the proxy and
+// copies data between the connections until either of them is closed.
+func (pd *ProxyDialer) threadedRelayConn(r *proxyRelay, conn net.Conn) {
+	pd.mu.Lock()
+	r.active++
+	pd.mu.Unlock()
+	defer func() {
+		pd.mu.Lock()
+		r.active--
+		r.lastUsed = time.Now()
+		pd.mu.Unlock()
+	}()
+	defer conn.Close()
+
+	target, err := pd.Dial(&net.Dialer{Timeout: proxyRelayDialTimeout}, r.target)
+	if err != nil {
+		return
+	}
+	defer target.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(target, conn)
+		target.Close()
+	}()
+	_, _ = io.Copy(conn, target)
+	conn.Close()
+	<-done
+}
@@ -0,0 +1,349 @@
+package modules
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+)
+
+// serveSOCKS5 is a minimal SOCKS5 server which supports unauthenticated
+// CONNECT requests. Targets found in hosts are connected to the mapped address
+// instead. It returns the listener of the server and a channel which receives
+// the target of every request.
+func serveSOCKS5(t *testing.T, hosts map[string]string) (net.Listener, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				// Read the greeting and choose no authentication.
+				greeting := make([]byte, 2)
+				if _, err := io.ReadFull(conn, greeting); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, greeting[1])); err != nil {
+					return
+				}
+				if _, err := conn.Write([]byte{5, 0}); err != nil {
+					return
+				}
+				// Read the request.
+				header := make([]byte, 4)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				var host string
+				switch header[3] {
+				case 1:
+					ip := make([]byte, 4)
+					if _, err := io.ReadFull(conn, ip); err != nil {
+						return
+					}
+					host = net.IP(ip).String()
+				case 3:
+					length := make([]byte, 1)
+					if _, err := io.ReadFull(conn, length); err != nil {
+						return
+					}
+					name := make([]byte, length[0])
+					if _, err := io.ReadFull(conn, name); err != nil {
+						return
+					}
+					host = string(name)
+				default:
+					return
+				}
+				port := make([]byte, 2)
+				if _, err := io.ReadFull(conn, port); err != nil {
+					return
+				}
+				target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+				targets <- target
+				if mapped, exists := hosts[target]; exists {
+					target = mapped
+				}
+				targetConn, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer targetConn.Close()
+				if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+					return
+				}
+				go io.Copy(targetConn, conn)
+				io.Copy(conn, targetConn)
+			}()
+		}
+	}()
+	return l, targets
+}
+
+// TestProxyDialer probes dialing through a SOCKS5 proxy.
+func TestProxyDialer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a target which echoes a message.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	proxy, targets := serveSOCKS5(t, nil)
+	defer proxy.Close()
+
+	// Invalid settings should be rejected.
+	var pd ProxyDialer
+	if err := pd.SetProxy("no port", false); err == nil {
+		t.Fatal("expected invalid address to be rejected")
+	}
+	if err := pd.SetProxy("", true); err == nil {
+		t.Fatal("expected proxy only without address to be rejected")
+	}
+	if err := pd.DirectAllowed(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Route connections through the proxy.
+	if err := pd.SetProxy(proxy.Addr().String(), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := pd.DirectAllowed(); !errors.Contains(err, ErrDirectConnectionRefused) {
+		t.Fatal("expected ErrDirectConnectionRefused", err)
+	}
+	conn, err := pd.Dial(&net.Dialer{Timeout: time.Minute}, l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if target := <-targets; target != l.Addr().String() {
+		t.Fatalf("proxy received target %v, expected %v", target, l.Addr())
+	}
+	msg := []byte("hello")
+	if _, err := conn.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	resp := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != string(msg) {
+		t.Fatalf("expected %s, got %s", msg, resp)
+	}
+
+	// A cancelled dial should fail.
+	cancel := make(chan struct{})
+	close(cancel)
+	if _, err := pd.Dial(&net.Dialer{Cancel: cancel}, l.Addr().String()); err == nil {
+		t.Fatal("expected cancelled dial to fail")
+	}
+}
+
+// TestProxyDialerRelay checks that connections to a relay are routed through
+// the proxy without dialing the target directly.
+func TestProxyDialerRelay(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a target which echoes a message.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	// The target is only reachable through the proxy, since its hostname
+	// can't be resolved. A direct dial would fail.
+	target := "host.invalid:9982"
+	proxy, targets := serveSOCKS5(t, map[string]string{target: l.Addr().String()})
+	defer proxy.Close()
+
+	// Without a proxy, the target itself is returned.
+	var pd ProxyDialer
+	address, err := pd.RelayAddress(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != target {
+		t.Fatalf("expected %v, got %v", target, address)
+	}
+
+	// With a proxy, a relay is returned and reused.
+	if err := pd.SetProxy(proxy.Addr().String(), false); err != nil {
+		t.Fatal(err)
+	}
+	relay, err := pd.RelayAddress(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relay == target {
+		t.Fatal("expected a relay address")
+	}
+	if address, err := pd.RelayAddress(target); err != nil || address != relay {
+		t.Fatal("relay wasn't reused", address, err)
+	}
+
+	conn, err := net.Dial("tcp", relay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	msg := []byte("hello")
+	if _, err := conn.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	resp := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != string(msg) {
+		t.Fatalf("expected %s, got %s", msg, resp)
+	}
+	if proxyTarget := <-targets; proxyTarget != target {
+		t.Fatalf("proxy received target %v, expected %v", proxyTarget, target)
+	}
+
+	// Changing the proxy settings closes the relay.
+	if err := pd.SetProxy("", false); err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := net.Dial("tcp", relay); err == nil {
+		conn.Close()
+		t.Fatal("relay should be closed")
+	}
+}
+
+// TestProxyOnlyNoDirectConnections checks that the connections which uplod
+// establishes with the global dialer are routed through the proxy if direct
+// connections are refused.
+func TestProxyOnlyNoDirectConnections(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	// Create a target which serves http requests. It's only reachable through
+	// the proxy, since its hostname can't be resolved.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		_ = http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		}))
+	}()
+	target := "host.invalid:80"
+	proxy, targets := serveSOCKS5(t, map[string]string{target: l.Addr().String()})
+	defer proxy.Close()
+
+	if err := GlobalDialer.SetProxy(proxy.Addr().String(), true); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := GlobalDialer.SetProxy("", false); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if err := GlobalDialer.DirectAllowed(); !errors.Contains(err, ErrDirectConnectionRefused) {
+		t.Fatal("expected ErrDirectConnectionRefused", err)
+	}
+
+	// Dial the target using the production dependencies.
+	conn, err := ProdDependencies.DialTimeout(NetAddress(target), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if proxyTarget := <-targets; proxyTarget != target {
+		t.Fatalf("proxy received target %v, expected %v", proxyTarget, target)
+	}
+
+	// Send an http request to the target.
+	resp, err := GlobalDialer.HTTPClient(time.Minute).Get("http://" + target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err := errors.Compose(err, resp.Body.Close()); err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "hello" {
+		t.Fatalf("expected hello, got %s", body)
+	}
+	if proxyTarget := <-targets; proxyTarget != target {
+		t.Fatalf("proxy received target %v, expected %v", proxyTarget, target)
+	}
+}
+
+// TestProxyDialerClose checks that closing the dialer closes its relays.
+func TestProxyDialerClose(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	proxy, _ := serveSOCKS5(t, nil)
+	defer proxy.Close()
+	var pd ProxyDialer
+	if err := pd.SetProxy(proxy.Addr().String(), false); err != nil {
+		t.Fatal(err)
+	}
+	relay, err := pd.RelayAddress("host.invalid:9982")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := net.Dial("tcp", relay); err == nil {
+		conn.Close()
+		t.Fatal("relay should be closed")
+	}
+	if _, err := pd.RelayAddress("host.invalid:9982"); err == nil {
+		t.Fatal("expected relay to be refused after closing the dialer")
+	}
+}
@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/uplo-tech/errors"
+)
+
+// isOwnConn returns whether a connection accepted by a local relay was
+// established by this process. The socket of the connecting end is looked up
+// in /proc/net/tcp and compared against the open file descriptors of the
+// process.
+func isOwnConn(conn net.Conn) bool {
+	relayAddr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	peerAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	inode, err := tcpSocketInode(peerAddr, relayAddr)
+	if err != nil {
+		return false
+	}
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return false
+	}
+	socket := fmt.Sprintf("socket:[%s]", inode)
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && link == socket {
+			return true
+		}
+	}
+	return false
+}
+
+// tcpSocketInode returns the inode of the IPv4 tcp socket with the given local
+// and remote address.
+func tcpSocketInode(local, remote *net.TCPAddr) (_ string, err error) {
+	f, err := os.Open("/proc/net/tcp")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		err = errors.Compose(err, f.Close())
+	}()
+	localHex, remoteHex := procNetAddr(local), procNetAddr(remote)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if fields[1] == localHex && fields[2] == remoteHex {
+			return fields[9], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("socket not found")
+}
+
+// procNetAddr formats an IPv4 address the way /proc/net/tcp does on little
+// endian machines.
+func procNetAddr(addr *net.TCPAddr) string {
+	ip := addr.IP.To4()
+	if ip == nil {
+		return ""
+	}
+	return fmt.Sprintf("%02X%02X%02X%02X:%04X", ip[3], ip[2], ip[1], ip[0], addr.Port)
+}
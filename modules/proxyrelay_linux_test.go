@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+package modules
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// relayHelperAddrEnv is the environment variable that makes
+// TestIsOwnConnHelper connect to the address it contains.
+const relayHelperAddrEnv = "UPLO_TEST_RELAY_HELPER_ADDR"
+
+// TestIsOwnConnHelper is run in a separate process by TestIsOwnConn to
+// connect to a listener from another process.
+func TestIsOwnConnHelper(t *testing.T) {
+	addr := os.Getenv(relayHelperAddrEnv)
+	if addr == "" {
+		t.SkipNow()
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// Wait for the listener to close the connection.
+	_, _ = conn.Read(make([]byte, 1))
+}
+
+// TestIsOwnConn checks that connections from this process are told apart from
+// connections from other processes.
+func TestIsOwnConn(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// A connection from this process is accepted.
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	accepted, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isOwnConn(accepted) {
+		t.Fatal("connection from this process wasn't recognized")
+	}
+	accepted.Close()
+
+	// A connection from another process is refused.
+	cmd := exec.Command(os.Args[0], "-test.run=TestIsOwnConnHelper")
+	cmd.Env = append(os.Environ(), relayHelperAddrEnv+"="+l.Addr().String())
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Wait()
+	}()
+	if err := l.(*net.TCPListener).SetDeadline(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	accepted, err = l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer accepted.Close()
+	if isOwnConn(accepted) {
+		t.Fatal("connection from another process was accepted")
+	}
+}
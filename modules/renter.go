@@ -407,6 +407,61 @@ type DirectoryInfo struct {
 	// Skynet Fields
 	SkynetFiles uint64 `json:"skynetfiles"`
 	SkynetSize  uint64 `json:"skynetsize"`
+
+	// Versioning is the versioning policy of the uplodir.
+	Versioning VersioningPolicy `json:"versioning"`
+}
+
+// VersioningPolicy is the versioning policy of a uplodir. If versioning is
+// enabled, files within the uplodir and its subdirectories are kept as
+// versions when they are deleted or overwritten.
+type VersioningPolicy struct {
+	// Enabled indicates whether versioning is enabled.
+	Enabled bool `json:"enabled"`
+
+	// MaxAge is the maximum age of a version before it is pruned. A MaxAge of
+	// 0 means that versions are never pruned due to their age.
+	MaxAge time.Duration `json:"maxage"`
+
+	// MaxVersions is the maximum number of versions kept per file. A
+	// MaxVersions of 0 means that the number of versions is unlimited.
+	MaxVersions uint64 `json:"maxversions"`
+}
+
+// FileVersion is a previous version of a file.
+type FileVersion struct {
+	// CreateTime is the time at which the file was deleted or overwritten and
+	// the version was created.
+	CreateTime time.Time `json:"createtime"`
+
+	// Filesize is the size of the file version.
+	Filesize uint64 `json:"filesize"`
+
+	// ID identifies the version among the versions of a file.
+	ID string `json:"id"`
+
+	// UploPath is the path of the hidden uplofile of the version within the
+	// versions folder.
+	UploPath UploPath `json:"uplopath"`
+}
+
+// TrashedItem is a file or directory which was moved to the trash when it was
+// deleted.
+type TrashedItem struct {
+	// DeleteTime is the time at which the item was moved to the trash.
+	DeleteTime time.Time `json:"deletetime"`
+
+	// ID identifies the item within the trash.
+	ID string `json:"id"`
+
+	// IsDir indicates whether the item is a directory.
+	IsDir bool `json:"isdir"`
+
+	// TrashPath is the path of the item within the trash folder.
+	TrashPath UploPath `json:"trashpath"`
+
+	// UploPath is the path of the item before it was deleted.
+	UploPath UploPath `json:"uplopath"`
 }
 
 // Name implements os.FileInfo.
@@ -463,6 +518,15 @@ type FileUploadParams struct {
 	// to create a CipherKey with the given CipherType. This value override
 	// CipherType if it is set.
 	CipherKey crypto.CipherKey
+
+	// Dedup enables content-addressed deduplication for the file. Chunks
+	// which were already uploaded by another deduplicated file with the same
+	// erasure coding and cipher type reuse the existing pieces.
+	Dedup bool
+
+	// Compression is the type of compression applied to the file before it
+	// is encrypted and uploaded.
+	Compression CompressionType
 }
 
 // FileInfo provides information about a file.
@@ -471,6 +535,8 @@ type FileInfo struct {
 	Available        bool              `json:"available"`
 	ChangeTime       time.Time         `json:"changetime"`
 	CipherType       string            `json:"ciphertype"`
+	CompressedSize   uint64            `json:"compressedsize"`
+	Compression      string            `json:"compression"`
 	CreateTime       time.Time         `json:"createtime"`
 	Expiration       types.BlockHeight `json:"expiration"`
 	Filesize         uint64            `json:"filesize"`
@@ -645,6 +711,7 @@ type RenterSettings struct {
 	IPViolationCheck bool          `json:"ipviolationcheck"`
 	MaxUploadSpeed   int64         `json:"maxuploadspeed"`
 	MaxDownloadSpeed int64         `json:"maxdownloadspeed"`
+	TrashRetention   time.Duration `json:"trashretention"`
 	UploadsStatus    UploadsStatus `json:"uploadsstatus"`
 }
 
@@ -967,6 +1034,7 @@ type (
 // user.
 type Renter interface {
 	Alerter
+	EventSubscriber
 
 	// ActiveHosts provides the list of hosts that the renter is selecting,
 	// sorted by preference.
@@ -1177,6 +1245,32 @@ type Renter interface {
 	// DirList lists the directories in a uplodir
 	DirList(uploPath UploPath) ([]DirectoryInfo, error)
 
+	// SetDirVersioning sets the versioning policy of a uplodir.
+	SetDirVersioning(uploPath UploPath, policy VersioningPolicy) error
+
+	// FileVersions returns the previous versions of a file, newest first.
+	FileVersions(uploPath UploPath) ([]FileVersion, error)
+
+	// PruneFileVersions removes the versions of the file or of all the files
+	// within the directory at the given path which exceed the provided limits.
+	// If no limits are provided, the versioning policies of the uplodirs
+	// apply.
+	PruneFileVersions(uploPath UploPath, maxVersions uint64, maxAge time.Duration) error
+
+	// RestoreFileVersion restores a previous version of a file. If the file
+	// exists, it is replaced and kept as a version.
+	RestoreFileVersion(uploPath UploPath, id string) error
+
+	// EmptyTrash permanently deletes all the items within the trash.
+	EmptyTrash() error
+
+	// RestoreTrashedItem moves an item from the trash back to its original
+	// location.
+	RestoreTrashedItem(id string) error
+
+	// Trash returns the items within the trash, most recently deleted first.
+	Trash() ([]TrashedItem, error)
+
 	// AddSkykey adds the skykey to the renter's skykey manager.
 	AddSkykey(skykey.Skykey) error
 
@@ -1206,6 +1300,29 @@ type Renter interface {
 	// Skykeys returns a slice containing each Skykey being stored by the renter.
 	Skykeys() ([]skykey.Skykey, error)
 
+	// RotateSkykey creates a new skykey with the given name and starts
+	// re-encrypting all skyfiles of the renter that were encrypted with the
+	// skykey with the given ID using the new skykey in the background. If
+	// blocklist is true, the old skylinks are added to the skynet blocklist.
+	RotateSkykey(oldID skykey.SkykeyID, newName string, blocklist bool) (SkykeyRotation, error)
+
+	// SkykeyRotation returns the state of the rotation to the skykey with the
+	// given ID.
+	SkykeyRotation(newID skykey.SkykeyID) (SkykeyRotation, error)
+
+	// SkykeySharePublicKey returns the X25519 public key other users can
+	// share skykeys with the renter for.
+	SkykeySharePublicKey() (crypto.X25519PublicKey, error)
+
+	// ShareSkykey wraps the skykey with the given ID for the owner of the
+	// given X25519 public key.
+	ShareSkykey(skykey.SkykeyID, crypto.X25519PublicKey) (skykey.SkykeyShare, error)
+
+	// ImportSharedSkykey opens a skykey that was shared with the renter and
+	// adds it to the renter's skykey manager. If the name is not empty, the
+	// skykey is stored under that name.
+	ImportSharedSkykey(skykey.SkykeyShare, string) (skykey.Skykey, error)
+
 	// CreateSkylinkFromUplofile will create a skylink from a uplofile. This will
 	// result in some uploading - the base sector skyfile needs to be uploaded
 	// separately, and if there is a fanout expansion that needs to be uploaded
@@ -1314,6 +1431,7 @@ func NeedsRepair(health float64) bool {
 // to upload to, and download from.
 type HostDB interface {
 	Alerter
+	EventSubscriber
 
 	// ActiveHosts returns the list of hosts that are actively being selected
 	// from.
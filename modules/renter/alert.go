@@ -15,3 +15,22 @@ func (r *Renter) Alerts() (crit, err, warn []modules.Alert) {
 	warn = append(append(renterWarn, contractorWarn...), hostdbWarn...)
 	return crit, err, warn
 }
+
+// SubscribeEvents implements the modules.EventSubscriber interface for the
+// renter. Subscribers are notified about the events of the renter and its
+// submodules.
+func (r *Renter) SubscribeEvents(l modules.EventListener) {
+	r.staticAlerter.SubscribeEvents(l)
+	r.staticEvents.SubscribeEvents(l)
+	r.hostContractor.SubscribeEvents(l)
+	r.hostDB.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents implements the modules.EventSubscriber interface for the
+// renter.
+func (r *Renter) UnsubscribeEvents(l modules.EventListener) {
+	r.staticAlerter.UnsubscribeEvents(l)
+	r.staticEvents.UnsubscribeEvents(l)
+	r.hostContractor.UnsubscribeEvents(l)
+	r.hostDB.UnsubscribeEvents(l)
+}
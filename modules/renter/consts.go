@@ -318,6 +318,22 @@ var (
 		Standard: 5 * time.Minute,
 		Testing:  5 * time.Second,
 	}).(time.Duration)
+
+	// trashPurgeInterval defines how long the renter sleeps between purging
+	// expired items from the trash.
+	trashPurgeInterval = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: time.Hour,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// versionPruneInterval defines how long the renter sleeps between pruning
+	// the file versions which exceed the versioning policies.
+	versionPruneInterval = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: time.Hour,
+		Testing:  time.Second,
+	}).(time.Duration)
 )
 
 // Constants that tune the worker swarm.
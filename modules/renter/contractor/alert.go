@@ -7,3 +7,18 @@ import "github.com/uplo-tech/uplo/modules"
 func (c *Contractor) Alerts() (crit, err, warn []modules.Alert) {
 	return c.staticAlerter.Alerts()
 }
+
+// SubscribeEvents implements the modules.EventSubscriber interface for the
+// contractor. Subscribers are notified about alerts and about contracts that
+// are formed, renewed or expire.
+func (c *Contractor) SubscribeEvents(l modules.EventListener) {
+	c.staticAlerter.SubscribeEvents(l)
+	c.staticEvents.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents implements the modules.EventSubscriber interface for the
+// contractor.
+func (c *Contractor) UnsubscribeEvents(l modules.EventListener) {
+	c.staticAlerter.UnsubscribeEvents(l)
+	c.staticEvents.UnsubscribeEvents(l)
+}
@@ -434,6 +434,11 @@ func (c *Contractor) managedNewContract(host modules.HostDBEntry, contractFundin
 
 	contractValue := contract.RenterFunds
 	c.log.Printf("Formed contract %v with %v for %v", contract.ID, host.NetAddress, contractValue.HumanString())
+	c.staticEvents.Notify(modules.EventContractFormed, modules.ContractEvent{
+		ID:            contract.ID,
+		HostPublicKey: contract.HostPublicKey,
+		EndHeight:     contract.EndHeight,
+	})
 
 	// Update the hostdb to include the new contract.
 	err = c.hdb.UpdateContracts(c.staticContracts.ViewAll())
@@ -907,6 +912,12 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 	c.mu.Unlock()
 	// Delete the old contract.
 	c.staticContracts.Delete(oldContract)
+	c.staticEvents.Notify(modules.EventContractRenewed, modules.ContractEvent{
+		ID:            newContract.ID,
+		HostPublicKey: newContract.HostPublicKey,
+		EndHeight:     newContract.EndHeight,
+		RenewedFrom:   id,
+	})
 
 	// Signal to the watchdog that it should immediately post the last
 	// revision for this contract.
@@ -58,6 +58,7 @@ type Contractor struct {
 	persistDir    string
 	staticAlerter *modules.GenericAlerter
 	staticDeps    modules.Dependencies
+	staticEvents  modules.EventNotifier
 	tg            threadgroup.ThreadGroup
 	tpool         modules.TransactionPool
 	wallet        modules.Wallet
@@ -42,6 +42,7 @@ func (c *Contractor) managedArchiveContracts() {
 	// Loop through the current set of contracts and migrate any expired ones to
 	// the set of old contracts.
 	var expired []types.FileContractID
+	var expiredUnrenewed []modules.RenterContract
 	for _, contract := range c.staticContracts.ViewAll() {
 		// Check map of renewedTo in case renew code was interrupted before
 		// archiving old contract
@@ -54,6 +55,9 @@ func (c *Contractor) managedArchiveContracts() {
 			c.oldContracts[id] = contract
 			c.mu.Unlock()
 			expired = append(expired, id)
+			if !renewed {
+				expiredUnrenewed = append(expiredUnrenewed, contract)
+			}
 			c.log.Println("INFO: archived expired contract", id)
 		}
 	}
@@ -69,6 +73,16 @@ func (c *Contractor) managedArchiveContracts() {
 			c.staticContracts.Delete(sc)
 		}
 	}
+
+	// Notify subscribers about the contracts which expired without being
+	// renewed.
+	for _, contract := range expiredUnrenewed {
+		c.staticEvents.Notify(modules.EventContractExpired, modules.ContractEvent{
+			ID:            contract.ID,
+			HostPublicKey: contract.HostPublicKey,
+			EndHeight:     contract.EndHeight,
+		})
+	}
 }
 
 // ProcessConsensusChange will be called by the consensus set every time there
@@ -0,0 +1,739 @@
+package renter
+
+// dedup.go contains the renter's content-addressed deduplication index. Files
+// which are uploaded with deduplication enabled are encrypted with a master key
+// that is derived from the renter seed instead of a random one. Two chunks with
+// the same plaintext, erasure coding and cipher type that are encrypted for the
+// same chunk index will therefore result in the same physical pieces. The index
+// maps the hash of the plaintext of a chunk together with these parameters to
+// the pieces that already exist on the network and the chunk index they were
+// encrypted for. This allows the renter to add those pieces to a new file
+// instead of uploading them again, independent of the chunk's offset within
+// the file. The chunk of the new file remembers the chunk index its pieces
+// were encrypted for.
+//
+// Every chunk of a file which references an entry in the index counts as one
+// reference to the entry. Once the last file referencing an entry is deleted,
+// the entry is dropped from the index. Since the pieces of an entry are shared
+// between files, the index needs to be consulted before the renter ever drops
+// sectors from its contracts.
+//
+// The index is persisted as an append-only log of changes which is replayed on
+// startup. The log is compacted on startup once it contains a lot more records
+// than needed to describe the index.
+//
+// NOTE: Deriving the master key from the renter seed is a form of convergent
+// encryption. It allows anyone with access to the renter seed to confirm
+// whether or not the renter stored a known plaintext. It does not leak any
+// information to the hosts beyond the fact that two pieces are identical.
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/fastrand"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/modules/renter/filesystem/uplofile"
+	"github.com/uplo-tech/uplo/persist"
+	"github.com/uplo-tech/uplo/types"
+)
+
+const (
+	// dedupPersistFilename is the filename of the dedup log within the
+	// renter's persist directory.
+	dedupPersistFilename = "dedup"
+
+	// dedupCompactFilename is the filename of the log which is written when
+	// compacting the dedup log.
+	dedupCompactFilename = dedupPersistFilename + "_compact"
+
+	// dedupCompatFilename is the filename of the dedup index before it was
+	// persisted as a log.
+	dedupCompatFilename = "dedup.json"
+)
+
+const (
+	// dedupRecordTrackFile marks a file as deduplicated.
+	dedupRecordTrackFile uint8 = iota + 1
+
+	// dedupRecordRemoveFile removes a file and all its references.
+	dedupRecordRemoveFile
+
+	// dedupRecordEntry sets the pieces of an entry.
+	dedupRecordEntry
+
+	// dedupRecordRef adds a reference from the chunk of a file to an entry.
+	dedupRecordRef
+)
+
+var (
+	// dedupKeySpecifier is the specifier used for deriving the master key of
+	// deduplicated files from the RenterSeed.
+	dedupKeySpecifier = types.NewSpecifier("dedup")
+
+	// dedupMetadataHeader is the header of the dedup log.
+	dedupMetadataHeader = types.NewSpecifier("DedupIndex\n")
+
+	// dedupMetadataVersion is the version of the dedup log.
+	dedupMetadataVersion = types.NewSpecifier("v1.5.4\n")
+
+	// dedupCompactThreshold is the minimum number of obsolete records in the
+	// dedup log before it is compacted.
+	dedupCompactThreshold = build.Select(build.Var{
+		Dev:      100,
+		Standard: 1000,
+		Testing:  10,
+	}).(int)
+
+	// dedupCompatMetadata is the metadata of the dedup index before it was
+	// persisted as a log.
+	dedupCompatMetadata = persist.Metadata{
+		Header:  "Renter Dedup Index",
+		Version: "1.0",
+	}
+)
+
+type (
+	// dedupIndex is the renter's content-addressed index of uploaded chunks.
+	dedupIndex struct {
+		// entries maps the dedup key of a chunk to the pieces of the chunk.
+		entries map[crypto.Hash]*dedupEntry
+
+		// files contains the files that were uploaded with deduplication
+		// enabled. For every file it maps the chunk index to the key of the
+		// entry referenced by the chunk.
+		files map[uplofile.UplofileUID]map[uint64]crypto.Hash
+
+		// numRecords is the number of records in the log.
+		numRecords int
+
+		staticAop        *persist.AppendOnlyPersist
+		staticPersistDir string
+		mu               sync.Mutex
+	}
+
+	// dedupEntry is a single entry of the dedup index.
+	dedupEntry struct {
+		// keyIndex is the chunk index the pieces were encrypted for.
+		keyIndex uint64
+		pieces   [][]uplofile.Piece
+		refs     map[dedupRef]struct{}
+	}
+
+	// dedupRef is a reference to an entry of the dedup index by the chunk of
+	// a file.
+	dedupRef struct {
+		FileUID    uplofile.UplofileUID `json:"fileuid"`
+		ChunkIndex uint64               `json:"chunkindex"`
+	}
+
+	// dedupRecord is a single change of the dedup index within the dedup log.
+	// Only the fields which are relevant for the record's type are set.
+	dedupRecord struct {
+		Type       uint8
+		Key        crypto.Hash
+		FileUID    uplofile.UplofileUID
+		ChunkIndex uint64
+		KeyIndex   uint64
+		Pieces     [][]uplofile.Piece
+	}
+
+	// dedupCompatPersist is the on-disk representation of the dedup index
+	// before it was persisted as a log.
+	dedupCompatPersist struct {
+		Entries []dedupCompatPersistEntry `json:"entries"`
+		Files   []uplofile.UplofileUID    `json:"files"`
+	}
+
+	// dedupCompatPersistEntry is the on-disk representation of a dedupEntry
+	// before the index was persisted as a log.
+	dedupCompatPersistEntry struct {
+		Key    crypto.Hash        `json:"key"`
+		Pieces [][]uplofile.Piece `json:"pieces"`
+		Refs   []dedupRef         `json:"refs"`
+	}
+)
+
+// newDedupIndex loads the dedup index from the persist directory or creates a
+// new one if it doesn't exist yet.
+func newDedupIndex(persistDir string) (*dedupIndex, error) {
+	di := &dedupIndex{
+		entries:          make(map[crypto.Hash]*dedupEntry),
+		files:            make(map[uplofile.UplofileUID]map[uint64]crypto.Hash),
+		staticPersistDir: persistDir,
+	}
+	aop, reader, err := persist.NewAppendOnlyPersist(persistDir, dedupPersistFilename, dedupMetadataHeader, dedupMetadataVersion)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to open dedup log")
+	}
+	di.staticAop = aop
+	if err := di.load(reader); err != nil {
+		return nil, errors.Compose(errors.AddContext(err, "unable to load dedup log"), aop.Close())
+	}
+	if err := di.convertCompat(); err != nil {
+		return nil, errors.Compose(errors.AddContext(err, "unable to convert dedup index"), di.staticAop.Close())
+	}
+	if di.numRecords-di.numLiveRecords() > dedupCompactThreshold {
+		if err := di.compact(); err != nil {
+			return nil, errors.Compose(errors.AddContext(err, "unable to compact dedup log"), di.staticAop.Close())
+		}
+	}
+	return di, nil
+}
+
+// Close closes the dedup log.
+func (di *dedupIndex) Close() error {
+	return di.staticAop.Close()
+}
+
+// dedupChunkKey returns the key of a chunk within the dedup index. The erasure
+// coder and cipher type are part of the key since they influence the physical
+// pieces created from the plaintext. The chunk index isn't, the entry stores
+// the chunk index its pieces were encrypted for instead.
+func dedupChunkKey(plaintextHash crypto.Hash, ec modules.ErasureCoder, ct crypto.CipherType) crypto.Hash {
+	return crypto.HashAll(dedupKeySpecifier, plaintextHash, ec.Identifier(), ct)
+}
+
+// dedupPlaintextHash returns the hash of the data pieces of a chunk.
+func dedupPlaintextHash(dataPieces [][]byte) (h crypto.Hash) {
+	hasher := crypto.NewHash()
+	for _, piece := range dataPieces {
+		_, _ = hasher.Write(piece)
+	}
+	copy(h[:], hasher.Sum(nil))
+	return
+}
+
+// load replays the records of the dedup log.
+func (di *dedupIndex) load(r io.Reader) error {
+	br := bufio.NewReader(r)
+	dec := encoding.NewDecoder(br, encoding.DefaultAllocLimit)
+	for {
+		if _, err := br.Peek(1); errors.Contains(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var rec dedupRecord
+		if err := dec.Decode(&rec); err != nil {
+			return err
+		}
+		di.apply(rec)
+		di.numRecords++
+	}
+}
+
+// convertCompat converts the dedup index from the JSON file it used to be
+// persisted in to the log.
+func (di *dedupIndex) convertCompat() error {
+	path := filepath.Join(di.staticPersistDir, dedupCompatFilename)
+	var p dedupCompatPersist
+	err := persist.LoadJSON(dedupCompatMetadata, &p, path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var recs []dedupRecord
+	tracked := make(map[uplofile.UplofileUID]struct{})
+	track := func(uid uplofile.UplofileUID) {
+		if _, exists := tracked[uid]; !exists {
+			tracked[uid] = struct{}{}
+			recs = append(recs, dedupRecord{Type: dedupRecordTrackFile, FileUID: uid})
+		}
+	}
+	for _, uid := range p.Files {
+		track(uid)
+	}
+	for _, pe := range p.Entries {
+		// The keys of the old index include the chunk index, so all
+		// references of an entry share the chunk index the pieces were
+		// encrypted for. New chunks won't match these keys but the references
+		// are kept.
+		if len(pe.Refs) == 0 {
+			continue
+		}
+		recs = append(recs, dedupRecord{
+			Type:     dedupRecordEntry,
+			Key:      pe.Key,
+			KeyIndex: pe.Refs[0].ChunkIndex,
+			Pieces:   pe.Pieces,
+		})
+		for _, ref := range pe.Refs {
+			track(ref.FileUID)
+			recs = append(recs, dedupRecord{
+				Type:       dedupRecordRef,
+				Key:        pe.Key,
+				FileUID:    ref.FileUID,
+				ChunkIndex: ref.ChunkIndex,
+			})
+		}
+	}
+	if err := di.write(recs...); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// numLiveRecords returns the number of records needed to describe the index.
+// The caller needs to hold the lock.
+func (di *dedupIndex) numLiveRecords() int {
+	n := len(di.files) + len(di.entries)
+	for _, entry := range di.entries {
+		n += len(entry.refs)
+	}
+	return n
+}
+
+// snapshotRecords returns the records which describe the index. The caller
+// needs to hold the lock.
+func (di *dedupIndex) snapshotRecords() []dedupRecord {
+	recs := make([]dedupRecord, 0, di.numLiveRecords())
+	for uid := range di.files {
+		recs = append(recs, dedupRecord{Type: dedupRecordTrackFile, FileUID: uid})
+	}
+	for key, entry := range di.entries {
+		recs = append(recs, dedupRecord{
+			Type:     dedupRecordEntry,
+			Key:      key,
+			KeyIndex: entry.keyIndex,
+			Pieces:   entry.pieces,
+		})
+		for ref := range entry.refs {
+			recs = append(recs, dedupRecord{
+				Type:       dedupRecordRef,
+				Key:        key,
+				FileUID:    ref.FileUID,
+				ChunkIndex: ref.ChunkIndex,
+			})
+		}
+	}
+	return recs
+}
+
+// compact replaces the dedup log with a log which only contains the records
+// needed to describe the index.
+func (di *dedupIndex) compact() (err error) {
+	compactPath := filepath.Join(di.staticPersistDir, dedupCompactFilename)
+	if err := os.Remove(compactPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	aop, _, err := persist.NewAppendOnlyPersist(di.staticPersistDir, dedupCompactFilename, dedupMetadataHeader, dedupMetadataVersion)
+	if err != nil {
+		return err
+	}
+	recs := di.snapshotRecords()
+	_, err = aop.Write(marshalDedupRecords(recs))
+	err = errors.Compose(err, aop.Close())
+	if err != nil {
+		return err
+	}
+
+	// Replace the old log.
+	if err := di.staticAop.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(compactPath, di.staticAop.FilePath()); err != nil {
+		return err
+	}
+	di.staticAop, _, err = persist.NewAppendOnlyPersist(di.staticPersistDir, dedupPersistFilename, dedupMetadataHeader, dedupMetadataVersion)
+	if err != nil {
+		return err
+	}
+	di.numRecords = len(recs)
+	return nil
+}
+
+// marshalDedupRecords encodes records of the dedup log.
+func marshalDedupRecords(recs []dedupRecord) []byte {
+	var buf bytes.Buffer
+	enc := encoding.NewEncoder(&buf)
+	for _, rec := range recs {
+		_ = enc.Encode(rec)
+	}
+	return buf.Bytes()
+}
+
+// write appends records to the dedup log and applies them to the index. The
+// caller needs to hold the lock.
+func (di *dedupIndex) write(recs ...dedupRecord) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	if _, err := di.staticAop.Write(marshalDedupRecords(recs)); err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		di.apply(rec)
+	}
+	di.numRecords += len(recs)
+	return nil
+}
+
+// apply applies a record of the dedup log to the index. The caller needs to
+// hold the lock.
+func (di *dedupIndex) apply(rec dedupRecord) {
+	switch rec.Type {
+	case dedupRecordTrackFile:
+		if _, tracked := di.files[rec.FileUID]; !tracked {
+			di.files[rec.FileUID] = make(map[uint64]crypto.Hash)
+		}
+	case dedupRecordRemoveFile:
+		for chunkIndex, key := range di.files[rec.FileUID] {
+			di.removeRef(key, dedupRef{
+				FileUID:    rec.FileUID,
+				ChunkIndex: chunkIndex,
+			})
+		}
+		delete(di.files, rec.FileUID)
+	case dedupRecordEntry:
+		entry, exists := di.entries[rec.Key]
+		if !exists {
+			entry = &dedupEntry{
+				refs: make(map[dedupRef]struct{}),
+			}
+			di.entries[rec.Key] = entry
+		}
+		entry.keyIndex = rec.KeyIndex
+		entry.pieces = rec.Pieces
+	case dedupRecordRef:
+		chunks, tracked := di.files[rec.FileUID]
+		entry, exists := di.entries[rec.Key]
+		if !tracked || !exists {
+			return
+		}
+		ref := dedupRef{
+			FileUID:    rec.FileUID,
+			ChunkIndex: rec.ChunkIndex,
+		}
+		// If the chunk referenced a different entry before, remove that
+		// reference.
+		if oldKey, exists := chunks[rec.ChunkIndex]; exists && oldKey != rec.Key {
+			di.removeRef(oldKey, ref)
+		}
+		entry.refs[ref] = struct{}{}
+		chunks[rec.ChunkIndex] = rec.Key
+	}
+}
+
+// removeRef removes a reference from an entry and drops the entry if it is no
+// longer referenced. The caller needs to hold the lock.
+func (di *dedupIndex) removeRef(key crypto.Hash, ref dedupRef) {
+	entry, exists := di.entries[key]
+	if !exists {
+		return
+	}
+	delete(entry.refs, ref)
+	if len(entry.refs) == 0 {
+		delete(di.entries, key)
+	}
+}
+
+// managedAddReference adds a reference from a chunk of a tracked file to the
+// entry with the given key. If the entry doesn't exist yet it is created with
+// the provided pieces, otherwise its pieces are replaced by the provided ones
+// which are the most recent pieces known for the data. keyIndex is the chunk
+// index the pieces were encrypted for.
+func (di *dedupIndex) managedAddReference(key crypto.Hash, uid uplofile.UplofileUID, chunkIndex, keyIndex uint64, pieces [][]uplofile.Piece) error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	if _, tracked := di.files[uid]; !tracked {
+		return errors.New("file is not tracked by the dedup index")
+	}
+	return di.write(dedupRecord{
+		Type:     dedupRecordEntry,
+		Key:      key,
+		KeyIndex: keyIndex,
+		Pieces:   pieces,
+	}, dedupRecord{
+		Type:       dedupRecordRef,
+		Key:        key,
+		FileUID:    uid,
+		ChunkIndex: chunkIndex,
+	})
+}
+
+// managedPieces returns the pieces of the entry with the given key and the
+// chunk index they were encrypted for.
+func (di *dedupIndex) managedPieces(key crypto.Hash) ([][]uplofile.Piece, uint64, bool) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[key]
+	if !exists {
+		return nil, 0, false
+	}
+	pieces := make([][]uplofile.Piece, len(entry.pieces))
+	for i := range entry.pieces {
+		pieces[i] = append([]uplofile.Piece{}, entry.pieces[i]...)
+	}
+	return pieces, entry.keyIndex, true
+}
+
+// managedRefCount returns the number of chunks referencing the entry with the
+// given key.
+func (di *dedupIndex) managedRefCount(key crypto.Hash) int {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[key]
+	if !exists {
+		return 0
+	}
+	return len(entry.refs)
+}
+
+// managedRemoveFile removes a file and all of its references from the index.
+func (di *dedupIndex) managedRemoveFile(uid uplofile.UplofileUID) error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	if _, tracked := di.files[uid]; !tracked {
+		return nil
+	}
+	return di.write(dedupRecord{Type: dedupRecordRemoveFile, FileUID: uid})
+}
+
+// managedTrackFile marks a file as deduplicated.
+func (di *dedupIndex) managedTrackFile(uid uplofile.UplofileUID) error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	if _, tracked := di.files[uid]; tracked {
+		return nil
+	}
+	return di.write(dedupRecord{Type: dedupRecordTrackFile, FileUID: uid})
+}
+
+// managedTracked returns whether a file was uploaded with deduplication
+// enabled.
+func (di *dedupIndex) managedTracked(uid uplofile.UplofileUID) bool {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	_, tracked := di.files[uid]
+	return tracked
+}
+
+// managedNumFiles returns the number of files tracked by the index.
+func (di *dedupIndex) managedNumFiles() int {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	return len(di.files)
+}
+
+// managedDedupMasterKey derives the master key used by deduplicated files of
+// the given cipher type from the renter seed.
+func (r *Renter) managedDedupMasterKey(ct crypto.CipherType) (crypto.CipherKey, error) {
+	// Get the wallet seed.
+	ws, _, err := r.w.PrimarySeed()
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to get wallet's primary seed")
+	}
+	// Derive the renter seed and wipe the memory once we are done using it.
+	rs := modules.DeriveRenterSeed(ws)
+	defer fastrand.Read(rs[:])
+	// Derive the secret and wipe it afterwards.
+	secret := crypto.HashAll(rs, dedupKeySpecifier, ct)
+	defer fastrand.Read(secret[:])
+
+	// Expand the secret to the amount of entropy required by the cipher.
+	entropySize := len(crypto.GenerateUploKey(ct).Key())
+	entropy := make([]byte, 0, entropySize+crypto.HashSize)
+	for i := uint64(0); len(entropy) < entropySize; i++ {
+		h := crypto.HashAll(secret, i)
+		entropy = append(entropy, h[:]...)
+	}
+	defer fastrand.Read(entropy)
+	return crypto.NewUploKey(ct, entropy[:entropySize])
+}
+
+// managedDedupFileUIDs returns the UIDs of the deduplicated files at the given
+// path. If the path is a directory, all files within it are considered.
+func (r *Renter) managedDedupFileUIDs(uploPath modules.UploPath, isDir bool) []uplofile.UplofileUID {
+	// Avoid opening any files if there are no deduplicated files.
+	if r.staticDedupIndex.managedNumFiles() == 0 {
+		return nil
+	}
+	paths := []modules.UploPath{uploPath}
+	if isDir {
+		paths = nil
+		var mu sync.Mutex
+		flf := func(fi modules.FileInfo) {
+			mu.Lock()
+			paths = append(paths, fi.UploPath)
+			mu.Unlock()
+		}
+		err := r.staticFileSystem.CachedList(uploPath, true, flf, func(modules.DirectoryInfo) {})
+		if err != nil {
+			r.log.Printf("WARN: unable to list %v for dedup index: %v", uploPath, err)
+			return nil
+		}
+	}
+	var uids []uplofile.UplofileUID
+	for _, path := range paths {
+		entry, err := r.staticFileSystem.OpenUploFile(path)
+		if err != nil {
+			continue
+		}
+		uid := entry.UID()
+		if err := entry.Close(); err != nil {
+			r.log.Printf("WARN: unable to close %v: %v", path, err)
+		}
+		if r.staticDedupIndex.managedTracked(uid) {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
+// managedRemoveDedupFiles removes the files with the given UIDs from the dedup
+// index.
+func (r *Renter) managedRemoveDedupFiles(uids []uplofile.UplofileUID) {
+	for _, uid := range uids {
+		if err := r.staticDedupIndex.managedRemoveFile(uid); err != nil {
+			r.log.Printf("WARN: unable to remove file %v from dedup index: %v", uid, err)
+		}
+	}
+}
+
+// managedDedupChunkKey returns the dedup key of a chunk and whether the chunk
+// is eligible for deduplication.
+func (r *Renter) managedDedupChunkKey(uc *unfinishedUploadChunk) (crypto.Hash, bool) {
+	var zeroHash crypto.Hash
+	if !uc.staticDedup || uc.plaintextHash == zeroHash {
+		return zeroHash, false
+	}
+	return dedupChunkKey(uc.plaintextHash, uc.fileEntry.ErasureCode(), uc.fileEntry.MasterKey().Type()), true
+}
+
+// managedTryDedupChunk checks whether the pieces of a freshly fetched chunk
+// already exist on the network. If they do, the existing pieces are added to
+// the chunk's file and the chunk is completed without uploading any data.
+// Returns 'true' if the chunk was deduplicated.
+func (r *Renter) managedTryDedupChunk(uc *unfinishedUploadChunk) bool {
+	key, ok := r.managedDedupChunkKey(uc)
+	if !ok {
+		return false
+	}
+	// Only chunks without any pieces are deduplicated. Chunks that are being
+	// repaired keep using their own pieces.
+	uc.mu.Lock()
+	fresh := uc.piecesCompleted == 0
+	for _, used := range uc.pieceUsage {
+		fresh = fresh && !used
+	}
+	uc.mu.Unlock()
+	if !fresh {
+		return false
+	}
+	pieces, keyIndex, exists := r.staticDedupIndex.managedPieces(key)
+	if !exists || len(pieces) != len(uc.pieceUsage) {
+		return false
+	}
+	for _, pieceSet := range pieces {
+		if len(pieceSet) == 0 {
+			return false
+		}
+	}
+
+	// If the existing pieces were encrypted for a different chunk index, the
+	// chunk needs to use that index from now on. Since the chunk doesn't have
+	// any pieces yet, this doesn't affect any existing pieces.
+	if keyIndex != uc.staticKeyIndex {
+		err := uc.fileEntry.SetChunkKeyIndex(uc.staticIndex, keyIndex)
+		if err != nil {
+			r.repairLog.Printf("Unable to set key index of chunk %v of %s: %v", uc.staticIndex, uc.staticUploPath, err)
+			return false
+		}
+	}
+
+	// Add the existing pieces to the file. If this fails midway and the
+	// pieces were encrypted for the same chunk index, the chunk is uploaded
+	// regularly which is fine since the pieces are identical. Otherwise the
+	// chunk's data was encrypted with the wrong keys and the chunk is dropped
+	// to be repaired later.
+	for pieceIndex, pieceSet := range pieces {
+		for _, piece := range pieceSet {
+			err := uc.fileEntry.AddPiece(piece.HostPubKey, uc.staticIndex, uint64(pieceIndex), piece.MerkleRoot)
+			if err != nil {
+				r.repairLog.Printf("Unable to add deduplicated piece to chunk %v of %s: %v", uc.staticIndex, uc.staticUploPath, err)
+				if keyIndex == uc.staticKeyIndex {
+					return false
+				}
+				r.managedReleaseDedupChunk(uc, false)
+				return true
+			}
+		}
+	}
+	r.repairLog.Printf("Deduplicated chunk %v of %s", uc.staticIndex, uc.staticUploPath)
+	r.managedReleaseDedupChunk(uc, true)
+	return true
+}
+
+// managedReleaseDedupChunk releases the memory of all pieces of a chunk that
+// was handled by the dedup index and cleans up the chunk. If completed is
+// true, the pieces are marked as completed. Otherwise the chunk fails without
+// uploading any pieces.
+func (r *Renter) managedReleaseDedupChunk(uc *unfinishedUploadChunk, completed bool) {
+	var memoryReleased uint64
+	uc.mu.Lock()
+	for i := range uc.pieceUsage {
+		if uc.pieceUsage[i] {
+			continue
+		}
+		uc.pieceUsage[i] = true
+		if completed {
+			uc.piecesCompleted++
+		}
+		uc.physicalChunkData[i] = nil
+		memoryReleased += modules.SectorSize
+	}
+	if !completed {
+		uc.workersRemaining = 0
+	}
+	uc.memoryReleased += memoryReleased
+	uc.mu.Unlock()
+	uc.staticMemoryManager.Return(memoryReleased)
+	r.managedCleanUpUploadChunk(uc)
+}
+
+// managedAddDedupChunk adds a completed chunk of a deduplicated file to the
+// dedup index.
+func (r *Renter) managedAddDedupChunk(uc *unfinishedUploadChunk) {
+	key, ok := r.managedDedupChunkKey(uc)
+	if !ok {
+		return
+	}
+	uc.mu.Lock()
+	complete := uc.piecesCompleted >= uc.staticPiecesNeeded
+	uc.mu.Unlock()
+	if !complete {
+		return
+	}
+	pieces, err := uc.fileEntry.Pieces(uc.staticIndex)
+	if err != nil {
+		r.log.Printf("WARN: unable to get pieces of chunk %v of %s for dedup index: %v", uc.staticIndex, uc.staticUploPath, err)
+		return
+	}
+	// The key index might have changed if the chunk was deduplicated.
+	keyIndex, err := uc.fileEntry.ChunkKeyIndex(uc.staticIndex)
+	if err != nil {
+		r.log.Printf("WARN: unable to get key index of chunk %v of %s for dedup index: %v", uc.staticIndex, uc.staticUploPath, err)
+		return
+	}
+	for _, pieceSet := range pieces {
+		if len(pieceSet) == 0 {
+			return
+		}
+	}
+	err = r.staticDedupIndex.managedAddReference(key, uc.id.fileUID, uc.staticIndex, keyIndex, pieces)
+	if err != nil {
+		r.log.Printf("WARN: unable to add chunk %v of %s to dedup index: %v", uc.staticIndex, uc.staticUploPath, err)
+	}
+}
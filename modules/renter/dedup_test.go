@@ -0,0 +1,199 @@
+package renter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/uplo-tech/fastrand"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/modules/renter/filesystem/uplofile"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestDedupIndex probes the reference counting and persistence of the dedup
+// index.
+func TestDedupIndex(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	testDir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(testDir, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+	di, err := newDedupIndex(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the pieces of a chunk.
+	ec := modules.NewRSSubCodeDefault()
+	pieces := make([][]uplofile.Piece, ec.NumPieces())
+	for i := range pieces {
+		var root crypto.Hash
+		fastrand.Read(root[:])
+		pk := types.UploPublicKey{
+			Algorithm: types.SignatureEd25519,
+			Key:       fastrand.Bytes(crypto.PublicKeySize),
+		}
+		pieces[i] = []uplofile.Piece{{HostPubKey: pk, MerkleRoot: root}}
+	}
+	var plaintextHash crypto.Hash
+	fastrand.Read(plaintextHash[:])
+	key := dedupChunkKey(plaintextHash, ec, crypto.TypeDefaultRenter)
+
+	// The key depends on the erasure coder and cipher type.
+	if key == dedupChunkKey(plaintextHash, modules.NewRSCodeDefault(), crypto.TypeDefaultRenter) {
+		t.Fatal("key doesn't depend on erasure coder")
+	}
+	if key == dedupChunkKey(plaintextHash, ec, crypto.TypePlain) {
+		t.Fatal("key doesn't depend on cipher type")
+	}
+
+	// Adding a reference for an untracked file should fail.
+	uid1, uid2 := uplofile.UplofileUID("file1"), uplofile.UplofileUID("file2")
+	if err := di.managedAddReference(key, uid1, 0, 0, pieces); err == nil {
+		t.Fatal("expected error for untracked file")
+	}
+
+	// Track both files and add a reference from each. The chunks have
+	// different indices, the pieces were encrypted for the first one.
+	for i, uid := range []uplofile.UplofileUID{uid1, uid2} {
+		if err := di.managedTrackFile(uid); err != nil {
+			t.Fatal(err)
+		}
+		if err := di.managedAddReference(key, uid, uint64(i), 0, pieces); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Adding the same reference again shouldn't change the refcount.
+	if err := di.managedAddReference(key, uid1, 0, 0, pieces); err != nil {
+		t.Fatal(err)
+	}
+	if rc := di.managedRefCount(key); rc != 2 {
+		t.Fatal("wrong refcount", rc)
+	}
+
+	// Reload the index and check that the entry was persisted.
+	if err := di.Close(); err != nil {
+		t.Fatal(err)
+	}
+	di, err = newDedupIndex(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !di.managedTracked(uid1) || !di.managedTracked(uid2) {
+		t.Fatal("files should be tracked")
+	}
+	if rc := di.managedRefCount(key); rc != 2 {
+		t.Fatal("wrong refcount after reload", rc)
+	}
+	loaded, keyIndex, exists := di.managedPieces(key)
+	if !exists || len(loaded) != len(pieces) {
+		t.Fatal("pieces weren't persisted")
+	}
+	if keyIndex != 0 {
+		t.Fatal("wrong key index", keyIndex)
+	}
+	for i := range pieces {
+		if !loaded[i][0].HostPubKey.Equals(pieces[i][0].HostPubKey) || loaded[i][0].MerkleRoot != pieces[i][0].MerkleRoot {
+			t.Fatal("wrong piece", i)
+		}
+	}
+
+	// Deleting one of the files should keep the shared entry.
+	if err := di.managedRemoveFile(uid1); err != nil {
+		t.Fatal(err)
+	}
+	if di.managedTracked(uid1) {
+		t.Fatal("file shouldn't be tracked anymore")
+	}
+	if _, _, exists := di.managedPieces(key); !exists {
+		t.Fatal("shared entry was removed")
+	}
+	if rc := di.managedRefCount(key); rc != 1 {
+		t.Fatal("wrong refcount", rc)
+	}
+
+	// Deleting the last reference should remove the entry.
+	if err := di.managedRemoveFile(uid2); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := di.managedPieces(key); exists {
+		t.Fatal("unreferenced entry wasn't removed")
+	}
+	if di.managedNumFiles() != 0 {
+		t.Fatal("expected no tracked files", di.managedNumFiles())
+	}
+	if err := di.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDedupIndexCompact probes compacting the log of the dedup index.
+func TestDedupIndexCompact(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	testDir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(testDir, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+	di, err := newDedupIndex(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Track a file and let one of its chunks reference a lot of different
+	// entries. Only the last reference is live.
+	uid := uplofile.UplofileUID("file")
+	if err := di.managedTrackFile(uid); err != nil {
+		t.Fatal(err)
+	}
+	var key crypto.Hash
+	for i := 0; i < 2*dedupCompactThreshold; i++ {
+		fastrand.Read(key[:])
+		if err := di.managedAddReference(key, uid, 0, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if di.numRecords <= di.numLiveRecords()+dedupCompactThreshold {
+		t.Fatal("expected obsolete records", di.numRecords, di.numLiveRecords())
+	}
+	if err := di.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reloading should compact the log without losing the live records.
+	di, err = newDedupIndex(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.numRecords != di.numLiveRecords() {
+		t.Fatal("log wasn't compacted", di.numRecords, di.numLiveRecords())
+	}
+	if !di.managedTracked(uid) || di.managedRefCount(key) != 1 {
+		t.Fatal("live records were lost")
+	}
+	if err := di.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The compacted log should load again.
+	di, err = newDedupIndex(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !di.managedTracked(uid) || di.managedRefCount(key) != 1 {
+		t.Fatal("live records were lost after reload")
+	}
+	if err := di.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
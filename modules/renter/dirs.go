@@ -20,13 +20,42 @@ func (r *Renter) CreateDir(uploPath modules.UploPath, mode os.FileMode) error {
 }
 
 // DeleteDir removes a directory from the renter and deletes all its sub
-// directories and files
+// directories and files. If the trash is enabled, the directory is moved to
+// the trash instead.
 func (r *Renter) DeleteDir(uploPath modules.UploPath) error {
 	if err := r.tg.Add(); err != nil {
 		return err
 	}
 	defer r.tg.Done()
-	return r.staticFileSystem.DeleteDir(uploPath)
+
+	// Keep the files within the directory which are subject to versioning.
+	err := r.managedVersionDirFiles(uploPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to keep files as versions")
+	}
+
+	// Move the directory to the trash if the trash is enabled.
+	trashed, err := r.managedTryTrash(uploPath, true)
+	if err != nil {
+		return errors.AddContext(err, "unable to move uplodir to the trash")
+	}
+	if trashed {
+		return nil
+	}
+	return r.managedDeleteDir(uploPath)
+}
+
+// managedDeleteDir permanently removes a dir from the renter.
+func (r *Renter) managedDeleteDir(uploPath modules.UploPath) error {
+	// Remember the deduplicated files within the directory before they are
+	// deleted.
+	dedupUIDs := r.managedDedupFileUIDs(uploPath, true)
+	err := r.staticFileSystem.DeleteDir(uploPath)
+	if err != nil {
+		return err
+	}
+	r.managedRemoveDedupFiles(dedupUIDs)
+	return nil
 }
 
 // DirList lists the directories in a uplodir
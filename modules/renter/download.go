@@ -265,25 +265,44 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 	if p.Destination != "" && !filepath.IsAbs(p.Destination) {
 		return nil, errors.New("destination must be an absolute path")
 	}
-	if p.Offset == entry.Size() && entry.Size() != 0 {
+	// The offset and length of compressed files refer to the uncompressed
+	// data.
+	ci := entry.Compression()
+	fileSize := entry.Size()
+	if ci.Compressed() {
+		fileSize = ci.UncompressedSize
+	}
+	if p.Offset == fileSize && fileSize != 0 {
 		return nil, errors.New("offset equals filesize")
 	}
 	// Sentinel: if length == 0, download the entire file.
 	if p.Length == 0 {
-		if p.Offset > entry.Size() {
+		if p.Offset > fileSize {
 			return nil, errors.New("offset cannot be greater than file size")
 		}
-		p.Length = entry.Size() - p.Offset
+		p.Length = fileSize - p.Offset
 	}
 	// Check whether offset and length is valid.
-	if p.Offset < 0 || p.Offset+p.Length > entry.Size() {
-		return nil, fmt.Errorf("offset and length combination invalid, max byte is at index %d", entry.Size()-1)
+	if p.Offset < 0 || p.Offset+p.Length > fileSize {
+		return nil, fmt.Errorf("offset and length combination invalid, max byte is at index %d", fileSize-1)
+	}
+	// Compressed files are downloaded by fetching the compressed frames
+	// containing the requested range and decompressing them.
+	fetchOffset, fetchLength := p.Offset, p.Length
+	if ci.Compressed() && p.Length > 0 {
+		fetchOffset, fetchLength, err = ci.CompressedRange(p.Offset, p.Length)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to determine compressed range")
+		}
 	}
 
 	// Instantiate the correct downloadWriter implementation.
 	var dw downloadDestination
 	var destinationType string
-	if isHTTPResp {
+	if isHTTPResp && ci.Compressed() {
+		dw = newDownloadDestinationDecompressor(p.Httpwriter, nil, ci, p.Offset, p.Length)
+		destinationType = "http stream"
+	} else if isHTTPResp {
 		dw = newDownloadDestinationWriter(p.Httpwriter)
 		destinationType = "http stream"
 	} else {
@@ -291,10 +310,16 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 		if err != nil {
 			return nil, err
 		}
-		dw = &downloadDestinationFile{
-			deps:            r.deps,
-			f:               osFile,
-			staticChunkSize: int64(entry.ChunkSize()),
+		if ci.Compressed() {
+			// The decompressed data needs to be written sequentially.
+			sw := NewSectionWriter(osFile, 0, int64(p.Length))
+			dw = newDownloadDestinationDecompressor(sw, osFile, ci, p.Offset, p.Length)
+		} else {
+			dw = &downloadDestinationFile{
+				deps:            r.deps,
+				f:               osFile,
+				staticChunkSize: int64(entry.ChunkSize()),
+			}
 		}
 		destinationType = "file"
 	}
@@ -309,7 +334,7 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 	}
 
 	// Prepare snapshot.
-	snap, err := entry.SnapshotRange(p.UploPath, p.Offset, p.Length)
+	snap, err := entry.SnapshotRange(p.UploPath, fetchOffset, fetchLength)
 	if err != nil {
 		return nil, err
 	}
@@ -322,9 +347,9 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 		file:              snap,
 
 		latencyTarget: 25e3 * time.Millisecond, // TODO: high default until full latency support is added.
-		length:        p.Length,
+		length:        fetchLength,
 		needsMemory:   true,
-		offset:        p.Offset,
+		offset:        fetchOffset,
 		overdrive:     3, // TODO: moderate default until full overdrive support is added.
 		priority:      5, // TODO: moderate default until full priority support is added.
 
@@ -473,6 +498,7 @@ func (d *download) Start() error {
 			masterKey:   params.file.MasterKey(),
 
 			staticChunkIndex: i,
+			staticKeyIndex:   params.file.ChunkKeyIndex(i),
 			staticCacheID:    fmt.Sprintf("%v:%v", d.staticUploPath, i),
 			staticChunkMap:   chunkMaps[i-minChunk],
 			staticChunkSize:  params.file.ChunkSize(),
@@ -42,6 +42,7 @@ type unfinishedDownloadChunk struct {
 
 	// Fetch + Write instructions - read only or otherwise thread safe.
 	staticChunkIndex  uint64                       // Required for deriving the encryption keys for each piece.
+	staticKeyIndex    uint64                       // Chunk index the encryption keys of the pieces are derived from.
 	staticCacheID     string                       // Used to uniquely identify a chunk in the chunk cache.
 	staticChunkMap    map[string]downloadPieceInfo // Maps from host PubKey to the info for the piece associated with that host
 	staticChunkSize   uint64
@@ -179,6 +179,33 @@ var (
 	errOffsetAlreadyWritten = errors.New("cannot write to that offset in stream, data already written")
 )
 
+// downloadDestinationDecompressor is a downloadDestination for compressed
+// files. It receives the compressed data of a file sequentially and writes the
+// requested range of the uncompressed data to the underlying writer.
+type downloadDestinationDecompressor struct {
+	*downloadDestinationWriter
+	closer io.Closer
+}
+
+// newDownloadDestinationDecompressor creates a downloadDestination which
+// writes the uncompressed range [offset;offset+length) of a compressed file to
+// w. If closer is not nil, it is closed together with the destination.
+func newDownloadDestinationDecompressor(w io.Writer, closer io.Closer, ci modules.CompressionInfo, offset, length uint64) *downloadDestinationDecompressor {
+	return &downloadDestinationDecompressor{
+		downloadDestinationWriter: newDownloadDestinationWriter(modules.NewDecompressionWriter(w, ci, offset, length)),
+		closer:                    closer,
+	}
+}
+
+// Close closes the destination and the underlying closer.
+func (ddd *downloadDestinationDecompressor) Close() error {
+	err := ddd.downloadDestinationWriter.Close()
+	if ddd.closer != nil {
+		err = errors.Compose(err, ddd.closer.Close())
+	}
+	return err
+}
+
 // newDownloadDestinationWriter takes an io.Writer and converts it
 // into a downloadDestination.
 func newDownloadDestinationWriter(w io.Writer) *downloadDestinationWriter {
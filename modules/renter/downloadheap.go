@@ -184,6 +184,11 @@ func (r *Renter) managedTryFetchChunkFromDisk(chunk *unfinishedDownloadChunk) bo
 	if localPath == "" {
 		return false
 	}
+	// The local copy of a compressed file contains the uncompressed data which
+	// doesn't match the chunks of the file.
+	if chunk.renterFile.Compression().Compressed() {
+		return false
+	}
 	// Open the file.
 	file, err := os.Open(localPath)
 	if err != nil {
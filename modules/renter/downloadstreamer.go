@@ -14,6 +14,14 @@ import (
 )
 
 type (
+	// decompressionStreamer is a modules.Streamer for compressed files. It
+	// wraps a streamer for the compressed data of the file and serves the
+	// uncompressed data.
+	decompressionStreamer struct {
+		io.ReadSeeker
+		staticSource modules.Streamer
+	}
+
 	// streamer is a modules.Streamer that can be used to stream downloads from
 	// the uplo network.
 	streamer struct {
@@ -527,5 +535,18 @@ func (r *Renter) managedStreamer(snapshot *uplofile.Snapshot, disableLocalFetch
 		targetCacheSize:         initialStreamerCacheSize,
 	}
 	go s.threadedFillCache()
+
+	// Compressed files are decompressed on the fly.
+	if ci := snapshot.Compression(); ci.Compressed() {
+		return &decompressionStreamer{
+			ReadSeeker:   modules.NewDecompressionReadSeeker(s, ci),
+			staticSource: s,
+		}
+	}
 	return s
 }
+
+// Close closes the underlying streamer.
+func (ds *decompressionStreamer) Close() error {
+	return ds.staticSource.Close()
+}
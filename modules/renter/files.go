@@ -2,6 +2,7 @@ package renter
 
 import (
 	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/modules/renter/filesystem/uplofile"
 
 	"github.com/uplo-tech/errors"
 )
@@ -14,12 +15,49 @@ func (r *Renter) DeleteFile(uploPath modules.UploPath) error {
 		return err
 	}
 	defer r.tg.Done()
+	return r.managedDeleteFile(uploPath)
+}
+
+// managedDeleteFile removes a file entry from the renter. If versioning
+// applies to the file, it is kept as a version instead. Otherwise it is moved
+// to the trash if the trash is enabled.
+func (r *Renter) managedDeleteFile(uploPath modules.UploPath) error {
+	versioned, err := r.managedTryVersionFile(uploPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to keep uplofile as a version")
+	}
+	if versioned {
+		return nil
+	}
+	trashed, err := r.managedTryTrash(uploPath, false)
+	if err != nil {
+		return errors.AddContext(err, "unable to move uplofile to the trash")
+	}
+	if trashed {
+		return nil
+	}
+
+	// Remember the deduplicated file and the uid of the file before it is
+	// deleted.
+	dedupUIDs := r.managedDedupFileUIDs(uploPath, false)
+	var uid uplofile.UplofileUID
+	if entry, err := r.staticFileSystem.OpenUploFile(uploPath); err == nil {
+		uid = entry.UID()
+		err = entry.Close()
+		if err != nil {
+			r.log.Println("WARN: unable to close uplofile:", err)
+		}
+	}
 
 	// Perform the delete operation.
 	err = r.staticFileSystem.DeleteFile(uploPath)
 	if err != nil {
 		return errors.AddContext(err, "unable to delete uplofile from filesystem")
 	}
+	r.managedRemoveDedupFiles(dedupUIDs)
+	if uid != "" {
+		r.staticRemoveCompressedCopy(uid)
+	}
 
 	// Update the filesystem metadata.
 	//
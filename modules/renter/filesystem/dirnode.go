@@ -156,6 +156,17 @@ func (n *DirNode) UpdateLastHealthCheckTime(aggregateLastHealthCheckTime, lastHe
 	return sd.UpdateLastHealthCheckTime(aggregateLastHealthCheckTime, lastHealthCheckTime)
 }
 
+// UpdateVersioning is a wrapper for uplodir.UpdateVersioning.
+func (n *DirNode) UpdateVersioning(policy modules.VersioningPolicy) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sd, err := n.uplodir()
+	if err != nil {
+		return err
+	}
+	return sd.UpdateVersioning(policy)
+}
+
 // UpdateMetadata is a wrapper for uplodir.UpdateMetadata.
 func (n *DirNode) UpdateMetadata(md uplodir.Metadata) error {
 	n.mu.Lock()
@@ -615,6 +626,8 @@ func (n *DirNode) managedInfo(uploPath modules.UploPath) (modules.DirectoryInfo,
 		// Skynet Fields
 		SkynetFiles: metadata.SkynetFiles,
 		SkynetSize:  metadata.SkynetSize,
+
+		Versioning: metadata.Versioning,
 	}, nil
 }
 
@@ -117,14 +117,17 @@ func (n *FileNode) managedFileInfo(uploPath modules.UploPath, offline map[string
 		return modules.FileInfo{}, errors.AddContext(err, "failed to get upload progress and bytes")
 	}
 	maxHealth := math.Max(health, stuckHealth)
+	filesize, compressedSize := fileSizes(n.Size(), n.Compression())
 	fileInfo := modules.FileInfo{
 		AccessTime:       n.AccessTime(),
 		Available:        redundancy >= 1,
 		ChangeTime:       n.ChangeTime(),
 		CipherType:       n.MasterKey().Type().String(),
+		CompressedSize:   compressedSize,
+		Compression:      n.Compression().Type.String(),
 		CreateTime:       n.CreateTime(),
 		Expiration:       n.Expiration(contracts),
-		Filesize:         n.Size(),
+		Filesize:         filesize,
 		Health:           health,
 		LocalPath:        localPath,
 		MaxHealth:        maxHealth,
@@ -200,14 +203,17 @@ func (n *FileNode) staticCachedInfo(uploPath modules.UploPath) (modules.FileInfo
 		onDisk = err == nil
 	}
 	maxHealth := math.Max(md.CachedHealth, md.CachedStuckHealth)
+	filesize, compressedSize := fileSizes(uint64(md.FileSize), md.Compression)
 	fileInfo := modules.FileInfo{
 		AccessTime:       md.AccessTime,
 		Available:        md.CachedUserRedundancy >= 1,
 		ChangeTime:       md.ChangeTime,
 		CipherType:       md.StaticMasterKeyType.String(),
+		CompressedSize:   compressedSize,
+		Compression:      md.Compression.Type.String(),
 		CreateTime:       md.CreateTime,
 		Expiration:       md.CachedExpiration,
-		Filesize:         uint64(md.FileSize),
+		Filesize:         filesize,
 		Health:           md.CachedHealth,
 		LocalPath:        localPath,
 		MaxHealth:        maxHealth,
@@ -228,3 +234,12 @@ func (n *FileNode) staticCachedInfo(uploPath modules.UploPath) (modules.FileInfo
 	}
 	return fileInfo, nil
 }
+
+// fileSizes returns the size of the uncompressed data and the size of the
+// stored data of a file with the given size and compression.
+func fileSizes(size uint64, ci modules.CompressionInfo) (uint64, uint64) {
+	if ci.Compressed() {
+		return ci.UncompressedSize, size
+	}
+	return size, size
+}
@@ -318,6 +318,7 @@ func (sd *Uplodir) UpdateBubbledMetadata(metadata Metadata) error {
 	sd.mu.Lock()
 	defer sd.mu.Unlock()
 	metadata.Mode = sd.metadata.Mode
+	metadata.Versioning = sd.metadata.Versioning
 	metadata.Version = sd.metadata.Version
 	return sd.updateMetadata(metadata)
 }
@@ -333,6 +334,16 @@ func (sd *Uplodir) UpdateLastHealthCheckTime(aggregateLastHealthCheckTime, lastH
 	return sd.updateMetadata(md)
 }
 
+// UpdateVersioning updates the uplodir versioning policy and saves the changes
+// to disk
+func (sd *Uplodir) UpdateVersioning(policy modules.VersioningPolicy) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	md := sd.metadata
+	md.Versioning = policy
+	return sd.updateMetadata(md)
+}
+
 // UpdateMetadata updates the uplodir metadata on disk
 func (sd *Uplodir) UpdateMetadata(metadata Metadata) error {
 	sd.mu.Lock()
@@ -381,6 +392,8 @@ func (sd *Uplodir) updateMetadata(metadata Metadata) error {
 	sd.metadata.SkynetFiles = metadata.SkynetFiles
 	sd.metadata.SkynetSize = metadata.SkynetSize
 
+	sd.metadata.Versioning = metadata.Versioning
+
 	sd.metadata.Version = metadata.Version
 
 	// Testing check to ensure new fields aren't missed
@@ -98,6 +98,10 @@ type (
 		SkynetFiles uint64 `json:"skynetfiles"`
 		SkynetSize  uint64 `json:"skynetsize"`
 
+		// Versioning is the versioning policy of the uplodir. It is not
+		// bubbled.
+		Versioning modules.VersioningPolicy `json:"versioning"`
+
 		// Version is the used version of the header file.
 		Version string `json:"version"`
 	}
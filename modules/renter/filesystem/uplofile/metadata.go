@@ -168,6 +168,12 @@ type (
 		// skyfiles, those skyfiles will be listed here. It should be noted that
 		// a single uplofile can be responsible for tracking many skyfiles.
 		Skylinks []string `json:"skylinks"`
+
+		// Compression describes the compression applied to the file's data
+		// before encryption. If the file is compressed, FileSize is the size
+		// of the compressed data and the frame index is used to map ranges of
+		// the uncompressed data to the compressed data.
+		Compression modules.CompressionInfo `json:"compression"`
 	}
 
 	// BubbledMetadata is the metadata of a uplofile that gets bubbled
@@ -215,6 +221,13 @@ func (sf *UploFile) AddSkylink(s modules.Skylink) (err error) {
 	return sf.createAndApplyTransaction(updates...)
 }
 
+// Compression returns the compression info of the file.
+func (sf *UploFile) Compression() modules.CompressionInfo {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.staticMetadata.Compression
+}
+
 // ChangeTime returns the ChangeTime timestamp of the file.
 func (sf *UploFile) ChangeTime() time.Time {
 	sf.mu.RLock()
@@ -374,6 +387,11 @@ func (md Metadata) backup() (b Metadata) {
 		b.Skylinks = make([]string, len(md.Skylinks), cap(md.Skylinks))
 		copy(b.Skylinks, md.Skylinks)
 	}
+	b.Compression = md.Compression
+	if md.Compression.Frames != nil {
+		b.Compression.Frames = make([]modules.CompressionFrame, len(md.Compression.Frames), cap(md.Compression.Frames))
+		copy(b.Compression.Frames, md.Compression.Frames)
+	}
 	// If the backup was successful it should match the original.
 	if build.Release == "testing" && !md.equals(b) {
 		fmt.Println("md:\n", md)
@@ -413,6 +431,7 @@ func (md *Metadata) restore(b Metadata) {
 	md.ChunkOffset = b.ChunkOffset
 	md.PubKeyTableOffset = b.PubKeyTableOffset
 	md.Skylinks = b.Skylinks
+	md.Compression = b.Compression
 	// If the backup was successful it should match the backup.
 	if build.Release == "testing" && !md.equals(b) {
 		fmt.Println("md:\n", md)
@@ -554,6 +573,32 @@ func (sf *UploFile) SetLocalPath(path string) (err error) {
 	return sf.createAndApplyTransaction(updates...)
 }
 
+// SetCompression marks the file as compressed. The file's size is expected to
+// be the compressed size already.
+func (sf *UploFile) SetCompression(ci modules.CompressionInfo) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if uint64(sf.staticMetadata.FileSize) != ci.CompressedSize() {
+		return fmt.Errorf("filesize %v doesn't match compressed size %v", sf.staticMetadata.FileSize, ci.CompressedSize())
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+
+	sf.staticMetadata.Compression = ci
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
 // Size returns the file's size.
 func (sf *UploFile) Size() uint64 {
 	sf.mu.RLock()
@@ -30,6 +30,7 @@ type (
 		staticLocalPath       string
 		staticPartialChunks   []PartialChunkInfo
 		staticUID             UplofileUID
+		staticCompression     modules.CompressionInfo
 	}
 )
 
@@ -108,6 +109,12 @@ func (s *Snapshot) ChunkIndexByOffset(offset uint64) (chunkIndex uint64, off uin
 	return
 }
 
+// ChunkKeyIndex returns the chunk index which is used to derive the encryption
+// keys of the chunk's pieces.
+func (s *Snapshot) ChunkKeyIndex(chunkIndex uint64) uint64 {
+	return s.staticChunks[chunkIndex].keyIndex
+}
+
 // ChunkSize returns the size of a single chunk of the file.
 func (s *Snapshot) ChunkSize() uint64 {
 	return s.staticPieceSize * uint64(s.staticErasureCode.MinPieces())
@@ -144,6 +151,11 @@ func (s *Snapshot) IsIncompletePartialChunk(chunkIndex uint64) bool {
 	return s.staticPartialChunks[idx].Status < CombinedChunkStatusCompleted
 }
 
+// Compression returns the compression info of the file.
+func (s *Snapshot) Compression() modules.CompressionInfo {
+	return s.staticCompression
+}
+
 // LocalPath returns the localPath used to repair the file.
 func (s *Snapshot) LocalPath() string {
 	return s.staticLocalPath
@@ -245,14 +257,16 @@ func (sf *UploFile) readlockSnapshot(sp modules.UploPath, chunks []chunk) (*Snap
 				return nil, err
 			}
 			exportedChunks = append(exportedChunks, Chunk{
-				Pieces: pieces,
+				Pieces:   pieces,
+				keyIndex: uint64(chunk.Index),
 			})
 			continue
 		}
 		// Handle incomplete partial chunk.
 		if sf.isIncompletePartialChunk(uint64(chunk.Index)) {
 			exportedChunks = append(exportedChunks, Chunk{
-				Pieces: make([][]Piece, sf.staticMetadata.staticErasureCode.NumPieces()),
+				Pieces:   make([][]Piece, sf.staticMetadata.staticErasureCode.NumPieces()),
+				keyIndex: uint64(chunk.Index),
 			})
 			continue
 		}
@@ -270,7 +284,8 @@ func (sf *UploFile) readlockSnapshot(sp modules.UploPath, chunks []chunk) (*Snap
 			}
 		}
 		exportedChunks = append(exportedChunks, Chunk{
-			Pieces: pieces,
+			Pieces:   pieces,
+			keyIndex: chunk.keyIndex(),
 		})
 	}
 	// Get non-static metadata fields under lock.
@@ -280,6 +295,7 @@ func (sf *UploFile) readlockSnapshot(sp modules.UploPath, chunks []chunk) (*Snap
 	hasPartial := sf.staticMetadata.HasPartialChunk
 	pcs := sf.staticMetadata.PartialChunks
 	localPath := sf.staticMetadata.LocalPath
+	compression := sf.staticMetadata.Compression
 
 	return &Snapshot{
 		staticChunks:          exportedChunks,
@@ -294,6 +310,7 @@ func (sf *UploFile) readlockSnapshot(sp modules.UploPath, chunks []chunk) (*Snap
 		staticUploPath:         sp,
 		staticLocalPath:       localPath,
 		staticUID:             uid,
+		staticCompression:     compression,
 	}, nil
 }
 
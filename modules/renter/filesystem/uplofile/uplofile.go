@@ -1,6 +1,7 @@
 package uplofile
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math"
@@ -18,6 +19,13 @@ import (
 	"github.com/uplo-tech/encoding"
 )
 
+const (
+	// extensionKeyIndex is set in the first byte of a chunk's ExtensionInfo
+	// if the pieces of the chunk were encrypted with the key of a different
+	// chunk index. The index is stored in the following 8 bytes.
+	extensionKeyIndex = 1
+)
+
 var (
 	// ErrPathOverload is an error when a file already exists at that location
 	ErrPathOverload = errors.New("a file already exists at that location")
@@ -94,6 +102,10 @@ type (
 	// Chunk is an exported chunk. It contains exported pieces.
 	Chunk struct {
 		Pieces [][]Piece
+
+		// keyIndex is the chunk index used to derive the encryption keys of
+		// the pieces.
+		keyIndex uint64
 	}
 
 	// piece represents a single piece of a chunk on disk
@@ -1024,6 +1036,49 @@ func (sf *UploFile) StuckChunkByIndex(index uint64) (bool, error) {
 	return chunk.Stuck, nil
 }
 
+// ChunkKeyIndex returns the chunk index which is used to derive the encryption
+// keys of the chunk's pieces. Usually this is the index of the chunk itself,
+// but the pieces of deduplicated chunks might have been encrypted for a
+// different chunk index.
+func (sf *UploFile) ChunkKeyIndex(chunkIndex uint64) (uint64, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if chunkIndex >= uint64(sf.numChunks) {
+		return 0, fmt.Errorf("chunkIndex %v out of bounds (%v)", chunkIndex, sf.numChunks)
+	}
+	chunk, err := sf.chunk(int(chunkIndex))
+	if err != nil {
+		return 0, errors.AddContext(err, "failed to read chunk")
+	}
+	return chunk.keyIndex(), nil
+}
+
+// SetChunkKeyIndex sets the chunk index which is used to derive the encryption
+// keys of the chunk's pieces. Partial chunks always use their own index.
+func (sf *UploFile) SetChunkKeyIndex(chunkIndex, keyIndex uint64) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't set key index of deleted file")
+	}
+	if chunkIndex >= uint64(sf.numChunks) {
+		return fmt.Errorf("chunkIndex %v out of bounds (%v)", chunkIndex, sf.numChunks)
+	}
+	_, included := sf.isIncludedPartialChunk(chunkIndex)
+	if included || sf.isIncompletePartialChunk(chunkIndex) {
+		return errors.New("can't set key index of partial chunk")
+	}
+	chunk, err := sf.chunk(int(chunkIndex))
+	if err != nil {
+		return errors.AddContext(err, "failed to read chunk")
+	}
+	if chunk.keyIndex() == keyIndex {
+		return nil
+	}
+	chunk.setKeyIndex(keyIndex)
+	return sf.createAndApplyTransaction(sf.saveChunkUpdate(chunk))
+}
+
 // UID returns a unique identifier for this file.
 func (sf *UploFile) UID() UplofileUID {
 	sf.mu.RLock()
@@ -1479,3 +1534,23 @@ func (sf *UploFile) uploadedBytes() (uint64, uint64, error) {
 	sf.staticMetadata.CachedUploadedBytes = total
 	return total, unique, nil
 }
+
+// keyIndex returns the chunk index which is used to derive the encryption keys
+// of the chunk's pieces.
+func (c chunk) keyIndex() uint64 {
+	if c.ExtensionInfo[0] == extensionKeyIndex {
+		return binary.LittleEndian.Uint64(c.ExtensionInfo[1:9])
+	}
+	return uint64(c.Index)
+}
+
+// setKeyIndex sets the chunk index which is used to derive the encryption keys
+// of the chunk's pieces.
+func (c *chunk) setKeyIndex(keyIndex uint64) {
+	c.ExtensionInfo = [16]byte{}
+	if keyIndex == uint64(c.Index) {
+		return
+	}
+	c.ExtensionInfo[0] = extensionKeyIndex
+	binary.LittleEndian.PutUint64(c.ExtensionInfo[1:9], keyIndex)
+}
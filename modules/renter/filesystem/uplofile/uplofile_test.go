@@ -1509,3 +1509,64 @@ func TestCalculateHealth(t *testing.T) {
 	}()
 	checkHealth(0, 0, 0, 0)
 }
+
+// TestChunkKeyIndex probes setting the chunk index which is used to derive the
+// encryption keys of a chunk's pieces.
+func TestChunkKeyIndex(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	sf, _, _ := newBlankTestFileAndWAL(2)
+
+	// By default a chunk uses its own index.
+	keyIndex, err := sf.ChunkKeyIndex(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyIndex != 1 {
+		t.Fatal("wrong default key index", keyIndex)
+	}
+
+	// Set a different index and check that it's persisted and exported by
+	// snapshots.
+	if err := sf.SetChunkKeyIndex(0, 42); err != nil {
+		t.Fatal(err)
+	}
+	sf, err = LoadUploFile(sf.UploFilePath(), sf.wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyIndex, err = sf.ChunkKeyIndex(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyIndex != 42 {
+		t.Fatal("key index wasn't persisted", keyIndex)
+	}
+	snap, err := sf.Snapshot(modules.RandomUploPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.ChunkKeyIndex(0) != 42 || snap.ChunkKeyIndex(1) != 1 {
+		t.Fatal("wrong key indices in snapshot", snap.ChunkKeyIndex(0), snap.ChunkKeyIndex(1))
+	}
+
+	// Resetting the index to the chunk's own index should clear it.
+	if err := sf.SetChunkKeyIndex(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := sf.chunk(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.ExtensionInfo != [16]byte{} {
+		t.Fatal("extension info wasn't cleared", chunk.ExtensionInfo)
+	}
+
+	// Out of bounds indices are rejected.
+	if err := sf.SetChunkKeyIndex(uint64(sf.numChunks), 0); err == nil {
+		t.Fatal("expected out of bounds error")
+	}
+}
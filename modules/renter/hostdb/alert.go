@@ -7,3 +7,15 @@ import "github.com/uplo-tech/uplo/modules"
 func (hdb *HostDB) Alerts() (crit, err, warn []modules.Alert) {
 	return hdb.staticAlerter.Alerts()
 }
+
+// SubscribeEvents implements the modules.EventSubscriber interface for the
+// hostdb.
+func (hdb *HostDB) SubscribeEvents(l modules.EventListener) {
+	hdb.staticAlerter.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents implements the modules.EventSubscriber interface for the
+// hostdb.
+func (hdb *HostDB) UnsubscribeEvents(l modules.EventListener) {
+	hdb.staticAlerter.UnsubscribeEvents(l)
+}
@@ -419,7 +419,7 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 			Timeout: timeout,
 		}
 		start := time.Now()
-		conn, err := dialer.Dial("tcp", string(netAddr))
+		conn, err := modules.GlobalDialer.Dial(dialer, string(netAddr))
 		latency = time.Since(start)
 		if err != nil {
 			return err
@@ -678,7 +678,13 @@ func (hdb *HostDB) threadedScan() {
 // TCP connections. Otherwise we would end up with one TCP connection for every
 // host in the network after scanning the whole network.
 func fetchPriceTable(uplomux *uplomux.UploMux, hostAddr string, timeout time.Duration, hpk mux.ED25519PublicKey) (_ *modules.RPCPriceTable, err error) {
-	stream, err := uplomux.NewEphemeralStream(modules.HostUploMuxSubscriberName, hostAddr, timeout, hpk)
+	// The mux dials on its own, so it connects to a local relay which routes
+	// the connection through the proxy if one is set.
+	address, err := modules.GlobalDialer.RelayAddress(hostAddr)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := uplomux.NewEphemeralStream(modules.HostUploMuxSubscriberName, address, timeout, hpk)
 	if err != nil {
 		return nil, errors.AddContext(err, "failed to create ephemeral stream")
 	}
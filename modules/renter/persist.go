@@ -3,6 +3,7 @@ package renter
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/uplo-tech/errors"
 	"github.com/uplo-tech/writeaheadlog"
@@ -25,6 +26,9 @@ const (
 	uplodirMetadata = ".uplodir"
 	// walFile is the filename of the renter's writeaheadlog's file.
 	walFile = modules.RenterDir + ".wal"
+	// compressedCopiesDir is the directory containing the compressed data of
+	// compressed files until their initial upload is finished.
+	compressedCopiesDir = "compressed"
 )
 
 var (
@@ -61,6 +65,7 @@ type (
 		MaxUploadSpeed   int64
 		UploadedBackups  []modules.UploadedBackup
 		SyncedContracts  []types.FileContractID
+		TrashRetention   time.Duration
 	}
 )
 
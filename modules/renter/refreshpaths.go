@@ -32,6 +32,23 @@ func (r *Renter) newUniqueRefreshPaths() *uniqueRefreshPaths {
 	}
 }
 
+// managedBubbleParentDirs bubbles the parent directories of the given paths.
+func (r *Renter) managedBubbleParentDirs(paths ...modules.UploPath) {
+	bubblePaths := r.newUniqueRefreshPaths()
+	for _, path := range paths {
+		dir, err := path.Dir()
+		if err != nil {
+			r.log.Printf("Unable to fetch the directory of %v: %v", path, err)
+			continue
+		}
+		err = bubblePaths.callAdd(dir)
+		if err != nil {
+			r.log.Printf("failed to add directory '%v' to bubble paths: %v", dir, err)
+		}
+	}
+	bubblePaths.callRefreshAll()
+}
+
 // callAdd adds a path to uniqueRefreshPaths.
 func (ufp *uniqueRefreshPaths) callAdd(path modules.UploPath) error {
 	ufp.mu.Lock()
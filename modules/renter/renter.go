@@ -66,6 +66,7 @@ var (
 // contracts.
 type hostContractor interface {
 	modules.Alerter
+	modules.EventSubscriber
 
 	// SetAllowance sets the amount of money the contractor is allowed to
 	// spend on contracts over a given time period, divided among the number
@@ -186,6 +187,7 @@ type Renter struct {
 	// Skynet Management
 	staticSkynetBlocklist *skynetblocklist.SkynetBlocklist
 	staticSkynetPortals   *skynetportals.SkynetPortals
+	skykeyRotationMu      uplosync.TryMutex // Ensures that only one skykey is rotated at a time.
 
 	// Download management. The heap has a separate mutex because it is always
 	// accessed in isolation.
@@ -266,6 +268,9 @@ type Renter struct {
 	repairLog                          *persist.Logger
 	staticAccountManager               *accountManager
 	staticAlerter                      *modules.GenericAlerter
+	staticDedupIndex                   *dedupIndex
+	staticEvents                       modules.EventNotifier
+	staticTrashIndex                   *trashIndex
 	staticFileSystem                   *filesystem.FileSystem
 	staticFuseManager                  renterFuseManager
 	staticSkykeyManager                *skykey.SkykeyManager
@@ -286,7 +291,7 @@ func (r *Renter) Close() error {
 		return nil
 	}
 
-	return errors.Compose(r.tg.Stop(), r.hostDB.Close(), r.hostContractor.Close(), r.staticSkynetBlocklist.Close(), r.staticSkynetPortals.Close())
+	return errors.Compose(r.tg.Stop(), r.hostDB.Close(), r.hostContractor.Close(), r.staticSkynetBlocklist.Close(), r.staticSkynetPortals.Close(), r.staticDedupIndex.Close())
 }
 
 // MemoryStatus returns the current status of the memory manager
@@ -616,6 +621,9 @@ func (r *Renter) SetSettings(s modules.RenterSettings) error {
 	if s.MaxDownloadSpeed < 0 || s.MaxUploadSpeed < 0 {
 		return errors.New("bandwidth limits cannot be negative")
 	}
+	if s.TrashRetention < 0 {
+		return errors.New("trash retention cannot be negative")
+	}
 
 	// Set allowance.
 	err := r.hostContractor.SetAllowance(s.Allowance)
@@ -635,6 +643,7 @@ func (r *Renter) SetSettings(s modules.RenterSettings) error {
 	id := r.mu.Lock()
 	r.persist.MaxDownloadSpeed = s.MaxDownloadSpeed
 	r.persist.MaxUploadSpeed = s.MaxUploadSpeed
+	r.persist.TrashRetention = s.TrashRetention
 	err = r.saveSync()
 	r.mu.Unlock(id)
 	if err != nil {
@@ -833,6 +842,7 @@ func (r *Renter) Settings() (modules.RenterSettings, error) {
 		IPViolationCheck: enabled,
 		MaxDownloadSpeed: download,
 		MaxUploadSpeed:   upload,
+		TrashRetention:   r.managedTrashRetention(),
 		UploadsStatus: modules.UploadsStatus{
 			Paused:       paused,
 			PauseEndTime: endTime,
@@ -951,6 +961,36 @@ func (r *Renter) Skykeys() ([]skykey.Skykey, error) {
 	return r.staticSkykeyManager.Skykeys(), nil
 }
 
+// SkykeySharePublicKey returns the X25519 public key other users can share
+// skykeys with the renter for.
+func (r *Renter) SkykeySharePublicKey() (crypto.X25519PublicKey, error) {
+	if err := r.tg.Add(); err != nil {
+		return crypto.X25519PublicKey{}, err
+	}
+	defer r.tg.Done()
+	return r.staticSkykeyManager.SharePublicKey(), nil
+}
+
+// ShareSkykey wraps the skykey with the given ID for the owner of the given
+// X25519 public key.
+func (r *Renter) ShareSkykey(id skykey.SkykeyID, recipient crypto.X25519PublicKey) (skykey.SkykeyShare, error) {
+	if err := r.tg.Add(); err != nil {
+		return skykey.SkykeyShare{}, err
+	}
+	defer r.tg.Done()
+	return r.staticSkykeyManager.ShareKey(id, recipient)
+}
+
+// ImportSharedSkykey opens a skykey that was shared with the renter and adds it
+// to the renter's skykey manager.
+func (r *Renter) ImportSharedSkykey(ss skykey.SkykeyShare, name string) (skykey.Skykey, error) {
+	if err := r.tg.Add(); err != nil {
+		return skykey.Skykey{}, err
+	}
+	defer r.tg.Done()
+	return r.staticSkykeyManager.ImportSharedKey(ss, name)
+}
+
 // Enforce that Renter satisfies the modules.Renter interface.
 var _ modules.Renter = (*Renter)(nil)
 
@@ -1073,6 +1113,20 @@ func renterBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 	}
 	r.staticSkynetPortals = sp
 
+	// Load the dedup index.
+	di, err := newDedupIndex(r.persistDir)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to load dedup index")
+	}
+	r.staticDedupIndex = di
+
+	// Load the trash index.
+	ti, err := newTrashIndex(r.persistDir)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to load trash index")
+	}
+	r.staticTrashIndex = ti
+
 	// Load all saved data.
 	err = r.managedInitPersist()
 	if err != nil {
@@ -1101,6 +1155,13 @@ func renterBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 	r.managedUpdateRenterContractsAndUtilities()
 	go r.threadedUpdateRenterContractsAndUtilities()
 
+	// Kick off a thread that purges expired items from the trash.
+	go r.threadedPurgeTrash()
+
+	// Kick off a thread that prunes file versions which exceed the versioning
+	// policies.
+	go r.threadedPruneFileVersions()
+
 	// Spin up background threads which are not depending on the renter being
 	// up-to-date with consensus.
 	if !r.deps.Disrupt("DisableRepairAndHealthLoops") {
@@ -1149,6 +1210,8 @@ func renterAsyncStartup(r *Renter, cs modules.ConsensusSet) error {
 	if !r.deps.Disrupt("DisableSnapshotSync") {
 		go r.threadedSynchronizeSnapshots()
 	}
+	// Resume the skykey rotations which were interrupted by a shutdown.
+	go r.threadedResumeSkykeyRotations()
 	return nil
 }
 
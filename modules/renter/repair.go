@@ -745,6 +745,9 @@ func (r *Renter) managedUpdateFileMetadatasParams(dirUploPath modules.UploPath,
 
 // managedUpdateFileMetadata updates the metadata of a uplofile.
 func (r *Renter) managedUpdateFileMetadata(sf *filesystem.FileNode, offlineMap, goodForRenew map[string]bool, contracts map[string]modules.RenterContract, used []types.UploPublicKey) (err error) {
+	// Remember the cached values to notify subscribers about changes.
+	oldMetadata := sf.Metadata()
+
 	// Update the uplofile's used hosts.
 	if err := sf.UpdateUsedHosts(used); err != nil {
 		return errors.AddContext(err, "WARN: Could not update used hosts")
@@ -755,7 +758,17 @@ func (r *Renter) managedUpdateFileMetadata(sf *filesystem.FileNode, offlineMap,
 		return errors.AddContext(err, "WARN: Could not update cached redundancy")
 	}
 	// Update cached health values.
-	_, _, _, _, _, _, _ = sf.Health(offlineMap, goodForRenew)
+	health, _, _, _, _, _, _ := sf.Health(offlineMap, goodForRenew)
+	// Update the cached upload progress.
+	uploadProgress, _, err := sf.UploadProgressAndBytes()
+	if err != nil {
+		return errors.AddContext(err, "WARN: Could not update cached upload progress")
+	}
+	// The compressed copy of a compressed file is no longer needed once its
+	// initial upload is finished.
+	if uploadProgress >= 100 && sf.Compression().Compressed() {
+		r.staticRemoveCompressedCopy(sf.UID())
+	}
 	// Set the LastHealthCheckTime
 	sf.SetLastHealthCheckTime()
 	// Update the cached expiration of the uplofile.
@@ -765,5 +778,24 @@ func (r *Renter) managedUpdateFileMetadata(sf *filesystem.FileNode, offlineMap,
 	if err != nil {
 		return err
 	}
+
+	// Notify subscribers about changes of the health and about finished
+	// uploads.
+	healthChanged := health != oldMetadata.CachedHealth
+	uploadFinished := uploadProgress >= 100 && oldMetadata.CachedUploadProgress < 100
+	if !healthChanged && !uploadFinished {
+		return nil
+	}
+	uploPath := r.staticFileSystem.FileUploPath(sf)
+	if healthChanged {
+		r.staticEvents.Notify(modules.EventFileHealthChanged, modules.FileHealthEvent{
+			UploPath:       uploPath,
+			Health:         health,
+			PreviousHealth: oldMetadata.CachedHealth,
+		})
+	}
+	if uploadFinished {
+		r.staticEvents.Notify(modules.EventUploadFinished, modules.UploadEvent{UploPath: uploPath})
+	}
 	return nil
 }
@@ -847,26 +847,9 @@ func (r *Renter) RestoreSkyfile(reader io.Reader) (modules.Skylink, error) {
 	}
 
 	// Create the SkyfileUploadReader for the restoration
-	var restoreReader modules.SkyfileUploadReader
-	var buf bytes.Buffer
-	// Define a TeeReader for the underlying io.Reader. This allows the fanout
-	// bytes to be generated before the upload has completed by reading the data
-	// from the buffer rather than the chunks.
-	tee := io.TeeReader(reader, &buf)
-	if len(sm.Subfiles) == 0 {
-		restoreReader = modules.NewSkyfileReader(tee, sup)
-	} else {
-		// Create multipart reader from the subfiles
-		multiReader, err := modules.NewMultipartReader(tee, sm.Subfiles)
-		if err != nil {
-			return modules.Skylink{}, errors.AddContext(err, "unable to create multireader")
-		}
-		// Create the multipart reader for the fanout using the TeeReader's buffer.
-		multiReaderFanout, err := modules.NewMultipartReader(&buf, sm.Subfiles)
-		if err != nil {
-			return modules.Skylink{}, errors.AddContext(err, "unable to create multireader")
-		}
-		restoreReader = modules.NewSkyfileMultipartReader(multiReader, multiReaderFanout, sup)
+	restoreReader, err := newSkyfileUploadReader(reader, sm, sup)
+	if err != nil {
+		return modules.Skylink{}, err
 	}
 
 	// Upload the Base Sector of the skyfile
@@ -941,6 +924,30 @@ func (r *Renter) RestoreSkyfile(reader io.Reader) (modules.Skylink, error) {
 	return skylink, nil
 }
 
+// newSkyfileUploadReader creates a SkyfileUploadReader for uploading the data
+// of an existing skyfile with the given metadata again.
+func newSkyfileUploadReader(reader io.Reader, sm modules.SkyfileMetadata, sup modules.SkyfileUploadParameters) (modules.SkyfileUploadReader, error) {
+	var buf bytes.Buffer
+	// Define a TeeReader for the underlying io.Reader. This allows the fanout
+	// bytes to be generated before the upload has completed by reading the data
+	// from the buffer rather than the chunks.
+	tee := io.TeeReader(reader, &buf)
+	if len(sm.Subfiles) == 0 {
+		return modules.NewSkyfileReader(tee, sup), nil
+	}
+	// Create multipart reader from the subfiles
+	multiReader, err := modules.NewMultipartReader(tee, sm.Subfiles)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to create multireader")
+	}
+	// Create the multipart reader for the fanout using the TeeReader's buffer.
+	multiReaderFanout, err := modules.NewMultipartReader(&buf, sm.Subfiles)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to create multireader")
+	}
+	return modules.NewSkyfileMultipartReader(multiReader, multiReaderFanout, sup), nil
+}
+
 // UploadSkyfile will upload the provided data with the provided metadata,
 // returning a skylink which can be used by any portal to recover the full
 // original file and metadata. The skylink will be unique to the combination of
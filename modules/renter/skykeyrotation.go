@@ -0,0 +1,653 @@
+package renter
+
+// skykeyrotation.go implements the rotation of skykeys. Rotating a skykey
+// creates a new skykey and re-encrypts every skyfile of the renter that was
+// encrypted with the old skykey using the new one.
+//
+// The fanout key of a large skyfile is derived from its file-specific skykey,
+// so re-encrypting only the base sector isn't enough. Instead the skyfile is
+// downloaded entirely and uploaded again with the new skykey, which
+// re-encrypts the base sector as well as the fanout. The uplofiles of the
+// re-encrypted skyfile replace the uplofiles of the old skyfile. Since the
+// content of the base sector changes, every re-encrypted skyfile gets a new
+// skylink. The mapping from the old to the new skylinks is written to a file
+// in the renter's persist directory after every skyfile, so the mapping of the
+// re-encrypted skyfiles survives a crash during the rotation.
+//
+// Re-encrypting all skyfiles can take a long time, so the rotation runs in the
+// background. Its progress can be followed using the mapping file. Rotations
+// which were interrupted by a shutdown are resumed from their mapping file once
+// the renter is started again. Uplofiles which were left behind by an
+// interrupted rotation are cleaned up whenever a rotation starts.
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aead/chacha20/chacha"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/modules/renter/filesystem"
+	"github.com/uplo-tech/uplo/skykey"
+)
+
+const (
+	// skykeyRotationDir is the directory within the renter's persist
+	// directory that contains the mapping files of the skykey rotations.
+	skykeyRotationDir = "skykeyrotations"
+
+	// skykeyRotationSuffix is the suffix of the uplopath a skyfile is
+	// re-encrypted to before it replaces the old skyfile.
+	skykeyRotationSuffix = "-skykeyrotation"
+
+	// skykeyRotationBackupSuffix is the suffix of the uplopath the old
+	// skyfile is moved to while it is replaced by the re-encrypted skyfile.
+	skykeyRotationBackupSuffix = "-skykeyrotation-old"
+)
+
+var (
+	// errSkykeyRotationInProgress is returned if a skykey is rotated while
+	// another rotation is in progress.
+	errSkykeyRotationInProgress = errors.New("a skykey rotation is already in progress")
+
+	// errSkykeyRotationNotFound is returned if there is no rotation to the
+	// skykey with the given ID.
+	errSkykeyRotationNotFound = errors.New("no rotation to the skykey found")
+
+	// skykeyRotationDownloadTimeout is the timeout for downloading a skyfile
+	// that is re-encrypted.
+	skykeyRotationDownloadTimeout = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: 5 * time.Minute,
+		Testing:  30 * time.Second,
+	}).(time.Duration)
+)
+
+// encryptedWithSkykey returns whether the base sector was encrypted with the
+// given skykey.
+func encryptedWithSkykey(baseSector []byte, sk skykey.Skykey) bool {
+	if !modules.IsEncryptedBaseSector(baseSector) {
+		return false
+	}
+	var sl modules.SkyfileLayout
+	sl.Decode(baseSector)
+	keyID := sl.KeyData[:skykey.SkykeyIDLen]
+	nonce := sl.KeyData[skykey.SkykeyIDLen : skykey.SkykeyIDLen+chacha.XNonceSize]
+	switch sk.Type {
+	case skykey.TypePublicID:
+		id := sk.ID()
+		return bytes.Equal(keyID, id[:])
+	case skykey.TypePrivateID:
+		matches, err := sk.MatchesSkyfileEncryptionID(keyID, nonce)
+		return err == nil && matches
+	default:
+		return false
+	}
+}
+
+// isSkyfileCandidate returns whether the file might be the base sector
+// uplofile of a skyfile that needs to be re-encrypted. Files in the trash and
+// old versions of files are not re-encrypted.
+func isSkyfileCandidate(fi modules.FileInfo) bool {
+	return len(fi.Skylinks) > 0 &&
+		!strings.HasSuffix(fi.UploPath.String(), modules.ExtendedSuffix) &&
+		!strings.HasSuffix(fi.UploPath.String(), skykeyRotationSuffix) &&
+		!strings.HasSuffix(fi.UploPath.String(), skykeyRotationBackupSuffix) &&
+		!isTrashPath(fi.UploPath) &&
+		!isVersionPath(fi.UploPath)
+}
+
+// RotateSkykey creates a new skykey with the given name and the type of the
+// skykey with the given ID, and starts re-encrypting all skyfiles of the
+// renter that were encrypted with the old skykey using the new skykey in the
+// background. Skyfiles that can't be re-encrypted are reported with an error
+// and keep their old skylink. If blocklist is true, the old skylinks of the
+// re-encrypted skyfiles are added to the skynet blocklist.
+//
+// The returned rotation contains the new skykey and the mapping file, which is
+// updated after every skyfile and marked as finished once all skyfiles were
+// processed. The old skykey is not deleted, since it's needed to access the
+// old skylinks until all users switched to the new ones. If a rotation of the
+// skykey was interrupted, it's resumed with the skykey it created instead of
+// creating another one, and newName and blocklist are ignored.
+func (r *Renter) RotateSkykey(oldID skykey.SkykeyID, newName string, blocklist bool) (_ modules.SkykeyRotation, err error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.SkykeyRotation{}, err
+	}
+	defer r.tg.Done()
+	if !r.skykeyRotationMu.TryLock() {
+		return modules.SkykeyRotation{}, errSkykeyRotationInProgress
+	}
+	// The lock is released by the background thread once the rotation is
+	// done.
+	defer func() {
+		if err != nil {
+			r.skykeyRotationMu.Unlock()
+		}
+	}()
+
+	oldKey, err := r.staticSkykeyManager.KeyByID(oldID)
+	if err != nil {
+		return modules.SkykeyRotation{}, errors.AddContext(err, "unable to get skykey")
+	}
+
+	// Resume an interrupted rotation of the skykey.
+	unfinished, err := r.managedUnfinishedSkykeyRotations()
+	if err != nil {
+		return modules.SkykeyRotation{}, errors.AddContext(err, "unable to read skylink mappings")
+	}
+	for _, rotation := range unfinished {
+		if rotation.OldSkykeyID != oldID.ToString() {
+			continue
+		}
+		newKey, err := r.managedSkykeyByIDString(rotation.NewSkykeyID)
+		if err != nil {
+			return modules.SkykeyRotation{}, errors.AddContext(err, "unable to get skykey of interrupted rotation")
+		}
+		if err := r.tg.Add(); err != nil {
+			return modules.SkykeyRotation{}, err
+		}
+		go r.threadedRotateSkykey(rotation, oldKey, newKey)
+		return rotation, nil
+	}
+
+	// Create the new skykey.
+	newKey, err := r.staticSkykeyManager.CreateKey(newName, oldKey.Type)
+	if err != nil {
+		return modules.SkykeyRotation{}, errors.AddContext(err, "unable to create new skykey")
+	}
+	rotation := modules.SkykeyRotation{
+		OldSkykeyID:   oldID.ToString(),
+		NewSkykeyID:   newKey.ID().ToString(),
+		NewSkykeyName: newKey.Name,
+		Skyfiles:      []modules.SkykeyRotationSkyfile{},
+		Blocklist:     blocklist,
+	}
+	rotation.MappingFile, err = r.managedSaveSkykeyRotation(rotation)
+	if err != nil {
+		return modules.SkykeyRotation{}, errors.AddContext(err, "unable to write skylink mapping")
+	}
+
+	// Re-encrypt the skyfiles in the background.
+	if err := r.tg.Add(); err != nil {
+		return modules.SkykeyRotation{}, err
+	}
+	go r.threadedRotateSkykey(rotation, oldKey, newKey)
+	return rotation, nil
+}
+
+// managedSkykeyByIDString returns the skykey with the given base-64 encoded ID.
+func (r *Renter) managedSkykeyByIDString(idStr string) (skykey.Skykey, error) {
+	var id skykey.SkykeyID
+	if err := id.FromString(idStr); err != nil {
+		return skykey.Skykey{}, err
+	}
+	return r.staticSkykeyManager.KeyByID(id)
+}
+
+// threadedResumeSkykeyRotations resumes the skykey rotations which were
+// interrupted by a shutdown of the renter. Rotations whose skykeys don't exist
+// anymore are marked as finished with an error.
+func (r *Renter) threadedResumeSkykeyRotations() {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+	if !r.skykeyRotationMu.TryLock() {
+		// A rotation was started in the meantime. It resumes the
+		// interrupted rotation of its skykey itself.
+		return
+	}
+	defer r.skykeyRotationMu.Unlock()
+
+	unfinished, err := r.managedUnfinishedSkykeyRotations()
+	if err != nil {
+		r.log.Println("WARN: unable to read skylink mappings of skykey rotations:", err)
+		return
+	}
+	for _, rotation := range unfinished {
+		oldKey, err1 := r.managedSkykeyByIDString(rotation.OldSkykeyID)
+		newKey, err2 := r.managedSkykeyByIDString(rotation.NewSkykeyID)
+		if err := errors.Compose(err1, err2); err != nil {
+			rotation.Error = errors.AddContext(err, "unable to resume rotation").Error()
+			rotation.Finished = true
+			if _, err := r.managedSaveSkykeyRotation(rotation); err != nil {
+				r.log.Printf("WARN: unable to write skylink mapping of rotation to skykey %v: %v", rotation.NewSkykeyID, err)
+			}
+			continue
+		}
+		r.log.Printf("Resuming rotation of skykey %v to skykey %v", rotation.OldSkykeyID, rotation.NewSkykeyID)
+		r.managedRunSkykeyRotation(rotation, oldKey, newKey)
+	}
+}
+
+// managedUnfinishedSkykeyRotations returns the rotations whose mapping files
+// aren't marked as finished.
+func (r *Renter) managedUnfinishedSkykeyRotations() ([]modules.SkykeyRotation, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(r.persistDir, skykeyRotationDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rotations []modules.SkykeyRotation
+	for _, fi := range fis {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(r.persistDir, skykeyRotationDir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var rotation modules.SkykeyRotation
+		if err := json.Unmarshal(b, &rotation); err != nil {
+			return nil, errors.AddContext(err, "unable to decode skylink mapping "+fi.Name())
+		}
+		if !rotation.Finished {
+			rotations = append(rotations, rotation)
+		}
+	}
+	return rotations, nil
+}
+
+// SkykeyRotation returns the current state of the rotation to the skykey with
+// the given ID as persisted in its mapping file.
+func (r *Renter) SkykeyRotation(newID skykey.SkykeyID) (modules.SkykeyRotation, error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.SkykeyRotation{}, err
+	}
+	defer r.tg.Done()
+	b, err := ioutil.ReadFile(r.skykeyRotationPath(newID.ToString()))
+	if os.IsNotExist(err) {
+		return modules.SkykeyRotation{}, errSkykeyRotationNotFound
+	}
+	if err != nil {
+		return modules.SkykeyRotation{}, errors.AddContext(err, "unable to read skylink mapping")
+	}
+	var rotation modules.SkykeyRotation
+	err = json.Unmarshal(b, &rotation)
+	if err != nil {
+		return modules.SkykeyRotation{}, errors.AddContext(err, "unable to decode skylink mapping")
+	}
+	return rotation, nil
+}
+
+// threadedRotateSkykey runs a skykey rotation in the background and releases
+// the rotation lock once it's done.
+func (r *Renter) threadedRotateSkykey(rotation modules.SkykeyRotation, oldKey, newKey skykey.Skykey) {
+	defer r.tg.Done()
+	defer r.skykeyRotationMu.Unlock()
+	r.managedRunSkykeyRotation(rotation, oldKey, newKey)
+}
+
+// managedRunSkykeyRotation re-encrypts the skyfiles that were encrypted with
+// the old skykey and persists the mapping after every skyfile. If the renter
+// shuts down, the rotation stops and the mapping file keeps the skyfiles that
+// were re-encrypted so far. Skyfiles which are already in the mapping are
+// skipped, so an interrupted rotation can be resumed. The caller needs to hold
+// the rotation lock.
+func (r *Renter) managedRunSkykeyRotation(rotation modules.SkykeyRotation, oldKey, newKey skykey.Skykey) {
+	// Clean up the uplofiles of interrupted rotations.
+	if err := r.managedCleanUpSkykeyRotation(); err != nil {
+		r.log.Println("WARN: unable to clean up uplofiles of interrupted skykey rotations:", err)
+	}
+
+	// save persists the current state of the rotation.
+	save := func() {
+		if _, err := r.managedSaveSkykeyRotation(rotation); err != nil {
+			r.log.Printf("WARN: unable to write skylink mapping of rotation to skykey %v: %v", rotation.NewSkykeyID, err)
+		}
+	}
+
+	// Collect the files that might be skyfiles.
+	var mu sync.Mutex
+	var candidates []modules.FileInfo
+	flf := func(fi modules.FileInfo) {
+		if !isSkyfileCandidate(fi) {
+			return
+		}
+		mu.Lock()
+		candidates = append(candidates, fi)
+		mu.Unlock()
+	}
+	err := r.staticFileSystem.CachedList(modules.RootUploPath(), true, flf, func(modules.DirectoryInfo) {})
+	if err != nil {
+		rotation.Error = errors.AddContext(err, "unable to list files").Error()
+		rotation.Finished = true
+		save()
+		return
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].UploPath.String() < candidates[j].UploPath.String()
+	})
+	processed := make(map[modules.UploPath]struct{})
+	for _, entry := range rotation.Skyfiles {
+		processed[entry.UploPath] = struct{}{}
+	}
+
+	// Re-encrypt the skyfiles that were encrypted with the old skykey.
+	for _, fi := range candidates {
+		select {
+		case <-r.tg.StopChan():
+			return
+		default:
+		}
+		if _, ok := processed[fi.UploPath]; ok {
+			continue
+		}
+		oldSkylinks, newSkylink, err := r.managedRotateSkyfile(fi, oldKey, newKey)
+		if err != nil {
+			r.log.Printf("WARN: unable to re-encrypt skyfile %v with rotated skykey: %v", fi.UploPath, err)
+		}
+		if len(oldSkylinks) == 0 {
+			continue
+		}
+		var blocked []crypto.Hash
+		for _, link := range oldSkylinks {
+			entry := modules.SkykeyRotationSkyfile{
+				UploPath:   fi.UploPath,
+				OldSkylink: link.String(),
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			} else {
+				entry.NewSkylink = newSkylink.String()
+				blocked = append(blocked, crypto.HashObject(link.MerkleRoot()))
+			}
+			rotation.Skyfiles = append(rotation.Skyfiles, entry)
+		}
+
+		// Blocklist the old skylinks.
+		if rotation.Blocklist && len(blocked) > 0 {
+			err = r.staticSkynetBlocklist.UpdateBlocklist(blocked, nil)
+			if err != nil {
+				r.log.Printf("WARN: unable to blocklist old skylinks of skyfile %v: %v", fi.UploPath, err)
+				for i := len(rotation.Skyfiles) - len(oldSkylinks); i < len(rotation.Skyfiles); i++ {
+					rotation.Skyfiles[i].Error = errors.AddContext(err, "unable to blocklist old skylink").Error()
+				}
+			}
+		}
+		save()
+	}
+	rotation.Finished = true
+	save()
+}
+
+// managedRotateSkyfile re-encrypts a skyfile with the new skykey if it was
+// encrypted with the old skykey. It returns the skylinks of the file that were
+// encrypted with the old skykey and the new skylink of the skyfile.
+func (r *Renter) managedRotateSkyfile(fi modules.FileInfo, oldKey, newKey skykey.Skykey) ([]modules.Skylink, modules.Skylink, error) {
+	// Find the skylinks of the file that were encrypted with the old key.
+	var oldSkylinks []modules.Skylink
+	for _, str := range fi.Skylinks {
+		var link modules.Skylink
+		if err := link.LoadString(str); err != nil {
+			continue
+		}
+		baseSector, err := r.managedDownloadBaseSector(link, skykeyRotationDownloadTimeout)
+		if errors.Contains(err, ErrSkylinkBlocked) {
+			continue
+		}
+		if err != nil {
+			// It's unknown whether the skyfile was encrypted with the old
+			// key, so it's reported with the error.
+			return []modules.Skylink{link}, modules.Skylink{}, errors.AddContext(err, "unable to download base sector")
+		}
+		if encryptedWithSkykey(baseSector, oldKey) {
+			oldSkylinks = append(oldSkylinks, link)
+		}
+	}
+	if len(oldSkylinks) == 0 {
+		return nil, modules.Skylink{}, nil
+	}
+
+	// Download the skyfile.
+	_, metadata, streamer, err := r.managedDownloadSkylink(oldSkylinks[0], skykeyRotationDownloadTimeout)
+	if err != nil {
+		return oldSkylinks, modules.Skylink{}, errors.AddContext(err, "unable to download skyfile")
+	}
+	defer func() {
+		if err := streamer.Close(); err != nil {
+			r.log.Printf("WARN: unable to close streamer of skyfile %v: %v", fi.UploPath, err)
+		}
+	}()
+
+	// Upload it next to the old skyfile using the new skykey. The base chunk
+	// keeps its redundancy.
+	fileNode, err := r.staticFileSystem.OpenUploFile(fi.UploPath)
+	if err != nil {
+		return oldSkylinks, modules.Skylink{}, errors.AddContext(err, "unable to open uplofile")
+	}
+	redundancy := fileNode.ErasureCode().NumPieces()
+	if err := fileNode.Close(); err != nil {
+		return oldSkylinks, modules.Skylink{}, errors.AddContext(err, "unable to close uplofile")
+	}
+	tmpPath, err := modules.NewUploPath(fi.UploPath.String() + skykeyRotationSuffix)
+	if err != nil {
+		return oldSkylinks, modules.Skylink{}, errors.AddContext(err, "unable to create uplopath")
+	}
+	sup := modules.SkyfileUploadParameters{
+		UploPath:            tmpPath,
+		Force:               true,
+		BaseChunkRedundancy: uint8(redundancy),
+		Filename:            metadata.Filename,
+		Mode:                metadata.Mode,
+		DefaultPath:         metadata.DefaultPath,
+		DisableDefaultPath:  metadata.DisableDefaultPath,
+		SkykeyID:            newKey.ID(),
+	}
+	reader, err := newSkyfileUploadReader(streamer, metadata, sup)
+	if err != nil {
+		return oldSkylinks, modules.Skylink{}, err
+	}
+	newSkylink, err := r.UploadSkyfile(sup, reader)
+	if err != nil {
+		return oldSkylinks, modules.Skylink{}, errors.AddContext(err, "unable to upload re-encrypted skyfile")
+	}
+
+	// Replace the old skyfile.
+	err = r.managedReplaceSkyfile(tmpPath, fi.UploPath)
+	if err != nil {
+		return oldSkylinks, modules.Skylink{}, errors.AddContext(err, "unable to replace skyfile")
+	}
+	return oldSkylinks, newSkylink, nil
+}
+
+// managedReplaceSkyfile replaces the uplofiles of the skyfile at 'dst' with
+// the uplofiles of the skyfile at 'src'. The old uplofiles are moved aside
+// until the re-encrypted uplofiles took their place, so a failed rename
+// doesn't lose the skyfile. Afterwards they are deleted permanently, even if
+// the trash or versioning is enabled for them, since they were encrypted with
+// a retired skykey.
+func (r *Renter) managedReplaceSkyfile(src, dst modules.UploPath) error {
+	type replacement struct {
+		src, dst, backup modules.UploPath
+		required         bool
+	}
+	var replacements []replacement
+	for _, suffix := range []string{"", modules.ExtendedSuffix} {
+		srcPath, err := modules.NewUploPath(src.String() + suffix)
+		if err != nil {
+			return err
+		}
+		dstPath, err := modules.NewUploPath(dst.String() + suffix)
+		if err != nil {
+			return err
+		}
+		backupPath, err := modules.NewUploPath(dst.String() + skykeyRotationBackupSuffix + suffix)
+		if err != nil {
+			return err
+		}
+		// Only large skyfiles have an extended uplofile.
+		replacements = append(replacements, replacement{
+			src:      srcPath,
+			dst:      dstPath,
+			backup:   backupPath,
+			required: suffix == "",
+		})
+	}
+
+	// Move the old uplofiles aside.
+	var moved []replacement
+	restore := func() (err error) {
+		for _, rp := range moved {
+			err = errors.Compose(err, r.staticFileSystem.RenameFile(rp.backup, rp.dst))
+		}
+		return errors.AddContext(err, "unable to restore old uplofile")
+	}
+	for _, rp := range replacements {
+		err := r.staticFileSystem.RenameFile(rp.dst, rp.backup)
+		if errors.Contains(err, filesystem.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return errors.Compose(errors.AddContext(err, "unable to move old uplofile"), restore())
+		}
+		moved = append(moved, rp)
+	}
+
+	// Move the re-encrypted uplofiles into place. If that fails, the old
+	// uplofiles are restored.
+	var renamed []replacement
+	for _, rp := range replacements {
+		err := r.staticFileSystem.RenameFile(rp.src, rp.dst)
+		if errors.Contains(err, filesystem.ErrNotExist) && !rp.required {
+			continue
+		}
+		if err != nil {
+			err = errors.AddContext(err, "unable to rename re-encrypted uplofile")
+			for _, rr := range renamed {
+				err = errors.Compose(err, r.staticFileSystem.RenameFile(rr.dst, rr.src))
+			}
+			return errors.Compose(err, restore())
+		}
+		renamed = append(renamed, rp)
+	}
+
+	// Delete the old uplofiles.
+	for _, rp := range moved {
+		if err := r.managedDeleteRotatedUplofile(rp.backup); err != nil {
+			r.log.Printf("WARN: unable to delete old uplofile %v: %v", rp.backup, err)
+		}
+	}
+	dir, err := dst.Dir()
+	if err != nil {
+		return err
+	}
+	go r.callThreadedBubbleMetadata(dir)
+	return nil
+}
+
+// managedDeleteRotatedUplofile deletes an uplofile of a skykey rotation
+// permanently, bypassing the trash and versioning, together with the
+// deduplicated data that only it referenced.
+func (r *Renter) managedDeleteRotatedUplofile(uploPath modules.UploPath) error {
+	dedupUIDs := r.managedDedupFileUIDs(uploPath, false)
+	if err := r.staticFileSystem.DeleteFile(uploPath); err != nil {
+		return err
+	}
+	r.managedRemoveDedupFiles(dedupUIDs)
+	return nil
+}
+
+// managedCleanUpSkykeyRotation cleans up the uplofiles that an interrupted
+// rotation left behind. Re-encrypted uplofiles which didn't replace their
+// skyfile yet are deleted, the skyfile is re-encrypted again by the rotation.
+// Old uplofiles which were moved aside are deleted if the re-encrypted
+// uplofile took their place, and restored otherwise.
+func (r *Renter) managedCleanUpSkykeyRotation() error {
+	var mu sync.Mutex
+	var tmpPaths, backupPaths []modules.UploPath
+	flf := func(fi modules.FileInfo) {
+		path := strings.TrimSuffix(fi.UploPath.String(), modules.ExtendedSuffix)
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasSuffix(path, skykeyRotationSuffix) {
+			tmpPaths = append(tmpPaths, fi.UploPath)
+		} else if strings.HasSuffix(path, skykeyRotationBackupSuffix) {
+			backupPaths = append(backupPaths, fi.UploPath)
+		}
+	}
+	err := r.staticFileSystem.CachedList(modules.RootUploPath(), true, flf, func(modules.DirectoryInfo) {})
+	if err != nil {
+		return errors.AddContext(err, "unable to list files")
+	}
+
+	for _, backup := range backupPaths {
+		extended := strings.HasSuffix(backup.String(), modules.ExtendedSuffix)
+		dstStr := strings.TrimSuffix(strings.TrimSuffix(backup.String(), modules.ExtendedSuffix), skykeyRotationBackupSuffix)
+		if extended {
+			dstStr += modules.ExtendedSuffix
+		}
+		dst, err := modules.NewUploPath(dstStr)
+		if err != nil {
+			return err
+		}
+		exists, err := r.staticFileSystem.FileExists(dst)
+		if err != nil {
+			return err
+		}
+		if exists {
+			err = r.managedDeleteRotatedUplofile(backup)
+		} else {
+			err = r.staticFileSystem.RenameFile(backup, dst)
+		}
+		if err != nil {
+			return errors.AddContext(err, "unable to clean up old uplofile "+backup.String())
+		}
+	}
+	for _, tmpPath := range tmpPaths {
+		if err := r.managedDeleteRotatedUplofile(tmpPath); err != nil {
+			return errors.AddContext(err, "unable to delete re-encrypted uplofile "+tmpPath.String())
+		}
+	}
+	return nil
+}
+
+// skykeyRotationPath returns the path of the mapping file of the rotation to
+// the skykey with the given ID.
+func (r *Renter) skykeyRotationPath(newID string) string {
+	return filepath.Join(r.persistDir, skykeyRotationDir, newID+".json")
+}
+
+// managedSaveSkykeyRotation writes the state of a skykey rotation to a file
+// within the renter's persist directory and returns the path of the file. The
+// file is replaced atomically, so a crash never leaves a partial mapping
+// behind.
+func (r *Renter) managedSaveSkykeyRotation(rotation modules.SkykeyRotation) (string, error) {
+	dir := filepath.Join(r.persistDir, skykeyRotationDir)
+	if err := os.MkdirAll(dir, modules.DefaultDirPerm); err != nil {
+		return "", err
+	}
+	path := r.skykeyRotationPath(rotation.NewSkykeyID)
+	rotation.MappingFile = path
+	b, err := json.MarshalIndent(rotation, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := path + "_temp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, modules.DefaultFilePerm)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	err = errors.Compose(err, f.Close())
+	if err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmpPath, path)
+}
@@ -0,0 +1,304 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/fastrand"
+
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/modules/renter/filesystem/uplofile"
+	"github.com/uplo-tech/uplo/skykey"
+)
+
+// TestEncryptedWithSkykey probes identifying the skykey a base sector was
+// encrypted with.
+func TestEncryptedWithSkykey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Create a plaintext base sector.
+	fileBytes := fastrand.Bytes(1000)
+	metadataBytes, err := modules.SkyfileMetadataBytes(modules.SkyfileMetadata{Filename: "file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl := modules.SkyfileLayout{
+		Version:      modules.SkyfileVersion,
+		Filesize:     uint64(len(fileBytes)),
+		MetadataSize: uint64(len(metadataBytes)),
+		CipherType:   crypto.TypePlain,
+	}
+	baseSector, _ := modules.BuildBaseSector(sl.Encode(), nil, metadataBytes, fileBytes)
+
+	for _, skType := range []skykey.SkykeyType{skykey.TypePublicID, skykey.TypePrivateID} {
+		sk1, err := rt.renter.CreateSkykey("key1"+skType.ToString(), skType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sk2, err := rt.renter.CreateSkykey("key2"+skType.ToString(), skType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encryptedWithSkykey(baseSector, sk1) {
+			t.Fatal("plaintext base sector shouldn't match a skykey")
+		}
+
+		// Encrypt a copy of the base sector with a file-specific key of the
+		// first skykey. Only the first skykey should match.
+		fsKey, err := sk1.GenerateFileSpecificSubkey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted := make([]byte, len(baseSector))
+		copy(encrypted, baseSector)
+		err = encryptBaseSectorWithSkykey(encrypted, sl, fsKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !encryptedWithSkykey(encrypted, sk1) {
+			t.Fatal("base sector should match the skykey it was encrypted with", skType)
+		}
+		if encryptedWithSkykey(encrypted, sk2) {
+			t.Fatal("base sector shouldn't match a different skykey", skType)
+		}
+	}
+}
+
+// TestIsSkyfileCandidate probes the selection of the files that are checked
+// when rotating a skykey.
+func TestIsSkyfileCandidate(t *testing.T) {
+	skylinks := []string{"skylink"}
+	tests := []struct {
+		path     string
+		skylinks []string
+		expected bool
+	}{
+		{"var/skynet/file", skylinks, true},
+		{"var/skynet/file", nil, false},
+		{"var/skynet/file" + modules.ExtendedSuffix, skylinks, false},
+		{"var/skynet/file" + skykeyRotationSuffix, skylinks, false},
+		{"var/skynet/file" + skykeyRotationBackupSuffix, skylinks, false},
+		{modules.TrashFolder.String() + "/file", skylinks, false},
+		{modules.VersionsFolder.String() + "/file/1", skylinks, false},
+	}
+	for _, test := range tests {
+		uploPath, err := modules.NewUploPath(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi := modules.FileInfo{UploPath: uploPath, Skylinks: test.skylinks}
+		if isSkyfileCandidate(fi) != test.expected {
+			t.Errorf("unexpected result for %v", test.path)
+		}
+	}
+}
+
+// TestReplaceSkyfile probes replacing the uplofile of a skyfile with its
+// re-encrypted version.
+func TestReplaceSkyfile(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Helper to create a file and return its UID.
+	createFile := func(path string) (modules.UploPath, uplofile.UplofileUID) {
+		uploPath, err := modules.NewUploPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := r.createRenterTestFile(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		return uploPath, f.UID()
+	}
+	// Helper to get the UID of an existing file.
+	fileUID := func(uploPath modules.UploPath) uplofile.UplofileUID {
+		f, err := r.staticFileSystem.OpenUploFile(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		return f.UID()
+	}
+	// Helper to check that a file doesn't exist.
+	assertNotExist := func(uploPath modules.UploPath) {
+		exists, err := r.staticFileSystem.FileExists(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Fatalf("%v shouldn't exist", uploPath)
+		}
+	}
+
+	// Replace a skyfile with its re-encrypted version.
+	dst, _ := createFile("skyfile")
+	src, srcUID := createFile("skyfile" + skykeyRotationSuffix)
+	err = r.managedReplaceSkyfile(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileUID(dst) != srcUID {
+		t.Fatal("skyfile wasn't replaced")
+	}
+	assertNotExist(src)
+	backup, err := modules.NewUploPath(dst.String() + skykeyRotationBackupSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotExist(backup)
+
+	// If the re-encrypted uplofile doesn't exist, the old one should be
+	// kept.
+	dst, dstUID := createFile("skyfile2")
+	src, err = modules.NewUploPath("skyfile2" + skykeyRotationSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.managedReplaceSkyfile(src, dst)
+	if err == nil {
+		t.Fatal("expected replacing with a missing uplofile to fail")
+	}
+	if fileUID(dst) != dstUID {
+		t.Fatal("old skyfile wasn't restored")
+	}
+	backup, err = modules.NewUploPath(dst.String() + skykeyRotationBackupSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotExist(backup)
+}
+
+// TestCleanUpSkykeyRotation probes cleaning up the uplofiles of an interrupted
+// skykey rotation.
+func TestCleanUpSkykeyRotation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Helper to create a file and return its UID.
+	createFile := func(path string) (modules.UploPath, uplofile.UplofileUID) {
+		uploPath, err := modules.NewUploPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := r.createRenterTestFile(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		return uploPath, f.UID()
+	}
+	// Helper to check whether a file exists.
+	assertExists := func(uploPath modules.UploPath, expected bool) {
+		exists, err := r.staticFileSystem.FileExists(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != expected {
+			t.Fatalf("%v: expected exists to be %v but was %v", uploPath, expected, exists)
+		}
+	}
+
+	// A re-encrypted uplofile which didn't replace its skyfile yet.
+	skyfile, skyfileUID := createFile("skyfile")
+	tmp, _ := createFile("skyfile" + skykeyRotationSuffix)
+	// An old uplofile whose re-encrypted uplofile replaced it.
+	replaced, replacedUID := createFile("replaced")
+	replacedBackup, _ := createFile("replaced" + skykeyRotationBackupSuffix)
+	// An old uplofile which was moved aside but not replaced.
+	moved, err := modules.NewUploPath("moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	movedBackup, movedUID := createFile("moved" + skykeyRotationBackupSuffix)
+
+	if err := r.managedCleanUpSkykeyRotation(); err != nil {
+		t.Fatal(err)
+	}
+	assertExists(tmp, false)
+	assertExists(replacedBackup, false)
+	assertExists(movedBackup, false)
+	for uploPath, uid := range map[modules.UploPath]uplofile.UplofileUID{
+		skyfile:  skyfileUID,
+		replaced: replacedUID,
+		moved:    movedUID,
+	} {
+		f, err := r.staticFileSystem.OpenUploFile(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.UID() != uid {
+			t.Errorf("%v: wrong uplofile", uploPath)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only unfinished rotations should be resumed.
+	for newID, finished := range map[string]bool{"unfinished": false, "finished": true} {
+		_, err := r.managedSaveSkykeyRotation(modules.SkykeyRotation{
+			OldSkykeyID: "old",
+			NewSkykeyID: newID,
+			Finished:    finished,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	unfinished, err := r.managedUnfinishedSkykeyRotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unfinished) != 1 || unfinished[0].NewSkykeyID != "unfinished" {
+		t.Fatal("wrong unfinished rotations", unfinished)
+	}
+}
@@ -13,6 +13,7 @@ package renter
 
 import (
 	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -34,6 +35,12 @@ const (
 	// minimumDataSections is only at play if there is not enough room for
 	// multiple cache nodes in the bytesBufferedPerStream.
 	minimumDataSections = 2
+
+	// hlsPrefetchSegments is the number of HLS segments following the segment
+	// at the current offset of a stream that are buffered by the stream. Video
+	// players request the segments of an HLS stream one after another, so
+	// buffering the next segments avoids a stall at every segment boundary.
+	hlsPrefetchSegments = 2
 )
 
 var (
@@ -183,6 +190,10 @@ type streamBuffer struct {
 	staticDataSectionSize uint64
 	staticStreamBufferSet *streamBufferSet
 	staticStreamID        modules.DataSourceID
+
+	// staticHLSSegments contains the HLS segments of the data source in
+	// playlist order. It is empty if the data source is not an HLS skyfile.
+	staticHLSSegments []modules.SkyfileSubfileMetadata
 }
 
 // streamBufferSet tracks all of the stream buffers that are currently active.
@@ -233,6 +244,8 @@ func (sbs *streamBufferSet) callNewStream(dataSource streamBufferDataSource, ini
 			staticDataSectionSize: dataSource.RequestSize(),
 			staticStreamBufferSet: sbs,
 			staticStreamID:        sourceID,
+
+			staticHLSSegments: dataSource.Metadata().HLSSegments(),
 		}
 		sbs.streams[sourceID] = streamBuf
 	} else {
@@ -435,6 +448,50 @@ func (s *stream) prepareOffset() {
 		s.lru.callUpdate(nextIndex)
 		nextIndex++
 	}
+
+	// If the data source is an HLS skyfile, also buffer the segments that
+	// follow the segment at the current offset.
+	s.prepareNextHLSSegments(index, nextIndex)
+}
+
+// prepareNextHLSSegments will add the data sections of the HLS segments that
+// follow the segment at the current offset to the LRU. The data sections from
+// 'index' up to 'nextIndex' are expected to have been added already. The total
+// number of data sections never exceeds the size of the LRU, to prevent the
+// data section at the current offset from being evicted.
+func (s *stream) prepareNextHLSSegments(index, nextIndex uint64) {
+	// Convenience variables.
+	dataSize := s.staticStreamBuffer.staticDataSize
+	dataSectionSize := s.staticStreamBuffer.staticDataSectionSize
+	segments := s.staticStreamBuffer.staticHLSSegments
+	if len(segments) == 0 {
+		return
+	}
+
+	// Find the segment that contains the current offset.
+	current := sort.Search(len(segments), func(i int) bool {
+		return segments[i].Offset+segments[i].Len > s.offset
+	})
+	if current == len(segments) || segments[current].Offset > s.offset {
+		return
+	}
+
+	// Determine where the last segment to buffer ends.
+	last := current + hlsPrefetchSegments
+	if last >= len(segments) {
+		last = len(segments) - 1
+	}
+	end := segments[last].Offset + segments[last].Len
+	if end > dataSize {
+		end = dataSize
+	}
+
+	// Add the data sections up to the end of the last segment.
+	prepared := nextIndex - index
+	for ; nextIndex*dataSectionSize < end && prepared < s.lru.staticSize; nextIndex++ {
+		s.lru.callUpdate(nextIndex)
+		prepared++
+	}
 }
 
 // callFetchDataSection will increment the refcount of a dataSection in the
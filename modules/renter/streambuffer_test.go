@@ -25,6 +25,7 @@ import (
 type mockDataSource struct {
 	data              []byte
 	staticDataLen     uint64
+	staticMetadata    modules.SkyfileMetadata
 	staticRequestSize uint64
 	mu                sync.Mutex
 }
@@ -54,7 +55,7 @@ func (mds *mockDataSource) ID() modules.DataSourceID {
 
 // Metadata implements streamBufferDataSource
 func (mds *mockDataSource) Metadata() modules.SkyfileMetadata {
-	return modules.SkyfileMetadata{}
+	return mds.staticMetadata
 }
 
 // Layout implements streamBufferDataSource
@@ -482,3 +483,100 @@ func TestStreamSmoke(t *testing.T) {
 		t.Fatal("bad")
 	}
 }
+
+// TestStreamHLSPrefetch checks that a stream of an HLS skyfile buffers the
+// segments following the segment at the current offset.
+func TestStreamHLSPrefetch(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	// Create the metadata of an HLS skyfile with 40 segments of 40 bytes
+	// followed by the playlist.
+	dataSectionSize := uint64(16)
+	segmentSize := uint64(40)
+	md := modules.SkyfileMetadata{
+		Subfiles: make(modules.SkyfileSubfiles),
+	}
+	for i := uint64(0); i < 40; i++ {
+		filename := fmt.Sprintf("segment%05d.ts", i)
+		md.Subfiles[filename] = modules.SkyfileSubfileMetadata{
+			Filename: filename,
+			Offset:   i * segmentSize,
+			Len:      segmentSize,
+		}
+	}
+	md.Subfiles[modules.HLSPlaylistFilename] = modules.SkyfileSubfileMetadata{
+		Filename: modules.HLSPlaylistFilename,
+		Offset:   40 * segmentSize,
+		Len:      segmentSize,
+	}
+
+	// hasSections is a helper that checks that the data sections from the
+	// current offset of a stream up to lastIndex are in the LRU of the stream.
+	hasSections := func(s *stream, lastIndex uint64) error {
+		s.lru.mu.Lock()
+		defer s.lru.mu.Unlock()
+		for i := s.offset / dataSectionSize; i <= lastIndex; i++ {
+			if _, exists := s.lru.nodes[i]; !exists {
+				return fmt.Errorf("section %v should be buffered", i)
+			}
+		}
+		if _, exists := s.lru.nodes[lastIndex+1]; exists {
+			return fmt.Errorf("section %v should not be buffered", lastIndex+1)
+		}
+		return nil
+	}
+
+	// Create a stream for a regular data source. It should only buffer the
+	// minimum lookahead.
+	var tg threadgroup.ThreadGroup
+	sbs := newStreamBufferSet(&tg)
+	data := fastrand.Bytes(int(41 * segmentSize))
+	stream := sbs.callNewStream(newMockDataSource(data, dataSectionSize), 0)
+	lookaheadSections := minimumLookahead / dataSectionSize
+	if err := hasSections(stream, lookaheadSections-1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a stream for an HLS data source. It should buffer the current
+	// segment and the two following segments, which end at byte 120.
+	hlsData := fastrand.Bytes(int(41 * segmentSize))
+	dataSource := newMockDataSource(hlsData, dataSectionSize)
+	dataSource.staticMetadata = md
+	hlsStream := sbs.callNewStream(dataSource, 0)
+	if err := hasSections(hlsStream, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	// Seek into the sixth segment. The segments up to the eighth segment,
+	// which ends at byte 320, should be buffered.
+	_, err := hlsStream.Seek(200, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hasSections(hlsStream, 19); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the buffered data should work.
+	buf := make([]byte, 3*segmentSize)
+	_, err = io.ReadFull(hlsStream, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, hlsData[200:320]) {
+		t.Fatal("bad")
+	}
+
+	// Close the streams.
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := hlsStream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,352 @@
+package renter
+
+// trash.go implements the renter's trash. If the trash retention of the renter
+// is greater than 0, deleted files and directories are not removed right away.
+// Instead they are moved to the trash folder where they continue to be repaired
+// like any other file until the retention expires and they are purged by a
+// background thread.
+//
+// An item that was deleted at 'path' is stored at '/trash/id/path' where the id
+// is the unix timestamp in nanoseconds at which the item was deleted. The trash
+// index keeps track of the original path and the type of every item.
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/persist"
+)
+
+const (
+	// trashPersistFilename is the filename of the trash index within the
+	// renter's persist directory.
+	trashPersistFilename = "trash.json"
+)
+
+var (
+	// ErrUnknownTrashedItem is returned if an item doesn't exist in the trash.
+	ErrUnknownTrashedItem = errors.New("unknown trashed item")
+
+	// trashPersistMetadata is the metadata of the persisted trash index.
+	trashPersistMetadata = persist.Metadata{
+		Header:  "Renter Trash Index",
+		Version: "1.0",
+	}
+)
+
+type (
+	// trashIndex keeps track of the items within the trash.
+	trashIndex struct {
+		items map[string]modules.TrashedItem
+
+		staticPath string
+		mu         sync.Mutex
+	}
+
+	// trashPersist is the on-disk representation of the trash index.
+	trashPersist struct {
+		Items []modules.TrashedItem `json:"items"`
+	}
+)
+
+// isTrashPath returns whether the uplopath is within the trash folder.
+func isTrashPath(uploPath modules.UploPath) bool {
+	return uploPath.Equals(modules.TrashFolder) || strings.HasPrefix(uploPath.String(), modules.TrashFolder.String()+"/")
+}
+
+// newTrashIndex loads the trash index from the persist directory or creates a
+// new one if it doesn't exist yet.
+func newTrashIndex(persistDir string) (*trashIndex, error) {
+	ti := &trashIndex{
+		items:      make(map[string]modules.TrashedItem),
+		staticPath: filepath.Join(persistDir, trashPersistFilename),
+	}
+	var p trashPersist
+	err := persist.LoadJSON(trashPersistMetadata, &p, ti.staticPath)
+	if os.IsNotExist(err) {
+		return ti, nil
+	}
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to load trash index")
+	}
+	for _, item := range p.Items {
+		ti.items[item.ID] = item
+	}
+	return ti, nil
+}
+
+// save persists the trash index. The caller needs to hold the lock.
+func (ti *trashIndex) save() error {
+	p := trashPersist{
+		Items: make([]modules.TrashedItem, 0, len(ti.items)),
+	}
+	for _, item := range ti.items {
+		p.Items = append(p.Items, item)
+	}
+	return persist.SaveJSON(trashPersistMetadata, p, ti.staticPath)
+}
+
+// managedAdd adds an item to the index.
+func (ti *trashIndex) managedAdd(item modules.TrashedItem) error {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	if _, exists := ti.items[item.ID]; exists {
+		return errors.New("trashed item already exists")
+	}
+	ti.items[item.ID] = item
+	return ti.save()
+}
+
+// managedItem returns the item with the given id.
+func (ti *trashIndex) managedItem(id string) (modules.TrashedItem, bool) {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	item, exists := ti.items[id]
+	return item, exists
+}
+
+// managedItems returns all the items of the index, most recently deleted
+// first.
+func (ti *trashIndex) managedItems() []modules.TrashedItem {
+	ti.mu.Lock()
+	items := make([]modules.TrashedItem, 0, len(ti.items))
+	for _, item := range ti.items {
+		items = append(items, item)
+	}
+	ti.mu.Unlock()
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeleteTime.After(items[j].DeleteTime)
+	})
+	return items
+}
+
+// managedRemove removes the item with the given id from the index.
+func (ti *trashIndex) managedRemove(id string) error {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	if _, exists := ti.items[id]; !exists {
+		return nil
+	}
+	delete(ti.items, id)
+	return ti.save()
+}
+
+// EmptyTrash permanently deletes all the items within the trash.
+func (r *Renter) EmptyTrash() error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	items, err := r.managedTrashedItems()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		err = r.managedPurgeTrashedItem(item)
+		if err != nil {
+			return errors.AddContext(err, "unable to purge trashed item "+item.ID)
+		}
+	}
+	return nil
+}
+
+// RestoreTrashedItem moves an item from the trash back to its original
+// location.
+func (r *Renter) RestoreTrashedItem(id string) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	item, exists := r.staticTrashIndex.managedItem(id)
+	if !exists {
+		return ErrUnknownTrashedItem
+	}
+	var err error
+	if item.IsDir {
+		err = r.staticFileSystem.RenameDir(item.TrashPath, item.UploPath)
+	} else {
+		err = r.staticFileSystem.RenameFile(item.TrashPath, item.UploPath)
+	}
+	if err != nil {
+		return errors.AddContext(err, "unable to restore trashed item")
+	}
+	r.managedBubbleParentDirs(item.UploPath, item.TrashPath)
+
+	// Remove the now empty directory of the item from the trash.
+	itemDir, err := modules.TrashFolder.Join(item.ID)
+	if err != nil {
+		return err
+	}
+	err = r.staticFileSystem.DeleteDir(itemDir)
+	if err != nil {
+		return errors.AddContext(err, "unable to delete trash directory of restored item")
+	}
+	return r.staticTrashIndex.managedRemove(item.ID)
+}
+
+// Trash returns the items within the trash, most recently deleted first.
+func (r *Renter) Trash() ([]modules.TrashedItem, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
+	return r.managedTrashedItems()
+}
+
+// managedPurgeExpiredTrash permanently deletes the items whose retention
+// expired. If the trash is disabled, nothing is purged. Items which were
+// trashed before it was disabled can still be removed with EmptyTrash.
+func (r *Renter) managedPurgeExpiredTrash() error {
+	retention := r.managedTrashRetention()
+	if retention == 0 {
+		return nil
+	}
+	items, err := r.managedTrashedItems()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if time.Since(item.DeleteTime) < retention {
+			continue
+		}
+		err = r.managedPurgeTrashedItem(item)
+		if err != nil {
+			return errors.AddContext(err, "unable to purge trashed item "+item.ID)
+		}
+	}
+	return nil
+}
+
+// managedPurgeTrashedItem permanently deletes an item within the trash.
+func (r *Renter) managedPurgeTrashedItem(item modules.TrashedItem) error {
+	itemDir, err := modules.TrashFolder.Join(item.ID)
+	if err != nil {
+		return err
+	}
+	err = r.managedDeleteDir(itemDir)
+	if err != nil {
+		return err
+	}
+	err = r.staticTrashIndex.managedRemove(item.ID)
+	if err != nil {
+		return err
+	}
+	r.managedBubbleParentDirs(itemDir)
+	return nil
+}
+
+// managedTrash moves the file or directory at the given uplopath to the trash.
+func (r *Renter) managedTrash(uploPath modules.UploPath, isDir bool) error {
+	now := time.Now()
+	item := modules.TrashedItem{
+		DeleteTime: now,
+		ID:         strconv.FormatInt(now.UnixNano(), 10),
+		IsDir:      isDir,
+		UploPath:   uploPath,
+	}
+	itemDir, err := modules.TrashFolder.Join(item.ID)
+	if err != nil {
+		return err
+	}
+	item.TrashPath, err = itemDir.Join(uploPath.String())
+	if err != nil {
+		return err
+	}
+
+	// Add the item to the index before moving it to make sure that the
+	// original path isn't lost.
+	err = r.staticTrashIndex.managedAdd(item)
+	if err != nil {
+		return errors.AddContext(err, "unable to add item to trash index")
+	}
+	if isDir {
+		err = r.staticFileSystem.RenameDir(uploPath, item.TrashPath)
+	} else {
+		err = r.staticFileSystem.RenameFile(uploPath, item.TrashPath)
+	}
+	if err != nil {
+		return errors.Compose(err, r.staticTrashIndex.managedRemove(item.ID))
+	}
+	r.managedBubbleParentDirs(uploPath, item.TrashPath)
+	return nil
+}
+
+// managedTrashedItems returns the items within the trash, most recently
+// deleted first. Items which were removed from the trash folder by other means
+// are dropped from the index.
+func (r *Renter) managedTrashedItems() ([]modules.TrashedItem, error) {
+	items := r.staticTrashIndex.managedItems()
+	existing := items[:0]
+	for _, item := range items {
+		var exists bool
+		var err error
+		if item.IsDir {
+			exists, err = r.staticFileSystem.DirExists(item.TrashPath)
+		} else {
+			exists, err = r.staticFileSystem.FileExists(item.TrashPath)
+		}
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to check for trashed item")
+		}
+		if exists {
+			existing = append(existing, item)
+			continue
+		}
+		err = r.staticTrashIndex.managedRemove(item.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// managedTrashRetention returns how long deleted items are kept in the trash.
+// A retention of 0 means that the trash is disabled.
+func (r *Renter) managedTrashRetention() time.Duration {
+	id := r.mu.RLock()
+	defer r.mu.RUnlock(id)
+	return r.persist.TrashRetention
+}
+
+// managedTryTrash moves the file or directory at the given uplopath to the
+// trash if the trash is enabled. Items within the trash or versions folders
+// are never trashed. The returned bool indicates whether the item was trashed.
+func (r *Renter) managedTryTrash(uploPath modules.UploPath, isDir bool) (bool, error) {
+	if r.managedTrashRetention() == 0 || uploPath.IsRoot() || isTrashPath(uploPath) || isVersionPath(uploPath) {
+		return false, nil
+	}
+	err := r.managedTrash(uploPath, isDir)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// threadedPurgeTrash periodically purges the items within the trash whose
+// retention expired.
+func (r *Renter) threadedPurgeTrash() {
+	err := r.tg.Add()
+	if err != nil {
+		return
+	}
+	defer r.tg.Done()
+	for {
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-time.After(trashPurgeInterval):
+		}
+		err = r.managedPurgeExpiredTrash()
+		if err != nil {
+			r.log.Println("Unable to purge expired trash:", err)
+		}
+	}
+}
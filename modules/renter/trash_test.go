@@ -0,0 +1,156 @@
+package renter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+)
+
+// TestTrash probes moving deleted files and directories to the trash,
+// restoring them and purging them.
+func TestTrash(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// createFile creates a file at the given path.
+	createFile := func(uploPath modules.UploPath) {
+		entry, err := r.createRenterTestFile(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := entry.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// setRetention sets the trash retention of the renter.
+	setRetention := func(retention time.Duration) {
+		id := r.mu.Lock()
+		r.persist.TrashRetention = retention
+		r.mu.Unlock(id)
+	}
+	// trash returns the items within the trash.
+	trash := func() []modules.TrashedItem {
+		items, err := r.Trash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return items
+	}
+
+	// Delete a file and a directory with the trash enabled.
+	setRetention(time.Hour)
+	file := newUploPath("trashed/file")
+	createFile(file)
+	if err := r.DeleteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	dir := newUploPath("trasheddir")
+	createFile(newUploPath("trasheddir/file"))
+	if err := r.DeleteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := r.staticFileSystem.FileExists(file); exists {
+		t.Fatal("file should have been moved to the trash")
+	}
+	if exists, _ := r.staticFileSystem.DirExists(dir); exists {
+		t.Fatal("dir should have been moved to the trash")
+	}
+	items := trash()
+	if len(items) != 2 {
+		t.Fatal("wrong number of trashed items", len(items))
+	}
+	if !items[0].IsDir || !items[0].UploPath.Equals(dir) {
+		t.Fatal("wrong item", items[0])
+	}
+	if items[1].IsDir || !items[1].UploPath.Equals(file) {
+		t.Fatal("wrong item", items[1])
+	}
+	if exists, _ := r.staticFileSystem.FileExists(items[1].TrashPath); !exists {
+		t.Fatal("file isn't in the trash")
+	}
+
+	// Restore the file.
+	if err := r.RestoreTrashedItem(items[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := r.staticFileSystem.FileExists(file); !exists {
+		t.Fatal("file wasn't restored")
+	}
+	if len(trash()) != 1 {
+		t.Fatal("restored item should be removed from the trash")
+	}
+	// Restoring an unknown item should fail.
+	if err := r.RestoreTrashedItem(items[1].ID); !errors.Contains(err, ErrUnknownTrashedItem) {
+		t.Fatal("expected ErrUnknownTrashedItem", err)
+	}
+
+	// Purging the trash shouldn't remove items before the retention expires.
+	if err := r.managedPurgeExpiredTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trash()) != 1 {
+		t.Fatal("item was purged before the retention expired")
+	}
+	setRetention(time.Nanosecond)
+	if err := r.managedPurgeExpiredTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trash()) != 0 {
+		t.Fatal("expired item wasn't purged")
+	}
+	if exists, _ := r.staticFileSystem.DirExists(items[0].TrashPath); exists {
+		t.Fatal("purged dir still exists")
+	}
+
+	// Emptying the trash removes all items.
+	setRetention(time.Hour)
+	if err := r.DeleteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EmptyTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trash()) != 0 {
+		t.Fatal("trash wasn't emptied")
+	}
+
+	// Files are deleted permanently if the trash is disabled.
+	setRetention(0)
+	createFile(file)
+	if err := r.DeleteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if len(trash()) != 0 {
+		t.Fatal("file shouldn't be trashed if the trash is disabled")
+	}
+
+	// Disabling the trash shouldn't purge the items that are already in it.
+	setRetention(time.Hour)
+	createFile(file)
+	if err := r.DeleteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	setRetention(0)
+	if err := r.managedPurgeExpiredTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trash()) != 1 {
+		t.Fatal("items were purged while the trash is disabled")
+	}
+}
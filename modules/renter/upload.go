@@ -10,7 +10,9 @@ package renter
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/uplo-tech/errors"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/modules/renter/filesystem"
+	"github.com/uplo-tech/uplo/modules/renter/filesystem/uplofile"
 )
 
 var (
@@ -27,7 +30,7 @@ var (
 
 // Upload instructs the renter to start tracking a file. The renter will
 // automatically upload and repair tracked files using a background loop.
-func (r *Renter) Upload(up modules.FileUploadParams) error {
+func (r *Renter) Upload(up modules.FileUploadParams) (err error) {
 	if err := r.tg.Add(); err != nil {
 		return err
 	}
@@ -88,11 +91,44 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	if up.CipherType == ct {
 		up.CipherType = crypto.TypeDefaultRenter
 	}
-	// Generate a key using the cipher type.
+	// Generate a key using the cipher type. Deduplicated files use a key
+	// derived from the renter seed to produce the same pieces for the same
+	// data.
 	cipherKey := crypto.GenerateUploKey(up.CipherType)
+	if up.Dedup {
+		cipherKey, err = r.managedDedupMasterKey(up.CipherType)
+		if err != nil {
+			return errors.AddContext(err, "unable to derive dedup master key")
+		}
+		// Partial chunks are combined with other files which would change the
+		// pieces of the last chunk.
+		up.DisablePartialChunk = true
+	}
+
+	// If the file is compressed, build the frame index. The uplofile tracks
+	// the compressed data. The compressed data is kept until the initial
+	// upload is finished to avoid compressing the file a second time.
+	fileSize := uint64(sourceInfo.Size())
+	var ci modules.CompressionInfo
+	var compressedCopy string
+	if up.Compression != modules.CompressionNone && fileSize > 0 {
+		ci, compressedCopy, err = r.staticCompressSourceFile(up.Source, up.Compression)
+		if err != nil {
+			return errors.AddContext(err, "unable to compress the source file")
+		}
+		defer func() {
+			if compressedCopy != "" {
+				err = errors.Compose(err, os.Remove(compressedCopy))
+			}
+		}()
+		fileSize = ci.CompressedSize()
+		// The partial chunk of a compressed file would be combined with
+		// uncompressed data.
+		up.DisablePartialChunk = true
+	}
 
 	// Create the Uplofile and add to renter
-	err = r.staticFileSystem.NewUploFile(up.UploPath, up.Source, up.ErasureCode, cipherKey, uint64(sourceInfo.Size()), sourceInfo.Mode(), up.DisablePartialChunk)
+	err = r.staticFileSystem.NewUploFile(up.UploPath, up.Source, up.ErasureCode, cipherKey, fileSize, sourceInfo.Mode(), up.DisablePartialChunk)
 	if err != nil {
 		return errors.AddContext(err, "could not create a new uplo file")
 	}
@@ -100,6 +136,23 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	if err != nil {
 		return errors.AddContext(err, "could not open the new uplo file")
 	}
+	if ci.Compressed() {
+		err = entry.SetCompression(ci)
+		if err != nil {
+			return errors.Compose(errors.AddContext(err, "could not set the compression of the new uplo file"), entry.Close())
+		}
+		err = os.Rename(compressedCopy, r.staticCompressedCopyPath(entry.UID()))
+		if err != nil {
+			return errors.Compose(errors.AddContext(err, "could not keep the compressed data of the new uplo file"), entry.Close())
+		}
+		compressedCopy = ""
+	}
+	if up.Dedup {
+		err = r.staticDedupIndex.managedTrackFile(entry.UID())
+		if err != nil {
+			return errors.Compose(errors.AddContext(err, "could not add the new uplo file to the dedup index"), entry.Close())
+		}
+	}
 
 	// No need to upload zero-byte files.
 	if sourceInfo.Size() == 0 {
@@ -125,3 +178,45 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	}
 	return nil
 }
+
+// staticCompressSourceFile compresses the file at the given path to build its
+// frame index. The compressed data is written to a temporary file whose path is
+// returned together with the frame index.
+func (r *Renter) staticCompressSourceFile(path string, ct modules.CompressionType) (_ modules.CompressionInfo, _ string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return modules.CompressionInfo{}, "", errors.AddContext(err, "unable to open the source file")
+	}
+	defer func() {
+		err = errors.Compose(err, file.Close())
+	}()
+	dir := filepath.Join(r.persistDir, compressedCopiesDir)
+	if err := os.MkdirAll(dir, modules.DefaultDirPerm); err != nil {
+		return modules.CompressionInfo{}, "", errors.AddContext(err, "unable to create the directory for compressed data")
+	}
+	compressed, err := ioutil.TempFile(dir, "upload")
+	if err != nil {
+		return modules.CompressionInfo{}, "", errors.AddContext(err, "unable to create the file for compressed data")
+	}
+	ci, err := modules.NewCompressionInfo(file, compressed, ct, modules.DefaultCompressionFrameSize)
+	err = errors.Compose(err, compressed.Sync(), compressed.Close())
+	if err != nil {
+		return modules.CompressionInfo{}, "", errors.Compose(err, os.Remove(compressed.Name()))
+	}
+	return ci, compressed.Name(), nil
+}
+
+// staticCompressedCopyPath returns the path of the compressed data that is
+// kept for the initial upload of the uplofile with the given uid.
+func (r *Renter) staticCompressedCopyPath(uid uplofile.UplofileUID) string {
+	return filepath.Join(r.persistDir, compressedCopiesDir, string(uid))
+}
+
+// staticRemoveCompressedCopy removes the compressed data that was kept for the
+// initial upload of the uplofile with the given uid if it exists.
+func (r *Renter) staticRemoveCompressedCopy(uid uplofile.UplofileUID) {
+	err := os.Remove(r.staticCompressedCopyPath(uid))
+	if err != nil && !os.IsNotExist(err) {
+		r.log.Println("WARN: unable to remove compressed copy:", err)
+	}
+}
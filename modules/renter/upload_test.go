@@ -1,8 +1,10 @@
 package renter
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/uplo-tech/uplo/modules"
@@ -48,3 +50,74 @@ func TestRenterUploadDirectory(t *testing.T) {
 		t.Fatal("expected ErrUploadDirectory, got", err)
 	}
 }
+
+// TestRenterUploadCompressedCopy verifies that the compressed data created
+// while building the frame index of a compressed upload is kept for the upload
+// and removed together with the file.
+func TestRenterUploadCompressedCopy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Create a compressible source file.
+	source := filepath.Join(rt.dir, "source")
+	data := bytes.Repeat([]byte("compressible"), int(modules.DefaultCompressionFrameSize))
+	if err := ioutil.WriteFile(source, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Upload the file with compression.
+	params := modules.FileUploadParams{
+		Source:      source,
+		UploPath:    modules.RandomUploPath(),
+		ErasureCode: modules.NewRSCodeDefault(),
+		Compression: modules.CompressionDeflate,
+	}
+	if err := r.Upload(params); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := r.staticFileSystem.OpenUploFile(params.UploPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := entry.Compression()
+	uid := entry.UID()
+	if err := entry.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The compressed copy should contain the compressed data of the file.
+	compressed, err := ioutil.ReadFile(r.staticCompressedCopyPath(uid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ci.ReadCompressedRange(f, 0, ci.CompressedSize())
+	if err := errors.Compose(err, f.Close()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(compressed, expected) {
+		t.Fatal("compressed copy doesn't match the compressed data")
+	}
+
+	// Deleting the file removes the compressed copy.
+	if err := r.DeleteFile(params.UploPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(r.staticCompressedCopyPath(uid)); !os.IsNotExist(err) {
+		t.Fatal("compressed copy wasn't removed", err)
+	}
+}
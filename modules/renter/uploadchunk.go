@@ -1,6 +1,7 @@
 package renter
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -46,6 +47,7 @@ type unfinishedUploadChunk struct {
 
 	// Static cached fields.
 	staticIndex    uint64
+	staticKeyIndex uint64 // chunk index the encryption keys of the pieces are derived from
 	staticUploPath  string
 	staticPriority bool // indicates if the chunk should get access to priority memory
 
@@ -64,6 +66,13 @@ type unfinishedUploadChunk struct {
 	// and be confident that the data now is the same as what it used to be.
 	staticExpectedPieceRoots []crypto.Hash
 
+	// staticDedup indicates that the chunk belongs to a deduplicated file. If
+	// it is set, plaintextHash is the hash of the chunk's data before erasure
+	// coding. It is set when the data is read from the source reader or a local
+	// file and used to look up the chunk in the dedup index.
+	staticDedup   bool
+	plaintextHash crypto.Hash
+
 	// sourceReader is an optional source for the logical chunk data. If
 	// available it will be tried before the repair path or remote repair.
 	sourceReader io.ReadCloser
@@ -211,7 +220,7 @@ func readDataPieces(r io.Reader, ec modules.ErasureCoder, pieceSize uint64) ([][
 // padAndEncryptPiece will add padding to a unfinishedUploadChunk's piece at
 // index i and then encrypt it.
 func (uc *unfinishedUploadChunk) padAndEncryptPiece(i int) {
-	padAndEncryptPiece(uc.staticIndex, uint64(i), uc.logicalChunkData, uc.fileEntry.MasterKey())
+	padAndEncryptPiece(uc.staticKeyIndex, uint64(i), uc.logicalChunkData, uc.fileEntry.MasterKey())
 }
 
 // padAndEncryptPiece will add padding to a piece and then encrypt it.
@@ -409,6 +418,12 @@ func (r *Renter) threadedFetchAndRepairChunk(chunk *unfinishedUploadChunk) {
 		return
 	}
 
+	// If the chunk's pieces already exist on the network, there is no need to
+	// distribute it.
+	if r.managedTryDedupChunk(chunk) {
+		return
+	}
+
 	// Distribute the chunk to the workers.
 	r.staticUploadChunkDistributionQueue.callAddUploadChunk(chunk)
 }
@@ -471,6 +486,9 @@ func (uc *unfinishedUploadChunk) staticReadLogicalData(r io.Reader) (uint64, err
 	if err != nil {
 		return 0, err
 	}
+	if uc.staticDedup {
+		uc.plaintextHash = dedupPlaintextHash(dataPieces)
+	}
 	// Encode the data pieces, forming the chunk's logical data.
 	//
 	// TODO: Ideally there is a way to only encode the shards that we need.
@@ -494,6 +512,7 @@ func (r *Renter) staticFetchLogicalDataFromReader(uc *unfinishedUploadChunk) (er
 	// Perform an integrity check on the data that was pulled from the reader.
 	err = uc.staticEncryptAndCheckIntegrity()
 	if err != nil {
+		uc.plaintextHash = crypto.Hash{}
 		return errors.AddContext(err, "source data does not match previously uploaded data - blocking corrupt repair")
 	}
 
@@ -508,6 +527,45 @@ func (r *Renter) staticFetchLogicalDataFromReader(uc *unfinishedUploadChunk) (er
 	return nil
 }
 
+// staticReadLogicalChunkData reads the data of the chunk from the reader,
+// erasure codes and encrypts it and checks its integrity.
+func staticReadLogicalChunkData(uc *unfinishedUploadChunk, r io.Reader) error {
+	dataPieces, _, err := readDataPieces(r, uc.fileEntry.ErasureCode(), uc.fileEntry.PieceSize())
+	if err != nil {
+		return errors.AddContext(err, "unable to read the data")
+	}
+	var plaintextHash crypto.Hash
+	if uc.staticDedup {
+		plaintextHash = dedupPlaintextHash(dataPieces)
+	}
+	uc.logicalChunkData, _ = uc.fileEntry.ErasureCode().EncodeShards(dataPieces)
+	err = uc.staticEncryptAndCheckIntegrity()
+	if err != nil {
+		return errors.AddContext(err, "data failed the integrity check")
+	}
+	uc.plaintextHash = plaintextHash
+	return nil
+}
+
+// staticFetchLogicalDataFromCompressedCopy reads the data of a chunk of a
+// compressed file from the compressed data that was kept after building its
+// frame index. The returned bool indicates whether the compressed data was
+// still available.
+func (r *Renter) staticFetchLogicalDataFromCompressedCopy(uc *unfinishedUploadChunk) (_ bool, err error) {
+	f, err := os.Open(r.staticCompressedCopyPath(uc.fileEntry.UID()))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	defer func() {
+		err = errors.Compose(err, f.Close())
+	}()
+	sr := io.NewSectionReader(f, uc.offset, int64(uc.length))
+	return true, errors.AddContext(staticReadLogicalChunkData(uc, sr), "compressed copy")
+}
+
 // managedFetchLogicalChunkData will get the raw data for a chunk, pulling it from disk if
 // possible but otherwise queueing a download.
 //
@@ -523,6 +581,18 @@ func (r *Renter) managedFetchLogicalChunkData(uc *unfinishedUploadChunk) error {
 		return nil
 	}
 
+	// Use the compressed data of a compressed file if it is still available
+	// from the initial upload.
+	if uc.fileEntry.Compression().Compressed() {
+		found, err := r.staticFetchLogicalDataFromCompressedCopy(uc)
+		if found && err == nil {
+			return nil
+		}
+		if err != nil {
+			r.log.Printf("WARN: unable to fetch chunk %v of %v from its compressed copy: %v", uc.staticIndex, uc.staticUploPath, err)
+		}
+	}
+
 	// No source reader available. Check if there's potentially a local file. If
 	// there is no local file, fall back to doing a remote repair.
 	// disk.
@@ -548,17 +618,18 @@ func (r *Renter) managedFetchLogicalChunkData(uc *unfinishedUploadChunk) error {
 		defer func() {
 			err = errors.Compose(err, osFile.Close())
 		}()
-		sr := io.NewSectionReader(osFile, uc.offset, int64(uc.length))
-		dataPieces, _, err := readDataPieces(sr, uc.fileEntry.ErasureCode(), uc.fileEntry.PieceSize())
-		if err != nil {
-			return errors.AddContext(err, "unable to read the data from the local file")
-		}
-		uc.logicalChunkData, _ = uc.fileEntry.ErasureCode().EncodeShards(dataPieces)
-		err = uc.staticEncryptAndCheckIntegrity()
-		if err != nil {
-			return errors.AddContext(err, "local file failed the integrity check")
+		// The local copy of a compressed file contains the uncompressed data.
+		// The compressed data of the chunk is recreated from the frames it
+		// spans.
+		var sr io.Reader = io.NewSectionReader(osFile, uc.offset, int64(uc.length))
+		if ci := uc.fileEntry.Compression(); ci.Compressed() {
+			data, err := ci.ReadCompressedRange(osFile, uint64(uc.offset), uc.length)
+			if err != nil {
+				return errors.AddContext(err, "unable to compress the data from the local file")
+			}
+			sr = bytes.NewReader(data)
 		}
-		return nil
+		return errors.AddContext(staticReadLogicalChunkData(uc, sr), "local file")
 	}()
 	if err != nil {
 		r.log.Printf("falling back to remote download for repair: fetch from local file %v failed: %v", uc.fileEntry.LocalPath(), err)
@@ -616,6 +687,7 @@ func (r *Renter) managedCleanUpUploadChunk(uc *unfinishedUploadChunk) {
 	// yet been released.
 	released := uc.released
 	canceled := uc.canceled
+	var uploadErr error
 	if chunkComplete && !released {
 		if uc.piecesCompleted >= uc.staticPiecesNeeded {
 			r.repairLog.Printf("Completed repair for chunk %v of %s, %v pieces were completed out of %v", uc.staticIndex, uc.staticUploPath, uc.piecesCompleted, uc.staticPiecesNeeded)
@@ -626,6 +698,7 @@ func (r *Renter) managedCleanUpUploadChunk(uc *unfinishedUploadChunk) {
 			uc.err = errors.New("unable to upload file, file is not available on the network")
 			uc.chunkAvailableTime = time.Now()
 			close(uc.staticAvailableChan)
+			uploadErr = uc.err
 		}
 		uc.released = true
 
@@ -653,6 +726,14 @@ func (r *Renter) managedCleanUpUploadChunk(uc *unfinishedUploadChunk) {
 	workersRemaining := uc.workersRemaining
 	uc.mu.Unlock()
 
+	// Notify subscribers if the chunk couldn't be uploaded.
+	if uploadErr != nil {
+		r.staticEvents.Notify(modules.EventUploadFailed, modules.UploadEvent{
+			UploPath: r.staticFileSystem.FileUploPath(uc.fileEntry),
+			Error:    uploadErr.Error(),
+		})
+	}
+
 	// If there are pieces available, add the standby workers to collect them.
 	// Standby workers are only added to the chunk when piecesAvailable is equal
 	// to zero, meaning this code will only trigger if the number of pieces
@@ -665,6 +746,9 @@ func (r *Renter) managedCleanUpUploadChunk(uc *unfinishedUploadChunk) {
 	if chunkComplete && !released {
 		r.managedUpdateUploadChunkStuckStatus(uc)
 
+		// Add the chunk to the dedup index if its file is deduplicated.
+		r.managedAddDedupChunk(uc)
+
 		// Update the file's metadata.
 		offlineMap, goodForRenewMap, contracts, used := r.managedRenterContractsAndUtilities()
 		err := r.managedUpdateFileMetadata(uc.fileEntry, offlineMap, goodForRenewMap, contracts, used)
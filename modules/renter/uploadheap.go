@@ -518,6 +518,16 @@ func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIn
 		r.log.Println("WARN: unable to get 'stuck' status:", err)
 		return nil, errors.AddContext(err, "unable to get 'stuck' status")
 	}
+	// The pieces of deduplicated chunks might be encrypted for a different
+	// chunk index.
+	dedup := r.staticDedupIndex.managedTracked(entry.UID())
+	keyIndex := chunkIndex
+	if dedup {
+		keyIndex, err = entry.ChunkKeyIndex(chunkIndex)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to get key index of chunk")
+		}
+	}
 	_, err = os.Stat(entryCopy.LocalPath())
 	onDisk := err == nil
 	uuc := &unfinishedUploadChunk{
@@ -531,10 +541,12 @@ func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIn
 		length:         entry.ChunkSize(),
 		offset:         int64(chunkIndex * entry.ChunkSize()),
 		onDisk:         onDisk,
+		staticDedup:    dedup,
 		staticPriority: priority,
 
-		staticIndex:   chunkIndex,
-		staticUploPath: entryCopy.UploFilePath(),
+		staticIndex:    chunkIndex,
+		staticKeyIndex: keyIndex,
+		staticUploPath:  entryCopy.UploFilePath(),
 
 		staticMemoryManager: mm,
 
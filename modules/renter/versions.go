@@ -0,0 +1,382 @@
+package renter
+
+// versions.go implements the versioning of files. If versioning is enabled for
+// a uplodir, files within the uplodir and its subdirectories are not removed
+// when they are deleted or overwritten. Instead they are moved to a hidden
+// location within the versions folder where they continue to be repaired like
+// any other file until they are pruned according to the versioning policy.
+//
+// The versions of the file at 'path' are stored at '/var/versions/path/id'
+// where the id is the unix timestamp in nanoseconds at which the version was
+// created.
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/modules/renter/filesystem"
+)
+
+var (
+	// ErrUnknownVersion is returned if a file version doesn't exist.
+	ErrUnknownVersion = errors.New("unknown file version")
+
+	// errVersionsFolder is returned when trying to enable versioning within
+	// the versions folder.
+	errVersionsFolder = errors.New("versioning can't be enabled within the versions folder")
+)
+
+// isVersionPath returns whether the uplopath is within the versions folder.
+func isVersionPath(uploPath modules.UploPath) bool {
+	return uploPath.Equals(modules.VersionsFolder) || strings.HasPrefix(uploPath.String(), modules.VersionsFolder.String()+"/")
+}
+
+// versionsDir returns the uplopath of the directory containing the versions
+// of the file at the given uplopath. The versions of all files are stored
+// within the versions folder.
+func versionsDir(uploPath modules.UploPath) (modules.UploPath, error) {
+	if uploPath.IsRoot() {
+		return modules.VersionsFolder, nil
+	}
+	return modules.VersionsFolder.Join(uploPath.String())
+}
+
+// versionedPath returns the uplopath of the file whose versions are stored in
+// the given directory.
+func versionedPath(versionsDir modules.UploPath) (modules.UploPath, error) {
+	return versionsDir.Rebase(modules.VersionsFolder, modules.RootUploPath())
+}
+
+// SetDirVersioning sets the versioning policy of a uplodir.
+func (r *Renter) SetDirVersioning(uploPath modules.UploPath, policy modules.VersioningPolicy) (err error) {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	if policy.Enabled && isVersionPath(uploPath) {
+		return errVersionsFolder
+	}
+	dir, err := r.staticFileSystem.Openuplodir(uploPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to open uplodir")
+	}
+	defer func() {
+		err = errors.Compose(err, dir.Close())
+	}()
+	return dir.UpdateVersioning(policy)
+}
+
+// FileVersions returns the previous versions of a file, newest first.
+func (r *Renter) FileVersions(uploPath modules.UploPath) ([]modules.FileVersion, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
+	return r.managedFileVersions(uploPath)
+}
+
+// PruneFileVersions removes the versions of the file or of all the files
+// within the directory at the given path which exceed the provided limits. If
+// no limits are provided, the versioning policies of the uplodirs apply.
+func (r *Renter) PruneFileVersions(uploPath modules.UploPath, maxVersions uint64, maxAge time.Duration) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	return r.managedPruneVersions(uploPath, maxVersions, maxAge)
+}
+
+// RestoreFileVersion restores a previous version of a file. If the file
+// exists, it is replaced and kept as a version.
+func (r *Renter) RestoreFileVersion(uploPath modules.UploPath, id string) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	vDir, err := versionsDir(uploPath)
+	if err != nil {
+		return err
+	}
+	versionPath, err := vDir.Join(id)
+	if err != nil {
+		return errors.AddContext(err, "invalid version id")
+	}
+	exists, err := r.staticFileSystem.FileExists(versionPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to check for version")
+	}
+	if !exists {
+		return ErrUnknownVersion
+	}
+
+	// Keep the current file as a version, even if versioning is disabled, to
+	// avoid losing data by restoring a version.
+	exists, err = r.staticFileSystem.FileExists(uploPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to check for file")
+	}
+	if exists {
+		err = r.managedVersionFile(uploPath)
+		if err != nil {
+			return errors.AddContext(err, "unable to keep the current file as a version")
+		}
+	}
+
+	// Restore the version.
+	err = r.staticFileSystem.RenameFile(versionPath, uploPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to restore version")
+	}
+	r.managedBubbleParentDirs(uploPath, versionPath)
+
+	// Apply the versioning policy now that the number of versions might have
+	// changed.
+	policy, err := r.managedVersioningPolicy(uploPath)
+	if err != nil {
+		return err
+	}
+	return r.managedPruneFileVersions(uploPath, policy)
+}
+
+// managedFileVersions returns the previous versions of a file, newest first.
+func (r *Renter) managedFileVersions(uploPath modules.UploPath) ([]modules.FileVersion, error) {
+	vDir, err := versionsDir(uploPath)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := r.staticFileSystem.DirExists(vDir)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to check for versions")
+	}
+	if !exists {
+		return nil, nil
+	}
+	var mu sync.Mutex
+	var versions []modules.FileVersion
+	flf := func(fi modules.FileInfo) {
+		// Skip files which aren't versions.
+		nanos, err := strconv.ParseInt(fi.UploPath.Name(), 10, 64)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		versions = append(versions, modules.FileVersion{
+			CreateTime: time.Unix(0, nanos),
+			Filesize:   fi.Filesize,
+			ID:         fi.UploPath.Name(),
+			UploPath:   fi.UploPath,
+		})
+		mu.Unlock()
+	}
+	err = r.staticFileSystem.CachedList(vDir, false, flf, func(modules.DirectoryInfo) {})
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to list versions")
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].CreateTime.After(versions[j].CreateTime)
+	})
+	return versions, nil
+}
+
+// managedPruneVersions removes the versions of the file or of all the files
+// within the directory at the given path which exceed the provided limits. If
+// no limits are provided, the versioning policies of the uplodirs apply.
+func (r *Renter) managedPruneVersions(uploPath modules.UploPath, maxVersions uint64, maxAge time.Duration) error {
+	vDir, err := versionsDir(uploPath)
+	if err != nil {
+		return err
+	}
+	exists, err := r.staticFileSystem.DirExists(vDir)
+	if err != nil {
+		return errors.AddContext(err, "unable to check for versions")
+	}
+	if !exists {
+		return nil
+	}
+
+	// Group the versions by the files they belong to.
+	var mu sync.Mutex
+	files := make(map[modules.UploPath]struct{})
+	flf := func(fi modules.FileInfo) {
+		dir, err := fi.UploPath.Dir()
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		files[dir] = struct{}{}
+		mu.Unlock()
+	}
+	err = r.staticFileSystem.CachedList(vDir, true, flf, func(modules.DirectoryInfo) {})
+	if err != nil {
+		return errors.AddContext(err, "unable to list versions")
+	}
+
+	// Prune the versions of every file.
+	for dir := range files {
+		path, err := versionedPath(dir)
+		if err != nil {
+			return err
+		}
+		policy := modules.VersioningPolicy{
+			Enabled:     true,
+			MaxAge:      maxAge,
+			MaxVersions: maxVersions,
+		}
+		if maxVersions == 0 && maxAge == 0 {
+			policy, err = r.managedVersioningPolicy(path)
+			if err != nil {
+				return err
+			}
+		}
+		err = r.managedPruneFileVersions(path, policy)
+		if err != nil {
+			return errors.AddContext(err, "unable to prune versions of "+path.String())
+		}
+	}
+	return nil
+}
+
+// managedPruneFileVersions removes the versions of a file which exceed the
+// limits of the versioning policy. Versions are only pruned while versioning
+// is enabled.
+func (r *Renter) managedPruneFileVersions(uploPath modules.UploPath, policy modules.VersioningPolicy) error {
+	if !policy.Enabled {
+		return nil
+	}
+	versions, err := r.managedFileVersions(uploPath)
+	if err != nil {
+		return err
+	}
+	for i, v := range versions {
+		expired := policy.MaxAge > 0 && time.Since(v.CreateTime) > policy.MaxAge
+		excess := policy.MaxVersions > 0 && uint64(i) >= policy.MaxVersions
+		if !expired && !excess {
+			continue
+		}
+		err = r.managedDeleteFile(v.UploPath)
+		if err != nil {
+			return errors.AddContext(err, "unable to delete version "+v.ID)
+		}
+	}
+	return nil
+}
+
+// managedVersioningPolicy returns the versioning policy which applies to the
+// file at the given uplopath. That is the policy of the closest uplodir which
+// has versioning enabled.
+func (r *Renter) managedVersioningPolicy(uploPath modules.UploPath) (modules.VersioningPolicy, error) {
+	if isVersionPath(uploPath) {
+		return modules.VersioningPolicy{}, nil
+	}
+	dir, err := uploPath.Dir()
+	if err != nil {
+		return modules.VersioningPolicy{}, err
+	}
+	for {
+		di, err := r.staticFileSystem.DirInfo(dir)
+		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+			return modules.VersioningPolicy{}, errors.AddContext(err, "unable to get uplodir info")
+		}
+		if err == nil && di.Versioning.Enabled {
+			return di.Versioning, nil
+		}
+		if dir.IsRoot() {
+			return modules.VersioningPolicy{}, nil
+		}
+		dir, err = dir.Dir()
+		if err != nil {
+			return modules.VersioningPolicy{}, err
+		}
+	}
+}
+
+// managedVersionFile moves the file at the given uplopath to its versions.
+func (r *Renter) managedVersionFile(uploPath modules.UploPath) error {
+	vDir, err := versionsDir(uploPath)
+	if err != nil {
+		return err
+	}
+	versionPath, err := vDir.Join(strconv.FormatInt(time.Now().UnixNano(), 10))
+	if err != nil {
+		return err
+	}
+	err = r.staticFileSystem.RenameFile(uploPath, versionPath)
+	if err != nil {
+		return err
+	}
+	r.managedBubbleParentDirs(uploPath, versionPath)
+	return nil
+}
+
+// managedVersionDirFiles moves all the files within the directory at the
+// given uplopath to their versions if versioning applies to them.
+func (r *Renter) managedVersionDirFiles(uploPath modules.UploPath) error {
+	if isVersionPath(uploPath) {
+		return nil
+	}
+	var mu sync.Mutex
+	var files []modules.UploPath
+	flf := func(fi modules.FileInfo) {
+		mu.Lock()
+		files = append(files, fi.UploPath)
+		mu.Unlock()
+	}
+	err := r.staticFileSystem.CachedList(uploPath, true, flf, func(modules.DirectoryInfo) {})
+	if err != nil {
+		return errors.AddContext(err, "unable to list files")
+	}
+	for _, file := range files {
+		_, err := r.managedTryVersionFile(file)
+		if err != nil {
+			return errors.AddContext(err, "unable to version "+file.String())
+		}
+	}
+	return nil
+}
+
+// managedTryVersionFile moves the file at the given uplopath to its versions if
+// versioning applies to it and prunes its versions afterwards. The returned
+// bool indicates whether the file was versioned.
+func (r *Renter) managedTryVersionFile(uploPath modules.UploPath) (bool, error) {
+	policy, err := r.managedVersioningPolicy(uploPath)
+	if err != nil {
+		return false, err
+	}
+	if !policy.Enabled {
+		return false, nil
+	}
+	err = r.managedVersionFile(uploPath)
+	if err != nil {
+		return false, err
+	}
+	return true, r.managedPruneFileVersions(uploPath, policy)
+}
+
+// threadedPruneFileVersions periodically prunes the versions of all files
+// according to the versioning policies of their uplodirs. Without it, the
+// versions of files which are never deleted or overwritten again would never
+// expire.
+func (r *Renter) threadedPruneFileVersions() {
+	err := r.tg.Add()
+	if err != nil {
+		return
+	}
+	defer r.tg.Done()
+	for {
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-time.After(versionPruneInterval):
+		}
+		err = r.managedPruneVersions(modules.RootUploPath(), 0, 0)
+		if err != nil {
+			r.log.Println("Unable to prune file versions:", err)
+		}
+	}
+}
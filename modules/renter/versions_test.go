@@ -0,0 +1,201 @@
+package renter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+)
+
+// TestFileVersions probes keeping, listing, restoring and pruning file
+// versions.
+func TestFileVersions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// createFile creates a file at the given path.
+	createFile := func(uploPath modules.UploPath) {
+		entry, err := r.createRenterTestFile(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := entry.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// numVersions returns the number of versions of a file.
+	numVersions := func(uploPath modules.UploPath) int {
+		versions, err := r.FileVersions(uploPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(versions)
+	}
+
+	// Enable versioning for a directory keeping up to 2 versions per file.
+	dir := newUploPath("versioned")
+	if err := r.CreateDir(dir, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+	policy := modules.VersioningPolicy{Enabled: true, MaxVersions: 2}
+	if err := r.SetDirVersioning(dir, policy); err != nil {
+		t.Fatal(err)
+	}
+	di, err := r.staticFileSystem.DirInfo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.Versioning != policy {
+		t.Fatal("policy wasn't set", di.Versioning)
+	}
+	// Versioning can't be enabled within the versions folder.
+	if err := r.SetDirVersioning(modules.VersionsFolder, policy); !errors.Contains(err, errVersionsFolder) {
+		t.Fatal("expected errVersionsFolder", err)
+	}
+
+	// Delete a file within a subdirectory of the versioned dir 3 times. Only
+	// the 2 newest versions should be kept.
+	file := newUploPath("versioned/sub/file")
+	for i := 0; i < 3; i++ {
+		createFile(file)
+		if err := r.DeleteFile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exists, _ := r.staticFileSystem.FileExists(file); exists {
+		t.Fatal("file should have been deleted")
+	}
+	versions, err := r.FileVersions(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatal("wrong number of versions", len(versions))
+	}
+	if !versions[0].CreateTime.After(versions[1].CreateTime) {
+		t.Fatal("versions should be sorted newest first")
+	}
+
+	// Restore the older version.
+	if err := r.RestoreFileVersion(file, versions[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := r.staticFileSystem.FileExists(file); !exists {
+		t.Fatal("file wasn't restored")
+	}
+	if n := numVersions(file); n != 1 {
+		t.Fatal("wrong number of versions", n)
+	}
+	// Restoring an unknown version should fail.
+	if err := r.RestoreFileVersion(file, versions[1].ID); !errors.Contains(err, ErrUnknownVersion) {
+		t.Fatal("expected ErrUnknownVersion", err)
+	}
+
+	// Deleting the directory should keep the file as a version.
+	if err := r.DeleteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if n := numVersions(file); n != 2 {
+		t.Fatal("wrong number of versions", n)
+	}
+
+	// Prune the versions with a custom limit.
+	if err := r.PruneFileVersions(dir, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := numVersions(file); n != 1 {
+		t.Fatal("wrong number of versions", n)
+	}
+
+	// Files outside of versioned directories are deleted permanently.
+	unversioned := newUploPath("unversioned/file")
+	createFile(unversioned)
+	if err := r.DeleteFile(unversioned); err != nil {
+		t.Fatal(err)
+	}
+	if n := numVersions(unversioned); n != 0 {
+		t.Fatal("unversioned file shouldn't have versions", n)
+	}
+}
+
+// TestFileVersionsPrunedByAge verifies that versions exceeding the max age of
+// the versioning policy are pruned in the background.
+func TestFileVersionsPrunedByAge(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Enable versioning with a max age for a directory.
+	dir := newUploPath("versioned")
+	if err := r.CreateDir(dir, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+	policy := modules.VersioningPolicy{Enabled: true, MaxAge: 2 * time.Second}
+	if err := r.SetDirVersioning(dir, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep a version of a file which is never updated again.
+	file := newUploPath("versioned/file")
+	entry, err := r.createRenterTestFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entry.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := r.FileVersions(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Fatal("wrong number of versions", len(versions))
+	}
+
+	// The version should be pruned once it expires.
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		versions, err := r.FileVersions(file)
+		if err != nil {
+			return err
+		}
+		if len(versions) != 0 {
+			return fmt.Errorf("expected no versions but got %v", len(versions))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
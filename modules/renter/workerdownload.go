@@ -151,7 +151,7 @@ func (w *worker) threadedPerformDownloadChunkJob(udc *unfinishedDownloadChunk) {
 	// a large overdrive. It shouldn't be a bottleneck though since bandwidth
 	// is usually a lot more scarce than CPU processing power.
 	pieceIndex := udc.staticChunkMap[w.staticHostPubKey.String()].index
-	key := udc.masterKey.Derive(udc.staticChunkIndex, pieceIndex)
+	key := udc.masterKey.Derive(udc.staticKeyIndex, pieceIndex)
 	decryptedPiece, err := key.DecryptBytesInPlace(pieceData, uint64(fetchOffset/crypto.SegmentSize))
 	if err != nil {
 		w.renter.log.Debugln("worker failed to decrypt piece:", err)
@@ -162,8 +162,15 @@ func (w *worker) staticNewStream() (uplomux.Stream, error) {
 		return nil, errors.New("InterruptNewStreamTimeout")
 	}
 
+	// The mux dials on its own, so it connects to a local relay which routes
+	// the connection through the proxy if one is set.
+	address, err := modules.GlobalDialer.RelayAddress(w.staticCache().staticHostMuxAddress)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a stream with a reasonable dial up timeout.
-	stream, err := w.renter.staticMux.NewStreamTimeout(modules.HostUploMuxSubscriberName, w.staticCache().staticHostMuxAddress, timeout, modules.UploPKToMuxPK(w.staticHostPubKey))
+	stream, err := w.renter.staticMux.NewStreamTimeout(modules.HostUploMuxSubscriberName, address, timeout, modules.UploPKToMuxPK(w.staticHostPubKey))
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +105,13 @@ type (
 		// content will be automatically served for the skyfile.
 		DisableDefaultPath bool
 
+		// HLSSegmentDuration indicates that the data supplied by the reader is
+		// an MPEG transport stream which should be split into HLS segments of
+		// roughly the given duration. The segments and the HLS playlist are
+		// uploaded as subfiles of the skyfile. If set to zero, the data is
+		// uploaded as is.
+		HLSSegmentDuration time.Duration
+
 		// Reader supplies the file data for the skyfile.
 		Reader io.Reader
 
@@ -177,6 +184,32 @@ type (
 		Public  bool       `json:"public"`  // indicates whether the portal can be accessed publicly or not
 
 	}
+
+	// SkykeyRotation is the state of a skykey rotation. It contains the
+	// skyfiles that were re-encrypted with the new skykey so far and the file
+	// the mapping from the old to the new skylinks is written to. Finished is
+	// set once all skyfiles were processed. Error is set if the rotation
+	// failed as a whole.
+	SkykeyRotation struct {
+		OldSkykeyID   string                  `json:"oldskykeyid"`
+		NewSkykeyID   string                  `json:"newskykeyid"`
+		NewSkykeyName string                  `json:"newskykeyname"`
+		Skyfiles      []SkykeyRotationSkyfile `json:"skyfiles"`
+		MappingFile   string                  `json:"mappingfile"`
+		Blocklist     bool                    `json:"blocklist"`
+		Finished      bool                    `json:"finished"`
+		Error         string                  `json:"error,omitempty"`
+	}
+
+	// SkykeyRotationSkyfile maps the skylink of a skyfile that was encrypted
+	// with a rotated skykey to the skylink of the re-encrypted skyfile. If the
+	// skyfile couldn't be re-encrypted, Error is set and NewSkylink is empty.
+	SkykeyRotationSkyfile struct {
+		UploPath   UploPath `json:"uplopath"`
+		OldSkylink string   `json:"oldskylink"`
+		NewSkylink string   `json:"newskylink"`
+		Error      string   `json:"error,omitempty"`
+	}
 )
 
 // ForPath returns a subset of the SkyfileMetadata that contains all of the
@@ -0,0 +1,515 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/uplo/build"
+)
+
+// skynethls.go contains a pure Go MPEG transport stream segmenter that is used
+// to turn a single video upload into an HLS skyfile. The resulting skyfile is
+// a directory that contains the fixed-duration segments as subfiles and a
+// playlist which lists the segments in playback order. MP4 uploads are remuxed
+// into a transport stream by skynethlsmp4.go before they are segmented.
+
+const (
+	// HLSPlaylistFilename is the name of the playlist subfile of a skyfile
+	// created by an HLS upload.
+	HLSPlaylistFilename = "index.m3u8"
+
+	// HLSPlaylistContentType is the content type of an HLS playlist.
+	HLSPlaylistContentType = "application/vnd.apple.mpegurl"
+
+	// HLSSegmentContentType is the content type of an HLS segment.
+	HLSSegmentContentType = "video/mp2t"
+
+	// HLSSegmentExtension is the file extension of an HLS segment.
+	HLSSegmentExtension = ".ts"
+
+	// tsPacketSize is the size of a single MPEG-TS packet.
+	tsPacketSize = 188
+
+	// tsSyncByte is the byte every MPEG-TS packet starts with.
+	tsSyncByte = 0x47
+
+	// tsPATPID is the packet identifier of the program association table.
+	tsPATPID = 0
+
+	// tsPTSMask masks a presentation timestamp to its 33 bits. PTS values are
+	// counted using a 90kHz clock and wrap around once they overflow.
+	tsPTSMask = 1<<33 - 1
+)
+
+var (
+	// DefaultHLSSegmentDuration is the target duration of a segment if none is
+	// specified.
+	DefaultHLSSegmentDuration = 6 * time.Second
+
+	// MaxHLSUploadSize is the maximum size of a video that can be uploaded
+	// for HLS. The segmented video is spooled to a temporary file before it
+	// is uploaded, so the size of an upload needs to be bounded.
+	MaxHLSUploadSize = build.Select(build.Var{
+		Dev:      uint64(64 << 20),  // 64 MiB
+		Standard: uint64(512 << 20), // 512 MiB
+		Testing:  uint64(1 << 20),   // 1 MiB
+	}).(uint64)
+
+	// ErrInvalidMPEGTS is returned when the data passed to the HLS segmenter
+	// is not a valid MPEG transport stream.
+	ErrInvalidMPEGTS = errors.New("data is not a valid MPEG transport stream")
+
+	// ErrHLSUnsupportedContainer is returned when the data passed to the HLS
+	// segmenter is in a container that the segmenter can't split.
+	ErrHLSUnsupportedContainer = errors.New("unsupported container, only MPEG transport streams and MP4 files with H.264 and AAC tracks can be segmented for HLS")
+
+	// ErrHLSUploadTooLarge is returned when a video uploaded for HLS exceeds
+	// MaxHLSUploadSize.
+	ErrHLSUploadTooLarge = fmt.Errorf("video exceeds the maximum HLS upload size of %v bytes", MaxHLSUploadSize)
+)
+
+type (
+	// HLSSegment describes a single segment of an HLS playlist.
+	HLSSegment struct {
+		Filename string
+		Duration time.Duration
+	}
+
+	// SkyfileHLSReader is the SkyfileUploadReader of an HLS upload. Its data
+	// is read from a temporary file, which is removed when the reader is
+	// closed.
+	SkyfileHLSReader struct {
+		SkyfileUploadReader
+		staticFile *os.File
+	}
+
+	// tsSegmenter splits an MPEG transport stream into segments. Segments are
+	// only cut at the start of a PES packet of the main elementary stream,
+	// preferably at random access points, and every segment starts with the
+	// most recent PAT and PMT so that it can be decoded on its own.
+	tsSegmenter struct {
+		targetDuration time.Duration
+
+		// The PIDs of the program map table and of the elementary stream the
+		// segment boundaries are aligned to.
+		pmtPID    uint16
+		hasPMTPID bool
+		esPID     uint16
+		hasESPID  bool
+
+		// The most recent PAT and PMT packets.
+		pat []byte
+		pmt []byte
+
+		// sawRandomAccess indicates whether the stream signals random access
+		// points. If it does, segments will only be cut at those points.
+		sawRandomAccess bool
+
+		// The current segment and its timestamps.
+		current     []byte
+		startPTS    uint64
+		endPTS      uint64
+		hasStartPTS bool
+
+		// frameDuration is the smallest difference between the timestamps of
+		// two consecutive PES packets of the elementary stream. It's the
+		// duration of the last frame of the stream.
+		frameDuration time.Duration
+		lastPTS       uint64
+		hasLastPTS    bool
+	}
+)
+
+// SegmentMPEGTS reads an MPEG transport stream from r and splits it into
+// segments of roughly the target duration. Every finished segment is passed
+// to fn in order, together with its duration.
+func SegmentMPEGTS(r io.Reader, targetDuration time.Duration, fn func(segment []byte, duration time.Duration) error) error {
+	if targetDuration <= 0 {
+		return errors.New("target duration of a segment has to be greater than zero")
+	}
+	s := &tsSegmenter{
+		targetDuration: targetDuration,
+	}
+	var numPackets uint64
+	for {
+		pkt := make([]byte, tsPacketSize)
+		_, err := io.ReadFull(r, pkt)
+		if errors.Contains(err, io.EOF) {
+			break
+		}
+		if errors.Contains(err, io.ErrUnexpectedEOF) {
+			return errors.AddContext(ErrInvalidMPEGTS, "stream ends with a partial packet")
+		}
+		if err != nil {
+			return errors.AddContext(err, "unable to read packet")
+		}
+		if numPackets == 0 && bytes.Equal(pkt[4:8], []byte("ftyp")) {
+			return ErrHLSUnsupportedContainer
+		}
+		numPackets++
+		err = s.processPacket(pkt, fn)
+		if err != nil {
+			return errors.AddContext(err, fmt.Sprintf("unable to process packet %v", numPackets))
+		}
+	}
+	if !s.hasStartPTS {
+		return errors.AddContext(ErrInvalidMPEGTS, "no timestamped elementary stream found")
+	}
+	// The last segment lasts until the end of its last frame.
+	return fn(s.current, ptsDuration(s.startPTS, s.endPTS)+s.frameDuration)
+}
+
+// HLSPlaylist returns an HLS media playlist for the given segments. The
+// segments are listed in the order in which they are passed in.
+func HLSPlaylist(segments []HLSSegment) []byte {
+	var targetDuration time.Duration
+	for _, segment := range segments {
+		if segment.Duration > targetDuration {
+			targetDuration = segment.Duration
+		}
+	}
+	// The target duration is an integer that must not be smaller than the
+	// duration of any segment.
+	targetSeconds := int64((targetDuration + time.Second - 1) / time.Second)
+
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:3\n")
+	b.WriteString(fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", targetSeconds))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	for _, segment := range segments {
+		b.WriteString(fmt.Sprintf("#EXTINF:%.6f,\n", segment.Duration.Seconds()))
+		b.WriteString(segment.Filename + "\n")
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.Bytes()
+}
+
+// NewSkyfileHLSReader segments the MPEG transport stream or MP4 file read from
+// reader and returns a SkyfileUploadReader for a skyfile that contains the
+// segments and an HLS playlist. The segments are written in playback order, so
+// the order of their offsets within the skyfile matches the order of the
+// playlist. Videos of up to MaxHLSUploadSize bytes are supported. The skyfile
+// is spooled to a temporary file, so only a single segment is held in memory.
+// The returned reader needs to be closed to remove the file.
+func NewSkyfileHLSReader(reader io.Reader, targetDuration time.Duration, sup SkyfileUploadParameters) (_ *SkyfileHLSReader, err error) {
+	// Limit the input to one byte more than the max size to be able to tell
+	// whether the upload exceeds it.
+	lr := &io.LimitedReader{R: reader, N: int64(MaxHLSUploadSize) + 1}
+	br := bufio.NewReader(lr)
+
+	// MP4 files are remuxed into a transport stream. They need to be spooled
+	// to disk first since their moov box might be at the end of the file.
+	var ts io.Reader = br
+	if header, _ := br.Peek(8); len(header) == 8 && header[0] != tsSyncByte && isMP4BoxType(string(header[4:8])) {
+		input, err := ioutil.TempFile("", "uplo-hls-input")
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to create temporary file")
+		}
+		defer func() {
+			err = errors.Compose(err, input.Close(), os.Remove(input.Name()))
+		}()
+		size, err := io.Copy(input, br)
+		if lr.N <= 0 {
+			return nil, ErrHLSUploadTooLarge
+		}
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to spool video")
+		}
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			_ = pw.CloseWithError(RemuxMP4(input, size, pw))
+		}()
+		ts = pr
+	}
+
+	// Write the skyfile to a temporary file.
+	body, err := ioutil.TempFile("", "uplo-hls")
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to create temporary file")
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Compose(err, body.Close(), os.Remove(body.Name()))
+		}
+	}()
+	bw := bufio.NewWriter(body)
+	writer := multipart.NewWriter(bw)
+
+	// Write the segments.
+	var segments []HLSSegment
+	err = SegmentMPEGTS(ts, targetDuration, func(data []byte, duration time.Duration) error {
+		filename := fmt.Sprintf("segment%05d%s", len(segments), HLSSegmentExtension)
+		err := writeMultipartPart(writer, data, filename, HLSSegmentContentType)
+		if err != nil {
+			return errors.AddContext(err, "unable to add segment")
+		}
+		segments = append(segments, HLSSegment{
+			Filename: filename,
+			Duration: duration,
+		})
+		return nil
+	})
+	if lr.N <= 0 {
+		return nil, ErrHLSUploadTooLarge
+	}
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to segment video")
+	}
+
+	// Write the playlist.
+	err = writeMultipartPart(writer, HLSPlaylist(segments), HLSPlaylistFilename, HLSPlaylistContentType)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to add playlist")
+	}
+	if err = writer.Close(); err != nil {
+		return nil, errors.AddContext(err, "unable to close writer")
+	}
+	if err = bw.Flush(); err != nil {
+		return nil, errors.AddContext(err, "unable to write skyfile")
+	}
+
+	size, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to get size of skyfile")
+	}
+	mpr := multipart.NewReader(io.NewSectionReader(body, 0, size), writer.Boundary())
+	mprFanout := multipart.NewReader(io.NewSectionReader(body, 0, size), writer.Boundary())
+	return &SkyfileHLSReader{
+		SkyfileUploadReader: NewSkyfileMultipartReader(mpr, mprFanout, sup),
+		staticFile:          body,
+	}, nil
+}
+
+// Close closes and removes the temporary file of the reader.
+func (r *SkyfileHLSReader) Close() error {
+	return errors.Compose(r.staticFile.Close(), os.Remove(r.staticFile.Name()))
+}
+
+// HLSSegments returns the metadata of the segments of a skyfile that was
+// created by an HLS upload, in playlist order. If the skyfile does not contain
+// an HLS playlist, nil is returned.
+func (sm SkyfileMetadata) HLSSegments() []SkyfileSubfileMetadata {
+	if _, exists := sm.Subfiles[HLSPlaylistFilename]; !exists {
+		return nil
+	}
+	var segments []SkyfileSubfileMetadata
+	for _, sf := range sm.Subfiles {
+		if filepath.Ext(sf.Filename) == HLSSegmentExtension {
+			segments = append(segments, sf)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Offset < segments[j].Offset
+	})
+	return segments
+}
+
+// processPacket adds a packet to the current segment. If the packet starts a
+// new segment, the current segment is passed to fn first.
+func (s *tsSegmenter) processPacket(pkt []byte, fn func([]byte, time.Duration) error) error {
+	if pkt[0] != tsSyncByte {
+		return errors.AddContext(ErrInvalidMPEGTS, "packet is missing the sync byte")
+	}
+	pusi := pkt[1]&0x40 != 0
+	pid := uint16(pkt[1]&0x1f)<<8 | uint16(pkt[2])
+	adaptationFieldControl := pkt[3] >> 4 & 0x03
+
+	// Parse the adaptation field to find the start of the payload and whether
+	// the packet is a random access point.
+	payloadStart := 4
+	var randomAccess bool
+	if adaptationFieldControl&0x02 != 0 {
+		adaptationFieldLen := int(pkt[4])
+		if adaptationFieldLen > 0 {
+			randomAccess = pkt[5]&0x40 != 0
+		}
+		payloadStart = 5 + adaptationFieldLen
+		if payloadStart > tsPacketSize {
+			return errors.AddContext(ErrInvalidMPEGTS, "adaptation field exceeds packet")
+		}
+	}
+	var payload []byte
+	if adaptationFieldControl&0x01 != 0 {
+		payload = pkt[payloadStart:]
+	}
+
+	switch {
+	case pid == tsPATPID:
+		s.pat = pkt
+		if pusi {
+			s.pmtPID, s.hasPMTPID = parsePAT(payload)
+		}
+	case s.hasPMTPID && pid == s.pmtPID:
+		s.pmt = pkt
+		if pusi {
+			s.esPID, s.hasESPID = parsePMT(payload)
+		}
+	case s.hasESPID && pid == s.esPID && pusi:
+		pts, ok := parsePESTimestamp(payload)
+		if !ok {
+			break
+		}
+		if randomAccess {
+			s.sawRandomAccess = true
+		}
+		if s.hasLastPTS && pts != s.lastPTS && ptsAfter(s.lastPTS, pts) {
+			if d := ptsDuration(s.lastPTS, pts); s.frameDuration == 0 || d < s.frameDuration {
+				s.frameDuration = d
+			}
+		}
+		s.lastPTS, s.hasLastPTS = pts, true
+		// Cut a new segment if the current one has reached the target
+		// duration.
+		canCut := randomAccess || !s.sawRandomAccess
+		if s.hasStartPTS && canCut && ptsAfter(s.startPTS, pts) && ptsDuration(s.startPTS, pts) >= s.targetDuration {
+			err := fn(s.current, ptsDuration(s.startPTS, pts))
+			if err != nil {
+				return err
+			}
+			s.current = nil
+			s.current = append(s.current, s.pat...)
+			s.current = append(s.current, s.pmt...)
+			s.startPTS = pts
+			s.endPTS = pts
+		}
+		if !s.hasStartPTS {
+			s.startPTS = pts
+			s.endPTS = pts
+			s.hasStartPTS = true
+		}
+		if ptsAfter(s.endPTS, pts) {
+			s.endPTS = pts
+		}
+	}
+	s.current = append(s.current, pkt...)
+	return nil
+}
+
+// parsePAT returns the PID of the first program map table listed in the given
+// PAT payload.
+func parsePAT(payload []byte) (uint16, bool) {
+	section, ok := psiSection(payload, 0x00)
+	if !ok {
+		return 0, false
+	}
+	// The program loop starts after the 8 byte header.
+	for i := 8; i+4 <= len(section); i += 4 {
+		programNumber := uint16(section[i])<<8 | uint16(section[i+1])
+		if programNumber == 0 {
+			// Program 0 points to the network information table.
+			continue
+		}
+		return uint16(section[i+2]&0x1f)<<8 | uint16(section[i+3]), true
+	}
+	return 0, false
+}
+
+// parsePMT returns the PID of the elementary stream segment boundaries should
+// be aligned to. That's the first video stream of the program or the first
+// stream if the program doesn't contain video.
+func parsePMT(payload []byte) (uint16, bool) {
+	section, ok := psiSection(payload, 0x02)
+	if !ok || len(section) < 12 {
+		return 0, false
+	}
+	programInfoLen := int(section[10]&0x0f)<<8 | int(section[11])
+	var firstPID uint16
+	var found bool
+	for i := 12 + programInfoLen; i+5 <= len(section); {
+		streamType := section[i]
+		pid := uint16(section[i+1]&0x1f)<<8 | uint16(section[i+2])
+		esInfoLen := int(section[i+3]&0x0f)<<8 | int(section[i+4])
+		switch streamType {
+		case 0x01, 0x02, 0x10, 0x1b, 0x24:
+			// MPEG-1, MPEG-2, MPEG-4 Part 2, H.264 and H.265 video.
+			return pid, true
+		}
+		if !found {
+			firstPID, found = pid, true
+		}
+		i += 5 + esInfoLen
+	}
+	return firstPID, found
+}
+
+// psiSection returns the section of a PSI table with the given table id from
+// a payload that starts with a pointer field. The returned section excludes
+// the trailing CRC.
+func psiSection(payload []byte, tableID byte) ([]byte, bool) {
+	if len(payload) < 1 {
+		return nil, false
+	}
+	start := 1 + int(payload[0])
+	if start+3 > len(payload) || payload[start] != tableID {
+		return nil, false
+	}
+	sectionLen := int(payload[start+1]&0x0f)<<8 | int(payload[start+2])
+	end := start + 3 + sectionLen - 4
+	if sectionLen < 4 || end > len(payload) {
+		return nil, false
+	}
+	return payload[start:end], true
+}
+
+// parsePESTimestamp returns the presentation timestamp of the PES packet that
+// starts at the beginning of the payload.
+func parsePESTimestamp(payload []byte) (uint64, bool) {
+	if len(payload) < 14 || payload[0] != 0x00 || payload[1] != 0x00 || payload[2] != 0x01 {
+		return 0, false
+	}
+	if payload[7]&0x80 == 0 {
+		// No PTS present.
+		return 0, false
+	}
+	p := payload[9:14]
+	pts := uint64(p[0]>>1&0x07)<<30 | uint64(p[1])<<22 | uint64(p[2]>>1)<<15 | uint64(p[3])<<7 | uint64(p[4]>>1)
+	return pts, true
+}
+
+// ptsAfter returns true if b is not before a, taking into account that
+// timestamps wrap around.
+func ptsAfter(a, b uint64) bool {
+	return (b-a)&tsPTSMask < 1<<32
+}
+
+// ptsDuration returns the duration between two timestamps, taking into
+// account that timestamps wrap around.
+func ptsDuration(a, b uint64) time.Duration {
+	ticks := (b - a) & tsPTSMask
+	// The PTS clock runs at 90kHz.
+	return time.Duration(ticks * 1e6 / 90)
+}
+
+// isMP4BoxType returns whether the type is the type of a box that an MP4 file
+// can start with.
+func isMP4BoxType(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "free", "skip", "wide":
+		return true
+	}
+	return false
+}
+
+// writeMultipartPart writes a file with the given content type as a part to
+// the multipart writer.
+func writeMultipartPart(w *multipart.Writer, data []byte, filename, contentType string) error {
+	partHeader := createFormFileHeaders("files[]", filename, fmt.Sprintf("%o", DefaultFilePerm), contentType)
+	part, err := w.CreatePart(partHeader)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
@@ -0,0 +1,484 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+)
+
+const (
+	// testTSPMTPID and testTSVideoPID are the PIDs used by the test stream.
+	testTSPMTPID   = 0x1000
+	testTSVideoPID = 0x0100
+)
+
+// TestSkynetHLS is a convenience function that wraps all of the HLS tests.
+func TestSkynetHLS(t *testing.T) {
+	t.Run("SegmentMPEGTS", testSegmentMPEGTS)
+	t.Run("SegmentMPEGTSInvalid", testSegmentMPEGTSInvalid)
+	t.Run("HLSPlaylist", testHLSPlaylist)
+	t.Run("SkyfileHLSReader", testSkyfileHLSReader)
+	t.Run("SkyfileHLSReaderMP4", testSkyfileHLSReaderMP4)
+}
+
+// testSegmentMPEGTS verifies that a transport stream is split at random access
+// points once the target duration is reached.
+func testSegmentMPEGTS(t *testing.T) {
+	t.Parallel()
+
+	// 20 seconds of video at 25 fps with a keyframe every 2 seconds.
+	stream := testMPEGTS(500, 25, 50)
+
+	var segments [][]byte
+	var durations []time.Duration
+	err := SegmentMPEGTS(bytes.NewReader(stream), 6*time.Second, func(segment []byte, duration time.Duration) error {
+		segments = append(segments, segment)
+		durations = append(durations, duration)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keyframes are 2 seconds apart so we expect 3 segments of 6 seconds and
+	// a final segment of 2 seconds, which includes the duration of its last
+	// frame.
+	expected := []time.Duration{6 * time.Second, 6 * time.Second, 6 * time.Second, 2 * time.Second}
+	if len(durations) != len(expected) {
+		t.Fatalf("expected %v segments but got %v", len(expected), len(durations))
+	}
+	for i := range expected {
+		if durations[i] != expected[i] {
+			t.Fatalf("segment %v: expected duration %v but got %v", i, expected[i], durations[i])
+		}
+	}
+
+	// Every segment should consist of whole packets and start with the PAT
+	// followed by the PMT.
+	var total int
+	for i, segment := range segments {
+		if len(segment)%tsPacketSize != 0 {
+			t.Fatalf("segment %v contains a partial packet", i)
+		}
+		if pid := uint16(segment[1]&0x1f)<<8 | uint16(segment[2]); pid != tsPATPID {
+			t.Fatalf("segment %v doesn't start with the PAT", i)
+		}
+		if pid := uint16(segment[tsPacketSize+1]&0x1f)<<8 | uint16(segment[tsPacketSize+2]); pid != testTSPMTPID {
+			t.Fatalf("segment %v doesn't start with the PMT", i)
+		}
+		total += len(segment)
+	}
+	// The PAT and PMT are duplicated for every segment but the first.
+	if total != len(stream)+(len(segments)-1)*2*tsPacketSize {
+		t.Fatal("segments don't add up to the stream", total, len(stream))
+	}
+}
+
+// testSegmentMPEGTSInvalid verifies that invalid input is rejected.
+func testSegmentMPEGTSInvalid(t *testing.T) {
+	t.Parallel()
+
+	noop := func([]byte, time.Duration) error { return nil }
+	stream := testMPEGTS(50, 25, 25)
+
+	// Partial packet.
+	err := SegmentMPEGTS(bytes.NewReader(stream[:len(stream)-1]), time.Second, noop)
+	if !errors.Contains(err, ErrInvalidMPEGTS) {
+		t.Fatal("expected ErrInvalidMPEGTS", err)
+	}
+	// Missing sync byte.
+	corrupt := append([]byte{}, stream...)
+	corrupt[tsPacketSize] = 0
+	err = SegmentMPEGTS(bytes.NewReader(corrupt), time.Second, noop)
+	if !errors.Contains(err, ErrInvalidMPEGTS) {
+		t.Fatal("expected ErrInvalidMPEGTS", err)
+	}
+	// No elementary stream.
+	err = SegmentMPEGTS(bytes.NewReader(stream[:2*tsPacketSize]), time.Second, noop)
+	if !errors.Contains(err, ErrInvalidMPEGTS) {
+		t.Fatal("expected ErrInvalidMPEGTS", err)
+	}
+	// MP4.
+	mp4 := make([]byte, tsPacketSize)
+	copy(mp4[4:], "ftypisom")
+	err = SegmentMPEGTS(bytes.NewReader(mp4), time.Second, noop)
+	if !errors.Contains(err, ErrHLSUnsupportedContainer) {
+		t.Fatal("expected ErrHLSUnsupportedContainer", err)
+	}
+	// Invalid duration.
+	err = SegmentMPEGTS(bytes.NewReader(stream), 0, noop)
+	if err == nil {
+		t.Fatal("expected error for zero target duration")
+	}
+}
+
+// testHLSPlaylist verifies the playlist created by HLSPlaylist.
+func testHLSPlaylist(t *testing.T) {
+	t.Parallel()
+
+	playlist := HLSPlaylist([]HLSSegment{
+		{Filename: "segment00000.ts", Duration: 6 * time.Second},
+		{Filename: "segment00001.ts", Duration: 6400 * time.Millisecond},
+	})
+	expected := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:7
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXTINF:6.000000,
+segment00000.ts
+#EXTINF:6.400000,
+segment00001.ts
+#EXT-X-ENDLIST
+`
+	if string(playlist) != expected {
+		t.Fatalf("unexpected playlist\n%v", string(playlist))
+	}
+}
+
+// testSkyfileHLSReader verifies that the HLS reader produces a skyfile with
+// the segments and the playlist as subfiles.
+func testSkyfileHLSReader(t *testing.T) {
+	t.Parallel()
+
+	stream := testMPEGTS(500, 25, 50)
+	sup := SkyfileUploadParameters{Filename: "video"}
+	reader, err := NewSkyfileHLSReader(bytes.NewReader(stream), 6*time.Second, sup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := reader.SkyfileMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md.Subfiles) != 5 {
+		t.Fatalf("expected 5 subfiles but got %v", len(md.Subfiles))
+	}
+	playlist, exists := md.Subfiles[HLSPlaylistFilename]
+	if !exists {
+		t.Fatal("playlist missing")
+	}
+	if playlist.ContentType != HLSPlaylistContentType {
+		t.Fatal("wrong playlist content type", playlist.ContentType)
+	}
+	if !bytes.HasPrefix(data[playlist.Offset:playlist.Offset+playlist.Len], []byte("#EXTM3U")) {
+		t.Fatal("playlist has wrong content")
+	}
+
+	// The segments should be returned in playlist order.
+	segments := md.HLSSegments()
+	if len(segments) != 4 {
+		t.Fatalf("expected 4 segments but got %v", len(segments))
+	}
+	for i, segment := range segments {
+		if segment.ContentType != HLSSegmentContentType {
+			t.Fatal("wrong segment content type", segment.ContentType)
+		}
+		if !strings.Contains(string(data[playlist.Offset:]), segment.Filename) {
+			t.Fatal("segment not in playlist", segment.Filename)
+		}
+		if i > 0 && segments[i-1].Offset+segments[i-1].Len != segment.Offset {
+			t.Fatal("segments are not contiguous")
+		}
+	}
+
+	// A skyfile without a playlist has no segments.
+	delete(md.Subfiles, HLSPlaylistFilename)
+	if md.HLSSegments() != nil {
+		t.Fatal("expected no segments without playlist")
+	}
+
+	// A video that exceeds the max upload size is rejected.
+	stream = testMPEGTS(int(MaxHLSUploadSize/tsPacketSize), 25, 50)
+	_, err = NewSkyfileHLSReader(bytes.NewReader(stream), 6*time.Second, sup)
+	if !errors.Contains(err, ErrHLSUploadTooLarge) {
+		t.Fatal("expected ErrHLSUploadTooLarge", err)
+	}
+
+	// MP4 input without supported tracks is rejected.
+	mp4 := append(testMP4Box("ftyp", []byte("isom")), testMP4Box("moov")...)
+	_, err = NewSkyfileHLSReader(bytes.NewReader(mp4), 6*time.Second, sup)
+	if !errors.Contains(err, ErrHLSUnsupportedContainer) {
+		t.Fatal("expected ErrHLSUnsupportedContainer", err)
+	}
+
+	// An MP4 file that exceeds the max upload size is rejected.
+	mp4 = append(testMP4Box("ftyp", []byte("isom")), testMP4Box("mdat", make([]byte, MaxHLSUploadSize))...)
+	_, err = NewSkyfileHLSReader(bytes.NewReader(mp4), 6*time.Second, sup)
+	if !errors.Contains(err, ErrHLSUploadTooLarge) {
+		t.Fatal("expected ErrHLSUploadTooLarge", err)
+	}
+}
+
+// testSkyfileHLSReaderMP4 verifies that MP4 input is remuxed into transport
+// stream segments.
+func testSkyfileHLSReaderMP4(t *testing.T) {
+	t.Parallel()
+
+	// 20 seconds of video at 25 fps with a keyframe every 2 seconds.
+	mp4 := testMP4(500, 25, 50)
+	var segments [][]byte
+	var durations []time.Duration
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(RemuxMP4(bytes.NewReader(mp4), int64(len(mp4)), pw))
+	}()
+	err := SegmentMPEGTS(pr, 6*time.Second, func(segment []byte, duration time.Duration) error {
+		segments = append(segments, segment)
+		durations = append(durations, duration)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Duration{6 * time.Second, 6 * time.Second, 6 * time.Second, 2 * time.Second}
+	if len(durations) != len(expected) {
+		t.Fatalf("expected %v segments but got %v", len(expected), len(durations))
+	}
+	for i := range expected {
+		if durations[i] != expected[i] {
+			t.Fatalf("segment %v: expected duration %v but got %v", i, expected[i], durations[i])
+		}
+	}
+
+	// Every segment should start with the tables, contain audio and start
+	// its video with the parameter sets of the keyframe.
+	for i, segment := range segments {
+		if len(segment)%tsPacketSize != 0 {
+			t.Fatalf("segment %v contains a partial packet", i)
+		}
+		if pid := uint16(segment[1]&0x1f)<<8 | uint16(segment[2]); pid != tsPATPID {
+			t.Fatalf("segment %v doesn't start with the PAT", i)
+		}
+		var audio bool
+		for off := 0; off < len(segment); off += tsPacketSize {
+			pkt := segment[off : off+tsPacketSize]
+			if pkt[0] != tsSyncByte {
+				t.Fatalf("segment %v: packet without sync byte", i)
+			}
+			if pid := uint16(pkt[1]&0x1f)<<8 | uint16(pkt[2]); pid == mp4TSAudioPID {
+				audio = true
+			}
+		}
+		if !audio {
+			t.Fatalf("segment %v contains no audio", i)
+		}
+		if !bytes.Contains(segment, []byte{0x00, 0x00, 0x00, 0x01, 0x67}) {
+			t.Fatalf("segment %v contains no SPS", i)
+		}
+	}
+
+	// The HLS reader accepts the MP4 file.
+	reader, err := NewSkyfileHLSReader(bytes.NewReader(mp4), 6*time.Second, SkyfileUploadParameters{Filename: "video"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if _, err := ioutil.ReadAll(reader); err != nil {
+		t.Fatal(err)
+	}
+	md, err := reader.SkyfileMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md.HLSSegments()) != len(expected) {
+		t.Fatalf("expected %v segments but got %v", len(expected), len(md.HLSSegments()))
+	}
+
+	// Corrupt sample tables are rejected.
+	corrupt := append([]byte{}, mp4...)
+	stco := bytes.LastIndex(corrupt, []byte("stco"))
+	binary.BigEndian.PutUint32(corrupt[stco+12:], uint32(len(corrupt)))
+	err = RemuxMP4(bytes.NewReader(corrupt), int64(len(corrupt)), ioutil.Discard)
+	if !errors.Contains(err, ErrInvalidMP4) {
+		t.Fatal("expected ErrInvalidMP4", err)
+	}
+}
+
+// testMP4Box creates an MP4 box with the given payload.
+func testMP4Box(typ string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	box := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(box, uint32(8+len(data)))
+	copy(box[4:], typ)
+	return append(box, data...)
+}
+
+// testMP4Table creates the payload of a sample table box with the given
+// entries.
+func testMP4Table(entries ...uint32) []byte {
+	data := make([]byte, 4, 4+4*len(entries))
+	for _, e := range entries {
+		data = append(data, byte(e>>24), byte(e>>16), byte(e>>8), byte(e))
+	}
+	return data
+}
+
+// testMP4Track creates a trak box with the given sample description. All
+// samples are stored in a single chunk.
+func testMP4Track(timescale uint32, sampleEntry []byte, sizes []uint32, delta uint32, syncSamples []uint32, chunkOffset uint32) []byte {
+	mdhd := testMP4Table(0, 0, timescale, uint32(len(sizes))*delta, 0)
+	stbl := [][]byte{
+		testMP4Box("stsd", testMP4Table(1), sampleEntry),
+		testMP4Box("stts", testMP4Table(1, uint32(len(sizes)), delta)),
+		testMP4Box("stsz", testMP4Table(append([]uint32{0, uint32(len(sizes))}, sizes...)...)),
+		testMP4Box("stsc", testMP4Table(1, 1, uint32(len(sizes)), 1)),
+		testMP4Box("stco", testMP4Table(1, chunkOffset)),
+	}
+	if syncSamples != nil {
+		stbl = append(stbl, testMP4Box("stss", testMP4Table(append([]uint32{uint32(len(syncSamples))}, syncSamples...)...)))
+	}
+	return testMP4Box("trak", testMP4Box("mdia", testMP4Box("mdhd", mdhd), testMP4Box("minf", testMP4Box("stbl", stbl...))))
+}
+
+// testMP4 creates an MP4 file with an H.264 track containing the given number
+// of frames and an AAC track of the same duration. Every keyframeInterval
+// frames is a sync sample. The moov box follows the mdat box.
+func testMP4(frames, fps, keyframeInterval int) []byte {
+	ftyp := testMP4Box("ftyp", []byte("isom"), make([]byte, 4))
+
+	// Create the samples. Every video sample is a single NAL unit with a 4
+	// byte length prefix.
+	var mdat []byte
+	var videoSizes, audioSizes, syncSamples []uint32
+	for i := 0; i < frames; i++ {
+		nal := []byte{0x41, byte(i)}
+		if i%keyframeInterval == 0 {
+			nal[0] = 0x65
+			syncSamples = append(syncSamples, uint32(i+1))
+		}
+		mdat = append(mdat, 0x00, 0x00, 0x00, byte(len(nal)))
+		mdat = append(mdat, nal...)
+		videoSizes = append(videoSizes, uint32(4+len(nal)))
+	}
+	audioOffset := len(mdat)
+	for i := 0; i < frames*44100/fps/1024; i++ {
+		mdat = append(mdat, bytes.Repeat([]byte{byte(i)}, 20)...)
+		audioSizes = append(audioSizes, 20)
+	}
+	mdatOffset := uint32(len(ftyp) + 8)
+
+	// Create the sample descriptions.
+	avcC := []byte{0x01, 0x64, 0x00, 0x1f, 0xff, 0xe1, 0x00, 0x02, 0x67, 0x64, 0x01, 0x00, 0x02, 0x68, 0xee}
+	avc1 := testMP4Box("avc1", make([]byte, 78), testMP4Box("avcC", avcC))
+	esds := []byte{
+		0x00, 0x00, 0x00, 0x00, // version and flags
+		0x03, 0x16, 0x00, 0x01, 0x00, // ES descriptor
+		0x04, 0x11, 0x40, 0x15, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // decoder config
+		0x05, 0x02, 0x12, 0x10, // AAC LC, 44.1kHz, stereo
+	}
+	mp4a := testMP4Box("mp4a", make([]byte, 28), testMP4Box("esds", esds))
+
+	moov := testMP4Box("moov",
+		testMP4Track(12800, avc1, videoSizes, 12800/uint32(fps), syncSamples, mdatOffset),
+		testMP4Track(44100, mp4a, audioSizes, 1024, nil, mdatOffset+uint32(audioOffset)),
+	)
+	return bytes.Join([][]byte{ftyp, testMP4Box("mdat", mdat), moov}, nil)
+}
+
+// testMPEGTS creates a transport stream with a single video stream containing
+// the given number of frames. Every frame is a single packet and every
+// keyframeInterval frames a PAT and PMT are sent and the frame is marked as a
+// random access point.
+func testMPEGTS(frames, fps, keyframeInterval int) []byte {
+	var stream []byte
+	for i := 0; i < frames; i++ {
+		keyframe := i%keyframeInterval == 0
+		if keyframe {
+			stream = append(stream, testTSPacket(tsPATPID, true, false, testPAT())...)
+			stream = append(stream, testTSPacket(testTSPMTPID, true, false, testPMT())...)
+		}
+		pts := uint64(i * 90000 / fps)
+		stream = append(stream, testTSPacket(testTSVideoPID, true, keyframe, testPES(pts))...)
+	}
+	return stream
+}
+
+// testTSPacket creates a transport stream packet.
+func testTSPacket(pid uint16, pusi, randomAccess bool, payload []byte) []byte {
+	pkt := make([]byte, 4, tsPacketSize)
+	pkt[0] = tsSyncByte
+	pkt[1] = byte(pid >> 8 & 0x1f)
+	if pusi {
+		pkt[1] |= 0x40
+	}
+	pkt[2] = byte(pid)
+	// Always use an adaptation field to pad the packet.
+	pkt[3] = 0x30
+	afLen := tsPacketSize - 5 - len(payload)
+	pkt = append(pkt, byte(afLen))
+	if afLen > 0 {
+		flags := byte(0)
+		if randomAccess {
+			flags |= 0x40
+		}
+		pkt = append(pkt, flags)
+		pkt = append(pkt, bytes.Repeat([]byte{0xff}, afLen-1)...)
+	}
+	return append(pkt, payload...)
+}
+
+// testPAT returns a PAT payload pointing to the test PMT.
+func testPAT() []byte {
+	return []byte{
+		0x00,       // pointer field
+		0x00,       // table id
+		0xb0, 0x0d, // section length 13
+		0x00, 0x01, // transport stream id
+		0xc1, 0x00, 0x00, // version, section numbers
+		0x00, 0x01, // program number
+		0xe0 | testTSPMTPID>>8, testTSPMTPID & 0xff, // PMT PID
+		0x00, 0x00, 0x00, 0x00, // CRC
+	}
+}
+
+// testPMT returns a PMT payload with an audio stream and the test video
+// stream.
+func testPMT() []byte {
+	return []byte{
+		0x00,       // pointer field
+		0x02,       // table id
+		0xb0, 0x17, // section length 23
+		0x00, 0x01, // program number
+		0xc1, 0x00, 0x00, // version, section numbers
+		0xe0 | testTSVideoPID>>8, testTSVideoPID & 0xff, // PCR PID
+		0xf0, 0x00, // program info length
+		0x0f, 0xe1, 0x01, 0xf0, 0x00, // AAC audio on PID 0x101
+		0x1b, 0xe0 | testTSVideoPID>>8, testTSVideoPID & 0xff, 0xf0, 0x00, // H.264 video
+		0x00, 0x00, 0x00, 0x00, // CRC
+	}
+}
+
+// testPES returns a PES header carrying the given presentation timestamp.
+func testPES(pts uint64) []byte {
+	return []byte{
+		0x00, 0x00, 0x01, 0xe0, // start code and stream id
+		0x00, 0x00, // packet length
+		0x80, 0x80, 0x05, // flags, PTS only, header length
+		byte(0x21 | pts>>29&0x0e),
+		byte(pts >> 22),
+		byte(0x01 | pts>>14&0xfe),
+		byte(pts >> 7),
+		byte(0x01 | pts<<1&0xfe),
+	}
+}
@@ -0,0 +1,857 @@
+package modules
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/uplo-tech/errors"
+)
+
+// skynethlsmp4.go contains a pure Go MP4 demuxer that remuxes the H.264 and
+// AAC tracks of an MP4 file into an MPEG transport stream. The transport
+// stream is then split into segments by the HLS segmenter. Only progressive
+// MP4 files are supported, fragmented MP4 files are rejected.
+
+const (
+	// mp4MaxMoovSize is the maximum size of the moov box of an MP4 file. The
+	// box is read into memory to build the sample tables of the tracks.
+	mp4MaxMoovSize = 64 << 20
+
+	// mp4TSPMTPID, mp4TSVideoPID and mp4TSAudioPID are the PIDs of the
+	// transport streams created from MP4 files.
+	mp4TSPMTPID   = 0x1000
+	mp4TSVideoPID = 0x0100
+	mp4TSAudioPID = 0x0101
+
+	// mp4TSTimestampOffset is added to the timestamps of the transport stream
+	// so that the program clock reference precedes the decode timestamps.
+	mp4TSTimestampOffset = 9000
+
+	// tsClockRate is the rate of the MPEG-TS presentation clock.
+	tsClockRate = 90000
+)
+
+var (
+	// ErrInvalidMP4 is returned when the data passed to the HLS segmenter is
+	// not a valid MP4 file.
+	ErrInvalidMP4 = errors.New("data is not a valid MP4 file")
+)
+
+type (
+	// mp4Box is a box of an MP4 file.
+	mp4Box struct {
+		typ  string
+		data []byte
+	}
+
+	// mp4Track is an H.264 or AAC track of an MP4 file.
+	mp4Track struct {
+		isVideo   bool
+		timescale uint32
+		samples   []mp4Sample
+
+		// nalLengthSize is the size of the length prefix of the NAL units of
+		// an H.264 track and parameterSets are its SPS and PPS.
+		nalLengthSize int
+		parameterSets [][]byte
+
+		// The fields of the ADTS header of an AAC track.
+		aacProfile   byte
+		aacFreqIndex byte
+		aacChannels  byte
+	}
+
+	// mp4Sample is a sample of an MP4 track. The timestamps are counted
+	// using the MPEG-TS presentation clock.
+	mp4Sample struct {
+		offset int64
+		size   uint32
+		dts    uint64
+		pts    uint64
+		sync   bool
+	}
+
+	// tsMuxer writes the samples of elementary streams to an MPEG transport
+	// stream.
+	tsMuxer struct {
+		w        io.Writer
+		hasVideo bool
+		hasAudio bool
+		pcrPID   uint16
+		counters map[uint16]byte
+	}
+)
+
+// RemuxMP4 reads the MP4 file of the given size from r and writes its first
+// H.264 track and its first AAC track to w as an MPEG transport stream.
+func RemuxMP4(r io.ReaderAt, size int64, w io.Writer) error {
+	moov, err := readMP4Moov(r, size)
+	if err != nil {
+		return err
+	}
+	boxes, err := parseMP4Boxes(moov)
+	if err != nil {
+		return err
+	}
+	var video, audio *mp4Track
+	for _, box := range boxes {
+		if box.typ != "trak" {
+			continue
+		}
+		track, err := parseMP4Track(box.data, size)
+		if err != nil {
+			return errors.AddContext(err, "unable to parse track")
+		}
+		if track == nil {
+			continue
+		}
+		if track.isVideo && video == nil {
+			video = track
+		} else if !track.isVideo && audio == nil {
+			audio = track
+		}
+	}
+	if video == nil && audio == nil {
+		return errors.AddContext(ErrHLSUnsupportedContainer, "MP4 file contains no H.264 or AAC track")
+	}
+
+	// Write the samples of both tracks interleaved by their decode
+	// timestamps.
+	m := &tsMuxer{
+		w:        w,
+		hasVideo: video != nil,
+		hasAudio: audio != nil,
+		pcrPID:   mp4TSVideoPID,
+		counters: make(map[uint16]byte),
+	}
+	if video == nil {
+		m.pcrPID = mp4TSAudioPID
+		video = &mp4Track{}
+	}
+	if audio == nil {
+		audio = &mp4Track{}
+	}
+	// The tables are written at the start of the stream and before every
+	// video sync sample.
+	var buf []byte
+	for i, j := 0, 0; i < len(video.samples) || j < len(audio.samples); {
+		isVideo := j == len(audio.samples) || (i < len(video.samples) && video.samples[i].dts <= audio.samples[j].dts)
+		var sample mp4Sample
+		if isVideo {
+			sample = video.samples[i]
+			i++
+		} else {
+			sample = audio.samples[j]
+			j++
+		}
+		if uint64(cap(buf)) < uint64(sample.size) {
+			buf = make([]byte, sample.size)
+		}
+		data := buf[:sample.size]
+		if _, err := r.ReadAt(data, sample.offset); err != nil {
+			return errors.AddContext(err, "unable to read sample")
+		}
+		if i+j == 1 || (isVideo && sample.sync) {
+			if err := m.writeTables(); err != nil {
+				return err
+			}
+		}
+		if isVideo {
+			es, err := video.annexB(data, sample.sync)
+			if err != nil {
+				return err
+			}
+			err = m.writePES(mp4TSVideoPID, 0xe0, es, sample.pts, sample.dts, sample.sync)
+			if err != nil {
+				return err
+			}
+		} else {
+			// Every AAC frame can be decoded on its own.
+			err := m.writePES(mp4TSAudioPID, 0xc0, audio.adts(data), sample.pts, sample.pts, true)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readMP4Moov returns the payload of the moov box of the MP4 file of the
+// given size.
+func readMP4Moov(r io.ReaderAt, size int64) ([]byte, error) {
+	header := make([]byte, 16)
+	for off := int64(0); off < size; {
+		if size-off < 8 {
+			return nil, errors.AddContext(ErrInvalidMP4, "truncated box header")
+		}
+		if _, err := r.ReadAt(header[:8], off); err != nil {
+			return nil, errors.AddContext(err, "unable to read box header")
+		}
+		boxSize := uint64(binary.BigEndian.Uint32(header))
+		headerLen := uint64(8)
+		switch boxSize {
+		case 0:
+			boxSize = uint64(size - off)
+		case 1:
+			if size-off < 16 {
+				return nil, errors.AddContext(ErrInvalidMP4, "truncated box header")
+			}
+			if _, err := r.ReadAt(header[8:], off+8); err != nil {
+				return nil, errors.AddContext(err, "unable to read box header")
+			}
+			boxSize = binary.BigEndian.Uint64(header[8:])
+			headerLen = 16
+		}
+		if boxSize < headerLen || boxSize > uint64(size-off) {
+			return nil, errors.AddContext(ErrInvalidMP4, "box exceeds the file")
+		}
+		if string(header[4:8]) == "moov" {
+			if boxSize-headerLen > mp4MaxMoovSize {
+				return nil, errors.AddContext(ErrHLSUnsupportedContainer, "moov box is too large")
+			}
+			moov := make([]byte, boxSize-headerLen)
+			if _, err := r.ReadAt(moov, off+int64(headerLen)); err != nil {
+				return nil, errors.AddContext(err, "unable to read moov box")
+			}
+			return moov, nil
+		}
+		off += int64(boxSize)
+	}
+	return nil, errors.AddContext(ErrInvalidMP4, "file contains no moov box")
+}
+
+// parseMP4Boxes splits data into the boxes it contains.
+func parseMP4Boxes(data []byte) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.AddContext(ErrInvalidMP4, "truncated box header")
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		headerLen := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errors.AddContext(ErrInvalidMP4, "truncated box header")
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			headerLen = 16
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			return nil, errors.AddContext(ErrInvalidMP4, "box exceeds its parent")
+		}
+		boxes = append(boxes, mp4Box{
+			typ:  string(data[4:8]),
+			data: data[headerLen:size],
+		})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// findMP4Box returns the payload of the first box of the given type.
+func findMP4Box(boxes []mp4Box, typ string) ([]byte, bool) {
+	for _, box := range boxes {
+		if box.typ == typ {
+			return box.data, true
+		}
+	}
+	return nil, false
+}
+
+// findMP4Path returns the child boxes of the box at the given path.
+func findMP4Path(boxes []mp4Box, path ...string) ([]mp4Box, error) {
+	for _, typ := range path {
+		data, ok := findMP4Box(boxes, typ)
+		if !ok {
+			return nil, errors.AddContext(ErrInvalidMP4, "missing "+typ+" box")
+		}
+		var err error
+		boxes, err = parseMP4Boxes(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return boxes, nil
+}
+
+// parseMP4Track parses a trak box. nil is returned for tracks which are
+// neither H.264 nor AAC.
+func parseMP4Track(trak []byte, fileSize int64) (*mp4Track, error) {
+	boxes, err := parseMP4Boxes(trak)
+	if err != nil {
+		return nil, err
+	}
+	mdia, err := findMP4Path(boxes, "mdia")
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the timescale of the track.
+	mdhd, ok := findMP4Box(mdia, "mdhd")
+	if !ok || len(mdhd) < 1 {
+		return nil, errors.AddContext(ErrInvalidMP4, "missing mdhd box")
+	}
+	timescaleOff := 12
+	if mdhd[0] == 1 {
+		timescaleOff = 20
+	}
+	if len(mdhd) < timescaleOff+4 {
+		return nil, errors.AddContext(ErrInvalidMP4, "truncated mdhd box")
+	}
+	t := &mp4Track{
+		timescale: binary.BigEndian.Uint32(mdhd[timescaleOff:]),
+	}
+	if t.timescale == 0 {
+		return nil, errors.AddContext(ErrInvalidMP4, "track has no timescale")
+	}
+
+	// Parse the sample description.
+	stbl, err := findMP4Path(mdia, "minf", "stbl")
+	if err != nil {
+		return nil, err
+	}
+	stsd, ok := findMP4Box(stbl, "stsd")
+	if !ok || len(stsd) < 8 {
+		return nil, errors.AddContext(ErrInvalidMP4, "missing stsd box")
+	}
+	entries, err := parseMP4Boxes(stsd[8:])
+	if err != nil || len(entries) == 0 {
+		return nil, errors.AddContext(ErrInvalidMP4, "invalid sample description")
+	}
+	switch entry := entries[0]; entry.typ {
+	case "avc1", "avc3":
+		t.isVideo = true
+		err = t.parseAVCSampleEntry(entry.data)
+	case "mp4a":
+		err = t.parseAACSampleEntry(entry.data)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := t.parseSampleTables(stbl, fileSize); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parseAVCSampleEntry parses the parameter sets and the NAL unit length size
+// of an H.264 sample entry.
+func (t *mp4Track) parseAVCSampleEntry(entry []byte) error {
+	// The child boxes follow the 78 bytes of the visual sample entry.
+	if len(entry) < 78 {
+		return errors.AddContext(ErrInvalidMP4, "truncated avc1 box")
+	}
+	boxes, err := parseMP4Boxes(entry[78:])
+	if err != nil {
+		return err
+	}
+	avcC, ok := findMP4Box(boxes, "avcC")
+	if !ok || len(avcC) < 6 {
+		return errors.AddContext(ErrInvalidMP4, "missing avcC box")
+	}
+	t.nalLengthSize = int(avcC[4]&0x03) + 1
+
+	// The SPS are followed by the PPS, each list is prefixed by its length.
+	data := avcC[5:]
+	for _, countMask := range []byte{0x1f, 0xff} {
+		if len(data) < 1 {
+			return errors.AddContext(ErrInvalidMP4, "truncated avcC box")
+		}
+		count := int(data[0] & countMask)
+		data = data[1:]
+		for i := 0; i < count; i++ {
+			if len(data) < 2 {
+				return errors.AddContext(ErrInvalidMP4, "truncated avcC box")
+			}
+			n := int(binary.BigEndian.Uint16(data))
+			if len(data) < 2+n {
+				return errors.AddContext(ErrInvalidMP4, "truncated avcC box")
+			}
+			t.parameterSets = append(t.parameterSets, data[2:2+n])
+			data = data[2+n:]
+		}
+	}
+	return nil
+}
+
+// parseAACSampleEntry parses the ADTS header fields of an AAC sample entry.
+func (t *mp4Track) parseAACSampleEntry(entry []byte) error {
+	// The child boxes follow the 28 bytes of the audio sample entry, which
+	// are extended by QuickTime sound description versions 1 and 2.
+	if len(entry) < 28 {
+		return errors.AddContext(ErrInvalidMP4, "truncated mp4a box")
+	}
+	childrenOff := 28
+	switch binary.BigEndian.Uint16(entry[8:]) {
+	case 1:
+		childrenOff += 16
+	case 2:
+		childrenOff += 36
+	}
+	if len(entry) < childrenOff {
+		return errors.AddContext(ErrInvalidMP4, "truncated mp4a box")
+	}
+	boxes, err := parseMP4Boxes(entry[childrenOff:])
+	if err != nil {
+		return err
+	}
+	esds, ok := findMP4Box(boxes, "esds")
+	if !ok || len(esds) < 4 {
+		return errors.AddContext(ErrInvalidMP4, "missing esds box")
+	}
+
+	// Find the audio specific config within the ES descriptor.
+	tag, es, _, ok := readMP4Descriptor(esds[4:])
+	if !ok || tag != 0x03 || len(es) < 3 {
+		return errors.AddContext(ErrInvalidMP4, "missing ES descriptor")
+	}
+	flags := es[2]
+	es = es[3:]
+	if flags&0x80 != 0 && len(es) >= 2 {
+		es = es[2:]
+	}
+	if flags&0x40 != 0 && len(es) >= 1 && len(es) >= 1+int(es[0]) {
+		es = es[1+int(es[0]):]
+	}
+	if flags&0x20 != 0 && len(es) >= 2 {
+		es = es[2:]
+	}
+	tag, dcd, _, ok := readMP4Descriptor(es)
+	if !ok || tag != 0x04 || len(dcd) < 13 {
+		return errors.AddContext(ErrInvalidMP4, "missing decoder config descriptor")
+	}
+	switch dcd[0] {
+	case 0x40, 0x66, 0x67, 0x68:
+	default:
+		return errors.AddContext(ErrHLSUnsupportedContainer, "audio track is not AAC")
+	}
+	tag, asc, _, ok := readMP4Descriptor(dcd[13:])
+	if !ok || tag != 0x05 || len(asc) < 2 {
+		return errors.AddContext(ErrInvalidMP4, "missing audio specific config")
+	}
+
+	// ADTS headers can only describe the first four audio object types with a
+	// sampling frequency from the table.
+	objectType := asc[0] >> 3
+	t.aacFreqIndex = (asc[0]&0x07)<<1 | asc[1]>>7
+	t.aacChannels = asc[1] >> 3 & 0x0f
+	if objectType == 0 || objectType > 4 || t.aacFreqIndex > 12 {
+		return errors.AddContext(ErrHLSUnsupportedContainer, "unsupported AAC configuration")
+	}
+	t.aacProfile = objectType - 1
+	return nil
+}
+
+// readMP4Descriptor returns the tag and the payload of the MPEG-4 descriptor
+// at the start of data and the data that follows it.
+func readMP4Descriptor(data []byte) (tag byte, payload, rest []byte, ok bool) {
+	if len(data) < 2 {
+		return 0, nil, nil, false
+	}
+	tag = data[0]
+	var size int
+	i := 1
+	for {
+		if i >= len(data) || i > 4 {
+			return 0, nil, nil, false
+		}
+		b := data[i]
+		i++
+		size = size<<7 | int(b&0x7f)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if size > len(data)-i {
+		return 0, nil, nil, false
+	}
+	return tag, data[i : i+size], data[i+size:], true
+}
+
+// mp4Table returns the entries of a sample table box, which start with a
+// version, flags and the number of entries.
+func mp4Table(stbl []mp4Box, typ string, entrySize int) ([]byte, uint32, bool, error) {
+	data, ok := findMP4Box(stbl, typ)
+	if !ok {
+		return nil, 0, false, nil
+	}
+	if len(data) < 8 {
+		return nil, 0, false, errors.AddContext(ErrInvalidMP4, "truncated "+typ+" box")
+	}
+	count := binary.BigEndian.Uint32(data[4:])
+	if uint64(len(data)-8) < uint64(count)*uint64(entrySize) {
+		return nil, 0, false, errors.AddContext(ErrInvalidMP4, "truncated "+typ+" box")
+	}
+	return data[8:], count, true, nil
+}
+
+// parseSampleTables builds the samples of the track from the sample table
+// boxes.
+func (t *mp4Track) parseSampleTables(stbl []mp4Box, fileSize int64) error {
+	// Get the sizes of the samples.
+	stsz, ok := findMP4Box(stbl, "stsz")
+	if !ok || len(stsz) < 12 {
+		return errors.AddContext(ErrInvalidMP4, "missing stsz box")
+	}
+	sampleSize := binary.BigEndian.Uint32(stsz[4:])
+	numSamples := binary.BigEndian.Uint32(stsz[8:])
+	if numSamples == 0 {
+		return errors.AddContext(ErrHLSUnsupportedContainer, "fragmented MP4 files are not supported")
+	}
+	if sampleSize == 0 && uint64(len(stsz)-12) < uint64(numSamples)*4 {
+		return errors.AddContext(ErrInvalidMP4, "truncated stsz box")
+	}
+	if sampleSize != 0 && uint64(numSamples)*uint64(sampleSize) > uint64(fileSize) {
+		return errors.AddContext(ErrInvalidMP4, "samples exceed the file")
+	}
+	t.samples = make([]mp4Sample, numSamples)
+	for i := range t.samples {
+		t.samples[i].size = sampleSize
+		if sampleSize == 0 {
+			t.samples[i].size = binary.BigEndian.Uint32(stsz[12+4*i:])
+		}
+	}
+
+	// Get the offsets of the samples from the chunk offsets.
+	chunks, numChunks, ok, err := mp4Table(stbl, "stco", 4)
+	chunkOffsetSize := 4
+	if err == nil && !ok {
+		chunks, numChunks, ok, err = mp4Table(stbl, "co64", 8)
+		chunkOffsetSize = 8
+	}
+	if err != nil {
+		return err
+	} else if !ok {
+		return errors.AddContext(ErrInvalidMP4, "missing chunk offsets")
+	}
+	stsc, numStsc, ok, err := mp4Table(stbl, "stsc", 12)
+	if err != nil {
+		return err
+	} else if !ok {
+		return errors.AddContext(ErrInvalidMP4, "missing stsc box")
+	}
+	var sample uint32
+	for i := uint32(0); i < numStsc; i++ {
+		firstChunk := binary.BigEndian.Uint32(stsc[12*i:])
+		samplesPerChunk := binary.BigEndian.Uint32(stsc[12*i+4:])
+		lastChunk := numChunks
+		if i+1 < numStsc {
+			lastChunk = binary.BigEndian.Uint32(stsc[12*(i+1):]) - 1
+		}
+		for chunk := firstChunk; chunk >= 1 && chunk <= lastChunk && chunk <= numChunks && sample < numSamples; chunk++ {
+			var offset uint64
+			if chunkOffsetSize == 4 {
+				offset = uint64(binary.BigEndian.Uint32(chunks[4*(chunk-1):]))
+			} else {
+				offset = binary.BigEndian.Uint64(chunks[8*(chunk-1):])
+			}
+			for j := uint32(0); j < samplesPerChunk && sample < numSamples; j++ {
+				s := &t.samples[sample]
+				if offset+uint64(s.size) > uint64(fileSize) {
+					return errors.AddContext(ErrInvalidMP4, "sample exceeds the file")
+				}
+				s.offset = int64(offset)
+				offset += uint64(s.size)
+				sample++
+			}
+		}
+	}
+	if sample != numSamples {
+		return errors.AddContext(ErrInvalidMP4, "chunks don't contain all samples")
+	}
+
+	// Get the decode timestamps of the samples.
+	stts, numStts, ok, err := mp4Table(stbl, "stts", 8)
+	if err != nil {
+		return err
+	} else if !ok {
+		return errors.AddContext(ErrInvalidMP4, "missing stts box")
+	}
+	var dts uint64
+	sample = 0
+	for i := uint32(0); i < numStts; i++ {
+		count := binary.BigEndian.Uint32(stts[8*i:])
+		delta := binary.BigEndian.Uint32(stts[8*i+4:])
+		for j := uint32(0); j < count && sample < numSamples; j++ {
+			t.samples[sample].dts = dts
+			t.samples[sample].pts = dts
+			dts += uint64(delta)
+			sample++
+		}
+	}
+	if sample != numSamples {
+		return errors.AddContext(ErrInvalidMP4, "stts box doesn't cover all samples")
+	}
+
+	// Add the composition offsets. They are signed in version 1 of the box
+	// and some muxers write negative offsets into version 0 boxes as well.
+	ctts, numCtts, ok, err := mp4Table(stbl, "ctts", 8)
+	if err != nil {
+		return err
+	}
+	sample = 0
+	for i := uint32(0); ok && i < numCtts; i++ {
+		count := binary.BigEndian.Uint32(ctts[8*i:])
+		offset := int64(int32(binary.BigEndian.Uint32(ctts[8*i+4:])))
+		for j := uint32(0); j < count && sample < numSamples; j++ {
+			s := &t.samples[sample]
+			if pts := int64(s.dts) + offset; pts > 0 {
+				s.pts = uint64(pts)
+			} else {
+				s.pts = 0
+			}
+			sample++
+		}
+	}
+
+	// Mark the sync samples. Without an stss box every sample is a sync
+	// sample.
+	stss, numStss, ok, err := mp4Table(stbl, "stss", 4)
+	if err != nil {
+		return err
+	}
+	for i := range t.samples {
+		t.samples[i].sync = !ok
+	}
+	for i := uint32(0); ok && i < numStss; i++ {
+		n := binary.BigEndian.Uint32(stss[4*i:])
+		if n >= 1 && n <= numSamples {
+			t.samples[n-1].sync = true
+		}
+	}
+
+	// Convert the timestamps to the presentation clock of the transport
+	// stream.
+	for i := range t.samples {
+		s := &t.samples[i]
+		s.dts = s.dts * tsClockRate / uint64(t.timescale)
+		s.pts = s.pts * tsClockRate / uint64(t.timescale)
+		if s.pts < s.dts {
+			s.pts = s.dts
+		}
+	}
+	return nil
+}
+
+// annexB converts an H.264 sample from the length prefixed format of MP4 to
+// the start code prefixed format of MPEG-TS. Every access unit starts with an
+// access unit delimiter and sync samples are preceded by the parameter sets.
+func (t *mp4Track) annexB(data []byte, sync bool) ([]byte, error) {
+	es := []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0}
+	if sync {
+		for _, ps := range t.parameterSets {
+			es = append(es, 0x00, 0x00, 0x00, 0x01)
+			es = append(es, ps...)
+		}
+	}
+	for len(data) > 0 {
+		if len(data) < t.nalLengthSize {
+			return nil, errors.AddContext(ErrInvalidMP4, "truncated NAL unit length")
+		}
+		var n uint64
+		for _, b := range data[:t.nalLengthSize] {
+			n = n<<8 | uint64(b)
+		}
+		data = data[t.nalLengthSize:]
+		if n > uint64(len(data)) {
+			return nil, errors.AddContext(ErrInvalidMP4, "NAL unit exceeds sample")
+		}
+		nal := data[:n]
+		data = data[n:]
+		if len(nal) > 0 && nal[0]&0x1f == 9 {
+			// Skip access unit delimiters since one was already added.
+			continue
+		}
+		es = append(es, 0x00, 0x00, 0x00, 0x01)
+		es = append(es, nal...)
+	}
+	return es, nil
+}
+
+// adts prefixes an AAC frame with an ADTS header.
+func (t *mp4Track) adts(frame []byte) []byte {
+	frameLen := len(frame) + 7
+	header := []byte{
+		0xff,
+		0xf1, // MPEG-4, no CRC
+		t.aacProfile<<6 | t.aacFreqIndex<<2 | t.aacChannels>>2,
+		t.aacChannels&0x03<<6 | byte(frameLen>>11),
+		byte(frameLen >> 3),
+		byte(frameLen&0x07)<<5 | 0x1f,
+		0xfc,
+	}
+	return append(header, frame...)
+}
+
+// writeTables writes the PAT and the PMT.
+func (m *tsMuxer) writeTables() error {
+	pat := []byte{
+		0x00,       // table id
+		0xb0, 0x0d, // section length
+		0x00, 0x01, // transport stream id
+		0xc1, 0x00, 0x00, // version, section numbers
+		0x00, 0x01, // program number
+		0xe0 | mp4TSPMTPID>>8, mp4TSPMTPID & 0xff, // PMT PID
+	}
+	if err := m.writeSection(tsPATPID, pat); err != nil {
+		return err
+	}
+	pmt := []byte{
+		0x02,       // table id
+		0xb0, 0x00, // section length
+		0x00, 0x01, // program number
+		0xc1, 0x00, 0x00, // version, section numbers
+		0xe0 | byte(m.pcrPID>>8), byte(m.pcrPID), // PCR PID
+		0xf0, 0x00, // program info length
+	}
+	if m.hasVideo {
+		pmt = append(pmt, 0x1b, 0xe0|mp4TSVideoPID>>8, mp4TSVideoPID&0xff, 0xf0, 0x00)
+	}
+	if m.hasAudio {
+		pmt = append(pmt, 0x0f, 0xe0|mp4TSAudioPID>>8, mp4TSAudioPID&0xff, 0xf0, 0x00)
+	}
+	// The section length includes the CRC.
+	pmt[2] = byte(len(pmt) - 3 + 4)
+	return m.writeSection(mp4TSPMTPID, pmt)
+}
+
+// writeSection writes a PSI section in a single packet.
+func (m *tsMuxer) writeSection(pid uint16, section []byte) error {
+	crc := crc32MPEG2(section)
+	pkt := make([]byte, tsPacketSize)
+	for i := range pkt {
+		pkt[i] = 0xff
+	}
+	pkt[0] = tsSyncByte
+	pkt[1] = 0x40 | byte(pid>>8&0x1f)
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10 | m.nextCounter(pid)
+	pkt[4] = 0x00 // pointer field
+	n := copy(pkt[5:], section)
+	binary.BigEndian.PutUint32(pkt[5+n:], crc)
+	_, err := m.w.Write(pkt)
+	return err
+}
+
+// writePES writes a sample of an elementary stream as a PES packet.
+func (m *tsMuxer) writePES(pid uint16, streamID byte, es []byte, pts, dts uint64, randomAccess bool) error {
+	hasDTS := dts != pts
+	headerLen := 5
+	if hasDTS {
+		headerLen = 10
+	}
+	pes := make([]byte, 0, 9+headerLen+len(es))
+	pes = append(pes, 0x00, 0x00, 0x01, streamID)
+	pesLen := 3 + headerLen + len(es)
+	if pesLen > 0xffff {
+		// Video PES packets may have an unbounded length.
+		pesLen = 0
+	}
+	pes = append(pes, byte(pesLen>>8), byte(pesLen))
+	if hasDTS {
+		pes = append(pes, 0x80, 0xc0, byte(headerLen))
+		pes = appendPESTimestamp(pes, 0x03, pts+mp4TSTimestampOffset)
+		pes = appendPESTimestamp(pes, 0x01, dts+mp4TSTimestampOffset)
+	} else {
+		pes = append(pes, 0x80, 0x80, byte(headerLen))
+		pes = appendPESTimestamp(pes, 0x02, pts+mp4TSTimestampOffset)
+	}
+	pes = append(pes, es...)
+
+	// Split the PES packet into transport stream packets. The first packet
+	// carries the PCR and the random access indicator in its adaptation field
+	// and the last packet is padded using the adaptation field.
+	for first := true; len(pes) > 0; first = false {
+		var af []byte
+		if first && (randomAccess || pid == m.pcrPID) {
+			af = []byte{0x00}
+			if randomAccess {
+				af[0] |= 0x40
+			}
+			if pid == m.pcrPID {
+				pcr := dts & tsPTSMask
+				af[0] |= 0x10
+				af = append(af, byte(pcr>>25), byte(pcr>>17), byte(pcr>>9), byte(pcr>>1), byte(pcr&0x01)<<7|0x7e, 0x00)
+			}
+		}
+		space := tsPacketSize - 4
+		if af != nil {
+			space -= 1 + len(af)
+		}
+		if len(pes) < space {
+			stuffing := space - len(pes)
+			if af == nil {
+				// The length byte of the adaptation field is part of the
+				// stuffing.
+				af = []byte{}
+				if stuffing > 1 {
+					af = append(af, 0x00)
+				}
+				stuffing -= 1 + len(af)
+			}
+			for i := 0; i < stuffing; i++ {
+				af = append(af, 0xff)
+			}
+			space = len(pes)
+		}
+		pkt := make([]byte, 4, tsPacketSize)
+		pkt[0] = tsSyncByte
+		pkt[1] = byte(pid >> 8 & 0x1f)
+		if first {
+			pkt[1] |= 0x40
+		}
+		pkt[2] = byte(pid)
+		pkt[3] = 0x10 | m.nextCounter(pid)
+		if af != nil {
+			pkt[3] |= 0x20
+			pkt = append(pkt, byte(len(af)))
+			pkt = append(pkt, af...)
+		}
+		pkt = append(pkt, pes[:space]...)
+		pes = pes[space:]
+		if _, err := m.w.Write(pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextCounter returns the continuity counter of the next packet of the PID.
+func (m *tsMuxer) nextCounter(pid uint16) byte {
+	cc := m.counters[pid]
+	m.counters[pid] = (cc + 1) & 0x0f
+	return cc
+}
+
+// appendPESTimestamp appends a PTS or DTS with the given 4 bit prefix to b.
+func appendPESTimestamp(b []byte, prefix byte, ts uint64) []byte {
+	ts &= tsPTSMask
+	return append(b,
+		prefix<<4|byte(ts>>29&0x0e)|0x01,
+		byte(ts>>22),
+		byte(ts>>14&0xfe)|0x01,
+		byte(ts>>7),
+		byte(ts<<1&0xfe)|0x01,
+	)
+}
+
+// crc32MPEG2 returns the CRC of a PSI section.
+func crc32MPEG2(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -47,6 +47,7 @@ type (
 	// renters and hosts, and primarily is stored on the hosts.
 	StorageManager interface {
 		Alerter
+		EventSubscriber
 
 		// AddSector will add a sector to the storage manager. If the sector
 		// already exists, a virtual sector will be added, meaning that the
@@ -208,11 +208,13 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []m
 		tp.transactionListSize -= len(encoding.Marshal(conflictSet))
 		delete(tp.transactionSets, conflict)
 		delete(tp.transactionSetDiffs, conflict)
+		tp.unpersistedSets[conflict] = struct{}{}
 	}
 
 	// Add the transaction set to the pool.
 	setID := modules.TransactionSetID(crypto.HashObject(superset))
 	tp.transactionSets[setID] = superset
+	tp.unpersistedSets[setID] = struct{}{}
 	for _, diff := range cc.UplocoinOutputDiffs {
 		tp.knownObjects[ObjectID(diff.ID)] = setID
 	}
@@ -288,6 +290,13 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 		return nil, errLowMinerFees
 	}
 
+	// Check whether the transaction set double-spends outputs that are spent
+	// by transaction sets in the pool. In that case the transaction set may
+	// replace them if it pays enough fees.
+	if replaced := tp.doubleSpentSets(ts); len(replaced) > 0 {
+		return tp.replaceTransactionSets(ts, replaced, txnFn)
+	}
+
 	// Check for conflicts with other transactions, which would indicate a
 	// double-spend. Legal children of a transaction set will also trigger the
 	// conflict-detector.
@@ -310,6 +319,7 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 	// Add the transaction set to the pool.
 	setID := modules.TransactionSetID(crypto.HashObject(ts))
 	tp.transactionSets[setID] = ts
+	tp.unpersistedSets[setID] = struct{}{}
 	for _, oid := range oids {
 		tp.knownObjects[oid] = setID
 	}
@@ -363,7 +373,11 @@ func (tp *TransactionPool) submitTransactionSet(ts []types.Transaction) ([]types
 			}
 			return acceptErr
 		}
-		// Notify subscribers of an accepted transaction set
+		// Persist the transaction sets and notify subscribers of an accepted
+		// transaction set.
+		if err := tp.putTransactionSets(tp.dbTx); err != nil {
+			tp.log.Println("WARN: unable to persist transaction sets:", err)
+		}
 		tp.updateSubscribersTransactions()
 		return nil
 	})
@@ -436,5 +450,61 @@ func (tp *TransactionPool) relayTransactionSet(conn modules.PeerConn) error {
 	if err != nil {
 		return err
 	}
-	return tp.AcceptTransactionSet(ts)
+	err = tp.AcceptTransactionSet(ts)
+	if err != nil && tp.consensusSet.Synced() {
+		tp.mu.RLock()
+		height := tp.blockHeight
+		tp.mu.RUnlock()
+		if invalidErr := provablyInvalidTransactionSetErr(ts, height+1); invalidErr != nil {
+			tp.log.Debugln("Peer relayed an invalid transaction set:", invalidErr)
+			tp.gateway.ReportPeer(conn.RPCAddr(), modules.PeerOffenseInvalidTransaction)
+		}
+	}
+	return err
+}
+
+// provablyInvalidTransactionSetErrs are the errors which prove that a
+// transaction is invalid regardless of the state of the blockchain and the
+// policy of the pool.
+var provablyInvalidTransactionSetErrs = []error{
+	crypto.ErrInvalidSignature,
+	types.ErrDoubleSpend,
+	types.ErrEntropyKey,
+	types.ErrFileContractOutputSumViolation,
+	types.ErrFileContractWindowEndViolation,
+	types.ErrFrivolousSignature,
+	types.ErrInvalidFoundationUpdateEncoding,
+	types.ErrInvalidPubKeyIndex,
+	types.ErrMissingSignatures,
+	types.ErrNonZeroClaimStart,
+	types.ErrNonZeroRevision,
+	types.ErrPublicKeyOveruse,
+	types.ErrSortedUniqueViolation,
+	types.ErrStorageProofWithOutputs,
+	types.ErrTransactionTooLarge,
+	types.ErrUninitializedFoundationUpdate,
+	types.ErrWholeTransactionViolation,
+	types.ErrZeroMinerFee,
+	types.ErrZeroOutput,
+	types.ErrZeroRevision,
+}
+
+// provablyInvalidTransactionSetErr returns an error if a transaction of a set
+// relayed by a peer is provably invalid, e.g. because of a bad signature. Sets
+// which are rejected for other reasons, like unknown outputs while either node
+// is syncing, outputs spent by a new block or the pool's fee and size policy,
+// might have been relayed by an honest peer and return nil.
+func provablyInvalidTransactionSetErr(ts []types.Transaction, height types.BlockHeight) error {
+	for _, txn := range ts {
+		err := txn.StandaloneValid(height)
+		if err == nil {
+			continue
+		}
+		for _, invalidErr := range provablyInvalidTransactionSetErrs {
+			if errors.Contains(err, invalidErr) {
+				return err
+			}
+		}
+	}
+	return nil
 }
@@ -826,3 +826,28 @@ func TestPartialConfirmationWeave(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestProvablyInvalidTransactionSetErr checks that only transaction sets which
+// are invalid regardless of the state of the blockchain are considered
+// provably invalid.
+func TestProvablyInvalidTransactionSetErr(t *testing.T) {
+	// A transaction without any content is valid on its own.
+	if err := provablyInvalidTransactionSetErr([]types.Transaction{{}}, 1); err != nil {
+		t.Fatal("empty transaction shouldn't be provably invalid", err)
+	}
+	// A transaction spending an unknown output might just be relayed by a
+	// peer that's ahead of us, but it still needs to be signed.
+	unsigned := types.Transaction{
+		UplocoinInputs: []types.UplocoinInput{{ParentID: types.UplocoinOutputID{1}}},
+	}
+	if err := provablyInvalidTransactionSetErr([]types.Transaction{{}, unsigned}, 1); !errors.Contains(err, types.ErrMissingSignatures) {
+		t.Fatal("expected ErrMissingSignatures, got", err)
+	}
+	// Zero value outputs are never valid.
+	zeroOutput := types.Transaction{
+		UplocoinOutputs: []types.UplocoinOutput{{Value: types.ZeroCurrency}},
+	}
+	if err := provablyInvalidTransactionSetErr([]types.Transaction{zeroOutput}, 1); !errors.Contains(err, types.ErrZeroOutput) {
+		t.Fatal("expected ErrZeroOutput, got", err)
+	}
+}
@@ -59,6 +59,10 @@ const (
 	// added to the current tpool size when estimating a good fee rate for new
 	// transactions.
 	feeEstimationProportionalPadding = 1.25
+
+	// maxReplacedSets is the maximum number of transaction sets that a single
+	// transaction set can replace.
+	maxReplacedSets = 10
 )
 
 // Variables related to the persisting structures of the transaction pool.
@@ -74,6 +78,11 @@ var (
 	// minEstimation defines a sane minimum fee per byte for transactions.  This
 	// will typically be only suggested as a fee in the absence of congestion.
 	minEstimation = types.UplocoinPrecision.Div64(100).Div64(1e3)
+
+	// minReplacementFeeIncrease is the fee per byte that a transaction set has
+	// to pay on top of the fees of the transaction sets it replaces. This
+	// prevents peers from repeatedly relaying replacements for free.
+	minReplacementFeeIncrease = minEstimation
 )
 
 // Variables related to propagating transactions through the network.
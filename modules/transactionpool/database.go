@@ -33,6 +33,10 @@ var (
 	// bucketRecentConsensusChange holds the most recent consensus change seen
 	// by the transaction pool.
 	bucketRecentConsensusChange = []byte("RecentConsensusChange")
+
+	// bucketTransactionSets holds the unconfirmed transaction sets of the
+	// transaction pool so that they survive a restart.
+	bucketTransactionSets = []byte("TransactionSets")
 )
 
 // Explicitly named fields in the database.
@@ -131,6 +135,20 @@ func (tp *TransactionPool) getRecentConsensusChange(tx *bolt.Tx) (cc modules.Con
 	return cc, nil
 }
 
+// getTransactionSets returns the unconfirmed transaction sets stored in the
+// database.
+func (tp *TransactionPool) getTransactionSets(tx *bolt.Tx) (sets [][]types.Transaction, err error) {
+	err = tx.Bucket(bucketTransactionSets).ForEach(func(_, v []byte) error {
+		var set []types.Transaction
+		if err := encoding.Unmarshal(v, &set); err != nil {
+			return err
+		}
+		sets = append(sets, set)
+		return nil
+	})
+	return
+}
+
 // putBlockHeight updates the transaction pool's block height.
 func (tp *TransactionPool) putBlockHeight(tx *bolt.Tx, height types.BlockHeight) error {
 	tp.blockHeight = height
@@ -158,6 +176,25 @@ func (tp *TransactionPool) putRecentConsensusChange(tx *bolt.Tx, cc modules.Cons
 	return tx.Bucket(bucketRecentConsensusChange).Put(fieldRecentConsensusChange, cc[:])
 }
 
+// putTransactionSets persists the transaction sets that were added to or
+// removed from the pool since the transaction sets were last persisted.
+func (tp *TransactionPool) putTransactionSets(tx *bolt.Tx) error {
+	b := tx.Bucket(bucketTransactionSets)
+	for setID := range tp.unpersistedSets {
+		var err error
+		if set, exists := tp.transactionSets[setID]; exists {
+			err = b.Put(setID[:], encoding.Marshal(set))
+		} else {
+			err = b.Delete(setID[:])
+		}
+		if err != nil {
+			return err
+		}
+		delete(tp.unpersistedSets, setID)
+	}
+	return nil
+}
+
 // putTransaction adds a transaction to the list of confirmed transactions.
 func (tp *TransactionPool) putTransaction(tx *bolt.Tx, id types.TransactionID) error {
 	return tx.Bucket(bucketConfirmedTransactions).Put(id[:], []byte{})
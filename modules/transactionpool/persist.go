@@ -12,6 +12,7 @@ import (
 	"github.com/uplo-tech/threadgroup"
 
 	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/persist"
 	"github.com/uplo-tech/uplo/types"
@@ -132,6 +133,7 @@ func (tp *TransactionPool) initPersist() error {
 		bucketRecentConsensusChange,
 		bucketConfirmedTransactions,
 		bucketFeeMedian,
+		bucketTransactionSets,
 	}
 	for _, bucket := range buckets {
 		_, err := tp.dbTx.CreateBucketIfNotExists(bucket)
@@ -178,8 +180,22 @@ func (tp *TransactionPool) initPersist() error {
 		tp.recentMedianFee = mp.RecentMedianFee
 	}
 
+	// Load the unconfirmed transaction sets. They are accepted again once the
+	// transaction pool is synced with the consensus set.
+	persistedSets, err := tp.getTransactionSets(tp.dbTx)
+	if err != nil {
+		return build.ExtendErr("unable to load the unconfirmed transaction sets", err)
+	}
+	// The persisted sets are not part of the pool until they are accepted
+	// again. Sets which are not accepted are removed from the database the
+	// next time the transaction sets are persisted.
+	for _, set := range persistedSets {
+		tp.unpersistedSets[modules.TransactionSetID(crypto.HashObject(set))] = struct{}{}
+	}
+
 	// Subscribe to the consensus set using the most recent consensus change.
 	go func() {
+		defer tp.managedAcceptPersistedSets(persistedSets)
 		err := tp.consensusSet.ConsensusSetSubscribe(tp, cc, tp.tg.StopChan())
 		if err != nil && strings.Contains(err.Error(), threadgroup.ErrStopped.Error()) {
 			return
@@ -219,6 +235,30 @@ func (tp *TransactionPool) initPersist() error {
 	return nil
 }
 
+// managedAcceptPersistedSets validates the unconfirmed transaction sets that
+// were loaded from the database against the current consensus state and adds
+// them back to the pool. Sets which were confirmed or became invalid while the
+// transaction pool was offline are dropped.
+func (tp *TransactionPool) managedAcceptPersistedSets(sets [][]types.Transaction) {
+	var accepted int
+	for _, set := range sets {
+		select {
+		case <-tp.tg.StopChan():
+			return
+		default:
+		}
+		err := tp.AcceptTransactionSet(set)
+		if err != nil && !errors.Contains(err, modules.ErrDuplicateTransactionSet) {
+			tp.log.Debugln("Dropping persisted transaction set:", err)
+			continue
+		}
+		accepted++
+	}
+	if len(sets) > 0 {
+		tp.log.Printf("Restored %v of %v persisted transaction sets", accepted, len(sets))
+	}
+}
+
 // TransactionConfirmed returns true if the transaction has been seen on the
 // blockchain. Note, however, that the block containing the transaction may
 // later be invalidated by a reorg.
@@ -163,3 +163,76 @@ func TestRescan(t *testing.T) {
 		t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
 	}
 }
+
+// TestPersistTransactionSets checks that unconfirmed transaction sets survive a
+// restart of the transaction pool.
+func TestPersistTransactionSets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := tpt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Create a valid transaction set using the wallet.
+	txns, err := tpt.wallet.SendUplocoins(types.NewCurrency64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart the tpool.
+	persistDir := tpt.tpool.persistDir
+	if err := tpt.tpool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The transactions should be accepted again once the tpool is synced.
+	err = build.Retry(20, 250*time.Millisecond, func() error {
+		for _, txn := range txns {
+			if _, _, exists := tpt.tpool.Transaction(txn.ID()); !exists {
+				return errors.New("transaction wasn't restored")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet(txns)
+	if !errors.Contains(err, modules.ErrDuplicateTransactionSet) {
+		t.Fatal("expecting modules.ErrDuplicateTransactionSet, got: ", err)
+	}
+
+	// numPersistedSets returns the number of transaction sets in the database.
+	numPersistedSets := func() int {
+		tpt.tpool.mu.Lock()
+		defer tpt.tpool.mu.Unlock()
+		sets, err := tpt.tpool.getTransactionSets(tpt.tpool.dbTx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(sets)
+	}
+	if n := numPersistedSets(); n != 1 {
+		t.Fatal("expected 1 persisted set, got", n)
+	}
+
+	// Once the transactions are confirmed, their set is removed from the
+	// database.
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if n := numPersistedSets(); n != 0 {
+		t.Fatal("expected no persisted sets, got", n)
+	}
+}
@@ -0,0 +1,225 @@
+package transactionpool
+
+import (
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+var (
+	errReplacementLowFees  = errors.New("transaction set doesn't pay enough fees to replace the conflicting transaction sets")
+	errReplaceStorageProof = errors.New("transaction sets containing storage proofs can't be replaced")
+	errTooManyReplacements = errors.New("transaction set conflicts with too many transaction sets to replace them")
+)
+
+// removedTransactionSet contains the state of a transaction set that was
+// removed from the pool, which is needed to restore it.
+type removedTransactionSet struct {
+	diff         *modules.ConsensusChange
+	knownObjects []ObjectID
+	set          []types.Transaction
+}
+
+// doubleSpentSets returns the ids of the transaction sets in the pool which
+// contain a transaction that spends an output that is also spent by a
+// different transaction of ts.
+func (tp *TransactionPool) doubleSpentSets(ts []types.Transaction) []modules.TransactionSetID {
+	txnIDs := make(map[types.TransactionID]struct{})
+	for _, txn := range ts {
+		txnIDs[txn.ID()] = struct{}{}
+	}
+	spent := make(map[ObjectID]struct{})
+	for _, txn := range ts {
+		for _, sci := range txn.UplocoinInputs {
+			spent[ObjectID(sci.ParentID)] = struct{}{}
+		}
+		for _, sfi := range txn.UplofundInputs {
+			spent[ObjectID(sfi.ParentID)] = struct{}{}
+		}
+	}
+
+	setMap := make(map[modules.TransactionSetID]struct{})
+	for oid := range spent {
+		setID, exists := tp.knownObjects[oid]
+		if !exists {
+			continue
+		}
+		for _, txn := range tp.transactionSets[setID] {
+			if _, exists := txnIDs[txn.ID()]; exists {
+				continue
+			}
+			for _, sci := range txn.UplocoinInputs {
+				if ObjectID(sci.ParentID) == oid {
+					setMap[setID] = struct{}{}
+				}
+			}
+			for _, sfi := range txn.UplofundInputs {
+				if ObjectID(sfi.ParentID) == oid {
+					setMap[setID] = struct{}{}
+				}
+			}
+		}
+	}
+	var setIDs []modules.TransactionSetID
+	for setID := range setMap {
+		setIDs = append(setIDs, setID)
+	}
+	return setIDs
+}
+
+// descendantSets returns the ids of the transaction sets in the pool which
+// depend on the given sets, directly or through other sets. These sets would
+// spend outputs that no longer exist if the given sets were removed.
+func (tp *TransactionPool) descendantSets(setIDs []modules.TransactionSetID) []modules.TransactionSetID {
+	visited := make(map[modules.TransactionSetID]struct{})
+	created := make(map[ObjectID]struct{})
+	addCreated := func(set []types.Transaction) {
+		for _, txn := range set {
+			for i := range txn.UplocoinOutputs {
+				created[ObjectID(txn.UplocoinOutputID(uint64(i)))] = struct{}{}
+			}
+			for i := range txn.UplofundOutputs {
+				created[ObjectID(txn.UplofundOutputID(uint64(i)))] = struct{}{}
+			}
+			for i := range txn.FileContracts {
+				created[ObjectID(txn.FileContractID(uint64(i)))] = struct{}{}
+			}
+		}
+	}
+	spendsCreated := func(set []types.Transaction) bool {
+		for _, txn := range set {
+			for _, sci := range txn.UplocoinInputs {
+				if _, exists := created[ObjectID(sci.ParentID)]; exists {
+					return true
+				}
+			}
+			for _, sfi := range txn.UplofundInputs {
+				if _, exists := created[ObjectID(sfi.ParentID)]; exists {
+					return true
+				}
+			}
+			for _, fcr := range txn.FileContractRevisions {
+				if _, exists := created[ObjectID(fcr.ParentID)]; exists {
+					return true
+				}
+			}
+			for _, sp := range txn.StorageProofs {
+				if _, exists := created[ObjectID(sp.ParentID)]; exists {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, setID := range setIDs {
+		visited[setID] = struct{}{}
+		addCreated(tp.transactionSets[setID])
+	}
+
+	// Keep going until no more descendants are found, since a descendant
+	// might have descendants of its own.
+	var descendants []modules.TransactionSetID
+	for found := true; found; {
+		found = false
+		for setID, set := range tp.transactionSets {
+			if _, exists := visited[setID]; exists {
+				continue
+			}
+			if !spendsCreated(set) {
+				continue
+			}
+			visited[setID] = struct{}{}
+			addCreated(set)
+			descendants = append(descendants, setID)
+			found = true
+		}
+	}
+	return descendants
+}
+
+// removeTransactionSet removes a transaction set from the pool and returns the
+// state needed to restore it.
+func (tp *TransactionPool) removeTransactionSet(setID modules.TransactionSetID) removedTransactionSet {
+	rts := removedTransactionSet{
+		diff: tp.transactionSetDiffs[setID],
+		set:  tp.transactionSets[setID],
+	}
+	for oid, id := range tp.knownObjects {
+		if id == setID {
+			rts.knownObjects = append(rts.knownObjects, oid)
+			delete(tp.knownObjects, oid)
+		}
+	}
+	tp.transactionListSize -= len(encoding.Marshal(rts.set))
+	delete(tp.transactionSets, setID)
+	delete(tp.transactionSetDiffs, setID)
+	tp.unpersistedSets[setID] = struct{}{}
+	return rts
+}
+
+// replaceTransactionSets replaces transaction sets of the pool with a
+// transaction set that double-spends them. The sets depending on the replaced
+// sets are evicted as well. The replacement needs to pay more fees than all of
+// the evicted sets together, and an additional fee for its own size. If the
+// replacement is not accepted, the evicted sets are restored.
+func (tp *TransactionPool) replaceTransactionSets(ts []types.Transaction, replaced []modules.TransactionSetID, txnFn func([]types.Transaction) (modules.ConsensusChange, error)) ([]types.Transaction, error) {
+	replaced = append(replaced, tp.descendantSets(replaced)...)
+	if len(replaced) > maxReplacedSets {
+		return nil, errTooManyReplacements
+	}
+
+	// Sum up the fees of the replaced sets. Storage proofs are never replaced
+	// since they can only be created by the host of the contract.
+	var replacedFees types.Currency
+	for _, setID := range replaced {
+		for _, txn := range tp.transactionSets[setID] {
+			if len(txn.StorageProofs) > 0 {
+				return nil, errReplaceStorageProof
+			}
+			for _, fee := range txn.MinerFees {
+				replacedFees = replacedFees.Add(fee)
+			}
+		}
+	}
+	var setFees types.Currency
+	for _, txn := range ts {
+		for _, fee := range txn.MinerFees {
+			setFees = setFees.Add(fee)
+		}
+	}
+	setSize := uint64(len(encoding.Marshal(ts)))
+	requiredFees := replacedFees.Add(minReplacementFeeIncrease.Mul64(setSize))
+	if requiredFees.Cmp(setFees) > 0 {
+		tp.log.Debugln("Replacement transaction set was rejected for having low fees", requiredFees, setFees)
+		return nil, errReplacementLowFees
+	}
+
+	// Remove the replaced sets and try to accept the replacement.
+	removed := make(map[modules.TransactionSetID]removedTransactionSet)
+	for _, setID := range replaced {
+		removed[setID] = tp.removeTransactionSet(setID)
+	}
+	superset, err := tp.acceptTransactionSet(ts, txnFn)
+	if err != nil {
+		tp.restoreTransactionSets(removed)
+		return nil, err
+	}
+	tp.log.Debugf("replaced %v transaction sets with a transaction set paying %v in fees\n", len(replaced), setFees)
+	return superset, nil
+}
+
+// restoreTransactionSets adds transaction sets that were removed by
+// removeTransactionSet back to the pool.
+func (tp *TransactionPool) restoreTransactionSets(removed map[modules.TransactionSetID]removedTransactionSet) {
+	for setID, rts := range removed {
+		tp.transactionSets[setID] = rts.set
+		tp.transactionSetDiffs[setID] = rts.diff
+		tp.unpersistedSets[setID] = struct{}{}
+		for _, oid := range rts.knownObjects {
+			tp.knownObjects[oid] = setID
+		}
+		tp.transactionListSize += len(encoding.Marshal(rts.set))
+	}
+}
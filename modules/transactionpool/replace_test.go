@@ -0,0 +1,153 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestReplaceTransactionSet probes replacing transaction sets in the pool with
+// transaction sets that double-spend them and pay higher fees.
+func TestReplaceTransactionSet(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := tpt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Fund a partial transaction.
+	fund := types.UplocoinPrecision.Mul64(100)
+	txnBuilder, err := tpt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = txnBuilder.FundUplocoins(fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wholeTransaction is set to false so that the same signature can be used
+	// to create the replacements.
+	txnSet, err := txnBuilder.Sign(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txnIndex := len(txnSet) - 1
+	copySet := func() []types.Transaction {
+		set := make([]types.Transaction, len(txnSet))
+		copy(set, txnSet)
+		return set
+	}
+
+	// Create three versions of the transaction which spend the same output.
+	// The first one pays no fee, the second one a small fee and the third one
+	// spends the whole output on fees.
+	lowFee := types.UplocoinPrecision.Div64(1e6)
+	original := copySet()
+	original[txnIndex].UplocoinOutputs = append(original[txnIndex].UplocoinOutputs, types.UplocoinOutput{Value: fund})
+	lowFeeReplacement := copySet()
+	lowFeeReplacement[txnIndex].UplocoinOutputs = append(lowFeeReplacement[txnIndex].UplocoinOutputs, types.UplocoinOutput{Value: fund.Sub(lowFee)})
+	lowFeeReplacement[txnIndex].MinerFees = append(lowFeeReplacement[txnIndex].MinerFees, lowFee)
+	replacement := copySet()
+	replacement[txnIndex].MinerFees = append(replacement[txnIndex].MinerFees, fund)
+
+	// Add the original set.
+	if err := tpt.tpool.AcceptTransactionSet(original); err != nil {
+		t.Fatal(err)
+	}
+
+	// The replacement with the low fee should be rejected and the original set
+	// should still be in the pool.
+	err = tpt.tpool.AcceptTransactionSet(lowFeeReplacement)
+	if !errors.Contains(err, errReplacementLowFees) {
+		t.Fatal("expected errReplacementLowFees", err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(original[txnIndex].ID()); !exists {
+		t.Fatal("original transaction was removed from the pool")
+	}
+
+	// The replacement with the high fee should replace the original set.
+	if err := tpt.tpool.AcceptTransactionSet(replacement); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(original[txnIndex].ID()); exists {
+		t.Fatal("original transaction wasn't removed from the pool")
+	}
+	if _, _, exists := tpt.tpool.Transaction(replacement[txnIndex].ID()); !exists {
+		t.Fatal("replacement transaction isn't in the pool")
+	}
+
+	// The original set can't replace the replacement.
+	err = tpt.tpool.AcceptTransactionSet(original)
+	if !errors.Contains(err, errReplacementLowFees) {
+		t.Fatal("expected errReplacementLowFees", err)
+	}
+
+	// The replacement should be mined.
+	block, _ := tpt.miner.FindBlock()
+	if err := tpt.cs.AcceptBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	confirmed, err := tpt.tpool.TransactionConfirmed(replacement[txnIndex].ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !confirmed {
+		t.Fatal("replacement wasn't confirmed")
+	}
+}
+
+// TestDescendantSets checks that the sets depending on a set are found,
+// including indirect descendants.
+func TestDescendantSets(t *testing.T) {
+	parent := types.Transaction{
+		UplocoinOutputs: []types.UplocoinOutput{{Value: types.NewCurrency64(1)}},
+	}
+	child := types.Transaction{
+		UplocoinInputs:  []types.UplocoinInput{{ParentID: parent.UplocoinOutputID(0)}},
+		UplocoinOutputs: []types.UplocoinOutput{{Value: types.NewCurrency64(1)}},
+	}
+	grandchild := types.Transaction{
+		UplocoinInputs: []types.UplocoinInput{{ParentID: child.UplocoinOutputID(0)}},
+	}
+	unrelated := types.Transaction{
+		UplocoinInputs: []types.UplocoinInput{{ParentID: types.UplocoinOutputID{1}}},
+	}
+	tp := &TransactionPool{
+		transactionSets: map[modules.TransactionSetID][]types.Transaction{
+			{1}: {parent},
+			{2}: {grandchild},
+			{3}: {child},
+			{4}: {unrelated},
+		},
+	}
+
+	descendants := tp.descendantSets([]modules.TransactionSetID{{1}})
+	if len(descendants) != 2 {
+		t.Fatal("expected 2 descendants, got", descendants)
+	}
+	found := make(map[modules.TransactionSetID]struct{})
+	for _, setID := range descendants {
+		found[setID] = struct{}{}
+	}
+	if _, exists := found[modules.TransactionSetID{2}]; !exists {
+		t.Fatal("grandchild wasn't found")
+	}
+	if _, exists := found[modules.TransactionSetID{3}]; !exists {
+		t.Fatal("child wasn't found")
+	}
+
+	// Sets without descendants have none.
+	if descendants := tp.descendantSets([]modules.TransactionSetID{{4}}); len(descendants) != 0 {
+		t.Fatal("expected no descendants, got", descendants)
+	}
+}
@@ -55,6 +55,11 @@ type (
 		transactionSetDiffs map[modules.TransactionSetID]*modules.ConsensusChange
 		transactionListSize int
 
+		// unpersistedSets contains the ids of the transaction sets that were
+		// added to or removed from the pool since the transaction sets were
+		// last persisted.
+		unpersistedSets map[modules.TransactionSetID]struct{}
+
 		// Variables related to the blockchain.
 		blockHeight     types.BlockHeight
 		recentMedians   []types.Currency
@@ -105,6 +110,7 @@ func NewCustomTPool(cs modules.ConsensusSet, g modules.Gateway, persistDir strin
 		transactionHeights:  make(map[types.TransactionID]types.BlockHeight),
 		transactionSets:     make(map[modules.TransactionSetID][]types.Transaction),
 		transactionSetDiffs: make(map[modules.TransactionSetID]*modules.ConsensusChange),
+		unpersistedSets:     make(map[modules.TransactionSetID]struct{}),
 
 		deps:       deps,
 		persistDir: persistDir,
@@ -164,6 +164,9 @@ func findSets(ts []types.Transaction) [][]types.Transaction {
 
 // purge removes all transactions from the transaction pool.
 func (tp *TransactionPool) purge() {
+	for setID := range tp.transactionSets {
+		tp.unpersistedSets[setID] = struct{}{}
+	}
 	tp.knownObjects = make(map[ObjectID]modules.TransactionSetID)
 	tp.transactionSets = make(map[modules.TransactionSetID][]types.Transaction)
 	tp.transactionSetDiffs = make(map[modules.TransactionSetID]*modules.ConsensusChange)
@@ -441,6 +444,11 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// not.
 	tp.log.Debugln("A new block has been found. After processing, the transaction pool has dropped from a size of", oldTxnListSize, "to a size of", tp.transactionListSize, "taking", time.Since(addTransactionsBackTime).Round(time.Millisecond), "milliseconds")
 
+	// Persist the transaction sets that remain in the pool.
+	if err := tp.putTransactionSets(tp.dbTx); err != nil {
+		tp.log.Println("WARN: unable to persist transaction sets:", err)
+	}
+
 	// Inform subscribers that an update has executed.
 	tp.mu.Demote()
 	tp.updateSubscribersTransactions()
@@ -451,5 +459,8 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 func (tp *TransactionPool) PurgeTransactionPool() {
 	tp.mu.Lock()
 	tp.purge()
+	if err := tp.putTransactionSets(tp.dbTx); err != nil {
+		tp.log.Println("WARN: unable to persist transaction sets:", err)
+	}
 	tp.mu.Unlock()
 }
@@ -3,6 +3,7 @@ package modules
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/uplo-tech/ratelimit"
@@ -19,6 +20,13 @@ type (
 		WriteBPS           int64  `json:"writebps"`
 		PacketSize         uint64 `json:"packetsize"`
 
+		// Proxy related fields
+		ProxyAddress string `json:"proxyaddress"`
+		ProxyOnly    bool   `json:"proxyonly"`
+
+		// Webhooks
+		Webhooks []Webhook `json:"webhooks"`
+
 		// path of config on disk.
 		path string
 		mu   sync.Mutex
@@ -59,6 +67,66 @@ func (cfg *UplodConfig) SetRatelimit(readBPS, writeBPS int64) error {
 	return cfg.save()
 }
 
+// SetProxy sets the proxy related fields in the config and persists it to
+// disk.
+func (cfg *UplodConfig) SetProxy(address string, proxyOnly bool) error {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	if err := GlobalDialer.SetProxy(address, proxyOnly); err != nil {
+		return err
+	}
+	// Persist settings.
+	cfg.ProxyAddress, cfg.ProxyOnly = GlobalDialer.Settings()
+	return cfg.save()
+}
+
+// AddWebhook adds a webhook to the config and persists it to disk.
+func (cfg *UplodConfig) AddWebhook(wh Webhook) error {
+	if err := wh.Validate(); err != nil {
+		return err
+	}
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for _, existing := range cfg.Webhooks {
+		if existing.Name == wh.Name {
+			return errors.New("webhook with that name already exists")
+		}
+	}
+	cfg.Webhooks = append(cfg.Webhooks, wh)
+	if err := cfg.save(); err != nil {
+		cfg.Webhooks = cfg.Webhooks[:len(cfg.Webhooks)-1]
+		return err
+	}
+	return nil
+}
+
+// RemoveWebhook removes the webhook with the given name from the config and
+// persists it to disk.
+func (cfg *UplodConfig) RemoveWebhook(name string) error {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for i, wh := range cfg.Webhooks {
+		if wh.Name != name {
+			continue
+		}
+		cfg.Webhooks = append(cfg.Webhooks[:i:i], cfg.Webhooks[i+1:]...)
+		return cfg.save()
+	}
+	return ErrWebhookNotFound
+}
+
+// GetWebhooks returns the webhooks of the config.
+func (cfg *UplodConfig) GetWebhooks() []Webhook {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	return append([]Webhook{}, cfg.Webhooks...)
+}
+
+// Dir returns the directory of the config, which is the uplod directory.
+func (cfg *UplodConfig) Dir() string {
+	return filepath.Dir(cfg.path)
+}
+
 // save saves the config to disk.
 func (cfg *UplodConfig) save() error {
 	return persist.SaveJSON(configMetadata, cfg, cfg.path)
@@ -99,5 +167,9 @@ func NewConfig(path string) (*UplodConfig, error) {
 	}
 	// Init the global ratelimit.
 	GlobalRateLimits.SetLimits(cfg.ReadBPS, cfg.WriteBPS, cfg.PacketSize)
+	// Init the global dialer.
+	if err := GlobalDialer.SetProxy(cfg.ProxyAddress, cfg.ProxyOnly); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
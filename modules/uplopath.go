@@ -57,11 +57,19 @@ var (
 	// default.
 	SkynetFolder = NewGlobalUploPath("/var/skynet")
 
+	// TrashFolder is the Uplo folder where deleted files and directories are
+	// kept until the trash retention of the renter expires.
+	TrashFolder = NewGlobalUploPath("/trash")
+
 	// UserFolder is the Uplo folder that is used to store the renter's uplofiles.
 	UserFolder = NewGlobalUploPath("/home/user")
 
 	// VarFolder is the Uplo folder that contains the skynet folder.
 	VarFolder = NewGlobalUploPath("/var")
+
+	// VersionsFolder is the Uplo folder where the previous versions of files
+	// in uplodirs with versioning enabled are stored.
+	VersionsFolder = NewGlobalUploPath("/var/versions")
 )
 
 type (
@@ -25,6 +25,62 @@ const (
 
 	// WalletDir is the directory that contains the wallet persistence.
 	WalletDir = "wallet"
+
+	// DefaultWalletAccount is the name of the account of the wallet that owns
+	// all addresses which don't belong to a sub-account. This includes the
+	// addresses of the primary seed, of loaded seeds and of unseeded keys.
+	DefaultWalletAccount = "default"
+)
+
+// The following consts are the types of objects that can be labeled in the
+// wallet.
+const (
+	// WalletLabelAddress is the type of the label of an address.
+	WalletLabelAddress WalletLabelType = "address"
+	// WalletLabelOutput is the type of the label of an output.
+	WalletLabelOutput WalletLabelType = "output"
+	// WalletLabelTransaction is the type of the label of a transaction.
+	WalletLabelTransaction WalletLabelType = "transaction"
+)
+
+// The following consts are the states of a payment that was sent for a
+// scheduled payment.
+const (
+	// ScheduledPaymentPending is the status of a payment that wasn't
+	// confirmed yet.
+	ScheduledPaymentPending ScheduledPaymentStatus = "pending"
+	// ScheduledPaymentConfirmed is the status of a confirmed payment.
+	ScheduledPaymentConfirmed ScheduledPaymentStatus = "confirmed"
+	// ScheduledPaymentStuck is the status of an unconfirmed payment that the
+	// transaction pool rejected for a reason which rebroadcasting it won't
+	// fix, e.g. because it's invalid or too large.
+	ScheduledPaymentStuck ScheduledPaymentStatus = "stuck"
+)
+
+// The following consts are the categories of the entries of a wallet history
+// export.
+const (
+	// WalletExportMinerPayout is the category of miner payouts to the wallet.
+	WalletExportMinerPayout WalletExportCategory = "minerpayout"
+	// WalletExportContractFormation is the category of funds the wallet
+	// spends on forming file contracts.
+	WalletExportContractFormation WalletExportCategory = "contractformation"
+	// WalletExportStorageProof is the category of storage proof payouts of
+	// file contracts to the wallet.
+	WalletExportStorageProof WalletExportCategory = "storageproof"
+	// WalletExportMissedProof is the category of the missed proof payouts of
+	// file contracts to the wallet.
+	WalletExportMissedProof WalletExportCategory = "missedproof"
+	// WalletExportFee is the category of miner fees paid by the wallet.
+	WalletExportFee WalletExportCategory = "fee"
+	// WalletExportUplofundClaim is the category of Uplocoins claimed by
+	// spending uplofunds of the wallet.
+	WalletExportUplofundClaim WalletExportCategory = "uplofundclaim"
+	// WalletExportSent is the category of Uplocoins sent to other addresses.
+	WalletExportSent WalletExportCategory = "sent"
+	// WalletExportReceived is the category of Uplocoins received from other
+	// addresses.
+	WalletExportReceived WalletExportCategory = "received"
 )
 
 var (
@@ -49,6 +105,17 @@ var (
 	// ErrWalletShutdown is returned when a method can't continue execution due
 	// to the wallet shutting down.
 	ErrWalletShutdown = errors.New("wallet is shutting down")
+
+	// ErrUnknownOutput is returned when an output is not an unspent Uplocoin
+	// output of the wallet.
+	ErrUnknownOutput = errors.New("output is not an unspent Uplocoin output of the wallet")
+
+	// ErrUnknownAccount is returned when a wallet account doesn't exist.
+	ErrUnknownAccount = errors.New("wallet account does not exist")
+
+	// ErrUnknownScheduledPayment is returned when a scheduled payment doesn't
+	// exist.
+	ErrUnknownScheduledPayment = errors.New("scheduled payment does not exist")
 )
 
 type (
@@ -126,6 +193,97 @@ type (
 		Value              types.Currency    `json:"value"`
 		ConfirmationHeight types.BlockHeight `json:"confirmationheight"`
 		IsWatchOnly        bool              `json:"iswatchonly"`
+		Frozen             bool              `json:"frozen"`
+		Label              string            `json:"label"`
+		Account            string            `json:"account"`
+	}
+
+	// WalletAccount is an account of the wallet. Every sub-account derives
+	// its addresses from its own subseed of the primary seed, so its balance
+	// is kept separate from the other accounts. Modules lists the modules
+	// that are pinned to the account.
+	WalletAccount struct {
+		Name                         string         `json:"name"`
+		ConfirmedUplocoinBalance     types.Currency `json:"confirmedUplocoinbalance"`
+		UnconfirmedOutgoingUplocoins types.Currency `json:"unconfirmedoutgoingUplocoins"`
+		UnconfirmedIncomingUplocoins types.Currency `json:"unconfirmedincomingUplocoins"`
+		UplofundBalance              types.Currency `json:"uplofundbalance"`
+		UplofundClaimBalance         types.Currency `json:"uplofundclaimbalance"`
+		Modules                      []string       `json:"modules"`
+	}
+
+	// WalletExportCategory is the category of an entry of a wallet history
+	// export.
+	WalletExportCategory string
+
+	// WalletExportEntry is a single inflow or outflow of Uplocoins in a
+	// wallet history export. Balance is the running Uplocoin balance of the
+	// wallet after the entry, which is negative if BalanceNegative is set. The
+	// fiat values are only set if the export was requested with an exchange
+	// rate that applies at the timestamp of the entry.
+	WalletExportEntry struct {
+		TransactionID   types.TransactionID  `json:"transactionid"`
+		Height          types.BlockHeight    `json:"height"`
+		Timestamp       types.Timestamp      `json:"timestamp"`
+		Category        WalletExportCategory `json:"category"`
+		Inflow          types.Currency       `json:"inflow"`
+		Outflow         types.Currency       `json:"outflow"`
+		Balance         types.Currency       `json:"balance"`
+		BalanceNegative bool                 `json:"balancenegative,omitempty"`
+
+		FiatInflow  string `json:"fiatinflow,omitempty"`
+		FiatOutflow string `json:"fiatoutflow,omitempty"`
+		FiatBalance string `json:"fiatbalance,omitempty"`
+	}
+
+	// ScheduledPayment is a payment that the wallet sends automatically once
+	// the blockchain reaches StartHeight, and then every Interval blocks. A
+	// payment with an Interval of 0 is only sent once. Otherwise Count limits
+	// the number of payments, or they continue indefinitely if Count is 0.
+	// The payments are funded by the given account of the wallet. StartHeight
+	// can't be in the past.
+	//
+	// NextHeight, Payments and LastError are reported by the wallet.
+	ScheduledPayment struct {
+		Name        string            `json:"name"`
+		Destination types.UnlockHash  `json:"destination"`
+		Amount      types.Currency    `json:"amount"`
+		Account     string            `json:"account"`
+		StartHeight types.BlockHeight `json:"startheight"`
+		Interval    types.BlockHeight `json:"interval"`
+		Count       uint64            `json:"count"`
+
+		NextHeight types.BlockHeight        `json:"nextheight"`
+		Payments   []ScheduledPaymentRecord `json:"payments"`
+		LastError  string                   `json:"lasterror"`
+	}
+
+	// ScheduledPaymentRecord is a payment that was sent for a scheduled
+	// payment. Height is the height the payment was scheduled for. Error is
+	// the reason why a stuck payment was rejected.
+	ScheduledPaymentRecord struct {
+		Height             types.BlockHeight      `json:"height"`
+		TransactionID      types.TransactionID    `json:"transactionid"`
+		Confirmed          bool                   `json:"confirmed"`
+		ConfirmationHeight types.BlockHeight      `json:"confirmationheight"`
+		Status             ScheduledPaymentStatus `json:"status"`
+		Error              string                 `json:"error,omitempty"`
+	}
+
+	// ScheduledPaymentStatus is the status of a payment that was sent for a
+	// scheduled payment.
+	ScheduledPaymentStatus string
+
+	// WalletLabelType is the type of the object a wallet label is attached to.
+	WalletLabelType string
+
+	// WalletLabel is a user label of an address, output or transaction of the
+	// wallet. The ID is the string representation of the UnlockHash, OutputID
+	// or TransactionID of the labeled object.
+	WalletLabel struct {
+		Type  WalletLabelType `json:"type"`
+		ID    string          `json:"id"`
+		Label string          `json:"label"`
 	}
 
 	// TransactionBuilder is used to construct custom transactions. A transaction
@@ -148,6 +306,12 @@ type (
 		// transaction failed.
 		FundUplocoins(amount types.Currency) error
 
+		// FundUplocoinsFromOutputs works like FundUplocoins, but only spends
+		// the given outputs of the wallet. All of the outputs are spent, even
+		// if fewer would cover the amount, and the remainder is refunded to
+		// the wallet. Frozen outputs can be spent this way.
+		FundUplocoinsFromOutputs(amount types.Currency, outputs []types.UplocoinOutputID) error
+
 		// FundUplofunds will add a uplofund input of exactly 'amount' to the
 		// transaction. A parent transaction may be needed to achieve an input
 		// with the correct value. The uplofund input will not be signed until
@@ -409,6 +573,7 @@ type (
 	// derived from a single address seed.
 	Wallet interface {
 		Alerter
+		EventSubscriber
 		EncryptionManager
 		KeyManager
 
@@ -422,9 +587,22 @@ type (
 		// the blockchain to search for transactions containing the addresses.
 		AddWatchAddresses(addrs []types.UnlockHash, unused bool) error
 
+		// Accounts returns the accounts of the wallet, starting with the
+		// default account.
+		Accounts() ([]WalletAccount, error)
+
 		// Close permits clean shutdown during testing and serving.
 		Close() error
 
+		// AddScheduledPayment adds a payment that the wallet sends
+		// automatically at the scheduled heights.
+		AddScheduledPayment(sp ScheduledPayment) error
+
+		// CreateAccount creates a new sub-account of the wallet. The
+		// addresses of the account are derived from a subseed of the primary
+		// seed.
+		CreateAccount(name string) error
+
 		// ConfirmedBalance returns the confirmed balance of the wallet, minus
 		// any outgoing transactions. ConfirmedBalance will include unconfirmed
 		// refund transactions.
@@ -458,6 +636,11 @@ type (
 		// included.
 		Transactions(startHeight types.BlockHeight, endHeight types.BlockHeight) ([]ProcessedTransaction, error)
 
+		// ExportEntries returns the Uplocoin inflows and outflows of the full
+		// history of the wallet for accounting, along with the running
+		// balance after each entry.
+		ExportEntries() ([]WalletExportEntry, error)
+
 		// UnconfirmedTransactions returns all unconfirmed transactions
 		// relative to the wallet.
 		UnconfirmedTransactions() ([]ProcessedTransaction, error)
@@ -466,6 +649,18 @@ type (
 		// a TransactionBuilder which can be used to expand the transaction.
 		RegisterTransaction(t types.Transaction, parents []types.Transaction) (TransactionBuilder, error)
 
+		// RegisterAccountTransaction works like RegisterTransaction, but the
+		// returned TransactionBuilder only spends outputs of the given
+		// account and sends change back to the account.
+		RegisterAccountTransaction(account string, t types.Transaction, parents []types.Transaction) (TransactionBuilder, error)
+
+		// ModuleAccounts returns the accounts that modules are pinned to,
+		// mapped by module name.
+		ModuleAccounts() (map[string]string, error)
+
+		// NextAccountAddresses returns n new addresses of an account.
+		NextAccountAddresses(account string, n uint64) ([]types.UnlockConditions, error)
+
 		// RemoveWatchAddresses instructs the wallet to stop tracking a set of
 		// addresses and delete their associated transactions. If none of the
 		// addresses have appeared in the blockchain, the unused flag may be
@@ -473,20 +668,35 @@ type (
 		// rebuild its transaction history.
 		RemoveWatchAddresses(addrs []types.UnlockHash, unused bool) error
 
+		// RemoveScheduledPayment stops and removes a scheduled payment.
+		RemoveScheduledPayment(name string) error
+
 		// Rescanning reports whether the wallet is currently rescanning the
 		// blockchain.
 		Rescanning() (bool, error)
 
+		// ScheduledPayments returns the scheduled payments of the wallet.
+		ScheduledPayments() ([]ScheduledPayment, error)
+
 		// Settings returns the Wallet's current settings.
 		Settings() (WalletSettings, error)
 
 		// SetSettings sets the Wallet's settings.
 		SetSettings(WalletSettings) error
 
+		// SetModuleAccount pins a module to an account. The transactions of
+		// the module are funded by the account and its payouts are sent to
+		// addresses of the account. The default account unpins the module.
+		SetModuleAccount(module, account string) error
+
 		// StartTransaction is a convenience method that calls
 		// RegisterTransaction(types.Transaction{}, nil)
 		StartTransaction() (TransactionBuilder, error)
 
+		// StartAccountTransaction is a convenience method that calls
+		// RegisterAccountTransaction(account, types.Transaction{}, nil)
+		StartAccountTransaction(account string) (TransactionBuilder, error)
+
 		// SendUplocoins is a tool for sending Uplocoins from the wallet to an
 		// address. Sending money usually results in multiple transactions. The
 		// transactions are automatically given to the transaction pool, and are
@@ -499,6 +709,29 @@ type (
 		// SendUplocoinsMulti sends coins to multiple addresses.
 		SendUplocoinsMulti(outputs []types.UplocoinOutput) ([]types.Transaction, error)
 
+		// SendUplocoinsFromAccount works like SendUplocoins, but only spends
+		// outputs of the given account.
+		SendUplocoinsFromAccount(account string, amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error)
+
+		// SendUplocoinsMultiFromAccount works like SendUplocoinsMulti, but
+		// only spends outputs of the given account.
+		SendUplocoinsMultiFromAccount(account string, outputs []types.UplocoinOutput) ([]types.Transaction, error)
+
+		// SendUplocoinsFromOutputs works like SendUplocoins, but only spends
+		// the given outputs of the wallet.
+		SendUplocoinsFromOutputs(amount types.Currency, dest types.UnlockHash, inputs []types.UplocoinOutputID) ([]types.Transaction, error)
+
+		// SendUplocoinsMultiFromOutputs works like SendUplocoinsMulti, but
+		// only spends the given outputs of the wallet.
+		SendUplocoinsMultiFromOutputs(outputs []types.UplocoinOutput, inputs []types.UplocoinOutputID) ([]types.Transaction, error)
+
+		// SendUplocoinsTimelocked sends Uplocoins to a time-locked version of
+		// an address whose unlock conditions are known to the wallet. The
+		// sent coins can't be spent before the blockchain reaches the
+		// timelock height. The time-locked unlock conditions are returned
+		// along with the transactions.
+		SendUplocoinsTimelocked(amount types.Currency, dest types.UnlockHash, timelock types.BlockHeight) ([]types.Transaction, types.UnlockConditions, error)
+
 		// SendUplofunds is a tool for sending uplofunds from the wallet to an
 		// address. Sending money usually results in multiple transactions. The
 		// transactions are automatically given to the transaction pool, and
@@ -509,9 +742,32 @@ type (
 		// considered to be Dust.
 		DustThreshold() (types.Currency, error)
 
+		// BumpTransaction increases the fees of an unconfirmed transaction
+		// by either replacing it or by spending its change output in a
+		// transaction with a higher fee. The submitted transactions are
+		// returned.
+		BumpTransaction(txid types.TransactionID) ([]types.Transaction, error)
+
 		// UnspentOutputs returns the unspent outputs tracked by the wallet.
 		UnspentOutputs() ([]UnspentOutput, error)
 
+		// FreezeOutputs marks Uplocoin outputs of the wallet as frozen. Frozen
+		// outputs are never selected automatically to fund transactions, so
+		// they can only be spent by explicitly selecting them.
+		FreezeOutputs(ids []types.UplocoinOutputID) error
+
+		// UnfreezeOutputs makes frozen outputs available for funding
+		// transactions again.
+		UnfreezeOutputs(ids []types.UplocoinOutputID) error
+
+		// Labels returns the labels of the wallet.
+		Labels() ([]WalletLabel, error)
+
+		// SetLabel sets the label of an address, output or transaction. The
+		// id is the string representation of the labeled object. An empty
+		// label removes the label.
+		SetLabel(labelType WalletLabelType, id string, label string) error
+
 		// UnlockConditions returns the UnlockConditions for the specified
 		// address, if they are known to the wallet.
 		UnlockConditions(addr types.UnlockHash) (types.UnlockConditions, error)
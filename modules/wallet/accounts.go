@@ -0,0 +1,594 @@
+package wallet
+
+import (
+	"github.com/uplo-tech/bolt"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// maxAccountNameLength is the maximum length of the name of an account.
+const maxAccountNameLength = 64
+
+var (
+	// accountLookahead is the number of unused addresses of every sub-account
+	// that the wallet tracks. Outputs sent to these addresses are detected
+	// even if the addresses were generated by a different wallet using the
+	// same seed.
+	accountLookahead = build.Select(build.Var{
+		Dev:      uint64(100),
+		Standard: uint64(1000),
+		Testing:  uint64(10),
+	}).(uint64)
+
+	// accountSeedSpecifier is used to derive the subseeds of the accounts from
+	// the primary seed.
+	accountSeedSpecifier = types.NewSpecifier("WalletAccount")
+
+	// pinnableModules are the modules that can be pinned to an account.
+	pinnableModules = []string{
+		modules.FeeManagerDir,
+		modules.HostDir,
+		modules.RenterDir,
+	}
+)
+
+var (
+	// errAccountExists is returned when an account is created with the name
+	// of an existing account.
+	errAccountExists = errors.New("account already exists")
+
+	// errInvalidAccountName is returned when an account is created with an
+	// invalid name.
+	errInvalidAccountName = errors.New("account name must be 1-64 characters long and only contain letters, digits, '-' and '_'")
+
+	// errUnpinnableModule is returned when a module that can't be pinned to an
+	// account is pinned.
+	errUnpinnableModule = errors.New("only the feemanager, host and renter can be pinned to an account")
+)
+
+type (
+	// walletAccount is the persisted state of a sub-account. Index is the
+	// index of the subseed of the account and Progress is the number of
+	// addresses that were generated from the subseed.
+	walletAccount struct {
+		Index    uint64
+		Progress uint64
+	}
+
+	// accountKey identifies the account and the index of a key that was
+	// derived from the subseed of a sub-account.
+	accountKey struct {
+		account string
+		index   uint64
+	}
+
+	// moduleWallet is the wallet of a module. The transactions of the module
+	// are funded from the account that the module is pinned to, and the
+	// addresses of the module are generated by that account.
+	moduleWallet struct {
+		modules.Wallet
+		staticModule string
+	}
+)
+
+// NewModuleWallet returns a wallet for a module that uses the account the
+// module is pinned to.
+func NewModuleWallet(w modules.Wallet, module string) modules.Wallet {
+	return &moduleWallet{
+		Wallet:       w,
+		staticModule: module,
+	}
+}
+
+// accountSeed derives the subseed of the account with the given index from the
+// primary seed.
+func accountSeed(primarySeed modules.Seed, index uint64) (seed modules.Seed) {
+	h := crypto.HashAll(primarySeed, accountSeedSpecifier, index)
+	copy(seed[:], h[:])
+	return seed
+}
+
+// validateAccountName checks that a name can be used for a new account.
+func validateAccountName(name string) error {
+	if name == modules.DefaultWalletAccount {
+		return errAccountExists
+	}
+	if name == "" || len(name) > maxAccountNameLength {
+		return errInvalidAccountName
+	}
+	for _, r := range name {
+		valid := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
+		if !valid {
+			return errInvalidAccountName
+		}
+	}
+	return nil
+}
+
+// addressAccount returns the account that owns an address of the wallet.
+func (w *Wallet) addressAccount(uh types.UnlockHash) string {
+	if ak, ok := w.accountKeys[uh]; ok {
+		return ak.account
+	}
+	return modules.DefaultWalletAccount
+}
+
+// checkAccount returns ErrUnknownAccount if the account doesn't exist.
+func (w *Wallet) checkAccount(tx *bolt.Tx, account string) error {
+	if account == modules.DefaultWalletAccount {
+		return nil
+	}
+	_, err := dbGetAccount(tx, account)
+	return err
+}
+
+// integrateAccountKeys generates n keys of a sub-account, starting from index
+// start, and loads them into the wallet.
+func (w *Wallet) integrateAccountKeys(account string, acc walletAccount, start, n uint64) {
+	for i, sk := range generateKeys(accountSeed(w.primarySeed, acc.Index), start, n) {
+		uh := sk.UnlockConditions.UnlockHash()
+		w.keys[uh] = sk
+		w.accountKeys[uh] = accountKey{
+			account: account,
+			index:   start + uint64(i),
+		}
+	}
+}
+
+// integrateAccounts loads the keys of all sub-accounts into the wallet,
+// including the lookahead of every account.
+func (w *Wallet) integrateAccounts(tx *bolt.Tx) error {
+	return dbForEachAccount(tx, func(name string, acc walletAccount) {
+		w.integrateAccountKeys(name, acc, 0, acc.Progress+accountLookahead)
+	})
+}
+
+// advanceAccountProgress sets the progress of a sub-account and extends its
+// lookahead accordingly.
+func (w *Wallet) advanceAccountProgress(tx *bolt.Tx, account string, acc walletAccount, progress uint64) error {
+	if progress <= acc.Progress {
+		return nil
+	}
+	w.integrateAccountKeys(account, acc, acc.Progress+accountLookahead, progress-acc.Progress)
+	acc.Progress = progress
+	return dbPutAccount(tx, account, acc)
+}
+
+// updateAccountLookahead uses a consensus change to update the progress of the
+// sub-accounts if one of the outputs was sent to an address of an account that
+// wasn't generated by this wallet.
+func (w *Wallet) updateAccountLookahead(tx *bolt.Tx, cc modules.ConsensusChange) error {
+	// The keys can only be generated while the primary seed is known.
+	if !w.unlocked {
+		return nil
+	}
+	progress := make(map[string]uint64)
+	update := func(uh types.UnlockHash) {
+		if ak, ok := w.accountKeys[uh]; ok && ak.index+1 > progress[ak.account] {
+			progress[ak.account] = ak.index + 1
+		}
+	}
+	for _, diff := range cc.UplocoinOutputDiffs {
+		update(diff.UplocoinOutput.UnlockHash)
+	}
+	for _, diff := range cc.UplofundOutputDiffs {
+		update(diff.UplofundOutput.UnlockHash)
+	}
+	for account, p := range progress {
+		acc, err := dbGetAccount(tx, account)
+		if err != nil {
+			return err
+		}
+		if err := w.advanceAccountProgress(tx, account, acc, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextAccountAddresses fetches the next n addresses of an account.
+func (w *Wallet) nextAccountAddresses(tx *bolt.Tx, account string, n uint64) ([]types.UnlockConditions, error) {
+	if account == modules.DefaultWalletAccount {
+		return w.nextPrimarySeedAddresses(tx, n)
+	}
+	if !w.unlocked {
+		return []types.UnlockConditions{}, modules.ErrLockedWallet
+	}
+	acc, err := dbGetAccount(tx, account)
+	if err != nil {
+		return []types.UnlockConditions{}, err
+	}
+	spendableKeys := generateKeys(accountSeed(w.primarySeed, acc.Index), acc.Progress, n)
+	ucs := make([]types.UnlockConditions, 0, len(spendableKeys))
+	for _, sk := range spendableKeys {
+		ucs = append(ucs, sk.UnlockConditions)
+	}
+	if err := w.advanceAccountProgress(tx, account, acc, acc.Progress+n); err != nil {
+		return []types.UnlockConditions{}, err
+	}
+	return ucs, nil
+}
+
+// nextAccountAddress fetches the next address of an account.
+func (w *Wallet) nextAccountAddress(tx *bolt.Tx, account string) (types.UnlockConditions, error) {
+	ucs, err := w.nextAccountAddresses(tx, account, 1)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	return ucs[0], nil
+}
+
+// CreateAccount creates a new sub-account of the wallet. The addresses of the
+// account are derived from a subseed of the primary seed. Accounts are numbered
+// in the order they are created, so creating the accounts of a restored wallet
+// in the same order recovers their funds. The blockchain is scanned for the
+// addresses of the new account in the background and rescanned if any of them
+// were used before.
+func (w *Wallet) CreateAccount(name string) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if err := validateAccountName(name); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return modules.ErrLockedWallet
+	}
+	if _, err := dbGetAccount(w.dbTx, name); err == nil {
+		return errAccountExists
+	}
+	var acc walletAccount
+	err := dbForEachAccount(w.dbTx, func(_ string, other walletAccount) {
+		if other.Index >= acc.Index {
+			acc.Index = other.Index + 1
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := dbPutAccount(w.dbTx, name, acc); err != nil {
+		return err
+	}
+	w.integrateAccountKeys(name, acc, 0, accountLookahead)
+	if err := w.syncDB(); err != nil {
+		return err
+	}
+	go w.threadedScanAccount(name, accountSeed(w.primarySeed, acc.Index))
+	return nil
+}
+
+// threadedScanAccount scans the blockchain for the addresses of a new account.
+// The wallet only tracks the addresses of an account from the moment it was
+// created, so if any of them were used before, the progress of the account is
+// advanced and the blockchain is rescanned to find their outputs.
+func (w *Wallet) threadedScanAccount(account string, seed modules.Seed) {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	s := newSeedScanner(seed, w.log)
+	s.generateKeys(accountLookahead)
+	err := w.cs.ConsensusSetSubscribe(s, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	w.cs.Unsubscribe(s)
+	if err != nil {
+		w.log.Printf("WARN: failed to scan the blockchain for the addresses of account %v: %v", account, err)
+		return
+	}
+	if !s.keySeen {
+		return
+	}
+
+	w.mu.Lock()
+	if !w.unlocked {
+		w.mu.Unlock()
+		return
+	}
+	acc, err := dbGetAccount(w.dbTx, account)
+	if err == nil {
+		err = w.advanceAccountProgress(w.dbTx, account, acc, s.largestIndexSeen+1)
+	}
+	err = errors.Compose(err, w.syncDB())
+	w.mu.Unlock()
+	if err != nil {
+		w.log.Printf("WARN: failed to update the progress of account %v: %v", account, err)
+		return
+	}
+	w.log.Printf("Addresses of account %v were used before it was created, rescanning the blockchain", account)
+	w.threadedResetSubscriptions()
+}
+
+// Accounts returns the accounts of the wallet and their balances, starting
+// with the default account.
+func (w *Wallet) Accounts() ([]modules.WalletAccount, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	// dustThreshold has to be obtained separate from the lock
+	dustThreshold, err := w.DustThreshold()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	accounts := []modules.WalletAccount{{
+		Name:    modules.DefaultWalletAccount,
+		Modules: []string{},
+	}}
+	indices := map[string]int{modules.DefaultWalletAccount: 0}
+	err = dbForEachAccount(w.dbTx, func(name string, _ walletAccount) {
+		indices[name] = len(accounts)
+		accounts = append(accounts, modules.WalletAccount{
+			Name:    name,
+			Modules: []string{},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	account := func(uh types.UnlockHash) *modules.WalletAccount {
+		return &accounts[indices[w.addressAccount(uh)]]
+	}
+
+	// Confirmed balances.
+	err = dbForEachUplocoinOutput(w.dbTx, func(_ types.UplocoinOutputID, sco types.UplocoinOutput) {
+		if sco.Value.Cmp(dustThreshold) > 0 {
+			acc := account(sco.UnlockHash)
+			acc.ConfirmedUplocoinBalance = acc.ConfirmedUplocoinBalance.Add(sco.Value)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	uplofundPool, err := dbGetUplofundPool(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	err = dbForEachUplofundOutput(w.dbTx, func(_ types.UplofundOutputID, sfo types.UplofundOutput) {
+		acc := account(sfo.UnlockHash)
+		acc.UplofundBalance = acc.UplofundBalance.Add(sfo.Value)
+		// Skip claims larger than the uplofund pool, like ConfirmedBalance.
+		if sfo.ClaimStart.Cmp(uplofundPool) <= 0 {
+			acc.UplofundClaimBalance = acc.UplofundClaimBalance.Add(uplofundPool.Sub(sfo.ClaimStart).Mul(sfo.Value).Div(types.UplofundCount))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Unconfirmed balances.
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		for _, input := range upt.Inputs {
+			if input.FundType == types.SpecifierUplocoinInput && input.WalletAddress {
+				acc := account(input.RelatedAddress)
+				acc.UnconfirmedOutgoingUplocoins = acc.UnconfirmedOutgoingUplocoins.Add(input.Value)
+			}
+		}
+		for _, output := range upt.Outputs {
+			if output.FundType == types.SpecifierUplocoinOutput && output.WalletAddress && output.Value.Cmp(dustThreshold) > 0 {
+				acc := account(output.RelatedAddress)
+				acc.UnconfirmedIncomingUplocoins = acc.UnconfirmedIncomingUplocoins.Add(output.Value)
+			}
+		}
+	}
+
+	// Pinned modules.
+	err = dbForEachModuleAccount(w.dbTx, func(module, name string) {
+		if i, ok := indices[name]; ok {
+			accounts[i].Modules = append(accounts[i].Modules, module)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// NextAccountAddresses returns n new addresses of an account.
+func (w *Wallet) NextAccountAddresses(account string, n uint64) ([]types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	ucs, err := w.nextAccountAddresses(w.dbTx, account, n)
+	err = errors.Compose(err, w.syncDB())
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return ucs, nil
+}
+
+// RegisterAccountTransaction takes a transaction and its parents and returns a
+// modules.TransactionBuilder which can be used to expand the transaction. The
+// builder only spends outputs of the given account and sends change back to
+// the account.
+func (w *Wallet) RegisterAccountTransaction(account string, t types.Transaction, parents []types.Transaction) (modules.TransactionBuilder, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkAccount(w.dbTx, account); err != nil {
+		return nil, err
+	}
+	tb := w.registerTransaction(t, parents)
+	tb.account = account
+	return tb, nil
+}
+
+// StartAccountTransaction is a convenience function that calls
+// RegisterAccountTransaction(account, types.Transaction{}, nil).
+func (w *Wallet) StartAccountTransaction(account string) (modules.TransactionBuilder, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	return w.RegisterAccountTransaction(account, types.Transaction{}, nil)
+}
+
+// ModuleAccounts returns the accounts that modules are pinned to, mapped by
+// module name. Modules that aren't pinned use the default account.
+func (w *Wallet) ModuleAccounts() (map[string]string, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pins := make(map[string]string)
+	err := dbForEachModuleAccount(w.dbTx, func(module, account string) {
+		pins[module] = account
+	})
+	return pins, err
+}
+
+// SetModuleAccount pins a module to an account. The transactions of the module
+// are funded by the account and its payouts are sent to addresses of the
+// account. Pinning a module to the default account unpins it.
+func (w *Wallet) SetModuleAccount(module, account string) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	pinnable := false
+	for _, m := range pinnableModules {
+		pinnable = pinnable || m == module
+	}
+	if !pinnable {
+		return errUnpinnableModule
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkAccount(w.dbTx, account); err != nil {
+		return err
+	}
+	if err := dbPutModuleAccount(w.dbTx, module, account); err != nil {
+		return err
+	}
+	return w.syncDB()
+}
+
+// managedAccount returns the account the module is pinned to.
+func (mw *moduleWallet) managedAccount() (string, error) {
+	pins, err := mw.Wallet.ModuleAccounts()
+	if err != nil {
+		return "", err
+	}
+	if account, ok := pins[mw.staticModule]; ok {
+		return account, nil
+	}
+	return modules.DefaultWalletAccount, nil
+}
+
+// managedAccountBalance returns the balances of the module's account.
+func (mw *moduleWallet) managedAccountBalance() (modules.WalletAccount, error) {
+	account, err := mw.managedAccount()
+	if err != nil {
+		return modules.WalletAccount{}, err
+	}
+	accounts, err := mw.Wallet.Accounts()
+	if err != nil {
+		return modules.WalletAccount{}, err
+	}
+	for _, acc := range accounts {
+		if acc.Name == account {
+			return acc, nil
+		}
+	}
+	return modules.WalletAccount{}, modules.ErrUnknownAccount
+}
+
+// ConfirmedBalance returns the confirmed balance of the module's account.
+func (mw *moduleWallet) ConfirmedBalance() (types.Currency, types.Currency, types.Currency, error) {
+	acc, err := mw.managedAccountBalance()
+	if err != nil {
+		return types.ZeroCurrency, types.ZeroCurrency, types.ZeroCurrency, err
+	}
+	return acc.ConfirmedUplocoinBalance, acc.UplofundBalance, acc.UplofundClaimBalance, nil
+}
+
+// UnconfirmedBalance returns the unconfirmed outgoing and incoming Uplocoins
+// of the module's account.
+func (mw *moduleWallet) UnconfirmedBalance() (types.Currency, types.Currency, error) {
+	acc, err := mw.managedAccountBalance()
+	if err != nil {
+		return types.ZeroCurrency, types.ZeroCurrency, err
+	}
+	return acc.UnconfirmedOutgoingUplocoins, acc.UnconfirmedIncomingUplocoins, nil
+}
+
+// NextAddress returns a new address of the module's account.
+func (mw *moduleWallet) NextAddress() (types.UnlockConditions, error) {
+	ucs, err := mw.NextAddresses(1)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	return ucs[0], nil
+}
+
+// NextAddresses returns n new addresses of the module's account.
+func (mw *moduleWallet) NextAddresses(n uint64) ([]types.UnlockConditions, error) {
+	account, err := mw.managedAccount()
+	if err != nil {
+		return nil, err
+	}
+	return mw.Wallet.NextAccountAddresses(account, n)
+}
+
+// RegisterTransaction registers a transaction that is funded by the module's
+// account.
+func (mw *moduleWallet) RegisterTransaction(t types.Transaction, parents []types.Transaction) (modules.TransactionBuilder, error) {
+	account, err := mw.managedAccount()
+	if err != nil {
+		return nil, err
+	}
+	return mw.Wallet.RegisterAccountTransaction(account, t, parents)
+}
+
+// StartTransaction starts a transaction that is funded by the module's
+// account.
+func (mw *moduleWallet) StartTransaction() (modules.TransactionBuilder, error) {
+	return mw.RegisterTransaction(types.Transaction{}, nil)
+}
+
+// SendUplocoins sends Uplocoins from the module's account.
+func (mw *moduleWallet) SendUplocoins(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error) {
+	account, err := mw.managedAccount()
+	if err != nil {
+		return nil, err
+	}
+	return mw.Wallet.SendUplocoinsFromAccount(account, amount, dest)
+}
+
+// SendUplocoinsMulti sends Uplocoins to multiple addresses from the module's
+// account.
+func (mw *moduleWallet) SendUplocoinsMulti(outputs []types.UplocoinOutput) ([]types.Transaction, error) {
+	account, err := mw.managedAccount()
+	if err != nil {
+		return nil, err
+	}
+	return mw.Wallet.SendUplocoinsMultiFromAccount(account, outputs)
+}
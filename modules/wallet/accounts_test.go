@@ -0,0 +1,279 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestAccounts probes creating sub-accounts and keeping their balances
+// separate.
+func TestAccounts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Create an account and check that invalid accounts are rejected.
+	if err := wt.wallet.CreateAccount("savings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.CreateAccount("savings"); !errors.Contains(err, errAccountExists) {
+		t.Fatal("expected errAccountExists, got", err)
+	}
+	if err := wt.wallet.CreateAccount(modules.DefaultWalletAccount); !errors.Contains(err, errAccountExists) {
+		t.Fatal("expected errAccountExists, got", err)
+	}
+	if err := wt.wallet.CreateAccount("my savings"); !errors.Contains(err, errInvalidAccountName) {
+		t.Fatal("expected errInvalidAccountName, got", err)
+	}
+	if _, err := wt.wallet.NextAccountAddresses("unknown", 1); !errors.Contains(err, modules.ErrUnknownAccount) {
+		t.Fatal("expected ErrUnknownAccount, got", err)
+	}
+
+	// Fund the account from the default account.
+	ucs, err := wt.wallet.NextAccountAddresses("savings", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ucs[0].UnlockHash()
+	wt.wallet.mu.Lock()
+	account := wt.wallet.addressAccount(addr)
+	wt.wallet.mu.Unlock()
+	if account != "savings" {
+		t.Fatal("address should belong to the account", account)
+	}
+	fundValue := types.UplocoinPrecision.Mul64(100)
+	if _, err := wt.wallet.SendUplocoins(fundValue, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := wt.wallet.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].Name != modules.DefaultWalletAccount || accounts[1].Name != "savings" {
+		t.Fatal("unexpected accounts", accounts)
+	}
+	if !accounts[1].ConfirmedUplocoinBalance.Equals(fundValue) {
+		t.Fatal("unexpected account balance", accounts[1].ConfirmedUplocoinBalance)
+	}
+	total, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !accounts[0].ConfirmedUplocoinBalance.Add(accounts[1].ConfirmedUplocoinBalance).Equals(total) {
+		t.Fatal("account balances don't add up to the wallet balance")
+	}
+
+	// The account can't spend more than its balance.
+	_, err = wt.wallet.SendUplocoinsFromAccount("savings", fundValue.Mul64(2), types.UnlockHash{})
+	if !errors.Contains(err, modules.ErrLowBalance) {
+		t.Fatal("expected ErrLowBalance, got", err)
+	}
+
+	// Spend from the account. The inputs and the change belong to the
+	// account.
+	txns, err := wt.wallet.SendUplocoinsFromAccount("savings", fundValue.Div64(2), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.Lock()
+	for _, sci := range txns[0].UplocoinInputs {
+		if account := wt.wallet.addressAccount(sci.UnlockConditions.UnlockHash()); account != "savings" {
+			t.Error("input doesn't belong to the account", account)
+		}
+	}
+	for _, sco := range txns[0].UplocoinOutputs {
+		if account := wt.wallet.addressAccount(sco.UnlockHash); account != "savings" {
+			t.Error("output doesn't belong to the account", account)
+		}
+	}
+	wt.wallet.mu.Unlock()
+}
+
+// TestModuleAccounts probes pinning modules to accounts.
+func TestModuleAccounts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := wt.wallet.CreateAccount("hosting"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetModuleAccount("miner", "hosting"); !errors.Contains(err, errUnpinnableModule) {
+		t.Fatal("expected errUnpinnableModule, got", err)
+	}
+	if err := wt.wallet.SetModuleAccount(modules.HostDir, "unknown"); !errors.Contains(err, modules.ErrUnknownAccount) {
+		t.Fatal("expected ErrUnknownAccount, got", err)
+	}
+	if err := wt.wallet.SetModuleAccount(modules.HostDir, "hosting"); err != nil {
+		t.Fatal(err)
+	}
+	pins, err := wt.wallet.ModuleAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 1 || pins[modules.HostDir] != "hosting" {
+		t.Fatal("unexpected pins", pins)
+	}
+
+	// The addresses and transactions of the module wallet use the account.
+	hw := NewModuleWallet(wt.wallet, modules.HostDir)
+	uc, err := hw.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.Lock()
+	account := wt.wallet.addressAccount(uc.UnlockHash())
+	wt.wallet.mu.Unlock()
+	if account != "hosting" {
+		t.Fatal("address should belong to the pinned account", account)
+	}
+	tb, err := hw.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.FundUplocoins(types.UplocoinPrecision); !errors.Contains(err, modules.ErrLowBalance) {
+		t.Fatal("the empty account shouldn't be able to fund the transaction", err)
+	}
+	tb.Drop()
+
+	// The balances of the module wallet are the balances of the account.
+	balance, _, _, err := hw.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance.IsZero() {
+		t.Fatal("the empty account should have no balance", balance)
+	}
+	fundValue := types.UplocoinPrecision.Mul64(100)
+	if _, err := wt.wallet.SendUplocoins(fundValue, uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	_, incoming, err := hw.UnconfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !incoming.Equals(fundValue) {
+		t.Fatal("unexpected unconfirmed incoming balance", incoming)
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	balance, _, _, err = hw.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance.Equals(fundValue) {
+		t.Fatal("unexpected account balance", balance)
+	}
+
+	// Unpin the module.
+	if err := wt.wallet.SetModuleAccount(modules.HostDir, modules.DefaultWalletAccount); err != nil {
+		t.Fatal(err)
+	}
+	tb, err = hw.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.FundUplocoins(types.UplocoinPrecision); err != nil {
+		t.Fatal(err)
+	}
+	tb.Drop()
+	accounts, err := wt.wallet.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, _, _, err = hw.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance.Equals(accounts[0].ConfirmedUplocoinBalance) {
+		t.Fatal("unpinned module should use the default account", balance, accounts[0].ConfirmedUplocoinBalance)
+	}
+}
+
+// TestCreateAccountRescan probes that creating an account whose addresses
+// were used before rescans the blockchain to recover its funds.
+func TestCreateAccountRescan(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Fund an address of the first account before the account exists, as if
+	// the account was created by another wallet using the same seed.
+	wt.wallet.mu.Lock()
+	seed := accountSeed(wt.wallet.primarySeed, 0)
+	wt.wallet.mu.Unlock()
+	addr := generateKeys(seed, 2, 1)[0].UnlockConditions.UnlockHash()
+	fundValue := types.UplocoinPrecision.Mul64(100)
+	if _, err := wt.wallet.SendUplocoins(fundValue, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the account. The output should be found by the rescan.
+	if err := wt.wallet.CreateAccount("restored"); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		accounts, err := wt.wallet.Accounts()
+		if err != nil {
+			return err
+		}
+		if !accounts[1].ConfirmedUplocoinBalance.Equals(fundValue) {
+			return errors.New("account balance wasn't recovered")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The progress of the account should include the used address.
+	wt.wallet.mu.Lock()
+	acc, err := dbGetAccount(wt.wallet.dbTx, "restored")
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Progress != 3 {
+		t.Fatal("unexpected account progress", acc.Progress)
+	}
+}
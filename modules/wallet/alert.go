@@ -8,3 +8,15 @@ import (
 func (w *Wallet) Alerts() (crit, err, warn []modules.Alert) {
 	return []modules.Alert{}, []modules.Alert{}, []modules.Alert{}
 }
+
+// SubscribeEvents implements the modules.EventSubscriber interface for the
+// wallet. Subscribers are notified about new transactions of the wallet.
+func (w *Wallet) SubscribeEvents(l modules.EventListener) {
+	w.staticEvents.SubscribeEvents(l)
+}
+
+// UnsubscribeEvents implements the modules.EventSubscriber interface for the
+// wallet.
+func (w *Wallet) UnsubscribeEvents(l modules.EventListener) {
+	w.staticEvents.UnsubscribeEvents(l)
+}
@@ -0,0 +1,229 @@
+package wallet
+
+import (
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+var (
+	// errBumpNoWalletOutput is returned if a transaction can't be bumped
+	// because none of its outputs belong to the wallet.
+	errBumpNoWalletOutput = errors.New("transaction has no output owned by the wallet to pay for the fee bump")
+
+	// errBumpNotInPool is returned if the transaction to bump is not in the
+	// transaction pool.
+	errBumpNotInPool = errors.New("transaction is not in the transaction pool")
+
+	// errBumpNotReplaceable is returned if a transaction can't be replaced by
+	// the wallet.
+	errBumpNotReplaceable = errors.New("transaction can't be replaced by the wallet")
+
+	// errBumpOutputTooSmall is returned if the wallet output of a transaction
+	// is too small to pay for the fee bump.
+	errBumpOutputTooSmall = errors.New("wallet output of the transaction is too small to pay for the fee bump")
+)
+
+// largestWalletOutput returns the index of the largest Uplocoin output of the
+// transaction that belongs to the wallet.
+func (w *Wallet) largestWalletOutput(txn types.Transaction) (int, bool) {
+	index := -1
+	for i, sco := range txn.UplocoinOutputs {
+		if _, exists := w.keys[sco.UnlockHash]; !exists {
+			continue
+		}
+		if index == -1 || sco.Value.Cmp(txn.UplocoinOutputs[index].Value) > 0 {
+			index = i
+		}
+	}
+	return index, index != -1
+}
+
+// managedCreateCPFPTransaction creates a child transaction which spends the
+// largest wallet output of the transaction set that isn't spent within the set
+// and pays the fee bump. The output spent by the child is marked as spent.
+func (w *Wallet) managedCreateCPFPTransaction(set []types.Transaction, fee types.Currency) (types.Transaction, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	spent := make(map[types.UplocoinOutputID]struct{})
+	for _, txn := range set {
+		for _, sci := range txn.UplocoinInputs {
+			spent[sci.ParentID] = struct{}{}
+		}
+	}
+	var parentID types.UplocoinOutputID
+	var output types.UplocoinOutput
+	found := false
+	for _, txn := range set {
+		for i, sco := range txn.UplocoinOutputs {
+			id := txn.UplocoinOutputID(uint64(i))
+			if _, exists := spent[id]; exists {
+				continue
+			}
+			if _, exists := w.keys[sco.UnlockHash]; !exists {
+				continue
+			}
+			if !found || sco.Value.Cmp(output.Value) > 0 {
+				parentID, output, found = id, sco, true
+			}
+		}
+	}
+	if !found {
+		return types.Transaction{}, errBumpNoWalletOutput
+	}
+	if output.Value.Cmp(fee) <= 0 {
+		return types.Transaction{}, errBumpOutputTooSmall
+	}
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	// The refund stays in the account of the spent output.
+	refundAddr, err := w.nextAccountAddress(w.dbTx, w.addressAccount(output.UnlockHash))
+	if err != nil {
+		return types.Transaction{}, err
+	}
+
+	uc := w.keys[output.UnlockHash].UnlockConditions
+	child := types.Transaction{
+		UplocoinInputs: []types.UplocoinInput{{
+			ParentID:         parentID,
+			UnlockConditions: uc,
+		}},
+		UplocoinOutputs: []types.UplocoinOutput{{
+			Value:      output.Value.Sub(fee),
+			UnlockHash: refundAddr.UnlockHash(),
+		}},
+		MinerFees: []types.Currency{fee},
+	}
+	addSignatures(&child, types.FullCoveredFields, uc, crypto.Hash(parentID), w.keys[output.UnlockHash], consensusHeight)
+	if err := dbPutSpentOutput(w.dbTx, types.OutputID(parentID), consensusHeight); err != nil {
+		return types.Transaction{}, err
+	}
+	return child, nil
+}
+
+// managedCreateReplacement creates a version of txn which pays an additional
+// fee. The fee is deducted from the wallet's output of txn. Only transactions
+// whose inputs are all owned by the wallet and which don't contain file
+// contracts, revisions or storage proofs can be replaced, since the
+// transaction id changes and all of the inputs need to be signed again.
+func (w *Wallet) managedCreateReplacement(txn types.Transaction, fee types.Currency) (types.Transaction, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(txn.FileContracts) > 0 || len(txn.FileContractRevisions) > 0 || len(txn.StorageProofs) > 0 {
+		return types.Transaction{}, errBumpNotReplaceable
+	}
+	for _, sci := range txn.UplocoinInputs {
+		if _, exists := w.keys[sci.UnlockConditions.UnlockHash()]; !exists {
+			return types.Transaction{}, errBumpNotReplaceable
+		}
+	}
+	for _, sfi := range txn.UplofundInputs {
+		if _, exists := w.keys[sfi.UnlockConditions.UnlockHash()]; !exists {
+			return types.Transaction{}, errBumpNotReplaceable
+		}
+	}
+	index, found := w.largestWalletOutput(txn)
+	if !found {
+		return types.Transaction{}, errBumpNoWalletOutput
+	}
+	if txn.UplocoinOutputs[index].Value.Cmp(fee) <= 0 {
+		return types.Transaction{}, errBumpOutputTooSmall
+	}
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+
+	// Deduct the fee from the wallet's output and sign the replacement.
+	replacement := types.Transaction{
+		UplocoinInputs:        append([]types.UplocoinInput(nil), txn.UplocoinInputs...),
+		UplocoinOutputs:       append([]types.UplocoinOutput(nil), txn.UplocoinOutputs...),
+		UplofundInputs:        append([]types.UplofundInput(nil), txn.UplofundInputs...),
+		UplofundOutputs:       append([]types.UplofundOutput(nil), txn.UplofundOutputs...),
+		MinerFees:             append([]types.Currency(nil), txn.MinerFees...),
+		ArbitraryData:         txn.ArbitraryData,
+		TransactionSignatures: nil,
+	}
+	replacement.UplocoinOutputs[index].Value = replacement.UplocoinOutputs[index].Value.Sub(fee)
+	if len(replacement.MinerFees) > 0 {
+		replacement.MinerFees[0] = replacement.MinerFees[0].Add(fee)
+	} else {
+		replacement.MinerFees = append(replacement.MinerFees, fee)
+	}
+	for _, sci := range replacement.UplocoinInputs {
+		addSignatures(&replacement, types.FullCoveredFields, sci.UnlockConditions, crypto.Hash(sci.ParentID), w.keys[sci.UnlockConditions.UnlockHash()], consensusHeight)
+	}
+	for _, sfi := range replacement.UplofundInputs {
+		addSignatures(&replacement, types.FullCoveredFields, sfi.UnlockConditions, crypto.Hash(sfi.ParentID), w.keys[sfi.UnlockConditions.UnlockHash()], consensusHeight)
+	}
+	return replacement, nil
+}
+
+// BumpTransaction increases the fees of an unconfirmed transaction. If the
+// transaction can be replaced, a version of it that pays a higher fee is
+// submitted to the transaction pool. Otherwise a child transaction which
+// spends a wallet output of the transaction or its unconfirmed parents and
+// pays the higher fee is submitted. The submitted transaction set is
+// returned.
+func (w *Wallet) BumpTransaction(txid types.TransactionID) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	unlocked := w.unlocked
+	w.mu.RUnlock()
+	if !unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	txn, parents, exists := w.tpool.Transaction(txid)
+	if !exists {
+		return nil, errBumpNotInPool
+	}
+
+	// Pay the maximum recommended fee for the whole set on top of the
+	// existing fees.
+	_, maxFee := w.tpool.FeeEstimation()
+	setSize := uint64(len(encoding.Marshal(parents)) + len(encoding.Marshal(txn)))
+
+	// Try to replace the transaction first.
+	fee := maxFee.Mul64(setSize)
+	replacement, err := w.managedCreateReplacement(txn, fee)
+	if err == nil {
+		txns = append(append([]types.Transaction(nil), parents...), replacement)
+		err = w.tpool.AcceptTransactionSet(txns)
+		if err == nil {
+			w.log.Println("Replaced transaction", txid, "with", replacement.ID(), "paying an additional fee of", fee.HumanString())
+			return txns, nil
+		}
+	}
+	w.log.Debugln("Unable to replace transaction", txid, "falling back to CPFP:", err)
+
+	// Fall back to a child transaction which pays for the parent. The fee
+	// also covers the size of the child.
+	fee = maxFee.Mul64(setSize + estimatedTransactionSize)
+	txns = append(append([]types.Transaction(nil), parents...), txn)
+	child, err := w.managedCreateCPFPTransaction(txns, fee)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to create CPFP transaction")
+	}
+	txns = append(txns, child)
+	err = w.tpool.AcceptTransactionSet(txns)
+	if err != nil {
+		w.mu.Lock()
+		dbDeleteSpentOutput(w.dbTx, types.OutputID(child.UplocoinInputs[0].ParentID))
+		w.mu.Unlock()
+		return nil, errors.AddContext(err, "CPFP transaction was rejected")
+	}
+	w.log.Println("Submitted CPFP transaction", child.ID(), "for transaction", txid, "paying a fee of", fee.HumanString())
+	return txns, nil
+}
@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestBumpTransaction probes bumping the fees of unconfirmed transactions by
+// replacing them and by creating child transactions.
+func TestBumpTransaction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Bumping an unknown transaction should fail.
+	_, err = wt.wallet.BumpTransaction(types.TransactionID{})
+	if !errors.Contains(err, errBumpNotInPool) {
+		t.Fatal("expected errBumpNotInPool", err)
+	}
+
+	// Send coins to the wallet itself. The transaction can be replaced since
+	// the wallet owns its inputs and outputs.
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.SendUplocoins(types.UplocoinPrecision.Mul64(100), uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := txns[len(txns)-1]
+	bumped, err := wt.wallet.BumpTransaction(original.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement := bumped[len(bumped)-1]
+	if _, _, exists := wt.tpool.Transaction(original.ID()); exists {
+		t.Fatal("original transaction wasn't replaced")
+	}
+	if _, _, exists := wt.tpool.Transaction(replacement.ID()); !exists {
+		t.Fatal("replacement isn't in the pool")
+	}
+	if modules.CalculateFee([]types.Transaction{replacement}).Cmp(modules.CalculateFee([]types.Transaction{original})) <= 0 {
+		t.Fatal("replacement doesn't pay a higher fee")
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Send coins to an address outside of the wallet. The transaction can't
+	// be replaced, so a child spending the change output of its parent pays
+	// for it.
+	txns, err = wt.wallet.SendUplocoins(types.UplocoinPrecision.Mul64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original = txns[len(txns)-1]
+	bumped, err = wt.wallet.BumpTransaction(original.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := bumped[len(bumped)-1]
+	if _, _, exists := wt.tpool.Transaction(original.ID()); !exists {
+		t.Fatal("original transaction was removed from the pool")
+	}
+	if _, _, exists := wt.tpool.Transaction(child.ID()); !exists {
+		t.Fatal("child transaction isn't in the pool")
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	confirmed, err := wt.tpool.TransactionConfirmed(child.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !confirmed {
+		t.Fatal("child transaction wasn't confirmed")
+	}
+}
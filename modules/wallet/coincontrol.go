@@ -0,0 +1,141 @@
+package wallet
+
+import (
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// maxLabelLength is the maximum length of a label in bytes.
+const maxLabelLength = 256
+
+var (
+	// errInvalidLabelType is returned when a label is set for an unknown type
+	// of object.
+	errInvalidLabelType = errors.New("label type must be address, output or transaction")
+
+	// errLabelTooLong is returned when a label exceeds maxLabelLength.
+	errLabelTooLong = errors.New("label is too long")
+)
+
+// parseLabelID parses the string representation of the ID of a labeled object.
+func parseLabelID(labelType modules.WalletLabelType, id string) (crypto.Hash, error) {
+	var h crypto.Hash
+	switch labelType {
+	case modules.WalletLabelAddress:
+		var addr types.UnlockHash
+		if err := addr.LoadString(id); err != nil {
+			return crypto.Hash{}, errors.AddContext(err, "invalid address")
+		}
+		h = crypto.Hash(addr)
+	case modules.WalletLabelOutput, modules.WalletLabelTransaction:
+		if err := h.LoadString(id); err != nil {
+			return crypto.Hash{}, errors.AddContext(err, "invalid ID")
+		}
+	default:
+		return crypto.Hash{}, errInvalidLabelType
+	}
+	return h, nil
+}
+
+// labelIDString returns the string representation of the ID of a labeled
+// object.
+func labelIDString(labelType modules.WalletLabelType, id crypto.Hash) string {
+	switch labelType {
+	case modules.WalletLabelAddress:
+		return types.UnlockHash(id).String()
+	case modules.WalletLabelOutput:
+		return types.OutputID(id).String()
+	case modules.WalletLabelTransaction:
+		return types.TransactionID(id).String()
+	}
+	return id.String()
+}
+
+// FreezeOutputs marks Uplocoin outputs of the wallet as frozen. Frozen outputs
+// are never selected automatically to fund transactions or to defragment the
+// wallet. They can only be spent by explicitly selecting them, which allows
+// keeping funds, like host collateral, separate within one wallet.
+func (w *Wallet) FreezeOutputs(ids []types.UplocoinOutputID) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return modules.ErrLockedWallet
+	}
+	for _, id := range ids {
+		if _, ok := w.uplocoinOutput(id); !ok {
+			return errors.AddContext(modules.ErrUnknownOutput, id.String())
+		}
+	}
+	for _, id := range ids {
+		if err := dbPutFrozenOutput(w.dbTx, id); err != nil {
+			return err
+		}
+	}
+	return w.syncDB()
+}
+
+// UnfreezeOutputs makes frozen outputs available for funding transactions
+// again.
+func (w *Wallet) UnfreezeOutputs(ids []types.UplocoinOutputID) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		if err := dbDeleteFrozenOutput(w.dbTx, id); err != nil {
+			return err
+		}
+	}
+	return w.syncDB()
+}
+
+// Labels returns the labels of the wallet.
+func (w *Wallet) Labels() ([]modules.WalletLabel, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	labels := []modules.WalletLabel{}
+	err := dbForEachLabel(w.dbTx, func(labelType modules.WalletLabelType, id crypto.Hash, label string) {
+		labels = append(labels, modules.WalletLabel{
+			Type:  labelType,
+			ID:    labelIDString(labelType, id),
+			Label: label,
+		})
+	})
+	return labels, err
+}
+
+// SetLabel sets the label of an address, output or transaction. The id is the
+// string representation of the labeled object. An empty label removes the
+// label.
+func (w *Wallet) SetLabel(labelType modules.WalletLabelType, id string, label string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if len(label) > maxLabelLength {
+		return errLabelTooLong
+	}
+	h, err := parseLabelID(labelType, id)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutLabel(w.dbTx, labelType, h, label); err != nil {
+		return err
+	}
+	return w.syncDB()
+}
@@ -0,0 +1,226 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestCoinControl probes freezing outputs and spending explicitly selected
+// outputs.
+func TestCoinControl(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Freeze all Uplocoin outputs of the wallet.
+	outputs, err := wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []types.UplocoinOutputID
+	for _, o := range outputs {
+		if o.FundType == types.SpecifierUplocoinOutput {
+			ids = append(ids, types.UplocoinOutputID(o.ID))
+		}
+	}
+	if len(ids) == 0 {
+		t.Fatal("wallet has no Uplocoin outputs")
+	}
+	if err := wt.wallet.FreezeOutputs(ids); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.FreezeOutputs([]types.UplocoinOutputID{{1}}); !errors.Contains(err, modules.ErrUnknownOutput) {
+		t.Fatal("expected ErrUnknownOutput, got", err)
+	}
+	outputs, err = wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range outputs {
+		if o.FundType == types.SpecifierUplocoinOutput && !o.Frozen {
+			t.Fatal("output should be frozen", o.ID)
+		}
+	}
+
+	// Frozen outputs are not selected automatically.
+	sendValue := types.UplocoinPrecision.Mul64(3)
+	_, err = wt.wallet.SendUplocoins(sendValue, types.UnlockHash{})
+	if !errors.Contains(err, modules.ErrLowBalance) {
+		t.Fatal("expected ErrLowBalance, got", err)
+	}
+
+	// They can be spent explicitly.
+	txns, err := wt.wallet.SendUplocoinsFromOutputs(sendValue, types.UnlockHash{}, ids[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := txns[0]
+	if len(parent.UplocoinInputs) != 1 || parent.UplocoinInputs[0].ParentID != ids[0] {
+		t.Fatal("transaction didn't spend the selected output", parent.UplocoinInputs)
+	}
+
+	// Unfrozen outputs can be selected automatically again. The change of the
+	// previous transaction wasn't frozen.
+	if err := wt.wallet.UnfreezeOutputs(ids); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendUplocoins(sendValue, types.UnlockHash{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCoinControlWatchOnly verifies that outputs of watch-only addresses
+// can't be spent explicitly.
+func TestCoinControlWatchOnly(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Send coins to an address the wallet has no key for and watch it.
+	addr := types.UnlockHash{1}
+	if _, err := wt.wallet.SendUplocoins(types.UplocoinPrecision.Mul64(77), addr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.AddWatchAddresses([]types.UnlockHash{addr}, false); err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []types.UplocoinOutputID
+	for _, o := range outputs {
+		if o.FundType == types.SpecifierUplocoinOutput && o.UnlockHash == addr {
+			ids = append(ids, types.UplocoinOutputID(o.ID))
+		}
+	}
+	if len(ids) != 1 {
+		t.Fatal("expected a single watch-only output, got", len(ids))
+	}
+
+	// Selecting the watch-only output fails.
+	tb, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Drop()
+	err = tb.FundUplocoinsFromOutputs(types.UplocoinPrecision, ids)
+	if !errors.Contains(err, errOutputWatchOnly) {
+		t.Fatal("expected errOutputWatchOnly, got", err)
+	}
+	txn, _ := tb.View()
+	if len(txn.UplocoinInputs) != 0 {
+		t.Fatal("watch-only output was added as an input")
+	}
+}
+
+// TestLabels probes setting and removing labels.
+func TestLabels(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	outputs, err := wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) == 0 {
+		t.Fatal("wallet has no outputs")
+	}
+	output := outputs[0]
+	txid := types.TransactionID{1, 2, 3}
+
+	// Set labels of all types.
+	labels := []modules.WalletLabel{
+		{Type: modules.WalletLabelAddress, ID: output.UnlockHash.String(), Label: "address"},
+		{Type: modules.WalletLabelOutput, ID: output.ID.String(), Label: "collateral"},
+		{Type: modules.WalletLabelTransaction, ID: txid.String(), Label: "rent"},
+	}
+	for _, l := range labels {
+		if err := wt.wallet.SetLabel(l.Type, l.ID, l.Label); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := wt.wallet.Labels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(labels) {
+		t.Fatal("unexpected number of labels", got)
+	}
+	for _, l := range labels {
+		found := false
+		for _, g := range got {
+			found = found || g == l
+		}
+		if !found {
+			t.Fatal("label is missing", l, got)
+		}
+	}
+	outputs, err = wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range outputs {
+		if o.ID == output.ID && o.Label != "collateral" {
+			t.Fatal("unspent output should have label", o.Label)
+		}
+	}
+
+	// Invalid labels are rejected.
+	if err := wt.wallet.SetLabel("block", txid.String(), "label"); !errors.Contains(err, errInvalidLabelType) {
+		t.Fatal("expected errInvalidLabelType, got", err)
+	}
+	if err := wt.wallet.SetLabel(modules.WalletLabelAddress, "invalid", "label"); err == nil {
+		t.Fatal("label with invalid address was set")
+	}
+	if err := wt.wallet.SetLabel(modules.WalletLabelTransaction, txid.String(), string(make([]byte, maxLabelLength+1))); !errors.Contains(err, errLabelTooLong) {
+		t.Fatal("expected errLabelTooLong, got", err)
+	}
+
+	// An empty label removes the label.
+	if err := wt.wallet.SetLabel(modules.WalletLabelTransaction, txid.String(), ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err = wt.wallet.Labels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(labels)-1 {
+		t.Fatal("label wasn't removed", got)
+	}
+}
@@ -1,6 +1,7 @@
 package wallet
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"reflect"
@@ -10,6 +11,7 @@ import (
 	"github.com/uplo-tech/errors"
 	"github.com/uplo-tech/fastrand"
 
+	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/types"
 	"github.com/uplo-tech/encoding"
@@ -46,6 +48,30 @@ var (
 	// bucketWallet contains various fields needed by the wallet, such as its
 	// UID, EncryptionVerification, and PrimarySeedFile.
 	bucketWallet = []byte("bucketWallet")
+	// bucketFrozenOutputs contains the UplocoinOutputIDs of the outputs that
+	// were frozen by the user. Frozen outputs are never used to fund
+	// transactions automatically.
+	bucketFrozenOutputs = []byte("bucketFrozenOutputs")
+	// bucketLabels maps the type and ID of a labeled address, output or
+	// transaction to its label.
+	bucketLabels = []byte("bucketLabels")
+	// bucketAccounts maps the name of a sub-account of the wallet to its
+	// walletAccount.
+	bucketAccounts = []byte("bucketAccounts")
+	// bucketModuleAccounts maps the name of a module to the name of the
+	// account the module is pinned to.
+	bucketModuleAccounts = []byte("bucketModuleAccounts")
+	// bucketTimelockedKeys maps the UnlockHash of a time-locked version of a
+	// key of the wallet to its UnlockConditions.
+	bucketTimelockedKeys = []byte("bucketTimelockedKeys")
+	// bucketScheduledPayments maps the name of a scheduled payment to its
+	// scheduledPayment.
+	bucketScheduledPayments = []byte("bucketScheduledPayments")
+	// bucketMaturedOutputs maps the UplocoinOutputID of a delayed output of
+	// the wallet, such as a miner payout or a storage proof output, to its
+	// maturedOutput once it matured. It records which of the storage proof
+	// outputs of a contract were actually created by consensus.
+	bucketMaturedOutputs = []byte("bucketMaturedOutputs")
 
 	dbBuckets = [][]byte{
 		bucketProcessedTransactions,
@@ -56,6 +82,13 @@ var (
 		bucketSpentOutputs,
 		bucketUnlockConditions,
 		bucketWallet,
+		bucketFrozenOutputs,
+		bucketLabels,
+		bucketAccounts,
+		bucketModuleAccounts,
+		bucketTimelockedKeys,
+		bucketScheduledPayments,
+		bucketMaturedOutputs,
 	}
 
 	errNoKey = errors.New("key does not exist")
@@ -232,6 +265,17 @@ func dbDeleteSpentOutput(tx *bolt.Tx, id types.OutputID) error {
 	return dbDelete(tx.Bucket(bucketSpentOutputs), id)
 }
 
+func dbPutMaturedOutput(tx *bolt.Tx, id types.UplocoinOutputID, mo maturedOutput) error {
+	return dbPut(tx.Bucket(bucketMaturedOutputs), id, mo)
+}
+func dbGetMaturedOutput(tx *bolt.Tx, id types.UplocoinOutputID) (mo maturedOutput, err error) {
+	err = dbGet(tx.Bucket(bucketMaturedOutputs), id, &mo)
+	return
+}
+func dbDeleteMaturedOutput(tx *bolt.Tx, id types.UplocoinOutputID) error {
+	return dbDelete(tx.Bucket(bucketMaturedOutputs), id)
+}
+
 func dbPutAddrTransactions(tx *bolt.Tx, addr types.UnlockHash, txns []uint64) error {
 	return dbPut(tx.Bucket(bucketAddrTransactions), addr, txns)
 }
@@ -248,6 +292,142 @@ func dbGetUnlockConditions(tx *bolt.Tx, addr types.UnlockHash) (uc types.UnlockC
 	return
 }
 
+func dbPutFrozenOutput(tx *bolt.Tx, id types.UplocoinOutputID) error {
+	return dbPut(tx.Bucket(bucketFrozenOutputs), id, true)
+}
+func dbDeleteFrozenOutput(tx *bolt.Tx, id types.UplocoinOutputID) error {
+	return dbDelete(tx.Bucket(bucketFrozenOutputs), id)
+}
+func dbIsFrozenOutput(tx *bolt.Tx, id types.UplocoinOutputID) bool {
+	return tx.Bucket(bucketFrozenOutputs).Get(encoding.Marshal(id)) != nil
+}
+
+// labelKey returns the key of the label of an object in bucketLabels.
+func labelKey(labelType modules.WalletLabelType, id crypto.Hash) []byte {
+	return append([]byte(labelType+"/"), id[:]...)
+}
+
+// dbPutLabel sets the label of an object. An empty label deletes the label.
+func dbPutLabel(tx *bolt.Tx, labelType modules.WalletLabelType, id crypto.Hash, label string) error {
+	if label == "" {
+		return tx.Bucket(bucketLabels).Delete(labelKey(labelType, id))
+	}
+	return tx.Bucket(bucketLabels).Put(labelKey(labelType, id), []byte(label))
+}
+
+// dbGetLabel returns the label of an object or the empty string if it doesn't
+// have a label.
+func dbGetLabel(tx *bolt.Tx, labelType modules.WalletLabelType, id crypto.Hash) string {
+	return string(tx.Bucket(bucketLabels).Get(labelKey(labelType, id)))
+}
+
+// dbForEachLabel calls fn with every label of the wallet.
+func dbForEachLabel(tx *bolt.Tx, fn func(modules.WalletLabelType, crypto.Hash, string)) error {
+	return tx.Bucket(bucketLabels).ForEach(func(k, v []byte) error {
+		sep := bytes.IndexByte(k, '/')
+		if sep == -1 || len(k)-sep-1 != crypto.HashSize {
+			return errors.New("invalid label key")
+		}
+		var id crypto.Hash
+		copy(id[:], k[sep+1:])
+		fn(modules.WalletLabelType(k[:sep]), id, string(v))
+		return nil
+	})
+}
+
+// dbPutAccount stores a sub-account of the wallet.
+func dbPutAccount(tx *bolt.Tx, name string, acc walletAccount) error {
+	return tx.Bucket(bucketAccounts).Put([]byte(name), encoding.Marshal(acc))
+}
+
+// dbGetAccount returns a sub-account of the wallet.
+func dbGetAccount(tx *bolt.Tx, name string) (acc walletAccount, err error) {
+	accBytes := tx.Bucket(bucketAccounts).Get([]byte(name))
+	if accBytes == nil {
+		return walletAccount{}, modules.ErrUnknownAccount
+	}
+	err = encoding.Unmarshal(accBytes, &acc)
+	return
+}
+
+// dbForEachAccount calls fn with every sub-account of the wallet.
+func dbForEachAccount(tx *bolt.Tx, fn func(string, walletAccount)) error {
+	return tx.Bucket(bucketAccounts).ForEach(func(k, v []byte) error {
+		var acc walletAccount
+		if err := encoding.Unmarshal(v, &acc); err != nil {
+			return err
+		}
+		fn(string(k), acc)
+		return nil
+	})
+}
+
+// dbPutModuleAccount pins a module to an account. Pinning a module to the
+// default account deletes the entry.
+func dbPutModuleAccount(tx *bolt.Tx, module, account string) error {
+	if account == modules.DefaultWalletAccount {
+		return tx.Bucket(bucketModuleAccounts).Delete([]byte(module))
+	}
+	return tx.Bucket(bucketModuleAccounts).Put([]byte(module), []byte(account))
+}
+
+// dbGetModuleAccount returns the account a module is pinned to.
+func dbGetModuleAccount(tx *bolt.Tx, module string) string {
+	account := tx.Bucket(bucketModuleAccounts).Get([]byte(module))
+	if account == nil {
+		return modules.DefaultWalletAccount
+	}
+	return string(account)
+}
+
+// dbForEachModuleAccount calls fn with every module that is pinned to a
+// sub-account.
+func dbForEachModuleAccount(tx *bolt.Tx, fn func(module, account string)) error {
+	return tx.Bucket(bucketModuleAccounts).ForEach(func(k, v []byte) error {
+		fn(string(k), string(v))
+		return nil
+	})
+}
+
+func dbPutTimelockedKey(tx *bolt.Tx, uc types.UnlockConditions) error {
+	return dbPut(tx.Bucket(bucketTimelockedKeys), uc.UnlockHash(), uc)
+}
+func dbForEachTimelockedKey(tx *bolt.Tx, fn func(types.UnlockHash, types.UnlockConditions)) error {
+	return dbForEach(tx.Bucket(bucketTimelockedKeys), fn)
+}
+
+// dbPutScheduledPayment stores a scheduled payment.
+func dbPutScheduledPayment(tx *bolt.Tx, name string, sp scheduledPayment) error {
+	return tx.Bucket(bucketScheduledPayments).Put([]byte(name), encoding.Marshal(sp))
+}
+
+// dbGetScheduledPayment returns a scheduled payment.
+func dbGetScheduledPayment(tx *bolt.Tx, name string) (sp scheduledPayment, err error) {
+	spBytes := tx.Bucket(bucketScheduledPayments).Get([]byte(name))
+	if spBytes == nil {
+		return scheduledPayment{}, modules.ErrUnknownScheduledPayment
+	}
+	err = encoding.Unmarshal(spBytes, &sp)
+	return
+}
+
+// dbDeleteScheduledPayment deletes a scheduled payment.
+func dbDeleteScheduledPayment(tx *bolt.Tx, name string) error {
+	return tx.Bucket(bucketScheduledPayments).Delete([]byte(name))
+}
+
+// dbForEachScheduledPayment calls fn with every scheduled payment.
+func dbForEachScheduledPayment(tx *bolt.Tx, fn func(string, scheduledPayment)) error {
+	return tx.Bucket(bucketScheduledPayments).ForEach(func(k, v []byte) error {
+		var sp scheduledPayment
+		if err := encoding.Unmarshal(v, &sp); err != nil {
+			return err
+		}
+		fn(string(k), sp)
+		return nil
+	})
+}
+
 // dbAddAddrTransaction appends a single transaction index to the set of
 // transactions associated with addr. If the index is already in the set, it is
 // not added again.
@@ -4,6 +4,7 @@ import (
 	"sort"
 
 	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/types"
 	"github.com/uplo-tech/errors"
 )
@@ -30,9 +31,14 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 		return nil, err
 	}
 
-	// Collect a value-sorted set of Uplocoin outputs.
+	// Collect a value-sorted set of Uplocoin outputs. Only the outputs of the
+	// default account are defragged to keep the balances of the sub-accounts
+	// separate.
 	var so sortedOutputs
 	err = dbForEachUplocoinOutput(w.dbTx, func(scoid types.UplocoinOutputID, sco types.UplocoinOutput) {
+		if w.addressAccount(sco.UnlockHash) != modules.DefaultWalletAccount {
+			return
+		}
 		if w.checkOutput(w.dbTx, consensusHeight, scoid, sco, dustThreshold) == nil {
 			so.ids = append(so.ids, scoid)
 			so.outputs = append(so.outputs, sco)
@@ -126,11 +132,11 @@ func (w *Wallet) managedCreateDefragTransaction() ([]types.Transaction, error) {
 	return []types.Transaction{parentTxn, txn}, nil
 }
 
-// threadedDefragWallet computes the sum of the 15 largest outputs in the wallet and
+// managedDefragWallet computes the sum of the 15 largest outputs in the wallet and
 // sends that sum to itself, effectively defragmenting the wallet. This defrag
 // operation is only performed if the wallet has greater than defragThreshold
 // outputs.
-func (w *Wallet) threadedDefragWallet() {
+func (w *Wallet) managedDefragWallet() {
 	// Don't defrag if it was disabled
 	w.mu.RLock()
 	disabled := w.defragDisabled
@@ -257,6 +257,11 @@ func (w *Wallet) managedBlockingUnlock(masterKey crypto.CipherKey) (modules.Cons
 		w.primarySeed = primarySeed
 		w.regenerateLookahead(primarySeedProgress)
 
+		// sub-accounts
+		if err := w.integrateAccounts(w.dbTx); err != nil {
+			return err
+		}
+
 		// auxiliarySeedFiles
 		for _, sf := range auxiliarySeedFiles {
 			auxSeed, err := decryptSeedFile(masterKey, sf)
@@ -276,6 +281,11 @@ func (w *Wallet) managedBlockingUnlock(masterKey crypto.CipherKey) (modules.Cons
 			w.integrateSpendableKey(masterKey, sk)
 		}
 
+		// time-locked keys
+		if err := w.integrateTimelockedKeys(w.dbTx); err != nil {
+			return err
+		}
+
 		// watchedAddrs
 		for _, addr := range watchedAddrs {
 			w.watchedAddrs[addr] = struct{}{}
@@ -316,7 +326,7 @@ func (w *Wallet) managedAsyncUnlock(lastChange modules.ConsensusChangeID) error
 		go w.rescanMessage(done)
 		defer close(done)
 
-		err := w.cs.ConsensusSetSubscribe(w, lastChange, w.tg.StopChan())
+		err := w.managedSubscribe(lastChange)
 		if errors.Contains(err, modules.ErrInvalidConsensusChangeID) {
 			// something went wrong; resubscribe from the beginning
 			err = dbPutConsensusChangeID(w.dbTx, modules.ConsensusChangeBeginning)
@@ -327,12 +337,11 @@ func (w *Wallet) managedAsyncUnlock(lastChange modules.ConsensusChangeID) error
 			if err != nil {
 				return fmt.Errorf("failed to reset db during rescan: %v", err)
 			}
-			err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+			err = w.managedSubscribe(modules.ConsensusChangeBeginning)
 		}
 		if err != nil {
 			return fmt.Errorf("wallet subscription failed: %v", err)
 		}
-		w.tpool.TransactionPoolSubscribe(w)
 	}
 	w.subscribed = true
 	return nil
@@ -452,6 +461,7 @@ func (w *Wallet) Reset() error {
 	w.wipeSecrets()
 	w.keys = make(map[types.UnlockHash]spendableKey)
 	w.lookahead = make(map[types.UnlockHash]uint64)
+	w.accountKeys = make(map[types.UnlockHash]accountKey)
 	w.seeds = []modules.Seed{}
 	w.unconfirmedProcessedTransactions = []modules.ProcessedTransaction{}
 	w.unlocked = false
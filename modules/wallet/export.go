@@ -0,0 +1,253 @@
+package wallet
+
+import (
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// maturedOutput is a delayed Uplocoin output of the wallet, such as a miner
+// payout or a storage proof output, that matured.
+type maturedOutput struct {
+	Value          types.Currency
+	MaturityHeight types.BlockHeight
+}
+
+// ExportEntries returns the entries of an export of the full history of the
+// wallet.
+func (w *Wallet) ExportEntries() ([]modules.WalletExportEntry, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	pts, err := w.Transactions(0, math.MaxUint64)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up which of the storage proof outputs of the wallet's contracts
+	// matured.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	matured := make(map[types.UplocoinOutputID]maturedOutput)
+	for _, pt := range pts {
+		for _, fcid := range contractIDs(pt.Transaction) {
+			valid, missed := storageProofOutputIDs(fcid, pt.Transaction)
+			for _, id := range append(valid, missed...) {
+				mo, err := dbGetMaturedOutput(w.dbTx, id)
+				if errors.Contains(err, errNoKey) {
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+				matured[id] = mo
+			}
+		}
+	}
+	return computeExportEntries(pts, matured), nil
+}
+
+// contractIDs returns the IDs of the file contracts that are formed or
+// revised by a transaction.
+func contractIDs(txn types.Transaction) []types.FileContractID {
+	var fcids []types.FileContractID
+	for i := range txn.FileContracts {
+		fcids = append(fcids, txn.FileContractID(uint64(i)))
+	}
+	for _, rev := range txn.FileContractRevisions {
+		fcids = append(fcids, rev.ParentID)
+	}
+	return fcids
+}
+
+// storageProofOutputIDs returns the IDs of the valid and missed proof outputs
+// of a file contract as they are formed or revised by a transaction.
+func storageProofOutputIDs(fcid types.FileContractID, txn types.Transaction) (valid, missed []types.UplocoinOutputID) {
+	add := func(validOutputs, missedOutputs []types.UplocoinOutput) {
+		for i := range validOutputs {
+			valid = append(valid, fcid.StorageProofOutputID(types.ProofValid, uint64(i)))
+		}
+		for i := range missedOutputs {
+			missed = append(missed, fcid.StorageProofOutputID(types.ProofMissed, uint64(i)))
+		}
+	}
+	for i, fc := range txn.FileContracts {
+		if txn.FileContractID(uint64(i)) == fcid {
+			add(fc.ValidProofOutputs, fc.MissedProofOutputs)
+		}
+	}
+	for _, rev := range txn.FileContractRevisions {
+		if rev.ParentID == fcid {
+			add(rev.NewValidProofOutputs, rev.NewMissedProofOutputs)
+		}
+	}
+	return valid, missed
+}
+
+// computeExportEntries creates the entries of a wallet history export from a
+// set of ProcessedTransactions. The transactions should contain the full
+// history of the wallet, since the running balance starts at zero.
+//
+// Every transaction is split into the Uplocoin inflows and outflows of the
+// wallet. Storage proof payouts are not part of the wallet history, so they
+// are taken from the storage proof outputs of the wallet that consensus
+// actually created, which are either the valid or the missed proof outputs
+// of a contract, and reported at their maturity height. Their timestamp is
+// left empty since it's not known to the wallet history.
+func computeExportEntries(pts []modules.ProcessedTransaction, matured map[types.UplocoinOutputID]maturedOutput) []modules.WalletExportEntry {
+	// Map the id of each contract to the most recent transaction that formed
+	// or revised it within the set.
+	latest := make(map[types.FileContractID]int)
+	for i, pt := range pts {
+		for _, fcid := range contractIDs(pt.Transaction) {
+			latest[fcid] = i
+		}
+	}
+
+	var entries []modules.WalletExportEntry
+	for i, pt := range pts {
+		newEntry := func(category modules.WalletExportCategory) modules.WalletExportEntry {
+			return modules.WalletExportEntry{
+				TransactionID: pt.TransactionID,
+				Height:        pt.ConfirmationHeight,
+				Timestamp:     pt.ConfirmationTimestamp,
+				Category:      category,
+			}
+		}
+
+		// Sum up the Uplocoin inputs of the wallet.
+		var spent types.Currency
+		for _, input := range pt.Inputs {
+			if input.FundType == types.SpecifierUplocoinInput && input.WalletAddress {
+				spent = spent.Add(input.Value)
+			}
+		}
+
+		// Sum up the outputs by category. The storage proof outputs of
+		// contracts are also processed as Uplocoin outputs, so only the
+		// regular outputs of the transaction are counted as received.
+		regularOutputs := make(map[types.OutputID]struct{})
+		for j := range pt.Transaction.UplocoinOutputs {
+			regularOutputs[types.OutputID(pt.Transaction.UplocoinOutputID(uint64(j)))] = struct{}{}
+		}
+		var minerPayouts, claims, received, fees types.Currency
+		for _, output := range pt.Outputs {
+			switch output.FundType {
+			case types.SpecifierMinerPayout:
+				if output.WalletAddress {
+					minerPayouts = minerPayouts.Add(output.Value)
+				}
+			case types.SpecifierClaimOutput:
+				if output.WalletAddress {
+					claims = claims.Add(output.Value)
+				}
+			case types.SpecifierUplocoinOutput:
+				if _, ok := regularOutputs[output.ID]; ok && output.WalletAddress {
+					received = received.Add(output.Value)
+				}
+			case types.SpecifierMinerFee:
+				fees = fees.Add(output.Value)
+			}
+		}
+
+		if !minerPayouts.IsZero() {
+			e := newEntry(modules.WalletExportMinerPayout)
+			e.Inflow = minerPayouts
+			entries = append(entries, e)
+		}
+		if !claims.IsZero() {
+			e := newEntry(modules.WalletExportUplofundClaim)
+			e.Inflow = claims
+			entries = append(entries, e)
+		}
+
+		// The fees are only paid by the wallet if it funded the transaction.
+		// Whatever is left of the inputs after the change and the fees went
+		// to the contracts or to other addresses.
+		if spent.IsZero() {
+			fees = types.ZeroCurrency
+		} else if fees.Cmp(spent) > 0 {
+			fees = spent
+		}
+		if !fees.IsZero() {
+			e := newEntry(modules.WalletExportFee)
+			e.Outflow = fees
+			entries = append(entries, e)
+		}
+		spent = spent.Sub(fees)
+		if spent.Cmp(received) > 0 {
+			e := newEntry(modules.WalletExportSent)
+			if len(pt.Transaction.FileContracts) > 0 {
+				e.Category = modules.WalletExportContractFormation
+			}
+			e.Outflow = spent.Sub(received)
+			entries = append(entries, e)
+		} else if received.Cmp(spent) > 0 {
+			e := newEntry(modules.WalletExportReceived)
+			e.Inflow = received.Sub(spent)
+			entries = append(entries, e)
+		}
+
+		// Add the storage proof payouts of the contracts that were formed or
+		// last revised by the transaction.
+		for _, fcid := range contractIDs(pt.Transaction) {
+			if latest[fcid] != i {
+				continue
+			}
+			latest[fcid] = -1 // only report a contract once
+			if e, ok := storageProofEntry(pt, fcid, matured); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	// Storage proof payouts are reported at a later height than the
+	// transaction they were derived from, so the entries need to be sorted
+	// before computing the running balance.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Height < entries[j].Height
+	})
+	// The balance can become negative if the history is incomplete, e.g. if
+	// the wallet doesn't know all of its addresses.
+	balance := new(big.Int)
+	for i := range entries {
+		balance.Add(balance, entries[i].Inflow.Big())
+		balance.Sub(balance, entries[i].Outflow.Big())
+		entries[i].Balance = types.NewCurrency(new(big.Int).Abs(balance))
+		entries[i].BalanceNegative = balance.Sign() < 0
+	}
+	return entries
+}
+
+// storageProofEntry returns the entry of the storage proof payout of a
+// contract to the wallet. The payout is the sum of the outputs of the wallet
+// that consensus created for the contract, so a missed proof is reported with
+// the missed proof outputs.
+func storageProofEntry(pt modules.ProcessedTransaction, fcid types.FileContractID, matured map[types.UplocoinOutputID]maturedOutput) (modules.WalletExportEntry, bool) {
+	e := modules.WalletExportEntry{
+		TransactionID: pt.TransactionID,
+		Category:      modules.WalletExportStorageProof,
+	}
+	found := false
+	valid, missed := storageProofOutputIDs(fcid, pt.Transaction)
+	for _, id := range append(valid, missed...) {
+		mo, ok := matured[id]
+		if !ok {
+			continue
+		}
+		e.Inflow = e.Inflow.Add(mo.Value)
+		e.Height = mo.MaturityHeight
+		found = true
+	}
+	for _, id := range missed {
+		if _, ok := matured[id]; ok {
+			e.Category = modules.WalletExportMissedProof
+		}
+	}
+	return e, found
+}
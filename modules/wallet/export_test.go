@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestComputeExportEntries probes the categories and the running balance of
+// the entries of a wallet history export.
+func TestComputeExportEntries(t *testing.T) {
+	walletAddr := types.UnlockHash{1}
+	otherAddr := types.UnlockHash{2}
+	sc := types.UplocoinPrecision
+
+	// A miner payout to the wallet.
+	payout := modules.ProcessedTransaction{
+		TransactionID:         types.TransactionID{1},
+		ConfirmationHeight:    1,
+		ConfirmationTimestamp: 100,
+		Outputs: []modules.ProcessedOutput{{
+			FundType:       types.SpecifierMinerPayout,
+			WalletAddress:  true,
+			RelatedAddress: walletAddr,
+			Value:          sc.Mul64(100),
+		}},
+	}
+
+	// A transaction sending 60 UC to another address with 30 UC change and
+	// a fee of 10 UC.
+	send := modules.ProcessedTransaction{
+		Transaction: types.Transaction{
+			UplocoinOutputs: []types.UplocoinOutput{
+				{UnlockHash: otherAddr, Value: sc.Mul64(60)},
+				{UnlockHash: walletAddr, Value: sc.Mul64(30)},
+			},
+			MinerFees: []types.Currency{sc.Mul64(10)},
+		},
+		TransactionID:         types.TransactionID{2},
+		ConfirmationHeight:    2,
+		ConfirmationTimestamp: 200,
+		Inputs: []modules.ProcessedInput{{
+			FundType:       types.SpecifierUplocoinInput,
+			WalletAddress:  true,
+			RelatedAddress: walletAddr,
+			Value:          sc.Mul64(100),
+		}},
+	}
+	send.Outputs = []modules.ProcessedOutput{
+		{ID: types.OutputID(send.Transaction.UplocoinOutputID(0)), FundType: types.SpecifierUplocoinOutput, RelatedAddress: otherAddr, Value: sc.Mul64(60)},
+		{ID: types.OutputID(send.Transaction.UplocoinOutputID(1)), FundType: types.SpecifierUplocoinOutput, WalletAddress: true, RelatedAddress: walletAddr, Value: sc.Mul64(30)},
+		{FundType: types.SpecifierMinerFee, Value: sc.Mul64(10)},
+	}
+
+	// A transaction forming a contract with 20 UC and a fee of 5 UC. The
+	// valid proof output of the contract pays 20 UC back to the wallet, the
+	// missed proof output only 15 UC.
+	fc := types.FileContract{
+		WindowEnd:          10,
+		ValidProofOutputs:  []types.UplocoinOutput{{UnlockHash: walletAddr, Value: sc.Mul64(20)}},
+		MissedProofOutputs: []types.UplocoinOutput{{UnlockHash: walletAddr, Value: sc.Mul64(15)}},
+	}
+	formation := modules.ProcessedTransaction{
+		Transaction: types.Transaction{
+			UplocoinOutputs: []types.UplocoinOutput{{UnlockHash: walletAddr, Value: sc.Mul64(5)}},
+			FileContracts:   []types.FileContract{fc},
+			MinerFees:       []types.Currency{sc.Mul64(5)},
+		},
+		TransactionID:         types.TransactionID{3},
+		ConfirmationHeight:    3,
+		ConfirmationTimestamp: 300,
+		Inputs: []modules.ProcessedInput{{
+			FundType:       types.SpecifierUplocoinInput,
+			WalletAddress:  true,
+			RelatedAddress: walletAddr,
+			Value:          sc.Mul64(30),
+		}},
+	}
+	formation.Outputs = []modules.ProcessedOutput{
+		{ID: types.OutputID(formation.Transaction.UplocoinOutputID(0)), FundType: types.SpecifierUplocoinOutput, WalletAddress: true, RelatedAddress: walletAddr, Value: sc.Mul64(5)},
+		{ID: types.OutputID(formation.Transaction.FileContractID(0).StorageProofOutputID(types.ProofValid, 0)), FundType: types.SpecifierUplocoinOutput, WalletAddress: true, RelatedAddress: walletAddr, Value: sc.Mul64(20)},
+		{ID: types.OutputID(formation.Transaction.FileContractID(0).StorageProofOutputID(types.ProofMissed, 0)), FundType: types.SpecifierUplocoinOutput, WalletAddress: true, RelatedAddress: walletAddr, Value: sc.Mul64(15)},
+		{FundType: types.SpecifierMinerFee, Value: sc.Mul64(5)},
+	}
+	pts := []modules.ProcessedTransaction{payout, send, formation}
+
+	// Before a proof output of the contract matured there is no storage proof
+	// payout.
+	entries := computeExportEntries(pts, nil)
+	expected := []struct {
+		category modules.WalletExportCategory
+		inflow   types.Currency
+		outflow  types.Currency
+		balance  types.Currency
+	}{
+		{modules.WalletExportMinerPayout, sc.Mul64(100), types.ZeroCurrency, sc.Mul64(100)},
+		{modules.WalletExportFee, types.ZeroCurrency, sc.Mul64(10), sc.Mul64(90)},
+		{modules.WalletExportSent, types.ZeroCurrency, sc.Mul64(60), sc.Mul64(30)},
+		{modules.WalletExportFee, types.ZeroCurrency, sc.Mul64(5), sc.Mul64(25)},
+		{modules.WalletExportContractFormation, types.ZeroCurrency, sc.Mul64(20), sc.Mul64(5)},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %v entries, got %v", len(expected), len(entries))
+	}
+	for i, e := range expected {
+		got := entries[i]
+		if got.Category != e.category || !got.Inflow.Equals(e.inflow) || !got.Outflow.Equals(e.outflow) || !got.Balance.Equals(e.balance) {
+			t.Fatalf("entry %v: unexpected entry %+v", i, got)
+		}
+	}
+
+	// Once the valid proof output matured, the payout is reported at the
+	// maturity height.
+	fcid := formation.Transaction.FileContractID(0)
+	maturityHeight := fc.WindowEnd + types.MaturityDelay
+	entries = computeExportEntries(pts, map[types.UplocoinOutputID]maturedOutput{
+		fcid.StorageProofOutputID(types.ProofValid, 0): {Value: sc.Mul64(20), MaturityHeight: maturityHeight},
+	})
+	if len(entries) != len(expected)+1 {
+		t.Fatalf("expected %v entries, got %v", len(expected)+1, len(entries))
+	}
+	last := entries[len(entries)-1]
+	if last.Category != modules.WalletExportStorageProof || last.Height != maturityHeight || last.Timestamp != 0 {
+		t.Fatal("unexpected storage proof entry", last)
+	}
+	if !last.Inflow.Equals(sc.Mul64(20)) || !last.Balance.Equals(sc.Mul64(25)) {
+		t.Fatal("unexpected storage proof payout", last.Inflow, last.Balance)
+	}
+
+	// If the proof was missed, the missed proof output is reported instead.
+	entries = computeExportEntries(pts, map[types.UplocoinOutputID]maturedOutput{
+		fcid.StorageProofOutputID(types.ProofMissed, 0): {Value: sc.Mul64(15), MaturityHeight: maturityHeight},
+	})
+	if len(entries) != len(expected)+1 {
+		t.Fatalf("expected %v entries, got %v", len(expected)+1, len(entries))
+	}
+	last = entries[len(entries)-1]
+	if last.Category != modules.WalletExportMissedProof || last.Height != maturityHeight {
+		t.Fatal("unexpected missed proof entry", last)
+	}
+	if !last.Inflow.Equals(sc.Mul64(15)) || !last.Balance.Equals(sc.Mul64(20)) {
+		t.Fatal("unexpected missed proof payout", last.Inflow, last.Balance)
+	}
+
+	// Without the miner payout the history is incomplete and the balance
+	// becomes negative instead of being clamped to zero.
+	entries = computeExportEntries([]modules.ProcessedTransaction{send}, nil)
+	last = entries[len(entries)-1]
+	if !last.BalanceNegative || !last.Balance.Equals(sc.Mul64(70)) {
+		t.Fatal("expected a negative balance", last.Balance, last.BalanceNegative)
+	}
+	if !entries[0].BalanceNegative || !entries[0].Balance.Equals(sc.Mul64(10)) {
+		t.Fatal("expected a negative balance after the fee", entries[0].Balance, entries[0].BalanceNegative)
+	}
+}
@@ -11,6 +11,10 @@ import (
 // Uplocoins.
 const estimatedTransactionSize = 750
 
+// estimatedInputSize is the estimated size that an explicitly selected input
+// adds to a transaction set, including its signature.
+const estimatedInputSize = 250
+
 // sortedOutputs is a struct containing a slice of Uplocoin outputs and their
 // corresponding ids. sortedOutputs can be sorted using the sort package.
 type sortedOutputs struct {
@@ -120,7 +124,39 @@ func (w *Wallet) SendUplocoins(amount types.Currency, dest types.UnlockHash) ([]
 
 	_, fee := w.tpool.FeeEstimation()
 	fee = fee.Mul64(estimatedTransactionSize)
-	return w.managedSendUplocoins(amount, fee, dest)
+	return w.managedSendUplocoins(modules.DefaultWalletAccount, amount, fee, dest, nil)
+}
+
+// SendUplocoinsFromAccount creates a transaction sending 'amount' to 'dest',
+// which is funded by the outputs of the given account. The transaction is
+// submitted to the transaction pool and is also returned. Fees are added to
+// the amount sent.
+func (w *Wallet) SendUplocoinsFromAccount(account string, amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	_, fee := w.tpool.FeeEstimation()
+	fee = fee.Mul64(estimatedTransactionSize)
+	return w.managedSendUplocoins(account, amount, fee, dest, nil)
+}
+
+// SendUplocoinsFromOutputs creates a transaction sending 'amount' to 'dest',
+// which is funded by spending the given outputs of the wallet. The transaction
+// is submitted to the transaction pool and is also returned. Fees are added to
+// the amount sent.
+func (w *Wallet) SendUplocoinsFromOutputs(amount types.Currency, dest types.UnlockHash, inputs []types.UplocoinOutputID) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	_, fee := w.tpool.FeeEstimation()
+	fee = fee.Mul64(estimatedTransactionSize + estimatedInputSize*uint64(len(inputs)))
+	return w.managedSendUplocoins(modules.DefaultWalletAccount, amount, fee, dest, inputs)
 }
 
 // SendUplocoinsFeeIncluded creates a transaction sending 'amount' to 'dest'. The
@@ -141,15 +177,44 @@ func (w *Wallet) SendUplocoinsFeeIncluded(amount types.Currency, dest types.Unlo
 		w.log.Println("Attempt to send coins has failed - not enough to cover fee")
 		return nil, errors.AddContext(modules.ErrLowBalance, "not enough coins to cover fee")
 	}
-	return w.managedSendUplocoins(amount.Sub(fee), fee, dest)
+	return w.managedSendUplocoins(modules.DefaultWalletAccount, amount.Sub(fee), fee, dest, nil)
 }
 
-// managedSendUplocoins creates a transaction sending 'amount' to 'dest'. The
-// transaction is submitted to the transaction pool and is also returned.
-func (w *Wallet) managedSendUplocoins(amount, fee types.Currency, dest types.UnlockHash) (txns []types.Transaction, err error) {
+// managedSendUplocoins creates a transaction sending 'amount' to 'dest', which
+// is funded by the given account. The transaction is submitted to the
+// transaction pool and is also returned. If inputs is not empty, the
+// transaction is funded by spending these outputs.
+func (w *Wallet) managedSendUplocoins(account string, amount, fee types.Currency, dest types.UnlockHash, inputs []types.UplocoinOutputID) (txns []types.Transaction, err error) {
+	txnBuilder, txnSet, err := w.managedCreateUplocoinsTransaction(account, amount, fee, dest, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if w.deps.Disrupt("SendUplocoinsInterrupted") {
+		txnBuilder.Drop()
+		return nil, errors.New("failed to accept transaction set (SendUplocoinsInterrupted)")
+	}
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		txnBuilder.Drop()
+		w.log.Println("Attempt to send coins has failed - transaction pool rejected transaction:", err)
+		return nil, build.ExtendErr("unable to get transaction accepted", err)
+	}
+	w.log.Println("Submitted a Uplocoin transfer transaction set for value", amount.HumanString(), "with fees", fee.HumanString(), "IDs:")
+	for _, txn := range txnSet {
+		w.log.Println("\t", txn.ID())
+	}
+	return txnSet, nil
+}
+
+// managedCreateUplocoinsTransaction creates and signs a transaction sending
+// 'amount' to 'dest', which is funded by the given account, without
+// submitting it to the transaction pool. If inputs is not empty, the
+// transaction is funded by spending these outputs. The caller has to drop the
+// returned builder if the transaction isn't submitted.
+func (w *Wallet) managedCreateUplocoinsTransaction(account string, amount, fee types.Currency, dest types.UnlockHash, inputs []types.UplocoinOutputID) (txnBuilder modules.TransactionBuilder, txnSet []types.Transaction, err error) {
 	// Check if consensus is synced
 	if !w.cs.Synced() || w.deps.Disrupt("UnsyncedConsensus") {
-		return nil, errors.New("cannot send Uplocoin until fully synced")
+		return nil, nil, errors.New("cannot send Uplocoin until fully synced")
 	}
 
 	w.mu.RLock()
@@ -157,7 +222,7 @@ func (w *Wallet) managedSendUplocoins(amount, fee types.Currency, dest types.Unl
 	w.mu.RUnlock()
 	if !unlocked {
 		w.log.Println("Attempt to send coins has failed - wallet is locked")
-		return nil, modules.ErrLockedWallet
+		return nil, nil, modules.ErrLockedWallet
 	}
 
 	output := types.UplocoinOutput{
@@ -165,40 +230,32 @@ func (w *Wallet) managedSendUplocoins(amount, fee types.Currency, dest types.Unl
 		UnlockHash: dest,
 	}
 
-	txnBuilder, err := w.StartTransaction()
+	txnBuilder, err = w.StartAccountTransaction(account)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() {
 		if err != nil {
 			txnBuilder.Drop()
 		}
 	}()
-	err = txnBuilder.FundUplocoins(amount.Add(fee))
+	if len(inputs) > 0 {
+		err = txnBuilder.FundUplocoinsFromOutputs(amount.Add(fee), inputs)
+	} else {
+		err = txnBuilder.FundUplocoins(amount.Add(fee))
+	}
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to fund transaction:", err)
-		return nil, build.ExtendErr("unable to fund transaction", err)
+		return nil, nil, build.ExtendErr("unable to fund transaction", err)
 	}
 	txnBuilder.AddMinerFee(fee)
 	txnBuilder.AddUplocoinOutput(output)
-	txnSet, err := txnBuilder.Sign(true)
+	txnSet, err = txnBuilder.Sign(true)
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to sign transaction:", err)
-		return nil, build.ExtendErr("unable to sign transaction", err)
-	}
-	if w.deps.Disrupt("SendUplocoinsInterrupted") {
-		return nil, errors.New("failed to accept transaction set (SendUplocoinsInterrupted)")
+		return nil, nil, build.ExtendErr("unable to sign transaction", err)
 	}
-	err = w.tpool.AcceptTransactionSet(txnSet)
-	if err != nil {
-		w.log.Println("Attempt to send coins has failed - transaction pool rejected transaction:", err)
-		return nil, build.ExtendErr("unable to get transaction accepted", err)
-	}
-	w.log.Println("Submitted a Uplocoin transfer transaction set for value", amount.HumanString(), "with fees", fee.HumanString(), "IDs:")
-	for _, txn := range txnSet {
-		w.log.Println("\t", txn.ID())
-	}
-	return txnSet, nil
+	return txnBuilder, txnSet, nil
 }
 
 // SendUplocoinsMulti creates a transaction that includes the specified
@@ -211,7 +268,42 @@ func (w *Wallet) SendUplocoinsMulti(outputs []types.UplocoinOutput) (txns []type
 	}
 	defer w.tg.Done()
 	w.log.Println("Beginning call to SendUplocoinsMulti")
+	return w.managedSendUplocoinsMulti(modules.DefaultWalletAccount, outputs, nil)
+}
+
+// SendUplocoinsMultiFromAccount creates a transaction that includes the
+// specified outputs and is funded by the outputs of the given account. The
+// transaction is submitted to the transaction pool and is also returned.
+func (w *Wallet) SendUplocoinsMultiFromAccount(account string, outputs []types.UplocoinOutput) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.log.Println("Beginning call to SendUplocoinsMultiFromAccount")
+	return w.managedSendUplocoinsMulti(account, outputs, nil)
+}
 
+// SendUplocoinsMultiFromOutputs creates a transaction that includes the
+// specified outputs and is funded by spending the given inputs of the wallet.
+// The transaction is submitted to the transaction pool and is also returned.
+func (w *Wallet) SendUplocoinsMultiFromOutputs(outputs []types.UplocoinOutput, inputs []types.UplocoinOutputID) (txns []types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		err = modules.ErrWalletShutdown
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.log.Println("Beginning call to SendUplocoinsMultiFromOutputs")
+	if len(inputs) == 0 {
+		return nil, errors.New("no inputs were selected")
+	}
+	return w.managedSendUplocoinsMulti(modules.DefaultWalletAccount, outputs, inputs)
+}
+
+// managedSendUplocoinsMulti creates a transaction that includes the specified
+// outputs and is funded by the given account. If inputs is not empty, the
+// transaction is funded by spending these outputs of the wallet.
+func (w *Wallet) managedSendUplocoinsMulti(account string, outputs []types.UplocoinOutput, inputs []types.UplocoinOutputID) (txns []types.Transaction, err error) {
 	// Check if consensus is synced
 	if !w.cs.Synced() || w.deps.Disrupt("UnsyncedConsensus") {
 		return nil, errors.New("cannot send Uplocoin until fully synced")
@@ -225,7 +317,7 @@ func (w *Wallet) SendUplocoinsMulti(outputs []types.UplocoinOutput) (txns []type
 		return nil, modules.ErrLockedWallet
 	}
 
-	txnBuilder, err := w.StartTransaction()
+	txnBuilder, err := w.StartAccountTransaction(account)
 	if err != nil {
 		return nil, err
 	}
@@ -235,10 +327,11 @@ func (w *Wallet) SendUplocoinsMulti(outputs []types.UplocoinOutput) (txns []type
 		}
 	}()
 
-	// Add estimated transaction fee.
+	// Add estimated transaction fee. Selected inputs are spent by a parent
+	// transaction, which grows the transaction set.
 	_, tpoolFee := w.tpool.FeeEstimation()
-	tpoolFee = tpoolFee.Mul64(2)                              // We don't want send-to-many transactions to fail.
-	tpoolFee = tpoolFee.Mul64(1000 + 60*uint64(len(outputs))) // Estimated transaction size in bytes
+	tpoolFee = tpoolFee.Mul64(2) // We don't want send-to-many transactions to fail.
+	tpoolFee = tpoolFee.Mul64(1000 + 60*uint64(len(outputs)) + estimatedInputSize*uint64(len(inputs)))
 	txnBuilder.AddMinerFee(tpoolFee)
 
 	// Calculate total cost to wallet.
@@ -250,7 +343,11 @@ func (w *Wallet) SendUplocoinsMulti(outputs []types.UplocoinOutput) (txns []type
 	for _, sco := range outputs {
 		totalCost = totalCost.Add(sco.Value)
 	}
-	err = txnBuilder.FundUplocoins(totalCost)
+	if len(inputs) > 0 {
+		err = txnBuilder.FundUplocoinsFromOutputs(totalCost, inputs)
+	} else {
+		err = txnBuilder.FundUplocoins(totalCost)
+	}
 	if err != nil {
 		return nil, build.ExtendErr("unable to fund transaction", err)
 	}
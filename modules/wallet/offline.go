@@ -97,10 +97,16 @@ outer:
 		}
 	}
 
-	// mark the watch-only outputs
+	// mark the watch-only and frozen outputs and add their labels and
+	// accounts
 	for i, o := range outputs {
 		_, ok := w.watchedAddrs[o.UnlockHash]
 		outputs[i].IsWatchOnly = ok
+		if o.FundType == types.SpecifierUplocoinOutput {
+			outputs[i].Frozen = dbIsFrozenOutput(w.dbTx, types.UplocoinOutputID(o.ID))
+		}
+		outputs[i].Label = dbGetLabel(w.dbTx, modules.WalletLabelOutput, crypto.Hash(o.ID))
+		outputs[i].Account = w.addressAccount(o.UnlockHash)
 	}
 
 	return outputs, nil
@@ -346,10 +352,9 @@ func (w *Wallet) AddWatchAddresses(addrs []types.UnlockHash, unused bool) error
 		done := make(chan struct{})
 		go w.rescanMessage(done)
 		defer close(done)
-		if err := w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan()); err != nil {
+		if err := w.managedSubscribe(modules.ConsensusChangeBeginning); err != nil {
 			return err
 		}
-		w.tpool.TransactionPoolSubscribe(w)
 	}
 
 	return nil
@@ -430,10 +435,9 @@ func (w *Wallet) RemoveWatchAddresses(addrs []types.UnlockHash, unused bool) err
 		done := make(chan struct{})
 		go w.rescanMessage(done)
 		defer close(done)
-		if err := w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan()); err != nil {
+		if err := w.managedSubscribe(modules.ConsensusChangeBeginning); err != nil {
 			return err
 		}
-		w.tpool.TransactionPoolSubscribe(w)
 	}
 
 	return nil
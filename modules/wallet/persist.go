@@ -50,6 +50,8 @@ func (w *Wallet) openDB(filename string) (err error) {
 	err = w.db.Update(func(tx *bolt.Tx) error {
 		// check whether we need to init bucketAddrTransactions
 		buildAddrTxns := tx.Bucket(bucketAddrTransactions) == nil
+		// check whether the matured outputs need to be rescanned
+		rescanMatured := tx.Bucket(bucketMaturedOutputs) == nil
 		// ensure that all buckets exist
 		for _, b := range dbBuckets {
 			_, err := tx.CreateBucketIfNotExists(b)
@@ -92,6 +94,24 @@ func (w *Wallet) openDB(filename string) (err error) {
 			}
 		}
 
+		// COMPATv154 wallets created before the matured outputs were tracked
+		// rescan the blockchain to learn the outcome of their contracts.
+		if rescanMatured && tx.Bucket(bucketWallet).Get(keyConsensusChange) != nil &&
+			dbGetConsensusChangeID(tx) != modules.ConsensusChangeBeginning {
+			if err := tx.DeleteBucket(bucketProcessedTransactions); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(bucketProcessedTransactions); err != nil {
+				return err
+			}
+			if err := dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning); err != nil {
+				return err
+			}
+			if err := dbPutConsensusHeight(tx, 0); err != nil {
+				return err
+			}
+		}
+
 		// check whether wallet is encrypted
 		w.encrypted = tx.Bucket(bucketWallet).Get(keyEncryptionVerification) != nil
 		return nil
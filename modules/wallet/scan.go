@@ -57,6 +57,7 @@ type seedScanner struct {
 	dustThreshold    types.Currency              // minimum value of outputs to be included
 	keys             map[types.UnlockHash]uint64 // map address to seed index
 	largestIndexSeen uint64                      // largest index that has appeared in the blockchain
+	keySeen          bool                        // whether any key has appeared in the blockchain
 	scannedHeight    types.BlockHeight
 	seed             modules.Seed
 	UplocoinOutputs   map[types.UplocoinOutputID]scannedOutput
@@ -123,6 +124,7 @@ func (s *seedScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
 		index, exists := s.keys[diff.UplocoinOutput.UnlockHash]
 		if exists {
 			s.log.Debugln("Seed scanner found a key used at index", index)
+			s.keySeen = true
 			if index > s.largestIndexSeen {
 				s.largestIndexSeen = index
 			}
@@ -132,6 +134,7 @@ func (s *seedScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
 		index, exists := s.keys[diff.UplofundOutput.UnlockHash]
 		if exists {
 			s.log.Debugln("Seed scanner found a key used at index", index)
+			s.keySeen = true
 			if index > s.largestIndexSeen {
 				s.largestIndexSeen = index
 			}
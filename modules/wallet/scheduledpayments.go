@@ -0,0 +1,459 @@
+package wallet
+
+import (
+	"github.com/uplo-tech/bolt"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// maxScheduledPaymentNameLength is the maximum length of the name of a
+// scheduled payment.
+const maxScheduledPaymentNameLength = 64
+
+var (
+	// scheduledPaymentSettleDepth is the number of confirmations after which
+	// a scheduled payment is considered final. Until then, the wallet
+	// rebroadcasts the payment if it's reverted by a reorg.
+	scheduledPaymentSettleDepth = types.MaturityDelay
+)
+
+var (
+	// errScheduledPaymentExists is returned when a scheduled payment is added
+	// with the name of an existing scheduled payment.
+	errScheduledPaymentExists = errors.New("scheduled payment already exists")
+
+	// errInvalidScheduledPayment is returned when a scheduled payment is added
+	// with invalid parameters.
+	errInvalidScheduledPayment = errors.New("invalid scheduled payment")
+)
+
+type (
+	// scheduledPayment is the persisted state of a scheduled payment.
+	scheduledPayment struct {
+		Destination types.UnlockHash
+		Amount      types.Currency
+		Account     string
+		StartHeight types.BlockHeight
+		Interval    types.BlockHeight
+		Count       uint64
+		Payments    []scheduledPaymentRecord
+		LastError   string
+	}
+
+	// scheduledPaymentRecord is the persisted state of a payment that was
+	// sent for a scheduled payment. The transaction set is kept until the
+	// payment is settled so that it can be rebroadcast after a reorg.
+	// DoubleSpent is set if an input of the transaction set was spent by a
+	// different confirmed transaction, which means that the set can never
+	// confirm. Rejected is the error of the transaction pool if it rejected
+	// the set for a reason that rebroadcasting it won't fix.
+	scheduledPaymentRecord struct {
+		Height             types.BlockHeight
+		TransactionID      types.TransactionID
+		Transactions       []types.Transaction
+		Confirmed          bool
+		ConfirmationHeight types.BlockHeight
+		DoubleSpent        bool
+		Rejected           string
+	}
+)
+
+// done returns whether all payments of the scheduled payment were sent.
+func (sp scheduledPayment) done() bool {
+	if sp.Interval == 0 {
+		return len(sp.Payments) > 0
+	}
+	return sp.Count > 0 && uint64(len(sp.Payments)) >= sp.Count
+}
+
+// nextHeight returns the height the next payment is scheduled for.
+func (sp scheduledPayment) nextHeight() types.BlockHeight {
+	return sp.StartHeight + types.BlockHeight(len(sp.Payments))*sp.Interval
+}
+
+// settled returns whether the payment is final.
+func (r scheduledPaymentRecord) settled(height types.BlockHeight) bool {
+	return r.Confirmed && height >= r.ConfirmationHeight+scheduledPaymentSettleDepth
+}
+
+// status returns the status of the payment.
+func (r scheduledPaymentRecord) status() modules.ScheduledPaymentStatus {
+	switch {
+	case r.Confirmed:
+		return modules.ScheduledPaymentConfirmed
+	case r.Rejected != "":
+		return modules.ScheduledPaymentStuck
+	default:
+		return modules.ScheduledPaymentPending
+	}
+}
+
+// setPoolResult records the result of submitting the transaction set of the
+// payment to the transaction pool. Only errors which won't go away by
+// rebroadcasting the set mark the payment as rejected. Other errors, e.g.
+// because the pool is full, the fees are too low at the moment or the set
+// conflicts with another unconfirmed set, are transient.
+func (r *scheduledPaymentRecord) setPoolResult(err error) {
+	permanent := err != nil && (modules.IsConsensusConflict(err) ||
+		errors.Contains(err, modules.ErrLargeTransaction) ||
+		errors.Contains(err, modules.ErrLargeTransactionSet) ||
+		errors.Contains(err, modules.ErrInvalidArbPrefix))
+	r.Rejected = ""
+	if permanent {
+		r.Rejected = err.Error()
+	}
+}
+
+// validateScheduledPaymentName checks that the name of a scheduled payment is
+// valid.
+func validateScheduledPaymentName(name string) error {
+	if name == "" || len(name) > maxScheduledPaymentNameLength {
+		return errors.AddContext(errInvalidScheduledPayment, "name must be 1-64 characters long")
+	}
+	return nil
+}
+
+// dbTransactionSetDoubleSpent returns whether an input of the transaction set
+// was spent by a confirmed transaction outside of the set. Inputs spending
+// outputs created within the set are ignored.
+func dbTransactionSetDoubleSpent(tx *bolt.Tx, txnSet []types.Transaction) bool {
+	ids := make(map[types.TransactionID]struct{})
+	created := make(map[types.OutputID]struct{})
+	for _, txn := range txnSet {
+		ids[txn.ID()] = struct{}{}
+		for i := range txn.UplocoinOutputs {
+			created[types.OutputID(txn.UplocoinOutputID(uint64(i)))] = struct{}{}
+		}
+	}
+	for _, txn := range txnSet {
+		for _, sci := range txn.UplocoinInputs {
+			parentID := types.OutputID(sci.ParentID)
+			if _, exists := created[parentID]; exists {
+				continue
+			}
+			indices, err := dbGetAddrTransactions(tx, sci.UnlockConditions.UnlockHash())
+			if err != nil {
+				continue
+			}
+			for _, index := range indices {
+				pt, err := dbGetProcessedTransaction(tx, index)
+				if err != nil {
+					continue
+				}
+				if _, exists := ids[pt.TransactionID]; exists {
+					continue
+				}
+				for _, input := range pt.Inputs {
+					if input.ParentID == parentID {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// AddScheduledPayment adds a payment that the wallet sends automatically at
+// the scheduled heights. Payments are only sent while the wallet is unlocked
+// and synced. The start height can't be in the past. Payments that were
+// missed, e.g. because uplod wasn't running, are caught up one payment per
+// block.
+func (w *Wallet) AddScheduledPayment(sp modules.ScheduledPayment) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if err := validateScheduledPaymentName(sp.Name); err != nil {
+		return err
+	}
+	if sp.Amount.IsZero() {
+		return errors.AddContext(errInvalidScheduledPayment, "amount must be greater than zero")
+	}
+	if sp.Interval == 0 && sp.Count > 1 {
+		return errors.AddContext(errInvalidScheduledPayment, "a payment without interval can only be sent once")
+	}
+	if sp.Account == "" {
+		sp.Account = modules.DefaultWalletAccount
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkAccount(w.dbTx, sp.Account); err != nil {
+		return err
+	}
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return err
+	}
+	if sp.StartHeight < height {
+		return errors.AddContext(errInvalidScheduledPayment, "start height can't be in the past")
+	}
+	if _, err := dbGetScheduledPayment(w.dbTx, sp.Name); err == nil {
+		return errScheduledPaymentExists
+	}
+	err = dbPutScheduledPayment(w.dbTx, sp.Name, scheduledPayment{
+		Destination: sp.Destination,
+		Amount:      sp.Amount,
+		Account:     sp.Account,
+		StartHeight: sp.StartHeight,
+		Interval:    sp.Interval,
+		Count:       sp.Count,
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.syncDB(); err != nil {
+		return err
+	}
+	wake(w.staticScheduledPaymentsWake)
+	return nil
+}
+
+// RemoveScheduledPayment stops and removes a scheduled payment. Payments that
+// were already sent are not affected.
+func (w *Wallet) RemoveScheduledPayment(name string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := dbGetScheduledPayment(w.dbTx, name); err != nil {
+		return err
+	}
+	if err := dbDeleteScheduledPayment(w.dbTx, name); err != nil {
+		return err
+	}
+	return w.syncDB()
+}
+
+// ScheduledPayments returns the scheduled payments of the wallet.
+func (w *Wallet) ScheduledPayments() ([]modules.ScheduledPayment, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sps := []modules.ScheduledPayment{}
+	err := dbForEachScheduledPayment(w.dbTx, func(name string, sp scheduledPayment) {
+		records := make([]modules.ScheduledPaymentRecord, 0, len(sp.Payments))
+		for _, r := range sp.Payments {
+			records = append(records, modules.ScheduledPaymentRecord{
+				Height:             r.Height,
+				TransactionID:      r.TransactionID,
+				Confirmed:          r.Confirmed,
+				ConfirmationHeight: r.ConfirmationHeight,
+				Status:             r.status(),
+				Error:              r.Rejected,
+			})
+		}
+		var next types.BlockHeight
+		if !sp.done() {
+			next = sp.nextHeight()
+		}
+		sps = append(sps, modules.ScheduledPayment{
+			Name:        name,
+			Destination: sp.Destination,
+			Amount:      sp.Amount,
+			Account:     sp.Account,
+			StartHeight: sp.StartHeight,
+			Interval:    sp.Interval,
+			Count:       sp.Count,
+			NextHeight:  next,
+			Payments:    records,
+			LastError:   sp.LastError,
+		})
+	})
+	return sps, err
+}
+
+// updateScheduledPaymentConfirmations updates the confirmation state of the
+// unsettled payments of a scheduled payment and checks whether the inputs of
+// the unconfirmed ones were spent by a different transaction. Settled payments
+// drop their transaction set. It returns whether any payment needs to be
+// rebroadcast.
+func (w *Wallet) updateScheduledPaymentConfirmations(sp *scheduledPayment, height types.BlockHeight) (rebroadcast bool) {
+	for i := range sp.Payments {
+		r := &sp.Payments[i]
+		if r.Transactions == nil {
+			continue
+		}
+		r.Confirmed = false
+		r.ConfirmationHeight = 0
+		r.DoubleSpent = false
+		if key, err := dbGetTransactionIndex(w.dbTx, r.TransactionID); err == nil {
+			var pt modules.ProcessedTransaction
+			if err := decodeProcessedTransaction(w.dbTx.Bucket(bucketProcessedTransactions).Get(key), &pt); err == nil {
+				r.Confirmed = true
+				r.ConfirmationHeight = pt.ConfirmationHeight
+			}
+		}
+		if r.Confirmed {
+			r.Rejected = ""
+		} else {
+			r.DoubleSpent = dbTransactionSetDoubleSpent(w.dbTx, r.Transactions)
+		}
+		if r.settled(height) {
+			r.Transactions = nil
+		}
+		rebroadcast = rebroadcast || (r.Transactions != nil && !r.Confirmed)
+	}
+	return rebroadcast
+}
+
+// managedRebroadcastScheduledPayment rebroadcasts the unconfirmed payments of
+// a scheduled payment. A payment is only sent again once its inputs were
+// spent by a different confirmed transaction. As long as that's not the case,
+// the original transaction set can still confirm and sending the payment
+// again could pay the destination twice.
+func (w *Wallet) managedRebroadcastScheduledPayment(name string, sp *scheduledPayment) {
+	for i := range sp.Payments {
+		r := &sp.Payments[i]
+		if r.Transactions == nil || r.Confirmed {
+			continue
+		}
+		if !r.DoubleSpent {
+			// The pool might reject the set for reasons that don't affect
+			// whether it can still confirm, e.g. because it's full. It's
+			// rebroadcast again on the next block. If the set is rejected
+			// for a different reason, the payment is reported as stuck.
+			err := w.tpool.AcceptTransactionSet(r.Transactions)
+			if errors.Contains(err, modules.ErrDuplicateTransactionSet) {
+				err = nil
+			}
+			if err != nil {
+				w.log.Debugln("Unable to rebroadcast scheduled payment:", err)
+			}
+			r.setPoolResult(err)
+			continue
+		}
+		w.log.Println("Inputs of scheduled payment", name, "were spent by a different transaction, sending it again")
+		if err := w.managedSendScheduledPayment(name, sp, i); err != nil {
+			sp.LastError = err.Error()
+		}
+	}
+}
+
+// managedSendScheduledPayment creates the transaction set of the i-th payment
+// of a scheduled payment and submits it to the transaction pool. The payment
+// is persisted before it's submitted, so it's never sent twice.
+func (w *Wallet) managedSendScheduledPayment(name string, sp *scheduledPayment, i int) error {
+	_, fee := w.tpool.FeeEstimation()
+	fee = fee.Mul64(estimatedTransactionSize)
+	txnBuilder, txnSet, err := w.managedCreateUplocoinsTransaction(sp.Account, sp.Amount, fee, sp.Destination, nil)
+	if err != nil {
+		return err
+	}
+	r := &sp.Payments[i]
+	r.TransactionID = txnSet[len(txnSet)-1].ID()
+	r.Transactions = txnSet
+	r.Confirmed = false
+	r.ConfirmationHeight = 0
+	r.DoubleSpent = false
+	r.Rejected = ""
+	if err := w.managedUpdateScheduledPayment(name, *sp); err != nil {
+		txnBuilder.Drop()
+		return err
+	}
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	r.setPoolResult(err)
+	if err != nil {
+		// The payment stays recorded and is rebroadcast on the next blocks.
+		return errors.AddContext(err, "transaction pool rejected the payment")
+	}
+	w.log.Printf("Sent scheduled payment %v of %v to %v\n", name, sp.Amount.HumanString(), sp.Destination)
+	return nil
+}
+
+// managedProcessScheduledPayments sends the scheduled payments that are due
+// and rebroadcasts the payments that were reverted by a reorg. It's only called
+// by threadedSyncedLoop, so a payment is never sent twice by concurrent calls.
+func (w *Wallet) managedProcessScheduledPayments() {
+	if !w.managedUnlocked() || !w.cs.Synced() {
+		return
+	}
+
+	// Update the confirmations of the payments and determine which scheduled
+	// payments need attention.
+	w.mu.Lock()
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		w.mu.Unlock()
+		w.log.Println("ERROR: failed to get the consensus height:", err)
+		return
+	}
+	pending := make(map[string]scheduledPayment)
+	updated := make(map[string]scheduledPayment)
+	err = dbForEachScheduledPayment(w.dbTx, func(name string, sp scheduledPayment) {
+		for _, r := range sp.Payments {
+			if r.Transactions != nil {
+				updated[name] = sp
+				break
+			}
+		}
+		rebroadcast := w.updateScheduledPaymentConfirmations(&sp, height)
+		if _, ok := updated[name]; ok {
+			updated[name] = sp
+		}
+		if rebroadcast || (!sp.done() && sp.nextHeight() <= height) {
+			pending[name] = sp
+		}
+	})
+	for name, sp := range updated {
+		if err != nil {
+			break
+		}
+		err = dbPutScheduledPayment(w.dbTx, name, sp)
+	}
+	if err == nil && len(updated) > 0 {
+		err = w.syncDB()
+	}
+	w.mu.Unlock()
+	if err != nil {
+		w.log.Println("ERROR: failed to update scheduled payments:", err)
+		return
+	}
+
+	for name, sp := range pending {
+		w.managedRebroadcastScheduledPayment(name, &sp)
+
+		// Send the next payment if it's due. If several payments were missed,
+		// only one of them is sent per block to avoid draining the account at
+		// once.
+		if !sp.done() && sp.nextHeight() <= height {
+			sp.Payments = append(sp.Payments, scheduledPaymentRecord{Height: sp.nextHeight()})
+			err := w.managedSendScheduledPayment(name, &sp, len(sp.Payments)-1)
+			if sp.Payments[len(sp.Payments)-1].Transactions == nil {
+				// The payment couldn't be created.
+				sp.Payments = sp.Payments[:len(sp.Payments)-1]
+			}
+			if err != nil {
+				sp.LastError = err.Error()
+			} else {
+				sp.LastError = ""
+			}
+		}
+		if err := w.managedUpdateScheduledPayment(name, sp); err != nil {
+			w.log.Println("ERROR: failed to update scheduled payment:", err)
+			return
+		}
+	}
+}
+
+// managedUpdateScheduledPayment persists the state of a scheduled payment
+// unless it was removed in the meantime.
+func (w *Wallet) managedUpdateScheduledPayment(name string, sp scheduledPayment) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := dbGetScheduledPayment(w.dbTx, name); errors.Contains(err, modules.ErrUnknownScheduledPayment) {
+		return nil
+	}
+	if err := dbPutScheduledPayment(w.dbTx, name, sp); err != nil {
+		return err
+	}
+	return w.syncDB()
+}
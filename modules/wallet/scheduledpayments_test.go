@@ -0,0 +1,252 @@
+package wallet
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestScheduledPaymentDone probes the done and nextHeight methods of
+// scheduledPayment.
+func TestScheduledPaymentDone(t *testing.T) {
+	// A payment without interval is sent once.
+	sp := scheduledPayment{StartHeight: 10}
+	if sp.done() || sp.nextHeight() != 10 {
+		t.Fatal("unexpected state", sp.done(), sp.nextHeight())
+	}
+	sp.Payments = append(sp.Payments, scheduledPaymentRecord{Height: 10})
+	if !sp.done() {
+		t.Fatal("payment without interval should be done after one payment")
+	}
+
+	// A payment with interval and count is sent count times.
+	sp = scheduledPayment{StartHeight: 10, Interval: 5, Count: 2}
+	sp.Payments = append(sp.Payments, scheduledPaymentRecord{Height: 10})
+	if sp.done() || sp.nextHeight() != 15 {
+		t.Fatal("unexpected state", sp.done(), sp.nextHeight())
+	}
+	sp.Payments = append(sp.Payments, scheduledPaymentRecord{Height: 15})
+	if !sp.done() {
+		t.Fatal("payment should be done after count payments")
+	}
+
+	// A payment without count is never done.
+	sp.Count = 0
+	if sp.done() || sp.nextHeight() != 20 {
+		t.Fatal("unexpected state", sp.done(), sp.nextHeight())
+	}
+}
+
+// TestScheduledPaymentStatus probes that only payments which were rejected
+// for permanent reasons are reported as stuck.
+func TestScheduledPaymentStatus(t *testing.T) {
+	var r scheduledPaymentRecord
+	if r.status() != modules.ScheduledPaymentPending {
+		t.Fatal("unexpected status", r.status())
+	}
+
+	// Transient errors don't mark the payment as stuck.
+	r.setPoolResult(errors.AddContext(modules.ErrDuplicateTransactionSet, "rejected"))
+	if r.status() != modules.ScheduledPaymentPending {
+		t.Fatal("unexpected status", r.status())
+	}
+	r.setPoolResult(errors.New("transaction set needs more miner fees to be accepted"))
+	if r.status() != modules.ScheduledPaymentPending {
+		t.Fatal("unexpected status", r.status())
+	}
+
+	// Permanent errors do.
+	r.setPoolResult(modules.NewConsensusConflict("invalid"))
+	if r.status() != modules.ScheduledPaymentStuck || r.Rejected == "" {
+		t.Fatal("unexpected status", r.status())
+	}
+	r.setPoolResult(errors.AddContext(modules.ErrLargeTransactionSet, "rejected"))
+	if r.status() != modules.ScheduledPaymentStuck {
+		t.Fatal("unexpected status", r.status())
+	}
+
+	// Accepting the set or confirming it clears the status.
+	r.setPoolResult(nil)
+	if r.status() != modules.ScheduledPaymentPending || r.Rejected != "" {
+		t.Fatal("unexpected status", r.status())
+	}
+	r.Confirmed = true
+	if r.status() != modules.ScheduledPaymentConfirmed {
+		t.Fatal("unexpected status", r.status())
+	}
+}
+
+// TestScheduledPayments probes adding, sending and removing scheduled
+// payments.
+func TestScheduledPayments(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	height, err := wt.wallet.Height()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := modules.ScheduledPayment{
+		Name:        "test",
+		Destination: types.UnlockHash{1},
+		Amount:      types.UplocoinPrecision.Mul64(10),
+		StartHeight: height + 1,
+		Interval:    2,
+		Count:       2,
+	}
+
+	// Invalid scheduled payments are rejected.
+	invalid := sp
+	invalid.Name = ""
+	if err := wt.wallet.AddScheduledPayment(invalid); err == nil {
+		t.Fatal("expected error for empty name")
+	}
+	invalid = sp
+	invalid.Amount = types.ZeroCurrency
+	if err := wt.wallet.AddScheduledPayment(invalid); !errors.Contains(err, errInvalidScheduledPayment) {
+		t.Fatal("expected errInvalidScheduledPayment, got", err)
+	}
+	invalid = sp
+	invalid.StartHeight = height - 1
+	if err := wt.wallet.AddScheduledPayment(invalid); !errors.Contains(err, errInvalidScheduledPayment) {
+		t.Fatal("expected errInvalidScheduledPayment for a start height in the past, got", err)
+	}
+	invalid = sp
+	invalid.Account = "unknown"
+	if err := wt.wallet.AddScheduledPayment(invalid); err == nil {
+		t.Fatal("expected error for unknown account")
+	}
+
+	// Add the scheduled payment. No payment is due yet.
+	if err := wt.wallet.AddScheduledPayment(sp); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.AddScheduledPayment(sp); !errors.Contains(err, errScheduledPaymentExists) {
+		t.Fatal("expected errScheduledPaymentExists, got", err)
+	}
+	sps, err := wt.wallet.ScheduledPayments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sps) != 1 || sps[0].NextHeight != height+1 || len(sps[0].Payments) != 0 {
+		t.Fatal("unexpected scheduled payments", sps)
+	}
+
+	// checkPayments waits for the scheduled payment to have the expected
+	// number of payments, of which the expected number are confirmed. Since
+	// payments are persisted before they are broadcast, it also waits for the
+	// unconfirmed payments to reach the transaction pool.
+	checkPayments := func(payments, confirmed int, next types.BlockHeight) {
+		err := build.Retry(50, 100*time.Millisecond, func() error {
+			sps, err := wt.wallet.ScheduledPayments()
+			if err != nil {
+				return err
+			}
+			if len(sps) != 1 {
+				return fmt.Errorf("expected 1 scheduled payment, got %v", len(sps))
+			}
+			if len(sps[0].Payments) != payments {
+				return fmt.Errorf("expected %v payments, got %v (%v)", payments, len(sps[0].Payments), sps[0].LastError)
+			}
+			var c int
+			for _, r := range sps[0].Payments {
+				if r.Confirmed {
+					c++
+				} else if _, _, exists := wt.tpool.Transaction(r.TransactionID); !exists {
+					return errors.New("payment is not in the transaction pool yet")
+				}
+			}
+			if c != confirmed {
+				return fmt.Errorf("expected %v confirmed payments, got %v", confirmed, c)
+			}
+			if sps[0].NextHeight != next {
+				return fmt.Errorf("expected next height %v, got %v", next, sps[0].NextHeight)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Mine blocks and check that the payments are sent at the scheduled
+	// heights and confirmed in the following block.
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	checkPayments(1, 0, height+3)
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	checkPayments(1, 1, height+3)
+
+	// The confirmed payment isn't double spent by itself, but a different
+	// transaction spending the same inputs is.
+	wt.wallet.mu.Lock()
+	stored, err := dbGetScheduledPayment(wt.wallet.dbTx, sp.Name)
+	if err != nil {
+		wt.wallet.mu.Unlock()
+		t.Fatal(err)
+	}
+	txnSet := stored.Payments[0].Transactions
+	conflict := append([]types.Transaction(nil), txnSet...)
+	for i := range conflict {
+		conflict[i].ArbitraryData = append(conflict[i].ArbitraryData, []byte("conflict"))
+	}
+	doubleSpent := dbTransactionSetDoubleSpent(wt.wallet.dbTx, txnSet)
+	conflictDoubleSpent := dbTransactionSetDoubleSpent(wt.wallet.dbTx, conflict)
+	wt.wallet.mu.Unlock()
+	if doubleSpent {
+		t.Fatal("confirmed payment shouldn't be double spent")
+	}
+	if !conflictDoubleSpent {
+		t.Fatal("conflicting transaction should be double spent")
+	}
+
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	checkPayments(2, 1, 0)
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	checkPayments(2, 2, 0)
+
+	// No more payments are sent.
+	for i := 0; i < 3; i++ {
+		if err := wt.addBlockNoPayout(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPayments(2, 2, 0)
+
+	// Remove the scheduled payment.
+	if err := wt.wallet.RemoveScheduledPayment(sp.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.RemoveScheduledPayment(sp.Name); !errors.Contains(err, modules.ErrUnknownScheduledPayment) {
+		t.Fatal("expected ErrUnknownScheduledPayment, got", err)
+	}
+	sps, err = wt.wallet.ScheduledPayments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sps) != 0 {
+		t.Fatal("expected no scheduled payments", sps)
+	}
+}
@@ -337,12 +337,7 @@ func (w *Wallet) LoadSeed(masterKey crypto.CipherKey, seed modules.Seed) error {
 	go w.rescanMessage(done)
 	defer close(done)
 
-	err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
-	if err != nil {
-		return err
-	}
-	w.tpool.TransactionPoolSubscribe(w)
-	return nil
+	return w.managedSubscribe(modules.ConsensusChangeBeginning)
 }
 
 // SweepSeed scans the blockchain for outputs generated from seed and creates
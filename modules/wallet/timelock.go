@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"github.com/uplo-tech/bolt"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+var (
+	// errInvalidTimelock is returned when coins are sent with a timelock that
+	// has already been reached.
+	errInvalidTimelock = errors.New("timelock must be greater than the current height")
+
+	// errUnknownUnlockConditions is returned when coins are sent with a
+	// timelock to an address whose unlock conditions are unknown.
+	errUnknownUnlockConditions = errors.New("unlock conditions of the destination are unknown, add them with /wallet/unlockconditions first")
+)
+
+// timelockedUnlockConditions returns the time-locked version of the unlock
+// conditions of an address. The bool indicates whether the wallet owns the
+// keys of the address.
+func (w *Wallet) timelockedUnlockConditions(addr types.UnlockHash, timelock types.BlockHeight) (types.UnlockConditions, bool, error) {
+	var uc types.UnlockConditions
+	sk, own := w.keys[addr]
+	if own {
+		uc = sk.UnlockConditions
+	} else {
+		var err error
+		uc, err = dbGetUnlockConditions(w.dbTx, addr)
+		if err != nil {
+			return types.UnlockConditions{}, false, errUnknownUnlockConditions
+		}
+	}
+	uc.PublicKeys = append([]types.UploPublicKey(nil), uc.PublicKeys...)
+	uc.Timelock = timelock
+	return uc, own, nil
+}
+
+// integrateTimelockedKey adds the time-locked version of a key of the wallet
+// to the set of spendable keys. The time-locked key belongs to the same
+// account as the original key.
+func (w *Wallet) integrateTimelockedKey(uc types.UnlockConditions) {
+	base := uc
+	base.Timelock = 0
+	baseAddr := base.UnlockHash()
+	sk, ok := w.keys[baseAddr]
+	if !ok {
+		return
+	}
+	addr := uc.UnlockHash()
+	w.keys[addr] = spendableKey{
+		UnlockConditions: uc,
+		SecretKeys:       sk.SecretKeys,
+	}
+	if ak, ok := w.accountKeys[baseAddr]; ok {
+		w.accountKeys[addr] = ak
+	}
+}
+
+// integrateTimelockedKeys adds the time-locked keys of the wallet to the set
+// of spendable keys. It must be called after all other keys were integrated.
+func (w *Wallet) integrateTimelockedKeys(tx *bolt.Tx) error {
+	return dbForEachTimelockedKey(tx, func(_ types.UnlockHash, uc types.UnlockConditions) {
+		w.integrateTimelockedKey(uc)
+	})
+}
+
+// SendUplocoinsTimelocked sends Uplocoins to a time-locked version of an
+// address. The unlock conditions of the address must be known to the wallet,
+// either because it's an address of the wallet or because they were added
+// with AddUnlockConditions. The sent coins can only be spent once the
+// blockchain reached the timelock height.
+//
+// The time-locked unlock conditions are stored in the wallet database and are
+// returned, since the recipient needs them to spend the coins. If the address
+// belongs to the wallet, the wallet tracks the time-locked output as its own.
+// Such outputs can't be recovered from the seed alone.
+func (w *Wallet) SendUplocoinsTimelocked(amount types.Currency, dest types.UnlockHash, timelock types.BlockHeight) ([]types.Transaction, types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, types.UnlockConditions{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	uc, err := func() (types.UnlockConditions, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !w.unlocked {
+			return types.UnlockConditions{}, modules.ErrLockedWallet
+		}
+		height, err := dbGetConsensusHeight(w.dbTx)
+		if err != nil {
+			return types.UnlockConditions{}, err
+		}
+		if timelock <= height {
+			return types.UnlockConditions{}, errInvalidTimelock
+		}
+		uc, own, err := w.timelockedUnlockConditions(dest, timelock)
+		if err != nil {
+			return types.UnlockConditions{}, err
+		}
+		if own {
+			w.integrateTimelockedKey(uc)
+			if err := dbPutTimelockedKey(w.dbTx, uc); err != nil {
+				return types.UnlockConditions{}, err
+			}
+		}
+		if err := dbPutUnlockConditions(w.dbTx, uc); err != nil {
+			return types.UnlockConditions{}, err
+		}
+		return uc, w.syncDB()
+	}()
+	if err != nil {
+		return nil, types.UnlockConditions{}, err
+	}
+
+	_, fee := w.tpool.FeeEstimation()
+	fee = fee.Mul64(estimatedTransactionSize)
+	txns, err := w.managedSendUplocoins(modules.DefaultWalletAccount, amount, fee, uc.UnlockHash(), nil)
+	if err != nil {
+		return nil, types.UnlockConditions{}, err
+	}
+	return txns, uc, nil
+}
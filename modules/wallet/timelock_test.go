@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestSendUplocoinsTimelocked probes sending Uplocoins to a time-locked
+// address of the wallet.
+func TestSendUplocoinsTimelocked(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	height, err := wt.wallet.Height()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := types.UplocoinPrecision.Mul64(10)
+
+	// Timelocks that were already reached are rejected.
+	_, _, err = wt.wallet.SendUplocoinsTimelocked(amount, uc.UnlockHash(), height)
+	if !errors.Contains(err, errInvalidTimelock) {
+		t.Fatal("expected errInvalidTimelock, got", err)
+	}
+	// The unlock conditions of the destination must be known.
+	_, _, err = wt.wallet.SendUplocoinsTimelocked(amount, types.UnlockHash{1}, height+3)
+	if !errors.Contains(err, errUnknownUnlockConditions) {
+		t.Fatal("expected errUnknownUnlockConditions, got", err)
+	}
+
+	// Send the coins to a time-locked version of the address.
+	_, tluc, err := wt.wallet.SendUplocoinsTimelocked(amount, uc.UnlockHash(), height+3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tluc.Timelock != height+3 || tluc.UnlockHash() == uc.UnlockHash() {
+		t.Fatal("unexpected unlock conditions", tluc)
+	}
+	stored, err := wt.wallet.UnlockConditions(tluc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.UnlockHash() != tluc.UnlockHash() {
+		t.Fatal("stored unlock conditions don't match")
+	}
+
+	// Once confirmed, the wallet tracks the time-locked output as its own.
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, o := range outputs {
+		if o.UnlockHash == tluc.UnlockHash() && o.Value.Equals(amount) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("time-locked output is not tracked by the wallet")
+	}
+}
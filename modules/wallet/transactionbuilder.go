@@ -22,9 +22,17 @@ var (
 	// errDustOutput indicates an output is not spendable because it is dust.
 	errDustOutput = errors.New("output is too small")
 
+	// errOutputFrozen indicates an output was frozen by the user and can only
+	// be spent by selecting it explicitly.
+	errOutputFrozen = errors.New("output is frozen")
+
 	// errOutputTimelock indicates an output's timelock is still active.
 	errOutputTimelock = errors.New("wallet consensus set height is lower than the output timelock")
 
+	// errOutputWatchOnly indicates an output belongs to a watch-only address
+	// and can't be spent because the wallet doesn't have its key.
+	errOutputWatchOnly = errors.New("output belongs to a watch-only address, the wallet has no key to spend it")
+
 	// errSpendHeightTooHigh indicates an output's spend height is greater than
 	// the allowed height.
 	errSpendHeightTooHigh = errors.New("output spend height exceeds the allowed height")
@@ -48,6 +56,10 @@ type transactionBuilder struct {
 	uplofundInputs         []int
 	transactionSignatures []int
 
+	// account is the account of the wallet that funds the transaction and
+	// receives its change.
+	account string
+
 	wallet *Wallet
 }
 
@@ -112,6 +124,11 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 	if currentHeight < outputUnlockConditions.Timelock {
 		return errOutputTimelock
 	}
+	// Check that the output wasn't frozen by the user. This is checked last
+	// so that callers which spend explicitly selected outputs can ignore it.
+	if dbIsFrozenOutput(tx, id) {
+		return errOutputFrozen
+	}
 
 	return nil
 }
@@ -121,6 +138,7 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 // transaction).
 func (tb *transactionBuilder) Copy() modules.TransactionBuilder {
 	copyBuilder := tb.wallet.registerTransaction(tb.transaction, tb.parents)
+	copyBuilder.account = tb.account
 
 	// Copy the non-transaction fields over to the new builder.
 	copyBuilder.newParents = make([]int, len(tb.newParents))
@@ -208,9 +226,12 @@ func (tb *transactionBuilder) FundUplocoins(amount types.Currency) error {
 		return err
 	}
 
-	// Collect a value-sorted set of Uplocoin outputs.
+	// Collect a value-sorted set of the Uplocoin outputs of the account.
 	var so sortedOutputs
 	err = dbForEachUplocoinOutput(tb.wallet.dbTx, func(scoid types.UplocoinOutputID, sco types.UplocoinOutput) {
+		if tb.wallet.addressAccount(sco.UnlockHash) != tb.account {
+			return
+		}
 		so.ids = append(so.ids, scoid)
 		so.outputs = append(so.outputs, sco)
 	})
@@ -220,9 +241,9 @@ func (tb *transactionBuilder) FundUplocoins(amount types.Currency) error {
 	// Add all of the unconfirmed outputs as well.
 	for _, upt := range tb.wallet.unconfirmedProcessedTransactions {
 		for i, sco := range upt.Transaction.UplocoinOutputs {
-			// Determine if the output belongs to the wallet.
+			// Determine if the output belongs to the account.
 			_, exists := tb.wallet.keys[sco.UnlockHash]
-			if !exists {
+			if !exists || tb.wallet.addressAccount(sco.UnlockHash) != tb.account {
 				continue
 			}
 			so.ids = append(so.ids, upt.Transaction.UplocoinOutputID(uint64(i)))
@@ -273,10 +294,97 @@ func (tb *transactionBuilder) FundUplocoins(amount types.Currency) error {
 	if fund.Cmp(amount) < 0 {
 		return modules.ErrLowBalance
 	}
+	return tb.addFundingParent(parentTxn, spentScoids, fund, amount, consensusHeight)
+}
+
+// FundUplocoinsFromOutputs will add a Uplocoin input of exactly 'amount' to
+// the transaction, which is funded by spending all of the given outputs of the
+// wallet. Frozen outputs and outputs of other accounts may be spent this way.
+// The Uplocoin input will not be signed until 'Sign' is called on the
+// transaction builder.
+func (tb *transactionBuilder) FundUplocoinsFromOutputs(amount types.Currency, outputs []types.UplocoinOutputID) error {
+	if amount.IsZero() {
+		return nil
+	}
+	if len(outputs) == 0 {
+		return errors.New("no outputs were selected")
+	}
+	// dustThreshold has to be obtained separate from the lock
+	dustThreshold, err := tb.wallet.DustThreshold()
+	if err != nil {
+		return err
+	}
 
+	tb.wallet.mu.Lock()
+	defer tb.wallet.mu.Unlock()
+
+	consensusHeight, err := dbGetConsensusHeight(tb.wallet.dbTx)
+	if err != nil {
+		return err
+	}
+
+	// Look up the selected outputs, which may be confirmed or the outputs of
+	// unconfirmed transactions.
+	var fund types.Currency
+	parentTxn := types.Transaction{}
+	var spentScoids []types.UplocoinOutputID
+	for _, scoid := range outputs {
+		sco, ok := tb.wallet.uplocoinOutput(scoid)
+		if !ok {
+			return errors.AddContext(modules.ErrUnknownOutput, scoid.String())
+		}
+		for _, spent := range spentScoids {
+			if spent == scoid {
+				return errors.New("output was selected more than once: " + scoid.String())
+			}
+		}
+		key, exists := tb.wallet.keys[sco.UnlockHash]
+		if !exists {
+			return errors.AddContext(errOutputWatchOnly, "unable to spend output "+scoid.String())
+		}
+		err := tb.wallet.checkOutput(tb.wallet.dbTx, consensusHeight, scoid, sco, dustThreshold)
+		if err != nil && !errors.Contains(err, errOutputFrozen) {
+			return errors.AddContext(err, "unable to spend output "+scoid.String())
+		}
+		parentTxn.UplocoinInputs = append(parentTxn.UplocoinInputs, types.UplocoinInput{
+			ParentID:         scoid,
+			UnlockConditions: key.UnlockConditions,
+		})
+		spentScoids = append(spentScoids, scoid)
+		fund = fund.Add(sco.Value)
+	}
+	if fund.Cmp(amount) < 0 {
+		return errors.AddContext(modules.ErrLowBalance, "selected outputs don't cover the amount")
+	}
+	return tb.addFundingParent(parentTxn, spentScoids, fund, amount, consensusHeight)
+}
+
+// uplocoinOutput returns the confirmed or unconfirmed Uplocoin output of
+// the wallet with the given ID. The wallet has to be locked by the caller.
+func (w *Wallet) uplocoinOutput(id types.UplocoinOutputID) (types.UplocoinOutput, bool) {
+	var sco types.UplocoinOutput
+	if err := dbGet(w.dbTx.Bucket(bucketUplocoinOutputs), id, &sco); err == nil {
+		return sco, true
+	}
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		for i, sco := range upt.Transaction.UplocoinOutputs {
+			if _, exists := w.keys[sco.UnlockHash]; exists && upt.Transaction.UplocoinOutputID(uint64(i)) == id {
+				return sco, true
+			}
+		}
+	}
+	return types.UplocoinOutput{}, false
+}
+
+// addFundingParent adds a parent transaction to the builder, which spends the
+// inputs of parentTxn and creates an output of exactly 'amount', and an input
+// that spends this output to the transaction. The difference between 'fund'
+// and 'amount' is refunded to the wallet. The wallet has to be locked by the
+// caller.
+func (tb *transactionBuilder) addFundingParent(parentTxn types.Transaction, spentScoids []types.UplocoinOutputID, fund, amount types.Currency, consensusHeight types.BlockHeight) error {
 	// Create and add the output that will be used to fund the standard
 	// transaction.
-	parentUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+	parentUnlockConditions, err := tb.wallet.nextAccountAddress(tb.wallet.dbTx, tb.account)
 	if err != nil {
 		return err
 	}
@@ -289,7 +397,7 @@ func (tb *transactionBuilder) FundUplocoins(amount types.Currency) error {
 
 	// Create a refund output if needed.
 	if !amount.Equals(fund) {
-		refundUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+		refundUnlockConditions, err := tb.wallet.nextAccountAddress(tb.wallet.dbTx, tb.account)
 		if err != nil {
 			return err
 		}
@@ -364,6 +472,11 @@ func (tb *transactionBuilder) FundUplofunds(amount types.Currency) error {
 			return err
 		}
 
+		// Check that the output belongs to the account.
+		if tb.wallet.addressAccount(sfo.UnlockHash) != tb.account {
+			continue
+		}
+
 		// Check that this output has not recently been spent by the wallet.
 		spendHeight, err := dbGetSpentOutput(tb.wallet.dbTx, types.OutputID(sfoid))
 		if err != nil {
@@ -385,7 +498,7 @@ func (tb *transactionBuilder) FundUplofunds(amount types.Currency) error {
 		}
 
 		// Add a uplofund input for this output.
-		parentClaimUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+		parentClaimUnlockConditions, err := tb.wallet.nextAccountAddress(tb.wallet.dbTx, tb.account)
 		if err != nil {
 			return err
 		}
@@ -413,7 +526,7 @@ func (tb *transactionBuilder) FundUplofunds(amount types.Currency) error {
 
 	// Create and add the output that will be used to fund the standard
 	// transaction.
-	parentUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+	parentUnlockConditions, err := tb.wallet.nextAccountAddress(tb.wallet.dbTx, tb.account)
 	if err != nil {
 		return err
 	}
@@ -425,7 +538,7 @@ func (tb *transactionBuilder) FundUplofunds(amount types.Currency) error {
 
 	// Create a refund output if needed.
 	if !amount.Equals(fund) {
-		refundUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+		refundUnlockConditions, err := tb.wallet.nextAccountAddress(tb.wallet.dbTx, tb.account)
 		if err != nil {
 			return err
 		}
@@ -442,7 +555,7 @@ func (tb *transactionBuilder) FundUplofunds(amount types.Currency) error {
 	}
 
 	// Add the exact output.
-	claimUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+	claimUnlockConditions, err := tb.wallet.nextAccountAddress(tb.wallet.dbTx, tb.account)
 	if err != nil {
 		return err
 	}
@@ -753,7 +866,8 @@ func (w *Wallet) registerTransaction(t types.Transaction, parents []types.Transa
 		parents:     pCopy,
 		transaction: tCopy,
 
-		wallet: w,
+		account: modules.DefaultWalletAccount,
+		wallet:  w,
 	}
 }
 
@@ -213,11 +213,10 @@ func (w *Wallet) LoadUplogKeys(masterKey crypto.CipherKey, keyfiles []string) er
 	go w.rescanMessage(done)
 	defer close(done)
 
-	err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	err = w.managedSubscribe(modules.ConsensusChangeBeginning)
 	if err != nil {
 		return err
 	}
-	w.tpool.TransactionPoolSubscribe(w)
 	return nil
 }
 
@@ -283,11 +282,10 @@ func (w *Wallet) Load033xWallet(masterKey crypto.CipherKey, filepath033x string)
 	go w.rescanMessage(done)
 	defer close(done)
 
-	err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	err = w.managedSubscribe(modules.ConsensusChangeBeginning)
 	if err != nil {
 		return err
 	}
-	w.tpool.TransactionPoolSubscribe(w)
 
 	return nil
 }
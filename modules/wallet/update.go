@@ -27,12 +27,34 @@ func (w *Wallet) threadedResetSubscriptions() {
 	w.cs.Unsubscribe(w)
 	w.tpool.Unsubscribe(w)
 
-	err := w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	err := w.managedSubscribe(modules.ConsensusChangeBeginning)
 	if err != nil {
 		w.log.Print("failed to subscribe wallet to consensus", err)
 		return
 	}
+}
+
+// managedSubscribe subscribes the wallet to the consensus set, starting at the
+// given consensus change, and to the transaction pool. Subscribing from the
+// beginning rescans the blockchain, so no events are published until the
+// wallet caught up since the transactions aren't new.
+func (w *Wallet) managedSubscribe(start modules.ConsensusChangeID) error {
+	if start == modules.ConsensusChangeBeginning {
+		w.mu.Lock()
+		w.rescanning = true
+		w.mu.Unlock()
+		defer func() {
+			w.mu.Lock()
+			w.rescanning = false
+			w.mu.Unlock()
+		}()
+	}
+	err := w.cs.ConsensusSetSubscribe(w, start, w.tg.StopChan())
+	if err != nil {
+		return err
+	}
 	w.tpool.TransactionPoolSubscribe(w)
+	return nil
 }
 
 // advanceSeedLookahead generates all keys from the current primary seed progress up to index
@@ -94,6 +116,9 @@ func (w *Wallet) updateLookahead(tx *bolt.Tx, cc modules.ConsensusChange) (bool,
 			}
 		}
 	}
+	if err := w.updateAccountLookahead(tx, cc); err != nil {
+		return false, err
+	}
 	if largestIndex > 0 {
 		return w.advanceSeedLookahead(largestIndex)
 	}
@@ -104,6 +129,18 @@ func (w *Wallet) updateLookahead(tx *bolt.Tx, cc modules.ConsensusChange) (bool,
 // updateConfirmedSet uses a consensus change to update the confirmed set of
 // outputs as understood by the wallet.
 func (w *Wallet) updateConfirmedSet(tx *bolt.Tx, cc modules.ConsensusChange) error {
+	// A delayed output matures when it is removed from the delayed outputs
+	// and added to the spendable outputs. Reverting the maturity adds it back
+	// to the delayed outputs.
+	matured := make(map[types.UplocoinOutputID]types.BlockHeight)
+	unmatured := make(map[types.UplocoinOutputID]struct{})
+	for _, diff := range cc.DelayedUplocoinOutputDiffs {
+		if diff.Direction == modules.DiffRevert {
+			matured[diff.ID] = diff.MaturityHeight
+		} else {
+			unmatured[diff.ID] = struct{}{}
+		}
+	}
 	for _, diff := range cc.UplocoinOutputDiffs {
 		// Verify that the diff is relevant to the wallet.
 		if !w.isWalletAddress(diff.UplocoinOutput.UnlockHash) {
@@ -114,9 +151,18 @@ func (w *Wallet) updateConfirmedSet(tx *bolt.Tx, cc modules.ConsensusChange) err
 		if diff.Direction == modules.DiffApply {
 			w.log.Println("Wallet has gained a spendable Uplocoin output:", diff.ID, "::", diff.UplocoinOutput.Value.HumanString())
 			err = dbPutUplocoinOutput(tx, diff.ID, diff.UplocoinOutput)
+			if height, ok := matured[diff.ID]; ok && err == nil {
+				err = dbPutMaturedOutput(tx, diff.ID, maturedOutput{
+					Value:          diff.UplocoinOutput.Value,
+					MaturityHeight: height,
+				})
+			}
 		} else {
 			w.log.Println("Wallet has lost a spendable Uplocoin output:", diff.ID, "::", diff.UplocoinOutput.Value.HumanString())
 			err = dbDeleteUplocoinOutput(tx, diff.ID)
+			if _, ok := unmatured[diff.ID]; ok && err == nil {
+				err = dbDeleteMaturedOutput(tx, diff.ID)
+			}
 		}
 		if err != nil {
 			w.log.Severe("Could not update Uplocoin output:", err)
@@ -482,22 +528,25 @@ func (w *Wallet) computeProcessedTransactionsFromBlock(tx *bolt.Tx, block types.
 }
 
 // applyHistory applies any transaction history that the applied blocks
-// introduced.
-func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
+// introduced. It returns the events about the newly confirmed transactions,
+// which must only be published once tx was committed.
+func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) ([]modules.WalletTransactionEvent, error) {
 	spentUplocoinOutputs := computeSpentUplocoinOutputSet(cc.UplocoinOutputDiffs)
 	spentUplofundOutputs := computeSpentUplofundOutputSet(cc.UplofundOutputDiffs)
 
+	var events []modules.WalletTransactionEvent
+
 	for _, block := range cc.AppliedBlocks {
 		consensusHeight, err := dbGetConsensusHeight(tx)
 		if err != nil {
-			return errors.AddContext(err, "failed to consensus height")
+			return nil, errors.AddContext(err, "failed to consensus height")
 		}
 		// Increment the consensus height.
 		if block.ID() != types.GenesisID {
 			consensusHeight++
 			err = dbPutConsensusHeight(tx, consensusHeight)
 			if err != nil {
-				return errors.AddContext(err, "failed to store consensus height in database")
+				return nil, errors.AddContext(err, "failed to store consensus height in database")
 			}
 		}
 
@@ -505,12 +554,16 @@ func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 		for _, pt := range pts {
 			err := dbAppendProcessedTransaction(tx, pt)
 			if err != nil {
-				return errors.AddContext(err, "could not put processed transaction")
+				return nil, errors.AddContext(err, "could not put processed transaction")
 			}
+			events = append(events, modules.WalletTransactionEvent{
+				TransactionID:      pt.TransactionID,
+				ConfirmationHeight: pt.ConfirmationHeight,
+			})
 		}
 	}
 
-	return nil
+	return events, nil
 }
 
 // ProcessConsensusChange parses a consensus change to update the set of
@@ -521,6 +574,17 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 	}
 	defer w.tg.Done()
 
+	events := w.managedProcessConsensusChange(cc)
+	for _, e := range events {
+		w.staticEvents.Notify(modules.EventWalletTransaction, e)
+	}
+}
+
+// managedProcessConsensusChange applies a consensus change to the database of
+// the wallet. It returns the events about newly confirmed transactions that
+// should be published. If there are any, the database is synced first, so
+// subscribers don't learn about transactions that might still be rolled back.
+func (w *Wallet) managedProcessConsensusChange(cc modules.ConsensusChange) []modules.WalletTransactionEvent {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -538,7 +602,8 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 		w.log.Severe("ERROR: failed to revert consensus change:", err)
 		w.dbRollback = true
 	}
-	if err := w.applyHistory(w.dbTx, cc); err != nil {
+	events, err := w.applyHistory(w.dbTx, cc)
+	if err != nil {
 		w.log.Severe("ERROR: failed to apply consensus change:", err)
 		w.dbRollback = true
 	}
@@ -548,8 +613,20 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 	}
 
 	if cc.Synced {
-		go w.threadedDefragWallet()
+		wake(w.staticDefragWake)
+		wake(w.staticScheduledPaymentsWake)
+	}
+
+	// Rescanned transactions aren't new, and nothing is published for a
+	// change that will be rolled back.
+	if len(events) == 0 || w.rescanning || w.dbRollback {
+		return nil
 	}
+	if err := w.syncDB(); err != nil {
+		w.log.Severe("ERROR: failed to commit consensus change:", err)
+		return nil
+	}
+	return events
 }
 
 // ReceiveUpdatedUnconfirmedTransactions updates the wallet's unconfirmed
@@ -662,6 +739,12 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 				})
 			}
 			w.unconfirmedProcessedTransactions = append(w.unconfirmedProcessedTransactions, pt)
+			if !w.rescanning {
+				w.staticEvents.Notify(modules.EventWalletTransaction, modules.WalletTransactionEvent{
+					TransactionID:      pt.TransactionID,
+					ConfirmationHeight: pt.ConfirmationHeight,
+				})
+			}
 		}
 	}
 }
@@ -73,11 +73,13 @@ type Wallet struct {
 	// the keys that are tracked on the blockchain. All keys are pregenerated
 	// from the seeds, when checking new outputs or spending outputs, the seeds
 	// are not referenced at all. The seeds are only stored so that the user
-	// may access them.
+	// may access them. accountKeys tracks which of the keys belong to
+	// sub-accounts of the wallet.
 	seeds        []modules.Seed
 	keys         map[types.UnlockHash]spendableKey
 	lookahead    map[types.UnlockHash]uint64
 	watchedAddrs map[types.UnlockHash]struct{}
+	accountKeys  map[types.UnlockHash]accountKey
 
 	// unconfirmedProcessedTransactions tracks unconfirmed transactions.
 	//
@@ -108,10 +110,23 @@ type Wallet struct {
 	// initialization.
 	scanLock uplosync.TryMutex
 
+	// staticDefragWake and staticScheduledPaymentsWake wake up
+	// threadedSyncedLoop to defrag the wallet and to process the scheduled
+	// payments. They have a buffer of one, so a wake-up is never lost and
+	// the loop never falls behind by more than one round.
+	staticDefragWake            chan struct{}
+	staticScheduledPaymentsWake chan struct{}
+
 	// The wallet's ThreadGroup tells tracked functions to shut down and
 	// blocks until they have all exited before returning from Close.
 	tg threadgroup.ThreadGroup
 
+	// staticEvents notifies subscribers about new transactions of the wallet.
+	// No events are published while rescanning is set, which happens while
+	// the wallet rescans the blockchain from the beginning.
+	staticEvents modules.EventNotifier
+	rescanning   bool
+
 	// defragDisabled determines if the wallet is set to defrag outputs once it
 	// reaches a certain threshold
 	defragDisabled bool
@@ -203,9 +218,13 @@ func NewCustomWallet(cs modules.ConsensusSet, tpool modules.TransactionPool, per
 		keys:         make(map[types.UnlockHash]spendableKey),
 		lookahead:    make(map[types.UnlockHash]uint64),
 		watchedAddrs: make(map[types.UnlockHash]struct{}),
+		accountKeys:  make(map[types.UnlockHash]accountKey),
 
 		unconfirmedSets: make(map[modules.TransactionSetID][]types.TransactionID),
 
+		staticDefragWake:            make(chan struct{}, 1),
+		staticScheduledPaymentsWake: make(chan struct{}, 1),
+
 		persistDir: persistDir,
 
 		deps: deps,
@@ -214,9 +233,38 @@ func NewCustomWallet(cs modules.ConsensusSet, tpool modules.TransactionPool, per
 	if err != nil {
 		return nil, err
 	}
+	go w.threadedSyncedLoop()
 	return w, nil
 }
 
+// wake wakes up a loop waiting on the channel without blocking.
+func wake(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// threadedSyncedLoop defrags the wallet and processes the scheduled payments
+// whenever it's woken up. Using a single thread prevents a goroutine from
+// being spawned for every consensus change while the wallet catches up.
+func (w *Wallet) threadedSyncedLoop() {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+	for {
+		select {
+		case <-w.tg.StopChan():
+			return
+		case <-w.staticDefragWake:
+			w.managedDefragWallet()
+		case <-w.staticScheduledPaymentsWake:
+			w.managedProcessScheduledPayments()
+		}
+	}
+}
+
 // Close terminates all ongoing processes involving the wallet, enabling
 // garbage collection.
 func (w *Wallet) Close() error {
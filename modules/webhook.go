@@ -0,0 +1,101 @@
+package modules
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/uplo-tech/errors"
+)
+
+var (
+	// ErrWebhookNotFound is returned when a webhook doesn't exist.
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// Webhook is an HTTP endpoint which is notified about the events of the daemon.
+// The payloads are signed using HMAC-SHA256 with the secret of the webhook. A
+// webhook only receives events of the listed types and modules, or all events
+// if the lists are empty. Alerts are only delivered if their severity is at
+// least MinSeverity.
+type Webhook struct {
+	Name        string        `json:"name"`
+	URL         string        `json:"url"`
+	Secret      string        `json:"secret"`
+	EventTypes  []EventType   `json:"eventtypes"`
+	Modules     []string      `json:"modules"`
+	MinSeverity AlertSeverity `json:"minseverity"`
+}
+
+// Module returns the module that publishes events of the type. Alerts are
+// published by the module that registered them, so Module returns the empty
+// string for alert events.
+func (t EventType) Module() string {
+	switch {
+	case strings.HasPrefix(string(t), "block."):
+		return "consensus"
+	case strings.HasPrefix(string(t), "contract."):
+		return "contractor"
+	case strings.HasPrefix(string(t), "file."), strings.HasPrefix(string(t), "upload."):
+		return "renter"
+	case strings.HasPrefix(string(t), "host."):
+		return "host"
+	case strings.HasPrefix(string(t), "wallet."):
+		return "wallet"
+	}
+	return ""
+}
+
+// Validate checks that the webhook can be added.
+func (wh Webhook) Validate() error {
+	if wh.Name == "" {
+		return errors.New("webhook needs a name")
+	}
+	if wh.Secret == "" {
+		return errors.New("webhook needs a secret to sign its payloads")
+	}
+	u, err := url.Parse(wh.URL)
+	if err != nil {
+		return errors.AddContext(err, "invalid webhook URL")
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook URL must be an absolute http or https URL")
+	}
+	for _, t := range wh.EventTypes {
+		if !t.IsKnown() {
+			return fmt.Errorf("unknown event type '%v'", t)
+		}
+	}
+	if wh.MinSeverity < SeverityWarning || wh.MinSeverity > SeverityCritical {
+		return errors.New("invalid minimum severity")
+	}
+	return nil
+}
+
+// MatchesEvent returns true if the webhook should receive an event of the
+// given type, which was published by the given module. severity is only
+// considered for alerts.
+func (wh Webhook) MatchesEvent(typ EventType, module string, severity AlertSeverity) bool {
+	if len(wh.EventTypes) > 0 {
+		match := false
+		for _, t := range wh.EventTypes {
+			match = match || t == typ
+		}
+		if !match {
+			return false
+		}
+	}
+	if len(wh.Modules) > 0 {
+		match := false
+		for _, m := range wh.Modules {
+			match = match || m == module
+		}
+		if !match {
+			return false
+		}
+	}
+	if typ == EventAlertRegistered || typ == EventAlertUnregistered {
+		return severity >= wh.MinSeverity
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/persist"
+)
+
+// TestWebhookMatchesEvent probes the filters of webhooks.
+func TestWebhookMatchesEvent(t *testing.T) {
+	wh := Webhook{MinSeverity: SeverityWarning}
+	if !wh.MatchesEvent(EventUploadFailed, EventUploadFailed.Module(), SeverityUnknown) {
+		t.Fatal("webhook without filters should match all events")
+	}
+
+	wh.EventTypes = []EventType{EventAlertRegistered, EventUploadFailed}
+	if wh.MatchesEvent(EventUploadFinished, "renter", SeverityUnknown) {
+		t.Fatal("webhook shouldn't match unlisted event type")
+	}
+	wh.Modules = []string{"renter"}
+	if !wh.MatchesEvent(EventUploadFailed, EventUploadFailed.Module(), SeverityUnknown) {
+		t.Fatal("webhook should match renter event")
+	}
+	if wh.MatchesEvent(EventAlertRegistered, "wallet", SeverityCritical) {
+		t.Fatal("webhook shouldn't match alert of unlisted module")
+	}
+
+	wh.MinSeverity = SeverityError
+	if wh.MatchesEvent(EventAlertRegistered, "renter", SeverityWarning) {
+		t.Fatal("webhook shouldn't match alert below minimum severity")
+	}
+	if !wh.MatchesEvent(EventAlertRegistered, "renter", SeverityCritical) {
+		t.Fatal("webhook should match alert above minimum severity")
+	}
+}
+
+// TestUplodConfigWebhooks probes adding and removing webhooks of the config.
+func TestUplodConfigWebhooks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	testDir := build.TempDir("uplodconfig", t.Name())
+	if err := os.MkdirAll(testDir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(testDir, ConfigName)
+	cfg, err := NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid webhooks are rejected.
+	wh := Webhook{Name: "test", URL: "http://localhost:1234", Secret: "secret", MinSeverity: SeverityWarning}
+	invalid := []Webhook{wh, wh, wh, wh, wh}
+	invalid[0].Name = ""
+	invalid[1].Secret = ""
+	invalid[2].URL = "ftp://localhost"
+	invalid[3].MinSeverity = SeverityUnknown
+	invalid[4].EventTypes = []EventType{EventUploadFinished, "upload.finsihed"}
+	for i, iwh := range invalid {
+		if err := cfg.AddWebhook(iwh); err == nil {
+			t.Fatal("invalid webhook was added", i)
+		}
+	}
+
+	// Names are unique.
+	if err := cfg.AddWebhook(wh); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.AddWebhook(wh); err == nil {
+		t.Fatal("webhook with duplicate name was added")
+	}
+
+	// Webhooks are persisted.
+	cfg, err = NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhooks := cfg.GetWebhooks(); len(webhooks) != 1 || webhooks[0].Name != wh.Name || webhooks[0].MinSeverity != wh.MinSeverity {
+		t.Fatal("webhook wasn't persisted", webhooks)
+	}
+
+	if err := cfg.RemoveWebhook(wh.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RemoveWebhook(wh.Name); !errors.Contains(err, ErrWebhookNotFound) {
+		t.Fatal("expected ErrWebhookNotFound, got", err)
+	}
+	if len(cfg.GetWebhooks()) != 0 {
+		t.Fatal("webhook wasn't removed")
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/uplo-tech/threadgroup"
+
 	"github.com/uplo-tech/uplo/build"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/modules/renter"
@@ -127,6 +130,10 @@ type (
 
 		requiredUserAgent string
 		requiredPassword  string
+		staticEvents      *eventStream
+		staticTokens      *apiTokens
+		staticWebhooks    *webhookDispatcher
+		tg                threadgroup.ThreadGroup
 		Shutdown          func() error
 		uplodConfig        *modules.UplodConfig
 
@@ -184,6 +191,15 @@ func (api *API) SetModules(cs modules.ConsensusSet, e modules.Explorer, fm modul
 	}
 	api.modulesSet = true
 	api.buildHTTPRoutes()
+	api.subscribeEvents()
+}
+
+// Close unsubscribes the API from the events of the modules and stops its
+// background threads, like the delivery of webhooks. It doesn't close the
+// modules.
+func (api *API) Close() error {
+	api.unsubscribeEvents()
+	return api.tg.Stop()
 }
 
 // StartTime returns the time at which the API started
@@ -194,7 +210,9 @@ func (api *API) StartTime() time.Time {
 // New creates a new Uplo API from the provided modules. The API will require
 // authentication using HTTP basic auth for certain endpoints of the supplied
 // password is not the empty string.  Usernames are ignored for authentication.
-func New(cfg *modules.UplodConfig, requiredUserAgent string, requiredPassword string, cs modules.ConsensusSet, e modules.Explorer, fm modules.FeeManager, g modules.Gateway, h modules.Host, m modules.Miner, r modules.Renter, tp modules.TransactionPool, w modules.Wallet) *API {
+// API tokens from the directory of the config are accepted in place of the
+// password for the routes within their scopes.
+func New(cfg *modules.UplodConfig, requiredUserAgent string, requiredPassword string, cs modules.ConsensusSet, e modules.Explorer, fm modules.FeeManager, g modules.Gateway, h modules.Host, m modules.Miner, r modules.Renter, tp modules.TransactionPool, w modules.Wallet) (*API, error) {
 	return NewCustom(cfg, requiredUserAgent, requiredPassword, cs, e, fm, g, h, m, r, tp, w, modules.ProdDependencies)
 }
 
@@ -203,7 +221,11 @@ func New(cfg *modules.UplodConfig, requiredUserAgent string, requiredPassword st
 // supplied password is not the empty string. Usernames are ignored for
 // authentication. It is custom because it allows to inject custom dependencies
 // into the API.
-func NewCustom(cfg *modules.UplodConfig, requiredUserAgent string, requiredPassword string, cs modules.ConsensusSet, e modules.Explorer, fm modules.FeeManager, g modules.Gateway, h modules.Host, m modules.Miner, r modules.Renter, tp modules.TransactionPool, w modules.Wallet, a modules.Dependencies) *API {
+func NewCustom(cfg *modules.UplodConfig, requiredUserAgent string, requiredPassword string, cs modules.ConsensusSet, e modules.Explorer, fm modules.FeeManager, g modules.Gateway, h modules.Host, m modules.Miner, r modules.Renter, tp modules.TransactionPool, w modules.Wallet, a modules.Dependencies) (*API, error) {
+	tokens, err := newAPITokens(filepath.Join(cfg.Dir(), apiTokensFilename))
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to load API tokens")
+	}
 	api := &API{
 		cs:                cs,
 		explorer:          e,
@@ -217,16 +239,22 @@ func NewCustom(cfg *modules.UplodConfig, requiredUserAgent string, requiredPassw
 		downloads:         make(map[modules.DownloadID]func()),
 		requiredUserAgent: requiredUserAgent,
 		requiredPassword:  requiredPassword,
+		staticEvents:      newEventStream(),
+		staticTokens:      tokens,
 		uplodConfig:        cfg,
 
 		staticDeps:      a,
 		staticStartTime: time.Now(),
 	}
 
+	api.staticWebhooks = newWebhookDispatcher(cfg, &api.tg)
+
 	// Register API handlers
 	api.buildHTTPRoutes()
+	api.staticEvents.addListener(api.staticWebhooks.dispatch)
+	api.subscribeEvents()
 
-	return api
+	return api, nil
 }
 
 // UnrecognizedCallHandler handles calls to disabled/not-loaded modules.
@@ -2,12 +2,15 @@ package client
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/uplo-tech/uplo/build"
 	"github.com/uplo-tech/uplo/node/api"
@@ -38,6 +41,10 @@ type (
 		// receives a redirect status code.
 		// For more see https://golang.org/pkg/net/http/#Client
 		CheckRedirect func(req *http.Request, via []*http.Request) error
+
+		// TLSConfig is an optional TLS config. If set, the client connects to
+		// the uplod server over https. See NewTLSConfig.
+		TLSConfig *tls.Config
 	}
 
 	// A UnsafeClient is a Client with additional access to unsafe methods that
@@ -47,6 +54,14 @@ type (
 	}
 )
 
+var (
+	// tlsTransports are the transports of the TLS configs of clients. Clients
+	// with the same TLS config share a transport, so that they can reuse
+	// connections.
+	tlsTransports   = make(map[*tls.Config]*http.Transport)
+	tlsTransportsMu sync.Mutex
+)
+
 // NewUnsafeClient creates a new UnsafeClient using the provided address.
 func NewUnsafeClient(client Client) *UnsafeClient {
 	return &UnsafeClient{client}
@@ -79,8 +94,7 @@ func (uc *UnsafeClient) GetWithHeaders(resource string, headers http.Header) (*h
 		}
 	}
 
-	httpClient := http.Client{CheckRedirect: uc.CheckRedirect}
-	return httpClient.Do(req)
+	return uc.httpClient().Do(req)
 }
 
 // New creates a new Client using the provided address. The password will be set
@@ -107,10 +121,67 @@ func DefaultOptions() (Options, error) {
 	}, nil
 }
 
+// NewTLSConfig creates a TLS config for connecting to an uplod server that
+// serves its API over TLS. If caFile is set, the certificate of the server is
+// verified using the CAs in the file instead of the system's CAs, which allows
+// for using the self-signed certificate of the server as the CA. If certFile
+// and keyFile are set, the client presents the certificate to the server for
+// mutual TLS.
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile != "" {
+		pemCerts, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to read CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemCerts) {
+			return nil, errors.New("CA file doesn't contain any certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client certificate requires a key")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// httpClient returns the http.Client used to make requests to the uplod
+// server.
+func (c *Client) httpClient() *http.Client {
+	httpClient := &http.Client{CheckRedirect: c.CheckRedirect}
+	if c.TLSConfig == nil {
+		return httpClient
+	}
+	tlsTransportsMu.Lock()
+	defer tlsTransportsMu.Unlock()
+	transport, exists := tlsTransports[c.TLSConfig]
+	if !exists {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = c.TLSConfig
+		tlsTransports[c.TLSConfig] = transport
+	}
+	httpClient.Transport = transport
+	return httpClient
+}
+
 // NewRequest constructs a request to the uplod HTTP API, setting the correct
 // User-Agent and Basic Auth. The resource path must begin with /.
 func (c *Client) NewRequest(method, resource string, body io.Reader) (*http.Request, error) {
-	url := "http://" + c.Address + resource
+	scheme := "http://"
+	if c.TLSConfig != nil {
+		scheme = "https://"
+	}
+	url := scheme + c.Address + resource
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
@@ -173,8 +244,7 @@ func (c *Client) getReaderResponse(resource string) (http.Header, io.ReadCloser,
 	if err != nil {
 		return nil, nil, errors.AddContext(err, "failed to construct GET request")
 	}
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
-	res, err := httpClient.Do(req)
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, nil, errors.AddContext(err, "GET request failed")
 	}
@@ -211,8 +281,7 @@ func (c *Client) getRawPartialResponse(resource string, from, to uint64) ([]byte
 	}
 	req.Header.Add("Range", fmt.Sprintf("bytes=%d-%d", from, to-1))
 
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
-	res, err := httpClient.Do(req)
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, errors.AddContext(err, "GET request failed")
 	}
@@ -268,8 +337,7 @@ func (c *Client) head(resource string) (int, http.Header, error) {
 	if err != nil {
 		return 0, nil, errors.AddContext(err, "failed to construct HEAD request")
 	}
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
-	res, err := httpClient.Do(req)
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return 0, nil, errors.AddContext(err, "HEAD request failed")
 	}
@@ -302,8 +370,7 @@ func (c *Client) postRawResponseWithHeaders(resource string, body io.Reader, hea
 		}
 	}
 
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
-	res, err := httpClient.Do(req)
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return http.Header{}, nil, errors.AddContext(err, "POST request failed")
 	}
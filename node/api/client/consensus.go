@@ -5,9 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/node/api"
 	"github.com/uplo-tech/uplo/types"
@@ -32,6 +33,29 @@ func (c *Client) ConsensusBlocksHeightGet(height types.BlockHeight) (cbg api.Con
 	return
 }
 
+// ConsensusSnapshotExportPost exports a snapshot of the consensus state at the
+// given height to the destination on the daemon's machine.
+func (c *Client) ConsensusSnapshotExportPost(dst string, height types.BlockHeight) (csp api.ConsensusSnapshotPOST, err error) {
+	values := url.Values{}
+	values.Set("destination", dst)
+	values.Set("height", fmt.Sprint(height))
+	err = c.post("/consensus/snapshot/export", values.Encode(), &csp)
+	return
+}
+
+// ConsensusSnapshotImportPost bootstraps the consensus set from the snapshot
+// at the source on the daemon's machine. If the checkpoint is empty, the
+// snapshot is checked against the checkpoints compiled into the daemon.
+func (c *Client) ConsensusSnapshotImportPost(src string, checkpoint crypto.Hash) (err error) {
+	values := url.Values{}
+	values.Set("source", src)
+	if checkpoint != (crypto.Hash{}) {
+		values.Set("checkpoint", checkpoint.String())
+	}
+	err = c.post("/consensus/snapshot/import", values.Encode(), nil)
+	return
+}
+
 // ConsensusSubscribeSingle streams consensus changes from the
 // /consensus/subscribe endpoint to the provided subscriber. Multiple calls may
 // be required before the subscriber is fully caught up. It returns the latest
@@ -44,7 +68,7 @@ func (c *Client) ConsensusSubscribeSingle(subscriber modules.ConsensusSetSubscri
 		return ccid, err
 	}
 	req.Cancel = cancel
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
 		return ccid, err
 	}
@@ -1,10 +1,19 @@
 package client
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/node/api"
+	"github.com/uplo-tech/uplo/types"
 )
 
 // DaemonGlobalRateLimitPost uses the /daemon/settings endpoint to change the
@@ -18,12 +27,157 @@ func (c *Client) DaemonGlobalRateLimitPost(downloadSpeed, uploadSpeed int64) (er
 	return
 }
 
+// DaemonProxyPost uses the /daemon/settings endpoint to change the SOCKS5 proxy
+// used for outbound connections. An empty address disables the proxy. If
+// proxyOnly is true, connections which can't be routed through the proxy are
+// refused.
+func (c *Client) DaemonProxyPost(address string, proxyOnly bool) (err error) {
+	values := url.Values{}
+	values.Set("proxyaddress", address)
+	values.Set("proxyonly", strconv.FormatBool(proxyOnly))
+	err = c.post("/daemon/settings", values.Encode(), nil)
+	return
+}
+
+// DaemonTokensGet requests the /daemon/tokens resource.
+func (c *Client) DaemonTokensGet() (dtg api.DaemonTokensGet, err error) {
+	err = c.get("/daemon/tokens", &dtg)
+	return
+}
+
+// DaemonTokensAddPost uses the /daemon/tokens/add endpoint to create an API
+// token with the given scopes. A zero expiry creates a token that never
+// expires and a zero spendCap creates a token that can send any amount of
+// Uplocoins. The returned secret can be used in place of the API password.
+func (c *Client) DaemonTokensAddPost(name string, scopes []string, expiry time.Time, spendCap types.Currency) (dtap api.DaemonTokensAddPost, err error) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("scopes", strings.Join(scopes, ","))
+	if !expiry.IsZero() {
+		values.Set("expiry", strconv.FormatInt(expiry.Unix(), 10))
+	}
+	if !spendCap.IsZero() {
+		values.Set("spendcap", spendCap.String())
+	}
+	err = c.post("/daemon/tokens/add", values.Encode(), &dtap)
+	return
+}
+
+// DaemonTokensRemovePost uses the /daemon/tokens/remove endpoint to remove an
+// API token.
+func (c *Client) DaemonTokensRemovePost(name string) (err error) {
+	values := url.Values{}
+	values.Set("name", name)
+	err = c.post("/daemon/tokens/remove", values.Encode(), nil)
+	return
+}
+
+// DaemonWebhooksGet requests the /daemon/webhooks resource.
+func (c *Client) DaemonWebhooksGet() (dwg api.DaemonWebhooksGet, err error) {
+	err = c.get("/daemon/webhooks", &dwg)
+	return
+}
+
+// DaemonWebhooksAddPost uses the /daemon/webhooks/add endpoint to add a
+// webhook. Empty eventTypes or moduleNames match all events and a minSeverity of
+// SeverityUnknown uses the default severity of the daemon.
+func (c *Client) DaemonWebhooksAddPost(name, webhookURL, secret string, eventTypes []modules.EventType, moduleNames []string, minSeverity modules.AlertSeverity) (err error) {
+	typeStrs := make([]string, 0, len(eventTypes))
+	for _, t := range eventTypes {
+		typeStrs = append(typeStrs, string(t))
+	}
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("url", webhookURL)
+	values.Set("secret", secret)
+	values.Set("eventtypes", strings.Join(typeStrs, ","))
+	values.Set("modules", strings.Join(moduleNames, ","))
+	if minSeverity != modules.SeverityUnknown {
+		values.Set("minseverity", minSeverity.String())
+	}
+	err = c.post("/daemon/webhooks/add", values.Encode(), nil)
+	return
+}
+
+// DaemonWebhooksRemovePost uses the /daemon/webhooks/remove endpoint to remove
+// a webhook.
+func (c *Client) DaemonWebhooksRemovePost(name string) (err error) {
+	values := url.Values{}
+	values.Set("name", name)
+	err = c.post("/daemon/webhooks/remove", values.Encode(), nil)
+	return
+}
+
+// DaemonWebhookDeliveriesGet requests the /daemon/webhooks/deliveries
+// resource. If name is not empty, only the deliveries to that webhook are
+// returned.
+func (c *Client) DaemonWebhookDeliveriesGet(name string) (dwdg api.DaemonWebhookDeliveriesGet, err error) {
+	values := url.Values{}
+	values.Set("webhook", name)
+	err = c.get("/daemon/webhooks/deliveries?"+values.Encode(), &dwdg)
+	return
+}
+
 // DaemonAlertsGet requests the /daemon/alerts resource.
 func (c *Client) DaemonAlertsGet() (dag api.DaemonAlertsGet, err error) {
 	err = c.get("/daemon/alerts", &dag)
 	return
 }
 
+// DaemonEventsSubscribe streams the events of the daemon from the
+// /daemon/events endpoint to fn, starting after the event with ID lastID. If
+// eventTypes is not empty, only events of these types are streamed. It blocks
+// until cancel is closed, the stream fails or fn returns an error.
+func (c *Client) DaemonEventsSubscribe(lastID uint64, eventTypes []modules.EventType, cancel <-chan struct{}, fn func(api.DaemonEvent) error) error {
+	values := url.Values{}
+	values.Set("lastid", strconv.FormatUint(lastID, 10))
+	if len(eventTypes) > 0 {
+		typeStrs := make([]string, 0, len(eventTypes))
+		for _, t := range eventTypes {
+			typeStrs = append(typeStrs, string(t))
+		}
+		values.Set("types", strings.Join(typeStrs, ","))
+	}
+	// We need to cancel the request when the cancel chan closes, so we have to
+	// construct it manually.
+	req, err := c.NewRequest("GET", "/daemon/events?"+values.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Cancel = cancel
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	// The stream never ends, so the body can't be drained.
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return readAPIError(resp.Body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// Only the data lines are needed since they contain the whole event.
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e api.DaemonEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+			return errors.AddContext(err, "could not decode event")
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	select {
+	case <-cancel:
+		return context.Canceled
+	default:
+	}
+	return scanner.Err()
+}
+
 // DaemonVersionGet requests the /daemon/version resource.
 func (c *Client) DaemonVersionGet() (dvg api.DaemonVersionGet, err error) {
 	err = c.get("/daemon/version", &dvg)
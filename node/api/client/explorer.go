@@ -0,0 +1,42 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/uplo-tech/uplo/node/api"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// ExplorerGet requests the /explorer api resource
+func (c *Client) ExplorerGet() (eg api.ExplorerGET, err error) {
+	err = c.get("/explorer", &eg)
+	return
+}
+
+// ExplorerAddressGet requests the /explorer/addresses/:addr api resource. It
+// returns the balance and unspent outputs of the address and up to limit of
+// its transactions, starting at offset.
+func (c *Client) ExplorerAddressGet(addr types.UnlockHash, offset, limit uint64) (eag api.ExplorerAddressGET, err error) {
+	values := url.Values{}
+	values.Set("offset", fmt.Sprint(offset))
+	values.Set("limit", fmt.Sprint(limit))
+	err = c.get(fmt.Sprintf("/explorer/addresses/%v?%v", addr, values.Encode()), &eag)
+	return
+}
+
+// ExplorerHostsGet requests the /explorer/hosts api resource. It returns up to
+// limit hosts, starting at offset.
+func (c *Client) ExplorerHostsGet(offset, limit uint64) (ehg api.ExplorerHostsGET, err error) {
+	values := url.Values{}
+	values.Set("offset", fmt.Sprint(offset))
+	values.Set("limit", fmt.Sprint(limit))
+	err = c.get("/explorer/hosts?"+values.Encode(), &ehg)
+	return
+}
+
+// ExplorerHostGet requests the /explorer/hosts/:pubkey api resource
+func (c *Client) ExplorerHostGet(pk types.UploPublicKey) (ehg api.ExplorerHostGET, err error) {
+	err = c.get("/explorer/hosts/"+pk.String(), &ehg)
+	return
+}
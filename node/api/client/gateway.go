@@ -41,6 +41,13 @@ func (c *Client) GatewayDisconnectPost(address modules.NetAddress) (err error) {
 	return
 }
 
+// GatewayForgetNodeKeyPost uses the /gateway/forgetnodekey/:address endpoint
+// to forget the node key of the node at address.
+func (c *Client) GatewayForgetNodeKeyPost(address modules.NetAddress) (err error) {
+	err = c.post("/gateway/forgetnodekey/"+string(address), "", nil)
+	return
+}
+
 // GatewayGet requests the /gateway api resource
 func (c *Client) GatewayGet() (gwg api.GatewayGET, err error) {
 	err = c.get("/gateway", &gwg)
@@ -58,6 +65,15 @@ func (c *Client) GatewayRateLimitPost(downloadSpeed, uploadSpeed int64) (err err
 	return
 }
 
+// GatewayRequireEncryptionPost uses the /gateway endpoint to set whether the
+// gateway refuses unencrypted peer connections.
+func (c *Client) GatewayRequireEncryptionPost(require bool) (err error) {
+	values := url.Values{}
+	values.Set("requireencryption", strconv.FormatBool(require))
+	err = c.post("/gateway", values.Encode(), nil)
+	return
+}
+
 // GatewayBlocklistGet uses the /gateway/blocklist endpoint to request the
 // Gateway's blocklist
 func (c *Client) GatewayBlocklistGet() (gbg api.GatewayBlocklistGET, err error) {
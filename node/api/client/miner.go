@@ -46,3 +46,9 @@ func (c *Client) MinerStopGet() (err error) {
 	err = c.get("/miner/stop", nil)
 	return
 }
+
+// MinerStratumGet requests the /miner/stratum endpoint's resources.
+func (c *Client) MinerStratumGet() (msg api.MinerStratumGET, err error) {
+	err = c.get("/miner/stratum", &msg)
+	return
+}
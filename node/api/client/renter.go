@@ -594,6 +594,33 @@ func (c *Client) RenterUploadForcePost(path string, uploPath modules.UploPath, d
 	return
 }
 
+// RenterUploadDedupPost uses the /renter/upload endpoint to upload a file with
+// deduplication enabled.
+func (c *Client) RenterUploadDedupPost(path string, uploPath modules.UploPath, dataPieces, parityPieces uint64, force bool) (err error) {
+	return c.RenterUploadCustomPost(path, uploPath, dataPieces, parityPieces, force, true, modules.CompressionNone)
+}
+
+// RenterUploadCompressedPost uses the /renter/upload endpoint to upload a file
+// which is compressed before it is encrypted.
+func (c *Client) RenterUploadCompressedPost(path string, uploPath modules.UploPath, dataPieces, parityPieces uint64, ct modules.CompressionType) (err error) {
+	return c.RenterUploadCustomPost(path, uploPath, dataPieces, parityPieces, false, false, ct)
+}
+
+// RenterUploadCustomPost uses the /renter/upload endpoint to upload a file
+// with custom deduplication and compression settings.
+func (c *Client) RenterUploadCustomPost(path string, uploPath modules.UploPath, dataPieces, parityPieces uint64, force, dedup bool, ct modules.CompressionType) (err error) {
+	sp := escapeUploPath(uploPath)
+	values := url.Values{}
+	values.Set("source", path)
+	values.Set("datapieces", strconv.FormatUint(dataPieces, 10))
+	values.Set("paritypieces", strconv.FormatUint(parityPieces, 10))
+	values.Set("force", strconv.FormatBool(force))
+	values.Set("dedup", strconv.FormatBool(dedup))
+	values.Set("compression", ct.String())
+	err = c.post(fmt.Sprintf("/renter/upload/%s", sp), values.Encode(), nil)
+	return
+}
+
 // RenterUploadDefaultPost uses the /renter/upload endpoint with default
 // redundancy settings to upload a file.
 func (c *Client) RenterUploadDefaultPost(path string, uploPath modules.UploPath) (err error) {
@@ -669,6 +696,19 @@ func (c *Client) RenterDirRenamePost(uploPath, newUploPath modules.UploPath) (er
 	return
 }
 
+// RenterDirSetVersioningPost uses the /renter/dir/ endpoint to set the
+// versioning policy of a directory.
+func (c *Client) RenterDirSetVersioningPost(uploPath modules.UploPath, policy modules.VersioningPolicy) (err error) {
+	sp := escapeUploPath(uploPath)
+	values := url.Values{}
+	values.Set("action", "versioning")
+	values.Set("enabled", strconv.FormatBool(policy.Enabled))
+	values.Set("maxversions", strconv.FormatUint(policy.MaxVersions, 10))
+	values.Set("maxage", strconv.FormatUint(uint64(policy.MaxAge.Seconds()), 10))
+	err = c.post(fmt.Sprintf("/renter/dir/%s", sp), values.Encode(), nil)
+	return
+}
+
 // RenterDirRootGet uses the /renter/dir/ endpoint to query a directory,
 // starting from the root path.
 func (c *Client) RenterDirRootGet(uploPath modules.UploPath) (rd api.RenterDirectory, err error) {
@@ -770,3 +810,72 @@ func (c *Client) RenterWorkersGet() (wps modules.WorkerPoolStatus, err error) {
 	err = c.get("/renter/workers", &wps)
 	return
 }
+
+// RenterFileVersionsGet uses the /renter/versions/ endpoint to list the
+// previous versions of a file.
+func (c *Client) RenterFileVersionsGet(uploPath modules.UploPath) (rfv api.RenterFileVersions, err error) {
+	sp := escapeUploPath(uploPath)
+	err = c.get(fmt.Sprintf("/renter/versions/%s", sp), &rfv)
+	return
+}
+
+// RenterFileVersionRestorePost uses the /renter/versions/ endpoint to restore
+// a previous version of a file.
+func (c *Client) RenterFileVersionRestorePost(uploPath modules.UploPath, id string) (err error) {
+	sp := escapeUploPath(uploPath)
+	values := url.Values{}
+	values.Set("action", "restore")
+	values.Set("id", id)
+	err = c.post(fmt.Sprintf("/renter/versions/%s", sp), values.Encode(), nil)
+	return
+}
+
+// RenterFileVersionsPrunePost uses the /renter/versions/ endpoint to prune the
+// previous versions of a file or of all the files within a directory. If
+// maxVersions and maxAge are 0, the versioning policies of the directories
+// apply.
+func (c *Client) RenterFileVersionsPrunePost(uploPath modules.UploPath, maxVersions uint64, maxAge time.Duration) (err error) {
+	sp := escapeUploPath(uploPath)
+	values := url.Values{}
+	values.Set("action", "prune")
+	values.Set("maxversions", strconv.FormatUint(maxVersions, 10))
+	values.Set("maxage", strconv.FormatUint(uint64(maxAge.Seconds()), 10))
+	err = c.post(fmt.Sprintf("/renter/versions/%s", sp), values.Encode(), nil)
+	return
+}
+
+// RenterTrashGet uses the /renter/trash endpoint to list the items within the
+// trash.
+func (c *Client) RenterTrashGet() (rt api.RenterTrash, err error) {
+	err = c.get("/renter/trash", &rt)
+	return
+}
+
+// RenterTrashEmptyPost uses the /renter/trash endpoint to permanently delete
+// all the items within the trash.
+func (c *Client) RenterTrashEmptyPost() (err error) {
+	values := url.Values{}
+	values.Set("action", "empty")
+	err = c.post("/renter/trash", values.Encode(), nil)
+	return
+}
+
+// RenterTrashRestorePost uses the /renter/trash endpoint to restore an item
+// from the trash.
+func (c *Client) RenterTrashRestorePost(id string) (err error) {
+	values := url.Values{}
+	values.Set("action", "restore")
+	values.Set("id", id)
+	err = c.post("/renter/trash", values.Encode(), nil)
+	return
+}
+
+// RenterTrashRetentionPost uses the /renter endpoint to set how long deleted
+// files and directories are kept in the trash. A retention of 0 disables the
+// trash.
+func (c *Client) RenterTrashRetentionPost(retention time.Duration) (err error) {
+	values := url.Values{}
+	values.Set("trashretention", strconv.FormatUint(uint64(retention.Seconds()), 10))
+	err = c.post("/renter", values.Encode(), nil)
+	return
+}
@@ -68,6 +68,10 @@ func (uc *UnsafeClient) SkynetSkyfilePostRawResponse(params modules.SkyfileUploa
 	values.Set("basechunkredundancy", redundancyStr)
 	rootStr := fmt.Sprintf("%t", params.Root)
 	values.Set("root", rootStr)
+	if params.HLSSegmentDuration != 0 {
+		hlsStr := fmt.Sprintf("%v", int64(params.HLSSegmentDuration.Seconds()))
+		values.Set("hlssegmentduration", hlsStr)
+	}
 
 	// Encode SkykeyName or SkykeyID.
 	if params.SkykeyName != "" {
@@ -745,6 +749,103 @@ func (c *Client) SkykeyDeleteByNamePost(name string) error {
 	return c.post("/skynet/deleteskykey", values.Encode(), nil)
 }
 
+// SkykeyRotateByNamePost requests the /skynet/skykey/rotate POST endpoint
+// using the name of the skykey.
+func (c *Client) SkykeyRotateByNamePost(name, newName string, blocklist bool) (modules.SkykeyRotation, error) {
+	values := url.Values{}
+	values.Set("name", name)
+	return c.skykeyRotatePost(values, newName, blocklist)
+}
+
+// SkykeyRotateByIDPost requests the /skynet/skykey/rotate POST endpoint using
+// the ID of the skykey.
+func (c *Client) SkykeyRotateByIDPost(id skykey.SkykeyID, newName string, blocklist bool) (modules.SkykeyRotation, error) {
+	values := url.Values{}
+	values.Set("id", id.ToString())
+	return c.skykeyRotatePost(values, newName, blocklist)
+}
+
+// skykeyRotatePost requests the /skynet/skykey/rotate POST endpoint.
+func (c *Client) skykeyRotatePost(values url.Values, newName string, blocklist bool) (rotation modules.SkykeyRotation, err error) {
+	values.Set("newname", newName)
+	values.Set("blocklist", strconv.FormatBool(blocklist))
+	err = c.post("/skynet/skykey/rotate", values.Encode(), &rotation)
+	return
+}
+
+// SkykeyRotationGet requests the /skynet/skykey/rotation GET endpoint to get
+// the state of the rotation to the skykey with the given ID.
+func (c *Client) SkykeyRotationGet(newID skykey.SkykeyID) (rotation modules.SkykeyRotation, err error) {
+	values := url.Values{}
+	values.Set("id", newID.ToString())
+	err = c.get("/skynet/skykey/rotation?"+values.Encode(), &rotation)
+	return
+}
+
+// SkykeySharePublicKeyGet requests the /skynet/skykey/share GET endpoint.
+func (c *Client) SkykeySharePublicKeyGet() (crypto.X25519PublicKey, error) {
+	var spkg api.SkykeySharePublicKeyGET
+	err := c.get("/skynet/skykey/share", &spkg)
+	if err != nil {
+		return crypto.X25519PublicKey{}, err
+	}
+	return skykey.SharePublicKeyFromString(spkg.PublicKey)
+}
+
+// SkykeyShareByNamePost requests the /skynet/skykey/share POST endpoint using
+// the name of the skykey.
+func (c *Client) SkykeyShareByNamePost(name string, recipient crypto.X25519PublicKey) (skykey.SkykeyShare, error) {
+	values := url.Values{}
+	values.Set("name", name)
+	return c.skykeySharePost(values, recipient)
+}
+
+// SkykeyShareByIDPost requests the /skynet/skykey/share POST endpoint using the
+// ID of the skykey.
+func (c *Client) SkykeyShareByIDPost(id skykey.SkykeyID, recipient crypto.X25519PublicKey) (skykey.SkykeyShare, error) {
+	values := url.Values{}
+	values.Set("id", id.ToString())
+	return c.skykeySharePost(values, recipient)
+}
+
+// skykeySharePost requests the /skynet/skykey/share POST endpoint.
+func (c *Client) skykeySharePost(values url.Values, recipient crypto.X25519PublicKey) (skykey.SkykeyShare, error) {
+	values.Set("publickey", skykey.SharePublicKeyToString(recipient))
+	var ssp api.SkykeySharePOST
+	err := c.post("/skynet/skykey/share", values.Encode(), &ssp)
+	if err != nil {
+		return skykey.SkykeyShare{}, err
+	}
+	var ss skykey.SkykeyShare
+	err = ss.FromString(ssp.Share)
+	if err != nil {
+		return skykey.SkykeyShare{}, errors.AddContext(err, "failed to decode skykey share string")
+	}
+	return ss, nil
+}
+
+// SkykeyShareImportPost requests the /skynet/skykey/share/import POST
+// endpoint. If name is not empty, the skykey is stored under that name.
+func (c *Client) SkykeyShareImportPost(ss skykey.SkykeyShare, name string) (skykey.Skykey, error) {
+	values := url.Values{}
+	values.Set("share", ss.ToString())
+	if name != "" {
+		values.Set("name", name)
+	}
+
+	var skykeyGet api.SkykeyGET
+	err := c.post("/skynet/skykey/share/import", values.Encode(), &skykeyGet)
+	if err != nil {
+		return skykey.Skykey{}, err
+	}
+	var sk skykey.Skykey
+	err = sk.FromString(skykeyGet.Skykey)
+	if err != nil {
+		return skykey.Skykey{}, errors.AddContext(err, "failed to decode skykey string")
+	}
+	return sk, nil
+}
+
 // SkykeyCreateKeyPost requests the /skynet/createskykey POST endpoint.
 func (c *Client) SkykeyCreateKeyPost(name string, skType skykey.SkykeyType) (skykey.Skykey, error) {
 	// Set the url values.
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/uplo-tech/uplo/crypto"
 	"github.com/uplo-tech/uplo/modules"
@@ -20,6 +21,41 @@ func (c *Client) WalletAddressGet() (wag api.WalletAddressGET, err error) {
 	return
 }
 
+// WalletAccountAddressGet requests a new address of an account of the wallet
+// from the /wallet/address endpoint.
+func (c *Client) WalletAccountAddressGet(account string) (wag api.WalletAddressGET, err error) {
+	values := url.Values{}
+	values.Set("account", account)
+	err = c.get("/wallet/address?"+values.Encode(), &wag)
+	return
+}
+
+// WalletAccountsGet requests the /wallet/accounts endpoint and returns the
+// accounts of the wallet.
+func (c *Client) WalletAccountsGet() (wag api.WalletAccountsGET, err error) {
+	err = c.get("/wallet/accounts", &wag)
+	return
+}
+
+// WalletAccountsPost uses the /wallet/accounts endpoint to create a new
+// account.
+func (c *Client) WalletAccountsPost(name string) (err error) {
+	values := url.Values{}
+	values.Set("name", name)
+	err = c.post("/wallet/accounts", values.Encode(), nil)
+	return
+}
+
+// WalletAccountsPinPost uses the /wallet/accounts/pin endpoint to pin a module
+// to an account. An empty account unpins the module.
+func (c *Client) WalletAccountsPinPost(module, account string) (err error) {
+	values := url.Values{}
+	values.Set("module", module)
+	values.Set("account", account)
+	err = c.post("/wallet/accounts/pin", values.Encode(), nil)
+	return
+}
+
 // WalletAddressesGet requests the wallets known addresses from the
 // /wallet/addresses endpoint.
 func (c *Client) WalletAddressesGet() (wag api.WalletAddressesGET, err error) {
@@ -152,6 +188,98 @@ func (c *Client) WalletUplocoinsPost(amount types.Currency, destination types.Un
 	return
 }
 
+// WalletUplocoinsFromAccountPost uses the /wallet/Uplocoins api endpoint to
+// send money to a single address from an account of the wallet.
+func (c *Client) WalletUplocoinsFromAccountPost(account string, amount types.Currency, destination types.UnlockHash) (wsp api.WalletUplocoinsPOST, err error) {
+	values := url.Values{}
+	values.Set("account", account)
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	err = c.post("/wallet/Uplocoins", values.Encode(), &wsp)
+	return
+}
+
+// WalletUplocoinsTimelockedPost uses the /wallet/Uplocoins/timelocked api
+// endpoint to send money to a time-locked version of an address which can't
+// be spent before the timelock height.
+func (c *Client) WalletUplocoinsTimelockedPost(amount types.Currency, destination types.UnlockHash, timelock types.BlockHeight) (wstp api.WalletUplocoinsTimelockedPOST, err error) {
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("timelock", fmt.Sprint(timelock))
+	err = c.post("/wallet/Uplocoins/timelocked", values.Encode(), &wstp)
+	return
+}
+
+// WalletScheduledPaymentsGet requests the /wallet/scheduledpayments api
+// resource.
+func (c *Client) WalletScheduledPaymentsGet() (wspg api.WalletScheduledPaymentsGET, err error) {
+	err = c.get("/wallet/scheduledpayments", &wspg)
+	return
+}
+
+// WalletScheduledPaymentsPost uses the /wallet/scheduledpayments endpoint to
+// add a scheduled payment. The reported fields of the payment are ignored.
+func (c *Client) WalletScheduledPaymentsPost(sp modules.ScheduledPayment) (err error) {
+	values := url.Values{}
+	values.Set("name", sp.Name)
+	values.Set("amount", sp.Amount.String())
+	values.Set("destination", sp.Destination.String())
+	values.Set("account", sp.Account)
+	// A start height of 0 lets uplod use its current height.
+	if sp.StartHeight != 0 {
+		values.Set("startheight", fmt.Sprint(sp.StartHeight))
+	}
+	values.Set("interval", fmt.Sprint(sp.Interval))
+	values.Set("count", fmt.Sprint(sp.Count))
+	err = c.post("/wallet/scheduledpayments", values.Encode(), nil)
+	return
+}
+
+// WalletScheduledPaymentsRemovePost uses the /wallet/scheduledpayments/remove
+// endpoint to remove a scheduled payment.
+func (c *Client) WalletScheduledPaymentsRemovePost(name string) (err error) {
+	values := url.Values{}
+	values.Set("name", name)
+	err = c.post("/wallet/scheduledpayments/remove", values.Encode(), nil)
+	return
+}
+
+// WalletUplocoinsFromOutputsPost uses the /wallet/Uplocoins api endpoint to
+// send money to a single address by spending the given outputs of the wallet.
+func (c *Client) WalletUplocoinsFromOutputsPost(amount types.Currency, destination types.UnlockHash, inputs []types.UplocoinOutputID) (wsp api.WalletUplocoinsPOST, err error) {
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("inputs", joinOutputIDs(inputs))
+	err = c.post("/wallet/Uplocoins", values.Encode(), &wsp)
+	return
+}
+
+// WalletUplocoinsMultiFromOutputsPost uses the /wallet/Uplocoins api endpoint
+// to send money to multiple addresses at once by spending the given outputs of
+// the wallet.
+func (c *Client) WalletUplocoinsMultiFromOutputsPost(outputs []types.UplocoinOutput, inputs []types.UplocoinOutputID) (wsp api.WalletUplocoinsPOST, err error) {
+	values := url.Values{}
+	marshaledOutputs, err := json.Marshal(outputs)
+	if err != nil {
+		return api.WalletUplocoinsPOST{}, err
+	}
+	values.Set("outputs", string(marshaledOutputs))
+	values.Set("inputs", joinOutputIDs(inputs))
+	err = c.post("/wallet/Uplocoins", values.Encode(), &wsp)
+	return
+}
+
+// joinOutputIDs returns a comma separated list of output IDs.
+func joinOutputIDs(ids []types.UplocoinOutputID) string {
+	idStrs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrs = append(idStrs, id.String())
+	}
+	return strings.Join(idStrs, ",")
+}
+
 // WalletSignPost uses the /wallet/sign api endpoint to sign a transaction.
 func (c *Client) WalletSignPost(txn types.Transaction, toSign []crypto.Hash) (wspr api.WalletSignPOSTResp, err error) {
 	json, err := json.Marshal(api.WalletSignPOSTParams{
@@ -175,6 +303,13 @@ func (c *Client) WalletUplofundsPost(amount types.Currency, destination types.Un
 	return
 }
 
+// WalletBumpPost uses the /wallet/bump/:txid endpoint to increase the fees of
+// an unconfirmed transaction.
+func (c *Client) WalletBumpPost(txid types.TransactionID) (wbp api.WalletBumpPOST, err error) {
+	err = c.post("/wallet/bump/"+txid.String(), "", &wbp)
+	return
+}
+
 // WalletUplogKeyPost uses the /wallet/uplogkey endpoint to load a uplog key into
 // the wallet.
 func (c *Client) WalletUplogKeyPost(keyfiles, password string) (err error) {
@@ -202,6 +337,34 @@ func (c *Client) WalletTransactionsGet(startHeight types.BlockHeight, endHeight
 	return
 }
 
+// WalletExportGet requests the /wallet/export api resource for the entries
+// confirmed at heights [startHeight, endHeight]. Empty exchange rates omit the
+// fiat values.
+func (c *Client) WalletExportGet(startHeight, endHeight types.BlockHeight, exchangeRates string) (weg api.WalletExportGET, err error) {
+	err = c.get("/wallet/export?"+walletExportValues(startHeight, endHeight, exchangeRates).Encode(), &weg)
+	return
+}
+
+// WalletExportCSVGet requests the /wallet/export api resource for the
+// entries confirmed at heights [startHeight, endHeight] formatted as CSV.
+func (c *Client) WalletExportCSVGet(startHeight, endHeight types.BlockHeight, exchangeRates string) ([]byte, error) {
+	values := walletExportValues(startHeight, endHeight, exchangeRates)
+	values.Set("format", "csv")
+	_, csv, err := c.getRawResponse("/wallet/export?" + values.Encode())
+	return csv, err
+}
+
+// walletExportValues returns the query values of a call to /wallet/export.
+func walletExportValues(startHeight, endHeight types.BlockHeight, exchangeRates string) url.Values {
+	values := url.Values{}
+	values.Set("startheight", fmt.Sprint(startHeight))
+	values.Set("endheight", fmt.Sprint(endHeight))
+	if exchangeRates != "" {
+		values.Set("exchangerates", exchangeRates)
+	}
+	return values
+}
+
 // WalletTransactionGet requests the /wallet/transaction/:id api resource for a
 // certain TransactionID.
 func (c *Client) WalletTransactionGet(id types.TransactionID) (wtg api.WalletTransactionGETid, err error) {
@@ -232,6 +395,41 @@ func (c *Client) WalletUnspentGet() (wug api.WalletUnspentGET, err error) {
 	return
 }
 
+// WalletUnspentFreezePost uses the /wallet/unspent/freeze endpoint to freeze
+// outputs of the wallet.
+func (c *Client) WalletUnspentFreezePost(ids []types.UplocoinOutputID) (err error) {
+	values := url.Values{}
+	values.Set("ids", joinOutputIDs(ids))
+	err = c.post("/wallet/unspent/freeze", values.Encode(), nil)
+	return
+}
+
+// WalletUnspentUnfreezePost uses the /wallet/unspent/unfreeze endpoint to
+// unfreeze outputs of the wallet.
+func (c *Client) WalletUnspentUnfreezePost(ids []types.UplocoinOutputID) (err error) {
+	values := url.Values{}
+	values.Set("ids", joinOutputIDs(ids))
+	err = c.post("/wallet/unspent/unfreeze", values.Encode(), nil)
+	return
+}
+
+// WalletLabelsGet requests the /wallet/labels endpoint.
+func (c *Client) WalletLabelsGet() (wlg api.WalletLabelsGET, err error) {
+	err = c.get("/wallet/labels", &wlg)
+	return
+}
+
+// WalletLabelsPost uses the /wallet/labels endpoint to set the label of an
+// address, output or transaction. An empty label removes the label.
+func (c *Client) WalletLabelsPost(labelType modules.WalletLabelType, id, label string) (err error) {
+	values := url.Values{}
+	values.Set("type", string(labelType))
+	values.Set("id", id)
+	values.Set("label", label)
+	err = c.post("/wallet/labels", values.Encode(), nil)
+	return
+}
+
 // WalletWatchGet requests the /wallet/watch endpoint and returns the set of
 // currently watched addresses.
 func (c *Client) WalletWatchGet() (wwg api.WalletWatchGET, err error) {
@@ -6,8 +6,11 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/uplo-tech/errors"
 
 	"github.com/uplo-tech/uplo/build"
 	"github.com/uplo-tech/uplo/crypto"
@@ -55,6 +58,13 @@ type ConsensusHeadersGET struct {
 	BlockID types.BlockID `json:"blockid"`
 }
 
+// ConsensusSnapshotPOST contains the height and hash of an exported
+// consensus snapshot.
+type ConsensusSnapshotPOST struct {
+	Height types.BlockHeight `json:"height"`
+	Hash   crypto.Hash       `json:"hash"`
+}
+
 // ConsensusBlocksGet contains all fields of a types.Block and additional
 // fields for ID and Height.
 type ConsensusBlocksGet struct {
@@ -290,6 +300,84 @@ func (api *API) consensusBlocksHandler(w http.ResponseWriter, req *http.Request,
 	WriteJSON(w, consensusBlocksGetFromBlock(b, h, d))
 }
 
+// consensusSnapshotExportHandlerPOST handles the API calls to
+// /consensus/snapshot/export.
+func (api *API) consensusSnapshotExportHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Check that the destination was specified and is an absolute path.
+	dst := req.FormValue("destination")
+	if dst == "" {
+		WriteError(w, Error{"destination not specified"}, http.StatusBadRequest)
+		return
+	}
+	if !filepath.IsAbs(dst) {
+		WriteError(w, Error{"destination must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	// Export the snapshot at the current height if no height was specified.
+	height := api.cs.Height()
+	if h := req.FormValue("height"); h != "" {
+		if _, err := fmt.Sscan(h, &height); err != nil {
+			WriteError(w, Error{"failed to parse block height"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		WriteError(w, Error{"failed to create destination: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	hash, err := api.cs.ExportSnapshot(f, height)
+	err = errors.Compose(err, f.Sync(), f.Close())
+	if err != nil {
+		err = errors.Compose(err, os.Remove(dst))
+		WriteError(w, Error{"failed to export snapshot: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, ConsensusSnapshotPOST{
+		Height: height,
+		Hash:   hash,
+	})
+}
+
+// consensusSnapshotImportHandlerPOST handles the API calls to
+// /consensus/snapshot/import.
+func (api *API) consensusSnapshotImportHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse the trusted checkpoint supplied by the operator. Without it the
+	// snapshot is checked against the checkpoints of the release.
+	var checkpoint crypto.Hash
+	if cp := req.FormValue("checkpoint"); cp != "" {
+		if err := checkpoint.LoadString(cp); err != nil {
+			WriteError(w, Error{"failed to parse checkpoint: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	} else if len(build.ConsensusSnapshotCheckpoints) == 0 {
+		WriteError(w, Error{"this release contains no snapshot checkpoints, a trusted checkpoint needs to be specified"}, http.StatusNotImplemented)
+		return
+	}
+	// Check that the source was specified and is an absolute path.
+	src := req.FormValue("source")
+	if src == "" {
+		WriteError(w, Error{"source not specified"}, http.StatusBadRequest)
+		return
+	}
+	if !filepath.IsAbs(src) {
+		WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		WriteError(w, Error{"failed to open source: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = errors.Compose(api.cs.ImportSnapshot(f, checkpoint), f.Close())
+	if err != nil {
+		WriteError(w, Error{"failed to import snapshot: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // consensusValidateTransactionsetHandler handles the API calls to
 // /consensus/validate/transactionset.
 func (api *API) consensusValidateTransactionsetHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
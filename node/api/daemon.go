@@ -14,7 +14,9 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/inconshreveable/go-update"
 
@@ -143,6 +145,19 @@ type (
 		MaxDownloadSpeed int64         `json:"maxdownloadspeed"`
 		MaxUploadSpeed   int64         `json:"maxuploadspeed"`
 		Modules          configModules `json:"modules"`
+		ProxyAddress     string        `json:"proxyaddress"`
+		ProxyOnly        bool          `json:"proxyonly"`
+	}
+
+	// DaemonTokensGet contains the API tokens of the daemon.
+	DaemonTokensGet struct {
+		Tokens []APIToken `json:"tokens"`
+	}
+
+	// DaemonTokensAddPost contains the secret of a new API token. The secret
+	// is only returned once.
+	DaemonTokensAddPost struct {
+		Token string `json:"token"`
 	}
 
 	// DaemonVersion holds the version information for uplod
@@ -155,7 +170,7 @@ type (
 
 // fetchLatestRelease returns metadata about the most recent GitLab release.
 func fetchLatestRelease() (_ gitlabRelease, err error) {
-	resp, err := http.Get("https://gitlab.com/api/v4/projects/7508674/repository/tags?order_by=name")
+	resp, err := modules.GlobalDialer.HTTPClient(0).Get("https://gitlab.com/api/v4/projects/7508674/repository/tags?order_by=name")
 	if err != nil {
 		return gitlabRelease{}, err
 	}
@@ -189,8 +204,10 @@ func updateToRelease(version string) (err error) {
 		return err
 	}
 
-	// Download file of signed hashes.
-	resp, err := http.Get(fmt.Sprintf("https://uplo.tech/releases/Uplo-%s-SHA256SUMS.txt.asc", version))
+	// Download file of signed hashes. Releases are downloaded using the
+	// global dialer, so the requests respect the proxy settings.
+	client := modules.GlobalDialer.HTTPClient(0)
+	resp, err := client.Get(fmt.Sprintf("https://uplo.tech/releases/Uplo-%s-SHA256SUMS.txt.asc", version))
 	if err != nil {
 		return err
 	}
@@ -233,7 +250,7 @@ func updateToRelease(version string) (err error) {
 
 	// download release archive
 	releaseFilePrefix := fmt.Sprintf("Uplo-%s-%s-%s", version, runtime.GOOS, runtime.GOARCH)
-	zipResp, err := http.Get(fmt.Sprintf("https://uplo.tech/releases/%s.zip", releaseFilePrefix))
+	zipResp, err := client.Get(fmt.Sprintf("https://uplo.tech/releases/%s.zip", releaseFilePrefix))
 	if err != nil {
 		return err
 	}
@@ -519,14 +536,74 @@ func (api *API) daemonStopHandler(w http.ResponseWriter, _ *http.Request, _ http
 	}()
 }
 
+// daemonTokensHandlerGET handles the API call to list the API tokens.
+func (api *API) daemonTokensHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, DaemonTokensGet{
+		Tokens: api.staticTokens.managedTokens(),
+	})
+}
+
+// daemonTokensAddHandlerPOST handles the API call to create an API token.
+func (api *API) daemonTokensAddHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	name := req.FormValue("name")
+	scopes := parseScopes(req.FormValue("scopes"))
+
+	// Parse the optional expiry.
+	var expiry time.Time
+	if expiryStr := req.FormValue("expiry"); expiryStr != "" {
+		unix, err := strconv.ParseInt(expiryStr, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse expiry: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		expiry = time.Unix(unix, 0)
+	}
+
+	// Parse the optional spend cap.
+	var spendCap types.Currency
+	if spendCapStr := req.FormValue("spendcap"); spendCapStr != "" {
+		var ok bool
+		spendCap, ok = scanAmount(spendCapStr)
+		if !ok {
+			WriteError(w, Error{"unable to parse spendcap"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	token, err := api.staticTokens.managedAdd(name, scopes, expiry, spendCap)
+	if err != nil {
+		WriteError(w, Error{"unable to create API token: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, DaemonTokensAddPost{
+		Token: token,
+	})
+}
+
+// daemonTokensRemoveHandlerPOST handles the API call to remove an API token.
+func (api *API) daemonTokensRemoveHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.staticTokens.managedRemove(req.FormValue("name"))
+	if errors.Contains(err, errTokenNotFound) {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		WriteError(w, Error{"unable to remove API token: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // daemonSettingsHandlerGET handles the API call asking for the daemon's
 // settings.
 func (api *API) daemonSettingsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	gmds, gmus, _ := modules.GlobalRateLimits.Limits()
+	proxyAddress, proxyOnly := modules.GlobalDialer.Settings()
 	WriteJSON(w, DaemonSettingsGet{
 		MaxDownloadSpeed: gmds,
 		MaxUploadSpeed:   gmus,
 		Modules:          api.staticConfigModules,
+		ProxyAddress:     proxyAddress,
+		ProxyOnly:        proxyOnly,
 	})
 }
 
@@ -552,10 +629,33 @@ func (api *API) daemonSettingsHandlerPOST(w http.ResponseWriter, req *http.Reque
 		}
 		maxUploadSpeed = uploadSpeed
 	}
+	proxyAddress, proxyOnly := modules.GlobalDialer.Settings()
+	// Scan the proxy address. (optional parameter)
+	_, setProxyAddress := req.Form["proxyaddress"]
+	if setProxyAddress {
+		proxyAddress = req.FormValue("proxyaddress")
+	}
+	// Scan whether direct connections are refused. (optional parameter)
+	_, setProxyOnly := req.Form["proxyonly"]
+	if setProxyOnly {
+		var err error
+		proxyOnly, err = strconv.ParseBool(req.FormValue("proxyonly"))
+		if err != nil {
+			WriteError(w, Error{"unable to parse proxyonly: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
 	// Set the limit.
 	if err := api.uplodConfig.SetRatelimit(maxDownloadSpeed, maxUploadSpeed); err != nil {
 		WriteError(w, Error{"unable to set limits: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
+	// Set the proxy.
+	if setProxyAddress || setProxyOnly {
+		if err := api.uplodConfig.SetProxy(proxyAddress, proxyOnly); err != nil {
+			WriteError(w, Error{"unable to set proxy: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
 	WriteSuccess(w)
 }
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+var (
+	// maxBufferedEvents is the number of recent events kept in memory, which
+	// clients can resume from.
+	maxBufferedEvents = build.Select(build.Var{
+		Standard: 10000,
+		Dev:      1000,
+		Testing:  100,
+	}).(int)
+
+	// eventKeepAliveInterval is the interval at which a comment is sent to
+	// idle event streams to keep the connection open.
+	eventKeepAliveInterval = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      10 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+)
+
+type (
+	// DaemonEvent is an event of the /daemon/events stream. The IDs of events
+	// are consecutive, so a gap between IDs means that the client missed
+	// events which were no longer buffered. After a restart of the daemon,
+	// IDs continue at the start time of the daemon in microseconds, so they
+	// are always larger than the IDs of earlier runs.
+	DaemonEvent struct {
+		ID   uint64            `json:"id"`
+		Type modules.EventType `json:"type"`
+		Time time.Time         `json:"time"`
+		Data json.RawMessage   `json:"data"`
+	}
+
+	// eventStream buffers the most recent events of the modules and notifies
+	// the streams of clients about new events. It subscribes to the consensus
+	// set itself to publish applied and reverted blocks.
+	eventStream struct {
+		events    []DaemonEvent
+		listeners []func(DaemonEvent)
+		newEvents chan struct{}
+		nextID    uint64
+		mu        sync.Mutex
+
+		// height is the height of the most recent block that was published.
+		// Until the height is known, consensus changes are kept in
+		// pendingChanges. ccMu serializes the processing of consensus changes
+		// and protects these fields.
+		height         types.BlockHeight
+		heightKnown    bool
+		pendingChanges []modules.ConsensusChange
+		ccMu           sync.Mutex
+	}
+)
+
+// newEventStream creates a new, empty event stream. The IDs of its events
+// start at the current time in microseconds, so clients that resume with the
+// ID of an event from before a restart receive all buffered events of the new
+// run instead of skipping them.
+func newEventStream() *eventStream {
+	return &eventStream{
+		heightKnown: true,
+		newEvents:   make(chan struct{}),
+		nextID:      uint64(time.Now().UnixNano() / int64(time.Microsecond)),
+	}
+}
+
+// publish adds an event to the stream and wakes up all waiting clients.
+func (es *eventStream) publish(typ modules.EventType, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		build.Critical("failed to marshal event data:", err)
+		return
+	}
+	es.mu.Lock()
+	e := DaemonEvent{
+		ID:   es.nextID,
+		Type: typ,
+		Time: time.Now(),
+		Data: b,
+	}
+	es.events = append(es.events, e)
+	es.nextID++
+	if len(es.events) > maxBufferedEvents {
+		es.events = es.events[len(es.events)-maxBufferedEvents:]
+	}
+	close(es.newEvents)
+	es.newEvents = make(chan struct{})
+	listeners := es.listeners
+	es.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(e)
+	}
+}
+
+// ProcessEvent implements the modules.EventListener interface by publishing
+// the events of the modules.
+func (es *eventStream) ProcessEvent(typ modules.EventType, data interface{}) {
+	es.publish(typ, data)
+}
+
+// addListener adds a function which is called with every published event.
+// Listeners are called synchronously, so they must not block.
+func (es *eventStream) addListener(listener func(DaemonEvent)) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.listeners = append(es.listeners, listener)
+}
+
+// managedEventsAfter returns the buffered events after the event with the
+// given ID and a channel which is closed once there are new events. IDs from
+// before a restart of the daemon are smaller than the IDs of the current run,
+// so all buffered events are returned for them. An ID which wasn't assigned
+// yet also returns all buffered events.
+func (es *eventStream) managedEventsAfter(id uint64) ([]DaemonEvent, <-chan struct{}) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if id >= es.nextID {
+		id = 0
+	}
+	var events []DaemonEvent
+	for _, e := range es.events {
+		if e.ID > id {
+			events = append(events, e)
+		}
+	}
+	return events, es.newEvents
+}
+
+// ProcessConsensusChange publishes the reverted and applied blocks of a
+// consensus change. Changes are kept until the height of the consensus set is
+// known.
+func (es *eventStream) ProcessConsensusChange(cc modules.ConsensusChange) {
+	es.ccMu.Lock()
+	defer es.ccMu.Unlock()
+	if !es.heightKnown {
+		es.pendingChanges = append(es.pendingChanges, cc)
+		return
+	}
+	es.processConsensusChange(cc)
+}
+
+// processConsensusChange publishes the blocks of a consensus change and
+// updates the height. It must be called while holding ccMu.
+func (es *eventStream) processConsensusChange(cc modules.ConsensusChange) {
+	for _, b := range cc.RevertedBlocks {
+		es.publish(modules.EventBlockReverted, modules.BlockEvent{ID: b.ID(), Height: es.height})
+		es.height--
+	}
+	for _, b := range cc.AppliedBlocks {
+		es.height++
+		es.publish(modules.EventBlockApplied, modules.BlockEvent{ID: b.ID(), Height: es.height})
+	}
+}
+
+// managedSubscribeConsensus subscribes the event stream to the consensus set
+// and determines the height of the consensus set at the time of the
+// subscription. The height can't be read atomically with the subscription, so
+// changes received in the meantime are kept until the height is known.
+func (es *eventStream) managedSubscribeConsensus(cs modules.ConsensusSet) error {
+	es.ccMu.Lock()
+	es.heightKnown = false
+	es.ccMu.Unlock()
+	err := cs.ConsensusSetSubscribe(es, modules.ConsensusChangeRecent, nil)
+	if err != nil {
+		return err
+	}
+
+	// The consensus set calls ProcessConsensusChange while holding its lock,
+	// so if no change arrived while reading the height, the height includes
+	// exactly the pending changes.
+	for {
+		es.ccMu.Lock()
+		numPending := len(es.pendingChanges)
+		es.ccMu.Unlock()
+		height := cs.Height()
+
+		es.ccMu.Lock()
+		if len(es.pendingChanges) != numPending {
+			es.ccMu.Unlock()
+			continue
+		}
+		for _, cc := range es.pendingChanges {
+			height += types.BlockHeight(len(cc.RevertedBlocks))
+			height -= types.BlockHeight(len(cc.AppliedBlocks))
+		}
+		es.height = height
+		for _, cc := range es.pendingChanges {
+			es.processConsensusChange(cc)
+		}
+		es.pendingChanges = nil
+		es.heightKnown = true
+		es.ccMu.Unlock()
+		return nil
+	}
+}
+
+// subscribeEvents subscribes the event stream of the API to the events of the
+// modules.
+func (api *API) subscribeEvents() {
+	if api.cs != nil {
+		// Subscribing to recent changes can only fail if the consensus set is
+		// shutting down.
+		_ = api.staticEvents.managedSubscribeConsensus(api.cs)
+	}
+	for _, s := range api.eventSubscribers() {
+		s.SubscribeEvents(api.staticEvents)
+	}
+}
+
+// unsubscribeEvents unsubscribes the event stream of the API from the events
+// of the modules.
+func (api *API) unsubscribeEvents() {
+	if api.cs != nil {
+		api.cs.Unsubscribe(api.staticEvents)
+	}
+	for _, s := range api.eventSubscribers() {
+		s.UnsubscribeEvents(api.staticEvents)
+	}
+}
+
+// eventSubscribers returns the loaded modules which publish events.
+func (api *API) eventSubscribers() []modules.EventSubscriber {
+	subscribers := []modules.EventSubscriber{}
+	if api.gateway != nil {
+		subscribers = append(subscribers, api.gateway)
+	}
+	if api.host != nil {
+		subscribers = append(subscribers, api.host)
+	}
+	if api.renter != nil {
+		subscribers = append(subscribers, api.renter)
+	}
+	if api.wallet != nil {
+		subscribers = append(subscribers, api.wallet)
+	}
+	return subscribers
+}
+
+// parseEventTypes parses a comma separated list of event types. An empty list
+// matches all event types.
+func parseEventTypes(s string) map[modules.EventType]struct{} {
+	eventTypes := make(map[modules.EventType]struct{})
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			eventTypes[modules.EventType(t)] = struct{}{}
+		}
+	}
+	return eventTypes
+}
+
+// daemonEventsHandlerGET handles the API call to /daemon/events. It streams
+// the events of the daemon as Server-Sent Events until the client disconnects.
+func (api *API) daemonEventsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, Error{"streaming is not supported by the connection"}, http.StatusInternalServerError)
+		return
+	}
+
+	// Clients resume from the last event they received, either by setting
+	// the Last-Event-ID header when reconnecting or the lastid parameter.
+	var lastID uint64
+	lastIDStr := req.FormValue("lastid")
+	if lastIDStr == "" {
+		lastIDStr = req.Header.Get("Last-Event-ID")
+	}
+	if lastIDStr != "" {
+		var err error
+		lastID, err = strconv.ParseUint(lastIDStr, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse last event ID: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	eventTypes := parseEventTypes(req.FormValue("types"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		events, newEvents := api.staticEvents.managedEventsAfter(lastID)
+		for _, e := range events {
+			lastID = e.ID
+			if _, match := eventTypes[e.Type]; len(eventTypes) > 0 && !match {
+				continue
+			}
+			b, err := json.Marshal(e)
+			if err != nil {
+				build.Critical("failed to marshal event:", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, b); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-req.Context().Done():
+			return
+		case <-newEvents:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestEventStream probes buffering and resuming events.
+func TestEventStream(t *testing.T) {
+	es := newEventStream()
+	first := es.nextID
+	events, newEvents := es.managedEventsAfter(0)
+	if len(events) != 0 {
+		t.Fatal("new stream shouldn't have events")
+	}
+
+	// Publishing should wake up waiting clients.
+	es.publish(modules.EventUploadFinished, modules.UploadEvent{UploPath: modules.RandomUploPath()})
+	select {
+	case <-newEvents:
+	default:
+		t.Fatal("clients weren't notified about the new event")
+	}
+	es.publish(modules.EventUploadFailed, modules.UploadEvent{Error: "failed"})
+	events, _ = es.managedEventsAfter(first)
+	if len(events) != 1 || events[0].ID != first+1 || events[0].Type != modules.EventUploadFailed {
+		t.Fatal("unexpected events", events)
+	}
+	var ue modules.UploadEvent
+	if err := json.Unmarshal(events[0].Data, &ue); err != nil || ue.Error != "failed" {
+		t.Fatal("unexpected event data", ue, err)
+	}
+
+	// An ID from before a restart returns all events.
+	events, _ = es.managedEventsAfter(first - 1000)
+	if len(events) != 2 {
+		t.Fatal("expected all events, got", len(events))
+	}
+	// So does an ID that wasn't assigned yet.
+	events, _ = es.managedEventsAfter(first + 1000)
+	if len(events) != 2 {
+		t.Fatal("expected all events, got", len(events))
+	}
+	// A restarted stream continues with larger IDs.
+	time.Sleep(time.Millisecond)
+	if restarted := newEventStream(); restarted.nextID <= es.nextID {
+		t.Fatal("IDs of restarted stream should be larger", restarted.nextID, es.nextID)
+	}
+
+	// Only the most recent events are buffered.
+	for i := 0; i < maxBufferedEvents; i++ {
+		es.publish(modules.EventUploadFinished, modules.UploadEvent{})
+	}
+	events, _ = es.managedEventsAfter(0)
+	if len(events) != maxBufferedEvents || events[0].ID != first+2 {
+		t.Fatal("unexpected buffered events", len(events), events[0].ID)
+	}
+
+	// Consensus changes are published with the heights of the blocks.
+	b1, b2 := types.Block{Timestamp: 1}, types.Block{Timestamp: 2}
+	es.ProcessConsensusChange(modules.ConsensusChange{AppliedBlocks: []types.Block{b1, b2}})
+	es.ProcessConsensusChange(modules.ConsensusChange{RevertedBlocks: []types.Block{b2}})
+	events, _ = es.managedEventsAfter(first + uint64(maxBufferedEvents) + 1)
+	expected := []modules.BlockEvent{{ID: b1.ID(), Height: 1}, {ID: b2.ID(), Height: 2}, {ID: b2.ID(), Height: 2}}
+	if len(events) != len(expected) || events[2].Type != modules.EventBlockReverted {
+		t.Fatal("unexpected block events", events)
+	}
+	for i, e := range events {
+		var be modules.BlockEvent
+		if err := json.Unmarshal(e.Data, &be); err != nil || be != expected[i] {
+			t.Fatal("unexpected block event", i, be, err)
+		}
+	}
+}
+
+// TestDaemonEventsHandler checks that events are streamed as Server-Sent
+// Events and that clients can filter them by type.
+func TestDaemonEventsHandler(t *testing.T) {
+	api := &API{staticEvents: newEventStream()}
+	first := api.staticEvents.nextID
+	router := httprouter.New()
+	router.GET("/daemon/events", api.daemonEventsHandlerGET)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	api.staticEvents.publish(modules.EventUploadFinished, modules.UploadEvent{})
+	api.staticEvents.publish(modules.EventWalletTransaction, modules.WalletTransactionEvent{})
+	resp, err := http.Get(srv.URL + "/daemon/events?types=" + string(modules.EventWalletTransaction))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatal("unexpected response", resp.StatusCode, resp.Header)
+	}
+
+	// The buffered event of the requested type should be streamed first,
+	// followed by new events.
+	api.staticEvents.publish(modules.EventWalletTransaction, modules.WalletTransactionEvent{ConfirmationHeight: 5})
+	scanner := bufio.NewScanner(resp.Body)
+	var events []DaemonEvent
+	for len(events) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e DaemonEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 || events[0].ID != first+1 || events[1].ID != first+2 || events[1].Type != modules.EventWalletTransaction {
+		t.Fatal("unexpected events", events, scanner.Err())
+	}
+}
@@ -12,6 +12,24 @@ import (
 	"github.com/uplo-tech/uplo/types"
 )
 
+const (
+	// defaultExplorerAddressLimit is the number of transactions returned by
+	// /explorer/addresses/:addr if no limit is specified.
+	defaultExplorerAddressLimit = 100
+
+	// maxExplorerAddressLimit is the maximum number of transactions returned
+	// by a single call to /explorer/addresses/:addr.
+	maxExplorerAddressLimit = 1000
+
+	// defaultExplorerHostLimit is the number of hosts returned by
+	// /explorer/hosts if no limit is specified.
+	defaultExplorerHostLimit = 100
+
+	// maxExplorerHostLimit is the maximum number of hosts returned by a single
+	// call to /explorer/hosts.
+	maxExplorerHostLimit = 1000
+)
+
 type (
 	// ExplorerBlock is a block with some extra information such as the id and
 	// height. This information is provided for programs that may not be
@@ -59,6 +77,32 @@ type (
 		Block ExplorerBlock `json:"block"`
 	}
 
+	// ExplorerAddressGET is the object returned as a response to a GET request
+	// to /explorer/addresses/:addr. It contains the balance and the unspent
+	// outputs of the address as well as a page of its transaction history,
+	// ordered from the most recent to the oldest transaction. Blocks are
+	// returned for miner payouts to the address.
+	ExplorerAddressGET struct {
+		modules.ExplorerAddress
+
+		TotalTransactions uint64                `json:"totaltransactions"`
+		Blocks            []ExplorerBlock       `json:"blocks"`
+		Transactions      []ExplorerTransaction `json:"transactions"`
+	}
+
+	// ExplorerHostsGET is the object returned as a response to a GET request
+	// to /explorer/hosts.
+	ExplorerHostsGET struct {
+		Hosts      []modules.ExplorerHost `json:"hosts"`
+		TotalHosts uint64                 `json:"totalhosts"`
+	}
+
+	// ExplorerHostGET is the object returned as a response to a GET request to
+	// /explorer/hosts/:pubkey.
+	ExplorerHostGET struct {
+		Host modules.ExplorerHost `json:"host"`
+	}
+
 	// ExplorerHashGET is the object returned as a response to a GET request to
 	// /explorer/hash. The HashType will indicate whether the hash corresponds
 	// to a block id, a transaction id, a Uplocoin output id, a file contract
@@ -245,6 +289,103 @@ func (api *API) buildTransactionSet(txids []types.TransactionID) (txns []Explore
 	return txns, blocks
 }
 
+// explorerAddressesHandler handles GET requests to /explorer/addresses/:addr.
+func (api *API) explorerAddressesHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr, err := scanAddress(ps.ByName("addr"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Lookups on the zero address are too expensive to allow, see
+	// explorerHashHandler.
+	if addr == (types.UnlockHash{}) {
+		WriteError(w, Error{"can't lookup the empty unlock hash"}, http.StatusBadRequest)
+		return
+	}
+
+	// Parse the pagination parameters.
+	offset, limit := uint64(0), uint64(defaultExplorerAddressLimit)
+	if offsetStr := req.FormValue("offset"); offsetStr != "" {
+		if _, err := fmt.Sscan(offsetStr, &offset); err != nil {
+			WriteError(w, Error{"unable to parse offset: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if limitStr := req.FormValue("limit"); limitStr != "" {
+		if _, err := fmt.Sscan(limitStr, &limit); err != nil {
+			WriteError(w, Error{"unable to parse limit: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if limit == 0 || limit > maxExplorerAddressLimit {
+		WriteError(w, Error{fmt.Sprintf("limit must be between 1 and %v", maxExplorerAddressLimit)}, http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the requested page of the history.
+	txids := api.explorer.AddressHistory(addr)
+	total := uint64(len(txids))
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	txns, blocks := api.buildTransactionSet(txids[offset:end])
+	WriteJSON(w, ExplorerAddressGET{
+		ExplorerAddress:   api.explorer.Address(addr),
+		TotalTransactions: total,
+		Blocks:            blocks,
+		Transactions:      txns,
+	})
+}
+
+// explorerHostsHandler handles GET requests to /explorer/hosts.
+func (api *API) explorerHostsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse the pagination parameters.
+	offset, limit := uint64(0), uint64(defaultExplorerHostLimit)
+	if offsetStr := req.FormValue("offset"); offsetStr != "" {
+		if _, err := fmt.Sscan(offsetStr, &offset); err != nil {
+			WriteError(w, Error{"unable to parse offset: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if limitStr := req.FormValue("limit"); limitStr != "" {
+		if _, err := fmt.Sscan(limitStr, &limit); err != nil {
+			WriteError(w, Error{"unable to parse limit: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if limit == 0 || limit > maxExplorerHostLimit {
+		WriteError(w, Error{fmt.Sprintf("limit must be between 1 and %v", maxExplorerHostLimit)}, http.StatusBadRequest)
+		return
+	}
+
+	hosts, total := api.explorer.Hosts(offset, limit)
+	WriteJSON(w, ExplorerHostsGET{
+		Hosts:      hosts,
+		TotalHosts: total,
+	})
+}
+
+// explorerHostHandler handles GET requests to /explorer/hosts/:pubkey.
+func (api *API) explorerHostHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	var pk types.UploPublicKey
+	if err := pk.LoadString(ps.ByName("pubkey")); err != nil {
+		WriteError(w, Error{"unable to parse public key: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	host, exists := api.explorer.Host(pk)
+	if !exists {
+		WriteError(w, Error{"host hasn't announced itself in the blockchain"}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, ExplorerHostGET{
+		Host: host,
+	})
+}
+
 // explorerHashHandler handles GET requests to /explorer/hash/:hash.
 func (api *API) explorerHashHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
 	// Scan the hash as a hash. If that fails, try scanning the hash as an
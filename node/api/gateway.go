@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -20,6 +21,10 @@ type (
 
 		MaxDownloadSpeed int64 `json:"maxdownloadspeed"`
 		MaxUploadSpeed   int64 `json:"maxuploadspeed"`
+
+		RequireEncryption bool `json:"requireencryption"`
+
+		Reputations []modules.PeerReputation `json:"reputations"`
 	}
 
 	// GatewayBandwidthGET contains the bandwidth usage of the gateway
@@ -53,7 +58,11 @@ func (api *API) gatewayHandlerGET(w http.ResponseWriter, _ *http.Request, _ http
 	if peers == nil {
 		peers = make([]modules.Peer, 0)
 	}
-	WriteJSON(w, GatewayGET{api.gateway.Address(), peers, api.gateway.Online(), mds, mus})
+	reputations := api.gateway.PeerReputations()
+	if reputations == nil {
+		reputations = make([]modules.PeerReputation, 0)
+	}
+	WriteJSON(w, GatewayGET{api.gateway.Address(), peers, api.gateway.Online(), mds, mus, api.gateway.RequireEncryption(), reputations})
 }
 
 // gatewayHandlerPOST handles the API call changing gateway specific settings.
@@ -77,6 +86,18 @@ func (api *API) gatewayHandlerPOST(w http.ResponseWriter, req *http.Request, _ h
 		}
 		maxUploadSpeed = uploadSpeed
 	}
+	// Scan whether encryption is required. (optional parameter)
+	if r := req.FormValue("requireencryption"); r != "" {
+		requireEncryption, err := strconv.ParseBool(r)
+		if err != nil {
+			WriteError(w, Error{"unable to parse requireencryption: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.gateway.SetRequireEncryption(requireEncryption); err != nil {
+			WriteError(w, Error{"failed to set requireencryption: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
 	// Try to set the limits.
 	err := api.gateway.SetRateLimits(maxDownloadSpeed, maxUploadSpeed)
 	if err != nil {
@@ -125,6 +146,19 @@ func (api *API) gatewayDisconnectHandler(w http.ResponseWriter, _ *http.Request,
 	WriteSuccess(w)
 }
 
+// gatewayForgetNodeKeyHandler handles the API call to forget the node key of a
+// node.
+func (api *API) gatewayForgetNodeKeyHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	addr := modules.NetAddress(ps.ByName("netaddress"))
+	err := api.gateway.ForgetNodeKey(addr)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
 // gatewayBlocklistHandlerGET handles the API call to get the gateway's
 // blocklist
 func (api *API) gatewayBlocklistHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 
+	"github.com/uplo-tech/uplo/modules"
 	"github.com/uplo-tech/uplo/types"
 	"github.com/uplo-tech/encoding"
 )
@@ -18,6 +19,12 @@ type (
 		CPUMining        bool `json:"cpumining"`
 		StaleBlocksMined int  `json:"staleblocksmined"`
 	}
+
+	// MinerStratumGET contains the information that is returned after a GET
+	// request to /miner/stratum.
+	MinerStratumGET struct {
+		modules.StratumStatus
+	}
 )
 
 // minerHandler handles the API call that queries the miner's status.
@@ -32,6 +39,14 @@ func (api *API) minerHandler(w http.ResponseWriter, _ *http.Request, _ httproute
 	WriteJSON(w, mg)
 }
 
+// minerStratumHandlerGET handles the API call that queries the status of the
+// miner's Stratum server.
+func (api *API) minerStratumHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, MinerStratumGET{
+		StratumStatus: api.miner.StratumStatus(),
+	})
+}
+
 // minerStartHandler handles the API call that starts the miner.
 func (api *API) minerStartHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	api.miner.StartCPUMining()
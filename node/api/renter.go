@@ -157,6 +157,16 @@ type (
 		Files       []modules.FileInfo      `json:"files"`
 	}
 
+	// RenterFileVersions lists the previous versions of a file.
+	RenterFileVersions struct {
+		Versions []modules.FileVersion `json:"versions"`
+	}
+
+	// RenterTrash lists the items within the renter's trash.
+	RenterTrash struct {
+		Items []modules.TrashedItem `json:"items"`
+	}
+
 	// RenterDownloadQueue contains the renter's download queue.
 	RenterDownloadQueue struct {
 		Downloads []DownloadInfo `json:"downloads"`
@@ -883,6 +893,16 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 		settings.MaxUploadSpeed = uploadSpeed
 	}
 
+	// Scan the trash retention. (optional parameter)
+	if tr := req.FormValue("trashretention"); tr != "" {
+		var retention uint64
+		if _, err := fmt.Sscan(tr, &retention); err != nil {
+			WriteError(w, Error{"unable to parse trashretention: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.TrashRetention = time.Duration(retention) * time.Second
+	}
+
 	// Scan the checkforipviolation flag.
 	if ipc := req.FormValue("checkforipviolation"); ipc != "" {
 		var ipviolationcheck bool
@@ -1873,6 +1893,22 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 			return
 		}
 	}
+	// Check whether the file should be deduplicated
+	dedup := false
+	if d := req.FormValue("dedup"); d != "" {
+		dedup, err = strconv.ParseBool(d)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'dedup' parameter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Check whether the file should be compressed
+	var compression modules.CompressionType
+	err = compression.FromString(req.FormValue("compression"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse 'compression' parameter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
 	// Parse the erasure coder.
 	ec, err := parseErasureCodingParameters(req.FormValue("datapieces"), req.FormValue("paritypieces"))
 	if err != nil {
@@ -1897,6 +1933,8 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 		ErasureCode:         ec,
 		Force:               force,
 		DisablePartialChunk: true, // TODO: remove this
+		Dedup:               dedup,
+		Compression:         compression,
 
 		// NOTE: can make this an optional param.
 		CipherType: crypto.TypeDefaultRenter,
@@ -2181,6 +2219,20 @@ func (api *API) renterDirHandlerPOST(w http.ResponseWriter, req *http.Request, p
 		WriteSuccess(w)
 		return
 	}
+	if action == "versioning" {
+		policy, err := parseVersioningPolicy(req)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.renter.SetDirVersioning(uploPath, policy)
+		if err != nil {
+			WriteError(w, Error{"failed to set versioning policy: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		WriteSuccess(w)
+		return
+	}
 	if action == "rename" {
 		newUploPath, err := modules.NewUploPath(req.FormValue("newuplopath"))
 		if err != nil {
@@ -2206,6 +2258,163 @@ func (api *API) renterDirHandlerPOST(w http.ResponseWriter, req *http.Request, p
 	return
 }
 
+// parseVersioningPolicy parses the versioning policy from the 'enabled',
+// 'maxversions' and 'maxage' parameters of a request.
+func parseVersioningPolicy(req *http.Request) (policy modules.VersioningPolicy, err error) {
+	enabled := req.FormValue("enabled")
+	if enabled == "" {
+		return modules.VersioningPolicy{}, errors.New("enabled must be specified")
+	}
+	policy.Enabled, err = strconv.ParseBool(enabled)
+	if err != nil {
+		return modules.VersioningPolicy{}, errors.AddContext(err, "unable to parse 'enabled' parameter")
+	}
+	policy.MaxVersions, policy.MaxAge, err = parseVersionLimits(req)
+	return policy, err
+}
+
+// parseVersionLimits parses the optional 'maxversions' and 'maxage'
+// parameters of a request. The max age is specified in seconds.
+func parseVersionLimits(req *http.Request) (maxVersions uint64, maxAge time.Duration, err error) {
+	if mv := req.FormValue("maxversions"); mv != "" {
+		maxVersions, err = strconv.ParseUint(mv, 10, 64)
+		if err != nil {
+			return 0, 0, errors.AddContext(err, "unable to parse 'maxversions' parameter")
+		}
+	}
+	if ma := req.FormValue("maxage"); ma != "" {
+		seconds, err := strconv.ParseUint(ma, 10, 64)
+		if err != nil {
+			return 0, 0, errors.AddContext(err, "unable to parse 'maxage' parameter")
+		}
+		maxAge = time.Duration(seconds) * time.Second
+	}
+	return maxVersions, maxAge, nil
+}
+
+// renterVersionsHandlerGET handles GET requests to
+// /renter/versions/:uplopath and returns the previous versions of a file.
+func (api *API) renterVersionsHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	uploPath, err := parseVersionsUploPath(req, ps)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	versions, err := api.renter.FileVersions(uploPath)
+	if err != nil {
+		WriteError(w, Error{"failed to get file versions: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, RenterFileVersions{
+		Versions: versions,
+	})
+}
+
+// renterVersionsHandlerPOST handles POST requests to
+// /renter/versions/:uplopath?action=<> in order to restore and prune the
+// previous versions of a file.
+func (api *API) renterVersionsHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	uploPath, err := parseVersionsUploPath(req, ps)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	switch action := req.FormValue("action"); action {
+	case "restore":
+		id := req.FormValue("id")
+		if id == "" {
+			WriteError(w, Error{"id must be specified"}, http.StatusBadRequest)
+			return
+		}
+		err = api.renter.RestoreFileVersion(uploPath, id)
+		if errors.Contains(err, renter.ErrUnknownVersion) {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			WriteError(w, Error{"failed to restore file version: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	case "prune":
+		maxVersions, maxAge, err := parseVersionLimits(req)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.renter.PruneFileVersions(uploPath, maxVersions, maxAge)
+		if err != nil {
+			WriteError(w, Error{"failed to prune file versions: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	default:
+		WriteError(w, Error{fmt.Sprintf("unknown action '%v', must be 'restore' or 'prune'", action)}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// parseVersionsUploPath parses the uplopath of a request to /renter/versions,
+// rebasing it to the user folder unless the root flag is set.
+func parseVersionsUploPath(req *http.Request, ps httprouter.Params) (modules.UploPath, error) {
+	uploPath, err := modules.NewUploPath(ps.ByName("uplopath"))
+	if err != nil {
+		return modules.UploPath{}, err
+	}
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		return modules.UploPath{}, err
+	}
+	if root {
+		return uploPath, nil
+	}
+	return rebaseInputUploPath(uploPath)
+}
+
+// renterTrashHandlerGET handles GET requests to /renter/trash in order to list
+// the items within the trash.
+func (api *API) renterTrashHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	items, err := api.renter.Trash()
+	if err != nil {
+		WriteError(w, Error{"failed to get trashed items: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, RenterTrash{
+		Items: items,
+	})
+}
+
+// renterTrashHandlerPOST handles POST requests to /renter/trash?action=<> in
+// order to restore items from the trash and to empty the trash.
+func (api *API) renterTrashHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	switch action := req.FormValue("action"); action {
+	case "restore":
+		id := req.FormValue("id")
+		if id == "" {
+			WriteError(w, Error{"id must be specified"}, http.StatusBadRequest)
+			return
+		}
+		err := api.renter.RestoreTrashedItem(id)
+		if errors.Contains(err, renter.ErrUnknownTrashedItem) {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			WriteError(w, Error{"failed to restore trashed item: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	case "empty":
+		err := api.renter.EmptyTrash()
+		if err != nil {
+			WriteError(w, Error{"failed to empty trash: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	default:
+		WriteError(w, Error{fmt.Sprintf("unknown action '%v', must be 'restore' or 'empty'", action)}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterContractStatusHandler  handles the API call to check the status of a
 // contract monitored by the renter.
 func (api *API) renterContractStatusHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
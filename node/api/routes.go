@@ -27,7 +27,6 @@ var (
 // parameters: requiredUserAgent and requiredPassword
 func (api *API) buildHTTPRoutes() {
 	router := httprouter.New()
-	requiredPassword := api.requiredPassword
 	requiredUserAgent := api.requiredUserAgent
 
 	router.NotFound = http.HandlerFunc(api.UnrecognizedCallHandler)
@@ -36,20 +35,30 @@ func (api *API) buildHTTPRoutes() {
 	// Daemon API Calls
 	router.GET("/daemon/alerts", api.daemonAlertsHandlerGET)
 	router.GET("/daemon/constants", api.daemonConstantsHandler)
+	router.GET("/daemon/events", api.requireScope(api.daemonEventsHandlerGET, ScopeDaemonEvents))
 	router.GET("/daemon/settings", api.daemonSettingsHandlerGET)
 	router.POST("/daemon/settings", api.daemonSettingsHandlerPOST)
 	router.GET("/daemon/stack", api.daemonStackHandlerGET)
 	router.POST("/daemon/startprofile", api.daemonStartProfileHandlerPOST)
-	router.GET("/daemon/stop", RequirePassword(api.daemonStopHandler, requiredPassword))
+	router.GET("/daemon/stop", api.requireScope(api.daemonStopHandler, ScopeDaemonAdmin))
 	router.POST("/daemon/stopprofile", api.daemonStopProfileHandlerPOST)
+	router.GET("/daemon/tokens", api.requireScope(api.daemonTokensHandlerGET, ScopeDaemonAdmin))
+	router.POST("/daemon/tokens/add", api.requireScope(api.daemonTokensAddHandlerPOST, ScopeDaemonAdmin))
+	router.POST("/daemon/tokens/remove", api.requireScope(api.daemonTokensRemoveHandlerPOST, ScopeDaemonAdmin))
 	router.GET("/daemon/update", api.daemonUpdateHandlerGET)
 	router.POST("/daemon/update", api.daemonUpdateHandlerPOST)
 	router.GET("/daemon/version", api.daemonVersionHandler)
+	router.GET("/daemon/webhooks", api.requireScope(api.daemonWebhooksHandlerGET, ScopeDaemonAdmin))
+	router.POST("/daemon/webhooks/add", api.requireScope(api.daemonWebhooksAddHandlerPOST, ScopeDaemonAdmin))
+	router.GET("/daemon/webhooks/deliveries", api.requireScope(api.daemonWebhookDeliveriesHandlerGET, ScopeDaemonAdmin))
+	router.POST("/daemon/webhooks/remove", api.requireScope(api.daemonWebhooksRemoveHandlerPOST, ScopeDaemonAdmin))
 
 	// Consensus API Calls
 	if api.cs != nil {
 		router.GET("/consensus", api.consensusHandler)
 		router.GET("/consensus/blocks", api.consensusBlocksHandler)
+		router.POST("/consensus/snapshot/export", api.requireScope(api.consensusSnapshotExportHandlerPOST, ScopeConsensusAdmin))
+		router.POST("/consensus/snapshot/import", api.requireScope(api.consensusSnapshotImportHandlerPOST, ScopeConsensusAdmin))
 		router.GET("/consensus/subscribe/:id", api.consensusSubscribeHandler)
 		router.POST("/consensus/validate/transactionset", api.consensusValidateTransactionsetHandler)
 	}
@@ -58,14 +67,17 @@ func (api *API) buildHTTPRoutes() {
 	if api.explorer != nil {
 		router.GET("/explorer", api.explorerHandler)
 		router.GET("/explorer/blocks/:height", api.explorerBlocksHandler)
+		router.GET("/explorer/addresses/:addr", api.explorerAddressesHandler)
+		router.GET("/explorer/hosts", api.explorerHostsHandler)
+		router.GET("/explorer/hosts/:pubkey", api.explorerHostHandler)
 		router.GET("/explorer/hashes/:hash", api.explorerHashHandler)
 	}
 
 	// FeeManager API Calls
 	if api.feemanager != nil {
 		router.GET("/feemanager", api.feemanagerHandlerGET)
-		router.POST("/feemanager/add", RequirePassword(api.feemanagerAddHandlerPOST, requiredPassword))
-		router.POST("/feemanager/cancel", RequirePassword(api.feemanagerCancelHandlerPOST, requiredPassword))
+		router.POST("/feemanager/add", api.requireScope(api.feemanagerAddHandlerPOST, ScopeFeeManagerAdmin))
+		router.POST("/feemanager/cancel", api.requireScope(api.feemanagerCancelHandlerPOST, ScopeFeeManagerAdmin))
 		router.GET("/feemanager/paidfees", api.feemanagerPaidFeesHandlerGET)
 		router.GET("/feemanager/pendingfees", api.feemanagerPendingFeesHandlerGET)
 	}
@@ -75,107 +87,118 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/gateway", api.gatewayHandlerGET)
 		router.POST("/gateway", api.gatewayHandlerPOST)
 		router.GET("/gateway/bandwidth", api.gatewayBandwidthHandlerGET)
-		router.POST("/gateway/connect/:netaddress", RequirePassword(api.gatewayConnectHandler, requiredPassword))
-		router.POST("/gateway/disconnect/:netaddress", RequirePassword(api.gatewayDisconnectHandler, requiredPassword))
+		router.POST("/gateway/connect/:netaddress", api.requireScope(api.gatewayConnectHandler, ScopeGatewayAdmin))
+		router.POST("/gateway/disconnect/:netaddress", api.requireScope(api.gatewayDisconnectHandler, ScopeGatewayAdmin))
+		router.POST("/gateway/forgetnodekey/:netaddress", api.requireScope(api.gatewayForgetNodeKeyHandler, ScopeGatewayAdmin))
 		router.GET("/gateway/blocklist", api.gatewayBlocklistHandlerGET)
-		router.POST("/gateway/blocklist", RequirePassword(api.gatewayBlocklistHandlerPOST, requiredPassword))
+		router.POST("/gateway/blocklist", api.requireScope(api.gatewayBlocklistHandlerPOST, ScopeGatewayAdmin))
 
 		// Deprecated fields
 		router.GET("/gateway/blacklist", api.gatewayBlocklistHandlerGET)
-		router.POST("/gateway/blacklist", RequirePassword(api.gatewayBlocklistHandlerPOST, requiredPassword))
+		router.POST("/gateway/blacklist", api.requireScope(api.gatewayBlocklistHandlerPOST, ScopeGatewayAdmin))
 	}
 
 	// Host API Calls
 	if api.host != nil {
 		// Calls directly pertaining to the host.
-		router.GET("/host", api.hostHandlerGET)                                                   // Get the host status.
-		router.POST("/host", RequirePassword(api.hostHandlerPOST, requiredPassword))              // Change the settings of the host.
-		router.POST("/host/announce", RequirePassword(api.hostAnnounceHandler, requiredPassword)) // Announce the host to the network.
-		router.GET("/host/contracts", api.hostContractInfoHandler)                                // Get info about contracts.
+		router.GET("/host", api.hostHandlerGET)                                                  // Get the host status.
+		router.POST("/host", api.requireScope(api.hostHandlerPOST, ScopeHostAdmin))              // Change the settings of the host.
+		router.POST("/host/announce", api.requireScope(api.hostAnnounceHandler, ScopeHostAdmin)) // Announce the host to the network.
+		router.GET("/host/contracts", api.hostContractInfoHandler)                               // Get info about contracts.
 		router.GET("/host/estimatescore", api.hostEstimateScoreGET)
 		router.GET("/host/bandwidth", api.hostBandwidthHandlerGET)
 
 		// Calls pertaining to the storage manager that the host uses.
 		router.GET("/host/storage", api.storageHandler)
-		router.POST("/host/storage/folders/add", RequirePassword(api.storageFoldersAddHandler, requiredPassword))
-		router.POST("/host/storage/folders/remove", RequirePassword(api.storageFoldersRemoveHandler, requiredPassword))
-		router.POST("/host/storage/folders/resize", RequirePassword(api.storageFoldersResizeHandler, requiredPassword))
-		router.POST("/host/storage/sectors/delete/:merkleroot", RequirePassword(api.storageSectorsDeleteHandler, requiredPassword))
+		router.POST("/host/storage/folders/add", api.requireScope(api.storageFoldersAddHandler, ScopeHostAdmin))
+		router.POST("/host/storage/folders/remove", api.requireScope(api.storageFoldersRemoveHandler, ScopeHostAdmin))
+		router.POST("/host/storage/folders/resize", api.requireScope(api.storageFoldersResizeHandler, ScopeHostAdmin))
+		router.POST("/host/storage/sectors/delete/:merkleroot", api.requireScope(api.storageSectorsDeleteHandler, ScopeHostAdmin))
 	}
 
 	// Miner API Calls
 	if api.miner != nil {
 		router.GET("/miner", api.minerHandler)
-		router.POST("/miner/block", RequirePassword(api.minerBlockHandlerPOST, requiredPassword))
-		router.GET("/miner/header", RequirePassword(api.minerHeaderHandlerGET, requiredPassword))
-		router.POST("/miner/header", RequirePassword(api.minerHeaderHandlerPOST, requiredPassword))
-		router.GET("/miner/start", RequirePassword(api.minerStartHandler, requiredPassword))
-		router.GET("/miner/stop", RequirePassword(api.minerStopHandler, requiredPassword))
+		router.POST("/miner/block", api.requireScope(api.minerBlockHandlerPOST, ScopeMinerAdmin))
+		router.GET("/miner/header", api.requireScope(api.minerHeaderHandlerGET, ScopeMinerAdmin))
+		router.POST("/miner/header", api.requireScope(api.minerHeaderHandlerPOST, ScopeMinerAdmin))
+		router.GET("/miner/start", api.requireScope(api.minerStartHandler, ScopeMinerAdmin))
+		router.GET("/miner/stop", api.requireScope(api.minerStopHandler, ScopeMinerAdmin))
+		router.GET("/miner/stratum", api.minerStratumHandlerGET)
 	}
 
 	// Renter API Calls
 	if api.renter != nil {
 		router.GET("/renter", api.renterHandlerGET)
-		router.POST("/renter", RequirePassword(api.renterHandlerPOST, requiredPassword))
-		router.POST("/renter/allowance/cancel", RequirePassword(api.renterAllowanceCancelHandlerPOST, requiredPassword))
-		router.GET("/renter/backups", RequirePassword(api.renterBackupsHandlerGET, requiredPassword))
-		router.POST("/renter/backups/create", RequirePassword(api.renterBackupsCreateHandlerPOST, requiredPassword))
-		router.POST("/renter/backups/restore", RequirePassword(api.renterBackupsRestoreHandlerGET, requiredPassword))
-		router.POST("/renter/clean", RequirePassword(api.renterCleanHandlerPOST, requiredPassword))
-		router.POST("/renter/contract/cancel", RequirePassword(api.renterContractCancelHandler, requiredPassword))
+		router.POST("/renter", api.requireScope(api.renterHandlerPOST, ScopeRenterAdmin))
+		router.POST("/renter/allowance/cancel", api.requireScope(api.renterAllowanceCancelHandlerPOST, ScopeRenterAdmin))
+		router.GET("/renter/backups", api.requireScope(api.renterBackupsHandlerGET, ScopeRenterRead))
+		router.POST("/renter/backups/create", api.requireScope(api.renterBackupsCreateHandlerPOST, ScopeRenterWrite))
+		router.POST("/renter/backups/restore", api.requireScope(api.renterBackupsRestoreHandlerGET, ScopeRenterWrite))
+		router.POST("/renter/clean", api.requireScope(api.renterCleanHandlerPOST, ScopeRenterWrite))
+		router.POST("/renter/contract/cancel", api.requireScope(api.renterContractCancelHandler, ScopeRenterAdmin))
 		router.GET("/renter/contracts", api.renterContractsHandler)
 		router.GET("/renter/contractorchurnstatus", api.renterContractorChurnStatus)
 
 		router.GET("/renter/downloadinfo/*uid", api.renterDownloadByUIDHandlerGET)
 		router.GET("/renter/downloads", api.renterDownloadsHandler)
-		router.POST("/renter/downloads/clear", RequirePassword(api.renterClearDownloadsHandler, requiredPassword))
+		router.POST("/renter/downloads/clear", api.requireScope(api.renterClearDownloadsHandler, ScopeRenterWrite))
 		router.GET("/renter/files", api.renterFilesHandler)
 		router.GET("/renter/file/*uplopath", api.renterFileHandlerGET)
-		router.POST("/renter/file/*uplopath", RequirePassword(api.renterFileHandlerPOST, requiredPassword))
+		router.POST("/renter/file/*uplopath", api.requireScope(api.renterFileHandlerPOST, ScopeRenterWrite))
 		router.GET("/renter/prices", api.renterPricesHandler)
-		router.POST("/renter/recoveryscan", RequirePassword(api.renterRecoveryScanHandlerPOST, requiredPassword))
+		router.POST("/renter/recoveryscan", api.requireScope(api.renterRecoveryScanHandlerPOST, ScopeRenterWrite))
 		router.GET("/renter/recoveryscan", api.renterRecoveryScanHandlerGET)
 		router.GET("/renter/fuse", api.renterFuseHandlerGET)
-		router.POST("/renter/fuse/mount", RequirePassword(api.renterFuseMountHandlerPOST, requiredPassword))
-		router.POST("/renter/fuse/unmount", RequirePassword(api.renterFuseUnmountHandlerPOST, requiredPassword))
+		router.POST("/renter/fuse/mount", api.requireScope(api.renterFuseMountHandlerPOST, ScopeRenterWrite))
+		router.POST("/renter/fuse/unmount", api.requireScope(api.renterFuseUnmountHandlerPOST, ScopeRenterWrite))
 
-		router.POST("/renter/delete/*uplopath", RequirePassword(api.renterDeleteHandler, requiredPassword))
-		router.GET("/renter/download/*uplopath", RequirePassword(api.renterDownloadHandler, requiredPassword))
-		router.POST("/renter/download/cancel", RequirePassword(api.renterCancelDownloadHandler, requiredPassword))
-		router.GET("/renter/downloadasync/*uplopath", RequirePassword(api.renterDownloadAsyncHandler, requiredPassword))
-		router.POST("/renter/rename/*uplopath", RequirePassword(api.renterRenameHandler, requiredPassword))
+		router.POST("/renter/delete/*uplopath", api.requireScope(api.renterDeleteHandler, ScopeRenterWrite))
+		router.GET("/renter/download/*uplopath", api.requireScope(api.renterDownloadHandler, ScopeRenterWrite))
+		router.POST("/renter/download/cancel", api.requireScope(api.renterCancelDownloadHandler, ScopeRenterWrite))
+		router.GET("/renter/downloadasync/*uplopath", api.requireScope(api.renterDownloadAsyncHandler, ScopeRenterWrite))
+		router.POST("/renter/rename/*uplopath", api.requireScope(api.renterRenameHandler, ScopeRenterWrite))
 		router.GET("/renter/stream/*uplopath", api.renterStreamHandler)
-		router.POST("/renter/upload/*uplopath", RequirePassword(api.renterUploadHandler, requiredPassword))
+		router.POST("/renter/upload/*uplopath", api.requireScope(api.renterUploadHandler, ScopeRenterWrite))
 		router.GET("/renter/uploadready", api.renterUploadReadyHandler)
-		router.POST("/renter/uploads/pause", RequirePassword(api.renterUploadsPauseHandler, requiredPassword))
-		router.POST("/renter/uploads/resume", RequirePassword(api.renterUploadsResumeHandler, requiredPassword))
-		router.POST("/renter/uploadstream/*uplopath", RequirePassword(api.renterUploadStreamHandler, requiredPassword))
-		router.POST("/renter/validateuplopath/*uplopath", RequirePassword(api.renterValidateUploPathHandler, requiredPassword))
+		router.POST("/renter/uploads/pause", api.requireScope(api.renterUploadsPauseHandler, ScopeRenterWrite))
+		router.POST("/renter/uploads/resume", api.requireScope(api.renterUploadsResumeHandler, ScopeRenterWrite))
+		router.POST("/renter/uploadstream/*uplopath", api.requireScope(api.renterUploadStreamHandler, ScopeRenterWrite))
+		router.POST("/renter/validateuplopath/*uplopath", api.requireScope(api.renterValidateUploPathHandler, ScopeRenterRead))
+		router.GET("/renter/versions/*uplopath", api.renterVersionsHandlerGET)
+		router.POST("/renter/versions/*uplopath", api.requireScope(api.renterVersionsHandlerPOST, ScopeRenterWrite))
+		router.GET("/renter/trash", api.renterTrashHandlerGET)
+		router.POST("/renter/trash", api.requireScope(api.renterTrashHandlerPOST, ScopeRenterWrite))
 		router.GET("/renter/workers", api.renterWorkersHandler)
 
 		// Skynet endpoints
 		router.GET("/skynet/basesector/*skylink", api.skynetBaseSectorHandlerGET)
 		router.GET("/skynet/blocklist", api.skynetBlocklistHandlerGET)
-		router.POST("/skynet/blocklist", RequirePassword(api.skynetBlocklistHandlerPOST, requiredPassword))
-		router.POST("/skynet/pin/:skylink", RequirePassword(api.skynetSkylinkPinHandlerPOST, requiredPassword))
+		router.POST("/skynet/blocklist", api.requireScope(api.skynetBlocklistHandlerPOST, ScopeSkynetAdmin))
+		router.POST("/skynet/pin/:skylink", api.requireScope(api.skynetSkylinkPinHandlerPOST, ScopeSkynetUpload))
 		router.GET("/skynet/portals", api.skynetPortalsHandlerGET)
-		router.POST("/skynet/portals", RequirePassword(api.skynetPortalsHandlerPOST, requiredPassword))
+		router.POST("/skynet/portals", api.requireScope(api.skynetPortalsHandlerPOST, ScopeSkynetAdmin))
 		router.GET("/skynet/root", api.skynetRootHandlerGET)
 		router.GET("/skynet/skylink/*skylink", api.skynetSkylinkHandlerGET)
 		router.HEAD("/skynet/skylink/*skylink", api.skynetSkylinkHandlerGET)
-		router.POST("/skynet/skyfile/*uplopath", RequirePassword(api.skynetSkyfileHandlerPOST, requiredPassword))
-		router.POST("/skynet/registry", RequirePassword(api.registryHandlerPOST, requiredPassword))
+		router.POST("/skynet/skyfile/*uplopath", api.requireScope(api.skynetSkyfileHandlerPOST, ScopeSkynetUpload))
+		router.POST("/skynet/registry", api.requireScope(api.registryHandlerPOST, ScopeSkynetUpload))
 		router.GET("/skynet/registry", api.registryHandlerGET)
-		router.POST("/skynet/restore", RequirePassword(api.skynetRestoreHandlerPOST, requiredPassword))
+		router.POST("/skynet/restore", api.requireScope(api.skynetRestoreHandlerPOST, ScopeSkynetUpload))
 		router.GET("/skynet/stats", api.skynetStatsHandlerGET)
-		router.GET("/skynet/skykey", RequirePassword(api.skykeyHandlerGET, requiredPassword))
-		router.POST("/skynet/addskykey", RequirePassword(api.skykeyAddKeyHandlerPOST, requiredPassword))
-		router.POST("/skynet/createskykey", RequirePassword(api.skykeyCreateKeyHandlerPOST, requiredPassword))
-		router.POST("/skynet/deleteskykey", RequirePassword(api.skykeyDeleteHandlerPOST, requiredPassword))
-		router.GET("/skynet/skykeys", RequirePassword(api.skykeysHandlerGET, requiredPassword))
+		router.GET("/skynet/skykey", api.requireScope(api.skykeyHandlerGET, ScopeSkynetAdmin))
+		router.POST("/skynet/addskykey", api.requireScope(api.skykeyAddKeyHandlerPOST, ScopeSkynetAdmin))
+		router.POST("/skynet/createskykey", api.requireScope(api.skykeyCreateKeyHandlerPOST, ScopeSkynetAdmin))
+		router.POST("/skynet/deleteskykey", api.requireScope(api.skykeyDeleteHandlerPOST, ScopeSkynetAdmin))
+		router.POST("/skynet/skykey/rotate", api.requireScope(api.skykeyRotateHandlerPOST, ScopeSkynetAdmin))
+		router.GET("/skynet/skykey/rotation", api.requireScope(api.skykeyRotationHandlerGET, ScopeSkynetAdmin))
+		router.GET("/skynet/skykey/share", api.requireScope(api.skykeySharePublicKeyHandlerGET, ScopeSkynetAdmin))
+		router.POST("/skynet/skykey/share", api.requireScope(api.skykeyShareHandlerPOST, ScopeSkynetAdmin))
+		router.POST("/skynet/skykey/share/import", api.requireScope(api.skykeyShareImportHandlerPOST, ScopeSkynetAdmin))
+		router.GET("/skynet/skykeys", api.requireScope(api.skykeysHandlerGET, ScopeSkynetAdmin))
 
 		// Directory endpoints
-		router.POST("/renter/dir/*uplopath", RequirePassword(api.renterDirHandlerPOST, requiredPassword))
+		router.POST("/renter/dir/*uplopath", api.requireScope(api.renterDirHandlerPOST, ScopeRenterWrite))
 		router.GET("/renter/dir/*uplopath", api.renterDirHandlerGET)
 
 		// HostDB endpoints.
@@ -184,16 +207,16 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/hostdb/all", api.hostdbAllHandler)
 		router.GET("/hostdb/hosts/:pubkey", api.hostdbHostsHandler)
 		router.GET("/hostdb/filtermode", api.hostdbFilterModeHandlerGET)
-		router.POST("/hostdb/filtermode", RequirePassword(api.hostdbFilterModeHandlerPOST, requiredPassword))
+		router.POST("/hostdb/filtermode", api.requireScope(api.hostdbFilterModeHandlerPOST, ScopeRenterAdmin))
 
 		// Renter watchdog endpoints.
 		router.GET("/renter/contractstatus", api.renterContractStatusHandler)
 
 		// Deprecated endpoints.
-		router.POST("/renter/backup", RequirePassword(api.renterBackupHandlerPOST, requiredPassword))
-		router.POST("/renter/recoverbackup", RequirePassword(api.renterLoadBackupHandlerPOST, requiredPassword))
+		router.POST("/renter/backup", api.requireScope(api.renterBackupHandlerPOST, ScopeRenterWrite))
+		router.POST("/renter/recoverbackup", api.requireScope(api.renterLoadBackupHandlerPOST, ScopeRenterWrite))
 		router.GET("/skynet/blacklist", api.skynetBlocklistHandlerGET)
-		router.POST("/skynet/blacklist", RequirePassword(api.skynetBlocklistHandlerPOST, requiredPassword))
+		router.POST("/skynet/blacklist", api.requireScope(api.skynetBlocklistHandlerPOST, ScopeSkynetAdmin))
 	}
 
 	// Transaction pool API Calls
@@ -208,33 +231,46 @@ func (api *API) buildHTTPRoutes() {
 	// Wallet API Calls
 	if api.wallet != nil {
 		router.GET("/wallet", api.walletHandler)
-		router.POST("/wallet/033x", RequirePassword(api.wallet033xHandler, requiredPassword))
-		router.GET("/wallet/address", RequirePassword(api.walletAddressHandler, requiredPassword))
+		router.POST("/wallet/033x", api.requireScope(api.wallet033xHandler, ScopeWalletAdmin))
+		router.GET("/wallet/accounts", api.requireScope(api.walletAccountsHandlerGET, ScopeWalletRead))
+		router.POST("/wallet/accounts", api.requireScope(api.walletAccountsHandlerPOST, ScopeWalletAdmin))
+		router.POST("/wallet/accounts/pin", api.requireScope(api.walletAccountsPinHandler, ScopeWalletAdmin))
+		router.GET("/wallet/address", api.requireScope(api.walletAddressHandler, ScopeWalletAdmin))
 		router.GET("/wallet/addresses", api.walletAddressesHandler)
 		router.GET("/wallet/seedaddrs", api.walletSeedAddressesHandler)
-		router.GET("/wallet/backup", RequirePassword(api.walletBackupHandler, requiredPassword))
-		router.POST("/wallet/init", RequirePassword(api.walletInitHandler, requiredPassword))
-		router.POST("/wallet/init/seed", RequirePassword(api.walletInitSeedHandler, requiredPassword))
-		router.POST("/wallet/lock", RequirePassword(api.walletLockHandler, requiredPassword))
-		router.POST("/wallet/seed", RequirePassword(api.walletSeedHandler, requiredPassword))
-		router.GET("/wallet/seeds", RequirePassword(api.walletSeedsHandler, requiredPassword))
-		router.POST("/wallet/Uplocoins", RequirePassword(api.walletUplocoinsHandler, requiredPassword))
-		router.POST("/wallet/uplofunds", RequirePassword(api.walletUplofundsHandler, requiredPassword))
-		router.POST("/wallet/uplogkey", RequirePassword(api.walletUplogkeyHandler, requiredPassword))
-		router.POST("/wallet/sweep/seed", RequirePassword(api.walletSweepSeedHandler, requiredPassword))
+		router.GET("/wallet/backup", api.requireScope(api.walletBackupHandler, ScopeWalletAdmin))
+		router.POST("/wallet/bump/:txid", api.requireScope(api.walletBumpHandler, ScopeWalletAdmin))
+		router.GET("/wallet/export", api.requireScope(api.walletExportHandler, ScopeWalletRead))
+		router.POST("/wallet/init", api.requireScope(api.walletInitHandler, ScopeWalletAdmin))
+		router.POST("/wallet/init/seed", api.requireScope(api.walletInitSeedHandler, ScopeWalletAdmin))
+		router.POST("/wallet/lock", api.requireScope(api.walletLockHandler, ScopeWalletAdmin))
+		router.POST("/wallet/seed", api.requireScope(api.walletSeedHandler, ScopeWalletAdmin))
+		router.GET("/wallet/seeds", api.requireScope(api.walletSeedsHandler, ScopeWalletAdmin))
+		router.POST("/wallet/Uplocoins", api.requireScope(api.walletUplocoinsHandler, ScopeWalletSend))
+		router.POST("/wallet/Uplocoins/timelocked", api.requireScope(api.walletUplocoinsTimelockedHandler, ScopeWalletSend))
+		router.GET("/wallet/scheduledpayments", api.requireScope(api.walletScheduledPaymentsHandlerGET, ScopeWalletRead))
+		router.POST("/wallet/scheduledpayments", api.requireScope(api.walletScheduledPaymentsHandlerPOST, ScopeWalletAdmin))
+		router.POST("/wallet/scheduledpayments/remove", api.requireScope(api.walletScheduledPaymentsRemoveHandlerPOST, ScopeWalletAdmin))
+		router.POST("/wallet/uplofunds", api.requireScope(api.walletUplofundsHandler, ScopeWalletAdmin))
+		router.POST("/wallet/uplogkey", api.requireScope(api.walletUplogkeyHandler, ScopeWalletAdmin))
+		router.POST("/wallet/sweep/seed", api.requireScope(api.walletSweepSeedHandler, ScopeWalletAdmin))
 		router.GET("/wallet/transaction/:id", api.walletTransactionHandler)
 		router.GET("/wallet/transactions", api.walletTransactionsHandler)
 		router.GET("/wallet/transactions/:addr", api.walletTransactionsAddrHandler)
 		router.GET("/wallet/verify/address/:addr", api.walletVerifyAddressHandler)
-		router.POST("/wallet/unlock", RequirePassword(api.walletUnlockHandler, requiredPassword))
-		router.POST("/wallet/changepassword", RequirePassword(api.walletChangePasswordHandler, requiredPassword))
-		router.GET("/wallet/verifypassword", RequirePassword(api.walletVerifyPasswordHandler, requiredPassword))
-		router.GET("/wallet/unlockconditions/:addr", RequirePassword(api.walletUnlockConditionsHandlerGET, requiredPassword))
-		router.POST("/wallet/unlockconditions", RequirePassword(api.walletUnlockConditionsHandlerPOST, requiredPassword))
-		router.GET("/wallet/unspent", RequirePassword(api.walletUnspentHandler, requiredPassword))
-		router.POST("/wallet/sign", RequirePassword(api.walletSignHandler, requiredPassword))
-		router.GET("/wallet/watch", RequirePassword(api.walletWatchHandlerGET, requiredPassword))
-		router.POST("/wallet/watch", RequirePassword(api.walletWatchHandlerPOST, requiredPassword))
+		router.POST("/wallet/unlock", api.requireScope(api.walletUnlockHandler, ScopeWalletAdmin))
+		router.POST("/wallet/changepassword", api.requireScope(api.walletChangePasswordHandler, ScopeWalletAdmin))
+		router.GET("/wallet/verifypassword", api.requireScope(api.walletVerifyPasswordHandler, ScopeWalletAdmin))
+		router.GET("/wallet/unlockconditions/:addr", api.requireScope(api.walletUnlockConditionsHandlerGET, ScopeWalletRead))
+		router.POST("/wallet/unlockconditions", api.requireScope(api.walletUnlockConditionsHandlerPOST, ScopeWalletAdmin))
+		router.GET("/wallet/unspent", api.requireScope(api.walletUnspentHandler, ScopeWalletRead))
+		router.POST("/wallet/unspent/freeze", api.requireScope(api.walletUnspentFreezeHandler, ScopeWalletAdmin))
+		router.POST("/wallet/unspent/unfreeze", api.requireScope(api.walletUnspentUnfreezeHandler, ScopeWalletAdmin))
+		router.GET("/wallet/labels", api.requireScope(api.walletLabelsHandlerGET, ScopeWalletRead))
+		router.POST("/wallet/labels", api.requireScope(api.walletLabelsHandlerPOST, ScopeWalletAdmin))
+		router.POST("/wallet/sign", api.requireScope(api.walletSignHandler, ScopeWalletAdmin))
+		router.GET("/wallet/watch", api.requireScope(api.walletWatchHandlerGET, ScopeWalletRead))
+		router.POST("/wallet/watch", api.requireScope(api.walletWatchHandlerPOST, ScopeWalletAdmin))
 	}
 
 	// Apply UserAgent middleware and return the Router
@@ -2,6 +2,7 @@ package api
 
 import (
 	"math/big"
+	"strings"
 
 	"errors"
 
@@ -38,6 +39,23 @@ func scanHash(s string) (h crypto.Hash, err error) {
 	return h, nil
 }
 
+// scanOutputIDs scans a comma separated list of types.UplocoinOutputIDs from a
+// string.
+func scanOutputIDs(s string) ([]types.UplocoinOutputID, error) {
+	var ids []types.UplocoinOutputID
+	for _, idStr := range strings.Split(s, ",") {
+		if idStr = strings.TrimSpace(idStr); idStr == "" {
+			continue
+		}
+		h, err := scanHash(idStr)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, types.UplocoinOutputID(h))
+	}
+	return ids, nil
+}
+
 // scanBool converts "true" and "false" strings to their respective
 // boolean value and returns an error if conversion is not possible.
 func scanBool(param string) (bool, error) {
@@ -4,6 +4,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -67,6 +68,8 @@ func (srv *Server) Close() error {
 	if !errors.Contains(srv.serveErr, http.ErrServerClosed) {
 		err = errors.Compose(err, srv.serveErr)
 	}
+	// Stop the background threads of the API.
+	err = errors.Compose(err, srv.api.Close())
 	// Shutdown modules.
 	if srv.node != nil {
 		err = errors.Compose(err, srv.node.Close())
@@ -154,8 +157,9 @@ func (srv *Server) Unlock(password string) error {
 // require authentication using HTTP basic auth if the supplied password is not
 // the empty string. Usernames are ignored for authentication. This type of
 // authentication sends passwords in plaintext and should therefore only be
-// used if the APIaddr is localhost.
-func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, <-chan error) {
+// used if the APIaddr is localhost or the API is served over TLS. The API is
+// served over TLS if tlsCfg is not nil.
+func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string, tlsCfg *TLSConfig, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, <-chan error) {
 	c := make(chan error, 1)
 	defer close(c)
 
@@ -167,6 +171,13 @@ func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string,
 		if err != nil {
 			return nil, err
 		}
+		if tlsCfg != nil {
+			tlsConfig, err := tlsCfg.load(APIaddr)
+			if err != nil {
+				return nil, errors.Compose(errors.AddContext(err, "failed to load TLS config"), listener.Close())
+			}
+			listener = tls.NewListener(listener, tlsConfig)
+		}
 
 		// Load the config file.
 		cfg, err := modules.NewConfig(filepath.Join(nodeParams.Dir, modules.ConfigName))
@@ -175,7 +186,10 @@ func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string,
 		}
 
 		// Create the api for the server.
-		api := api.New(cfg, requiredUserAgent, requiredPassword, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		api, err := api.New(cfg, requiredUserAgent, requiredPassword, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to create api")
+		}
 		srv := &Server{
 			api: api,
 			apiServer: &http.Server{
@@ -253,10 +267,11 @@ func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string,
 // require authentication using HTTP basic auth if the supplied password is not
 // the empty string. Usernames are ignored for authentication. This type of
 // authentication sends passwords in plaintext and should therefore only be
-// used if the APIaddr is localhost.
-func New(APIaddr string, requiredUserAgent string, requiredPassword string, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, error) {
+// used if the APIaddr is localhost or the API is served over TLS. The API is
+// served over TLS if tlsCfg is not nil.
+func New(APIaddr string, requiredUserAgent string, requiredPassword string, tlsCfg *TLSConfig, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, error) {
 	// Wait for the node to be done loading.
-	srv, errChan := NewAsync(APIaddr, requiredUserAgent, requiredPassword, nodeParams, loadStartTime)
+	srv, errChan := NewAsync(APIaddr, requiredUserAgent, requiredPassword, tlsCfg, nodeParams, loadStartTime)
 	if err := <-errChan; err != nil {
 		// Error occurred during async load. Close all modules.
 		if build.Release == "standard" {
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/fastrand"
+)
+
+const (
+	// selfSignedCertValidity is the duration for which a generated self-signed
+	// certificate is valid.
+	selfSignedCertValidity = 10 * 365 * 24 * time.Hour
+)
+
+// TLSConfig configures the server to serve the API over TLS. If ClientCAFile
+// is set, clients are required to present a certificate signed by one of the
+// CAs in the file. The common name of a client certificate identifies the API
+// token whose scopes the client is granted.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+
+	// GenerateCert generates a self-signed certificate and key at CertFile
+	// and KeyFile if neither of them exists yet.
+	GenerateCert bool
+}
+
+// fileExists returns true if a file exists at path.
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// generateSelfSignedCert generates a self-signed certificate for the API
+// address and writes it and its key to certFile and keyFile in PEM format. The
+// certificate is a leaf which can only authenticate the server. Clients trust
+// it by adding it to their root pool, but it can't sign other certificates.
+func generateSelfSignedCert(apiAddr, certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return errors.AddContext(err, "failed to generate key")
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          new(big.Int).SetBytes(fastrand.Bytes(16)),
+		Subject:               pkix.Name{Organization: []string{"Uplo"}, CommonName: "uplod"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	// Add the host of the API address and the hostname of the machine.
+	hosts := make([]string, 0, 2)
+	if host, _, err := net.SplitHostPort(apiAddr); err == nil && host != "" {
+		hosts = append(hosts, host)
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "localhost" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return errors.AddContext(err, "failed to create certificate")
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return errors.AddContext(err, "failed to marshal key")
+	}
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		return errors.AddContext(err, "failed to write key")
+	}
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	return errors.AddContext(err, "failed to write certificate")
+}
+
+// load creates the tls.Config of the API server, generating a self-signed
+// certificate first if necessary.
+func (cfg TLSConfig) load(apiAddr string) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("TLS requires a certificate and a key")
+	}
+	if cfg.GenerateCert {
+		certExists, err1 := fileExists(cfg.CertFile)
+		keyExists, err2 := fileExists(cfg.KeyFile)
+		if err := errors.Compose(err1, err2); err != nil {
+			return nil, err
+		}
+		if !certExists && !keyExists {
+			if err := generateSelfSignedCert(apiAddr, cfg.CertFile, cfg.KeyFile); err != nil {
+				return nil, errors.AddContext(err, "failed to generate self-signed certificate")
+			}
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to load TLS certificate")
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile != "" {
+		pemCerts, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to read client CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemCerts) {
+			return nil, errors.New("client CA file doesn't contain any certificates")
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
@@ -59,6 +59,7 @@ func (srv *Server) panicClose() {
 func (srv *Server) Close() error {
 	err := srv.listener.Close()
 	err = errors.Extend(err, srv.tg.Stop())
+	err = errors.Extend(err, srv.api.Close())
 
 	// Safely close each module.
 	mods := []struct {
@@ -129,7 +130,10 @@ func NewCustomServer(dir string, APIaddr string, requiredUserAgent string, requi
 		return nil, errors.AddContext(err, "failed to load uplod config")
 	}
 
-	api := NewCustom(cfg, requiredUserAgent, requiredPassword, cs, e, fm, g, h, m, r, tp, w, apiDeps)
+	api, err := NewCustom(cfg, requiredUserAgent, requiredPassword, cs, e, fm, g, h, m, r, tp, w, apiDeps)
+	if err != nil {
+		return nil, err
+	}
 	srv := &Server{
 		api: api,
 		apiServer: &http.Server{
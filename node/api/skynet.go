@@ -135,6 +135,17 @@ type (
 		Skykeys []SkykeyGET `json:"skykeys"`
 	}
 
+	// SkykeySharePublicKeyGET contains the base64 encoded X25519 public key
+	// other users can share skykeys with the renter for.
+	SkykeySharePublicKeyGET struct {
+		PublicKey string `json:"publickey"`
+	}
+
+	// SkykeySharePOST contains a skykey wrapped for a single recipient.
+	SkykeySharePOST struct {
+		Share string `json:"share"`
+	}
+
 	// RegistryHandlerGET is the response returned by the registryHandlerGET
 	// handler.
 	RegistryHandlerGET struct {
@@ -979,6 +990,9 @@ func (api *API) skynetSkyfileHandlerPOST(w http.ResponseWriter, req *http.Reques
 		DefaultPath:        params.defaultPath,
 		DisableDefaultPath: params.disableDefaultPath,
 
+		// Set the HLS segmentation params
+		HLSSegmentDuration: params.hlsSegmentDuration,
+
 		// Set encryption key details
 		SkykeyName: params.skyKeyName,
 		SkykeyID:   params.skyKeyID,
@@ -988,6 +1002,25 @@ func (api *API) skynetSkyfileHandlerPOST(w http.ResponseWriter, req *http.Reques
 	var reader modules.SkyfileUploadReader
 	if isMultipartRequest(headers.mediaType) {
 		reader, err = modules.NewSkyfileMultipartReaderFromRequest(req, sup)
+	} else if sup.HLSSegmentDuration != 0 {
+		var hlsReader *modules.SkyfileHLSReader
+		hlsReader, err = modules.NewSkyfileHLSReader(req.Body, sup.HLSSegmentDuration, sup)
+		if errors.Contains(err, modules.ErrHLSUnsupportedContainer) {
+			WriteError(w, Error{fmt.Sprintf("unable to create HLS reader: %v", err)}, http.StatusUnsupportedMediaType)
+			return
+		}
+		if errors.Contains(err, modules.ErrHLSUploadTooLarge) {
+			WriteError(w, Error{fmt.Sprintf("unable to create HLS reader: %v", err)}, http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			WriteError(w, Error{fmt.Sprintf("unable to create HLS reader: %v", err)}, http.StatusBadRequest)
+			return
+		}
+		defer func() {
+			_ = hlsReader.Close()
+		}()
+		reader = hlsReader
 	} else {
 		reader = modules.NewSkyfileReader(req.Body, sup)
 	}
@@ -1333,6 +1366,178 @@ func (api *API) skykeysHandlerGET(w http.ResponseWriter, _ *http.Request, _ http
 	WriteJSON(w, res)
 }
 
+// skykeyRotateHandlerPOST handles the API call to rotate a skykey using its
+// name or ID. The skyfiles are re-encrypted in the background, so the call
+// returns right away.
+func (api *API) skykeyRotateHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse Skykey id and name.
+	name := req.FormValue("name")
+	idString := req.FormValue("id")
+	newName := req.FormValue("newname")
+
+	if idString == "" && name == "" {
+		WriteError(w, Error{"you must specify the name or ID of the skykey"}, http.StatusBadRequest)
+		return
+	}
+	if idString != "" && name != "" {
+		WriteError(w, Error{"you must specify either the name or ID of the skykey, not both"}, http.StatusBadRequest)
+		return
+	}
+	if newName == "" {
+		WriteError(w, Error{"you must specify the name of the new skykey"}, http.StatusBadRequest)
+		return
+	}
+	var blocklist bool
+	if b := req.FormValue("blocklist"); b != "" {
+		var err error
+		blocklist, err = strconv.ParseBool(b)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'blocklist' arg: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var id skykey.SkykeyID
+	var err error
+	if name != "" {
+		id, err = api.renter.SkykeyIDByName(name)
+		if err != nil {
+			WriteError(w, Error{"failed to retrieve skykey: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	} else {
+		err = id.FromString(idString)
+		if err != nil {
+			WriteError(w, Error{"Invalid skykey ID: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rotation, err := api.renter.RotateSkykey(id, newName, blocklist)
+	if err != nil {
+		WriteError(w, Error{"failed to rotate skykey: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, rotation)
+}
+
+// skykeyRotationHandlerGET handles the API call to get the state of the
+// rotation to the skykey with the given ID.
+func (api *API) skykeyRotationHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var id skykey.SkykeyID
+	err := id.FromString(req.FormValue("id"))
+	if err != nil {
+		WriteError(w, Error{"Invalid skykey ID: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	rotation, err := api.renter.SkykeyRotation(id)
+	if err != nil {
+		WriteError(w, Error{"failed to get skykey rotation: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, rotation)
+}
+
+// skykeySharePublicKeyHandlerGET handles the API call to get the public key
+// other users can use to share skykeys with the renter.
+func (api *API) skykeySharePublicKeyHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	xpk, err := api.renter.SkykeySharePublicKey()
+	if err != nil {
+		WriteError(w, Error{"failed to get skykey sharing public key: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, SkykeySharePublicKeyGET{
+		PublicKey: skykey.SharePublicKeyToString(xpk),
+	})
+}
+
+// skykeyShareHandlerPOST handles the API call to wrap a skykey for the owner
+// of an X25519 public key using the skykey's name or ID.
+func (api *API) skykeyShareHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse Skykey id and name.
+	name := req.FormValue("name")
+	idString := req.FormValue("id")
+	pkString := req.FormValue("publickey")
+
+	if idString == "" && name == "" {
+		WriteError(w, Error{"you must specify the name or ID of the skykey"}, http.StatusBadRequest)
+		return
+	}
+	if idString != "" && name != "" {
+		WriteError(w, Error{"you must specify either the name or ID of the skykey, not both"}, http.StatusBadRequest)
+		return
+	}
+	if pkString == "" {
+		WriteError(w, Error{"you must specify the public key of the recipient"}, http.StatusBadRequest)
+		return
+	}
+	xpk, err := skykey.SharePublicKeyFromString(pkString)
+	if err != nil {
+		WriteError(w, Error{"Invalid public key: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	var id skykey.SkykeyID
+	if name != "" {
+		id, err = api.renter.SkykeyIDByName(name)
+		if err != nil {
+			WriteError(w, Error{"failed to retrieve skykey: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	} else {
+		err = id.FromString(idString)
+		if err != nil {
+			WriteError(w, Error{"Invalid skykey ID: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ss, err := api.renter.ShareSkykey(id, xpk)
+	if err != nil {
+		WriteError(w, Error{"failed to share skykey: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, SkykeySharePOST{
+		Share: ss.ToString(),
+	})
+}
+
+// skykeyShareImportHandlerPOST handles the API call to import a skykey that
+// was shared with the renter's public key.
+func (api *API) skykeyShareImportHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	shareString := req.FormValue("share")
+	name := req.FormValue("name")
+	if shareString == "" {
+		WriteError(w, Error{"you must specify the skykey share"}, http.StatusBadRequest)
+		return
+	}
+
+	var ss skykey.SkykeyShare
+	err := ss.FromString(shareString)
+	if err != nil {
+		WriteError(w, Error{"failed to decode skykey share: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	sk, err := api.renter.ImportSharedSkykey(ss, name)
+	if err != nil {
+		WriteError(w, Error{"failed to import shared skykey: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	skString, err := sk.ToString()
+	if err != nil {
+		WriteError(w, Error{"failed to decode skykey: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, SkykeyGET{
+		Skykey: skString,
+		Name:   sk.Name,
+		ID:     sk.ID().ToString(),
+		Type:   sk.Type.ToString(),
+	})
+}
+
 // registryHandlerPOST handles the POST calls to /skynet/registry.
 func (api *API) registryHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	startTime := time.Now()
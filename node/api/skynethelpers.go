@@ -33,6 +33,7 @@ type (
 		dryRun              bool
 		filename            string
 		force               bool
+		hlsSegmentDuration  time.Duration
 		mode                os.FileMode
 		root                bool
 		uploPath             modules.UploPath
@@ -187,6 +188,20 @@ func parseUploadHeadersAndRequestParameters(req *http.Request, ps httprouter.Par
 		}
 	}
 
+	// parse 'hlssegmentduration' query parameter
+	var hlsSegmentDuration time.Duration
+	hlsStr := queryForm.Get("hlssegmentduration")
+	if hlsStr != "" {
+		hlsSeconds, err := strconv.ParseUint(hlsStr, 10, 64)
+		if err != nil {
+			return nil, nil, errors.AddContext(err, "unable to parse 'hlssegmentduration' parameter")
+		}
+		if hlsSeconds == 0 {
+			return nil, nil, errors.New("'hlssegmentduration' has to be greater than zero")
+		}
+		hlsSegmentDuration = time.Duration(hlsSeconds) * time.Second
+	}
+
 	// parse 'mode' query parameter
 	modeStr := queryForm.Get("mode")
 	var mode os.FileMode
@@ -254,6 +269,11 @@ func parseUploadHeadersAndRequestParameters(req *http.Request, ps httprouter.Par
 		return nil, nil, errors.New("DefaultPath and DisableDefaultPath can only be set on multipart uploads")
 	}
 
+	// verify HLS segmentation is only requested for regular uploads
+	if hlsSegmentDuration != 0 && (isMultipartRequest(mediaType) || convertPath != "") {
+		return nil, nil, errors.New("'hlssegmentduration' can only be set on regular uploads")
+	}
+
 	// verify convertpath and filename are not combined
 	if convertPath != "" && filename != "" {
 		return nil, nil, errors.New("cannot set both a 'convertpath' and a 'filename'")
@@ -277,6 +297,7 @@ func parseUploadHeadersAndRequestParameters(req *http.Request, ps httprouter.Par
 		dryRun:              dryRun,
 		filename:            filename,
 		force:               force,
+		hlsSegmentDuration:  hlsSegmentDuration,
 		mode:                mode,
 		root:                root,
 		uploPath:             uploPath,
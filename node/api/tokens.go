@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/fastrand"
+
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/persist"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// The scopes of API tokens. Every password protected route belongs to exactly
+// one scope.
+const (
+	ScopeConsensusAdmin  = "consensus:admin"
+	ScopeDaemonAdmin     = "daemon:admin"
+	ScopeDaemonEvents    = "daemon:events"
+	ScopeFeeManagerAdmin = "feemanager:admin"
+	ScopeGatewayAdmin    = "gateway:admin"
+	ScopeHostAdmin       = "host:admin"
+	ScopeMinerAdmin      = "miner:admin"
+	ScopeRenterAdmin     = "renter:admin"
+	ScopeRenterRead      = "renter:read"
+	ScopeRenterWrite     = "renter:write"
+	ScopeSkynetAdmin     = "skynet:admin"
+	ScopeSkynetUpload    = "skynet:upload"
+	ScopeWalletAdmin     = "wallet:admin"
+	ScopeWalletRead      = "wallet:read"
+	ScopeWalletSend      = "wallet:send"
+)
+
+const (
+	// apiTokensFilename is the name of the file in the uplod directory which
+	// contains the API tokens.
+	apiTokensFilename = "apitokens.json"
+
+	// apiTokenSize is the number of random bytes of an API token.
+	apiTokenSize = 32
+)
+
+var (
+	// apiTokensMetadata is the metadata of the persisted API tokens.
+	apiTokensMetadata = persist.Metadata{
+		Header:  "API Tokens",
+		Version: "1.0.0",
+	}
+
+	// TokenScopes are all scopes that can be granted to an API token.
+	TokenScopes = []string{
+		ScopeConsensusAdmin,
+		ScopeDaemonAdmin,
+		ScopeDaemonEvents,
+		ScopeFeeManagerAdmin,
+		ScopeGatewayAdmin,
+		ScopeHostAdmin,
+		ScopeMinerAdmin,
+		ScopeRenterAdmin,
+		ScopeRenterRead,
+		ScopeRenterWrite,
+		ScopeSkynetAdmin,
+		ScopeSkynetUpload,
+		ScopeWalletAdmin,
+		ScopeWalletRead,
+		ScopeWalletSend,
+	}
+
+	// errSpendCapExceeded is returned when a payment would exceed the spend
+	// cap of the API token of a request.
+	errSpendCapExceeded = errors.New("payment exceeds the spend cap of the API token")
+
+	// errTokenExpired is returned when authenticating with an expired API
+	// token.
+	errTokenExpired = errors.New("API token has expired")
+
+	// errTokenNotFound is returned when a token doesn't exist.
+	errTokenNotFound = errors.New("API token not found")
+)
+
+type (
+	// APIToken describes a named API token. The token grants access to the
+	// password protected routes of its scopes until it expires. A zero Expiry
+	// means that the token never expires. A non-zero SpendCap limits the
+	// Uplocoins that can be sent using the token.
+	APIToken struct {
+		Name     string         `json:"name"`
+		Scopes   []string       `json:"scopes"`
+		Created  time.Time      `json:"created"`
+		Expiry   time.Time      `json:"expiry"`
+		SpendCap types.Currency `json:"spendcap"`
+		Spent    types.Currency `json:"spent"`
+	}
+
+	// persistedAPIToken is an API token together with the hash of its
+	// secret. The secret itself is never stored.
+	persistedAPIToken struct {
+		APIToken
+		Hash crypto.Hash `json:"hash"`
+	}
+
+	// apiTokens manages the API tokens of the daemon.
+	apiTokens struct {
+		tokens []*persistedAPIToken
+		path   string
+		mu     sync.Mutex
+	}
+
+	// apiTokenContextKey is the key of the name of the API token that
+	// authenticated a request in the context of the request.
+	apiTokenContextKey struct{}
+)
+
+// newAPITokens loads the API tokens from the file at path. If the file doesn't
+// exist yet, there are no tokens.
+func newAPITokens(path string) (*apiTokens, error) {
+	at := &apiTokens{
+		path: path,
+	}
+	err := persist.LoadJSON(apiTokensMetadata, &at.tokens, path)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return at, err
+}
+
+// validScope returns true if scope is a known scope.
+func validScope(scope string) bool {
+	for _, s := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hasScope returns true if the token was granted the scope.
+func (t APIToken) hasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// expired returns true if the token has an expiry which has passed.
+func (t APIToken) expired() bool {
+	return !t.Expiry.IsZero() && time.Now().After(t.Expiry)
+}
+
+// save persists the tokens.
+func (at *apiTokens) save() error {
+	return persist.SaveJSON(apiTokensMetadata, at.tokens, at.path)
+}
+
+// token returns the token with the given name.
+func (at *apiTokens) token(name string) (*persistedAPIToken, bool) {
+	for _, t := range at.tokens {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// managedAdd creates a new API token and returns its secret. The secret can't
+// be retrieved again.
+func (at *apiTokens) managedAdd(name string, scopes []string, expiry time.Time, spendCap types.Currency) (string, error) {
+	if name == "" {
+		return "", errors.New("API token needs a name")
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("API token needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return "", errors.New("unknown scope " + scope)
+		}
+	}
+	if !expiry.IsZero() && expiry.Before(time.Now()) {
+		return "", errors.New("expiry is in the past")
+	}
+
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if _, exists := at.token(name); exists {
+		return "", errors.New("API token with that name already exists")
+	}
+	secret := hex.EncodeToString(fastrand.Bytes(apiTokenSize))
+	at.tokens = append(at.tokens, &persistedAPIToken{
+		APIToken: APIToken{
+			Name:     name,
+			Scopes:   scopes,
+			Created:  time.Now(),
+			Expiry:   expiry,
+			SpendCap: spendCap,
+		},
+		Hash: crypto.HashBytes([]byte(secret)),
+	})
+	if err := at.save(); err != nil {
+		at.tokens = at.tokens[:len(at.tokens)-1]
+		return "", errors.AddContext(err, "unable to persist API token")
+	}
+	return secret, nil
+}
+
+// managedRemove removes the API token with the given name.
+func (at *apiTokens) managedRemove(name string) error {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	for i, t := range at.tokens {
+		if t.Name != name {
+			continue
+		}
+		at.tokens = append(at.tokens[:i], at.tokens[i+1:]...)
+		return at.save()
+	}
+	return errTokenNotFound
+}
+
+// managedTokens returns all API tokens sorted by name.
+func (at *apiTokens) managedTokens() []APIToken {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	tokens := make([]APIToken, 0, len(at.tokens))
+	for _, t := range at.tokens {
+		tokens = append(tokens, t.APIToken)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens
+}
+
+// managedAuthenticate returns the token that matches the secret.
+func (at *apiTokens) managedAuthenticate(secret string) (APIToken, error) {
+	hash := crypto.HashBytes([]byte(secret))
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	for _, t := range at.tokens {
+		if t.Hash != hash {
+			continue
+		}
+		if t.expired() {
+			return APIToken{}, errTokenExpired
+		}
+		return t.APIToken, nil
+	}
+	return APIToken{}, errTokenNotFound
+}
+
+// managedAuthenticateName returns the token with the given name. It is used to
+// authenticate requests with a verified client certificate, whose common name
+// identifies the token.
+func (at *apiTokens) managedAuthenticateName(name string) (APIToken, error) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	t, exists := at.token(name)
+	if !exists {
+		return APIToken{}, errTokenNotFound
+	}
+	if t.expired() {
+		return APIToken{}, errTokenExpired
+	}
+	return t.APIToken, nil
+}
+
+// managedSpend adds amount to the spending of the token with the given name.
+// If the token has a spend cap, the spending can't exceed it unless force is
+// set, which is used to charge for fees after the fact.
+func (at *apiTokens) managedSpend(name string, amount types.Currency, force bool) error {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	t, exists := at.token(name)
+	if !exists {
+		return errTokenNotFound
+	}
+	spent := t.Spent.Add(amount)
+	if !force && !t.SpendCap.IsZero() && spent.Cmp(t.SpendCap) > 0 {
+		return errSpendCapExceeded
+	}
+	t.Spent = spent
+	return at.save()
+}
+
+// managedRefund subtracts amount from the spending of the token with the given
+// name after a payment failed.
+func (at *apiTokens) managedRefund(name string, amount types.Currency) error {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	t, exists := at.token(name)
+	if !exists {
+		return errTokenNotFound
+	}
+	if t.Spent.Cmp(amount) < 0 {
+		t.Spent = types.ZeroCurrency
+	} else {
+		t.Spent = t.Spent.Sub(amount)
+	}
+	return at.save()
+}
+
+// requestToken returns the name of the API token that authenticated the
+// request. It returns false if the request was authenticated with the API
+// password or no authentication is required.
+func requestToken(req *http.Request) (string, bool) {
+	name, ok := req.Context().Value(apiTokenContextKey{}).(string)
+	return name, ok
+}
+
+// clientCertName returns the common name of the verified client certificate
+// of a request. Client certificates are only verified if the API is served
+// with mutual TLS.
+func clientCertName(req *http.Request) (string, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	name := req.TLS.VerifiedChains[0][0].Subject.CommonName
+	return name, name != ""
+}
+
+// authenticateToken returns the API token of a request. A request either
+// provides the secret of the token as its password or, if the API is served
+// with mutual TLS, a verified client certificate whose common name is the name
+// of the token.
+func (api *API) authenticateToken(req *http.Request) (APIToken, error) {
+	if api.staticTokens == nil {
+		return APIToken{}, errTokenNotFound
+	}
+	err := errTokenNotFound
+	if _, pass, ok := req.BasicAuth(); ok {
+		var token APIToken
+		token, err = api.staticTokens.managedAuthenticate(pass)
+		if err == nil {
+			return token, nil
+		}
+	}
+	if name, ok := clientCertName(req); ok {
+		return api.staticTokens.managedAuthenticateName(name)
+	}
+	return APIToken{}, err
+}
+
+// requireScope is like RequirePassword but it additionally accepts API tokens
+// which were granted the provided scope in place of the password.
+func (api *API) requireScope(h httprouter.Handle, scope string) httprouter.Handle {
+	// An empty password is equivalent to no password.
+	if api.requiredPassword == "" {
+		return h
+	}
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		_, pass, ok := req.BasicAuth()
+		if ok && pass == api.requiredPassword {
+			h(w, req, ps)
+			return
+		}
+		token, err := api.authenticateToken(req)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Basic realm=\"UploAPI\"")
+			WriteError(w, Error{"API authentication failed."}, http.StatusUnauthorized)
+			return
+		}
+		if !token.hasScope(scope) {
+			WriteError(w, Error{"API token '" + token.Name + "' doesn't have the " + scope + " scope"}, http.StatusForbidden)
+			return
+		}
+		h(w, req.WithContext(context.WithValue(req.Context(), apiTokenContextKey{}, token.Name)), ps)
+	}
+}
+
+// parseScopes splits a comma separated list of scopes.
+func parseScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// chargeToken charges a payment to the API token which authenticated the
+// request, if any. The returned function refunds the payment if it fails.
+func (api *API) chargeToken(req *http.Request, amount types.Currency) (refund func(), err error) {
+	name, ok := requestToken(req)
+	if !ok {
+		return func() {}, nil
+	}
+	if err := api.staticTokens.managedSpend(name, amount, false); err != nil {
+		return nil, err
+	}
+	return func() {
+		_ = api.staticTokens.managedRefund(name, amount)
+	}, nil
+}
+
+// chargeTokenFees charges the fees of a payment to the API token which
+// authenticated the request, if any. Fees are only known after the payment
+// was made, so they are charged even if they exceed the spend cap.
+func (api *API) chargeTokenFees(req *http.Request, fees types.Currency) {
+	if name, ok := requestToken(req); ok {
+		_ = api.staticTokens.managedSpend(name, fees, true)
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/types"
+)
+
+// TestAPITokens probes creating, authenticating, charging and persisting API
+// tokens.
+func TestAPITokens(t *testing.T) {
+	dir := build.TempDir("api", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, apiTokensFilename)
+	at, err := newAPITokens(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid tokens should be rejected.
+	if _, err := at.managedAdd("", []string{ScopeWalletSend}, time.Time{}, types.ZeroCurrency); err == nil {
+		t.Fatal("token without name was created")
+	}
+	if _, err := at.managedAdd("foo", []string{"wallet:everything"}, time.Time{}, types.ZeroCurrency); err == nil {
+		t.Fatal("token with unknown scope was created")
+	}
+	if _, err := at.managedAdd("foo", []string{ScopeWalletSend}, time.Now().Add(-time.Hour), types.ZeroCurrency); err == nil {
+		t.Fatal("expired token was created")
+	}
+
+	// Create a token with a spend cap.
+	secret, err := at.managedAdd("foo", []string{ScopeWalletSend}, time.Time{}, types.NewCurrency64(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := at.managedAdd("foo", []string{ScopeWalletRead}, time.Time{}, types.ZeroCurrency); err == nil {
+		t.Fatal("token with duplicate name was created")
+	}
+	token, err := at.managedAuthenticate(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Name != "foo" || !token.hasScope(ScopeWalletSend) || token.hasScope(ScopeWalletAdmin) {
+		t.Fatal("wrong token", token)
+	}
+	if _, err := at.managedAuthenticate("wrong"); !errors.Contains(err, errTokenNotFound) {
+		t.Fatal("expected errTokenNotFound", err)
+	}
+
+	// Spending is limited by the cap, apart from fees.
+	if err := at.managedSpend("foo", types.NewCurrency64(60), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := at.managedSpend("foo", types.NewCurrency64(60), false); !errors.Contains(err, errSpendCapExceeded) {
+		t.Fatal("expected errSpendCapExceeded", err)
+	}
+	if err := at.managedRefund("foo", types.NewCurrency64(20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := at.managedSpend("foo", types.NewCurrency64(60), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := at.managedSpend("foo", types.NewCurrency64(1), true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tokens should be persisted without their secrets.
+	at2, err := newAPITokens(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := at2.managedTokens()
+	if len(tokens) != 1 || !tokens[0].Spent.Equals64(101) {
+		t.Fatal("tokens weren't persisted", tokens)
+	}
+	if _, err := at2.managedAuthenticate(secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := at2.managedRemove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := at2.managedRemove("foo"); !errors.Contains(err, errTokenNotFound) {
+		t.Fatal("expected errTokenNotFound", err)
+	}
+	if _, err := at2.managedAuthenticate(secret); err == nil {
+		t.Fatal("removed token was accepted")
+	}
+}
+
+// TestRequireScope checks that routes accept the API password and tokens with
+// the scope of the route, either by their secret or by a client certificate.
+func TestRequireScope(t *testing.T) {
+	dir := build.TempDir("api", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	at, err := newAPITokens(filepath.Join(dir, apiTokensFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &API{
+		requiredPassword: "password",
+		staticTokens:     at,
+	}
+	sendSecret, err := at.managedAdd("send", []string{ScopeWalletSend}, time.Time{}, types.ZeroCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readSecret, err := at.managedAdd("read", []string{ScopeWalletRead}, time.Time{}, types.ZeroCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokenName string
+	h := api.requireScope(func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		tokenName, _ = requestToken(req)
+		WriteSuccess(w)
+	}, ScopeWalletSend)
+	tests := []struct {
+		password string
+		cert     string
+		code     int
+		token    string
+	}{
+		{"password", "", http.StatusNoContent, ""},
+		{sendSecret, "", http.StatusNoContent, "send"},
+		{readSecret, "", http.StatusForbidden, ""},
+		{"wrong", "", http.StatusUnauthorized, ""},
+		{"", "send", http.StatusNoContent, "send"},
+		{"wrong", "send", http.StatusNoContent, "send"},
+		{"", "read", http.StatusForbidden, ""},
+		{"", "unknown", http.StatusUnauthorized, ""},
+	}
+	for _, test := range tests {
+		tokenName = ""
+		req := httptest.NewRequest("POST", "/wallet/Uplocoins", nil)
+		if test.password != "" {
+			req.SetBasicAuth("", test.password)
+		}
+		if test.cert != "" {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: test.cert}}
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		w := httptest.NewRecorder()
+		h(w, req, nil)
+		if w.Code != test.code || tokenName != test.token {
+			t.Errorf("password %q, cert %q: expected %v and token %q, got %v and token %q", test.password, test.cert, test.code, test.token, w.Code, tokenName)
+		}
+	}
+}
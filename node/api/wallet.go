@@ -1,13 +1,17 @@
 package api
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	mnemonics "github.com/uplo-tech/entropy-mnemonics"
@@ -36,6 +40,18 @@ type (
 		DustThreshold types.Currency `json:"dustthreshold"`
 	}
 
+	// WalletAccountsGET contains the accounts of the wallet and their
+	// balances.
+	WalletAccountsGET struct {
+		Accounts []modules.WalletAccount `json:"accounts"`
+	}
+
+	// WalletExportGET contains the entries of the wallet history export
+	// returned by a GET call to /wallet/export.
+	WalletExportGET struct {
+		Entries []modules.WalletExportEntry `json:"entries"`
+	}
+
 	// WalletAddressGET contains an address returned by a GET call to
 	// /wallet/address.
 	WalletAddressGET struct {
@@ -48,6 +64,18 @@ type (
 		Addresses []types.UnlockHash `json:"addresses"`
 	}
 
+	// WalletBumpPOST contains the transactions submitted to bump the fees of
+	// a transaction in the POST call to /wallet/bump/:txid.
+	WalletBumpPOST struct {
+		Transactions   []types.Transaction   `json:"transactions"`
+		TransactionIDs []types.TransactionID `json:"transactionids"`
+	}
+
+	// WalletLabelsGET contains the labels of the wallet.
+	WalletLabelsGET struct {
+		Labels []modules.WalletLabel `json:"labels"`
+	}
+
 	// WalletInitPOST contains the primary seed that gets generated during a
 	// POST call to /wallet/init.
 	WalletInitPOST struct {
@@ -61,6 +89,22 @@ type (
 		TransactionIDs []types.TransactionID `json:"transactionids"`
 	}
 
+	// WalletUplocoinsTimelockedPOST contains the transaction sent in the POST
+	// call to /wallet/Uplocoins/timelocked and the time-locked address the
+	// coins were sent to.
+	WalletUplocoinsTimelockedPOST struct {
+		Transactions     []types.Transaction    `json:"transactions"`
+		TransactionIDs   []types.TransactionID  `json:"transactionids"`
+		Address          types.UnlockHash       `json:"address"`
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+	}
+
+	// WalletScheduledPaymentsGET contains the scheduled payments of the
+	// wallet.
+	WalletScheduledPaymentsGET struct {
+		ScheduledPayments []modules.ScheduledPayment `json:"scheduledpayments"`
+	}
+
 	// WalletUplofundsPOST contains the transaction sent in the POST call to
 	// /wallet/uplofunds.
 	WalletUplofundsPOST struct {
@@ -105,6 +149,7 @@ type (
 	WalletTransactionsGET struct {
 		ConfirmedTransactions   []modules.ProcessedTransaction `json:"confirmedtransactions"`
 		UnconfirmedTransactions []modules.ProcessedTransaction `json:"unconfirmedtransactions"`
+		Labels                  map[string]string              `json:"labels"`
 	}
 
 	// WalletTransactionsGETaddr contains the set of wallet transactions
@@ -113,6 +158,7 @@ type (
 	WalletTransactionsGETaddr struct {
 		ConfirmedTransactions   []modules.ProcessedTransaction `json:"confirmedtransactions"`
 		UnconfirmedTransactions []modules.ProcessedTransaction `json:"unconfirmedtransactions"`
+		Labels                  map[string]string              `json:"labels"`
 	}
 
 	// WalletUnlockConditionsGET contains a set of unlock conditions.
@@ -254,8 +300,18 @@ func (api *API) wallet033xHandler(w http.ResponseWriter, req *http.Request, _ ht
 }
 
 // walletAddressHandler handles API calls to /wallet/address.
-func (api *API) walletAddressHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-	unlockConditions, err := api.wallet.NextAddress()
+func (api *API) walletAddressHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var unlockConditions types.UnlockConditions
+	var err error
+	if account := req.FormValue("account"); account != "" {
+		var ucs []types.UnlockConditions
+		ucs, err = api.wallet.NextAccountAddresses(account, 1)
+		if err == nil {
+			unlockConditions = ucs[0]
+		}
+	} else {
+		unlockConditions, err = api.wallet.NextAddress()
+	}
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/addresses: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -493,6 +549,19 @@ func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ h
 
 // walletUplocoinsHandler handles API calls to /wallet/Uplocoins.
 func (api *API) walletUplocoinsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse the optional outputs of the wallet that should be spent.
+	inputs, err := scanOutputIDs(req.FormValue("inputs"))
+	if err != nil {
+		WriteError(w, Error{"could not read inputs from POST call to /wallet/Uplocoins: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Parse the optional account that funds the transaction.
+	account := req.FormValue("account")
+	if account != "" && len(inputs) > 0 {
+		WriteError(w, Error{"cannot supply both account and inputs parameter"}, http.StatusBadRequest)
+		return
+	}
+
 	var txns []types.Transaction
 	if req.FormValue("outputs") != "" {
 		// multiple amounts + destinations
@@ -507,8 +576,24 @@ func (api *API) walletUplocoinsHandler(w http.ResponseWriter, req *http.Request,
 			WriteError(w, Error{"could not decode outputs: " + err.Error()}, http.StatusInternalServerError)
 			return
 		}
-		txns, err = api.wallet.SendUplocoinsMulti(outputs)
+		var total types.Currency
+		for _, sco := range outputs {
+			total = total.Add(sco.Value)
+		}
+		refund, err := api.chargeToken(req, total)
 		if err != nil {
+			WriteError(w, Error{"error when calling /wallet/Uplocoins: " + err.Error()}, http.StatusForbidden)
+			return
+		}
+		if len(inputs) > 0 {
+			txns, err = api.wallet.SendUplocoinsMultiFromOutputs(outputs, inputs)
+		} else if account != "" {
+			txns, err = api.wallet.SendUplocoinsMultiFromAccount(account, outputs)
+		} else {
+			txns, err = api.wallet.SendUplocoinsMulti(outputs)
+		}
+		if err != nil {
+			refund()
 			WriteError(w, Error{"error when calling /wallet/Uplocoins: " + err.Error()}, http.StatusInternalServerError)
 			return
 		}
@@ -529,18 +614,45 @@ func (api *API) walletUplocoinsHandler(w http.ResponseWriter, req *http.Request,
 			WriteError(w, Error{"could not read feeIncluded from POST call to /wallet/Uplocoins"}, http.StatusBadRequest)
 			return
 		}
+		if feeIncluded && len(inputs) > 0 {
+			WriteError(w, Error{"cannot supply both inputs and feeIncluded parameter"}, http.StatusBadRequest)
+			return
+		}
+		if feeIncluded && account != "" {
+			WriteError(w, Error{"cannot supply both account and feeIncluded parameter"}, http.StatusBadRequest)
+			return
+		}
 
+		refund, err := api.chargeToken(req, amount)
+		if err != nil {
+			WriteError(w, Error{"error when calling /wallet/Uplocoins: " + err.Error()}, http.StatusForbidden)
+			return
+		}
 		if feeIncluded {
 			txns, err = api.wallet.SendUplocoinsFeeIncluded(amount, dest)
+		} else if len(inputs) > 0 {
+			txns, err = api.wallet.SendUplocoinsFromOutputs(amount, dest, inputs)
+		} else if account != "" {
+			txns, err = api.wallet.SendUplocoinsFromAccount(account, amount, dest)
 		} else {
 			txns, err = api.wallet.SendUplocoins(amount, dest)
 		}
 		if err != nil {
+			refund()
 			WriteError(w, Error{"error when calling /wallet/Uplocoins: " + err.Error()}, http.StatusInternalServerError)
 			return
 		}
 	}
 
+	// Charge the fees of the transactions to the API token after the fact.
+	var fees types.Currency
+	for _, txn := range txns {
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+	}
+	api.chargeTokenFees(req, fees)
+
 	var txids []types.TransactionID
 	for _, txn := range txns {
 		txids = append(txids, txn.ID())
@@ -551,6 +663,134 @@ func (api *API) walletUplocoinsHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// walletUplocoinsTimelockedHandler handles API calls to
+// /wallet/Uplocoins/timelocked.
+func (api *API) walletUplocoinsTimelockedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	amount, ok := scanAmount(req.FormValue("amount"))
+	if !ok {
+		WriteError(w, Error{"could not read amount from POST call to /wallet/Uplocoins/timelocked"}, http.StatusBadRequest)
+		return
+	}
+	dest, err := scanAddress(req.FormValue("destination"))
+	if err != nil {
+		WriteError(w, Error{"could not read address from POST call to /wallet/Uplocoins/timelocked"}, http.StatusBadRequest)
+		return
+	}
+	timelock, err := strconv.ParseUint(req.FormValue("timelock"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"could not read timelock from POST call to /wallet/Uplocoins/timelocked: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	refund, err := api.chargeToken(req, amount)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/Uplocoins/timelocked: " + err.Error()}, http.StatusForbidden)
+		return
+	}
+	txns, uc, err := api.wallet.SendUplocoinsTimelocked(amount, dest, types.BlockHeight(timelock))
+	if err != nil {
+		refund()
+		WriteError(w, Error{"error when calling /wallet/Uplocoins/timelocked: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	// Charge the fees of the transactions to the API token after the fact.
+	var fees types.Currency
+	var txids []types.TransactionID
+	for _, txn := range txns {
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+		txids = append(txids, txn.ID())
+	}
+	api.chargeTokenFees(req, fees)
+
+	WriteJSON(w, WalletUplocoinsTimelockedPOST{
+		Transactions:     txns,
+		TransactionIDs:   txids,
+		Address:          uc.UnlockHash(),
+		UnlockConditions: uc,
+	})
+}
+
+// walletScheduledPaymentsHandlerGET handles GET API calls to
+// /wallet/scheduledpayments.
+func (api *API) walletScheduledPaymentsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	sps, err := api.wallet.ScheduledPayments()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/scheduledpayments: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, WalletScheduledPaymentsGET{ScheduledPayments: sps})
+}
+
+// walletScheduledPaymentsHandlerPOST handles POST API calls to
+// /wallet/scheduledpayments.
+func (api *API) walletScheduledPaymentsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	amount, ok := scanAmount(req.FormValue("amount"))
+	if !ok {
+		WriteError(w, Error{"could not read amount from POST call to /wallet/scheduledpayments"}, http.StatusBadRequest)
+		return
+	}
+	dest, err := scanAddress(req.FormValue("destination"))
+	if err != nil {
+		WriteError(w, Error{"could not read address from POST call to /wallet/scheduledpayments"}, http.StatusBadRequest)
+		return
+	}
+	sp := modules.ScheduledPayment{
+		Name:        req.FormValue("name"),
+		Destination: dest,
+		Amount:      amount,
+		Account:     req.FormValue("account"),
+	}
+	// The start height defaults to the current height.
+	if s := req.FormValue("startheight"); s != "" {
+		start, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `startheight` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		sp.StartHeight = types.BlockHeight(start)
+	} else {
+		sp.StartHeight, err = api.wallet.Height()
+		if err != nil {
+			WriteError(w, Error{"error when calling /wallet/scheduledpayments: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	}
+	if s := req.FormValue("interval"); s != "" {
+		interval, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `interval` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		sp.Interval = types.BlockHeight(interval)
+	}
+	if s := req.FormValue("count"); s != "" {
+		sp.Count, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `count` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := api.wallet.AddScheduledPayment(sp); err != nil {
+		WriteError(w, Error{"error when calling /wallet/scheduledpayments: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletScheduledPaymentsRemoveHandlerPOST handles API calls to
+// /wallet/scheduledpayments/remove.
+func (api *API) walletScheduledPaymentsRemoveHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.wallet.RemoveScheduledPayment(req.FormValue("name"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/scheduledpayments/remove: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // walletUplofundsHandler handles API calls to /wallet/uplofunds.
 func (api *API) walletUplofundsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	amount, ok := scanAmount(req.FormValue("amount"))
@@ -579,6 +819,31 @@ func (api *API) walletUplofundsHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// walletBumpHandler handles API calls to /wallet/bump/:txid.
+func (api *API) walletBumpHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	var txid types.TransactionID
+	jsonID := "\"" + ps.ByName("txid") + "\""
+	err := txid.UnmarshalJSON([]byte(jsonID))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/bump/:txid: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	txns, err := api.wallet.BumpTransaction(txid)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/bump/:txid: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	var txids []types.TransactionID
+	for _, txn := range txns {
+		txids = append(txids, txn.ID())
+	}
+	WriteJSON(w, WalletBumpPOST{
+		Transactions:   txns,
+		TransactionIDs: txids,
+	})
+}
+
 // walletSweepSeedHandler handles API calls to /wallet/sweep/seed.
 func (api *API) walletSweepSeedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	// Get the seed using the dictionary + phrase
@@ -664,12 +929,216 @@ func (api *API) walletTransactionsHandler(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
+	labels, err := api.transactionLabels(confirmedTxns, unconfirmedTxns)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
 	WriteJSON(w, WalletTransactionsGET{
 		ConfirmedTransactions:   confirmedTxns,
 		UnconfirmedTransactions: unconfirmedTxns,
+		Labels:                  labels,
 	})
 }
 
+// walletExportHandler handles API calls to /wallet/export.
+func (api *API) walletExportHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse the heights. Both are optional.
+	var start uint64
+	end := uint64(math.MaxUint64)
+	var err error
+	if s := req.FormValue("startheight"); s != "" {
+		start, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `startheight` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if s := req.FormValue("endheight"); s != "" && s != "-1" {
+		end, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `endheight` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if start > end {
+		WriteError(w, Error{"startheight must not be greater than endheight"}, http.StatusBadRequest)
+		return
+	}
+	format := req.FormValue("format")
+	if format != "" && format != "json" && format != "csv" {
+		WriteError(w, Error{"format must be either json or csv"}, http.StatusBadRequest)
+		return
+	}
+	rates, err := parseExportExchangeRates(req.FormValue("exchangerates"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse exchangerates: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// The running balance requires the full history of the wallet.
+	all, err := api.wallet.ExportEntries()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/export: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	entries := []modules.WalletExportEntry{}
+	for _, e := range all {
+		if e.Height < types.BlockHeight(start) || e.Height > types.BlockHeight(end) {
+			continue
+		}
+		// Storage proof payouts don't have a timestamp in the wallet
+		// history, so it's taken from the block they matured in.
+		if e.Timestamp == 0 && api.cs != nil {
+			if b, ok := api.cs.BlockAtHeight(e.Height); ok {
+				e.Timestamp = b.Timestamp
+			}
+		}
+		// The fiat values are left empty if no rate applies at the time of
+		// the entry.
+		if rate := exportExchangeRateAt(rates, e.Timestamp); rate != nil {
+			e.FiatInflow = rate.Apply(e.Inflow)
+			e.FiatOutflow = rate.Apply(e.Outflow)
+			e.FiatBalance = rate.Apply(e.Balance)
+			if e.BalanceNegative {
+				e.FiatBalance = "-" + e.FiatBalance
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	if format != "csv" {
+		WriteJSON(w, WalletExportGET{Entries: entries})
+		return
+	}
+	header := []string{"transactionid", "height", "timestamp", "category", "inflow (UC)", "outflow (UC)", "balance (UC)"}
+	if len(rates) > 0 {
+		symbol := rates[0].rate.Symbol()
+		header = append(header, "inflow ("+symbol+")", "outflow ("+symbol+")", "balance ("+symbol+")")
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return
+	}
+	for _, e := range entries {
+		var timestamp string
+		if e.Timestamp != 0 {
+			timestamp = time.Unix(int64(e.Timestamp), 0).UTC().Format(time.RFC3339)
+		}
+		balance := formatUplocoins(e.Balance)
+		if e.BalanceNegative {
+			balance = "-" + balance
+		}
+		record := []string{
+			e.TransactionID.String(),
+			fmt.Sprint(e.Height),
+			timestamp,
+			string(e.Category),
+			formatUplocoins(e.Inflow),
+			formatUplocoins(e.Outflow),
+			balance,
+		}
+		if len(rates) > 0 {
+			record = append(record, e.FiatInflow, e.FiatOutflow, e.FiatBalance)
+		}
+		if err := cw.Write(record); err != nil {
+			return
+		}
+	}
+	cw.Flush()
+}
+
+// exportExchangeRate is an exchange rate that applies to the entries of a
+// wallet history export from a certain time on.
+type exportExchangeRate struct {
+	since types.Timestamp
+	rate  *types.ExchangeRate
+}
+
+// parseExportExchangeRates parses a comma separated list of exchange rates,
+// each prefixed with the unix timestamp from which on it applies, e.g.
+// "1600000000:0.004 USD,1610000000:0.005 USD". All rates must use the same
+// symbol. The rates are returned sorted by their timestamps.
+func parseExportExchangeRates(s string) ([]exportExchangeRate, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var rates []exportExchangeRate
+	for _, r := range strings.Split(s, ",") {
+		parts := strings.SplitN(r, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("exchange rate %q must be of the form <timestamp>:<rate>", r)
+		}
+		since, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse timestamp of exchange rate %q: %v", r, err)
+		}
+		rate, err := types.ParseExchangeRate(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse exchange rate %q: %v", r, err)
+		}
+		if rate == nil {
+			return nil, fmt.Errorf("exchange rate %q is empty", r)
+		}
+		if len(rates) > 0 && rate.Symbol() != rates[0].rate.Symbol() {
+			return nil, fmt.Errorf("exchange rates must use the same symbol, got %v and %v", rates[0].rate.Symbol(), rate.Symbol())
+		}
+		rates = append(rates, exportExchangeRate{since: types.Timestamp(since), rate: rate})
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].since < rates[j].since
+	})
+	return rates, nil
+}
+
+// exportExchangeRateAt returns the most recent of the sorted rates at the
+// timestamp, or nil if none of the rates applies yet.
+func exportExchangeRateAt(rates []exportExchangeRate, timestamp types.Timestamp) *types.ExchangeRate {
+	var rate *types.ExchangeRate
+	for _, r := range rates {
+		if timestamp == 0 || r.since > timestamp {
+			break
+		}
+		rate = r.rate
+	}
+	return rate
+}
+
+// formatUplocoins formats a currency amount as an exact decimal number of
+// Uplocoins.
+func formatUplocoins(c types.Currency) string {
+	s := new(big.Rat).SetFrac(c.Big(), types.UplocoinPrecision.Big()).FloatString(24)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// transactionLabels returns the labels of the given transactions, mapped by
+// their IDs.
+func (api *API) transactionLabels(txnSets ...[]modules.ProcessedTransaction) (map[string]string, error) {
+	walletLabels, err := api.wallet.Labels()
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]string)
+	for _, l := range walletLabels {
+		if l.Type == modules.WalletLabelTransaction {
+			all[l.ID] = l.Label
+		}
+	}
+	labels := make(map[string]string)
+	for _, txns := range txnSets {
+		for _, txn := range txns {
+			id := txn.TransactionID.String()
+			if label, ok := all[id]; ok {
+				labels[id] = label
+			}
+		}
+	}
+	return labels, nil
+}
+
 // walletTransactionsAddrHandler handles API calls to
 // /wallet/transactions/:addr.
 func (api *API) walletTransactionsAddrHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
@@ -692,9 +1161,15 @@ func (api *API) walletTransactionsAddrHandler(w http.ResponseWriter, _ *http.Req
 		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
+	labels, err := api.transactionLabels(confirmedATs, unconfirmedATs)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
 	WriteJSON(w, WalletTransactionsGETaddr{
 		ConfirmedTransactions:   confirmedATs,
 		UnconfirmedTransactions: unconfirmedATs,
+		Labels:                  labels,
 	})
 }
 
@@ -821,6 +1296,97 @@ func (api *API) walletUnspentHandler(w http.ResponseWriter, _ *http.Request, _ h
 	})
 }
 
+// walletUnspentFreezeHandler handles API calls to /wallet/unspent/freeze.
+func (api *API) walletUnspentFreezeHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	ids, err := scanOutputIDs(req.FormValue("ids"))
+	if err != nil || len(ids) == 0 {
+		WriteError(w, Error{"could not read ids from POST call to /wallet/unspent/freeze"}, http.StatusBadRequest)
+		return
+	}
+	err = api.wallet.FreezeOutputs(ids)
+	if errors.Contains(err, modules.ErrUnknownOutput) {
+		WriteError(w, Error{"error when calling /wallet/unspent/freeze: " + err.Error()}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		WriteError(w, Error{"error when calling /wallet/unspent/freeze: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletUnspentUnfreezeHandler handles API calls to /wallet/unspent/unfreeze.
+func (api *API) walletUnspentUnfreezeHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	ids, err := scanOutputIDs(req.FormValue("ids"))
+	if err != nil || len(ids) == 0 {
+		WriteError(w, Error{"could not read ids from POST call to /wallet/unspent/unfreeze"}, http.StatusBadRequest)
+		return
+	}
+	if err := api.wallet.UnfreezeOutputs(ids); err != nil {
+		WriteError(w, Error{"error when calling /wallet/unspent/unfreeze: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletLabelsHandlerGET handles GET API calls to /wallet/labels.
+func (api *API) walletLabelsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	labels, err := api.wallet.Labels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, WalletLabelsGET{
+		Labels: labels,
+	})
+}
+
+// walletLabelsHandlerPOST handles POST API calls to /wallet/labels.
+func (api *API) walletLabelsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	labelType := modules.WalletLabelType(req.FormValue("type"))
+	err := api.wallet.SetLabel(labelType, req.FormValue("id"), req.FormValue("label"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletAccountsHandlerGET handles GET API calls to /wallet/accounts.
+func (api *API) walletAccountsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	accounts, err := api.wallet.Accounts()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/accounts: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletAccountsGET{
+		Accounts: accounts,
+	})
+}
+
+// walletAccountsHandlerPOST handles POST API calls to /wallet/accounts.
+func (api *API) walletAccountsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.wallet.CreateAccount(req.FormValue("name"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/accounts: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletAccountsPinHandler handles API calls to /wallet/accounts/pin.
+func (api *API) walletAccountsPinHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	account := req.FormValue("account")
+	if account == "" {
+		account = modules.DefaultWalletAccount
+	}
+	err := api.wallet.SetModuleAccount(req.FormValue("module"), account)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/accounts/pin: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // walletSignHandler handles API calls to /wallet/sign.
 func (api *API) walletSignHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	var params WalletSignPOSTParams
@@ -1810,3 +1810,43 @@ func TestWalletTransactionsGetAddr(t *testing.T) {
 		t.Errorf("There should be exactly 0 unconfirmed and 1 confirmed related txns")
 	}
 }
+
+// TestParseExportExchangeRates probes the parsing of the dated exchange rates
+// of a wallet history export and the selection of the rate of an entry.
+func TestParseExportExchangeRates(t *testing.T) {
+	rates, err := parseExportExchangeRates("2000:0.5 USD,1000:0.25 USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 2 || rates[0].since != 1000 || rates[1].since != 2000 {
+		t.Fatal("rates weren't sorted", rates)
+	}
+	sc := types.UplocoinPrecision.Mul64(4)
+	tests := []struct {
+		timestamp types.Timestamp
+		fiat      string
+	}{
+		{0, ""},
+		{999, ""},
+		{1000, "1.00"},
+		{1999, "1.00"},
+		{2000, "2.00"},
+	}
+	for _, test := range tests {
+		rate := exportExchangeRateAt(rates, test.timestamp)
+		var fiat string
+		if rate != nil {
+			fiat = rate.Apply(sc)
+		}
+		if fiat != test.fiat {
+			t.Errorf("timestamp %v: expected %q, got %q", test.timestamp, test.fiat, fiat)
+		}
+	}
+
+	// Invalid rates are rejected.
+	for _, s := range []string{"0.5 USD", "abc:0.5 USD", "1000:", "1000:0.5 USD,2000:0.5 EUR"} {
+		if _, err := parseExportExchangeRates(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/threadgroup"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+)
+
+const (
+	// webhookSignatureHeader is the header which contains the HMAC-SHA256
+	// signature of the timestamp and the payload of a webhook request.
+	webhookSignatureHeader = "Uplo-Signature"
+
+	// webhookTimestampHeader is the header which contains the time at which a
+	// webhook request was sent as a unix timestamp in seconds.
+	webhookTimestampHeader = "Uplo-Timestamp"
+)
+
+var (
+	// maxWebhookAttempts is the number of times the delivery of an event to a
+	// webhook is attempted before giving up.
+	maxWebhookAttempts = build.Select(build.Var{
+		Standard: 6,
+		Dev:      4,
+		Testing:  3,
+	}).(int)
+
+	// webhookQueueSize is the number of events that can wait for their
+	// delivery to a single webhook. Events are dropped while the queue is
+	// full.
+	webhookQueueSize = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      100,
+		Testing:  10,
+	}).(int)
+
+	// maxWebhookDeliveries is the number of recent deliveries kept in the
+	// delivery log.
+	maxWebhookDeliveries = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      1000,
+		Testing:  100,
+	}).(int)
+
+	// webhookRetryInterval is the time to wait before the first retry of a
+	// failed delivery. The interval doubles with every retry.
+	webhookRetryInterval = build.Select(build.Var{
+		Standard: 10 * time.Second,
+		Dev:      time.Second,
+		Testing:  10 * time.Millisecond,
+	}).(time.Duration)
+
+	// webhookTimeout is the timeout of a single delivery attempt.
+	webhookTimeout = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      10 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+)
+
+var (
+	// errWebhookQueueFull is logged for events which are dropped because the
+	// delivery queue of the webhook is full.
+	errWebhookQueueFull = errors.New("delivery queue of the webhook is full")
+)
+
+type (
+	// WebhookDelivery is an entry of the delivery log of the webhooks. It
+	// describes the latest attempt to deliver an event to a webhook.
+	WebhookDelivery struct {
+		ID         uint64            `json:"id"`
+		Webhook    string            `json:"webhook"`
+		EventID    uint64            `json:"eventid"`
+		EventType  modules.EventType `json:"eventtype"`
+		Attempts   int               `json:"attempts"`
+		Delivered  bool              `json:"delivered"`
+		StatusCode int               `json:"statuscode"`
+		Error      string            `json:"error"`
+		Time       time.Time         `json:"time"`
+	}
+
+	// DaemonWebhooksGet contains the webhooks of the daemon. The secrets of
+	// the webhooks are omitted.
+	DaemonWebhooksGet struct {
+		Webhooks []modules.Webhook `json:"webhooks"`
+	}
+
+	// DaemonWebhookDeliveriesGet contains the recent deliveries of the
+	// webhooks, starting with the most recent one.
+	DaemonWebhookDeliveriesGet struct {
+		Deliveries []WebhookDelivery `json:"deliveries"`
+	}
+
+	// webhookDispatcher delivers the events of the event stream to the
+	// webhooks of the uplod config and keeps a log of the recent deliveries.
+	// Every webhook has its own queue and worker, so events are delivered to
+	// a webhook in order and a slow webhook doesn't delay the others.
+	webhookDispatcher struct {
+		deliveries []WebhookDelivery
+		nextID     uint64
+		workers    map[string]*webhookWorker
+
+		staticClient *http.Client
+		staticConfig *modules.UplodConfig
+		staticTG     *threadgroup.ThreadGroup
+		mu           sync.Mutex
+	}
+
+	// webhookJob is an event waiting for its delivery to a webhook.
+	webhookJob struct {
+		webhook modules.Webhook
+		event   DaemonEvent
+	}
+
+	// webhookWorker delivers the queued events of a single webhook. The
+	// worker stops once stop is closed, which happens when the webhook is
+	// removed.
+	webhookWorker struct {
+		queue chan webhookJob
+		stop  chan struct{}
+	}
+)
+
+// newWebhookDispatcher creates a dispatcher for the webhooks of the config.
+// Webhooks are delivered using the global dialer, so they respect the proxy
+// settings of the daemon. The workers of the dispatcher are part of the
+// threadgroup and stop when it is stopped.
+func newWebhookDispatcher(cfg *modules.UplodConfig, tg *threadgroup.ThreadGroup) *webhookDispatcher {
+	return &webhookDispatcher{
+		nextID:       1,
+		workers:      make(map[string]*webhookWorker),
+		staticClient: modules.GlobalDialer.HTTPClient(webhookTimeout),
+		staticConfig: cfg,
+		staticTG:     tg,
+	}
+}
+
+// webhookSignature returns the hex encoded HMAC-SHA256 of the timestamp and
+// the payload, separated by a '.'. Signing the timestamp allows receivers to
+// reject replayed requests.
+func webhookSignature(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// eventModuleAndSeverity returns the module that published the event and, for
+// alerts, the severity of the alert.
+func eventModuleAndSeverity(e DaemonEvent) (string, modules.AlertSeverity) {
+	if e.Type != modules.EventAlertRegistered && e.Type != modules.EventAlertUnregistered {
+		return e.Type.Module(), modules.SeverityUnknown
+	}
+	var ae modules.AlertEvent
+	if err := json.Unmarshal(e.Data, &ae); err != nil {
+		build.Critical("failed to decode alert event:", err)
+	}
+	return ae.Module, ae.Severity
+}
+
+// dispatch queues an event for its delivery to all webhooks that match it. It
+// is used as a listener of the event stream, so it never blocks. If the queue
+// of a webhook is full, the event is dropped and the failed delivery is
+// logged.
+func (wd *webhookDispatcher) dispatch(e DaemonEvent) {
+	module, severity := eventModuleAndSeverity(e)
+	for _, wh := range wd.staticConfig.GetWebhooks() {
+		if !wh.MatchesEvent(e.Type, module, severity) {
+			continue
+		}
+		w, exists, err := wd.managedWorker(wh.Name)
+		if err != nil {
+			// The daemon is shutting down.
+			return
+		}
+		if !exists {
+			// The webhook was removed in the meantime.
+			continue
+		}
+		select {
+		case w.queue <- webhookJob{webhook: wh, event: e}:
+		default:
+			wd.managedLogDelivery(WebhookDelivery{
+				Webhook:   wh.Name,
+				EventID:   e.ID,
+				EventType: e.Type,
+				Error:     errWebhookQueueFull.Error(),
+				Time:      time.Now(),
+			})
+		}
+	}
+}
+
+// managedWorker returns the worker of the webhook with the given name,
+// starting it if necessary. The returned bool is false if the webhook doesn't
+// exist anymore. Since a removed webhook is removed from the config before its
+// worker is stopped, checking the config under the lock ensures that no worker
+// is started for a removed webhook.
+func (wd *webhookDispatcher) managedWorker(name string) (*webhookWorker, bool, error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	exists := false
+	for _, wh := range wd.staticConfig.GetWebhooks() {
+		exists = exists || wh.Name == name
+	}
+	if !exists {
+		return nil, false, nil
+	}
+	if w, exists := wd.workers[name]; exists {
+		return w, true, nil
+	}
+	if err := wd.staticTG.Add(); err != nil {
+		return nil, false, err
+	}
+	w := &webhookWorker{
+		queue: make(chan webhookJob, webhookQueueSize),
+		stop:  make(chan struct{}),
+	}
+	wd.workers[name] = w
+	go wd.threadedDeliverQueue(w)
+	return w, true, nil
+}
+
+// managedStopWorker stops the worker of the webhook with the given name. Events
+// which are still queued are dropped.
+func (wd *webhookDispatcher) managedStopWorker(name string) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	if w, exists := wd.workers[name]; exists {
+		close(w.stop)
+		delete(wd.workers, name)
+	}
+}
+
+// managedLogDelivery adds or updates a delivery in the delivery log. New
+// deliveries have an ID of 0 and are assigned a new ID.
+func (wd *webhookDispatcher) managedLogDelivery(d WebhookDelivery) uint64 {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	if d.ID != 0 {
+		for i := len(wd.deliveries) - 1; i >= 0; i-- {
+			if wd.deliveries[i].ID == d.ID {
+				wd.deliveries[i] = d
+				return d.ID
+			}
+		}
+		// The delivery was already pruned from the log.
+		return d.ID
+	}
+	d.ID = wd.nextID
+	wd.nextID++
+	wd.deliveries = append(wd.deliveries, d)
+	if len(wd.deliveries) > maxWebhookDeliveries {
+		wd.deliveries = wd.deliveries[len(wd.deliveries)-maxWebhookDeliveries:]
+	}
+	return d.ID
+}
+
+// managedDeliveries returns the logged deliveries to the webhook with the
+// given name, or all deliveries if name is empty, starting with the most
+// recent one.
+func (wd *webhookDispatcher) managedDeliveries(name string) []WebhookDelivery {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	deliveries := []WebhookDelivery{}
+	for i := len(wd.deliveries) - 1; i >= 0; i-- {
+		if name == "" || wd.deliveries[i].Webhook == name {
+			deliveries = append(deliveries, wd.deliveries[i])
+		}
+	}
+	return deliveries
+}
+
+// post makes a single attempt to deliver a payload to a webhook. Every attempt
+// is signed with the current time.
+func (wd *webhookDispatcher) post(ctx context.Context, wh modules.Webhook, e DaemonEvent, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", wh.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Uplo-Agent")
+	req.Header.Set("Uplo-Event", string(e.Type))
+	req.Header.Set("Uplo-Event-ID", strconv.FormatUint(e.ID, 10))
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, webhookSignature(wh.Secret, timestamp, payload))
+	resp, err := wd.staticClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %v", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// threadedDeliverQueue delivers the queued events of a webhook one after
+// another until the worker or the dispatcher is stopped.
+func (wd *webhookDispatcher) threadedDeliverQueue(w *webhookWorker) {
+	defer wd.staticTG.Done()
+
+	// Abort deliveries in progress when the worker is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-wd.staticTG.StopChan():
+		case <-w.stop:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-w.queue:
+			wd.managedDeliver(ctx, job.webhook, job.event)
+		}
+	}
+}
+
+// managedDeliver delivers an event to a webhook. Failed deliveries are retried
+// with an exponential backoff until maxWebhookAttempts is reached or ctx is
+// cancelled.
+func (wd *webhookDispatcher) managedDeliver(ctx context.Context, wh modules.Webhook, e DaemonEvent) {
+	d := WebhookDelivery{
+		Webhook:   wh.Name,
+		EventID:   e.ID,
+		EventType: e.Type,
+		Time:      time.Now(),
+	}
+	d.ID = wd.managedLogDelivery(d)
+	payload, err := json.Marshal(e)
+	if err != nil {
+		build.Critical("failed to marshal event:", err)
+		return
+	}
+	backoff := webhookRetryInterval
+	for d.Attempts < maxWebhookAttempts {
+		if d.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		d.Attempts++
+		d.Time = time.Now()
+		d.StatusCode, err = wd.post(ctx, wh, e, payload)
+		d.Delivered = err == nil
+		d.Error = ""
+		if err != nil {
+			d.Error = err.Error()
+		}
+		wd.managedLogDelivery(d)
+		if d.Delivered {
+			return
+		}
+	}
+}
+
+// parseSeverity parses the name of an alert severity.
+func parseSeverity(s string) (modules.AlertSeverity, error) {
+	var severity modules.AlertSeverity
+	err := severity.UnmarshalJSON([]byte(strconv.Quote(s)))
+	return severity, err
+}
+
+// splitList splits a comma separated list, ignoring empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// daemonWebhooksHandlerGET handles the API call to list the webhooks.
+func (api *API) daemonWebhooksHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	webhooks := api.uplodConfig.GetWebhooks()
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	WriteJSON(w, DaemonWebhooksGet{
+		Webhooks: webhooks,
+	})
+}
+
+// daemonWebhooksAddHandlerPOST handles the API call to add a webhook.
+func (api *API) daemonWebhooksAddHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	wh := modules.Webhook{
+		Name:        req.FormValue("name"),
+		URL:         req.FormValue("url"),
+		Secret:      req.FormValue("secret"),
+		Modules:     splitList(req.FormValue("modules")),
+		MinSeverity: modules.SeverityWarning,
+	}
+	for t := range parseEventTypes(req.FormValue("eventtypes")) {
+		wh.EventTypes = append(wh.EventTypes, t)
+	}
+	if severityStr := req.FormValue("minseverity"); severityStr != "" {
+		severity, err := parseSeverity(severityStr)
+		if err != nil {
+			WriteError(w, Error{"unable to parse minseverity: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		wh.MinSeverity = severity
+	}
+	if err := api.uplodConfig.AddWebhook(wh); err != nil {
+		WriteError(w, Error{"unable to add webhook: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// daemonWebhooksRemoveHandlerPOST handles the API call to remove a webhook.
+func (api *API) daemonWebhooksRemoveHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	name := req.FormValue("name")
+	err := api.uplodConfig.RemoveWebhook(name)
+	if errors.Contains(err, modules.ErrWebhookNotFound) {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		WriteError(w, Error{"unable to remove webhook: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	api.staticWebhooks.managedStopWorker(name)
+	WriteSuccess(w)
+}
+
+// daemonWebhookDeliveriesHandlerGET handles the API call to list the recent
+// deliveries of the webhooks.
+func (api *API) daemonWebhookDeliveriesHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, DaemonWebhookDeliveriesGet{
+		Deliveries: api.staticWebhooks.managedDeliveries(req.FormValue("webhook")),
+	})
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uplo-tech/errors"
+	"github.com/uplo-tech/threadgroup"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/modules"
+	"github.com/uplo-tech/uplo/persist"
+)
+
+// TestWebhookDelivery checks that events are delivered to matching webhooks
+// with a valid signature and that failed deliveries are retried.
+func TestWebhookDelivery(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("api", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := modules.NewConfig(filepath.Join(dir, modules.ConfigName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The receiver fails the first request of every event.
+	var mu sync.Mutex
+	var requests int
+	received := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		timestamp := req.Header.Get(webhookTimestampHeader)
+		if req.Header.Get(webhookSignatureHeader) != webhookSignature("secret", timestamp, b) {
+			t.Error("invalid signature")
+		}
+		if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+			t.Error("invalid timestamp", timestamp)
+		}
+		if req.Header.Get("Uplo-Event") != string(modules.EventUploadFailed) {
+			t.Error("unexpected event type", req.Header.Get("Uplo-Event"))
+		}
+		mu.Lock()
+		requests++
+		fail := requests == 1
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received <- b
+	}))
+	defer srv.Close()
+
+	err = cfg.AddWebhook(modules.Webhook{
+		Name:        "test",
+		URL:         srv.URL,
+		Secret:      "secret",
+		EventTypes:  []modules.EventType{modules.EventUploadFailed},
+		MinSeverity: modules.SeverityWarning,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tg threadgroup.ThreadGroup
+	es := newEventStream()
+	first := es.nextID
+	wd := newWebhookDispatcher(cfg, &tg)
+	es.addListener(wd.dispatch)
+
+	// Only the matching event is delivered.
+	es.publish(modules.EventUploadFinished, modules.UploadEvent{})
+	es.publish(modules.EventUploadFailed, modules.UploadEvent{Error: "failed"})
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("event wasn't delivered")
+	}
+
+	// The delivery log should contain the retried delivery.
+	err = build.Retry(100, 10*time.Millisecond, func() error {
+		deliveries := wd.managedDeliveries("test")
+		if len(deliveries) != 1 {
+			return errors.New("expected 1 delivery")
+		}
+		d := deliveries[0]
+		if !d.Delivered || d.Attempts != 2 || d.StatusCode != http.StatusOK || d.EventID != first+1 || d.Error != "" {
+			return errors.New("unexpected delivery")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err, wd.managedDeliveries(""))
+	}
+	if len(wd.managedDeliveries("other")) != 0 {
+		t.Fatal("deliveries should be filtered by webhook")
+	}
+
+	// Events are delivered in order.
+	for i := 0; i < 3; i++ {
+		es.publish(modules.EventUploadFailed, modules.UploadEvent{Error: "failed"})
+	}
+	for i := first + 2; i < first+5; i++ {
+		select {
+		case b := <-received:
+			var e DaemonEvent
+			if err := json.Unmarshal(b, &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.ID != i {
+				t.Fatalf("expected event %v, got %v", i, e.ID)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("event wasn't delivered")
+		}
+	}
+
+	// Stopping the threadgroup stops the worker, and no new deliveries are
+	// started afterwards.
+	if err := tg.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	numDeliveries := len(wd.managedDeliveries(""))
+	es.publish(modules.EventUploadFailed, modules.UploadEvent{Error: "failed"})
+	if len(wd.managedDeliveries("")) != numDeliveries {
+		t.Fatal("event was dispatched after shutdown")
+	}
+}
+
+// TestWebhookQueueFull checks that events are dropped and logged when the
+// queue of a webhook is full.
+func TestWebhookQueueFull(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("api", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := modules.NewConfig(filepath.Join(dir, modules.ConfigName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The receiver blocks until the test is done.
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	err = cfg.AddWebhook(modules.Webhook{
+		Name:        "test",
+		URL:         srv.URL,
+		Secret:      "secret",
+		MinSeverity: modules.SeverityWarning,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tg threadgroup.ThreadGroup
+	defer func() {
+		if err := tg.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	es := newEventStream()
+	wd := newWebhookDispatcher(cfg, &tg)
+	es.addListener(wd.dispatch)
+
+	// One event is in flight, the queue holds webhookQueueSize events and the
+	// remaining ones are dropped.
+	numEvents := webhookQueueSize + 5
+	for i := 0; i < numEvents; i++ {
+		es.publish(modules.EventUploadFailed, modules.UploadEvent{})
+		if i == 0 {
+			err = build.Retry(100, 10*time.Millisecond, func() error {
+				if len(wd.managedDeliveries("test")) != 1 {
+					return errors.New("first delivery wasn't started")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var dropped int
+	for _, d := range wd.managedDeliveries("test") {
+		if d.Error == errWebhookQueueFull.Error() {
+			dropped++
+		}
+	}
+	if dropped != numEvents-webhookQueueSize-1 {
+		t.Fatalf("expected %v dropped events, got %v", numEvents-webhookQueueSize-1, dropped)
+	}
+
+	// Removing the webhook stops its worker.
+	if err := cfg.RemoveWebhook("test"); err != nil {
+		t.Fatal(err)
+	}
+	wd.managedStopWorker("test")
+	wd.mu.Lock()
+	numWorkers := len(wd.workers)
+	wd.mu.Unlock()
+	if numWorkers != 0 {
+		t.Fatal("worker wasn't removed")
+	}
+
+	// No worker is started for the removed webhook, even by a dispatch which
+	// still saw the webhook.
+	if _, exists, err := wd.managedWorker("test"); err != nil || exists {
+		t.Fatal("worker was started for a removed webhook", exists, err)
+	}
+	wd.mu.Lock()
+	numWorkers = len(wd.workers)
+	wd.mu.Unlock()
+	if numWorkers != 0 {
+		t.Fatal("worker was started for a removed webhook")
+	}
+}
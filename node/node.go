@@ -110,6 +110,13 @@ type NodeParams struct {
 	HostStorage uint64
 	RPCAddress  string
 
+	// Custom settings for the miner's Stratum server. The server is only
+	// started if the address is set. Workers need to authorize with the
+	// password.
+	StratumAddress    string
+	StratumDifficulty uint64
+	StratumPassword   string
+
 	// Initialize node from existing seed.
 	PrimarySeed string
 
@@ -196,6 +203,15 @@ func printfRelease(format string, a ...interface{}) {
 	}
 }
 
+// moduleWallet returns the wallet of a module, which uses the wallet account
+// that the module is pinned to.
+func moduleWallet(w modules.Wallet, module string) modules.Wallet {
+	if w == nil {
+		return nil
+	}
+	return wallet.NewModuleWallet(w, module)
+}
+
 // Close will call close on every module within the node, combining and
 // returning the errors.
 func (n *Node) Close() (err error) {
@@ -410,7 +426,7 @@ func New(params NodeParams, loadStartTime time.Time) (*Node, <-chan error) {
 		}
 		i++
 		printfRelease("(%d/%d) Loading feemanager...\n", i, numModules)
-		return feemanager.NewCustomFeeManager(cs, tp, w, filepath.Join(dir, modules.FeeManagerDir), feeManagerDeps)
+		return feemanager.NewCustomFeeManager(cs, tp, moduleWallet(w, modules.FeeManagerDir), filepath.Join(dir, modules.FeeManagerDir), feeManagerDeps)
 	}()
 	if err != nil {
 		errChan <- errors.Extend(err, errors.New("unable to create feemanager"))
@@ -434,6 +450,16 @@ func New(params NodeParams, loadStartTime time.Time) (*Node, <-chan error) {
 		if err != nil {
 			return nil, err
 		}
+		if params.StratumAddress != "" {
+			difficulty := params.StratumDifficulty
+			if difficulty == 0 {
+				difficulty = modules.DefaultStratumDifficulty
+			}
+			err = m.StartStratum(params.StratumAddress, params.StratumPassword, difficulty)
+			if err != nil {
+				return nil, errors.Compose(err, m.Close())
+			}
+		}
 		return m, nil
 	}()
 	if err != nil {
@@ -465,7 +491,7 @@ func New(params NodeParams, loadStartTime time.Time) (*Node, <-chan error) {
 		}
 		i++
 		printfRelease("(%d/%d) Loading host...\n", i, numModules)
-		host, err := host.NewCustomTestHost(hostDeps, smDeps, cs, g, tp, w, mux, params.HostAddress, filepath.Join(dir, modules.HostDir))
+		host, err := host.NewCustomTestHost(hostDeps, smDeps, cs, g, tp, moduleWallet(w, modules.HostDir), mux, params.HostAddress, filepath.Join(dir, modules.HostDir))
 		return host, err
 	}()
 	if err != nil {
@@ -526,19 +552,20 @@ func New(params NodeParams, loadStartTime time.Time) (*Node, <-chan error) {
 			return nil, c
 		}
 		// Contractor
+		rw := moduleWallet(w, modules.RenterDir)
 		logger, err := persist.NewFileLogger(filepath.Join(persistDir, "contractor.log"))
 		if err != nil {
 			c <- err
 			close(c)
 			return nil, c
 		}
-		hc, errChanContractor := contractor.NewCustomContractor(cs, w, tp, hdb, persistDir, contractSet, logger, contractorDeps)
+		hc, errChanContractor := contractor.NewCustomContractor(cs, rw, tp, hdb, persistDir, contractSet, logger, contractorDeps)
 		if err := modules.PeekErr(errChanContractor); err != nil {
 			c <- err
 			close(c)
 			return nil, c
 		}
-		renter, errChanRenter := renter.NewCustomRenter(g, cs, tp, hdb, w, hc, mux, persistDir, renterRateLimit, renterDeps)
+		renter, errChanRenter := renter.NewCustomRenter(g, cs, tp, hdb, rw, hc, mux, persistDir, renterRateLimit, renterDeps)
 		if err := modules.PeekErr(errChanRenter); err != nil {
 			c <- err
 			close(c)
@@ -0,0 +1,155 @@
+package skykey
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+
+	"github.com/uplo-tech/encoding"
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/crypto"
+	"github.com/uplo-tech/uplo/types"
+)
+
+const (
+	// SkykeyShareScheme is the URI scheme for encoded skykey shares.
+	SkykeyShareScheme = "skykeyshare"
+
+	// maxSkykeyShareCiphertextLen is a cap on the ciphertext of a share to
+	// prevent over-allocating when decoding untrusted input. It must be at
+	// least the size of a marshaled skykey plus the cipher's overhead.
+	maxSkykeyShareCiphertextLen = 1 + maxEntropyLen + 8 + MaxKeyNameLen + 64
+)
+
+var (
+	// skykeyShareSpecifier is used when deriving the key that wraps a shared
+	// skykey.
+	skykeyShareSpecifier = types.NewSpecifier("SkykeyShare")
+
+	// errSkykeyShareCiphertextTooLarge is returned when decoding a share with a
+	// ciphertext that can't possibly contain a valid skykey.
+	errSkykeyShareCiphertextTooLarge = errors.New("Skykey share ciphertext exceeds max length")
+
+	// ErrSkykeyShareDecryptionFailed is returned when a share can't be opened
+	// with the given secret key, usually because it was wrapped for a
+	// different recipient.
+	ErrSkykeyShareDecryptionFailed = errors.New("Unable to decrypt skykey share, it might have been shared with a different public key")
+)
+
+// SkykeyShare is a skykey wrapped for a single recipient. The skykey is
+// encrypted with a key derived from an X25519 key exchange between an
+// ephemeral key pair and the recipient's public key, so only the holder of the
+// recipient's secret key can open it.
+type SkykeyShare struct {
+	EphemeralPublicKey crypto.X25519PublicKey
+	Ciphertext         crypto.Ciphertext
+}
+
+// shareCipherKey derives the key used to wrap a skykey shared between the
+// ephemeral key and the recipient key.
+func shareCipherKey(secret [32]byte, epk, rpk crypto.X25519PublicKey) crypto.CipherKey {
+	entropy := crypto.HashAll(skykeyShareSpecifier, secret, epk, rpk)
+	key, err := crypto.NewUploKey(crypto.TypeTwofish, entropy[:])
+	if err != nil {
+		panic("a hash always has the length of a twofish key: " + err.Error())
+	}
+	return key
+}
+
+// Share wraps the Skykey for the owner of the given X25519 public key.
+func (sk Skykey) Share(recipient crypto.X25519PublicKey) (SkykeyShare, error) {
+	if err := sk.IsValid(); err != nil {
+		return SkykeyShare{}, errors.AddContext(err, "Invalid skykey cannot be shared")
+	}
+	var b bytes.Buffer
+	if err := sk.marshalUplo(&b); err != nil {
+		return SkykeyShare{}, err
+	}
+
+	esk, epk := crypto.GenerateX25519KeyPair()
+	secret := crypto.DeriveSharedSecret(esk, recipient)
+	key := shareCipherKey(secret, epk, recipient)
+	return SkykeyShare{
+		EphemeralPublicKey: epk,
+		Ciphertext:         key.EncryptBytes(b.Bytes()),
+	}, nil
+}
+
+// Open unwraps the shared Skykey using the recipient's X25519 secret key.
+func (ss SkykeyShare) Open(xsk crypto.X25519SecretKey) (Skykey, error) {
+	secret := crypto.DeriveSharedSecret(xsk, ss.EphemeralPublicKey)
+	key := shareCipherKey(secret, ss.EphemeralPublicKey, xsk.PublicKey())
+
+	// DecryptBytes might operate in place, so don't pass our own ciphertext.
+	ct := append(crypto.Ciphertext(nil), ss.Ciphertext...)
+	plaintext, err := key.DecryptBytes(ct)
+	if err != nil {
+		return Skykey{}, errors.Compose(ErrSkykeyShareDecryptionFailed, err)
+	}
+	var sk Skykey
+	if err := sk.unmarshalUplo(bytes.NewReader(plaintext)); err != nil {
+		return Skykey{}, errors.AddContext(err, "Unable to unmarshal shared skykey")
+	}
+	return sk, nil
+}
+
+// ToString encodes the SkykeyShare as a URI.
+func (ss SkykeyShare) ToString() string {
+	shareURL := url.URL{
+		Scheme: SkykeyShareScheme,
+		Opaque: base64.URLEncoding.EncodeToString(encoding.Marshal(ss)),
+	}
+	return shareURL.String()
+}
+
+// FromString decodes a SkykeyShare from its URI or base64 representation.
+func (ss *SkykeyShare) FromString(s string) error {
+	sURL, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	var shareData string
+	if sURL.Scheme == SkykeyShareScheme {
+		shareData = sURL.Opaque
+	} else if sURL.Scheme == "" {
+		shareData = sURL.Path
+	} else {
+		return errors.New("Unknown URI scheme for skykey share")
+	}
+
+	shareBytes, err := base64.URLEncoding.DecodeString(shareData)
+	if err != nil {
+		return err
+	}
+	d := encoding.NewDecoder(bytes.NewReader(shareBytes), encoding.DefaultAllocLimit)
+	d.ReadFull(ss.EphemeralPublicKey[:])
+	ctLen := d.NextUint64()
+	if ctLen > maxSkykeyShareCiphertextLen {
+		return errSkykeyShareCiphertextTooLarge
+	}
+	ss.Ciphertext = make(crypto.Ciphertext, ctLen)
+	d.ReadFull(ss.Ciphertext)
+	return d.Err()
+}
+
+// SharePublicKeyToString encodes an X25519 public key used for sharing skykeys
+// as a base64 string.
+func SharePublicKeyToString(xpk crypto.X25519PublicKey) string {
+	return base64.URLEncoding.EncodeToString(xpk[:])
+}
+
+// SharePublicKeyFromString decodes an X25519 public key used for sharing
+// skykeys from its base64 string.
+func SharePublicKeyFromString(s string) (xpk crypto.X25519PublicKey, err error) {
+	b, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return xpk, err
+	}
+	if len(b) != len(xpk) {
+		return xpk, errors.New("Skykey share public key has invalid length")
+	}
+	copy(xpk[:], b)
+	return xpk, nil
+}
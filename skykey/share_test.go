@@ -0,0 +1,124 @@
+package skykey
+
+import (
+	"testing"
+
+	"github.com/uplo-tech/errors"
+
+	"github.com/uplo-tech/uplo/build"
+	"github.com/uplo-tech/uplo/crypto"
+)
+
+// TestSkykeyShare tests wrapping a skykey for a recipient and opening it
+// again.
+func TestSkykeyShare(t *testing.T) {
+	sk := Skykey{
+		Name:    "share",
+		Type:    TypePrivateID,
+		Entropy: crypto.GenerateUploKey(crypto.TypeXChaCha20).Key(),
+	}
+	xsk, xpk := crypto.GenerateX25519KeyPair()
+	ss, err := sk.Share(xpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The recipient should be able to open the share.
+	opened, err := ss.Open(xsk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opened.equals(sk) {
+		t.Fatal("opened skykey doesn't match shared skykey")
+	}
+
+	// The share should survive a string roundtrip.
+	var ss2 SkykeyShare
+	err = ss2.FromString(ss.ToString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err = ss2.Open(xsk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opened.equals(sk) {
+		t.Fatal("opened skykey doesn't match shared skykey after roundtrip")
+	}
+
+	// Somebody else shouldn't be able to open the share.
+	otherSK, _ := crypto.GenerateX25519KeyPair()
+	_, err = ss.Open(otherSK)
+	if !errors.Contains(err, ErrSkykeyShareDecryptionFailed) {
+		t.Fatal("expected decryption to fail", err)
+	}
+
+	// A tampered share shouldn't open either.
+	ss.Ciphertext[len(ss.Ciphertext)-1] ^= 1
+	_, err = ss.Open(xsk)
+	if !errors.Contains(err, ErrSkykeyShareDecryptionFailed) {
+		t.Fatal("expected decryption to fail", err)
+	}
+}
+
+// TestSkykeyManagerShare tests sharing skykeys between two SkykeyManagers.
+func TestSkykeyManagerShare(t *testing.T) {
+	sender, err := NewSkykeyManager(build.TempDir("skykey", t.Name(), "sender"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientDir := build.TempDir("skykey", t.Name(), "recipient")
+	recipient, err := NewSkykeyManager(recipientDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sharing key should be persisted.
+	recipient2, err := NewSkykeyManager(recipientDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipient.SharePublicKey() != recipient2.SharePublicKey() {
+		t.Fatal("sharing public key changed after reload")
+	}
+	if recipient.SharePublicKey() == sender.SharePublicKey() {
+		t.Fatal("managers shouldn't share the same sharing key")
+	}
+
+	sk, err := sender.CreateKey("shared", TypePublicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := sender.ShareKey(sk.ID(), recipient.SharePublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sender can't import a share meant for the recipient.
+	_, err = sender.ImportSharedKey(ss, "")
+	if !errors.Contains(err, ErrSkykeyShareDecryptionFailed) {
+		t.Fatal("expected decryption to fail", err)
+	}
+
+	// The recipient can import it under a different name.
+	imported, err := recipient.ImportSharedKey(ss, "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Name != "renamed" || !imported.equalData(sk) {
+		t.Fatal("imported skykey doesn't match")
+	}
+	stored, err := recipient.KeyByName("renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID() != sk.ID() {
+		t.Fatal("stored skykey has wrong ID")
+	}
+
+	// Importing it twice should fail.
+	_, err = recipient.ImportSharedKey(ss, "")
+	if !errors.Contains(err, ErrSkykeyWithIDAlreadyExists) {
+		t.Fatal("expected duplicate import to fail", err)
+	}
+}
@@ -3,6 +3,7 @@ package skykey
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -38,6 +39,10 @@ var (
 	// SkykeyPersistFilename is the name of the skykey persistence file.
 	SkykeyPersistFilename = "skykeys.dat"
 
+	// SkykeySharePersistFilename is the name of the file which stores the
+	// X25519 secret key used to receive shared skykeys.
+	SkykeySharePersistFilename = "skykeyshare.dat"
+
 	// oldFormatSkykeyVersionString is the version number which used a different
 	// marshaling/unmarshaling scheme for skykeys.
 	oldFormatSkykeyVersionString = "1.4.4"
@@ -76,6 +81,10 @@ type SkykeyManager struct {
 
 	staticPersistFile string
 	mu                sync.Mutex
+
+	// staticShareSecretKey is the secret half of the key pair other users can
+	// wrap skykeys for using their public key.
+	staticShareSecretKey crypto.X25519SecretKey
 }
 
 // countingWriter is a wrapper of an io.Writer that keeps track of the total
@@ -123,9 +132,43 @@ func NewSkykeyManager(persistDir string) (*SkykeyManager, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	// Load the sharing key. If it doesn't exist yet, it will be generated.
+	sm.staticShareSecretKey, err = loadShareSecretKey(filepath.Join(persistDir, SkykeySharePersistFilename))
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to load skykey sharing key")
+	}
 	return sm, nil
 }
 
+// loadShareSecretKey loads the X25519 secret key used for sharing skykeys from
+// the file at path. If the file doesn't exist, a new key is generated and
+// persisted.
+func loadShareSecretKey(path string) (xsk crypto.X25519SecretKey, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		if len(b) != len(xsk) {
+			return xsk, errors.New("skykey sharing key file has invalid length")
+		}
+		copy(xsk[:], b)
+		return xsk, nil
+	}
+	if !os.IsNotExist(err) {
+		return xsk, err
+	}
+
+	xsk, _ = crypto.GenerateX25519KeyPair()
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, defaultFilePerm)
+	if err != nil {
+		return xsk, err
+	}
+	_, err = file.Write(xsk[:])
+	if err != nil {
+		return xsk, errors.Compose(err, file.Close())
+	}
+	return xsk, errors.Compose(file.Sync(), file.Close())
+}
+
 // loadSkykey loads a skykey from the file starting at the offset n. It returns
 // the skykey and the offset to the first byte after the skykey.
 func loadSkykey(file *os.File, n int) (Skykey, int, error) {
@@ -306,6 +349,43 @@ func (sm *SkykeyManager) Skykeys() []Skykey {
 	return keys
 }
 
+// SharePublicKey returns the X25519 public key other users can share skykeys
+// with this SkykeyManager for.
+func (sm *SkykeyManager) SharePublicKey() crypto.X25519PublicKey {
+	return sm.staticShareSecretKey.PublicKey()
+}
+
+// ShareKey wraps the skykey with the given ID for the owner of the given X25519
+// public key.
+func (sm *SkykeyManager) ShareKey(id SkykeyID, recipient crypto.X25519PublicKey) (SkykeyShare, error) {
+	sk, err := sm.KeyByID(id)
+	if err != nil {
+		return SkykeyShare{}, err
+	}
+	return sk.Share(recipient)
+}
+
+// ImportSharedKey opens a skykey that was shared with this SkykeyManager's
+// public key and adds it. If name is not empty, the key is stored under that
+// name instead of the one it was shared with.
+func (sm *SkykeyManager) ImportSharedKey(ss SkykeyShare, name string) (Skykey, error) {
+	sk, err := ss.Open(sm.staticShareSecretKey)
+	if err != nil {
+		return Skykey{}, err
+	}
+	if name != "" {
+		sk.Name = name
+	}
+	if len(sk.Name) > MaxKeyNameLen {
+		return Skykey{}, errSkykeyNameToolong
+	}
+	err = sm.AddKey(sk)
+	if err != nil {
+		return Skykey{}, err
+	}
+	return sk, nil
+}
+
 // SupportsSkykeyType returns true if and only if the SkykeyManager supports
 // skykeys with the given type.
 func (sm *SkykeyManager) SupportsSkykeyType(skykeyType SkykeyType) bool {
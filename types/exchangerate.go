@@ -62,13 +62,14 @@ func ParseExchangeRate(s string) (*ExchangeRate, error) {
 	return rate, nil
 }
 
-// ApplyAndFormat applies the exchange rate to a currency amount and formats the
-// result. Assumes that c cannot be negative. The output will use two decimal
-// places, expect for small values where three or four decimal places are used.
-func (r *ExchangeRate) ApplyAndFormat(c Currency) string {
+// Apply applies the exchange rate to a currency amount and returns the result
+// as a decimal number without the symbol. Assumes that c cannot be negative.
+// The output will use two decimal places, expect for small values where three
+// or four decimal places are used.
+func (r *ExchangeRate) Apply(c Currency) string {
 	// deal with zero as a special case
 	if c.IsZero() {
-		return fmt.Sprintf("0.00 %s", r.staticSymbol)
+		return "0.00"
 	}
 
 	asRatio, _ := r.staticValue.Rat(nil)
@@ -88,7 +89,21 @@ func (r *ExchangeRate) ApplyAndFormat(c Currency) string {
 	if resultRat.Cmp(big.NewRat(1, 1000)) == -1 {
 		result = resultRat.FloatString(4)
 	}
-
-	result = fmt.Sprintf("~ %s %s", result, r.staticSymbol)
 	return result
 }
+
+// ApplyAndFormat applies the exchange rate to a currency amount and formats the
+// result. Assumes that c cannot be negative. The output will use two decimal
+// places, expect for small values where three or four decimal places are used.
+func (r *ExchangeRate) ApplyAndFormat(c Currency) string {
+	// deal with zero as a special case
+	if c.IsZero() {
+		return fmt.Sprintf("0.00 %s", r.staticSymbol)
+	}
+	return fmt.Sprintf("~ %s %s", r.Apply(c), r.staticSymbol)
+}
+
+// Symbol returns the symbol of the currency of the exchange rate.
+func (r *ExchangeRate) Symbol() string {
+	return r.staticSymbol
+}
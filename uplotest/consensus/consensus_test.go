@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -1192,3 +1194,86 @@ func TestFoundationHardfork(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestConsensusSnapshot tests exporting a snapshot of the consensus state on
+// one node and bootstrapping a new node from it with the checkpoint returned
+// by the export.
+func TestConsensusSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	testDir := consensusTestDir(t.Name())
+
+	// Create a funded node to export the snapshot from and a clean node to
+	// import it into.
+	exporter, err := uplotest.NewNode(node.AllModules(filepath.Join(testDir, "exporter")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := exporter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	importer, err := uplotest.NewCleanNode(node.Wallet(filepath.Join(testDir, "importer")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := importer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Export the snapshot.
+	snapshotPath := filepath.Join(testDir, "consensus.snapshot")
+	csp, err := exporter.ConsensusSnapshotExportPost(snapshotPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cg, err := exporter.ConsensusGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csp.Height != cg.Height {
+		t.Fatal("snapshot wasn't exported at the current height", csp.Height, cg.Height)
+	}
+
+	// Importing the snapshot with the wrong checkpoint fails.
+	if err := importer.ConsensusSnapshotImportPost(snapshotPath, crypto.Hash{1}); err == nil {
+		t.Fatal("snapshot with wrong checkpoint was imported")
+	}
+
+	// Import the snapshot with the checkpoint returned by the export.
+	if err := importer.ConsensusSnapshotImportPost(snapshotPath, csp.Hash); err != nil {
+		t.Fatal(err)
+	}
+	icg, err := importer.ConsensusGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if icg.Height != cg.Height || icg.CurrentBlock != cg.CurrentBlock {
+		t.Fatal("importer isn't at the state of the snapshot", icg.Height, cg.Height)
+	}
+
+	// The importer continues to sync from the snapshot.
+	if err := exporter.MineBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := importer.GatewayConnectPost(exporter.GatewayAddress()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		icg, err := importer.ConsensusGet()
+		if err != nil {
+			return err
+		}
+		if icg.Height != cg.Height+1 {
+			return fmt.Errorf("importer didn't sync: height %v", icg.Height)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}